@@ -0,0 +1,18 @@
+// Package adminbp provides an internal admin HTTP server.
+//
+// The admin server listens on its own port, separated from the main listener
+// of the service, and serves the debugging endpoints:
+//
+//     /debug/pprof/  net/http/pprof profiles
+//     /health        health check, see Server.SetHealthCheck
+//     /vars          expvar
+//
+// Additional endpoints (e.g. /metrics) can be registered via Server.Handle.
+//
+// Only requests from the networks in Config.AllowedNetworks
+// (defaults to loopback only) are allowed,
+// all other requests are rejected with 403.
+//
+// baseplate.New starts the admin server automatically when the admin address is
+// configured.
+package adminbp
@@ -0,0 +1,198 @@
+package adminbp
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// DefaultAllowedNetworks are the networks allowed to access the admin server
+// when AllowedNetworks in Config is empty.
+var DefaultAllowedNetworks = []string{
+	"127.0.0.0/8",
+	"::1/128",
+}
+
+// Config is the configuration struct for the admin server.
+//
+// Can be deserialized from YAML.
+type Config struct {
+	// Addr is the local address to run the admin server on,
+	// in the same format as baseplate.Config.Addr.
+	//
+	// When it's empty, the admin server is not started by baseplate.New.
+	Addr string `yaml:"addr"`
+
+	// AllowedNetworks are the networks in CIDR notation that are allowed to
+	// access the admin server.
+	//
+	// Optional, defaults to DefaultAllowedNetworks.
+	AllowedNetworks []string `yaml:"allowedNetworks"`
+}
+
+// HealthCheck is the function used by the /health endpoint.
+//
+// It should return nil when the service is healthy.
+type HealthCheck func(ctx context.Context) error
+
+// Server is the admin HTTP server.
+//
+// Please use NewServer to create a Server.
+type Server struct {
+	mux     *http.ServeMux
+	allowed []*net.IPNet
+	server  *http.Server
+
+	lock   sync.RWMutex
+	health HealthCheck
+	addr   net.Addr
+}
+
+var _ http.Handler = (*Server)(nil)
+
+// NewServer creates a new Server with the debugging endpoints registered.
+//
+// It returns an error if any of the AllowedNetworks is not a valid CIDR.
+// The returned Server is not started, call Start to start it.
+func NewServer(cfg Config) (*Server, error) {
+	networks := cfg.AllowedNetworks
+	if len(networks) == 0 {
+		networks = DefaultAllowedNetworks
+	}
+	allowed := make([]*net.IPNet, 0, len(networks))
+	for _, cidr := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("adminbp: invalid allowed network %q: %w", cidr, err)
+		}
+		allowed = append(allowed, network)
+	}
+
+	s := &Server{
+		mux:     http.NewServeMux(),
+		allowed: allowed,
+	}
+	s.server = &http.Server{
+		Addr:    cfg.Addr,
+		Handler: s,
+	}
+
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.HandleFunc("/health", s.serveHealth)
+	s.mux.Handle("/vars", expvar.Handler())
+	return s, nil
+}
+
+// Handle registers an additional handler for the given pattern,
+// for example a /metrics endpoint.
+//
+// The access control applies to the additional handlers as well.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// SetHealthCheck sets the HealthCheck used by the /health endpoint.
+//
+// Before it's called, /health always reports healthy.
+func (s *Server) SetHealthCheck(health HealthCheck) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.health = health
+}
+
+// ServeHTTP implements http.Handler.
+//
+// It rejects the requests not from the allowed networks with 403.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.isAllowed(r.RemoteAddr) {
+		code := http.StatusForbidden
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) isAllowed(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	s.lock.RLock()
+	health := s.health
+	s.lock.RUnlock()
+
+	if health != nil {
+		if err := health(r.Context()); err != nil {
+			log.Named("adminbp").Warnw("Admin server health check failed", "err", err)
+			code := http.StatusServiceUnavailable
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+// Start binds the configured address and starts serving in a background
+// goroutine.
+//
+// It returns an error when it failed to bind the address.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("adminbp: failed to listen on %q: %w", s.server.Addr, err)
+	}
+	s.lock.Lock()
+	s.addr = listener.Addr()
+	s.lock.Unlock()
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Named("adminbp").Errorw("Admin server stopped unexpectedly", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the admin server is listening on.
+//
+// Before Start is called, it returns the configured address.
+func (s *Server) Addr() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.addr != nil {
+		return s.addr.String()
+	}
+	return s.server.Addr
+}
+
+// Close implements io.Closer.
+//
+// It closes the admin server immediately.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
@@ -0,0 +1,155 @@
+package adminbp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddit/baseplate.go/adminbp"
+)
+
+func TestServer(t *testing.T) {
+	server, err := adminbp.NewServer(adminbp.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "metrics")
+	}))
+
+	for _, c := range []struct {
+		label      string
+		path       string
+		remoteAddr string
+		expected   int
+	}{
+		{
+			label:      "pprof",
+			path:       "/debug/pprof/",
+			remoteAddr: "127.0.0.1:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			label:      "vars",
+			path:       "/vars",
+			remoteAddr: "[::1]:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			label:      "health",
+			path:       "/health",
+			remoteAddr: "127.0.0.1:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			label:      "additional",
+			path:       "/metrics",
+			remoteAddr: "127.0.0.1:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			label:      "forbidden",
+			path:       "/debug/pprof/",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   http.StatusForbidden,
+		},
+		{
+			label:      "forbidden-additional",
+			path:       "/metrics",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   http.StatusForbidden,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			req.RemoteAddr = c.remoteAddr
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+			if w.Code != c.expected {
+				t.Errorf("Expected status code %d, got %d", c.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestServerAllowedNetworks(t *testing.T) {
+	if _, err := adminbp.NewServer(adminbp.Config{
+		AllowedNetworks: []string{"foo"},
+	}); err == nil {
+		t.Error("Expected error for invalid allowed network, got nil")
+	}
+
+	server, err := adminbp.NewServer(adminbp.Config{
+		AllowedNetworks: []string{"10.0.0.0/8"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for remoteAddr, expected := range map[string]int{
+		"10.1.2.3:1234":  http.StatusOK,
+		"127.0.0.1:1234": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("Expected status code %d for %q, got %d", expected, remoteAddr, w.Code)
+		}
+	}
+}
+
+func TestServerHealthCheck(t *testing.T) {
+	server, err := adminbp.NewServer(adminbp.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var healthErr error
+	server.SetHealthCheck(func(context.Context) error {
+		return healthErr
+	})
+
+	check := func(t *testing.T, expected int) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("Expected status code %d, got %d", expected, w.Code)
+		}
+	}
+
+	check(t, http.StatusOK)
+	healthErr = errors.New("unhealthy")
+	check(t, http.StatusServiceUnavailable)
+}
+
+func TestServerStart(t *testing.T) {
+	server, err := adminbp.NewServer(adminbp.Config{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	addr := server.Addr()
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "OK\n" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode, body)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	yaml "gopkg.in/yaml.v2"
 
+	"github.com/reddit/baseplate.go/adminbp"
 	"github.com/reddit/baseplate.go/batcherror"
 	"github.com/reddit/baseplate.go/edgecontext"
 	"github.com/reddit/baseplate.go/log"
@@ -36,10 +37,12 @@ type Config struct {
 	// If this is not set, then no timeout will be set on the Stop command.
 	StopTimeout time.Duration `yaml:"stopTimeout"`
 
+	Admin   adminbp.Config   `yaml:"admin"`
 	Log     log.Config       `yaml:"log"`
 	Metrics metricsbp.Config `yaml:"metrics"`
+	Runtime runtimebp.Config `yaml:"runtime"`
 	Secrets secrets.Config   `yaml:"secrets"`
-	Sentry  log.SentryConfig `yaml:"setry"`
+	Sentry  log.SentryConfig `yaml:"sentry"`
 	Tracing tracing.Config   `yaml:"tracing"`
 }
 
@@ -52,6 +55,25 @@ type Baseplate interface {
 	Secrets() *secrets.Store
 }
 
+// AdminServerProvider is an optional interface a Baseplate can implement to
+// provide its admin server.
+//
+// The Baseplate returned by New implements it.
+type AdminServerProvider interface {
+	// AdminServer returns the admin server started by New,
+	// or nil if Admin.Addr was not configured.
+	AdminServer() *adminbp.Server
+}
+
+// AdminServer returns the admin server of bp,
+// or nil if bp doesn't implement AdminServerProvider.
+func AdminServer(bp Baseplate) *adminbp.Server {
+	if p, ok := bp.(AdminServerProvider); ok {
+		return p.AdminServer()
+	}
+	return nil
+}
+
 // Server is the primary interface for baseplate servers.
 type Server interface {
 	// Close should stop the server gracefully and only return after the server has
@@ -82,13 +104,34 @@ type Server interface {
 // If a StopTimeout is configure, Serve will wait for that duration for the
 // server to stop before timing out and returning to force a shutdown.
 //
+// After the server is closed,
+// Serve also flushes the spans buffered by the global tracer (see
+// tracing.FlushTracer) within the same StopTimeout,
+// and the metrics buffered by metricsbp.M (see metricsbp.Statsd.Flush),
+// so the spans and metrics of the last requests are not lost.
+//
+// If the server stops serving on its own before any shutdown signal is
+// received (for example, it failed to bind to the address),
+// Serve returns the error returned by server.Serve immediately instead of
+// waiting for a shutdown signal that will never come.
+//
+// Serve does not close the Baseplate the server is built on,
+// the caller should still close it after Serve returns to flush the tracer,
+// metrics, and other resources.
+//
 // This is the recommended way to run a Baseplate Server rather than calling
 // server.Start/Stop directly.
 func Serve(ctx context.Context, server Server) error {
-	shutdownChannel := make(chan error)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	shutdownStarted := make(chan struct{})
+	shutdownChannel := make(chan error, 1)
 	go runtimebp.HandleShutdown(
 		ctx,
 		func(signal os.Signal) {
+			close(shutdownStarted)
+
 			timeout := server.Baseplate().Config().StopTimeout
 			ctx := context.Background()
 			if timeout != 0 {
@@ -111,16 +154,32 @@ func Serve(ctx context.Context, server Server) error {
 				err = e
 			}
 
-			log.Infow(
+			// Publish the spans of the last requests still buffered in memory,
+			// with whatever is left of the StopTimeout.
+			flushErr := tracing.FlushTracer(ctx)
+			// Then the metrics reported since the last flush interval.
+			metricsFlushErr := metricsbp.M.Flush()
+
+			log.Named("baseplate").Infow(
 				"graceful shutdown",
 				"signal", signal,
 				"close error", err,
+				"tracer flush error", flushErr,
+				"metrics flush error", metricsFlushErr,
 			)
 			shutdownChannel <- err
 		},
 	)
-	log.Info(server.Serve())
-	return <-shutdownChannel
+
+	err := server.Serve()
+	select {
+	case <-shutdownStarted:
+		log.Named("baseplate").Info(err)
+		return <-shutdownChannel
+	default:
+		// The server stopped without receiving a shutdown signal.
+		return err
+	}
 }
 
 // ParseConfig returns a new Config parsed from the YAML file at the given path.
@@ -147,7 +206,7 @@ func DecodeConfigYAML(reader io.Reader) (Config, error) {
 		return cfg, err
 	}
 
-	log.Debugf("%#v", cfg)
+	log.Named("baseplate").Debugf("%#v", cfg)
 	return cfg, nil
 }
 
@@ -163,6 +222,9 @@ func (c cancelCloser) Close() error {
 // New parses the config file at the given path, initializes the monitoring and
 // logging frameworks, and returns the "serve" context and a new Baseplate to
 // run your service on.
+//
+// If Admin.Addr is configured, it also starts the admin server on that address,
+// serving the Prometheus metrics at "/metrics" when Metrics.Prometheus is true.
 func New(ctx context.Context, path string) (Baseplate, error) {
 	cfg, err := ParseConfig(path)
 	if err != nil {
@@ -174,6 +236,7 @@ func New(ctx context.Context, path string) (Baseplate, error) {
 	bp.closers = append(bp.closers, cancelCloser{cancel})
 
 	log.InitFromConfig(cfg.Log)
+	runtimebp.InitFromConfig(cfg.Runtime)
 	bp.closers = append(bp.closers, metricsbp.InitFromConfig(ctx, cfg.Metrics))
 
 	closer, err := log.InitSentry(cfg.Sentry)
@@ -198,9 +261,26 @@ func New(ctx context.Context, path string) (Baseplate, error) {
 	bp.closers = append(bp.closers, closer)
 
 	bp.ecImpl = edgecontext.Init(edgecontext.Config{
-		Store:  bp.secrets,
-		Logger: log.ErrorWithSentryWrapper(),
+		Store:    bp.secrets,
+		Logger:   log.ErrorWithSentryWrapper(),
+		SpanTags: cfg.Tracing.EdgeContextSpanTags,
 	})
+
+	if cfg.Admin.Addr != "" {
+		bp.admin, err = adminbp.NewServer(cfg.Admin)
+		if err != nil {
+			bp.Close()
+			return nil, err
+		}
+		if registry := metricsbp.M.Prometheus(); registry != nil {
+			bp.admin.Handle("/metrics", registry)
+		}
+		if err = bp.admin.Start(); err != nil {
+			bp.Close()
+			return nil, err
+		}
+		bp.closers = append(bp.closers, bp.admin)
+	}
 	return bp, nil
 }
 
@@ -209,6 +289,7 @@ type impl struct {
 	closers []io.Closer
 	ecImpl  *edgecontext.Impl
 	secrets *secrets.Store
+	admin   *adminbp.Server
 }
 
 func (bp impl) Config() Config {
@@ -223,11 +304,15 @@ func (bp impl) EdgeContextImpl() *edgecontext.Impl {
 	return bp.ecImpl
 }
 
+func (bp impl) AdminServer() *adminbp.Server {
+	return bp.admin
+}
+
 func (bp impl) Close() error {
 	batch := &batcherror.BatchError{}
 	for _, c := range bp.closers {
 		if err := c.Close(); err != nil {
-			log.Errorw(
+			log.Named("baseplate").Errorw(
 				"Failed to close closer",
 				"err", err,
 				"closer", fmt.Sprintf("%#v", c),
@@ -252,6 +337,7 @@ func NewTestBaseplate(cfg Config, store *secrets.Store) Baseplate {
 }
 
 var (
-	_ Baseplate = impl{}
-	_ Baseplate = (*impl)(nil)
+	_ Baseplate           = impl{}
+	_ Baseplate           = (*impl)(nil)
+	_ AdminServerProvider = impl{}
 )
@@ -13,8 +13,10 @@ import (
 	"time"
 
 	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/adminbp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/runtimebp"
 	"github.com/reddit/baseplate.go/secrets"
 	"github.com/reddit/baseplate.go/tracing"
 )
@@ -126,6 +128,23 @@ func (s *testServer) Close() error {
 
 var _ baseplate.Server = (*testServer)(nil)
 
+type serveErrorServer struct {
+	bp       baseplate.Baseplate
+	serveErr error
+}
+
+func (s serveErrorServer) Baseplate() baseplate.Baseplate {
+	return s.bp
+}
+
+func (s serveErrorServer) Serve() error {
+	return s.serveErr
+}
+
+func (s serveErrorServer) Close() error {
+	return nil
+}
+
 func TestServe(t *testing.T) {
 	t.Parallel()
 
@@ -188,6 +207,36 @@ func TestServe(t *testing.T) {
 	}
 }
 
+func TestServeError(t *testing.T) {
+	t.Parallel()
+
+	store, dir := newSecretsStore(t)
+	defer func() {
+		os.RemoveAll(dir)
+		store.Close()
+	}()
+
+	bp := baseplate.NewTestBaseplate(baseplate.Config{StopTimeout: testTimeout}, store)
+	serveErr := errors.New("test serve error")
+
+	ch := make(chan error)
+	go func() {
+		ch <- baseplate.Serve(context.Background(), serveErrorServer{
+			bp:       bp,
+			serveErr: serveErr,
+		})
+	}()
+
+	select {
+	case err := <-ch:
+		if !errors.Is(err, serveErr) {
+			t.Errorf("error mismatch, expected %v, got %v", serveErr, err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after server.Serve failed")
+	}
+}
+
 func float64Ptr(v float64) *float64 {
 	return &v
 }
@@ -198,6 +247,11 @@ addr: :8080
 timeout: 30s
 stopTimeout: 30s
 
+admin:
+ addr: :6060
+ allowedNetworks:
+  - 10.0.0.0/8
+
 log:
  level: info
 
@@ -206,9 +260,19 @@ metrics:
  endpoint: metrics:8125
  histogramSampleRate: 0.01
 
+runtime:
+ numProcesses:
+  max: 4
+
 secrets:
  path: /tmp/secrets.json
 
+sentry:
+ dsn: https://key@sentry.local/1
+ environment: test
+ sampleRate: 0.2
+ flushTimeout: 1s
+
 tracing:
  namespace: baseplate-test
  queueName: test
@@ -221,6 +285,11 @@ tracing:
 		Timeout:     time.Second * 30,
 		StopTimeout: time.Second * 30,
 
+		Admin: adminbp.Config{
+			Addr:            ":6060",
+			AllowedNetworks: []string{"10.0.0.0/8"},
+		},
+
 		Log: log.Config{
 			Level: "info",
 		},
@@ -232,10 +301,23 @@ tracing:
 			HistogramSampleRate: float64Ptr(0.01),
 		},
 
+		Runtime: runtimebp.Config{
+			NumProcesses: runtimebp.NumProcessesConfig{
+				Max: 4,
+			},
+		},
+
 		Secrets: secrets.Config{
 			Path: "/tmp/secrets.json",
 		},
 
+		Sentry: log.SentryConfig{
+			DSN:          "https://key@sentry.local/1",
+			Environment:  "test",
+			SampleRate:   float64Ptr(0.2),
+			FlushTimeout: time.Second,
+		},
+
 		Tracing: tracing.Config{
 			Namespace:     "baseplate-test",
 			QueueName:     "test",
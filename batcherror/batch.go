@@ -18,6 +18,7 @@ var (
 //
 // This type is not thread-safe.
 // The same batch should not be operated on different goroutines concurrently.
+// Use SyncBatchError for that instead.
 type BatchError struct {
 	errors []error
 }
@@ -85,6 +86,15 @@ func (be BatchError) Is(target error) bool {
 	return false
 }
 
+// Unwrap returns a copy of the underlying error(s).
+//
+// It's the same as GetErrors, and implements the multi-error form of the
+// helper interface for errors.Unwrap, which is used by errors.Is and errors.As
+// in newer Go versions, and by other error inspecting libraries.
+func (be BatchError) Unwrap() []error {
+	return be.GetErrors()
+}
+
 func (be *BatchError) addBatch(batch BatchError) {
 	be.errors = append(be.errors, batch.errors...)
 }
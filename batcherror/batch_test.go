@@ -2,6 +2,8 @@ package batcherror_test
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"reflect"
 	"testing"
 
@@ -141,3 +143,25 @@ func TestGetErrors(t *testing.T) {
 		)
 	}
 }
+
+func TestUnwrap(t *testing.T) {
+	var batch batcherror.BatchError
+	err0 := errors.New("foo")
+	err1 := fmt.Errorf("wrapped: %w", io.EOF)
+
+	batch.Add(err0)
+	batch.Add(err1)
+	expect := []error{err0, err1}
+	if errs := batch.Unwrap(); !reflect.DeepEqual(errs, expect) {
+		t.Errorf("Unwrap expected %#v, got %#v", expect, errs)
+	}
+
+	err := batch.Compile()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("Expected %v to be io.EOF", err)
+	}
+	var unwrapper interface{ Unwrap() []error }
+	if !errors.As(err, &unwrapper) {
+		t.Errorf("Expected %v to implement Unwrap() []error", err)
+	}
+}
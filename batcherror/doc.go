@@ -29,6 +29,8 @@
 //         return batch.Compile()
 //     }
 //
-// This package is not thread-safe.
+// BatchError is not thread-safe.
 // The same batch should not be operated on different goroutines concurrently.
+// Use SyncBatchError instead when the errors are added from different
+// goroutines.
 package batcherror
@@ -0,0 +1,73 @@
+package batcherror
+
+import (
+	"sync"
+)
+
+// SyncBatchError is the thread-safe version of BatchError.
+//
+// It can be used to collect errors from multiple goroutines without an
+// external lock, e.g.:
+//
+//     var batch batcherror.SyncBatchError
+//     var wg sync.WaitGroup
+//     for _, work := range works {
+//         wg.Add(1)
+//         go func(work worker) {
+//             defer wg.Done()
+//             batch.Add(work())
+//         }(work)
+//     }
+//     wg.Wait()
+//     return batch.Compile()
+//
+// The zero value of SyncBatchError is valid (with no errors) and ready to use.
+// A SyncBatchError must not be copied after first use.
+type SyncBatchError struct {
+	lock  sync.Mutex
+	batch BatchError
+}
+
+// Add adds an error into the batch.
+//
+// It's safe to be called from different goroutines concurrently.
+//
+// See BatchError.Add for more details.
+func (be *SyncBatchError) Add(err error) {
+	if err == nil {
+		return
+	}
+
+	be.lock.Lock()
+	defer be.lock.Unlock()
+	be.batch.Add(err)
+}
+
+// Compile compiles the batch.
+//
+// The returned error is a snapshot of the batch,
+// later Add calls don't change it.
+//
+// See BatchError.Compile for more details.
+func (be *SyncBatchError) Compile() error {
+	be.lock.Lock()
+	defer be.lock.Unlock()
+
+	// Copy the errors so the returned BatchError doesn't share the underlying
+	// array with the later Add calls.
+	return BatchError{errors: be.batch.GetErrors()}.Compile()
+}
+
+// Clear clears the batch.
+func (be *SyncBatchError) Clear() {
+	be.lock.Lock()
+	defer be.lock.Unlock()
+	be.batch.Clear()
+}
+
+// GetErrors returns a copy of the underlying error(s).
+func (be *SyncBatchError) GetErrors() []error {
+	be.lock.Lock()
+	defer be.lock.Unlock()
+	return be.batch.GetErrors()
+}
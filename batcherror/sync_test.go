@@ -0,0 +1,53 @@
+package batcherror_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/batcherror"
+)
+
+func TestSyncBatchError(t *testing.T) {
+	const n = 100
+
+	var batch batcherror.SyncBatchError
+	if err := batch.Compile(); err != nil {
+		t.Errorf("Expected nil error from empty batch, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				batch.Add(nil)
+			} else {
+				batch.Add(fmt.Errorf("error %d", i))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if errs := batch.GetErrors(); len(errs) != n/2 {
+		t.Errorf("Expected %d errors, got %d", n/2, len(errs))
+	}
+	err := batch.Compile()
+	var be batcherror.BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("Expected BatchError, got %v", err)
+	}
+
+	// The compiled error is a snapshot.
+	batch.Add(errors.New("foo"))
+	if errs := be.GetErrors(); len(errs) != n/2 {
+		t.Errorf("Expected compiled error to have %d errors, got %d", n/2, len(errs))
+	}
+
+	batch.Clear()
+	if err := batch.Compile(); err != nil {
+		t.Errorf("Expected nil error after Clear, got %v", err)
+	}
+}
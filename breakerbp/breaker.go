@@ -0,0 +1,298 @@
+package breakerbp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// Default values used when the corresponding fields in Config are not set.
+const (
+	DefaultMinRequestsToTrip   = 10
+	DefaultFailureThreshold    = 0.5
+	DefaultInterval            = time.Second * 10
+	DefaultTimeout             = time.Second * 10
+	DefaultMaxRequestsHalfOpen = 1
+)
+
+// Errors returned by FailureRatioBreaker when it rejects a call.
+var (
+	// ErrOpen is returned when the breaker is open.
+	ErrOpen = errors.New("breakerbp: circuit breaker is open")
+
+	// ErrTooManyRequests is returned when the breaker is half-open and already
+	// let MaxRequestsHalfOpen calls through.
+	ErrTooManyRequests = errors.New("breakerbp: too many requests while circuit breaker is half-open")
+)
+
+// State is the state of a circuit breaker.
+//
+// The numeric values are also the values reported by the state gauge.
+type State int
+
+// Possible States.
+const (
+	StateClosed   State = 0
+	StateHalfOpen State = 1
+	StateOpen     State = 2
+)
+
+func (s State) String() string {
+	switch s {
+	default:
+		return "unknown"
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	}
+}
+
+// IsFailure decides whether an error returned by the call counts as a failure.
+type IsFailure func(err error) bool
+
+// DefaultIsFailure is the IsFailure used when IsFailure in Config is nil.
+//
+// It treats all non-nil errors as failures, except context.Canceled.
+func DefaultIsFailure(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled)
+}
+
+// Config is the configuration used by NewFailureRatioBreaker.
+//
+// Other than IsFailure, it can be deserialized from YAML.
+type Config struct {
+	// Name of the breaker, used in the metrics and logs.
+	Name string `yaml:"name"`
+
+	// MinRequestsToTrip is the minimal number of calls in the current Interval
+	// before the breaker can trip open.
+	MinRequestsToTrip int `yaml:"minRequestsToTrip"`
+
+	// FailureThreshold is the failure ratio, in range of (0, 1],
+	// that trips the breaker open.
+	FailureThreshold float64 `yaml:"failureThreshold"`
+
+	// Interval is the period the failure ratio is calculated over while the
+	// breaker is closed.
+	Interval time.Duration `yaml:"interval"`
+
+	// Timeout is how long the breaker stays open before becoming half-open.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxRequestsHalfOpen is the number of calls let through while the breaker
+	// is half-open.
+	// If all of them succeed, the breaker closes.
+	MaxRequestsHalfOpen int `yaml:"maxRequestsHalfOpen"`
+
+	// IsFailure decides whether an error counts as a failure.
+	//
+	// Optional, defaults to DefaultIsFailure.
+	IsFailure IsFailure `yaml:"-"`
+}
+
+// FailureRatioBreaker is a circuit breaker that trips open when the ratio of
+// failed calls goes over the threshold.
+//
+// Its state is reported as the "<name>.breaker-state" gauge to metricsbp.M,
+// see State for the values.
+//
+// Please use NewFailureRatioBreaker to create a FailureRatioBreaker.
+type FailureRatioBreaker struct {
+	name                string
+	minRequestsToTrip   int
+	failureThreshold    float64
+	interval            time.Duration
+	timeout             time.Duration
+	maxRequestsHalfOpen int
+	isFailure           IsFailure
+	stateGauge          metrics.Gauge
+
+	lock       sync.Mutex
+	state      State
+	generation uint64
+	expiry     time.Time
+	requests   int
+	failures   int
+	successes  int
+}
+
+// NewFailureRatioBreaker creates a new FailureRatioBreaker in closed state.
+func NewFailureRatioBreaker(cfg Config) *FailureRatioBreaker {
+	b := &FailureRatioBreaker{
+		name:                cfg.Name,
+		minRequestsToTrip:   cfg.MinRequestsToTrip,
+		failureThreshold:    cfg.FailureThreshold,
+		interval:            cfg.Interval,
+		timeout:             cfg.Timeout,
+		maxRequestsHalfOpen: cfg.MaxRequestsHalfOpen,
+		isFailure:           cfg.IsFailure,
+		stateGauge:          metricsbp.M.Gauge(cfg.Name + ".breaker-state"),
+	}
+	if b.minRequestsToTrip <= 0 {
+		b.minRequestsToTrip = DefaultMinRequestsToTrip
+	}
+	if b.failureThreshold <= 0 {
+		b.failureThreshold = DefaultFailureThreshold
+	}
+	if b.interval <= 0 {
+		b.interval = DefaultInterval
+	}
+	if b.timeout <= 0 {
+		b.timeout = DefaultTimeout
+	}
+	if b.maxRequestsHalfOpen <= 0 {
+		b.maxRequestsHalfOpen = DefaultMaxRequestsHalfOpen
+	}
+	if b.isFailure == nil {
+		b.isFailure = DefaultIsFailure
+	}
+	b.setState(StateClosed, time.Now())
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *FailureRatioBreaker) State() State {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.currentState(time.Now())
+}
+
+// Execute calls fn if the breaker allows it, and records its result.
+//
+// If the breaker rejects the call, fn won't be called and either ErrOpen or
+// ErrTooManyRequests will be returned.
+// Otherwise the error returned by fn is returned as-is.
+func (b *FailureRatioBreaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// Allow checks whether the breaker allows a call.
+//
+// If it does, it returns a done function that must be called with the result
+// of the call exactly once.
+// If it doesn't, it returns either ErrOpen or ErrTooManyRequests.
+//
+// Execute should be used instead when possible.
+// Allow is provided for integrations where the call and its result are not in
+// the same function, e.g. redis hooks.
+func (b *FailureRatioBreaker) Allow() (done func(err error), err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	switch b.currentState(now) {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.requests >= b.maxRequestsHalfOpen {
+			return nil, ErrTooManyRequests
+		}
+	}
+	b.requests++
+
+	generation := b.generation
+	return func(err error) {
+		b.record(generation, b.isFailure(err))
+	}, nil
+}
+
+func (b *FailureRatioBreaker) record(generation uint64, failed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	state := b.currentState(now)
+	if generation != b.generation {
+		// The state changed since the call was allowed, ignore its result.
+		return
+	}
+
+	switch state {
+	case StateClosed:
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.minRequestsToTrip &&
+			float64(b.failures)/float64(b.requests) >= b.failureThreshold {
+			b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if failed {
+			b.setState(StateOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.maxRequestsHalfOpen {
+			b.setState(StateClosed, now)
+		}
+	}
+}
+
+// currentState returns the current state,
+// and moves to the next state/interval when the current one expired.
+//
+// Caller must hold the lock.
+func (b *FailureRatioBreaker) currentState(now time.Time) State {
+	switch b.state {
+	case StateClosed:
+		if now.After(b.expiry) {
+			// Start a new interval.
+			b.newGeneration(now.Add(b.interval))
+		}
+	case StateOpen:
+		if now.After(b.expiry) {
+			b.setState(StateHalfOpen, now)
+		}
+	}
+	return b.state
+}
+
+// setState moves the breaker into the new state.
+//
+// Caller must hold the lock.
+func (b *FailureRatioBreaker) setState(state State, now time.Time) {
+	prev := b.state
+	b.state = state
+	switch state {
+	case StateClosed:
+		b.newGeneration(now.Add(b.interval))
+	case StateOpen:
+		b.newGeneration(now.Add(b.timeout))
+	default:
+		b.newGeneration(time.Time{})
+	}
+	b.stateGauge.Set(float64(state))
+	if prev != state {
+		log.Named("breakerbp").Infow(
+			"circuit breaker state changed",
+			"breaker", b.name,
+			"from", prev.String(),
+			"to", state.String(),
+		)
+	}
+}
+
+// Caller must hold the lock.
+func (b *FailureRatioBreaker) newGeneration(expiry time.Time) {
+	b.generation++
+	b.expiry = expiry
+	b.requests = 0
+	b.failures = 0
+	b.successes = 0
+}
@@ -0,0 +1,117 @@
+package breakerbp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+var errTest = errors.New("test error")
+
+func succeed() error {
+	return nil
+}
+
+func fail() error {
+	return errTest
+}
+
+func TestFailureRatioBreaker(t *testing.T) {
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	const timeout = time.Millisecond * 10
+	b := breakerbp.NewFailureRatioBreaker(breakerbp.Config{
+		Name:                "test",
+		MinRequestsToTrip:   4,
+		FailureThreshold:    0.5,
+		Interval:            time.Minute,
+		Timeout:             timeout,
+		MaxRequestsHalfOpen: 2,
+	})
+
+	checkState := func(t *testing.T, expected breakerbp.State) {
+		t.Helper()
+		if actual := b.State(); actual != expected {
+			t.Errorf("Expected state %v, got %v", expected, actual)
+		}
+	}
+
+	checkState(t, breakerbp.StateClosed)
+	for _, fn := range []func() error{succeed, succeed, fail} {
+		b.Execute(fn)
+	}
+	checkState(t, breakerbp.StateClosed)
+
+	// 2 failures out of 4 requests trips the breaker.
+	if err := b.Execute(fail); !errors.Is(err, errTest) {
+		t.Errorf("Expected error %v, got %v", errTest, err)
+	}
+	checkState(t, breakerbp.StateOpen)
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	if str := buf.String(); !strings.Contains(str, "test.breaker-state:2.000000|g") {
+		t.Errorf("Expected breaker state gauge in metrics, got %q", str)
+	}
+
+	called := false
+	err := b.Execute(func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, breakerbp.ErrOpen) {
+		t.Errorf("Expected error %v, got %v", breakerbp.ErrOpen, err)
+	}
+	if called {
+		t.Error("Expected fn not called while the breaker is open")
+	}
+
+	time.Sleep(timeout * 2)
+	checkState(t, breakerbp.StateHalfOpen)
+
+	// Half-open only let MaxRequestsHalfOpen calls through.
+	done1, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, breakerbp.ErrTooManyRequests) {
+		t.Errorf("Expected error %v, got %v", breakerbp.ErrTooManyRequests, err)
+	}
+	done1(nil)
+	checkState(t, breakerbp.StateHalfOpen)
+	done2(nil)
+	checkState(t, breakerbp.StateClosed)
+}
+
+func TestFailureRatioBreakerHalfOpenFailure(t *testing.T) {
+	const timeout = time.Millisecond * 10
+	b := breakerbp.NewFailureRatioBreaker(breakerbp.Config{
+		Name:              "test",
+		MinRequestsToTrip: 1,
+		Timeout:           timeout,
+	})
+
+	b.Execute(fail)
+	if state := b.State(); state != breakerbp.StateOpen {
+		t.Fatalf("Expected state %v, got %v", breakerbp.StateOpen, state)
+	}
+
+	time.Sleep(timeout * 2)
+	b.Execute(fail)
+	if state := b.State(); state != breakerbp.StateOpen {
+		t.Errorf("Expected state %v, got %v", breakerbp.StateOpen, state)
+	}
+}
@@ -0,0 +1,12 @@
+// Package breakerbp provides a failure-rate-based circuit breaker.
+//
+// When the failure rate of the calls to a downstream service goes over the
+// configured threshold, the breaker opens and fails the calls immediately
+// with ErrOpen instead of piling more load onto the service that's already
+// having a brownout.
+// After a timeout, the breaker lets a few calls through to probe the service,
+// and closes again if they succeed.
+//
+// thriftbp.CircuitBreaker and redisbp.BreakerHook integrate it with thrift
+// clients and redis clients respectively.
+package breakerbp
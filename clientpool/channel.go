@@ -1,15 +1,114 @@
 package clientpool
 
 import (
+	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/reddit/baseplate.go/metricsbp"
 )
 
+// DefaultMinIdleInterval is the default value of
+// ChannelPoolConfig.MinIdleInterval.
+const DefaultMinIdleInterval = time.Second
+
+// ChannelPoolConfig is the configuration used by NewChannelPoolWithConfig.
+//
+// Can be deserialized from YAML.
+type ChannelPoolConfig struct {
+	// InitialClients is the number of clients opened when the pool is created.
+	InitialClients int `yaml:"initialClients"`
+
+	// MaxClients is the max number of clients the pool can give out at the
+	// same time.
+	MaxClients int `yaml:"maxClients"`
+
+	// MinIdle is the min number of idle clients the pool keeps.
+	//
+	// When MinIdle > 0, a background goroutine checks the pool every
+	// MinIdleInterval, and opens new clients when there are fewer than MinIdle
+	// idle ones, as long as the idle and the active clients don't exceed
+	// MaxClients.
+	// Failures to open the clients are retried on the next check.
+	// The goroutine is stopped by Close.
+	//
+	// MinIdle must not be greater than MaxClients.
+	MinIdle int `yaml:"minIdle"`
+
+	// MinIdleInterval is the interval the pool is checked for MinIdle.
+	//
+	// When MinIdleInterval <= 0, DefaultMinIdleInterval will be used instead.
+	MinIdleInterval time.Duration `yaml:"minIdleInterval"`
+
+	// AcquireTimeout is the max amount of time Get waits for a client to be
+	// released back to the pool when the pool is exhausted.
+	//
+	// When AcquireTimeout <= 0, Get returns ErrExhausted immediately when the
+	// pool is exhausted.
+	AcquireTimeout time.Duration `yaml:"acquireTimeout"`
+
+	// IdleTimeout is the max amount of time a client is allowed to sit in the
+	// pool unused.
+	//
+	// Clients that have been idle for longer than IdleTimeout are closed and
+	// replaced with new ones on checkout instead of being handed out by Get.
+	//
+	// When IdleTimeout <= 0, clients never expire for being idle.
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+
+	// DisableHealthCheck skips the IsOpen check on clients when they are
+	// checked out of and released back to the pool.
+	DisableHealthCheck bool `yaml:"disableHealthCheck"`
+
+	// Name is used as the prefix of the metrics reported by the pool.
+	//
+	// When Name is non-empty, the pool reports:
+	// - the number of clients given out to a gauge named
+	//   "${Name}.pool-active-clients".
+	// - the number of clients idle in the pool to a gauge named
+	//   "${Name}.pool-idle-clients".
+	// - the number of Get calls failed with ErrExhausted to a counter named
+	//   "${Name}.pool-exhausted".
+	//
+	// When Name is empty, no metrics are reported.
+	Name string `yaml:"name"`
+
+	// Any labels that should be applied to metrics reported by the pool.
+	MetricsLabels metricsbp.Labels `yaml:"metricsLabels"`
+}
+
+// pooledClient is the client stored in the channel pool,
+// with the time it was released back to the pool.
+type pooledClient struct {
+	Client
+
+	idleSince time.Time
+}
+
 type channelPool struct {
-	pool           chan Client
+	pool           chan pooledClient
 	opener         ClientOpener
 	numActive      int32
 	initialClients int
 	maxClients     int
+
+	acquireTimeout time.Duration
+	idleTimeout    time.Duration
+	healthCheck    bool
+	minIdle        int
+
+	// Used to stop and wait for the min idle goroutine in Close.
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	// All metrics are nil when the pool is not named.
+	activeGauge      metrics.Gauge
+	idleGauge        metrics.Gauge
+	exhaustedCounter metrics.Counter
 }
 
 // Make sure channelPool implements Pool interface.
@@ -17,53 +116,191 @@ var _ Pool = (*channelPool)(nil)
 
 // NewChannelPool creates a new client pool implemented via channel.
 func NewChannelPool(initialClients, maxClients int, opener ClientOpener) (Pool, error) {
-	if initialClients > maxClients {
-		return nil, &ConfigError{
+	return NewChannelPoolWithConfig(
+		ChannelPoolConfig{
 			InitialClients: initialClients,
 			MaxClients:     maxClients,
+		},
+		opener,
+	)
+}
+
+// NewChannelPoolWithConfig creates a new client pool implemented via channel,
+// with the additional features configured by cfg.
+func NewChannelPoolWithConfig(cfg ChannelPoolConfig, opener ClientOpener) (Pool, error) {
+	if cfg.InitialClients > cfg.MaxClients {
+		return nil, &ConfigError{
+			InitialClients: cfg.InitialClients,
+			MaxClients:     cfg.MaxClients,
 		}
 	}
+	if cfg.MinIdle > cfg.MaxClients {
+		return nil, fmt.Errorf(
+			"clientpool: minIdle (%d) > maxClients (%d)",
+			cfg.MinIdle,
+			cfg.MaxClients,
+		)
+	}
+
+	cp := &channelPool{
+		pool:           make(chan pooledClient, cfg.MaxClients),
+		opener:         opener,
+		initialClients: cfg.InitialClients,
+		maxClients:     cfg.MaxClients,
+		acquireTimeout: cfg.AcquireTimeout,
+		idleTimeout:    cfg.IdleTimeout,
+		healthCheck:    !cfg.DisableHealthCheck,
+		minIdle:        cfg.MinIdle,
+		done:           make(chan struct{}),
+	}
+	if cfg.Name != "" {
+		labels := cfg.MetricsLabels.AsStatsdLabels()
+		cp.activeGauge = metricsbp.M.Gauge(cfg.Name + ".pool-active-clients").With(labels...)
+		cp.idleGauge = metricsbp.M.Gauge(cfg.Name + ".pool-idle-clients").With(labels...)
+		cp.exhaustedCounter = metricsbp.M.Counter(cfg.Name + ".pool-exhausted").With(labels...)
+	}
 
-	pool := make(chan Client, maxClients)
-	for i := 0; i < initialClients; i++ {
+	for i := 0; i < cfg.InitialClients; i++ {
 		c, err := opener()
 		if err != nil {
 			return nil, err
 		}
-		pool <- c
+		cp.pool <- cp.wrap(c)
 	}
+	cp.reportGauges()
 
-	return &channelPool{
-		pool:           pool,
-		opener:         opener,
-		initialClients: initialClients,
-		maxClients:     maxClients,
-	}, nil
+	if cp.minIdle > 0 {
+		interval := cfg.MinIdleInterval
+		if interval <= 0 {
+			interval = DefaultMinIdleInterval
+		}
+		cp.wg.Add(1)
+		go cp.minIdleLoop(interval)
+	}
+
+	return cp, nil
+}
+
+// minIdleLoop refills the pool to minIdle clients every interval,
+// until the pool is closed.
+func (cp *channelPool) minIdleLoop(interval time.Duration) {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cp.refill()
+		select {
+		case <-cp.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refill opens new clients until there are minIdle idle clients in the pool,
+// or the pool is full.
+func (cp *channelPool) refill() {
+	defer cp.reportGauges()
+
+	for cp.NumAllocated() < int32(cp.minIdle) &&
+		cp.NumAllocated()+cp.NumActiveClients() < int32(cp.maxClients) {
+		select {
+		case <-cp.done:
+			return
+		default:
+		}
+
+		c, err := cp.opener()
+		if err != nil {
+			return
+		}
+		select {
+		case cp.pool <- cp.wrap(c):
+		default:
+			c.Close()
+			return
+		}
+	}
 }
 
 // Get returns a client from the pool.
+//
+// When the pool is exhausted, Get waits up to the configured AcquireTimeout
+// for a client to be released before returning ErrExhausted.
 func (cp *channelPool) Get() (client Client, err error) {
 	defer func() {
 		if err == nil {
 			atomic.AddInt32(&cp.numActive, 1)
 		}
+		cp.reportGauges()
 	}()
 
-	select {
-	case c := <-cp.pool:
-		if c.IsOpen() {
-			return c, nil
+	for {
+		select {
+		case pc, ok := <-cp.pool:
+			if !ok {
+				return nil, ErrClosed
+			}
+			if c := cp.checkout(pc); c != nil {
+				return c, nil
+			}
+			// The pooled client was stale, try the next one.
+			continue
+		default:
 		}
-	default:
+		break
 	}
 
 	if cp.IsExhausted() {
-		err = ErrExhausted
-		return
+		if cp.acquireTimeout > 0 {
+			return cp.wait()
+		}
+		cp.markExhausted()
+		return nil, ErrExhausted
 	}
 	return cp.opener()
 }
 
+// wait blocks until a client is released back to the pool, or acquireTimeout
+// passed.
+func (cp *channelPool) wait() (Client, error) {
+	timer := time.NewTimer(cp.acquireTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case pc, ok := <-cp.pool:
+			if !ok {
+				return nil, ErrClosed
+			}
+			if c := cp.checkout(pc); c != nil {
+				return c, nil
+			}
+			if !cp.IsExhausted() {
+				return cp.opener()
+			}
+		case <-timer.C:
+			cp.markExhausted()
+			return nil, ErrExhausted
+		}
+	}
+}
+
+// checkout returns the client if it's still healthy,
+// or closes it and returns nil otherwise.
+func (cp *channelPool) checkout(pc pooledClient) Client {
+	if cp.idleTimeout > 0 && time.Since(pc.idleSince) > cp.idleTimeout {
+		pc.Close()
+		return nil
+	}
+	if cp.healthCheck && !pc.IsOpen() {
+		pc.Close()
+		return nil
+	}
+	return pc.Client
+}
+
 // Release releases a client back to the pool.
 //
 // If the pool is full, the client will be closed instead.
@@ -76,9 +313,12 @@ func (cp *channelPool) Release(c Client) error {
 
 	// As long as c is not nil, we always need to decrease numActive by 1,
 	// even if we encounter errors here, either due to close or opener.
-	defer atomic.AddInt32(&cp.numActive, -1)
+	defer func() {
+		atomic.AddInt32(&cp.numActive, -1)
+		cp.reportGauges()
+	}()
 
-	if !c.IsOpen() {
+	if cp.healthCheck && !c.IsOpen() {
 		newC, err := cp.opener()
 		if err != nil {
 			return err
@@ -87,7 +327,7 @@ func (cp *channelPool) Release(c Client) error {
 	}
 
 	select {
-	case cp.pool <- c:
+	case cp.pool <- cp.wrap(c):
 		return nil
 	default:
 		// Pool is full, just close it instead.
@@ -97,6 +337,13 @@ func (cp *channelPool) Release(c Client) error {
 
 // Close closes the pool, and all allocated clients.
 func (cp *channelPool) Close() error {
+	// Stop the min idle goroutine first, so it doesn't send to the closed
+	// channel.
+	cp.closeOnce.Do(func() {
+		close(cp.done)
+	})
+	cp.wg.Wait()
+
 	var lastErr error
 	close(cp.pool)
 	for c := range cp.pool {
@@ -121,3 +368,24 @@ func (cp *channelPool) NumAllocated() int32 {
 func (cp *channelPool) IsExhausted() bool {
 	return cp.NumActiveClients() >= int32(cp.maxClients)
 }
+
+func (cp *channelPool) wrap(c Client) pooledClient {
+	pc := pooledClient{Client: c}
+	if cp.idleTimeout > 0 {
+		pc.idleSince = time.Now()
+	}
+	return pc
+}
+
+func (cp *channelPool) markExhausted() {
+	if cp.exhaustedCounter != nil {
+		cp.exhaustedCounter.Add(1)
+	}
+}
+
+func (cp *channelPool) reportGauges() {
+	if cp.activeGauge != nil {
+		cp.activeGauge.Set(float64(cp.NumActiveClients()))
+		cp.idleGauge.Set(float64(cp.NumAllocated()))
+	}
+}
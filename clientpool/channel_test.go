@@ -4,8 +4,10 @@ import (
 	"errors"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/reddit/baseplate.go/clientpool"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
 )
 
 func TestChannelPoolInvalidConfig(t *testing.T) {
@@ -98,3 +100,241 @@ func TestChannelPoolWithOpenerFailure(t *testing.T) {
 		},
 	)
 }
+
+func TestChannelPoolAcquireTimeout(t *testing.T) {
+	opener := func() (clientpool.Client, error) {
+		return &testClient{}, nil
+	}
+	const timeout = 50 * time.Millisecond
+
+	pool, err := clientpool.NewChannelPoolWithConfig(
+		clientpool.ChannelPoolConfig{
+			MaxClients:     1,
+			AcquireTimeout: timeout,
+		},
+		opener,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run(
+		"timeout",
+		func(t *testing.T) {
+			start := time.Now()
+			_, err := pool.Get()
+			if !errors.Is(err, clientpool.ErrExhausted) {
+				t.Errorf("Expected ErrExhausted, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < timeout {
+				t.Errorf("Expected Get to wait at least %v, returned after %v", timeout, elapsed)
+			}
+		},
+	)
+
+	t.Run(
+		"released",
+		func(t *testing.T) {
+			go func() {
+				time.Sleep(timeout / 5)
+				pool.Release(c)
+			}()
+			got, err := pool.Get()
+			if err != nil {
+				t.Fatalf("Expected Get to succeed after release, got %v", err)
+			}
+			if got != c {
+				t.Errorf("Expected the released client %p, got %p", c, got)
+			}
+		},
+	)
+}
+
+func TestChannelPoolIdleTimeout(t *testing.T) {
+	var called int32
+	opener := func() (clientpool.Client, error) {
+		atomic.AddInt32(&called, 1)
+		return &testClient{}, nil
+	}
+	const timeout = 10 * time.Millisecond
+
+	pool, err := clientpool.NewChannelPoolWithConfig(
+		clientpool.ChannelPoolConfig{
+			InitialClients: 1,
+			MaxClients:     1,
+			IdleTimeout:    timeout,
+		},
+		opener,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	time.Sleep(timeout * 2)
+	c, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&called); n != 2 {
+		t.Errorf("Expected the idle client to be replaced with opener called 2 times, got %d", n)
+	}
+
+	if err := pool.Release(c); err != nil {
+		t.Fatal(err)
+	}
+	got, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != c {
+		t.Errorf("Expected the non-idle client %p to be reused, got %p", c, got)
+	}
+}
+
+func TestChannelPoolMinIdle(t *testing.T) {
+	var called int32
+	opener := func() (clientpool.Client, error) {
+		atomic.AddInt32(&called, 1)
+		return &testClient{}, nil
+	}
+	const interval = 5 * time.Millisecond
+
+	pool, err := clientpool.NewChannelPoolWithConfig(
+		clientpool.ChannelPoolConfig{
+			MaxClients:      3,
+			MinIdle:         2,
+			MinIdleInterval: interval,
+		},
+		opener,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	waitForIdle := func(expected int32) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for pool.NumAllocated() != expected && time.Now().Before(deadline) {
+			time.Sleep(interval)
+		}
+		if n := pool.NumAllocated(); n != expected {
+			t.Fatalf("Expected %d idle clients, got %d", expected, n)
+		}
+	}
+	waitForIdle(2)
+
+	// Taking the idle clients out gets them refilled,
+	// up to MaxClients in total.
+	for i := 0; i < 2; i++ {
+		c, err := pool.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Release(c)
+	}
+	waitForIdle(1)
+	// Give the goroutine a few more checks to make sure it stops at
+	// MaxClients.
+	time.Sleep(interval * 5)
+	if n := atomic.LoadInt32(&called); n != 3 {
+		t.Errorf("Expected opener to be called 3 times, got %d", n)
+	}
+}
+
+func TestChannelPoolMinIdleInvalidConfig(t *testing.T) {
+	_, err := clientpool.NewChannelPoolWithConfig(
+		clientpool.ChannelPoolConfig{
+			MaxClients: 1,
+			MinIdle:    2,
+		},
+		nil,
+	)
+	if err == nil {
+		t.Error("Expected error for MinIdle > MaxClients, got nil")
+	}
+}
+
+func TestChannelPoolDisableHealthCheck(t *testing.T) {
+	opener := func() (clientpool.Client, error) {
+		return &testClient{}, nil
+	}
+	pool, err := clientpool.NewChannelPoolWithConfig(
+		clientpool.ChannelPoolConfig{
+			MaxClients:         1,
+			DisableHealthCheck: true,
+		},
+		opener,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if err := pool.Release(c); err != nil {
+		t.Fatal(err)
+	}
+	got, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != c {
+		t.Errorf("Expected the closed client %p to be handed out without health check, got %p", c, got)
+	}
+}
+
+func TestChannelPoolMetrics(t *testing.T) {
+	recorder := metricstest.Replace(t)
+	opener := func() (clientpool.Client, error) {
+		return &testClient{}, nil
+	}
+	pool, err := clientpool.NewChannelPoolWithConfig(
+		clientpool.ChannelPoolConfig{
+			InitialClients: 1,
+			MaxClients:     1,
+			Name:           "test",
+		},
+		opener,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	recorder.AssertGaugeEquals(t, "test.pool-active-clients", 0)
+	recorder.AssertGaugeEquals(t, "test.pool-idle-clients", 1)
+
+	if _, err := pool.Get(); err != nil {
+		t.Fatal(err)
+	}
+	recorder.AssertGaugeEquals(t, "test.pool-active-clients", 1)
+	recorder.AssertGaugeEquals(t, "test.pool-idle-clients", 0)
+
+	if _, err := pool.Get(); !errors.Is(err, clientpool.ErrExhausted) {
+		t.Errorf("Expected ErrExhausted, got %v", err)
+	}
+	recorder.AssertCounterEquals(t, "test.pool-exhausted", 1)
+}
+
+func TestChannelPoolGetAfterClose(t *testing.T) {
+	pool, err := clientpool.NewChannelPool(0, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+	if _, err := pool.Get(); !errors.Is(err, clientpool.ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
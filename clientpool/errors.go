@@ -8,6 +8,9 @@ import (
 // ErrExhausted is the error returned by Get when the pool is exhausted.
 var ErrExhausted = errors.New("clientpool: exhausted")
 
+// ErrClosed is the error returned by Get when the pool is already closed.
+var ErrClosed = errors.New("clientpool: closed")
+
 // ConfigError is the error type returned when trying to open a new
 // client pool, but the configuration values passed in won't work.
 type ConfigError struct {
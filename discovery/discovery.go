@@ -0,0 +1,254 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// Default values of Config.
+const (
+	DefaultFailureThreshold = 3
+	DefaultEjectDuration    = 10 * time.Second
+)
+
+// ErrNoBackends is returned by Watcher.Pick when there are no backends
+// available to be picked.
+var ErrNoBackends = errors.New("discovery: no backends available")
+
+// Backend is a backend of a service in the endpoint file.
+type Backend struct {
+	Host string
+	Port int
+
+	// The name of the backend, e.g. the instance id. Optional.
+	Name string
+
+	// The relative weight of the backend, default to 1 when missing from the
+	// endpoint file.
+	//
+	// Backends with weight 0 are never picked.
+	Weight int
+}
+
+// Addr returns the address of the backend in "host:port" format.
+func (b Backend) Addr() string {
+	return net.JoinHostPort(b.Host, strconv.Itoa(b.Port))
+}
+
+// backendJSON is the JSON representation of Backend in the endpoint file.
+type backendJSON struct {
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	Name   string `json:"name"`
+	Weight *int   `json:"weight"`
+}
+
+// Config is the arg struct for New.
+type Config struct {
+	// The path to the endpoint file, required.
+	Path string
+
+	// Optional. When non-nil, it will be used to log the errors reading the
+	// endpoint file.
+	Logger log.Wrapper
+
+	// Optional, see filewatcher.Config.MaxFileSize.
+	MaxFileSize int64
+
+	// Optional, see filewatcher.Config.PollingInterval.
+	PollingInterval time.Duration
+
+	// Optional, the number of consecutive failures reported for a backend to
+	// eject it, see HealthTracker for more details.
+	//
+	// Default to DefaultFailureThreshold.
+	FailureThreshold int
+
+	// Optional, the duration an ejected backend is not picked.
+	//
+	// Default to DefaultEjectDuration.
+	EjectDuration time.Duration
+}
+
+// backendSet is the parsed endpoint file.
+type backendSet struct {
+	backends []Backend
+	addrs    map[string]bool
+}
+
+// Watcher watches an endpoint file and picks backends from it.
+type Watcher struct {
+	health *HealthTracker
+	result *filewatcher.Result
+}
+
+// New creates a new Watcher.
+//
+// Like filewatcher.New, if the path is not available at the time of calling,
+// it blocks until the file becomes available, or context is cancelled,
+// whichever comes first.
+func New(ctx context.Context, cfg Config) (*Watcher, error) {
+	w := &Watcher{
+		health: NewHealthTracker(cfg.FailureThreshold, cfg.EjectDuration),
+	}
+	result, err := filewatcher.New(
+		ctx,
+		filewatcher.Config{
+			Path:            cfg.Path,
+			Parser:          w.parse,
+			Logger:          cfg.Logger,
+			MaxFileSize:     cfg.MaxFileSize,
+			PollingInterval: cfg.PollingInterval,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	w.result = result
+	return w, nil
+}
+
+// parse is the filewatcher.Parser of the endpoint file.
+//
+// It also drops the health accounting of the removed backends.
+func (w *Watcher) parse(r io.Reader) (interface{}, error) {
+	var raw []backendJSON
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("discovery: failed to decode endpoint file: %w", err)
+	}
+	set := &backendSet{
+		backends: make([]Backend, 0, len(raw)),
+		addrs:    make(map[string]bool, len(raw)),
+	}
+	for _, b := range raw {
+		backend := Backend{
+			Host:   b.Host,
+			Port:   b.Port,
+			Name:   b.Name,
+			Weight: 1,
+		}
+		if b.Weight != nil {
+			backend.Weight = *b.Weight
+		}
+		if backend.Host == "" || backend.Port <= 0 || backend.Weight < 0 {
+			return nil, fmt.Errorf("discovery: invalid backend %+v", backend)
+		}
+		set.backends = append(set.backends, backend)
+		set.addrs[backend.Addr()] = true
+	}
+
+	w.health.retain(set.addrs)
+	return set, nil
+}
+
+func (w *Watcher) get() *backendSet {
+	return w.result.Get().(*backendSet)
+}
+
+// Backends returns the current backends from the endpoint file.
+//
+// The returned slice is shared and must not be modified.
+func (w *Watcher) Backends() []Backend {
+	return w.get().backends
+}
+
+// Contains returns whether the backend with the address is still in the
+// endpoint file.
+//
+// Clients use it to close their connections to the removed backends.
+func (w *Watcher) Contains(addr string) bool {
+	return w.get().addrs[addr]
+}
+
+// Pick picks a backend randomly by their weights.
+//
+// Ejected backends are skipped, unless all the backends are ejected,
+// in which case it falls back to all the backends,
+// as it's more likely a problem on the client side.
+func (w *Watcher) Pick() (Backend, error) {
+	backends := w.get().backends
+	healthy := make([]Backend, 0, len(backends))
+	for _, b := range backends {
+		if !w.health.Ejected(b.Addr()) {
+			healthy = append(healthy, b)
+		}
+	}
+
+	if b, ok := pickWeighted(healthy); ok {
+		return b, nil
+	}
+	if b, ok := pickWeighted(backends); ok {
+		return b, nil
+	}
+	return Backend{}, ErrNoBackends
+}
+
+func pickWeighted(backends []Backend) (Backend, bool) {
+	var total int64
+	for _, b := range backends {
+		total += int64(b.Weight)
+	}
+	if total <= 0 {
+		return Backend{}, false
+	}
+	n := randbp.R.Int63n(total)
+	for _, b := range backends {
+		n -= int64(b.Weight)
+		if n < 0 {
+			return b, true
+		}
+	}
+	// Unreachable.
+	return Backend{}, false
+}
+
+// AddressGenerator returns a function returning the address of a picked
+// backend, which can be used as thriftbp.AddressGenerator.
+func (w *Watcher) AddressGenerator() func() (string, error) {
+	return func() (string, error) {
+		b, err := w.Pick()
+		if err != nil {
+			return "", err
+		}
+		return b.Addr(), nil
+	}
+}
+
+// ReportSuccess reports a successful request to the backend with the address,
+// see HealthTracker.ReportSuccess.
+func (w *Watcher) ReportSuccess(addr string) {
+	w.health.ReportSuccess(addr)
+}
+
+// ReportFailure reports a failed request to the backend with the address,
+// see HealthTracker.ReportFailure.
+//
+// The failures of the backends no longer in the endpoint file are ignored.
+func (w *Watcher) ReportFailure(addr string) {
+	if !w.Contains(addr) {
+		return
+	}
+	w.health.ReportFailure(addr)
+}
+
+// Ejected returns whether the backend with the address is currently ejected.
+func (w *Watcher) Ejected(addr string) bool {
+	return w.health.Ejected(addr)
+}
+
+// Stop stops watching the endpoint file.
+//
+// After Stop is called, the last backends are still used.
+func (w *Watcher) Stop() {
+	w.result.Stop()
+}
@@ -0,0 +1,177 @@
+package discovery_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/discovery"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// writeFile atomically replaces the file at path with content.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newWatcher(t *testing.T, content string, cfg discovery.Config) (*discovery.Watcher, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "discovery_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	cfg.Path = filepath.Join(dir, "endpoints.json")
+	writeFile(t, cfg.Path, content)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	w, err := discovery.New(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+	return w, cfg.Path
+}
+
+func TestWatcher(t *testing.T) {
+	w, path := newWatcher(
+		t,
+		`[
+			{"host": "10.0.0.1", "port": 9090, "name": "a"},
+			{"host": "10.0.0.2", "port": 9090, "name": "b", "weight": 0}
+		]`,
+		discovery.Config{Logger: log.TestWrapper(t)},
+	)
+
+	expected := []discovery.Backend{
+		{Host: "10.0.0.1", Port: 9090, Name: "a", Weight: 1},
+		{Host: "10.0.0.2", Port: 9090, Name: "b", Weight: 0},
+	}
+	if actual := w.Backends(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected backends %+v, got %+v", expected, actual)
+	}
+	for i := 0; i < 10; i++ {
+		b, err := w.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Name != "a" {
+			t.Errorf("Expected backend with weight 0 to be never picked, got %+v", b)
+		}
+	}
+
+	writeFile(t, path, `[{"host": "10.0.0.3", "port": 9090}]`)
+	deadline := time.Now().Add(time.Second)
+	for w.Contains("10.0.0.1:9090") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if w.Contains("10.0.0.1:9090") {
+		t.Error("Expected removed backend to be gone")
+	}
+	addr, err := w.AddressGenerator()()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "10.0.0.3:9090" {
+		t.Errorf("Expected address %q, got %q", "10.0.0.3:9090", addr)
+	}
+}
+
+func TestWatcherHealth(t *testing.T) {
+	const (
+		bad  = "10.0.0.1:9090"
+		good = "10.0.0.2:9090"
+	)
+	w, _ := newWatcher(
+		t,
+		`[{"host": "10.0.0.1", "port": 9090}, {"host": "10.0.0.2", "port": 9090}]`,
+		discovery.Config{
+			FailureThreshold: 2,
+			EjectDuration:    time.Millisecond * 50,
+		},
+	)
+
+	w.ReportFailure(bad)
+	w.ReportSuccess(bad)
+	w.ReportFailure(bad)
+	if w.Ejected(bad) {
+		t.Fatal("Expected success to reset consecutive failures")
+	}
+	w.ReportFailure(bad)
+	if !w.Ejected(bad) {
+		t.Fatal("Expected backend to be ejected after consecutive failures")
+	}
+	for i := 0; i < 20; i++ {
+		b, err := w.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Addr() != good {
+			t.Fatalf("Expected ejected backend to be skipped, got %+v", b)
+		}
+	}
+
+	// When all the backends are ejected, they are still picked.
+	w.ReportFailure(good)
+	w.ReportFailure(good)
+	if _, err := w.Pick(); err != nil {
+		t.Errorf("Expected pick to fall back to ejected backends, got %v", err)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+	if w.Ejected(bad) {
+		t.Error("Expected backend to be back after EjectDuration")
+	}
+
+	// The backend is probed after the ejection, a single failure ejects it
+	// again and a success brings it back.
+	w.ReportFailure(bad)
+	if !w.Ejected(bad) {
+		t.Error("Expected probed backend to be ejected again after a failure")
+	}
+	w.ReportSuccess(bad)
+	if w.Ejected(bad) {
+		t.Error("Expected success to bring the backend back")
+	}
+}
+
+func TestWatcherInvalid(t *testing.T) {
+	w, _ := newWatcher(t, `[]`, discovery.Config{})
+	if _, err := w.Pick(); !errors.Is(err, discovery.ErrNoBackends) {
+		t.Errorf("Expected %v, got %v", discovery.ErrNoBackends, err)
+	}
+
+	dir, err := ioutil.TempDir("", "discovery_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, content := range []string{
+		`{"host": "10.0.0.1"}`,
+		`[{"port": 9090}]`,
+		`[{"host": "10.0.0.1", "port": 9090, "weight": -1}]`,
+	} {
+		path := filepath.Join(dir, "endpoints.json")
+		writeFile(t, path, content)
+		if _, err := discovery.New(context.Background(), discovery.Config{Path: path}); err == nil {
+			t.Errorf("Expected error for invalid endpoint file %s", content)
+		}
+	}
+}
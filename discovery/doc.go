@@ -0,0 +1,22 @@
+// Package discovery provides service discovery backed by the endpoint files
+// maintained by a local discovery agent, e.g. Synapse.
+//
+// The endpoint file is a JSON array of the backends of a service:
+//
+//     [
+//       {"host": "10.0.0.1", "port": 9090, "name": "i-abc", "weight": 1},
+//       {"host": "10.0.0.2", "port": 9090, "name": "i-def", "weight": 2}
+//     ]
+//
+// The file is watched by filewatcher, so the backends are updated as the
+// agent rewrites it.
+//
+// A Watcher picks the backends randomly by their weights,
+// and keeps per-backend health accounting from the results reported by the
+// clients with a HealthTracker: a backend failing FailureThreshold times in a
+// row is ejected for EjectDuration before being probed again.
+//
+// Watcher is usually not used directly,
+// but passed into thriftbp.ClientPoolConfig.Discovery or
+// httpbp.ServiceDiscovery.
+package discovery
@@ -0,0 +1,96 @@
+package discovery
+
+import (
+	"sync"
+	"time"
+)
+
+type health struct {
+	failures     int
+	ejectedUntil time.Time
+}
+
+// HealthTracker keeps per-backend health accounting from the results reported
+// by the clients, keyed by the addresses of the backends.
+//
+// A backend failing failureThreshold times in a row is ejected for
+// ejectDuration.
+// Once the ejection is over, the backend is probed by the next request:
+// a success brings it back, a failure ejects it again right away.
+//
+// Watcher uses a HealthTracker for the backends in the endpoint file,
+// it can also be used directly for a static list of backends.
+type HealthTracker struct {
+	threshold     int
+	ejectDuration time.Duration
+
+	lock  sync.Mutex
+	hosts map[string]*health
+}
+
+// NewHealthTracker creates a new HealthTracker.
+//
+// When failureThreshold <= 0, DefaultFailureThreshold will be used instead.
+// When ejectDuration <= 0, DefaultEjectDuration will be used instead.
+func NewHealthTracker(failureThreshold int, ejectDuration time.Duration) *HealthTracker {
+	if failureThreshold <= 0 {
+		failureThreshold = DefaultFailureThreshold
+	}
+	if ejectDuration <= 0 {
+		ejectDuration = DefaultEjectDuration
+	}
+	return &HealthTracker{
+		threshold:     failureThreshold,
+		ejectDuration: ejectDuration,
+		hosts:         make(map[string]*health),
+	}
+}
+
+// ReportSuccess reports a successful request to the backend with the address,
+// resetting its consecutive failures and ending its ejection.
+func (t *HealthTracker) ReportSuccess(addr string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if h := t.hosts[addr]; h != nil {
+		h.failures = 0
+		h.ejectedUntil = time.Time{}
+	}
+}
+
+// ReportFailure reports a failed request to the backend with the address.
+//
+// Only failures caused by the backend, e.g. connection errors, should be
+// reported.
+func (t *HealthTracker) ReportFailure(addr string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	h := t.hosts[addr]
+	if h == nil {
+		h = new(health)
+		t.hosts[addr] = h
+	}
+	h.failures++
+	if h.failures >= t.threshold || !h.ejectedUntil.IsZero() {
+		h.failures = 0
+		h.ejectedUntil = time.Now().Add(t.ejectDuration)
+	}
+}
+
+// Ejected returns whether the backend with the address is currently ejected.
+func (t *HealthTracker) Ejected(addr string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	h := t.hosts[addr]
+	return h != nil && time.Now().Before(h.ejectedUntil)
+}
+
+// retain drops the health accounting of the backends not in addrs.
+func (t *HealthTracker) retain(addrs map[string]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for addr := range t.hosts {
+		if !addrs[addr] {
+			delete(t.hosts, addr)
+		}
+	}
+}
@@ -2,12 +2,16 @@ package edgecontext
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	sentry "github.com/getsentry/sentry-go"
+	opentracing "github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 	"github.com/reddit/baseplate.go/log"
@@ -18,6 +22,20 @@ import (
 // LoIDPrefix is the prefix for all LoIDs.
 const LoIDPrefix = "t2_"
 
+// Sentry tags set by SetEdgeContext.
+const (
+	SentryTagKeyDeviceID      = "device_id"
+	SentryTagKeyOriginService = "origin_service"
+)
+
+// Span tags set by SetEdgeContext when Config.SpanTags is true.
+const (
+	// The hex encoded sha256 hash of the user id.
+	SpanTagKeyUserIDHash    = "edgecontext.user_id_hash"
+	SpanTagKeyOAuthClientID = "edgecontext.oauth_client_id"
+	SpanTagKeyOriginService = "edgecontext.origin_service"
+)
+
 // ErrLoIDWrongPrefix is an error could be returned by New() when passed in LoID
 // does not have the correct prefix.
 var ErrLoIDWrongPrefix = errors.New("edgecontext: loid should have " + LoIDPrefix + " prefix")
@@ -28,6 +46,7 @@ var ErrLoIDWrongPrefix = errors.New("edgecontext: loid should have " + LoIDPrefi
 type Impl struct {
 	store     *secrets.Store
 	logger    log.Wrapper
+	spanTags  bool
 	keysValue atomic.Value
 }
 
@@ -62,13 +81,65 @@ const (
 )
 
 // SetEdgeContext sets the given EdgeRequestContext on the context object.
+//
+// If the user in the EdgeRequestContext is logged in,
+// the user id is also attached to the context object as a log field,
+// see log.Attach.
+//
+// If there's a sentry hub attached to the context object
+// (e.g. the one attached to the server span),
+// the user id, device id and origin service are also set on its scope,
+// so that they are attached to the errors reported to sentry.
+//
+// If the EdgeRequestContext was created by an Impl with Config.SpanTags set to
+// true and there's a span attached to the context object
+// (e.g. the server span),
+// the hashed user id, OAuth client id and origin service are also set as tags
+// on the span, see SpanTagKey* constants.
 func SetEdgeContext(ctx context.Context, ec *EdgeRequestContext) context.Context {
 	if ec == nil {
 		return ctx
 	}
+	userID, loggedIn := ec.User().ID()
+	if loggedIn {
+		ctx = log.Attach(ctx, "userID", userID)
+	}
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			if loggedIn {
+				scope.SetUser(sentry.User{ID: userID})
+			}
+			if id := ec.DeviceID(); id != "" {
+				scope.SetTag(SentryTagKeyDeviceID, id)
+			}
+			if name := ec.OriginService().Name(); name != "" {
+				scope.SetTag(SentryTagKeyOriginService, name)
+			}
+		})
+	}
+	if ec.impl != nil && ec.impl.spanTags {
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			setSpanTags(span, ec)
+		}
+	}
 	return context.WithValue(ctx, edgeContextKey, ec)
 }
 
+func setSpanTags(span opentracing.Span, ec *EdgeRequestContext) {
+	if userID, ok := ec.User().ID(); ok {
+		hash := sha256.Sum256([]byte(userID))
+		span.SetTag(SpanTagKeyUserIDHash, hex.EncodeToString(hash[:]))
+	}
+	if client, ok := ec.OAuthClient(); ok {
+		if id := client.ID(); id != "" {
+			span.SetTag(SpanTagKeyOAuthClientID, id)
+		}
+	}
+	if name := ec.OriginService().Name(); name != "" {
+		span.SetTag(SpanTagKeyOriginService, name)
+	}
+}
+
 // GetEdgeContext gets the current EdgeRequestContext from the context object,
 // if set.
 func GetEdgeContext(ctx context.Context) (ec *EdgeRequestContext, ok bool) {
@@ -82,13 +153,20 @@ type Config struct {
 	Store *secrets.Store
 	// The logger to log key decoding errors
 	Logger log.Wrapper
+	// If SpanTags is true, SetEdgeContext also sets the fields of the edge
+	// request context as tags on the span attached to the context object,
+	// so that the traces can be filtered by them.
+	//
+	// The user id is hashed before being set as a tag.
+	SpanTags bool
 }
 
 // Init intializes an Impl.
 func Init(cfg Config) *Impl {
 	impl := &Impl{
-		store:  cfg.Store,
-		logger: cfg.Logger,
+		store:    cfg.Store,
+		logger:   cfg.Logger,
+		spanTags: cfg.SpanTags,
 	}
 	impl.store.AddMiddlewares(impl.validatorMiddleware)
 	return impl
@@ -2,15 +2,20 @@ package edgecontext_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"testing"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	sentry "github.com/getsentry/sentry-go"
 	"github.com/gofrs/uuid"
 
 	"github.com/reddit/baseplate.go/edgecontext"
 	"github.com/reddit/baseplate.go/experiments"
 	"github.com/reddit/baseplate.go/timebp"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
 )
 
 const (
@@ -322,6 +327,22 @@ func TestFromHeader(t *testing.T) {
 				)
 			}
 
+			if e.Session().ID() != expectedSessionID {
+				t.Errorf(
+					"Expected Session().ID() %q, got %q",
+					expectedSessionID,
+					e.Session().ID(),
+				)
+			}
+
+			if e.Device().ID() != expectedDeviceID {
+				t.Errorf(
+					"Expected Device().ID() %q, got %q",
+					expectedDeviceID,
+					e.Device().ID(),
+				)
+			}
+
 			t.Run(
 				"user",
 				func(t *testing.T) {
@@ -697,3 +718,98 @@ func TestFromHeader(t *testing.T) {
 		},
 	)
 }
+
+func TestSetEdgeContextSentry(t *testing.T) {
+	const expectedUser = "t2_example"
+
+	e, err := edgecontext.FromHeader(headerWithValidAuth, globalTestImpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := sentry.NewHub(nil, sentry.NewScope())
+	ctx := context.WithValue(context.Background(), sentry.HubContextKey, hub)
+	ctx = edgecontext.SetEdgeContext(ctx, e)
+	if _, ok := edgecontext.GetEdgeContext(ctx); !ok {
+		t.Fatal("Expected edge context set on the context object")
+	}
+
+	event := hub.Scope().ApplyToEvent(sentry.NewEvent(), nil)
+	if event.User.ID != expectedUser {
+		t.Errorf("Expected sentry user id %q, got %q", expectedUser, event.User.ID)
+	}
+	expected := map[string]string{
+		edgecontext.SentryTagKeyDeviceID:      expectedDeviceID,
+		edgecontext.SentryTagKeyOriginService: expectedOrigin,
+	}
+	for k, v := range expected {
+		if event.Tags[k] != v {
+			t.Errorf("Expected sentry tag %q to be %q, got %q", k, v, event.Tags[k])
+		}
+	}
+}
+
+func TestSetEdgeContextSpanTags(t *testing.T) {
+	const expectedUser = "t2_example"
+	hash := sha256.Sum256([]byte(expectedUser))
+	expectedUserHash := hex.EncodeToString(hash[:])
+
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	for _, c := range []struct {
+		name     string
+		impl     *edgecontext.Impl
+		expected map[string]string
+	}{
+		{
+			name: "enabled",
+			impl: spanTagsImpl,
+			expected: map[string]string{
+				edgecontext.SpanTagKeyUserIDHash:    expectedUserHash,
+				edgecontext.SpanTagKeyOriginService: expectedOrigin,
+			},
+		},
+		{
+			name:     "disabled",
+			impl:     globalTestImpl,
+			expected: map[string]string{},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			recorder.Reset()
+
+			e, err := edgecontext.FromHeader(headerWithValidAuth, c.impl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sampled := true
+			ctx, span := tracing.StartSpanFromHeaders(
+				context.Background(),
+				"test",
+				tracing.Headers{Sampled: &sampled},
+			)
+			edgecontext.SetEdgeContext(ctx, e)
+			span.Finish()
+
+			recorded := recorder.MustFindSpan(t, "test")
+			for _, key := range []string{
+				edgecontext.SpanTagKeyUserIDHash,
+				edgecontext.SpanTagKeyOAuthClientID,
+				edgecontext.SpanTagKeyOriginService,
+			} {
+				value, ok := recorded.Tag(key)
+				expected, expectedOK := c.expected[key]
+				if ok != expectedOK || (ok && value != expected) {
+					t.Errorf(
+						"Expected span tag %q to be %q (%v), got %v (%v)",
+						key,
+						expected,
+						expectedOK,
+						value,
+						ok,
+					)
+				}
+			}
+		})
+	}
+}
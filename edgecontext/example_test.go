@@ -0,0 +1,43 @@
+package edgecontext_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// This example demonstrates how an edge service creates a fresh edge request
+// context for a client request,
+// and attaches it to the context object so that it's forwarded to the
+// services called with Baseplate clients.
+func ExampleNew() {
+	// variables should be properly initialized in production code
+	var (
+		ctx    context.Context
+		impl   *edgecontext.Impl
+		client baseplate.BaseplateService
+		// The token returned by the authentication service for the client.
+		authToken string
+	)
+
+	ec, err := edgecontext.New(ctx, impl, edgecontext.NewArgs{
+		LoID:              "t2_example",
+		LoIDCreatedAt:     time.Now(),
+		SessionID:         "session-id",
+		DeviceID:          "device-id",
+		AuthToken:         authToken,
+		OriginServiceName: "my-edge-service",
+	})
+	if err != nil {
+		log.Errorw("Failed to create edge request context", "err", err)
+		return
+	}
+	// The edge request context will be forwarded by thriftbp and httpbp
+	// clients using ctx.
+	ctx = edgecontext.SetEdgeContext(ctx, ec)
+	healthy, err := client.IsHealthy(ctx)
+	log.Debug(healthy, err)
+}
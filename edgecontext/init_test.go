@@ -23,7 +23,10 @@ const secretStore = `
 	}
 }`
 
-var globalTestImpl *edgecontext.Impl
+var (
+	globalTestImpl *edgecontext.Impl
+	spanTagsImpl   *edgecontext.Impl
+)
 
 func TestMain(m *testing.M) {
 	dir, err := ioutil.TempDir("", "edge_context_test_")
@@ -51,5 +54,9 @@ func TestMain(m *testing.M) {
 	defer store.Close()
 
 	globalTestImpl = edgecontext.Init(edgecontext.Config{Store: store})
+	spanTagsImpl = edgecontext.Init(edgecontext.Config{
+		Store:    store,
+		SpanTags: true,
+	})
 	os.Exit(m.Run())
 }
@@ -30,7 +30,7 @@ type EdgeRequestContext struct {
 func (e *EdgeRequestContext) AuthToken() *AuthenticationToken {
 	e.tokenOnce.Do(func() {
 		if token, err := e.impl.ValidateToken(e.raw.AuthToken); err != nil {
-			log.Errorw("token validation failed", "err", err)
+			log.Named("edgecontext").Errorw("token validation failed", "err", err)
 			e.token = nil
 		} else {
 			e.token = token
@@ -58,6 +58,20 @@ func (e *EdgeRequestContext) DeviceID() string {
 	return e.raw.DeviceID
 }
 
+// Session returns the info about the session of this request.
+func (e *EdgeRequestContext) Session() Session {
+	return Session{
+		raw: e.raw,
+	}
+}
+
+// Device returns the info about the device of this request.
+func (e *EdgeRequestContext) Device() Device {
+	return Device{
+		raw: e.raw,
+	}
+}
+
 // User returns the info about the user of this request.
 func (e *EdgeRequestContext) User() User {
 	return User{
@@ -138,3 +152,27 @@ type OriginService struct {
 func (os OriginService) Name() string {
 	return os.raw.OriginServiceName
 }
+
+// Session holds metadata about the session of the request.
+type Session struct {
+	raw NewArgs
+}
+
+// ID returns the session id of the request.
+//
+// It's the same as EdgeRequestContext.SessionID.
+func (s Session) ID() string {
+	return s.raw.SessionID
+}
+
+// Device holds metadata about the device of the request.
+type Device struct {
+	raw NewArgs
+}
+
+// ID returns the device id of the request.
+//
+// It's the same as EdgeRequestContext.DeviceID.
+func (d Device) ID() string {
+	return d.raw.DeviceID
+}
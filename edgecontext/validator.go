@@ -18,12 +18,63 @@ const (
 	jwtAlg                         = "RS256"
 )
 
-// ErrNoPublicKeysLoaded is an error returned by ValidateToken indicates that
-// the function is called before any public keys are loaded from secrets.
-var ErrNoPublicKeysLoaded = errors.New("edgecontext.ValidateToken: no public keys loaded")
+// Errors returned by ValidateToken.
+//
+// Other than ErrNoPublicKeysLoaded, they are returned as the Reason of a
+// TokenError, so use errors.Is to check for them.
+var (
+	// ErrNoPublicKeysLoaded indicates that ValidateToken is called before any
+	// public keys are loaded from secrets.
+	ErrNoPublicKeysLoaded = errors.New("edgecontext.ValidateToken: no public keys loaded")
+
+	// ErrExpiredToken indicates that the token is well-formed and correctly
+	// signed, but already expired.
+	ErrExpiredToken = errors.New("edgecontext.ValidateToken: token expired")
+
+	// ErrInvalidToken indicates that the token failed validation for any other
+	// reason, e.g. it's malformed or the signature doesn't match any of the
+	// loaded public keys.
+	ErrInvalidToken = errors.New("edgecontext.ValidateToken: invalid token")
+)
+
+// TokenError is the error returned by ValidateToken when the token failed
+// validation.
+//
+// errors.Is(err, ErrExpiredToken) and errors.Is(err, ErrInvalidToken) can be
+// used to check the Reason,
+// and errors.As can be used to inspect the underlying jwt error,
+// e.g. *jwt.ValidationError.
+type TokenError struct {
+	// Reason is either ErrExpiredToken or ErrInvalidToken.
+	Reason error
+
+	// Cause is the underlying error, usually a *jwt.ValidationError.
+	//
+	// It could be nil.
+	Cause error
+}
+
+func (e TokenError) Error() string {
+	if e.Cause == nil {
+		return e.Reason.Error()
+	}
+	return e.Reason.Error() + ": " + e.Cause.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e TokenError) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is the Reason of the error.
+func (e TokenError) Is(target error) bool {
+	return target == e.Reason
+}
 
 // ValidateToken parses and validates a jwt token, and return the decoded
 // AuthenticationToken.
+//
+// Other than ErrNoPublicKeysLoaded, the errors returned are TokenError.
 func (impl *Impl) ValidateToken(token string) (*AuthenticationToken, error) {
 	keys, ok := impl.keysValue.Load().(keysType)
 	if !ok {
@@ -39,22 +90,32 @@ func (impl *Impl) ValidateToken(token string) (*AuthenticationToken, error) {
 		},
 	)
 	if err != nil {
-		return nil, err
+		var ve *jwt.ValidationError
+		if errors.As(err, &ve) && ve.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, TokenError{Reason: ErrExpiredToken, Cause: err}
+		}
+		return nil, TokenError{Reason: ErrInvalidToken, Cause: err}
 	}
 
 	if !tok.Valid {
-		return nil, jwt.NewValidationError("invalid token", 0)
+		return nil, TokenError{Reason: ErrInvalidToken}
 	}
 
 	if tok.Method.Alg() != jwtAlg {
-		return nil, jwt.NewValidationError("wrong signing method", 0)
+		return nil, TokenError{
+			Reason: ErrInvalidToken,
+			Cause:  fmt.Errorf("wrong signing method %q", tok.Method.Alg()),
+		}
 	}
 
 	if claims, ok := tok.Claims.(*AuthenticationToken); ok {
 		return claims, nil
 	}
 
-	return nil, jwt.NewValidationError("invalid token type", 0)
+	return nil, TokenError{
+		Reason: ErrInvalidToken,
+		Cause:  fmt.Errorf("invalid token type %T", tok.Claims),
+	}
 }
 
 func (impl *Impl) validatorMiddleware(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
@@ -1,7 +1,23 @@
 package edgecontext_test
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	jwt "gopkg.in/dgrijalva/jwt-go.v3"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/secrets"
 )
 
 // copied from https://github.com/reddit/baseplate.py/blob/db9c1d7cddb1cb242546349e821cad0b0cbd6fce/tests/__init__.py#L55
@@ -18,3 +34,133 @@ func TestValidToken(t *testing.T) {
 		t.Errorf("subject expected %q, got %q", expected, actual)
 	}
 }
+
+func TestInvalidTokenErrors(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impl := newTestImplWithKey(t, &key.PublicKey)
+
+	sign := func(t *testing.T, method jwt.SigningMethod, key interface{}, exp time.Time) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(method, edgecontext.AuthenticationToken{
+			StandardClaims: jwt.StandardClaims{
+				Subject:   "t2_example",
+				ExpiresAt: exp.Unix(),
+			},
+		}).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+
+	for _, c := range []struct {
+		label    string
+		token    string
+		expected error
+	}{
+		{
+			label:    "valid",
+			token:    sign(t, jwt.SigningMethodRS256, key, future),
+			expected: nil,
+		},
+		{
+			label:    "expired",
+			token:    sign(t, jwt.SigningMethodRS256, key, time.Now().Add(-time.Hour)),
+			expected: edgecontext.ErrExpiredToken,
+		},
+		{
+			label:    "wrong-key",
+			token:    sign(t, jwt.SigningMethodRS256, otherKey, future),
+			expected: edgecontext.ErrInvalidToken,
+		},
+		{
+			label:    "wrong-method",
+			token:    sign(t, jwt.SigningMethodRS512, key, future),
+			expected: edgecontext.ErrInvalidToken,
+		},
+		{
+			label:    "malformed",
+			token:    "foo.bar.baz",
+			expected: edgecontext.ErrInvalidToken,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			_, err := impl.ValidateToken(c.token)
+			if !errors.Is(err, c.expected) {
+				t.Errorf("Expected error %v, got %v", c.expected, err)
+			}
+		})
+	}
+
+	t.Run("unwrap", func(t *testing.T) {
+		_, err := impl.ValidateToken(sign(t, jwt.SigningMethodRS256, key, time.Now().Add(-time.Hour)))
+		var tokenErr edgecontext.TokenError
+		if !errors.As(err, &tokenErr) {
+			t.Fatalf("Expected TokenError, got %#v", err)
+		}
+		if tokenErr.Reason != edgecontext.ErrExpiredToken {
+			t.Errorf("Expected reason %v, got %v", edgecontext.ErrExpiredToken, tokenErr.Reason)
+		}
+		if errors.Is(err, edgecontext.ErrInvalidToken) {
+			t.Errorf("Expected expired token error to not be %v", edgecontext.ErrInvalidToken)
+		}
+		var ve *jwt.ValidationError
+		if !errors.As(err, &ve) {
+			t.Fatalf("Expected the jwt error to be wrapped, got %#v", err)
+		}
+		if ve.Errors&jwt.ValidationErrorExpired == 0 {
+			t.Errorf("Expected jwt expired error, got %v", ve)
+		}
+	})
+}
+
+func newTestImplWithKey(t *testing.T, key *rsa.PublicKey) *edgecontext.Impl {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	content, err := json.Marshal(map[string]interface{}{
+		"secrets": map[string]interface{}{
+			"secret/authentication/public-key": map[string]string{
+				"type":    "versioned",
+				"current": string(pemKey),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "edge_context_validator_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "secrets.json")
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := secrets.NewStore(context.Background(), path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return edgecontext.Init(edgecontext.Config{Store: store})
+}
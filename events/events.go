@@ -4,9 +4,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/reddit/baseplate.go/metricsbp"
 	"github.com/reddit/baseplate.go/mqsend"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/go-kit/kit/metrics"
 )
 
 // Configuration values for the message queue.
@@ -39,9 +41,20 @@ var serializerPool = thrift.NewTSerializerPool(
 )
 
 // A Queue is an event queue.
+//
+// It reports the following counters with metricsbp.M:
+//
+// - events.<name>.put.success: the number of events successfully put into the
+// queue;
+//
+// - events.<name>.put.fail: the number of events failed to be put into the
+// queue, either because they are too large or because the queue is full.
 type Queue struct {
 	queue      mqsend.MessageQueue
 	maxTimeout time.Duration
+
+	success metrics.Counter
+	fail    metrics.Counter
 }
 
 // The Config used to initialize an event queue.
@@ -83,6 +96,12 @@ func V2WithConfig(cfg Config) (*Queue, error) {
 }
 
 func v2WithConfig(cfg Config, queue mqsend.MessageQueue) *Queue {
+	name := cfg.Name
+	if name == "" {
+		name = DefaultV2Name
+	}
+	prefix := "events." + name + ".put"
+
 	maxTimeout := cfg.MaxPutTimeout
 	if maxTimeout <= 0 {
 		maxTimeout = DefaultMaxPutTimeout
@@ -91,6 +110,9 @@ func v2WithConfig(cfg Config, queue mqsend.MessageQueue) *Queue {
 	return &Queue{
 		queue:      queue,
 		maxTimeout: maxTimeout,
+
+		success: metricsbp.M.Counter(prefix + ".success"),
+		fail:    metricsbp.M.Counter(prefix + ".fail"),
 	}
 }
 
@@ -102,6 +124,10 @@ func (q *Queue) Close() error {
 }
 
 // Put serializes and puts an event into the event queue.
+//
+// If the serialized event is larger than MaxEventSize,
+// mqsend.MessageTooLargeError will be returned without trying to put it into
+// the queue.
 func (q *Queue) Put(ctx context.Context, event thrift.TStruct) error {
 	ctx, cancel := context.WithTimeout(ctx, q.maxTimeout)
 	defer cancel()
@@ -111,5 +137,18 @@ func (q *Queue) Put(ctx context.Context, event thrift.TStruct) error {
 		return err
 	}
 
-	return q.queue.Send(ctx, data)
+	if len(data) > MaxEventSize {
+		q.fail.Add(1)
+		return mqsend.MessageTooLargeError{
+			MessageSize: len(data),
+			MaxSize:     MaxEventSize,
+		}
+	}
+
+	if err := q.queue.Send(ctx, data); err != nil {
+		q.fail.Add(1)
+		return err
+	}
+	q.success.Add(1)
+	return nil
 }
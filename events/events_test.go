@@ -2,11 +2,14 @@ package events
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
 	"github.com/reddit/baseplate.go/mqsend"
 
 	"github.com/apache/thrift/lib/go/thrift"
@@ -95,3 +98,43 @@ func TestV2Put(t *testing.T) {
 		}()
 	}
 }
+
+type bigTStruct struct{}
+
+func (bigTStruct) Read(_ thrift.TProtocol) error {
+	return nil
+}
+
+func (bigTStruct) Write(p thrift.TProtocol) error {
+	if err := p.WriteMessageBegin(strings.Repeat("a", MaxEventSize), thrift.CALL, 0); err != nil {
+		return err
+	}
+	return p.WriteMessageEnd()
+}
+
+func TestV2PutMetrics(t *testing.T) {
+	recorder := metricstest.Replace(t)
+
+	queue := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxMessageSize: MaxEventSize,
+		MaxQueueSize:   1,
+	})
+	v2 := v2WithConfig(Config{}, queue)
+
+	if err := v2.Put(context.Background(), mockTStruct{}); err != nil {
+		t.Fatal(err)
+	}
+	// The queue is full now.
+	if err := v2.Put(context.Background(), mockTStruct{}); !errors.As(err, new(mqsend.TimedOutError)) {
+		t.Errorf("Expected TimedOutError, got %v", err)
+	}
+	var tooLarge mqsend.MessageTooLargeError
+	if err := v2.Put(context.Background(), bigTStruct{}); !errors.As(err, &tooLarge) {
+		t.Errorf("Expected MessageTooLargeError, got %v", err)
+	} else if tooLarge.MaxSize != MaxEventSize {
+		t.Errorf("Expected MaxSize %d, got %d", MaxEventSize, tooLarge.MaxSize)
+	}
+
+	recorder.AssertCounterEquals(t, "events.v2.put.success", 1)
+	recorder.AssertCounterEquals(t, "events.v2.put.fail", 2)
+}
@@ -0,0 +1,52 @@
+package experiments
+
+import (
+	"context"
+	"sync"
+)
+
+type contextKey int
+
+const (
+	bucketedContextKey contextKey = iota
+)
+
+// NewContext returns a copy of ctx to be used for a single request,
+// so that Experiments.VariantWithEvent only logs one bucketing event per
+// experiment and bucketing value during that request.
+//
+// It should be called at the beginning of every request,
+// e.g. in a server middleware.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bucketedContextKey, &bucketedSet{
+		seen: make(map[bucketedKey]bool),
+	})
+}
+
+func fromContext(ctx context.Context) (set *bucketedSet, ok bool) {
+	set, ok = ctx.Value(bucketedContextKey).(*bucketedSet)
+	return set, ok && set != nil
+}
+
+type bucketedKey struct {
+	experiment string
+	bucketVal  string
+}
+
+// bucketedSet records the experiments already bucketed during a request.
+type bucketedSet struct {
+	lock sync.Mutex
+	seen map[bucketedKey]bool
+}
+
+// add returns true if experiment and bucketVal were not seen before.
+func (s *bucketedSet) add(experiment, bucketVal string) bool {
+	key := bucketedKey{experiment: experiment, bucketVal: bucketVal}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	return true
+}
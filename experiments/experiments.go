@@ -28,6 +28,7 @@ const targetAllOverride = `{"OVERRIDE": true}`
 type Experiments struct {
 	watcher     *filewatcher.Result
 	eventLogger EventLogger
+	logger      log.Wrapper
 }
 
 // NewExperiments returns a new instance of the experiments clients. The path
@@ -58,6 +59,7 @@ func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, l
 	return &Experiments{
 		watcher:     result,
 		eventLogger: eventLogger,
+		logger:      log.FallbackWrapper(logger),
 	}, nil
 }
 
@@ -69,14 +71,68 @@ func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, l
 //
 // Returns the name of the enabled variant as a string if any variant is
 // enabled. If no variant is enabled returns an empty string.
+//
+// Variant doesn't log any bucketing events,
+// use VariantWithEvent for that.
 func (e *Experiments) Variant(name string, args map[string]interface{}, bucketingEventOverride bool) (string, error) {
-	experiment, err := e.experiment(name)
+	experiment, err := experimentFromDocument(e.watcher.Get().(document), name)
 	if err != nil {
 		return "", err
 	}
 	return experiment.Variant(args)
 }
 
+// VariantWithEvent is the same as Variant,
+// but also logs a bucketing event with the EventLogger when a variant is
+// enabled, unless bucketingEventOverride is true.
+//
+// The bucketing events are deduplicated per request:
+// when ctx comes from NewContext, a bucketing event is only logged the first
+// time the same experiment is evaluated for the same bucketing value.
+// Without NewContext every call logs a bucketing event.
+//
+// Failures to log the bucketing event are not returned,
+// but logged with the logger passed into NewExperiments.
+func (e *Experiments) VariantWithEvent(ctx context.Context, name string, args map[string]interface{}, bucketingEventOverride bool) (string, error) {
+	doc := e.watcher.Get().(document)
+	experiment, err := experimentFromDocument(doc, name)
+	if err != nil {
+		return "", err
+	}
+	variant, isOverride, err := experiment.variant(args)
+	if err != nil {
+		return "", err
+	}
+	if variant == "" || bucketingEventOverride || e.eventLogger == nil {
+		return variant, nil
+	}
+
+	args = lowerArguments(args)
+	if bucketed, ok := fromContext(ctx); ok {
+		if !bucketed.add(name, fmt.Sprint(args[experiment.bucketVal])) {
+			return variant, nil
+		}
+	}
+
+	event := ExperimentEvent{
+		Experiment:  doc[name],
+		VariantName: variant,
+		IsOverride:  isOverride,
+		EventType:   "BUCKET",
+	}
+	if userID, ok := args["user_id"].(string); ok {
+		event.UserID = userID
+	}
+	if err := e.eventLogger.Log(ctx, event); err != nil {
+		e.logger(fmt.Sprintf(
+			"experiments: failed to log bucketing event for experiment %q: %v",
+			name,
+			err,
+		))
+	}
+	return variant, nil
+}
+
 // Expose logs an event to indicate that a user has been exposed to an
 // experimental treatment.
 func (e *Experiments) Expose(ctx context.Context, experimentName string, event ExperimentEvent) error {
@@ -92,8 +148,7 @@ func (e *Experiments) Expose(ctx context.Context, experimentName string, event E
 	return e.eventLogger.Log(ctx, event)
 }
 
-func (e *Experiments) experiment(name string) (*SimpleExperiment, error) {
-	doc := e.watcher.Get().(document)
+func experimentFromDocument(doc document, name string) (*SimpleExperiment, error) {
 	experiment, ok := doc[name]
 	if !ok {
 		return nil, UnknownExperimentError(name)
@@ -215,7 +270,7 @@ func NewSimpleExperiment(experiment *ExperimentConfig) (*SimpleExperiment, error
 	}
 
 	targetingConfig := experiment.Experiment.Targeting
-	if len(targetingConfig) == 0 {
+	if len(targetingConfig) == 0 || string(targetingConfig) == "null" {
 		targetingConfig = []byte(targetAllOverride)
 	}
 	targeting, err := NewTargeting(targetingConfig)
@@ -253,12 +308,19 @@ func NewSimpleExperiment(experiment *ExperimentConfig) (*SimpleExperiment, error
 // Variant determines the variant, if any, is active. Bucket calculation is
 // determined based on the bucketVal.
 func (e *SimpleExperiment) Variant(args map[string]interface{}) (string, error) {
+	variant, _, err := e.variant(args)
+	return variant, err
+}
+
+// variant is the implementation of Variant,
+// it also returns whether the variant was chosen because of an override.
+func (e *SimpleExperiment) variant(args map[string]interface{}) (variant string, isOverride bool, err error) {
 	if !e.isEnabled() {
-		return "", nil
+		return "", false, nil
 	}
 	args = lowerArguments(args)
 	if value, ok := args[e.bucketVal]; !ok || value == "" {
-		return "", fmt.Errorf(
+		return "", false, fmt.Errorf(
 			"experiment.SimpleExperiment.Variant: must specify %s in call to variant for experiment %s",
 			e.bucketVal,
 			e.name,
@@ -267,23 +329,23 @@ func (e *SimpleExperiment) Variant(args map[string]interface{}) (string, error)
 	for _, override := range e.overrides {
 		for variant, targeting := range override {
 			if targeting.Evaluate(args) {
-				return variant, nil
+				return variant, true, nil
 			}
 		}
 	}
 	if !e.targeting.Evaluate(args) {
-		return "", nil
+		return "", false, nil
 	}
 	bucketVal, ok := args[e.bucketVal].(string)
 	if !ok {
-		return "", fmt.Errorf(
+		return "", false, fmt.Errorf(
 			"experiment.SimpleExperiment.Variant: expected bucket val to be a string, actual: %T",
 			args[e.bucketVal],
 		)
 	}
 
 	bucket := e.calculateBucket(bucketVal)
-	return e.variantSet.ChooseVariant(bucket), nil
+	return e.variantSet.ChooseVariant(bucket), false, nil
 }
 
 func lowerArguments(args map[string]interface{}) map[string]interface{} {
@@ -331,15 +393,12 @@ type Variant struct {
 type UnknownExperimentError string
 
 func (name UnknownExperimentError) Error() string {
-	return fmt.Sprintf("experiments: experiment with name %s unknown", name)
+	return fmt.Sprintf("experiments: experiment with name %s unknown", string(name))
 }
 
 func isSimpleExperiment(experimentType string) bool {
 	switch experimentType {
-	case "single_variant":
-	case "multi_variant":
-	case "feature_rollout":
-	case "range_variant":
+	case "single_variant", "multi_variant", "feature_rollout", "range_variant":
 		return true
 	}
 	return false
@@ -1,9 +1,13 @@
 package experiments
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -406,3 +410,129 @@ func roundTo(num float64, digits int) float64 {
 	shift := math.Pow(10, float64(digits))
 	return math.Round(num*shift) / shift
 }
+
+type recordingEventLogger struct {
+	events []ExperimentEvent
+}
+
+func (l *recordingEventLogger) Log(_ context.Context, event ExperimentEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestExperimentsVariant(t *testing.T) {
+	dir, err := ioutil.TempDir("", "experiments_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "experiments.json")
+	data, err := json.Marshal(document{simpleConfig.Name: simpleConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	eventLogger := new(recordingEventLogger)
+	experiments, err := NewExperiments(ctx, path, eventLogger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := experiments.Variant("unknown", nil, false); err == nil {
+		t.Error("Expected error for unknown experiment, got nil")
+	}
+	if _, err := experiments.VariantWithEvent(ctx, "unknown", nil, false); err == nil {
+		t.Error("Expected error for unknown experiment, got nil")
+	}
+
+	// Find a user bucketed into a variant.
+	var userID, variant string
+	for i := 0; variant == ""; i++ {
+		if i >= 1000 {
+			t.Fatal("No user bucketed into any variant")
+		}
+		userID = fmt.Sprintf("t2_%d", i)
+		variant, err = experiments.Variant(
+			simpleConfig.Name,
+			map[string]interface{}{"user_id": userID},
+			false,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(eventLogger.events) != 0 {
+		t.Fatalf("Expected no bucketing events from Variant, got %+v", eventLogger.events)
+	}
+
+	if _, err := experiments.VariantWithEvent(
+		ctx,
+		simpleConfig.Name,
+		map[string]interface{}{"user_id": userID},
+		true, // suppress bucketing events
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(eventLogger.events) != 0 {
+		t.Fatalf("Expected no bucketing events with override, got %+v", eventLogger.events)
+	}
+
+	requestCtx := NewContext(ctx)
+	for i := 0; i < 3; i++ {
+		actual, err := experiments.VariantWithEvent(
+			requestCtx,
+			simpleConfig.Name,
+			map[string]interface{}{"user_id": userID},
+			false,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != variant {
+			t.Errorf("Expected deterministic variant %q, got %q", variant, actual)
+		}
+	}
+	if len(eventLogger.events) != 1 {
+		t.Fatalf("Expected 1 bucketing event in the same request, got %+v", eventLogger.events)
+	}
+	event := eventLogger.events[0]
+	if event.EventType != "BUCKET" || event.VariantName != variant || event.UserID != userID || event.IsOverride {
+		t.Errorf("Unexpected bucketing event %+v", event)
+	}
+	if event.Experiment == nil || event.Experiment.Name != simpleConfig.Name {
+		t.Errorf("Expected event experiment %q, got %+v", simpleConfig.Name, event.Experiment)
+	}
+
+	if _, err := experiments.VariantWithEvent(
+		NewContext(ctx),
+		simpleConfig.Name,
+		map[string]interface{}{"user_id": userID},
+		false,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(eventLogger.events) != 2 {
+		t.Errorf("Expected a new bucketing event in a new request, got %+v", eventLogger.events)
+	}
+}
+
+func TestIsSimpleExperiment(t *testing.T) {
+	for _, experimentType := range []string{
+		"single_variant",
+		"multi_variant",
+		"feature_rollout",
+		"range_variant",
+	} {
+		if !isSimpleExperiment(experimentType) {
+			t.Errorf("Expected %q to be a simple experiment", experimentType)
+		}
+	}
+	if isSimpleExperiment("unknown") {
+		t.Error("Expected unknown to not be a simple experiment")
+	}
+}
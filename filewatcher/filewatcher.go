@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,6 +21,10 @@ import (
 // caller can tweak its value when needed.
 var InitialReadInterval = time.Second / 2
 
+// DefaultPollingInterval is the polling interval used when the file system
+// watcher cannot be created and Config.PollingInterval is not set.
+const DefaultPollingInterval = time.Second * 10
+
 // DefaultMaxFileSize is the default MaxFileSize used when it's <= 0.
 //
 // It's 1MiB, which is following the size limit of Apache ZooKeeper nodes.
@@ -38,6 +43,12 @@ type Result struct {
 
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// The modification time and size of the file when it was last read,
+	// used by polling.
+	lock    sync.Mutex
+	modTime time.Time
+	size    int64
 }
 
 // Get returns the latest parsed data from the file watcher.
@@ -61,21 +72,39 @@ func (r *Result) Stop() {
 
 func (r *Result) watcherLoop(
 	watcher *fsnotify.Watcher,
+	pollingInterval time.Duration,
 	path string,
 	parser Parser,
 	logger log.Wrapper,
 ) {
+	logger = log.FallbackWrapper(logger)
 	file := filepath.Base(path)
+
+	// Both the watcher and the ticker are optional,
+	// nil channels are never selected.
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if watcher != nil {
+		defer watcher.Close()
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+	var tick <-chan time.Time
+	if pollingInterval > 0 {
+		ticker := time.NewTicker(pollingInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
 	for {
 		select {
 		case <-r.ctx.Done():
-			watcher.Close()
 			return
 
-		case err := <-watcher.Errors:
-			log.FallbackWrapper(logger)("watcher error: " + err.Error())
+		case err := <-errs:
+			logger("watcher error: " + err.Error())
 
-		case ev := <-watcher.Events:
+		case ev := <-events:
 			if filepath.Base(ev.Name) != file {
 				continue
 			}
@@ -84,25 +113,60 @@ func (r *Result) watcherLoop(
 			default:
 				// Ignore uninterested events.
 			case fsnotify.Create, fsnotify.Write:
-				// Wrap with an anonymous function to make sure that defer works.
-				func() {
-					f, err := os.Open(path)
-					if err != nil {
-						log.FallbackWrapper(logger)("parser error: " + err.Error())
-					}
-					defer f.Close()
-					d, err := parser(f)
-					if err != nil {
-						log.FallbackWrapper(logger)("parser error: " + err.Error())
-					} else {
-						r.data.Store(d)
-					}
-				}()
+				r.reload(path, parser, logger)
+			}
+
+		case <-tick:
+			stat, err := os.Stat(path)
+			if err != nil {
+				if r.ctx.Err() != nil {
+					// Stopped while polling, the file might be already gone.
+					return
+				}
+				logger("polling error: " + err.Error())
+				continue
+			}
+			if r.changed(stat) {
+				r.reload(path, parser, logger)
 			}
 		}
 	}
 }
 
+// changed returns true if the file was modified since last read,
+// according to its modification time and size.
+func (r *Result) changed(stat os.FileInfo) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return !stat.ModTime().Equal(r.modTime) || stat.Size() != r.size
+}
+
+func (r *Result) reload(path string, parser Parser, logger log.Wrapper) {
+	f, err := os.Open(path)
+	if err != nil {
+		logger("parser error: " + err.Error())
+		return
+	}
+	defer f.Close()
+
+	// Record the stat before parsing,
+	// so that changes during parsing will be picked up by the next poll.
+	if stat, err := f.Stat(); err == nil {
+		r.lock.Lock()
+		r.modTime = stat.ModTime()
+		r.size = stat.Size()
+		r.lock.Unlock()
+	}
+
+	d, err := parser(f)
+	if err != nil {
+		logger("parser error: " + err.Error())
+		return
+	}
+	r.data.Store(d)
+}
+
 // Config defines the config to be used in New function.
 type Config struct {
 	// The path to the file to be watched, required.
@@ -134,6 +198,16 @@ type Config struct {
 	// 3. Getting the real size of the content without actually reading them could
 	//    be tricky. Only send partial data to parsers is a more robust solution.
 	MaxFileSize int64
+
+	// Optional. When > 0, the file will also be polled at this interval,
+	// and re-parsed when its modification time or size changed.
+	//
+	// This is useful on file systems where inotify events are unreliable
+	// (e.g. some network or overlay file systems).
+	// When the underlying file system watcher cannot be created,
+	// the file watcher falls back to polling only,
+	// using DefaultPollingInterval if PollingInterval is not set.
+	PollingInterval time.Duration
 }
 
 func limitParser(parser Parser, limit int64) Parser {
@@ -182,30 +256,45 @@ func New(ctx context.Context, cfg Config) (*Result, error) {
 
 	defer f.Close()
 
-	var watcher *fsnotify.Watcher
+	pollingInterval := cfg.PollingInterval
 	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, err
+	if err == nil {
+		// Note: We need to watch the parent directory instead of the file itself,
+		// because only watching the file won't give us CREATE events,
+		// which will happen with atomic renames.
+		err = watcher.Add(filepath.Dir(cfg.Path))
+		if err != nil {
+			watcher.Close()
+		}
 	}
-	// Note: We need to watch the parent directory instead of the file itself,
-	// because only watching the file won't give us CREATE events,
-	// which will happen with atomic renames.
-	err = watcher.Add(filepath.Dir(cfg.Path))
 	if err != nil {
-		return nil, err
+		log.FallbackWrapper(cfg.Logger)(
+			"filewatcher: failed to create file system watcher, falling back to polling: " + err.Error(),
+		)
+		watcher = nil
+		if pollingInterval <= 0 {
+			pollingInterval = DefaultPollingInterval
+		}
+	}
+
+	res := &Result{}
+	if stat, err := f.Stat(); err == nil {
+		res.modTime = stat.ModTime()
+		res.size = stat.Size()
 	}
 
 	var d interface{}
 	d, err = parser(f)
 	if err != nil {
-		watcher.Close()
+		if watcher != nil {
+			watcher.Close()
+		}
 		return nil, err
 	}
-	res := &Result{}
 	res.data.Store(d)
 	res.ctx, res.cancel = context.WithCancel(context.Background())
 
-	go res.watcherLoop(watcher, cfg.Path, parser, cfg.Logger)
+	go res.watcherLoop(watcher, pollingInterval, cfg.Path, parser, cfg.Logger)
 
 	return res, nil
 }
@@ -153,7 +153,8 @@ func TestFileWatcherRename(t *testing.T) {
 		time.Sleep(writeDelay)
 		f, err := os.Create(path)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 		defer f.Close()
 		if _, err := f.Write(payload1); err != nil {
@@ -375,3 +376,59 @@ func TestParserSizeLimit(t *testing.T) {
 	time.Sleep(time.Millisecond * 500)
 	compareBytesData(t, data.Get(), expectedPayload2)
 }
+
+func TestFileWatcherPolling(t *testing.T) {
+	payload1 := []byte("Hello, world!")
+	payload2 := []byte("Bye, world!!")
+
+	// The watched path is a symlink to a file in another directory,
+	// so writing to the target file won't trigger any file system events in the
+	// watched directory, only polling can pick up the change.
+	targetDir, err := ioutil.TempDir("", "filewatcher_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(targetDir)
+	target := filepath.Join(targetDir, "foo")
+	if err := ioutil.WriteFile(target, payload1, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "filewatcher_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "foo")
+	if err := os.Symlink(target, path); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := filewatcher.New(
+		ctx,
+		filewatcher.Config{
+			Path:            path,
+			Parser:          parser,
+			Logger:          log.TestWrapper(t),
+			PollingInterval: time.Millisecond,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Stop()
+	compareBytesData(t, data.Get(), payload1)
+
+	if err := ioutil.WriteFile(target, payload2, 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for string(data.Get().([]byte)) != string(payload2) {
+		if time.Now().After(deadline) {
+			t.Fatal("Polling did not pick up the change")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
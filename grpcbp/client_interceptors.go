@@ -0,0 +1,258 @@
+package grpcbp
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+var (
+	_ grpc.UnaryClientInterceptor  = MonitorClientUnary
+	_ grpc.StreamClientInterceptor = MonitorClientStream
+	_ grpc.UnaryClientInterceptor  = ForwardEdgeRequestContextUnary
+	_ grpc.StreamClientInterceptor = ForwardEdgeRequestContextStream
+)
+
+// BaseplateDefaultDialOptions returns the grpc.DialOptions chaining the default
+// unary and stream client interceptors that should be used by a baseplate
+// gRPC client.
+//
+// Currently they are (in order):
+//
+// 1. MonitorClientUnary/MonitorClientStream
+//
+// 2. ForwardEdgeRequestContextUnary/ForwardEdgeRequestContextStream
+//
+// Additional interceptors (e.g. RetryUnary) can be chained after them by
+// passing grpc.WithChainUnaryInterceptor and grpc.WithChainStreamInterceptor
+// options into grpc.Dial after these options,
+// so all the retries share the same client span.
+func BaseplateDefaultDialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(
+			MonitorClientUnary,
+			ForwardEdgeRequestContextUnary,
+		),
+		grpc.WithChainStreamInterceptor(
+			MonitorClientStream,
+			ForwardEdgeRequestContextStream,
+		),
+	}
+}
+
+// startClientSpan starts the client span of a call and injects it into the
+// outgoing metadata.
+func startClientSpan(ctx context.Context, fullMethod string) (context.Context, opentracing.Span) {
+	span, ctx := opentracing.StartSpanFromContext(
+		ctx,
+		methodName(fullMethod),
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	return CreateOutgoingContextFromSpan(ctx, tracing.AsSpan(span)), span
+}
+
+// MonitorClientUnary is a grpc.UnaryClientInterceptor that wraps the call in a
+// client span.
+//
+// The span is named after the full method name in the form of
+// "package.service.method".
+func MonitorClientUnary(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) (err error) {
+	ctx, span := startClientSpan(ctx, method)
+	defer func() {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}()
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// MonitorClientStream is the grpc.StreamClientInterceptor version of
+// MonitorClientUnary.
+//
+// The span covers the whole stream,
+// it's finished when the stream ends, that is when:
+//
+// - RecvMsg returns an error (including io.EOF at the end of the stream);
+//
+// - RecvMsg returns the response of a non-server-streaming RPC;
+//
+// - SendMsg returns an error other than io.EOF;
+//
+// - or the context passed in is done,
+// whichever happens first.
+func MonitorClientStream(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	ctx, span := startClientSpan(ctx, method)
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+		return nil, err
+	}
+
+	stream := &monitoredClientStream{
+		ClientStream: cs,
+		desc:         desc,
+		ctx:          ctx,
+		span:         span,
+	}
+	go func() {
+		<-cs.Context().Done()
+		// The context of the stream is also canceled by grpc when the stream
+		// ends normally, in which case the span is finished by RecvMsg or
+		// SendMsg instead, so only the errors of ctx are used here.
+		if err := ctx.Err(); err != nil {
+			stream.finish(err)
+		}
+	}()
+	return stream, nil
+}
+
+// monitoredClientStream is the grpc.ClientStream finishing the client span
+// when the stream ends.
+type monitoredClientStream struct {
+	grpc.ClientStream
+
+	desc *grpc.StreamDesc
+	ctx  context.Context
+	span opentracing.Span
+	once sync.Once
+}
+
+func (s *monitoredClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF means the stream is ended by the server,
+	// the actual status is returned by RecvMsg.
+	if err != nil && err != io.EOF {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *monitoredClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.desc.ServerStreams:
+		// The only response of a non-server-streaming RPC ends the stream.
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *monitoredClientStream) finish(err error) {
+	s.once.Do(func() {
+		s.span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: s.ctx,
+			Err: err,
+		}.Convert())
+	})
+}
+
+// forwardEdgeRequestContext returns a context forwarding the
+// EdgeRequestContext to the server being called.
+func forwardEdgeRequestContext(ctx context.Context) context.Context {
+	if ec, ok := edgecontext.GetEdgeContext(ctx); ok {
+		return setOutgoing(ctx, MetadataEdgeRequest, ec.Header())
+	}
+	if header, ok := getIncoming(ctx, MetadataEdgeRequest); ok {
+		return setOutgoing(ctx, MetadataEdgeRequest, header)
+	}
+	return ctx
+}
+
+// ForwardEdgeRequestContextUnary is a grpc.UnaryClientInterceptor that
+// forwards the EdgeRequestContext set on the context object to the gRPC
+// service being called if one is set.
+//
+// If there's no EdgeRequestContext set on the context object,
+// but the context object is from an incoming gRPC request with the
+// "edge-request-bin" metadata
+// (e.g. the server doesn't use InjectEdgeContextUnary),
+// the incoming metadata is forwarded as-is instead.
+func ForwardEdgeRequestContextUnary(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	return invoker(forwardEdgeRequestContext(ctx), method, req, reply, cc, opts...)
+}
+
+// ForwardEdgeRequestContextStream is the grpc.StreamClientInterceptor version
+// of ForwardEdgeRequestContextUnary.
+func ForwardEdgeRequestContextStream(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return streamer(forwardEdgeRequestContext(ctx), desc, cc, method, opts...)
+}
+
+// IsRetryableError is the retrybp.Classifier used by RetryUnary when the
+// Classifier in retrybp.Config is nil.
+//
+// It only treats the errors with codes.Unavailable status as retryable,
+// which gRPC uses when the server cannot be reached and is safe to retry.
+func IsRetryableError(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// RetryUnary returns a grpc.UnaryClientInterceptor that retries the calls using
+// retrybp.Do with the given config.
+//
+// When cfg.Classifier is nil, IsRetryableError will be used.
+//
+// Streaming calls are not retried,
+// as the messages already sent or received cannot be replayed.
+func RetryUnary(cfg retrybp.Config) grpc.UnaryClientInterceptor {
+	if cfg.Classifier == nil {
+		cfg.Classifier = IsRetryableError
+	}
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return retrybp.Do(ctx, cfg, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+// Package grpcbp provides Baseplate specific gRPC related helpers.
+//
+// Servers
+//
+// On the server side,
+// this package provides unary and streaming interceptors creating a server
+// span for every call, extracting the EdgeRequestContext from the incoming
+// metadata, and recovering from panics in the handlers.
+// Use BaseplateDefaultServerOptions to get them all in the recommended order:
+//
+//     server := grpc.NewServer(grpcbp.BaseplateDefaultServerOptions(ecImpl)...)
+//
+// The metrics of the calls are reported by the span hooks registered on the
+// server spans (e.g. metricsbp.CreateServerSpanHook),
+// the same way as thriftbp and httpbp servers.
+//
+// Clients
+//
+// On the client side,
+// this package provides unary and streaming interceptors creating a client
+// span for every call, propagating the tracing and EdgeRequestContext metadata
+// to the server, and retrying the failed unary calls.
+// Use BaseplateDefaultDialOptions to get the default ones:
+//
+//     conn, err := grpc.Dial(addr, grpcbp.BaseplateDefaultDialOptions()...)
+//
+// The metadata keys used for the propagation are the lower case versions of
+// the headers used by thriftbp.
+package grpcbp
@@ -0,0 +1,380 @@
+package grpcbp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	testpb "google.golang.org/grpc/test/grpc_testing"
+
+	"github.com/reddit/baseplate.go/grpcbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+const checkMethod = "grpc.health.v1.Health.Check"
+
+// startHealthServer starts a gRPC server with the health service over an in
+// memory connection, and returns a client connection to it.
+func startHealthServer(t *testing.T, opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+
+	return startServer(t, func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	}, opts...)
+}
+
+// startServer starts a gRPC server with the services registered by register
+// over an in memory connection, and returns a client connection to it.
+func startServer(t *testing.T, register func(*grpc.Server), opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(opts...)
+	register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	dialOpts := append(
+		[]grpc.DialOption{
+			grpc.WithInsecure(),
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				return lis.Dial()
+			}),
+		},
+		grpcbp.BaseplateDefaultDialOptions()...,
+	)
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+// streamingServer implements the streaming RPCs of testpb.TestService.
+type streamingServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (*streamingServer) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	for range req.GetResponseParameters() {
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*streamingServer) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{})
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	var md metadata.MD
+	capture := func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		md, _ = metadata.FromIncomingContext(ctx)
+		return handler(ctx, req)
+	}
+	conn := startHealthServer(
+		t,
+		grpc.ChainUnaryInterceptor(
+			grpcbp.InjectServerSpanUnary,
+			capture,
+			grpcbp.RecoverPanicUnary,
+		),
+	)
+
+	// Simulate an incoming request with the edge request context,
+	// which should be forwarded as-is.
+	const header = "edge-request-header"
+	ctx := metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs(grpcbp.MetadataEdgeRequest, header),
+	)
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if values := md.Get(grpcbp.MetadataEdgeRequest); len(values) != 1 || values[0] != header {
+		t.Errorf("Expected edge request metadata %q, got %v", header, values)
+	}
+
+	spans := recorder.FindSpans(checkMethod)
+	if len(spans) != 2 {
+		t.Fatalf("Expected a client span and a server span, got %+v", recorder.Spans())
+	}
+	// The server span finishes first.
+	server, client := spans[0], spans[1]
+	if !server.HasAnnotation(tracing.ZipkinTimeAnnotationKeyServerReceive) {
+		t.Errorf("Expected server span, got %+v", server)
+	}
+	if !client.HasAnnotation(tracing.ZipkinTimeAnnotationKeyClientSend) {
+		t.Errorf("Expected client span, got %+v", client)
+	}
+	if parent, ok := recorder.Parent(server); !ok || parent.SpanID != client.SpanID {
+		t.Errorf("Expected the client span to be the parent of the server span, got %+v", recorder.Spans())
+	}
+}
+
+func TestMonitorClientStream(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+	conn := startHealthServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	const name = "grpc.health.v1.Health.Watch"
+	if spans := recorder.FindSpans(name); len(spans) != 0 {
+		t.Fatalf("Expected the client span to be unfinished while streaming, got %+v", spans)
+	}
+
+	cancel()
+	// RecvMsg returns the cancellation and waits for the span to be finished.
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("Expected Canceled error, got %v", err)
+	}
+	span := recorder.MustFindSpan(t, name)
+	if !span.IsError() {
+		t.Errorf("Expected the canceled stream to finish the span with error, got %+v", span)
+	}
+}
+
+func TestMonitorClientStreamCompletion(t *testing.T) {
+	conn := startServer(t, func(server *grpc.Server) {
+		testpb.RegisterTestServiceServer(server, &streamingServer{})
+	})
+	client := testpb.NewTestServiceClient(conn)
+
+	// grpc cancels the context of the streams when they end normally,
+	// repeat the calls to catch the spans finished with the cancellation.
+	const n = 20
+
+	t.Run("server-streaming", func(t *testing.T) {
+		recorder := tracingtest.InitGlobalTracer(t)
+		for i := 0; i < n; i++ {
+			stream, err := client.StreamingOutputCall(
+				context.Background(),
+				&testpb.StreamingOutputCallRequest{
+					ResponseParameters: []*testpb.ResponseParameters{{}, {}},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for {
+				if _, err := stream.Recv(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		assertStreamSpans(t, recorder, "grpc.testing.TestService.StreamingOutputCall", n)
+	})
+
+	t.Run("client-streaming", func(t *testing.T) {
+		recorder := tracingtest.InitGlobalTracer(t)
+		for i := 0; i < n; i++ {
+			stream, err := client.StreamingInputCall(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j := 0; j < 2; j++ {
+				if err := stream.Send(&testpb.StreamingInputCallRequest{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := stream.CloseAndRecv(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assertStreamSpans(t, recorder, "grpc.testing.TestService.StreamingInputCall", n)
+	})
+}
+
+// assertStreamSpans asserts that there are n spans with name,
+// all finished without errors.
+func assertStreamSpans(t *testing.T, recorder *tracingtest.Recorder, name string, n int) {
+	t.Helper()
+
+	// The spans of the canceled streams are finished asynchronously.
+	time.Sleep(time.Millisecond * 50)
+	spans := recorder.FindSpans(name)
+	if len(spans) != n {
+		t.Fatalf("Expected %d spans, got %d", n, len(spans))
+	}
+	for _, span := range spans {
+		if span.IsError() {
+			t.Errorf("Expected the span to finish without error, got %+v", span)
+		}
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	const (
+		fullMethod = "/test.Service/Panic"
+		counter    = "grpc.test.Service.Panic.panic"
+		secret     = "secret value"
+	)
+
+	checkErr := func(t *testing.T, err error) {
+		t.Helper()
+		if status.Code(err) != codes.Internal {
+			t.Errorf("Expected internal error, got %v", err)
+		}
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("Expected the panic value to not be returned, got %v", err)
+		}
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		recorder := metricstest.Replace(t)
+		_, err := grpcbp.RecoverPanicUnary(
+			context.Background(),
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: fullMethod},
+			func(context.Context, interface{}) (interface{}, error) {
+				panic(secret)
+			},
+		)
+		checkErr(t, err)
+		recorder.AssertCounterEquals(t, counter, 1)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		recorder := metricstest.Replace(t)
+		err := grpcbp.RecoverPanicStream(
+			nil,
+			contextStream{ctx: context.Background()},
+			&grpc.StreamServerInfo{FullMethod: fullMethod},
+			func(interface{}, grpc.ServerStream) error {
+				panic(secret)
+			},
+		)
+		checkErr(t, err)
+		recorder.AssertCounterEquals(t, counter, 1)
+	})
+
+	t.Run("logger", func(t *testing.T) {
+		metricstest.Replace(t)
+		core, logs := observer.New(zap.DebugLevel)
+		logger := zap.New(core).Sugar()
+		unary := grpcbp.InjectLoggerUnary(logger)
+		_, err := unary(
+			context.Background(),
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: fullMethod},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return grpcbp.RecoverPanicUnary(
+					ctx,
+					req,
+					&grpc.UnaryServerInfo{FullMethod: fullMethod},
+					func(context.Context, interface{}) (interface{}, error) {
+						panic(secret)
+					},
+				)
+			},
+		)
+		checkErr(t, err)
+		entries := logs.FilterMessage("recovered from panic in grpc handler").AllUntimed()
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 log entry from the injected logger, got %d", len(entries))
+		}
+	})
+}
+
+// contextStream is a grpc.ServerStream only implementing Context.
+type contextStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRetryUnary(t *testing.T) {
+	interceptor := grpcbp.RetryUnary(retrybp.Config{
+		MaxAttempts: 3,
+	})
+
+	for _, c := range []struct {
+		label    string
+		err      error
+		expected int
+	}{
+		{
+			label:    "unavailable",
+			err:      status.Error(codes.Unavailable, "unavailable"),
+			expected: 3,
+		},
+		{
+			label:    "invalid-argument",
+			err:      status.Error(codes.InvalidArgument, "invalid"),
+			expected: 1,
+		},
+		{
+			label:    "other",
+			err:      errors.New("other"),
+			expected: 1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var calls int
+			err := interceptor(
+				context.Background(),
+				"/test.Service/Method",
+				nil,
+				nil,
+				nil,
+				func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+					calls++
+					return c.err
+				},
+			)
+			if err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if calls != c.expected {
+				t.Errorf("Expected %d calls, got %d", c.expected, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package grpcbp
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Edge request context propagation related metadata keys.
+const (
+	// The serialized EdgeRequestContext.
+	//
+	// It's binary so the key comes with the "-bin" suffix required by gRPC.
+	MetadataEdgeRequest = "edge-request-bin"
+)
+
+// Tracing related metadata keys,
+// the same as the thrift headers defined in thriftbp.
+const (
+	// The Trace ID, a 64-bit integer encoded in decimal.
+	MetadataTracingTrace = "trace"
+	// The Span ID, a 64-bit integer encoded in decimal.
+	MetadataTracingSpan = "span"
+	// The Parent Span ID, a 64-bit integer encoded in decimal.
+	MetadataTracingParent = "parent"
+	// The Sampled flag, an ASCII "1" (MetadataTracingSampledTrue) if true,
+	// otherwise false.
+	// If not present, defaults to false.
+	MetadataTracingSampled = "sampled"
+	// Trace flags, a 64-bit integer encoded in decimal.
+	// If not present, defaults to null.
+	MetadataTracingFlags = "flags"
+)
+
+// MetadataTracingSampledTrue is the metadata value to indicate that this trace
+// should be sampled.
+const MetadataTracingSampledTrue = "1"
+
+// getIncoming returns the first value of key in the incoming metadata.
+func getIncoming(ctx context.Context, key string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// setOutgoing returns a context with key set to value in the outgoing
+// metadata, replacing the existing values of key.
+func setOutgoing(ctx context.Context, key, value string) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	md.Set(key, value)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// StartSpanFromIncomingContext creates a server span from the incoming gRPC
+// metadata on the context object.
+//
+// This span would usually be used as the span of the whole gRPC method
+// handler, and the parent of the child-spans.
+//
+// Please note that "sampled" metadata is default to false according to
+// baseplate spec, so if the context object doesn't have the metadata set
+// correctly, this span (and all its child-spans) will never be sampled,
+// unless debug flag was set explicitly later.
+//
+// If any of the tracing related metadata is present but malformed,
+// it will be ignored.
+func StartSpanFromIncomingContext(ctx context.Context, name string) (context.Context, *tracing.Span) {
+	var headers tracing.Headers
+	var sampled bool
+
+	if str, ok := getIncoming(ctx, MetadataTracingTrace); ok {
+		headers.TraceID = str
+	}
+	if str, ok := getIncoming(ctx, MetadataTracingSpan); ok {
+		headers.SpanID = str
+	}
+	if str, ok := getIncoming(ctx, MetadataTracingFlags); ok {
+		headers.Flags = str
+	}
+	if str, ok := getIncoming(ctx, MetadataTracingSampled); ok {
+		sampled = str == MetadataTracingSampledTrue
+		headers.Sampled = &sampled
+	}
+
+	return tracing.StartSpanFromHeaders(ctx, name, headers)
+}
+
+// CreateOutgoingContextFromSpan injects the span info into the outgoing gRPC
+// metadata of the context object.
+//
+// If you are using the interceptors from BaseplateDefaultDialOptions,
+// it's already called for every call,
+// so there is no need to use it directly.
+func CreateOutgoingContextFromSpan(ctx context.Context, span *tracing.Span) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	md.Set(MetadataTracingTrace, strconv.FormatUint(span.TraceID(), 10))
+	md.Set(MetadataTracingSpan, strconv.FormatUint(span.ID(), 10))
+	md.Set(MetadataTracingFlags, strconv.FormatInt(span.Flags(), 10))
+	if span.ParentID() != 0 {
+		md.Set(MetadataTracingParent, strconv.FormatUint(span.ParentID(), 10))
+	} else {
+		delete(md, MetadataTracingParent)
+	}
+	if span.Sampled() {
+		md.Set(MetadataTracingSampled, MetadataTracingSampledTrue)
+	} else {
+		delete(md, MetadataTracingSampled)
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// methodName converts the full gRPC method name in the form of
+// "/package.service/method" into "package.service.method",
+// to be used as the span names.
+func methodName(fullMethod string) string {
+	return strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", -1)
+}
@@ -0,0 +1,279 @@
+package grpcbp
+
+import (
+	"context"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+var (
+	_ grpc.UnaryServerInterceptor  = InjectServerSpanUnary
+	_ grpc.StreamServerInterceptor = InjectServerSpanStream
+	_ grpc.UnaryServerInterceptor  = RecoverPanicUnary
+	_ grpc.StreamServerInterceptor = RecoverPanicStream
+)
+
+// BaseplateDefaultServerOptions returns the grpc.ServerOptions chaining the
+// default unary and stream server interceptors that should be used by a
+// baseplate gRPC service.
+//
+// It's the same as DefaultServerOptions with only EdgeContextImpl set.
+func BaseplateDefaultServerOptions(ecImpl *edgecontext.Impl) []grpc.ServerOption {
+	return DefaultServerOptions(DefaultServerOptionsArgs{
+		EdgeContextImpl: ecImpl,
+	})
+}
+
+// DefaultServerOptionsArgs are the args to be passed into
+// DefaultServerOptions function.
+type DefaultServerOptionsArgs struct {
+	EdgeContextImpl *edgecontext.Impl
+
+	// Logger, if non-nil, is attached to the request context object by
+	// InjectLoggerUnary/InjectLoggerStream, and used by the interceptors and
+	// log.FromContext instead of the global logger.
+	//
+	// Optional.
+	Logger *zap.SugaredLogger
+}
+
+// DefaultServerOptions returns the grpc.ServerOptions chaining the default
+// unary and stream server interceptors that should be used by a baseplate
+// gRPC service.
+//
+// Currently they are (in order):
+//
+// 1. InjectLoggerUnary/InjectLoggerStream (only when Logger is set)
+//
+// 2. InjectServerSpanUnary/InjectServerSpanStream
+//
+// 3. InjectEdgeContextUnary/InjectEdgeContextStream
+//
+// 4. RecoverPanicUnary/RecoverPanicStream
+//
+// Additional interceptors can be chained after them by passing
+// grpc.ChainUnaryInterceptor and grpc.ChainStreamInterceptor options into
+// grpc.NewServer after these options.
+func DefaultServerOptions(args DefaultServerOptionsArgs) []grpc.ServerOption {
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+	if args.Logger != nil {
+		unary = append(unary, InjectLoggerUnary(args.Logger))
+		stream = append(stream, InjectLoggerStream(args.Logger))
+	}
+	unary = append(
+		unary,
+		InjectServerSpanUnary,
+		InjectEdgeContextUnary(args.EdgeContextImpl),
+		RecoverPanicUnary,
+	)
+	stream = append(
+		stream,
+		InjectServerSpanStream,
+		InjectEdgeContextStream(args.EdgeContextImpl),
+		RecoverPanicStream,
+	)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+}
+
+// InjectLoggerUnary returns a grpc.UnaryServerInterceptor that attaches
+// logger to the context passed to the handler (see log.AttachLogger),
+// so the logs from the interceptors after it and log.FromContext in the
+// handlers go through logger instead of the global logger.
+func InjectLoggerUnary(logger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(log.AttachLogger(ctx, logger), req)
+	}
+}
+
+// InjectLoggerStream is the grpc.StreamServerInterceptor version of
+// InjectLoggerUnary.
+func InjectLoggerStream(logger *zap.SugaredLogger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, serverStream{
+			ServerStream: ss,
+			ctx:          log.AttachLogger(ss.Context(), logger),
+		})
+	}
+}
+
+// serverStream is a grpc.ServerStream with the context replaced.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// InjectServerSpanUnary is a grpc.UnaryServerInterceptor that injects a server
+// span into the context passed to the handler.
+//
+// The span is named after the full method name in the form of
+// "package.service.method",
+// and created according to the tracing related incoming metadata.
+// If the handler returns an error, that will be passed to span.Stop.
+func InjectServerSpanUnary(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	ctx, span := StartSpanFromIncomingContext(ctx, methodName(info.FullMethod))
+	defer func() {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}()
+
+	return handler(ctx, req)
+}
+
+// InjectServerSpanStream is the grpc.StreamServerInterceptor version of
+// InjectServerSpanUnary.
+//
+// The span covers the whole stream.
+func InjectServerSpanStream(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	ctx, span := StartSpanFromIncomingContext(ss.Context(), methodName(info.FullMethod))
+	defer func() {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}()
+
+	return handler(srv, serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// InitializeEdgeContext sets an edge request context created from the incoming
+// gRPC metadata on the context onto the context.
+func InitializeEdgeContext(ctx context.Context, impl *edgecontext.Impl) context.Context {
+	header, ok := getIncoming(ctx, MetadataEdgeRequest)
+	if !ok {
+		return ctx
+	}
+
+	ec, err := edgecontext.FromHeader(header, impl)
+	if err != nil {
+		log.FromContext(ctx).Errorw("Error while parsing EdgeRequestContext", "err", err)
+		return ctx
+	}
+	if ec == nil {
+		return ctx
+	}
+
+	return edgecontext.SetEdgeContext(ctx, ec)
+}
+
+// InjectEdgeContextUnary returns a grpc.UnaryServerInterceptor that injects an
+// edge request context created from the incoming gRPC metadata into the
+// context passed to the handler.
+func InjectEdgeContextUnary(impl *edgecontext.Impl) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(InitializeEdgeContext(ctx, impl), req)
+	}
+}
+
+// InjectEdgeContextStream is the grpc.StreamServerInterceptor version of
+// InjectEdgeContextUnary.
+func InjectEdgeContextStream(impl *edgecontext.Impl) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, serverStream{
+			ServerStream: ss,
+			ctx:          InitializeEdgeContext(ss.Context(), impl),
+		})
+	}
+}
+
+// errInternal is the error returned to the clients when a handler panics.
+//
+// It's intentionally generic to not leak any internal state to the clients.
+var errInternal = status.Error(codes.Internal, "internal error")
+
+// recoverPanic is the shared implementation of RecoverPanicUnary and
+// RecoverPanicStream, to be deferred.
+func recoverPanic(ctx context.Context, fullMethod string, err *error) {
+	if r := recover(); r != nil {
+		name := methodName(fullMethod)
+		log.FromContext(ctx).Errorw(
+			"recovered from panic in grpc handler",
+			"endpoint", name,
+			"panic", r,
+			"stack", string(debug.Stack()),
+		)
+		metricsbp.M.Counter("grpc." + name + ".panic").Add(1)
+		*err = errInternal
+	}
+}
+
+// RecoverPanicUnary is a grpc.UnaryServerInterceptor that recovers from panics
+// in the handler.
+//
+// When a panic happens, it logs the panic with the stack trace,
+// increments the "grpc.<package.service.method>.panic" counter on
+// metricsbp.M, and returns an error with codes.Internal status to the client
+// instead of crashing the whole server.
+// The panic value is never sent to the client.
+func RecoverPanicUnary(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	defer recoverPanic(ctx, info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+// RecoverPanicStream is the grpc.StreamServerInterceptor version of
+// RecoverPanicUnary.
+func RecoverPanicStream(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	defer recoverPanic(ss.Context(), info.FullMethod, &err)
+	return handler(srv, ss)
+}
@@ -0,0 +1,207 @@
+package httpbp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Span tags set by MonitorClient on the client spans,
+// and by InjectServerSpan on the server spans.
+const (
+	SpanTagKeyMethod     = "http.method"
+	SpanTagKeyHost       = "http.host"
+	SpanTagKeyStatusCode = "http.status_code"
+
+	// Only set on server spans.
+	SpanTagKeyPeerAddress = "peer.address"
+	SpanTagKeyProtocol    = "http.protocol"
+)
+
+// ClientMiddleware wraps the given http.RoundTripper and returns a new,
+// wrapped, http.RoundTripper.
+type ClientMiddleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as
+// http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ClientConfig is the configuration used by NewClient.
+type ClientConfig struct {
+	// Slug is a short identifier for the service the client is talking to.
+	//
+	// It's used as the name of the client spans and the prefix of the metrics.
+	// Required.
+	Slug string
+
+	// Transport is the base http.RoundTripper to be wrapped.
+	//
+	// Optional, defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Timeout is the timeout of the returned *http.Client.
+	//
+	// Optional, defaults to no timeout.
+	Timeout time.Duration
+
+	// TraceHeaderFormats are the formats of the span headers injected into
+	// the outgoing requests.
+	//
+	// Optional, defaults to TraceHeaderFormatBaseplate only.
+	TraceHeaderFormats []TraceHeaderFormat
+
+	// Additional ClientMiddlewares to be applied after the default ones.
+	Middlewares []ClientMiddleware
+}
+
+// BaseplateDefaultClientMiddlewares returns the default client middlewares that
+// should be used by a baseplate service.
+//
+// Currently they are (in order):
+//
+// 1. MonitorClient
+//
+// 2. ForwardEdgeRequestContext
+//
+// formats are passed to MonitorClient as-is.
+func BaseplateDefaultClientMiddlewares(slug string, formats ...TraceHeaderFormat) []ClientMiddleware {
+	return []ClientMiddleware{
+		MonitorClient(slug, formats...),
+		ForwardEdgeRequestContext,
+	}
+}
+
+// NewClient returns a new *http.Client with its Transport wrapped by
+// BaseplateDefaultClientMiddlewares and the additional middlewares from
+// ClientConfig.
+func NewClient(cfg ClientConfig) *http.Client {
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	defaults := BaseplateDefaultClientMiddlewares(cfg.Slug, cfg.TraceHeaderFormats...)
+	middlewares := make([]ClientMiddleware, 0, len(defaults)+len(cfg.Middlewares))
+	middlewares = append(middlewares, defaults...)
+	middlewares = append(middlewares, cfg.Middlewares...)
+	return &http.Client{
+		Transport: WrapTransport(transport, middlewares...),
+		Timeout:   cfg.Timeout,
+	}
+}
+
+// WrapTransport wraps the given http.RoundTripper with the given
+// ClientMiddlewares.
+//
+// Middlewares will be called in the order that they are defined:
+//
+//		1. Middlewares[0]
+//		2. Middlewares[1]
+//		...
+//		N. Middlewares[n]
+func WrapTransport(transport http.RoundTripper, middlewares ...ClientMiddleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+	return transport
+}
+
+// MonitorClient returns a ClientMiddleware that wraps every outgoing request in
+// a client span, and injects the span into the request headers.
+//
+// The span is named "<slug>.<method>", and tagged with the method, host, and
+// the status code of the response.
+//
+// It also reports the following metrics to metricsbp.M:
+//
+// - "<slug>.latency": timing of the requests.
+//
+// - "<slug>.status.<code>": counter of the responses by status code.
+//
+// - "<slug>.fail": counter of the requests failed without a response.
+//
+// The span is injected into the request headers in all the given formats.
+// When formats is empty, only TraceHeaderFormatBaseplate is used.
+func MonitorClient(slug string, formats ...TraceHeaderFormat) ClientMiddleware {
+	if len(formats) == 0 {
+		formats = []TraceHeaderFormat{TraceHeaderFormatBaseplate}
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
+			span, ctx := opentracing.StartSpanFromContext(
+				req.Context(),
+				slug+"."+req.Method,
+				tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+			)
+			span.SetTag(SpanTagKeyMethod, req.Method)
+			span.SetTag(SpanTagKeyHost, req.URL.Host)
+			timer := metricsbp.NewTimer(metricsbp.M.Timing(slug + ".latency"))
+			defer func() {
+				timer.ObserveDuration()
+				if err != nil {
+					metricsbp.M.Counter(slug + ".fail").Add(1)
+				} else {
+					span.SetTag(SpanTagKeyStatusCode, resp.StatusCode)
+					metricsbp.M.Counter(fmt.Sprintf("%s.status.%d", slug, resp.StatusCode)).Add(1)
+				}
+				span.FinishWithOptions(tracing.FinishOptions{
+					Ctx: ctx,
+					Err: err,
+				}.Convert())
+			}()
+
+			req = req.Clone(ctx)
+			injectSpanHeaders(ctx, req.Header, tracing.AsSpan(span), formats)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func setSpanHeaders(h http.Header, span *tracing.Span) {
+	h.Set(TraceIDHeader, span.TraceIDString())
+	h.Set(SpanIDHeader, strconv.FormatUint(span.ID(), 10))
+	h.Set(SpanFlagsHeader, strconv.FormatInt(span.Flags(), 10))
+	if span.ParentID() != 0 {
+		h.Set(ParentIDHeader, strconv.FormatUint(span.ParentID(), 10))
+	} else {
+		h.Del(ParentIDHeader)
+	}
+	if span.Sampled() {
+		h.Set(SpanSampledHeader, spanSampledTrue)
+	} else {
+		h.Del(SpanSampledHeader)
+	}
+}
+
+// ForwardEdgeRequestContext is a ClientMiddleware that forwards the
+// EdgeRequestContext set on the request context object to the service being
+// called, if one is set.
+func ForwardEdgeRequestContext(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if ec, ok := edgecontext.GetEdgeContext(req.Context()); ok {
+			req = req.Clone(req.Context())
+			req.Header.Set(
+				EdgeContextHeader,
+				base64.StdEncoding.EncodeToString([]byte(ec.Header())),
+			)
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+var (
+	_ http.RoundTripper = RoundTripperFunc(nil)
+	_ ClientMiddleware  = ForwardEdgeRequestContext
+)
@@ -0,0 +1,104 @@
+package httpbp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func TestNewClient(t *testing.T) {
+	defer func() {
+		tracing.CloseTracer()
+		tracing.InitGlobalTracer(tracing.TracerConfig{})
+	}()
+	mmq := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxQueueSize:   100,
+		MaxMessageSize: 1024,
+	})
+	logger, startFailing := tracing.TestWrapper(t)
+	tracing.InitGlobalTracer(tracing.TracerConfig{
+		SampleRate:               1,
+		MaxRecordTimeout:         testTimeout,
+		Logger:                   logger,
+		TestOnlyMockMessageQueue: mmq,
+	})
+	startFailing()
+
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusTeapot)
+		},
+	))
+	defer server.Close()
+
+	client := httpbp.NewClient(httpbp.ClientConfig{Slug: "test"})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("Expected status code %d, got %d", http.StatusTeapot, resp.StatusCode)
+	}
+
+	for _, h := range []string{
+		httpbp.TraceIDHeader,
+		httpbp.SpanIDHeader,
+		httpbp.SpanFlagsHeader,
+	} {
+		if received.Get(h) == "" {
+			t.Errorf("Expected header %q to be set, got %v", h, received)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	msg, err := mmq.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var span tracing.ZipkinSpan
+	if err := json.Unmarshal(msg, &span); err != nil {
+		t.Fatal(err)
+	}
+	if span.Name != "test.GET" {
+		t.Errorf("Expected span name %q, got %q", "test.GET", span.Name)
+	}
+	tags := make(map[string]string)
+	for _, annotation := range span.BinaryAnnotations {
+		if s, ok := annotation.Value.(string); ok {
+			tags[annotation.Key] = s
+		}
+	}
+	for k, v := range map[string]string{
+		httpbp.SpanTagKeyMethod:     "GET",
+		httpbp.SpanTagKeyHost:       strings.TrimPrefix(server.URL, "http://"),
+		httpbp.SpanTagKeyStatusCode: "418",
+	} {
+		if tags[k] != v {
+			t.Errorf("Expected tag %q to be %q, got %q", k, v, tags[k])
+		}
+	}
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	if str := buf.String(); !strings.Contains(str, "test.status.418:1.000000|c") {
+		t.Errorf("Expected status code counter in metrics, got %q", str)
+	}
+}
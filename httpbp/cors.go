@@ -0,0 +1,293 @@
+package httpbp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCORSAllowedMethods are the methods allowed by CORS when
+// CORSConfig.AllowedMethods is empty.
+var DefaultCORSAllowedMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+}
+
+// CORSConfig is the configuration of the CORS middleware.
+//
+// Can be deserialized from YAML,
+// usually as a part of the service's config file, for example:
+//
+//     type config struct {
+//       baseplate.Config `yaml:",inline"`
+//
+//       CORS httpbp.CORSConfig `yaml:"cors"`
+//     }
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests,
+	// e.g. "https://www.reddit.com".
+	//
+	// An origin can contain one "*" wildcard,
+	// e.g. "https://*.reddit.com" allows all the subdomains of reddit.com over
+	// https, and "*" alone allows all origins.
+	//
+	// The origins are matched case-insensitively.
+	//
+	// If it's empty, no cross-origin requests are allowed.
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+
+	// AllowedMethods are the methods allowed for the cross-origin requests.
+	//
+	// Optional, defaults to DefaultCORSAllowedMethods.
+	AllowedMethods []string `yaml:"allowedMethods"`
+
+	// AllowedHeaders are the non-simple request headers allowed for the
+	// cross-origin requests, "*" allows all headers.
+	//
+	// Optional, defaults to no non-simple headers allowed.
+	AllowedHeaders []string `yaml:"allowedHeaders"`
+
+	// ExposedHeaders are the response headers exposed to the browsers,
+	// in addition to the simple response headers.
+	//
+	// Optional.
+	ExposedHeaders []string `yaml:"exposedHeaders"`
+
+	// MaxAge is how long the results of the preflight requests can be cached
+	// by the browsers, in seconds precision.
+	//
+	// Optional, when it's 0 the Access-Control-Max-Age header is not sent and
+	// the browsers use their defaults.
+	MaxAge time.Duration `yaml:"maxAge"`
+
+	// AllowCredentials controls whether the cross-origin requests can include
+	// the user credentials, e.g. cookies.
+	//
+	// As "*" is not allowed as the Access-Control-Allow-Origin of the requests
+	// with credentials,
+	// the origin of the request is sent instead when AllowCredentials is true.
+	//
+	// It can't be used together with the "*" AllowedOrigins,
+	// as that would allow any origin to make requests with the user
+	// credentials.
+	//
+	// Optional, defaults to false.
+	AllowCredentials bool `yaml:"allowCredentials"`
+}
+
+// ErrCORSAllOriginsWithCredentials is the error returned by
+// CORSConfig.Validate when AllowCredentials is used together with the "*"
+// AllowedOrigins.
+var ErrCORSAllOriginsWithCredentials = errors.New(`httpbp: CORS AllowCredentials cannot be used with "*" AllowedOrigins`)
+
+// Validate checks CORSConfig for any erroneous values.
+func (cfg CORSConfig) Validate() error {
+	if !cfg.AllowCredentials {
+		return nil
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			return ErrCORSAllOriginsWithCredentials
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+//
+// It rejects the invalid configs, see Validate.
+func (cfg *CORSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain CORSConfig
+	if err := unmarshal((*plain)(cfg)); err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+// CORS returns a Middleware that handles Cross-Origin Resource Sharing (CORS)
+// according to cfg.
+//
+// The preflight requests (OPTIONS requests with the
+// Access-Control-Request-Method header) are responded with
+// http.StatusNoContent (204) directly without calling the `next` HandlerFunc.
+// When the origin, the method, or any of the headers of a preflight request is
+// not allowed, the response doesn't have any of the CORS headers,
+// so the browser blocks the actual request.
+//
+// For the actual cross-origin requests from the allowed origins,
+// the CORS headers are set on the response before calling the `next`
+// HandlerFunc.
+// The requests from the origins not allowed are still passed to the `next`
+// HandlerFunc, but without the CORS headers,
+// so the browsers don't expose the responses to the scripts.
+//
+// "Origin" is always added to the Vary header,
+// unless all the origins are allowed without credentials.
+//
+// cfg should be valid, see CORSConfig.Validate.
+// If it's not, the credentials are never allowed.
+//
+// To apply it to all the endpoints of the server, use ServerArgs.CORS.
+func CORS(cfg CORSConfig) Middleware {
+	c := newCORS(cfg)
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				c.handlePreflight(w, r)
+				return nil
+			}
+			c.handleActual(w, r)
+			return next(ctx, w, r)
+		}
+	}
+}
+
+type cors struct {
+	cfg CORSConfig
+
+	allowAllOrigins bool
+	allowAllHeaders bool
+	origins         []string
+	methods         map[string]bool
+	headers         map[string]bool
+	exposedHeaders  string
+	maxAge          string
+}
+
+func newCORS(cfg CORSConfig) *cors {
+	c := &cors{
+		cfg:     cfg,
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			c.allowAllOrigins = true
+		}
+		c.origins = append(c.origins, strings.ToLower(origin))
+	}
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSAllowedMethods
+	}
+	for _, method := range methods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+	for _, header := range cfg.AllowedHeaders {
+		if header == "*" {
+			c.allowAllHeaders = true
+		}
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	if c.allowAllOrigins {
+		// Never echo back any origin with the credentials allowed.
+		c.cfg.AllowCredentials = false
+	}
+	c.exposedHeaders = strings.Join(cfg.ExposedHeaders, ", ")
+	if seconds := int64(cfg.MaxAge / time.Second); seconds > 0 {
+		c.maxAge = strconv.FormatInt(seconds, 10)
+	}
+	return c
+}
+
+// originAllowed reports whether the origin is allowed.
+func (c *cors) originAllowed(origin string) bool {
+	if c.allowAllOrigins {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.origins {
+		if i := strings.IndexByte(allowed, '*'); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) &&
+				strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		} else if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// headersAllowed reports whether all the headers in the
+// Access-Control-Request-Headers header are allowed.
+func (c *cors) headersAllowed(requested string) bool {
+	if c.allowAllHeaders {
+		return true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !c.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+// setAllowOrigin sets the Access-Control-Allow-Origin and
+// Access-Control-Allow-Credentials headers.
+func (c *cors) setAllowOrigin(h http.Header, origin string) {
+	if c.allowAllOrigins && !c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// varyOrigin reports whether the response varies by the Origin header.
+func (c *cors) varyOrigin() bool {
+	return !c.allowAllOrigins || c.cfg.AllowCredentials
+}
+
+func (c *cors) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	if c.varyOrigin() {
+		h.Add("Vary", "Origin")
+	}
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	defer w.WriteHeader(http.StatusNoContent)
+
+	origin := r.Header.Get("Origin")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	requestedHeaders := strings.Join(r.Header.Values("Access-Control-Request-Headers"), ", ")
+	if origin == "" || !c.originAllowed(origin) || !c.methods[method] || !c.headersAllowed(requestedHeaders) {
+		return
+	}
+
+	c.setAllowOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", method)
+	if requestedHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", requestedHeaders)
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+}
+
+func (c *cors) handleActual(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	if c.varyOrigin() {
+		h.Add("Vary", "Origin")
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.originAllowed(origin) {
+		return
+	}
+	c.setAllowOrigin(h, origin)
+	if c.exposedHeaders != "" {
+		h.Set("Access-Control-Expose-Headers", c.exposedHeaders)
+	}
+}
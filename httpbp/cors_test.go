@@ -0,0 +1,273 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestCORSConfigYAML(t *testing.T) {
+	const body = `
+allowedOrigins:
+  - https://*.reddit.com
+allowedMethods:
+  - GET
+  - PUT
+allowedHeaders:
+  - X-Foo
+exposedHeaders:
+  - X-Bar
+maxAge: 10m
+allowCredentials: true
+`
+	expected := httpbp.CORSConfig{
+		AllowedOrigins:   []string{"https://*.reddit.com"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"X-Foo"},
+		ExposedHeaders:   []string{"X-Bar"},
+		MaxAge:           10 * time.Minute,
+		AllowCredentials: true,
+	}
+	var cfg httpbp.CORSConfig
+	if err := yaml.Unmarshal([]byte(body), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, cfg)
+	}
+}
+
+func TestCORSConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		body     string
+		expected error
+	}{
+		{
+			label: "all-origins",
+			body: `
+allowedOrigins:
+  - "*"
+`,
+		},
+		{
+			label: "credentials",
+			body: `
+allowedOrigins:
+  - https://*.reddit.com
+allowCredentials: true
+`,
+		},
+		{
+			label: "all-origins-credentials",
+			body: `
+allowedOrigins:
+  - https://www.reddit.com
+  - "*"
+allowCredentials: true
+`,
+			expected: httpbp.ErrCORSAllOriginsWithCredentials,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var cfg httpbp.CORSConfig
+			err := yaml.Unmarshal([]byte(c.body), &cfg)
+			if !errors.Is(err, c.expected) {
+				t.Errorf("Expected error %v, got %v", c.expected, err)
+			}
+		})
+	}
+
+	t.Run("server", func(t *testing.T) {
+		store, dir := newSecretsStore(t)
+		defer func() {
+			os.RemoveAll(dir)
+			store.Close()
+		}()
+
+		_, err := httpbp.ServerArgs{
+			Baseplate: baseplate.NewTestBaseplate(baseplate.Config{}, store),
+			CORS: &httpbp.CORSConfig{
+				AllowedOrigins:   []string{"*"},
+				AllowCredentials: true,
+			},
+		}.ValidateAndSetDefaults()
+		if !errors.Is(err, httpbp.ErrCORSAllOriginsWithCredentials) {
+			t.Errorf("Expected error %v, got %v", httpbp.ErrCORSAllOriginsWithCredentials, err)
+		}
+	})
+}
+
+func TestCORS(t *testing.T) {
+	cfg := httpbp.CORSConfig{
+		AllowedOrigins: []string{"https://www.reddit.com", "https://*.redditmedia.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders: []string{"X-Foo"},
+		ExposedHeaders: []string{"X-Bar", "X-Baz"},
+		MaxAge:         time.Minute,
+	}
+
+	for _, c := range []struct {
+		label    string
+		cfg      httpbp.CORSConfig
+		method   string
+		header   http.Header
+		called   bool
+		expected map[string]string
+	}{
+		{
+			label:  "no-origin",
+			cfg:    cfg,
+			method: http.MethodGet,
+			called: true,
+			expected: map[string]string{
+				"Vary":                        "Origin",
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			label:  "actual",
+			cfg:    cfg,
+			method: http.MethodGet,
+			header: http.Header{"Origin": {"https://www.reddit.com"}},
+			called: true,
+			expected: map[string]string{
+				"Vary":                          "Origin",
+				"Access-Control-Allow-Origin":   "https://www.reddit.com",
+				"Access-Control-Expose-Headers": "X-Bar, X-Baz",
+			},
+		},
+		{
+			label:  "actual-wildcard",
+			cfg:    cfg,
+			method: http.MethodGet,
+			header: http.Header{"Origin": {"https://b.RedditMedia.com"}},
+			called: true,
+			expected: map[string]string{
+				"Access-Control-Allow-Origin": "https://b.RedditMedia.com",
+			},
+		},
+		{
+			label:  "actual-not-allowed",
+			cfg:    cfg,
+			method: http.MethodGet,
+			header: http.Header{"Origin": {"https://evil.com"}},
+			called: true,
+			expected: map[string]string{
+				"Vary":                          "Origin",
+				"Access-Control-Allow-Origin":   "",
+				"Access-Control-Expose-Headers": "",
+			},
+		},
+		{
+			label:  "preflight",
+			cfg:    cfg,
+			method: http.MethodOptions,
+			header: http.Header{
+				"Origin":                         {"https://www.reddit.com"},
+				"Access-Control-Request-Method":  {"PUT"},
+				"Access-Control-Request-Headers": {"x-foo"},
+			},
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":  "https://www.reddit.com",
+				"Access-Control-Allow-Methods": "PUT",
+				"Access-Control-Allow-Headers": "x-foo",
+				"Access-Control-Max-Age":       "60",
+			},
+		},
+		{
+			label:  "preflight-method-not-allowed",
+			cfg:    cfg,
+			method: http.MethodOptions,
+			header: http.Header{
+				"Origin":                        {"https://www.reddit.com"},
+				"Access-Control-Request-Method": {"DELETE"},
+			},
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			label:  "preflight-header-not-allowed",
+			cfg:    cfg,
+			method: http.MethodOptions,
+			header: http.Header{
+				"Origin":                         {"https://www.reddit.com"},
+				"Access-Control-Request-Method":  {"GET"},
+				"Access-Control-Request-Headers": {"X-Foo, X-Other"},
+			},
+			expected: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			label: "all-origins",
+			cfg: httpbp.CORSConfig{
+				AllowedOrigins: []string{"*"},
+			},
+			method: http.MethodGet,
+			header: http.Header{"Origin": {"https://foo.com"}},
+			called: true,
+			expected: map[string]string{
+				"Vary":                        "",
+				"Access-Control-Allow-Origin": "*",
+			},
+		},
+		{
+			label: "all-origins-credentials",
+			cfg: httpbp.CORSConfig{
+				AllowedOrigins:   []string{"*"},
+				AllowCredentials: true,
+			},
+			method: http.MethodGet,
+			header: http.Header{"Origin": {"https://foo.com"}},
+			called: true,
+			expected: map[string]string{
+				"Vary":                             "",
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var called bool
+			handler := httpbp.NewHandler(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					called = true
+					return nil
+				},
+				httpbp.CORS(c.cfg),
+			)
+			r := httptest.NewRequest(c.method, "/", nil)
+			for k, v := range c.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if called != c.called {
+				t.Errorf("Expected next called to be %v, got %v", c.called, called)
+			}
+			if !c.called && w.Code != http.StatusNoContent {
+				t.Errorf("Expected preflight status %d, got %d", http.StatusNoContent, w.Code)
+			}
+			for k, v := range c.expected {
+				if actual := w.Header().Get(k); actual != v {
+					t.Errorf("Expected header %s to be %q, got %q", k, v, actual)
+				}
+			}
+		})
+	}
+}
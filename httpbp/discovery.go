@@ -0,0 +1,52 @@
+package httpbp
+
+import (
+	"net/http"
+
+	"github.com/reddit/baseplate.go/discovery"
+)
+
+// ServiceDiscovery returns a ClientMiddleware that sends every request to a
+// backend picked from the discovery.Watcher,
+// by replacing the host of the request URL with the address of the backend.
+//
+// The Host header of the request is kept,
+// so the request URL can still use the name of the service.
+//
+// The results of the requests are reported to the watcher for its health
+// accounting: requests failed without a response or with a 502, 503,
+// or 504 response are reported as failures of the backend.
+//
+// When used together with Retry, it should come after Retry so that every
+// attempt picks a new backend.
+func ServiceDiscovery(watcher *discovery.Watcher) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			backend, err := watcher.Pick()
+			if err != nil {
+				return nil, err
+			}
+			addr := backend.Addr()
+
+			req = req.Clone(req.Context())
+			if req.Host == "" {
+				req.Host = req.URL.Host
+			}
+			req.URL.Host = addr
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				watcher.ReportFailure(addr)
+				return nil, err
+			}
+			switch resp.StatusCode {
+			default:
+				watcher.ReportSuccess(addr)
+			case http.StatusBadGateway,
+				http.StatusServiceUnavailable,
+				http.StatusGatewayTimeout:
+				watcher.ReportFailure(addr)
+			}
+			return resp, nil
+		})
+	}
+}
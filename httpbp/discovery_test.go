@@ -0,0 +1,83 @@
+package httpbp_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/discovery"
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestServiceDiscovery(t *testing.T) {
+	hosts := make(chan string, 1)
+	status := int32(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			hosts <- r.Host
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+		},
+	))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "httpbp_discovery_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	path := filepath.Join(dir, "endpoints.json")
+	content := fmt.Sprintf(`[{"host": %q, "port": %d}]`, addr.IP.String(), addr.Port)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	watcher, err := discovery.New(ctx, discovery.Config{
+		Path:             path,
+		FailureThreshold: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	client := &http.Client{
+		Transport: httpbp.WrapTransport(
+			http.DefaultTransport,
+			httpbp.ServiceDiscovery(watcher),
+		),
+	}
+
+	resp, err := client.Get("http://service.local/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if host := <-hosts; host != "service.local" {
+		t.Errorf("Expected Host header %q, got %q", "service.local", host)
+	}
+	if watcher.Ejected(addr.String()) {
+		t.Error("Expected backend not to be ejected after success")
+	}
+
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	resp, err = client.Get("http://service.local/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	<-hosts
+	if !watcher.Ejected(addr.String()) {
+		t.Error("Expected backend to be ejected after 503 response")
+	}
+}
@@ -1,6 +1,7 @@
 package httpbp
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -295,6 +296,20 @@ func RawError(resp *ErrorResponse, cause error, contentType string) HTTPError {
 	return newHTTPError(resp.code, resp, cause, RawContentWriter(contentType))
 }
 
+// WriteError writes err to w as a standard Baseplate error response.
+//
+// If err is or wraps an HTTPError, its Response is written using its
+// ContentWriter.
+// Otherwise err is written as a JSONError with InternalServerError,
+// without exposing the message of err to the client.
+func WriteError(w http.ResponseWriter, err error) error {
+	var httpErr HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = JSONError(InternalServerError(), err)
+	}
+	return WriteResponse(w, httpErr.ContentWriter(), httpErr.Response())
+}
+
 // RegisterDefaultErrorTemplate adds the default HTML template for error pages to the
 // given templates and returns the result.
 //
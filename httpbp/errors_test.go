@@ -3,6 +3,7 @@ package httpbp_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
@@ -437,3 +438,69 @@ func TestRetryable(t *testing.T) {
 		)
 	}
 }
+
+func TestWriteError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedBody httpbp.ErrorResponseJSONWrapper
+	}{
+		{
+			name:         "http-error",
+			err:          httpbp.JSONError(httpbp.BadRequest(), errors.New("bad")),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: httpbp.ErrorResponseJSONWrapper{
+				Error: httpbp.BadRequest(),
+			},
+		},
+		{
+			name: "wrapped-http-error",
+			err: fmt.Errorf(
+				"wrapped: %w",
+				httpbp.JSONError(httpbp.NotFound(), errors.New("missing")),
+			),
+			expectedCode: http.StatusNotFound,
+			expectedBody: httpbp.ErrorResponseJSONWrapper{
+				Error: httpbp.NotFound(),
+			},
+		},
+		{
+			name:         "plain-error",
+			err:          errors.New("secret error message"),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: httpbp.ErrorResponseJSONWrapper{
+				Error: httpbp.InternalServerError(),
+			},
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			if err := httpbp.WriteError(w, c.err); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != c.expectedCode {
+				t.Errorf("Expected status code %d, got %d", c.expectedCode, w.Code)
+			}
+			if contentType := w.Header().Get(httpbp.ContentTypeHeader); contentType != httpbp.JSONContentType {
+				t.Errorf("Expected content type %q, got %q", httpbp.JSONContentType, contentType)
+			}
+			var body httpbp.ErrorResponseJSONWrapper
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error == nil {
+				t.Fatal("Expected error in the response body, got nil")
+			}
+			if body.Success || body.Error.Reason != c.expectedBody.Error.Reason || body.Error.Explanation != c.expectedBody.Error.Explanation {
+				t.Errorf("Expected body %+v, got %+v", c.expectedBody.Error, body.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package httpbp
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// Default values used by Gzip.
+const (
+	DefaultGzipMinSize = 1024
+	DefaultGzipLevel   = gzip.DefaultCompression
+)
+
+// DefaultGzipContentTypes are the content types compressed by Gzip when
+// GzipArgs.ContentTypes is empty.
+var DefaultGzipContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// GzipArgs are the args of Gzip.
+type GzipArgs struct {
+	// ContentTypes is the allowlist of the content types of the responses to
+	// be compressed.
+	//
+	// An entry ending with "/" (e.g. "text/") matches all the subtypes of the
+	// type,
+	// other entries match the media type exactly, ignoring the parameters
+	// (e.g. "application/json" matches "application/json; charset=utf-8").
+	//
+	// Optional, defaults to DefaultGzipContentTypes.
+	ContentTypes []string
+
+	// MinSize is the minimal size in bytes of the response body to be
+	// compressed,
+	// as the smaller responses are usually not worth the overhead.
+	//
+	// Optional, defaults to DefaultGzipMinSize.
+	MinSize int
+
+	// Level is the compression level, see compress/gzip.
+	//
+	// Optional, defaults to DefaultGzipLevel.
+	Level int
+}
+
+// Gzip returns a Middleware that compresses the response bodies with gzip for
+// the clients accepting it.
+//
+// A response is compressed when all of the following are true:
+//
+// 1. The request has "gzip" in its Accept-Encoding header.
+//
+// 2. The response doesn't already have a Content-Encoding header.
+//
+// 3. The Content-Type of the response matches GzipArgs.ContentTypes.
+// When the Content-Type header is not set by the `next` HandlerFunc,
+// it's detected by http.DetectContentType.
+//
+// 4. The response body is at least GzipArgs.MinSize bytes,
+// or the `next` HandlerFunc flushes the response.
+//
+// For all the responses with the matching content types,
+// "Accept-Encoding" is added to the Vary header,
+// including the ones not compressed,
+// so that the caches don't serve the compressed responses to the clients not
+// accepting them, or vice versa.
+//
+// Up to MinSize bytes of the response body are buffered to make the decision.
+// When the `next` HandlerFunc returns an error before writing anything,
+// nothing is written so the error response can still be written.
+//
+// The number of the compressed responses and the bytes saved by the
+// compression are reported to the "http.<name>.gzip.responses" and
+// "http.<name>.gzip.saved-bytes" counters on metricsbp.M.
+func Gzip(args GzipArgs) Middleware {
+	if len(args.ContentTypes) == 0 {
+		args.ContentTypes = DefaultGzipContentTypes
+	}
+	if args.MinSize <= 0 {
+		args.MinSize = DefaultGzipMinSize
+	}
+	if args.Level == 0 {
+		args.Level = DefaultGzipLevel
+	}
+	pool := &sync.Pool{
+		New: func() interface{} {
+			// The only possible error is invalid level, in which case we fallback
+			// to the default level.
+			gw, err := gzip.NewWriterLevel(nil, args.Level)
+			if err != nil {
+				gw = gzip.NewWriter(nil)
+			}
+			return gw
+		},
+	}
+
+	return func(name string, next HandlerFunc) HandlerFunc {
+		responses := metricsbp.M.Counter("http." + name + ".gzip.responses")
+		savedBytes := metricsbp.M.Counter("http." + name + ".gzip.saved-bytes")
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if !acceptsGzip(r.Header) {
+				// We still need to set the Vary header for the matching content types.
+				return next(ctx, &varyResponseWriter{
+					ResponseWriter: w,
+					contentTypes:   args.ContentTypes,
+				}, r)
+			}
+
+			gw := &gzipResponseWriter{
+				ResponseWriter: w,
+				args:           args,
+				pool:           pool,
+			}
+			err := next(ctx, gw, r)
+			if err != nil && !gw.wroteHeader && len(gw.buf) == 0 {
+				return err
+			}
+			if closeErr := gw.close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+			if gw.compressed {
+				responses.Add(1)
+				savedBytes.Add(float64(gw.written - gw.compressedSize))
+			}
+			return err
+		}
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header accepts gzip.
+func acceptsGzip(h http.Header) bool {
+	var gzipQ, anyQ string
+	var hasGzip, hasAny bool
+	for _, value := range h.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			coding, params, _ := mime.ParseMediaType("x/" + strings.TrimSpace(part))
+			switch strings.TrimPrefix(coding, "x/") {
+			case "gzip":
+				hasGzip = true
+				gzipQ = params["q"]
+			case "*":
+				hasAny = true
+				anyQ = params["q"]
+			}
+		}
+	}
+	if hasGzip {
+		return nonZeroQ(gzipQ)
+	}
+	return hasAny && nonZeroQ(anyQ)
+}
+
+func nonZeroQ(q string) bool {
+	if q == "" {
+		return true
+	}
+	v, err := strconv.ParseFloat(q, 64)
+	return err == nil && v > 0
+}
+
+// gzipContentTypeMatches reports whether the content type matches the
+// allowlist.
+func gzipContentTypeMatches(contentType string, allowlist []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range allowlist {
+		if strings.HasSuffix(allowed, "/") {
+			if strings.HasPrefix(mediaType, allowed) {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// addVary adds "Accept-Encoding" to the Vary header if it's not already there.
+func addVary(h http.Header) {
+	for _, value := range h.Values("Vary") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "*" || strings.EqualFold(part, "Accept-Encoding") {
+				return
+			}
+		}
+	}
+	h.Add("Vary", "Accept-Encoding")
+}
+
+// varyResponseWriter is the http.ResponseWriter used by Gzip for the requests
+// not accepting gzip, which only adds the Vary header.
+type varyResponseWriter struct {
+	http.ResponseWriter
+
+	contentTypes []string
+	wroteHeader  bool
+}
+
+func (w *varyResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader && !isInformational(code) {
+		w.wroteHeader = true
+		h := w.Header()
+		if h.Get("Content-Encoding") == "" && gzipContentTypeMatches(h.Get("Content-Type"), w.contentTypes) {
+			addVary(h)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *varyResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *varyResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// gzipResponseWriter is the http.ResponseWriter used by Gzip for the requests
+// accepting gzip.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+
+	args GzipArgs
+	pool *sync.Pool
+
+	// The status code written by the `next` HandlerFunc.
+	code        int
+	wroteHeader bool
+	// The buffered response body before decided.
+	buf []byte
+	// Whether the decision is made, and the header is written to
+	// ResponseWriter.
+	decided    bool
+	compressed bool
+	gw         *gzip.Writer
+	counter    *countingWriter
+
+	// The number of bytes written by the `next` HandlerFunc.
+	written int64
+	// The number of compressed bytes written to ResponseWriter.
+	compressedSize int64
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if isInformational(code) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+	if !bodyAllowedForStatus(code) {
+		w.decide(false)
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.written += int64(len(p))
+	if w.decided {
+		return w.write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.args.MinSize {
+		if err := w.decideAndWriteBuffer(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush implements http.Flusher.
+//
+// When the decision is not made yet,
+// the response is compressed regardless of the MinSize.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		w.decideAndWriteBuffer(true)
+	}
+	if w.gw != nil {
+		w.gw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close writes the buffered response body if the decision is not made yet,
+// and finishes the gzip stream.
+func (w *gzipResponseWriter) close() error {
+	if !w.decided {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := w.decideAndWriteBuffer(false); err != nil {
+			return err
+		}
+	}
+	if w.gw == nil {
+		return nil
+	}
+	err := w.gw.Close()
+	w.compressedSize = w.counter.n
+	w.gw.Reset(nil)
+	w.pool.Put(w.gw)
+	w.gw = nil
+	return err
+}
+
+func (w *gzipResponseWriter) decideAndWriteBuffer(compress bool) error {
+	if w.decided {
+		return nil
+	}
+	w.decide(compress && len(w.buf) > 0)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+// decide decides whether to compress the response and writes the header.
+func (w *gzipResponseWriter) decide(compress bool) {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if h.Get("Content-Encoding") == "" && gzipContentTypeMatches(h.Get("Content-Type"), w.args.ContentTypes) {
+		addVary(h)
+	} else {
+		compress = false
+	}
+	if compress {
+		w.compressed = true
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.counter = &countingWriter{w: w.ResponseWriter}
+		w.gw = w.pool.Get().(*gzip.Writer)
+		w.gw.Reset(w.counter)
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
+
+func (w *gzipResponseWriter) write(p []byte) (int, error) {
+	if w.gw != nil {
+		return w.gw.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// isInformational reports whether the status code is 1xx,
+// which can be written multiple times before the final status code.
+func isInformational(code int) bool {
+	return code >= 100 && code <= 199
+}
+
+// bodyAllowedForStatus reports whether a response with the (final) status code
+// can have a body.
+func bodyAllowedForStatus(code int) bool {
+	return code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+var (
+	_ http.Flusher = (*gzipResponseWriter)(nil)
+	_ http.Flusher = (*varyResponseWriter)(nil)
+)
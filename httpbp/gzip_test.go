@@ -0,0 +1,146 @@
+package httpbp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+)
+
+func TestGzip(t *testing.T) {
+	const minSize = 100
+	large := strings.Repeat(`{"foo": "bar"}`, 100)
+	small := `{"foo": "bar"}`
+
+	for _, c := range []struct {
+		label          string
+		acceptEncoding string
+		contentType    string
+		body           string
+		err            error
+		compressed     bool
+		vary           bool
+	}{
+		{
+			label:          "compressed",
+			acceptEncoding: "deflate, gzip",
+			contentType:    "application/json; charset=utf-8",
+			body:           large,
+			compressed:     true,
+			vary:           true,
+		},
+		{
+			label:          "detected-content-type",
+			acceptEncoding: "gzip",
+			body:           strings.Repeat("foo", 100),
+			compressed:     true,
+			vary:           true,
+		},
+		{
+			label:          "too-small",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           small,
+			vary:           true,
+		},
+		{
+			label:       "not-accepted",
+			contentType: "application/json",
+			body:        large,
+			vary:        true,
+		},
+		{
+			label:          "q=0",
+			acceptEncoding: "gzip;q=0, *",
+			contentType:    "application/json",
+			body:           large,
+			vary:           true,
+		},
+		{
+			label:          "content-type-not-allowed",
+			acceptEncoding: "gzip",
+			contentType:    "image/png",
+			body:           large,
+		},
+		{
+			label:          "error",
+			acceptEncoding: "gzip",
+			err:            httpbp.JSONError(httpbp.BadRequest(), nil),
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			recorder := metricstest.Replace(t)
+			handler := httpbp.NewHandler(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					if c.err != nil {
+						return c.err
+					}
+					if c.contentType != "" {
+						w.Header().Set("Content-Type", c.contentType)
+					}
+					// Write in multiple chunks.
+					for i := 0; i < len(c.body); i += 10 {
+						end := i + 10
+						if end > len(c.body) {
+							end = len(c.body)
+						}
+						w.Write([]byte(c.body[i:end]))
+					}
+					return nil
+				},
+				httpbp.Gzip(httpbp.GzipArgs{MinSize: minSize}),
+			)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", c.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if c.err != nil {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected the error response, got %d", w.Code)
+				}
+				if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+					t.Errorf("Expected no Content-Encoding, got %q", encoding)
+				}
+				return
+			}
+
+			if vary := w.Header().Get("Vary"); (vary == "Accept-Encoding") != c.vary {
+				t.Errorf("Expected Vary header set to be %v, got %q", c.vary, vary)
+			}
+			body := w.Body.Bytes()
+			encoding := w.Header().Get("Content-Encoding")
+			if c.compressed {
+				if encoding != "gzip" {
+					t.Fatalf("Expected gzip Content-Encoding, got %q", encoding)
+				}
+				reader, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, err = ioutil.ReadAll(reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				recorder.AssertCounterEquals(t, "http.test.gzip.responses", 1)
+				saved := float64(len(c.body) - w.Body.Len())
+				recorder.AssertCounterEquals(t, "http.test.gzip.saved-bytes", saved)
+			} else if encoding != "" {
+				t.Errorf("Expected no Content-Encoding, got %q", encoding)
+			}
+			if string(body) != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, body)
+			}
+		})
+	}
+}
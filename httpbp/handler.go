@@ -41,7 +41,7 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
-		log.Error("Unhandled server error: " + err.Error())
+		log.Named("httpbp").Error("Unhandled server error: " + err.Error())
 		code := http.StatusInternalServerError
 		http.Error(w, http.StatusText(code), code)
 	}
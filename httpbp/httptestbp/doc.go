@@ -0,0 +1,26 @@
+// Package httptestbp provides a test server harness for httpbp handlers.
+//
+// It serves the endpoints under test with an httptest.Server wrapped with the
+// Baseplate default middlewares,
+// records the spans and metrics produced while handling the requests,
+// and creates fake edge request contexts signed with a test key accepted by
+// the server.
+//
+// A typical test looks like:
+//
+//     func TestMyEndpoint(t *testing.T) {
+//       server := httptestbp.NewServer(t, httptestbp.ServerConfig{
+//         Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+//           "/foo": {Name: "foo", Handle: myHandler},
+//         },
+//       })
+//       req := server.NewRequest(t, http.MethodGet, "/foo", nil)
+//       httptestbp.SetEdgeContext(req, server.NewEdgeContext(t, httptestbp.EdgeContextArgs{
+//         UserID: "t2_example",
+//       }))
+//       resp, err := server.Client().Do(req)
+//       // Assert on resp and err...
+//       server.Spans.MustFindSpan(t, "foo")
+//       server.Metrics.AssertCounterEquals(t, "my-counter", 1)
+//     }
+package httptestbp
@@ -0,0 +1,121 @@
+package httptestbp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "gopkg.in/dgrijalva/jwt-go.v3"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+// The key used to sign the authentication tokens of the fake edge request
+// contexts, generated once as it's slow.
+var (
+	keyOnce sync.Once
+	key     *rsa.PrivateKey
+	keyErr  error
+)
+
+func signingKey(tb testing.TB) *rsa.PrivateKey {
+	tb.Helper()
+
+	keyOnce.Do(func() {
+		key, keyErr = rsa.GenerateKey(rand.Reader, 2048)
+	})
+	if keyErr != nil {
+		tb.Fatalf("httptestbp: failed to generate signing key: %v", keyErr)
+	}
+	return key
+}
+
+// publicKeyPEM returns the public key of the signing key in PEM format.
+func publicKeyPEM(tb testing.TB) string {
+	tb.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(&signingKey(tb).PublicKey)
+	if err != nil {
+		tb.Fatalf("httptestbp: failed to marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	}))
+}
+
+// EdgeContextArgs are the args for Server.NewEdgeContext.
+//
+// All fields are optional.
+type EdgeContextArgs struct {
+	// When UserID is non-empty, the edge request context has an authentication
+	// token with UserID as the subject, and Roles as the roles.
+	UserID string
+	Roles  []string
+
+	// When OAuthClientID is non-empty, the authentication token also has the
+	// OAuth client.
+	OAuthClientID   string
+	OAuthClientType string
+
+	// The other fields are passed to edgecontext.NewArgs as-is.
+	LoID              string
+	LoIDCreatedAt     time.Time
+	SessionID         string
+	DeviceID          string
+	OriginServiceName string
+}
+
+// NewEdgeContext creates a fake edge request context,
+// with an authentication token signed by a test key accepted by the server.
+func (s *Server) NewEdgeContext(tb testing.TB, args EdgeContextArgs) *edgecontext.EdgeRequestContext {
+	tb.Helper()
+
+	var token string
+	if args.UserID != "" || args.OAuthClientID != "" {
+		claims := edgecontext.AuthenticationToken{
+			StandardClaims: jwt.StandardClaims{
+				Subject:   args.UserID,
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+			Roles:           args.Roles,
+			OAuthClientID:   args.OAuthClientID,
+			OAuthClientType: args.OAuthClientType,
+		}
+		var err error
+		token, err = jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(signingKey(tb))
+		if err != nil {
+			tb.Fatalf("httptestbp: failed to sign authentication token: %v", err)
+		}
+	}
+
+	ec, err := edgecontext.New(context.Background(), s.Baseplate.EdgeContextImpl(), edgecontext.NewArgs{
+		LoID:              args.LoID,
+		LoIDCreatedAt:     args.LoIDCreatedAt,
+		SessionID:         args.SessionID,
+		DeviceID:          args.DeviceID,
+		AuthToken:         token,
+		OriginServiceName: args.OriginServiceName,
+	})
+	if err != nil {
+		tb.Fatalf("httptestbp: failed to create edge request context: %v", err)
+	}
+	return ec
+}
+
+// SetEdgeContext sets the header of the edge request context on the request,
+// the same way as httpbp.ForwardEdgeRequestContext.
+func SetEdgeContext(req *http.Request, ec *edgecontext.EdgeRequestContext) {
+	req.Header.Set(
+		httpbp.EdgeContextHeader,
+		base64.StdEncoding.EncodeToString([]byte(ec.Header())),
+	)
+}
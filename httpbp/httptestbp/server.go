@@ -0,0 +1,134 @@
+package httptestbp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/secrets"
+	"github.com/reddit/baseplate.go/secrets/secretstest"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+// The secret path edgecontext loads the authentication public key from.
+const authenticationPublicKeyPath = "secret/authentication/public-key"
+
+// ServerConfig is the arg struct for NewServer.
+type ServerConfig struct {
+	// The endpoints under test.
+	Endpoints map[httpbp.Pattern]httpbp.Endpoint
+
+	// Optional, additional middlewares to be applied after
+	// httpbp.DefaultMiddleware.
+	Middlewares []httpbp.Middleware
+
+	// Optional, the HeaderTrustHandler used by the default middlewares.
+	//
+	// Default to httpbp.AlwaysTrustHeaders,
+	// so that the spans and edge request contexts set on the requests by the
+	// test are honored.
+	TrustHandler httpbp.HeaderTrustHandler
+}
+
+// Server is a test server started by NewServer.
+type Server struct {
+	*httptest.Server
+
+	// Spans records the spans produced by the server,
+	// and any other spans produced during the test.
+	Spans *tracingtest.Recorder
+
+	// Metrics records the metrics produced by the server,
+	// and any other metrics produced during the test.
+	Metrics *metricstest.Recorder
+
+	// Baseplate is the baseplate.Baseplate used by the server.
+	Baseplate baseplate.Baseplate
+}
+
+// NewServer starts a test server serving cfg.Endpoints.
+//
+// The server is created by httpbp.NewTestBaseplateServer,
+// with the Baseplate default middlewares and cfg.Middlewares.
+//
+// NewServer replaces the global tracer and metricsbp.M with recorders for the
+// duration of the test, so it cannot be used by parallel tests.
+//
+// The server will be stopped when the test finishes.
+func NewServer(tb testing.TB, cfg ServerConfig) *Server {
+	tb.Helper()
+
+	spans := tracingtest.InitGlobalTracer(tb)
+	metrics := metricstest.Replace(tb)
+	store := newSecretsStore(tb)
+
+	trustHandler := cfg.TrustHandler
+	if trustHandler == nil {
+		trustHandler = httpbp.AlwaysTrustHeaders{}
+	}
+	bp := baseplate.NewTestBaseplate(baseplate.Config{}, store)
+	server, ts, err := httpbp.NewTestBaseplateServer(httpbp.ServerArgs{
+		Baseplate:    bp,
+		Endpoints:    cfg.Endpoints,
+		Middlewares:  cfg.Middlewares,
+		TrustHandler: trustHandler,
+	})
+	if err != nil {
+		tb.Fatalf("httptestbp: failed to create server: %v", err)
+	}
+	tb.Cleanup(func() {
+		server.Close()
+	})
+
+	return &Server{
+		Server:    ts,
+		Spans:     spans,
+		Metrics:   metrics,
+		Baseplate: bp,
+	}
+}
+
+// NewRequest creates a new request to path on the server.
+//
+// The request has the span headers of a new sampled trace set,
+// as a server span started without an upstream sampled trace would not be
+// recorded.
+func (s *Server) NewRequest(tb testing.TB, method, path string, body io.Reader) *http.Request {
+	tb.Helper()
+
+	req, err := http.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		tb.Fatalf("httptestbp: failed to create request: %v", err)
+	}
+	req.Header.Set(httpbp.TraceIDHeader, strconv.FormatUint(randUint64(), 10))
+	req.Header.Set(httpbp.SpanIDHeader, strconv.FormatUint(randUint64(), 10))
+	req.Header.Set(httpbp.SpanSampledHeader, "1")
+	return req
+}
+
+// randUint64 returns a non-zero random uint64 to be used as trace and span ids.
+func randUint64() uint64 {
+	for {
+		if id := randbp.R.Uint64(); id != 0 {
+			return id
+		}
+	}
+}
+
+// newSecretsStore creates a secrets store with the public key used to verify
+// the fake edge request contexts created by Server.NewEdgeContext.
+func newSecretsStore(tb testing.TB) *secrets.Store {
+	tb.Helper()
+
+	store := secretstest.NewStore(tb)
+	store.SetVersionedSecret(tb, authenticationPublicKeyPath, secrets.VersionedSecret{
+		Current: secrets.Secret(publicKeyPEM(tb)),
+	})
+	return store.Store
+}
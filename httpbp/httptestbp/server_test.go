@@ -0,0 +1,76 @@
+package httptestbp_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/httpbp/httptestbp"
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+func TestServer(t *testing.T) {
+	var userID string
+	server := httptestbp.NewServer(t, httptestbp.ServerConfig{
+		Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+			"/foo": {
+				Name: "foo",
+				Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					if ec, ok := edgecontext.GetEdgeContext(ctx); ok {
+						userID, _ = ec.User().ID()
+					}
+					metricsbp.M.Counter("foo.called").Add(1)
+					w.WriteHeader(http.StatusNoContent)
+					return nil
+				},
+			},
+		},
+	})
+
+	const expectedUserID = "t2_example"
+	req := server.NewRequest(t, http.MethodGet, "/foo", nil)
+	httptestbp.SetEdgeContext(req, server.NewEdgeContext(t, httptestbp.EdgeContextArgs{
+		UserID: expectedUserID,
+	}))
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if userID != expectedUserID {
+		t.Errorf("Expected user id %q from the edge request context, got %q", expectedUserID, userID)
+	}
+	server.Spans.MustFindSpan(t, "foo")
+	server.Metrics.AssertCounterEquals(t, "foo.called", 1)
+}
+
+func TestServerNoEdgeContext(t *testing.T) {
+	var ok bool
+	server := httptestbp.NewServer(t, httptestbp.ServerConfig{
+		Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+			"/foo": {
+				Name: "foo",
+				Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					_, ok = edgecontext.GetEdgeContext(ctx)
+					return nil
+				},
+			},
+		},
+	})
+
+	resp, err := server.Client().Do(server.NewRequest(t, http.MethodGet, "/foo", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if ok {
+		t.Error("Expected no edge request context")
+	}
+}
@@ -2,10 +2,23 @@ package httpbp
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/reddit/baseplate.go/edgecontext"
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+	"github.com/reddit/baseplate.go/requestevent"
+	"github.com/reddit/baseplate.go/secrets"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -39,14 +52,61 @@ func Wrap(name string, handle HandlerFunc, middlewares ...Middleware) HandlerFun
 type DefaultMiddlewareArgs struct {
 	TrustHandler    HeaderTrustHandler
 	EdgeContextImpl *edgecontext.Impl
+
+	// TraceHeaderFormats is the priority order of the span headers read by
+	// InjectServerSpan.
+	//
+	// Optional, defaults to DefaultTraceHeaderFormats.
+	TraceHeaderFormats []TraceHeaderFormat
+
+	// Route, if non-nil, is used to name the server spans after the matched
+	// route templates, see InjectRoutedServerSpan.
+	//
+	// Optional.
+	Route RouteFunc
+
+	// Logger, if non-nil, is attached to the request context object by
+	// InjectLogger, and used by the middlewares and log.FromContext instead of
+	// the global logger.
+	//
+	// Optional.
+	Logger *zap.SugaredLogger
 }
 
 // DefaultMiddleware returns a slice of all of the default Middleware for a
 // Baseplate HTTP server.
+//
+// Currently they are (in order):
+//
+// 1. InjectLogger (only when Logger is set)
+//
+// 2. InjectServerSpan (InjectRoutedServerSpan when Route is set)
+//
+// 3. InjectEdgeRequestContext
+//
+// 4. RecoverPanic
 func DefaultMiddleware(args DefaultMiddlewareArgs) []Middleware {
-	return []Middleware{
-		InjectServerSpan(args.TrustHandler),
+	var middlewares []Middleware
+	if args.Logger != nil {
+		middlewares = append(middlewares, InjectLogger(args.Logger))
+	}
+	return append(
+		middlewares,
+		InjectRoutedServerSpan(args.TrustHandler, args.Route, args.TraceHeaderFormats...),
 		InjectEdgeRequestContext(args.TrustHandler, args.EdgeContextImpl),
+		RecoverPanic,
+	)
+}
+
+// InjectLogger returns a Middleware that attaches logger to the context object
+// passed into the `next` HandlerFunc (see log.AttachLogger),
+// so the logs from the middlewares after it and log.FromContext in the
+// handlers go through logger instead of the global logger.
+func InjectLogger(logger *zap.SugaredLogger) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return next(log.AttachLogger(ctx, logger), w, r)
+		}
 	}
 }
 
@@ -59,6 +119,12 @@ func isHeaderSet(h http.Header, key string) bool {
 // be trusted and the Span headers are provided, otherwise it starts a new
 // server span.
 //
+// The span headers are read in the priority order of formats,
+// the first format with its headers set on the request wins.
+// When formats is empty, DefaultTraceHeaderFormats will be used.
+// The W3C "tracestate" header, if set, is kept in the returned context and
+// forwarded by MonitorClient when TraceHeaderFormatW3C is used.
+//
 // StartSpanFromTrustedRequest is used by InjectServerSpan and should not
 // generally be used directly but is provided for testing purposes or use cases
 // that are not covered by Baseplate.
@@ -67,23 +133,26 @@ func StartSpanFromTrustedRequest(
 	name string,
 	truster HeaderTrustHandler,
 	r *http.Request,
+	formats ...TraceHeaderFormat,
 ) (context.Context, *tracing.Span) {
 	var spanHeaders tracing.Headers
-	var sampled bool
 
 	if truster.TrustSpan(r) {
-		if isHeaderSet(r.Header, TraceIDHeader) {
-			spanHeaders.TraceID = r.Header.Get(TraceIDHeader)
+		if len(formats) == 0 {
+			formats = DefaultTraceHeaderFormats
 		}
-		if isHeaderSet(r.Header, SpanIDHeader) {
-			spanHeaders.SpanID = r.Header.Get(SpanIDHeader)
-		}
-		if isHeaderSet(r.Header, SpanFlagsHeader) {
-			spanHeaders.Flags = r.Header.Get(SpanFlagsHeader)
-		}
-		if isHeaderSet(r.Header, SpanSampledHeader) {
-			sampled = r.Header.Get(SpanSampledHeader) == spanSampledTrue
-			spanHeaders.Sampled = &sampled
+		for _, format := range formats {
+			if headers, ok := extractSpanHeaders(r.Header, format); ok {
+				spanHeaders = headers
+				if format == TraceHeaderFormatW3C && isHeaderSet(r.Header, W3CTraceStateHeader) {
+					ctx = context.WithValue(
+						ctx,
+						traceStateContextKey{},
+						r.Header.Get(W3CTraceStateHeader),
+					)
+				}
+				break
+			}
 		}
 	}
 
@@ -94,14 +163,55 @@ func StartSpanFromTrustedRequest(
 // HansderFunc in a new server span and stop the span after the function
 // returns.
 //
+// The server span is tagged with the request method, the protocol version,
+// and the peer address.
+// When the HandlerFunc returns an error,
+// the status code of the error response is also tagged.
+//
+// It also increments the "http.<name>.requests" counter on metricsbp.M,
+// with a "protocol" label of "http1" or "http2",
+// to distinguish the requests by the protocol version.
+//
+// The span headers are read in the priority order of formats,
+// see StartSpanFromTrustedRequest for more details.
+//
 // InjectServerSpan should generally not be used directly, instead use one of of
 // the NewBaseplateHandler constructor methods which will automatically include
 // InjectServerSpan as one of the Middlewares to wrap your handler in.
-func InjectServerSpan(truster HeaderTrustHandler) Middleware {
-	return func(name string, next HandlerFunc) HandlerFunc {
+func InjectServerSpan(truster HeaderTrustHandler, formats ...TraceHeaderFormat) Middleware {
+	return InjectRoutedServerSpan(truster, nil, formats...)
+}
+
+// InjectRoutedServerSpan is the same as InjectServerSpan,
+// except that the server span and the "http.<name>.requests" counter are
+// named after the route template matched by the router for the request,
+// as returned by route and converted by RouteName,
+// e.g. "users.{id}" for "/users/{id}".
+//
+// When route is nil or returns an empty string for the request,
+// the name of the endpoint is used instead, same as InjectServerSpan.
+//
+// It's used by the default Middlewares when ServerArgs.Route is set.
+func InjectRoutedServerSpan(truster HeaderTrustHandler, route RouteFunc, formats ...TraceHeaderFormat) Middleware {
+	return func(endpoint string, next HandlerFunc) HandlerFunc {
 		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
-			ctx, span := StartSpanFromTrustedRequest(ctx, name, truster, r)
+			name := endpoint
+			if route != nil {
+				if tmpl := route(r); tmpl != "" {
+					name = cachedRouteName(tmpl)
+				}
+			}
+			ctx, span := StartSpanFromTrustedRequest(ctx, name, truster, r, formats...)
+			span.SetTag(SpanTagKeyMethod, r.Method)
+			span.SetTag(SpanTagKeyProtocol, r.Proto)
+			span.SetTag(SpanTagKeyPeerAddress, r.RemoteAddr)
+			metricsbp.M.Counter("http."+name+".requests").With(
+				"protocol", "http"+strconv.Itoa(r.ProtoMajor),
+			).Add(1)
 			defer func() {
+				if err != nil {
+					span.SetTag(SpanTagKeyStatusCode, errorStatusCode(err))
+				}
 				span.FinishWithOptions(tracing.FinishOptions{
 					Ctx: ctx,
 					Err: err,
@@ -113,6 +223,19 @@ func InjectServerSpan(truster HeaderTrustHandler) Middleware {
 	}
 }
 
+// errorStatusCode returns the status code of the error response handler writes
+// for err.
+func errorStatusCode(err error) int {
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		if code := httpErr.Response().Code; code > 0 {
+			return code
+		}
+		return http.StatusOK
+	}
+	return http.StatusInternalServerError
+}
+
 // InitializeEdgeContextFromTrustedRequest initializen an EdgeRequestContext on
 // the context object if the provided HeaderTrustHandler confirms that the
 // headers can be trusted and the header is set on the request.  If the header
@@ -133,9 +256,15 @@ func InitializeEdgeContextFromTrustedRequest(
 	}
 
 	header := r.Header.Get(EdgeContextHeader)
+	// The header is base64 encoded by ForwardEdgeRequestContext,
+	// as the raw header is not a valid HTTP header value,
+	// but unencoded headers are still accepted for backward compatibility.
+	if decoded, err := base64.StdEncoding.DecodeString(header); err == nil {
+		header = string(decoded)
+	}
 	ec, err := edgecontext.FromHeader(header, impl)
 	if err != nil {
-		log.Errorw("Error while parsing EdgeRequestContext: ", "err", err)
+		log.FromContext(ctx).Errorw("Error while parsing EdgeRequestContext", "err", err)
 		return ctx
 	}
 
@@ -146,6 +275,10 @@ func InitializeEdgeContextFromTrustedRequest(
 // the EdgeRequestContext header from the request headers and attach it to
 // the context object if present.
 //
+// Whether the header is trusted is decided by truster,
+// use TrustHeaderSignature to only trust the headers with a valid HMAC
+// signature in the "X-Edge-Request-Signature" header.
+//
 // InjectEdgeRequestContext should generally not be used directly, instead use
 // one of of the NewBaseplateHandler constructor methods which will
 // automatically include InjectEdgeRequestContext as one of the Middlewares to
@@ -158,3 +291,249 @@ func InjectEdgeRequestContext(truster HeaderTrustHandler, impl *edgecontext.Impl
 		}
 	}
 }
+
+// EdgeContextServerBefore returns a function that attaches the
+// EdgeRequestContext from the request headers to the context object,
+// the same way as InjectEdgeRequestContext.
+//
+// It's intended to be used as a go-kit http transport RequestFunc,
+// for the servers not built with the Handlers in this package:
+//
+//     httptransport.NewServer(
+//       endpoint,
+//       decodeRequest,
+//       encodeResponse,
+//       httptransport.ServerBefore(
+//         httpbp.EdgeContextServerBefore(truster, ecImpl),
+//       ),
+//     )
+func EdgeContextServerBefore(truster HeaderTrustHandler, impl *edgecontext.Impl) func(ctx context.Context, r *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return InitializeEdgeContextFromTrustedRequest(ctx, truster, impl, r)
+	}
+}
+
+// InjectSecretsStore returns a Middleware that attaches store to the context
+// object passed into the `next` HandlerFunc,
+// so it can be retrieved by secrets.FromContext.
+func InjectSecretsStore(store *secrets.Store) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return next(secrets.NewContext(ctx, store), w, r)
+		}
+	}
+}
+
+// RecoverPanic is a Middleware that recovers from panics in the `next`
+// HandlerFunc, instead of letting net/http's default recovery hide them.
+//
+// When a panic happens, it logs the panic with the stack trace and reports it
+// to sentry (if sentry is configured) via log.ErrorWithSentry,
+// increments the "http.<name>.panic" counter on metricsbp.M,
+// and returns it as a JSONError with InternalServerError,
+// which will be written to the client as a http.StatusInternalServerError
+// (500) response with the standard JSON error body.
+//
+// http.ErrAbortHandler is not recovered,
+// as it's used to abort the handler on purpose.
+func RecoverPanic(name string, next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				panicErr := fmt.Errorf("httpbp: recovered from panic in %s: %v", name, rec)
+				log.ErrorWithSentry(
+					ctx,
+					"recovered from panic in http handler",
+					panicErr,
+					"endpoint", name,
+					"panic", rec,
+					"stack", string(debug.Stack()),
+				)
+				metricsbp.M.Counter("http." + name + ".panic").Add(1)
+				err = JSONError(InternalServerError(), panicErr)
+			}
+		}()
+		return next(ctx, w, r)
+	}
+}
+
+// ConvertErrorsToJSON is a Middleware that converts the errors returned by the
+// handler that are not HTTPErrors into JSONErrors with InternalServerError,
+// so every error response of the endpoint uses the standard JSON error body
+// instead of a plain-text one.
+//
+// The original error is kept as the cause of the JSONError.
+func ConvertErrorsToJSON(name string, next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		err := next(ctx, w, r)
+		if err == nil {
+			return nil
+		}
+		var httpErr HTTPError
+		if errors.As(err, &httpErr) {
+			return err
+		}
+		return JSONError(InternalServerError(), err)
+	}
+}
+
+// RateLimitKeyFunc returns the key used by the rate limiter for a request to
+// the endpoint with the given name.
+type RateLimitKeyFunc func(r *http.Request, name string) string
+
+// RateLimit returns a Middleware that rejects the requests over the rate limit
+// of the given limiter with a http.StatusTooManyRequests (429) response.
+//
+// The key of a request is returned by keyFunc.
+// If keyFunc is nil, the name of the endpoint will be used as the key,
+// so every endpoint is rate limited separately.
+func RateLimit(limiter ratelimitbp.RateLimiter, keyFunc RateLimitKeyFunc) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := name
+			if keyFunc != nil {
+				key = keyFunc(r, name)
+			}
+			if !limiter.Allow(ctx, key) {
+				return JSONError(TooManyRequests(), ratelimitbp.ErrRateLimited)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// RateLimitByIdentity returns a Middleware that rate limits the requests per
+// authenticated user or OAuth client from the edge request context,
+// instead of per client IP,
+// with a separate limit for the anonymous requests.
+//
+// Every endpoint is rate limited separately.
+// See ratelimitbp.IdentityLimiter for more details.
+//
+// The identity is read from the edge request context on the context object,
+// so it should be applied after InjectEdgeRequestContext.
+// ServerArgs.Middlewares are already applied after the default middlewares.
+func RateLimitByIdentity(limiter ratelimitbp.IdentityLimiter) Middleware {
+	return RateLimit(limiter, nil)
+}
+
+// ErrRequestBodyTooLarge is the error returned by reading the request body
+// beyond the limit set by LimitRequestBody.
+var ErrRequestBodyTooLarge = errors.New("httpbp: request body too large")
+
+// LimitRequestBody returns a Middleware that limits the size of the request
+// body to limit bytes, using http.MaxBytesReader.
+//
+// Requests with a Content-Length larger than limit are rejected without
+// calling the `next` HandlerFunc.
+// Otherwise reading beyond the limit from the request body returns
+// ErrRequestBodyTooLarge,
+// and when the `next` HandlerFunc returns an error after that,
+// the error is replaced by a JSONError with PayloadTooLarge.
+// In both cases the client gets a http.StatusRequestEntityTooLarge (413)
+// response with the standard JSON error body,
+// and the "http.<name>.oversized-request" counter on metricsbp.M is
+// incremented.
+//
+// To set different limits for different endpoints,
+// use it in the Middlewares of each Endpoint.
+func LimitRequestBody(limit int64) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		counter := metricsbp.M.Counter("http." + name + ".oversized-request")
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.ContentLength > limit {
+				counter.Add(1)
+				return JSONError(PayloadTooLarge(), ErrRequestBodyTooLarge)
+			}
+			if r.Body == nil || r.Body == http.NoBody {
+				return next(ctx, w, r)
+			}
+
+			body := &limitedBody{
+				ReadCloser: http.MaxBytesReader(w, r.Body, limit),
+				limit:      limit,
+			}
+			r.Body = body
+			err := next(ctx, w, r)
+			if err != nil && body.exceeded {
+				counter.Add(1)
+				return JSONError(PayloadTooLarge(), err)
+			}
+			return err
+		}
+	}
+}
+
+// limitedBody wraps the request body returned by http.MaxBytesReader,
+// to record whether the limit is exceeded.
+type limitedBody struct {
+	io.ReadCloser
+
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	// http.MaxBytesReader returns an error after returning exactly limit bytes
+	// when there are more to read.
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		b.exceeded = true
+		err = ErrRequestBodyTooLarge
+	}
+	return n, err
+}
+
+// EmitRequestEvent returns a Middleware that attaches a new
+// requestevent.Event to the context object passed into the `next`
+// HandlerFunc, and publishes it with publisher when the request finishes.
+//
+// It sets the endpoint, protocol ("http"), user agent, duration, success,
+// error code (the status code of the error response), trace id and user id
+// fields on the event.
+// The handler and the clients used by the handler can add more fields via
+// requestevent.SetString and requestevent.Add.
+//
+// The trace id and the user id are read from the server span and the edge
+// request context on the context object,
+// so it should be applied after InjectServerSpan and InjectEdgeRequestContext.
+//
+// Publishing errors are not returned to the client,
+// they are reported by the metrics of the publisher (e.g. events.Queue).
+func EmitRequestEvent(publisher requestevent.Publisher) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+			event := requestevent.New()
+			event.SetString(requestevent.KeyEndpoint, name)
+			event.SetString(requestevent.KeyProtocol, "http")
+			if ua := r.UserAgent(); ua != "" {
+				event.SetString(requestevent.KeyUserAgent, ua)
+			}
+			event.SetFromContext(ctx)
+			ctx = requestevent.NewContext(ctx, event)
+
+			start := time.Now()
+			defer func() {
+				event.SetNumber(
+					requestevent.KeyDurationMS,
+					float64(time.Since(start))/float64(time.Millisecond),
+				)
+				if err == nil {
+					event.SetNumber(requestevent.KeySuccess, 1)
+				} else {
+					event.SetNumber(requestevent.KeySuccess, 0)
+					event.SetNumber(requestevent.KeyErrorCode, float64(errorStatusCode(err)))
+				}
+				// Use a new context object as ctx might be already canceled.
+				publisher.Put(context.Background(), event)
+			}()
+
+			return next(ctx, w, r)
+		}
+	}
+}
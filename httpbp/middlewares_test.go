@@ -1,18 +1,31 @@
 package httpbp_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/apache/thrift/lib/go/thrift"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
 	"github.com/reddit/baseplate.go/edgecontext"
 
 	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
 	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+	"github.com/reddit/baseplate.go/requestevent"
+	"github.com/reddit/baseplate.go/secrets"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -50,7 +63,7 @@ func TestInjectServerSpan(t *testing.T) {
 	}()
 	mmq := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
 		MaxQueueSize:   100,
-		MaxMessageSize: 1024,
+		MaxMessageSize: tracing.MaxSpanSize,
 	})
 	logger, startFailing := tracing.TestWrapper(t)
 	tracing.InitGlobalTracer(tracing.TracerConfig{
@@ -125,16 +138,26 @@ func TestInjectServerSpan(t *testing.T) {
 					t.Fatal("no binary annotations")
 				}
 				t.Logf("%#v", trace.BinaryAnnotations)
+				tags := make(map[string]interface{})
+				for _, annotation := range trace.BinaryAnnotations {
+					tags[annotation.Key] = annotation.Value
+				}
 				if c.err != nil {
-					hasError := false
-					for _, annotation := range trace.BinaryAnnotations {
-						if annotation.Key == "error" {
-							hasError = true
-						}
-					}
-					if !hasError {
+					if _, ok := tags["error"]; !ok {
 						t.Error("error binary annotation was not present.")
 					}
+					if code := tags[httpbp.SpanTagKeyStatusCode]; code != "500" {
+						t.Errorf("Expected status code tag to be 500, got %v", code)
+					}
+				}
+				if method := tags[httpbp.SpanTagKeyMethod]; method != req.Method {
+					t.Errorf("Expected method tag to be %q, got %v", req.Method, method)
+				}
+				if proto := tags[httpbp.SpanTagKeyProtocol]; proto != req.Proto {
+					t.Errorf("Expected protocol tag to be %q, got %v", req.Proto, proto)
+				}
+				if addr := tags[httpbp.SpanTagKeyPeerAddress]; addr != req.RemoteAddr {
+					t.Errorf("Expected peer address tag to be %q, got %v", req.RemoteAddr, addr)
 				}
 			},
 		)
@@ -156,6 +179,11 @@ func TestInjectEdgeRequestContext(t *testing.T) {
 	req := newRequest(t)
 	noHeader := newRequest(t)
 	noHeader.Header.Del(httpbp.EdgeContextHeader)
+	encoded := newRequest(t)
+	encoded.Header.Set(
+		httpbp.EdgeContextHeader,
+		base64.StdEncoding.EncodeToString([]byte(headerWithValidAuth)),
+	)
 
 	cases := []struct {
 		name       string
@@ -169,6 +197,12 @@ func TestInjectEdgeRequestContext(t *testing.T) {
 			request:    req,
 			expectedID: expectedID,
 		},
+		{
+			name:       "trust/base64-header",
+			truster:    httpbp.AlwaysTrustHeaders{},
+			request:    encoded,
+			expectedID: expectedID,
+		},
 		{
 			name:       "trust/no-header",
 			truster:    httpbp.AlwaysTrustHeaders{},
@@ -217,3 +251,329 @@ func TestInjectEdgeRequestContext(t *testing.T) {
 		)
 	}
 }
+
+func TestEdgeContextServerBefore(t *testing.T) {
+	t.Parallel()
+
+	store, dir := newSecretsStore(t)
+	defer func() {
+		os.RemoveAll(dir)
+		store.Close()
+	}()
+
+	impl := edgecontext.Init(edgecontext.Config{Store: store})
+
+	t.Run("trust", func(t *testing.T) {
+		before := httpbp.EdgeContextServerBefore(httpbp.AlwaysTrustHeaders{}, impl)
+		ctx := before(context.Background(), newRequest(t))
+		ec, ok := edgecontext.GetEdgeContext(ctx)
+		if !ok || ec == nil {
+			t.Fatal("edge request context not set")
+		}
+		if userID, _ := ec.User().ID(); userID != "t2_example" {
+			t.Errorf("user ID mismatch, expected %q, got %q", "t2_example", userID)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		before := httpbp.EdgeContextServerBefore(getTrustHeaderSignature(store), impl)
+		ctx := before(context.Background(), newRequest(t))
+		if _, ok := edgecontext.GetEdgeContext(ctx); ok {
+			t.Error("edge request context should not be set without a valid signature")
+		}
+	})
+}
+
+func TestInjectSecretsStore(t *testing.T) {
+	t.Parallel()
+
+	store, dir := newSecretsStore(t)
+	defer func() {
+		os.RemoveAll(dir)
+		store.Close()
+	}()
+
+	var actual *secrets.Store
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			actual, _ = secrets.FromContext(ctx)
+			return nil
+		},
+		httpbp.InjectSecretsStore(store),
+	)
+	req := newRequest(t)
+	handle(req.Context(), httptest.NewRecorder(), req)
+	if actual != store {
+		t.Errorf("Expected secrets store %p from context, got %p", store, actual)
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	handler := httpbp.NewHandler(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			panic("oops")
+		},
+		httpbp.RecoverPanic,
+	)
+	req := httptest.NewRequest("GET", "localhost:9090", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if contentType := w.Header().Get(httpbp.ContentTypeHeader); contentType != httpbp.JSONContentType {
+		t.Errorf("Expected content type %q, got %q", httpbp.JSONContentType, contentType)
+	}
+	var body httpbp.ErrorResponseJSONWrapper
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error == nil || body.Error.Reason != httpbp.InternalServerError().Reason {
+		t.Errorf("Expected error reason %q, got %+v", httpbp.InternalServerError().Reason, body.Error)
+	}
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	expected := "http.test.panic:1.000000|c\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("Expected metrics %q, got %q", expected, actual)
+	}
+}
+
+func TestRecoverPanicInjectLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	handler := httpbp.NewHandler(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			panic("oops")
+		},
+		httpbp.DefaultMiddleware(httpbp.DefaultMiddlewareArgs{
+			TrustHandler: httpbp.NeverTrustHeaders{},
+			Logger:       zap.New(core).Sugar(),
+		})...,
+	)
+	req := httptest.NewRequest("GET", "localhost:9090", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("recovered from panic in http handler").AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry from the injected logger, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["endpoint"] != "test" {
+		t.Errorf("Expected endpoint %q, got %v", "test", fields["endpoint"])
+	}
+	if _, ok := fields["traceID"]; !ok {
+		t.Errorf("Expected the fields attached by InjectServerSpan, got %v", fields)
+	}
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	const limit = 8
+
+	for _, c := range []struct {
+		label         string
+		body          string
+		contentLength int64
+		expected      int
+	}{
+		{
+			label:         "within-limit",
+			body:          strings.Repeat("a", limit),
+			contentLength: limit,
+			expected:      http.StatusOK,
+		},
+		{
+			label:         "content-length",
+			body:          strings.Repeat("a", limit+1),
+			contentLength: limit + 1,
+			expected:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			label:         "unknown-length-within-limit",
+			body:          strings.Repeat("a", limit),
+			contentLength: -1,
+			expected:      http.StatusOK,
+		},
+		{
+			label:         "unknown-length",
+			body:          strings.Repeat("a", limit+1),
+			contentLength: -1,
+			expected:      http.StatusRequestEntityTooLarge,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			recorder := metricstest.Replace(t)
+			handler := httpbp.NewHandler(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					_, err := ioutil.ReadAll(r.Body)
+					return err
+				},
+				httpbp.LimitRequestBody(limit),
+			)
+
+			req := httptest.NewRequest("POST", "localhost:9090", strings.NewReader(c.body))
+			req.ContentLength = c.contentLength
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != c.expected {
+				t.Errorf("Expected status code %d, got %d", c.expected, w.Code)
+			}
+			var expectedCount float64
+			if c.expected == http.StatusRequestEntityTooLarge {
+				expectedCount = 1
+				var body httpbp.ErrorResponseJSONWrapper
+				if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Error == nil || body.Error.Reason != httpbp.PayloadTooLarge().Reason {
+					t.Errorf("Expected error reason %q, got %+v", httpbp.PayloadTooLarge().Reason, body.Error)
+				}
+			}
+			recorder.AssertCounterEquals(t, "http.test.oversized-request", expectedCount)
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimitbp.NewLimiter(ratelimitbp.Config{
+		RatePerSecond: 0.001,
+		Burst:         1,
+	})
+	handler := httpbp.NewHandler(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return nil
+		},
+		httpbp.RateLimit(limiter, func(r *http.Request, name string) string {
+			return r.Header.Get("X-Client")
+		}),
+	)
+
+	for _, c := range []struct {
+		client   string
+		expected int
+	}{
+		{client: "a", expected: http.StatusOK},
+		{client: "a", expected: http.StatusTooManyRequests},
+		{client: "b", expected: http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "localhost:9090", nil)
+		req.Header.Set("X-Client", c.client)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.expected {
+			t.Errorf("Expected status code %d for client %q, got %d", c.expected, c.client, w.Code)
+		}
+	}
+}
+
+func TestConvertErrorsToJSON(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "plain-error",
+			err:          errors.New("oops"),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "http-error",
+			err:          httpbp.JSONError(httpbp.Forbidden(), nil),
+			expectedCode: http.StatusForbidden,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := httpbp.NewHandler(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return c.err
+				},
+				httpbp.ConvertErrorsToJSON,
+			)
+			req := httptest.NewRequest("GET", "localhost:9090", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != c.expectedCode {
+				t.Errorf("Expected status code %d, got %d", c.expectedCode, w.Code)
+			}
+			var body httpbp.ErrorResponseJSONWrapper
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("Expected JSON error body, got %v", err)
+			}
+			if body.Error == nil || body.Error.Reason == "" {
+				t.Errorf("Expected error reason in the body, got %+v", body)
+			}
+		})
+	}
+}
+
+type requestEventRecorder struct {
+	events []*requestevent.Event
+}
+
+func (r *requestEventRecorder) Put(ctx context.Context, event thrift.TStruct) error {
+	r.events = append(r.events, event.(*requestevent.Event))
+	return nil
+}
+
+func TestEmitRequestEvent(t *testing.T) {
+	recorder := &requestEventRecorder{}
+	handler := httpbp.NewHandler(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			requestevent.Add(ctx, "custom", 2)
+			return httpbp.JSONError(httpbp.TooManyRequests(), nil)
+		},
+		httpbp.EmitRequestEvent(recorder),
+	)
+	req := httptest.NewRequest("GET", "localhost:9090", nil)
+	req.Header.Set("User-Agent", "test-client")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("Expected 1 event published, got %d", len(recorder.events))
+	}
+	event := recorder.events[0]
+
+	strs := event.Strings()
+	for key, expected := range map[string]string{
+		requestevent.KeyEndpoint:  "test",
+		requestevent.KeyProtocol:  "http",
+		requestevent.KeyUserAgent: "test-client",
+	} {
+		if strs[key] != expected {
+			t.Errorf("Expected string field %q to be %q, got %q", key, expected, strs[key])
+		}
+	}
+
+	nums := event.Numbers()
+	for key, expected := range map[string]float64{
+		requestevent.KeySuccess:   0,
+		requestevent.KeyErrorCode: http.StatusTooManyRequests,
+		"custom":                  2,
+	} {
+		if nums[key] != expected {
+			t.Errorf("Expected number field %q to be %v, got %v", key, expected, nums[key])
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package httpbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+// ResponseStatusError is the error passed into the retrybp.Classifier by
+// Retry when the response has a 5xx or 429 status code.
+type ResponseStatusError struct {
+	StatusCode int
+}
+
+func (e ResponseStatusError) Error() string {
+	return fmt.Sprintf("httpbp: response status code %d", e.StatusCode)
+}
+
+// IsRetryableError is the retrybp.Classifier used by Retry when the
+// Classifier in retrybp.Config is nil.
+//
+// It treats ResponseStatusError with http.StatusTooManyRequests,
+// http.StatusBadGateway, http.StatusServiceUnavailable,
+// and http.StatusGatewayTimeout as retryable,
+// and falls back to retrybp.DefaultClassifier for other errors.
+func IsRetryableError(err error) bool {
+	var statusErr ResponseStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		default:
+			return false
+		case http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return retrybp.DefaultClassifier(err)
+}
+
+// DefaultRetryMethods are the methods retried by Retry and RetryWithOptions
+// when RetryOptions.Methods is empty.
+//
+// They are the idempotent methods defined by RFC 7231.
+var DefaultRetryMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// RetryOptions are the additional options used by RetryWithOptions.
+type RetryOptions struct {
+	// Methods are the request methods allowed to be retried.
+	//
+	// Optional, defaults to DefaultRetryMethods.
+	Methods []string
+
+	// StatusCodes are the response status codes that make the request
+	// retryable.
+	//
+	// When it's empty, responses with 5xx or 429 status codes are passed into
+	// the retrybp.Classifier as ResponseStatusError,
+	// which only retries some of them when the Classifier is nil
+	// (see IsRetryableError).
+	//
+	// When it's non-empty, only responses with these status codes are passed
+	// into the retrybp.Classifier as ResponseStatusError,
+	// and they are all retried when the Classifier is nil.
+	StatusCodes []int
+
+	// Slug, when non-empty, is used as the prefix of the metrics reported to
+	// metricsbp.M:
+	//
+	// - "<slug>.retry.attempts": counter of the retries made.
+	//
+	// - "<slug>.retry.budget-exhausted": counter of the retries denied because
+	// the retrybp.Budget ran out.
+	Slug string
+}
+
+func (opts RetryOptions) retryMethod(method string) bool {
+	methods := opts.Methods
+	if len(methods) == 0 {
+		methods = DefaultRetryMethods
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (opts RetryOptions) retryStatus(code int) bool {
+	if len(opts.StatusCodes) == 0 {
+		return code >= 500 || code == http.StatusTooManyRequests
+	}
+	for _, c := range opts.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry returns a ClientMiddleware that retries the requests using retrybp.Do
+// with the given config.
+//
+// It's a shorthand for RetryWithOptions with empty RetryOptions.
+func Retry(cfg retrybp.Config) ClientMiddleware {
+	return RetryWithOptions(cfg, RetryOptions{})
+}
+
+// RetryWithOptions returns a ClientMiddleware that retries the requests using
+// retrybp.Do with the given config and options.
+//
+// When cfg.Classifier is nil, IsRetryableError will be used,
+// or when opts.StatusCodes is non-empty,
+// a Classifier treating all ResponseStatusError as retryable.
+//
+// Only requests with methods in opts.Methods will be retried.
+// Requests with a body can only be retried when GetBody is set,
+// which is the case for requests created by http.NewRequest with common body
+// types.
+//
+// When the retries stopped with a response with retryable status code,
+// that response will be returned as-is without an error.
+//
+// When cfg.Budget is set, it's recommended to share the same budget across
+// all the requests to the same service,
+// so the retries stay under the configured ratio during a brownout.
+func RetryWithOptions(cfg retrybp.Config, opts RetryOptions) ClientMiddleware {
+	if cfg.Classifier == nil {
+		if len(opts.StatusCodes) == 0 {
+			cfg.Classifier = IsRetryableError
+		} else {
+			cfg.Classifier = func(err error) bool {
+				var statusErr ResponseStatusError
+				if errors.As(err, &statusErr) {
+					return true
+				}
+				return retrybp.DefaultClassifier(err)
+			}
+		}
+	}
+	if opts.Slug != "" {
+		attempts := metricsbp.M.Counter(opts.Slug + ".retry.attempts")
+		exhausted := metricsbp.M.Counter(opts.Slug + ".retry.budget-exhausted")
+		onRetry := cfg.OnRetry
+		cfg.OnRetry = func(attempt int, err error) {
+			attempts.Add(1)
+			if onRetry != nil {
+				onRetry(attempt, err)
+			}
+		}
+		onBudgetExhausted := cfg.OnBudgetExhausted
+		cfg.OnBudgetExhausted = func(err error) {
+			exhausted.Add(1)
+			if onBudgetExhausted != nil {
+				onBudgetExhausted(err)
+			}
+		}
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !opts.retryMethod(req.Method) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+				return next.RoundTrip(req)
+			}
+			var resp *http.Response
+			attempt := 0
+			err := retrybp.Do(req.Context(), cfg, func(ctx context.Context) error {
+				attempt++
+				if resp != nil {
+					// Discard the response of the previous attempt.
+					io.Copy(ioutil.Discard, resp.Body)
+					resp.Body.Close()
+					resp = nil
+				}
+				r := req
+				if attempt > 1 && req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return err
+					}
+					r = req.Clone(ctx)
+					r.Body = body
+				}
+
+				var err error
+				resp, err = next.RoundTrip(r)
+				if err != nil {
+					return err
+				}
+				if opts.retryStatus(resp.StatusCode) {
+					return ResponseStatusError{StatusCode: resp.StatusCode}
+				}
+				return nil
+			})
+			if resp != nil {
+				return resp, nil
+			}
+			return nil, err
+		})
+	}
+}
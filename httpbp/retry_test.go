@@ -0,0 +1,157 @@
+package httpbp_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+func TestRetry(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		method   string
+		codes    []int
+		expected int
+		calls    int
+	}{
+		{
+			label:    "succeed-on-retry",
+			method:   http.MethodPut,
+			codes:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			expected: http.StatusOK,
+			calls:    2,
+		},
+		{
+			label:    "exhausted",
+			method:   http.MethodGet,
+			codes:    []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			expected: http.StatusBadGateway,
+			calls:    3,
+		},
+		{
+			label:    "not-retryable-status",
+			method:   http.MethodGet,
+			codes:    []int{http.StatusInternalServerError},
+			expected: http.StatusInternalServerError,
+			calls:    1,
+		},
+		{
+			label:    "not-idempotent",
+			method:   http.MethodPost,
+			codes:    []int{http.StatusServiceUnavailable},
+			expected: http.StatusServiceUnavailable,
+			calls:    1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var calls int
+			server := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					if string(body) != "body" {
+						t.Errorf("Expected request body %q, got %q", "body", body)
+					}
+					code := c.codes[calls]
+					calls++
+					w.WriteHeader(code)
+				},
+			))
+			defer server.Close()
+
+			client := &http.Client{
+				Transport: httpbp.WrapTransport(
+					http.DefaultTransport,
+					httpbp.Retry(retrybp.Config{MaxAttempts: 3}),
+				),
+			}
+			req, err := http.NewRequest(c.method, server.URL, strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != c.expected {
+				t.Errorf("Expected status code %d, got %d", c.expected, resp.StatusCode)
+			}
+			if calls != c.calls {
+				t.Errorf("Expected %d calls, got %d", c.calls, calls)
+			}
+		})
+	}
+}
+
+func TestRetryWithOptions(t *testing.T) {
+	metrics := metricstest.Replace(t)
+
+	var calls int
+	codes := []int{
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+	}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(codes[calls])
+			calls++
+		},
+	))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: httpbp.WrapTransport(
+			http.DefaultTransport,
+			httpbp.RetryWithOptions(
+				retrybp.Config{
+					MaxAttempts: 3,
+					Budget:      retrybp.NewBudget(0, 1),
+				},
+				httpbp.RetryOptions{
+					Methods:     []string{http.MethodPost},
+					StatusCodes: []int{http.StatusInternalServerError},
+					Slug:        "test",
+				},
+			),
+		),
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+	// The budget only allows one retry.
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+	metrics.AssertCounterEquals(t, "test.retry.attempts", 1)
+	metrics.AssertCounterEquals(t, "test.retry.budget-exhausted", 1)
+
+	calls = 0
+	req, err = http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Errorf("Expected GET not to be retried, got %d calls", calls)
+	}
+}
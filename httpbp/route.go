@@ -0,0 +1,125 @@
+package httpbp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// RouteFunc returns the template of the route matched by the router for the
+// request, e.g. "/users/{id}",
+// or an empty string if it's unknown.
+//
+// It's used by InjectRoutedServerSpan to name the server spans and metrics
+// after the route templates instead of the actual paths,
+// to keep the cardinality bounded.
+//
+// ServeMuxRoute is the RouteFunc for *http.ServeMux.
+// For other routers, it's usually a one-liner, for example:
+//
+//     // github.com/go-chi/chi
+//     func(r *http.Request) string {
+//       return chi.RouteContext(r.Context()).RoutePattern()
+//     }
+//
+//     // github.com/gorilla/mux
+//     func(r *http.Request) string {
+//       if route := mux.CurrentRoute(r); route != nil {
+//         tmpl, _ := route.GetPathTemplate()
+//         return tmpl
+//       }
+//       return ""
+//     }
+type RouteFunc func(r *http.Request) string
+
+// ServeMuxRoute is the RouteFunc for *http.ServeMux,
+// which returns the pattern the Endpoint handling the request is registered
+// with in ServerArgs.Endpoints, e.g. "/users/".
+//
+// The pattern is recorded by SetupEndpoints when it registers the Endpoints,
+// so it works with any EndpointRegistry,
+// but it's empty for the handlers registered to the EndpointRegistry directly.
+func ServeMuxRoute(r *http.Request) string {
+	pattern, _ := r.Context().Value(registeredPatternContextKey{}).(string)
+	return pattern
+}
+
+var _ RouteFunc = ServeMuxRoute
+
+type registeredPatternContextKey struct{}
+
+// withRegisteredPattern wraps handler to attach the pattern it's registered
+// with to the context object of the requests, to be returned by
+// ServeMuxRoute.
+func withRegisteredPattern(pattern string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), registeredPatternContextKey{}, pattern)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RouteName converts a route template to the name used by the server spans
+// and metrics.
+//
+// The template is split by the slashes and spaces,
+// and the non-empty parts are joined by periods.
+// The wildcards are kept as-is except for the "..." suffixes and the "{$}"
+// anchors, for example:
+//
+//     "/users/{id}"             -> "users.{id}"
+//     "GET /users/{id}/"        -> "GET.users.{id}"
+//     "/files/{path...}"        -> "files.{path}"
+//     "example.com/{$}"         -> "example.com"
+//
+// The template of the root path ("/") is converted to "root".
+func RouteName(route string) string {
+	parts := strings.FieldsFunc(
+		routeWildcardReplacer.Replace(route),
+		func(r rune) bool {
+			return r == '/' || unicode.IsSpace(r)
+		},
+	)
+	if len(parts) == 0 {
+		return "root"
+	}
+	return strings.Join(parts, ".")
+}
+
+var routeWildcardReplacer = strings.NewReplacer(
+	"...}", "}",
+	"{$}", "",
+)
+
+// maxCachedRouteNames is the maximum number of entries in routeNamesCache.
+//
+// It's only to guard against a RouteFunc returning unbounded values,
+// e.g. the actual paths instead of the templates.
+const maxCachedRouteNames = 1024
+
+// routeNamesCache caches the names converted from the route templates,
+// so that they are not rebuilt on every request.
+var routeNamesCache = struct {
+	sync.RWMutex
+	m map[string]string
+}{
+	m: make(map[string]string),
+}
+
+func cachedRouteName(route string) string {
+	routeNamesCache.RLock()
+	name, ok := routeNamesCache.m[route]
+	routeNamesCache.RUnlock()
+	if ok {
+		return name
+	}
+
+	name = RouteName(route)
+	routeNamesCache.Lock()
+	defer routeNamesCache.Unlock()
+	if len(routeNamesCache.m) < maxCachedRouteNames {
+		routeNamesCache.m[route] = name
+	}
+	return name
+}
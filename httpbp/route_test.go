@@ -0,0 +1,152 @@
+package httpbp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+func TestRouteName(t *testing.T) {
+	for _, c := range []struct {
+		route    string
+		expected string
+	}{
+		{route: "/users/{id}", expected: "users.{id}"},
+		{route: "GET /users/{id}/", expected: "GET.users.{id}"},
+		{route: "/files/{path...}", expected: "files.{path}"},
+		{route: "example.com/{$}", expected: "example.com"},
+		{route: "/", expected: "root"},
+		{route: "", expected: "root"},
+	} {
+		if actual := httpbp.RouteName(c.route); actual != c.expected {
+			t.Errorf("RouteName(%q) expected %q, got %q", c.route, c.expected, actual)
+		}
+	}
+}
+
+func TestServeMuxRoute(t *testing.T) {
+	t.Run("unregistered", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		if route := httpbp.ServeMuxRoute(r); route != "" {
+			t.Errorf("Expected empty route, got %q", route)
+		}
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		recorder := metricstest.Replace(t)
+		spans := tracingtest.InitGlobalTracer(t)
+
+		store, dir := newSecretsStore(t)
+		defer func() {
+			os.RemoveAll(dir)
+			store.Close()
+		}()
+
+		routes := make(chan string, 1)
+		bp := baseplate.NewTestBaseplate(baseplate.Config{Addr: ":8080"}, store)
+		server, ts, err := httpbp.NewTestBaseplateServer(httpbp.ServerArgs{
+			Baseplate: bp,
+			Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+				"/users/": {
+					Name: "list_users",
+					Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+						routes <- httpbp.ServeMuxRoute(r)
+						return nil
+					},
+				},
+			},
+			Route: httpbp.ServeMuxRoute,
+			// Trust the span headers so the server span is sampled.
+			TrustHandler: httpbp.AlwaysTrustHeaders{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(httpbp.TraceIDHeader, "1")
+		req.Header.Set(httpbp.SpanIDHeader, "2")
+		req.Header.Set(httpbp.SpanSampledHeader, "1")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if route := <-routes; route != "/users/" {
+			t.Errorf("Expected route %q, got %q", "/users/", route)
+		}
+
+		// The span is finished after the response is written.
+		deadline := time.Now().Add(time.Second)
+		for len(spans.FindSpans("users")) == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond * 10)
+		}
+		spans.MustFindSpan(t, "users")
+		if found := spans.FindSpans("list_users"); len(found) != 0 {
+			t.Errorf("Expected no span named after the endpoint, got %+v", found)
+		}
+		recorder.AssertCounterEquals(t, "http.users.requests,protocol=http1", 1)
+		recorder.AssertCounterEquals(t, "http.list_users.requests,protocol=http1", 0)
+	})
+}
+
+func TestNewTestBaseplateServerRoute(t *testing.T) {
+	recorder := metricstest.Replace(t)
+
+	store, dir := newSecretsStore(t)
+	defer func() {
+		os.RemoveAll(dir)
+		store.Close()
+	}()
+
+	handle := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+	bp := baseplate.NewTestBaseplate(baseplate.Config{Addr: ":8080"}, store)
+	server, ts, err := httpbp.NewTestBaseplateServer(httpbp.ServerArgs{
+		Baseplate: bp,
+		Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+			"/users/": {
+				Name:   "users",
+				Handle: handle,
+			},
+			"/other": {
+				Name:   "other",
+				Handle: handle,
+			},
+		},
+		Route: func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/users/") {
+				return "/users/{id}"
+			}
+			return ""
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, path := range []string{"/users/1", "/users/2", "/other"} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	recorder.AssertCounterEquals(t, "http.users.{id}.requests,protocol=http1", 2)
+	recorder.AssertCounterEquals(t, "http.other.requests,protocol=http1", 1)
+}
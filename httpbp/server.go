@@ -7,6 +7,10 @@ import (
 	"net/http/httptest"
 	"sync"
 
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
 	baseplate "github.com/reddit/baseplate.go"
 	"github.com/reddit/baseplate.go/batcherror"
 )
@@ -111,6 +115,33 @@ type ServerArgs struct {
 	//
 	// Defaults to NeverTrustHeaders.
 	TrustHandler HeaderTrustHandler
+
+	// H2C enables serving HTTP/2 over plaintext connections ("h2c" with prior
+	// knowledge), in addition to HTTP/1.1.
+	//
+	// It's intended for servers behind a service mesh,
+	// where the sidecar proxy talks to the server over plaintext HTTP/2.
+	// Every HTTP/2 stream is handled as a separate request,
+	// with its own server span.
+	H2C bool
+
+	// Route is an optional RouteFunc returning the route template matched by
+	// EndpointRegistry for a request,
+	// e.g. ServeMuxRoute for the patterns of the Endpoints.
+	//
+	// When it's set, the server spans and metrics are named after the matched
+	// route templates (see RouteName) instead of the names of the Endpoints,
+	// which are still used when the route is unknown.
+	Route RouteFunc
+
+	// CORS, if non-nil, applies the CORS Middleware with the config to all
+	// the Endpoints, right after the default Middlewares.
+	CORS *CORSConfig
+
+	// Logger is an optional logger used by the default Middlewares and
+	// log.FromContext in the Endpoints instead of the global logger,
+	// see InjectLogger.
+	Logger *zap.SugaredLogger
 }
 
 // ValidateAndSetDefaults checks the ServerArgs for any errors and sets any
@@ -127,6 +158,9 @@ func (args ServerArgs) ValidateAndSetDefaults() (ServerArgs, error) {
 	for _, endpoint := range args.Endpoints {
 		inputErrors.Add(endpoint.Validate())
 	}
+	if args.CORS != nil {
+		inputErrors.Add(args.CORS.Validate())
+	}
 	if args.EndpointRegistry == nil {
 		args.EndpointRegistry = http.NewServeMux()
 	}
@@ -151,14 +185,22 @@ func (args ServerArgs) SetupEndpoints() (ServerArgs, error) {
 	wrappers := DefaultMiddleware(DefaultMiddlewareArgs{
 		TrustHandler:    args.TrustHandler,
 		EdgeContextImpl: args.Baseplate.EdgeContextImpl(),
+		Route:           args.Route,
+		Logger:          args.Logger,
 	})
+	if args.CORS != nil {
+		wrappers = append(wrappers, CORS(*args.CORS))
+	}
 	wrappers = append(wrappers, args.Middlewares...)
 
 	factory := httpHandlerFactory{middlewares: wrappers}
 	for pattern, endpoint := range args.Endpoints {
 		args.EndpointRegistry.Handle(
 			string(pattern),
-			factory.NewHandler(endpoint.Name, endpoint.Handle, endpoint.Middlewares...),
+			withRegisteredPattern(
+				string(pattern),
+				factory.NewHandler(endpoint.Name, endpoint.Handle, endpoint.Middlewares...),
+			),
 		)
 	}
 	return args, nil
@@ -182,12 +224,33 @@ func NewBaseplateServer(args ServerArgs) (baseplate.Server, error) {
 		ReadTimeout:  args.Baseplate.Config().Timeout,
 		WriteTimeout: args.Baseplate.Config().Timeout,
 	}
+	if err := setProtocols(srv, args); err != nil {
+		return nil, err
+	}
 	for _, f := range args.OnShutdown {
 		srv.RegisterOnShutdown(f)
 	}
 	return &server{args.Baseplate, srv}, nil
 }
 
+// setProtocols sets the protocols served by srv according to args.
+//
+// When args.H2C is set, the handler of srv is wrapped to also serve HTTP/2
+// over plaintext connections.
+// The HTTP/2 server is also registered to srv,
+// so that Shutdown gracefully shuts down the HTTP/2 connections.
+func setProtocols(srv *http.Server, args ServerArgs) error {
+	if !args.H2C {
+		return nil
+	}
+	h2s := new(http2.Server)
+	if err := http2.ConfigureServer(srv, h2s); err != nil {
+		return err
+	}
+	srv.Handler = h2c.NewHandler(srv.Handler, h2s)
+	return nil
+}
+
 type server struct {
 	bp  baseplate.Baseplate
 	srv *http.Server
@@ -232,7 +295,11 @@ func NewTestBaseplateServer(args ServerArgs) (baseplate.Server, *httptest.Server
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 
-	ts := httptest.NewServer(args.EndpointRegistry)
+	ts := httptest.NewUnstartedServer(args.EndpointRegistry)
+	if err := setProtocols(ts.Config, args); err != nil {
+		return nil, nil, err
+	}
+	ts.Start()
 	return &testServer{
 		bp:         args.Baseplate,
 		onShutdown: args.OnShutdown,
@@ -2,15 +2,20 @@ package httpbp_test
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
 	"testing"
 
+	"golang.org/x/net/http2"
+
 	"github.com/reddit/baseplate.go"
 	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
 	"github.com/reddit/baseplate.go/mqsend"
 	"github.com/reddit/baseplate.go/tracing"
 )
@@ -359,3 +364,73 @@ func TestNewTestBaseplateServer(t *testing.T) {
 		t.Fatalf("Unexpected count value %v", c.count)
 	}
 }
+
+func TestNewTestBaseplateServerH2C(t *testing.T) {
+	const name = "test"
+	recorder := metricstest.Replace(t)
+
+	store, dir := newSecretsStore(t)
+	defer func() {
+		os.RemoveAll(dir)
+		store.Close()
+	}()
+
+	bp := baseplate.NewTestBaseplate(baseplate.Config{Addr: ":8080"}, store)
+	protos := make(chan string, 1)
+	server, ts, err := httpbp.NewTestBaseplateServer(httpbp.ServerArgs{
+		Baseplate: bp,
+		Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+			"/test": {
+				Name: name,
+				Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					protos <- r.Proto
+					return nil
+				},
+			},
+		},
+		H2C: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, c := range []struct {
+		label    string
+		client   *http.Client
+		proto    string
+		protocol string
+	}{
+		{
+			label:    "http1",
+			client:   http.DefaultClient,
+			proto:    "HTTP/1.1",
+			protocol: "http1",
+		},
+		{
+			label: "h2c",
+			client: &http.Client{
+				Transport: &http2.Transport{
+					AllowHTTP: true,
+					DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+						return net.Dial(network, addr)
+					},
+				},
+			},
+			proto:    "HTTP/2.0",
+			protocol: "http2",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			res, err := c.client.Get(ts.URL + "/test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if proto := <-protos; proto != c.proto {
+				t.Errorf("Expected request protocol %q, got %q", c.proto, proto)
+			}
+			recorder.AssertCounterEquals(t, "http."+name+".requests,protocol="+c.protocol, 1)
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package httpbp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// W3C Trace Context headers.
+//
+// Reference: https://www.w3.org/TR/trace-context/
+const (
+	W3CTraceParentHeader = "traceparent"
+	W3CTraceStateHeader  = "tracestate"
+)
+
+// B3 propagation headers.
+//
+// Reference: https://github.com/openzipkin/b3-propagation
+const (
+	B3SingleHeader       = "b3"
+	B3TraceIDHeader      = "X-B3-TraceId"
+	B3SpanIDHeader       = "X-B3-SpanId"
+	B3ParentSpanIDHeader = "X-B3-ParentSpanId"
+	B3SampledHeader      = "X-B3-Sampled"
+	B3FlagsHeader        = "X-B3-Flags"
+)
+
+// TraceHeaderFormat is a format of the headers used to propagate spans over
+// HTTP.
+type TraceHeaderFormat int
+
+// Supported TraceHeaderFormat values.
+const (
+	// TraceHeaderFormatBaseplate is the Baseplate "X-Trace", "X-Span", etc.
+	// headers.
+	TraceHeaderFormatBaseplate TraceHeaderFormat = iota
+
+	// TraceHeaderFormatW3C is the W3C Trace Context "traceparent" and
+	// "tracestate" headers.
+	TraceHeaderFormatW3C
+
+	// TraceHeaderFormatB3 is the B3 headers,
+	// in either the single "b3" header or the multiple "X-B3-*" headers.
+	TraceHeaderFormatB3
+)
+
+func (f TraceHeaderFormat) String() string {
+	switch f {
+	default:
+		return fmt.Sprintf("TraceHeaderFormat(%d)", int(f))
+	case TraceHeaderFormatBaseplate:
+		return "baseplate"
+	case TraceHeaderFormatW3C:
+		return "w3c"
+	case TraceHeaderFormatB3:
+		return "b3"
+	}
+}
+
+// DefaultTraceHeaderFormats is the priority order of the trace header formats
+// used by StartSpanFromTrustedRequest when no formats are passed in.
+var DefaultTraceHeaderFormats = []TraceHeaderFormat{
+	TraceHeaderFormatBaseplate,
+	TraceHeaderFormatW3C,
+	TraceHeaderFormatB3,
+}
+
+type traceStateContextKey struct{}
+
+// traceStateFromContext returns the W3C tracestate header of the incoming
+// request, to be forwarded on outgoing requests.
+func traceStateFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(traceStateContextKey{}).(string)
+	return s
+}
+
+// extractSpanHeaders reads the span headers of the given format from h.
+//
+// It returns false when the headers of the format are absent or malformed.
+func extractSpanHeaders(h http.Header, format TraceHeaderFormat) (tracing.Headers, bool) {
+	switch format {
+	default:
+		return tracing.Headers{}, false
+	case TraceHeaderFormatBaseplate:
+		return extractBaseplateHeaders(h)
+	case TraceHeaderFormatW3C:
+		return extractW3CHeaders(h)
+	case TraceHeaderFormatB3:
+		if isHeaderSet(h, B3SingleHeader) {
+			return extractB3SingleHeader(h)
+		}
+		return extractB3MultiHeaders(h)
+	}
+}
+
+func extractBaseplateHeaders(h http.Header) (headers tracing.Headers, ok bool) {
+	if isHeaderSet(h, TraceIDHeader) {
+		headers.TraceID = h.Get(TraceIDHeader)
+		ok = true
+	}
+	if isHeaderSet(h, SpanIDHeader) {
+		headers.SpanID = h.Get(SpanIDHeader)
+		ok = true
+	}
+	if isHeaderSet(h, SpanFlagsHeader) {
+		headers.Flags = h.Get(SpanFlagsHeader)
+		ok = true
+	}
+	if isHeaderSet(h, SpanSampledHeader) {
+		sampled := h.Get(SpanSampledHeader) == spanSampledTrue
+		headers.Sampled = &sampled
+		ok = true
+	}
+	return headers, ok
+}
+
+// extractW3CHeaders parses the "traceparent" header in the format of:
+//
+//     {version}-{trace-id}-{parent-id}-{trace-flags}
+func extractW3CHeaders(h http.Header) (headers tracing.Headers, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h.Get(W3CTraceParentHeader)), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return headers, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return headers, false
+	}
+	traceID, ok := parseHexTraceID(parts[1])
+	if !ok {
+		return headers, false
+	}
+	spanID, ok := parseHexID(parts[2])
+	if !ok {
+		return headers, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return headers, false
+	}
+	sampled := flags&1 != 0
+	return tracing.Headers{
+		TraceID: traceID,
+		SpanID:  strconv.FormatUint(spanID, 10),
+		Sampled: &sampled,
+	}, true
+}
+
+// extractB3SingleHeader parses the "b3" header in the format of:
+//
+//     {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}
+//
+// where SamplingState and ParentSpanId are optional.
+// A header with only the SamplingState is not supported,
+// as it doesn't carry the ids.
+func extractB3SingleHeader(h http.Header) (headers tracing.Headers, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h.Get(B3SingleHeader)), "-")
+	if len(parts) < 2 {
+		return headers, false
+	}
+	traceID, ok := parseHexTraceID(parts[0])
+	if !ok {
+		return headers, false
+	}
+	spanID, ok := parseHexID(parts[1])
+	if !ok {
+		return headers, false
+	}
+	headers.TraceID = traceID
+	headers.SpanID = strconv.FormatUint(spanID, 10)
+	if len(parts) > 2 {
+		setB3SamplingState(&headers, parts[2])
+	}
+	return headers, true
+}
+
+func extractB3MultiHeaders(h http.Header) (headers tracing.Headers, ok bool) {
+	traceID, ok := parseHexTraceID(h.Get(B3TraceIDHeader))
+	if !ok {
+		return headers, false
+	}
+	spanID, ok := parseHexID(h.Get(B3SpanIDHeader))
+	if !ok {
+		return headers, false
+	}
+	headers.TraceID = traceID
+	headers.SpanID = strconv.FormatUint(spanID, 10)
+	if isHeaderSet(h, B3SampledHeader) {
+		setB3SamplingState(&headers, h.Get(B3SampledHeader))
+	}
+	if h.Get(B3FlagsHeader) == "1" {
+		setB3SamplingState(&headers, "d")
+	}
+	return headers, true
+}
+
+func setB3SamplingState(headers *tracing.Headers, state string) {
+	var sampled bool
+	switch state {
+	default:
+		return
+	case "1", "true":
+		sampled = true
+	case "0", "false":
+		sampled = false
+	case "d":
+		sampled = true
+		headers.Flags = strconv.FormatInt(tracing.FlagMaskDebug, 10)
+	}
+	headers.Sampled = &sampled
+}
+
+// parseHexTraceID parses a 64-bit or 128-bit hex encoded trace id into the
+// format of tracing.Headers.TraceID.
+//
+// 128-bit ids with all-zero higher 64 bits are converted to 64-bit ids.
+// All-zero ids are considered invalid.
+func parseHexTraceID(s string) (string, bool) {
+	if len(s) != 32 {
+		id, ok := parseHexID(s)
+		return strconv.FormatUint(id, 10), ok
+	}
+	high, err := strconv.ParseUint(s[:16], 16, 64)
+	if err != nil {
+		return "", false
+	}
+	low, ok := parseHexID(s[16:])
+	if !ok {
+		return "", false
+	}
+	if high == 0 {
+		return strconv.FormatUint(low, 10), true
+	}
+	return strings.ToLower(s), true
+}
+
+// parseHexID parses a 64-bit hex encoded id.
+//
+// All-zero ids are considered invalid.
+func parseHexID(s string) (uint64, bool) {
+	if len(s) == 0 || len(s) > 16 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(s, 16, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// injectSpanHeaders sets the span headers of the given formats to h.
+func injectSpanHeaders(ctx context.Context, h http.Header, span *tracing.Span, formats []TraceHeaderFormat) {
+	for _, format := range formats {
+		switch format {
+		case TraceHeaderFormatBaseplate:
+			setSpanHeaders(h, span)
+		case TraceHeaderFormatW3C:
+			setW3CHeaders(ctx, h, span)
+		case TraceHeaderFormatB3:
+			setB3Headers(h, span)
+		}
+	}
+}
+
+func setW3CHeaders(ctx context.Context, h http.Header, span *tracing.Span) {
+	flags := "00"
+	if span.Sampled() {
+		flags = "01"
+	}
+	h.Set(W3CTraceParentHeader, fmt.Sprintf(
+		"00-%016x%016x-%016x-%s",
+		span.TraceIDHigh(),
+		span.TraceID(),
+		span.ID(),
+		flags,
+	))
+	if state := traceStateFromContext(ctx); state != "" {
+		h.Set(W3CTraceStateHeader, state)
+	} else {
+		h.Del(W3CTraceStateHeader)
+	}
+}
+
+func setB3Headers(h http.Header, span *tracing.Span) {
+	h.Set(B3TraceIDHeader, span.TraceIDHex())
+	h.Set(B3SpanIDHeader, fmt.Sprintf("%016x", span.ID()))
+	if span.ParentID() != 0 {
+		h.Set(B3ParentSpanIDHeader, fmt.Sprintf("%016x", span.ParentID()))
+	} else {
+		h.Del(B3ParentSpanIDHeader)
+	}
+	if span.Flags()&tracing.FlagMaskDebug != 0 {
+		h.Set(B3FlagsHeader, "1")
+		h.Del(B3SampledHeader)
+	} else {
+		h.Del(B3FlagsHeader)
+		if span.Sampled() {
+			h.Set(B3SampledHeader, "1")
+		} else {
+			h.Set(B3SampledHeader, "0")
+		}
+	}
+}
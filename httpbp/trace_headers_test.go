@@ -0,0 +1,236 @@
+package httpbp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+func TestStartSpanFromTrustedRequestFormats(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers map[string]string
+		formats []httpbp.TraceHeaderFormat
+
+		expectedTraceID     uint64
+		expectedTraceIDHigh uint64
+		expectedParentID    uint64
+		expectedSampled     bool
+		expectedDebug       bool
+	}{
+		{
+			name: "baseplate",
+			headers: map[string]string{
+				httpbp.TraceIDHeader:     "1234",
+				httpbp.SpanIDHeader:      "5678",
+				httpbp.SpanSampledHeader: "1",
+			},
+			expectedTraceID:  1234,
+			expectedParentID: 5678,
+			expectedSampled:  true,
+		},
+		{
+			name: "w3c",
+			headers: map[string]string{
+				httpbp.W3CTraceParentHeader: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			},
+			expectedTraceID:     0x8448eb211c80319c,
+			expectedTraceIDHigh: 0x0af7651916cd43dd,
+			expectedParentID:    0xb7ad6b7169203331,
+			expectedSampled:     true,
+		},
+		{
+			name: "w3c-not-sampled",
+			headers: map[string]string{
+				httpbp.W3CTraceParentHeader: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00",
+			},
+			expectedTraceID:     0x8448eb211c80319c,
+			expectedTraceIDHigh: 0x0af7651916cd43dd,
+			expectedParentID:    0xb7ad6b7169203331,
+		},
+		{
+			name: "w3c-64-bit",
+			headers: map[string]string{
+				httpbp.W3CTraceParentHeader: "00-00000000000000008448eb211c80319c-b7ad6b7169203331-00",
+			},
+			expectedTraceID:  0x8448eb211c80319c,
+			expectedParentID: 0xb7ad6b7169203331,
+		},
+		{
+			name: "b3-single",
+			headers: map[string]string{
+				httpbp.B3SingleHeader: "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90",
+			},
+			expectedTraceID:     0x64fe8b2a57d3eff7,
+			expectedTraceIDHigh: 0x80f198ee56343ba8,
+			expectedParentID:    0xe457b5a2e4d86bd1,
+			expectedSampled:     true,
+		},
+		{
+			name: "b3-single-debug",
+			headers: map[string]string{
+				httpbp.B3SingleHeader: "64fe8b2a57d3eff7-e457b5a2e4d86bd1-d",
+			},
+			expectedTraceID:  0x64fe8b2a57d3eff7,
+			expectedParentID: 0xe457b5a2e4d86bd1,
+			expectedSampled:  true,
+			expectedDebug:    true,
+		},
+		{
+			name: "b3-multi",
+			headers: map[string]string{
+				httpbp.B3TraceIDHeader: "64fe8b2a57d3eff7",
+				httpbp.B3SpanIDHeader:  "e457b5a2e4d86bd1",
+				httpbp.B3SampledHeader: "1",
+			},
+			expectedTraceID:  0x64fe8b2a57d3eff7,
+			expectedParentID: 0xe457b5a2e4d86bd1,
+			expectedSampled:  true,
+		},
+		{
+			name: "baseplate-128-bit",
+			headers: map[string]string{
+				httpbp.TraceIDHeader: "80f198ee56343ba864fe8b2a57d3eff7",
+				httpbp.SpanIDHeader:  "5678",
+			},
+			expectedTraceID:     0x64fe8b2a57d3eff7,
+			expectedTraceIDHigh: 0x80f198ee56343ba8,
+			expectedParentID:    5678,
+		},
+		{
+			name: "default-priority",
+			headers: map[string]string{
+				httpbp.TraceIDHeader:        "1234",
+				httpbp.SpanIDHeader:         "5678",
+				httpbp.W3CTraceParentHeader: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			},
+			expectedTraceID:  1234,
+			expectedParentID: 5678,
+		},
+		{
+			name: "custom-priority",
+			headers: map[string]string{
+				httpbp.TraceIDHeader:        "1234",
+				httpbp.SpanIDHeader:         "5678",
+				httpbp.W3CTraceParentHeader: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			},
+			formats: []httpbp.TraceHeaderFormat{
+				httpbp.TraceHeaderFormatW3C,
+				httpbp.TraceHeaderFormatBaseplate,
+			},
+			expectedTraceID:     0x8448eb211c80319c,
+			expectedTraceIDHigh: 0x0af7651916cd43dd,
+			expectedParentID:    0xb7ad6b7169203331,
+			expectedSampled:     true,
+		},
+		{
+			name: "malformed-fallback",
+			headers: map[string]string{
+				httpbp.W3CTraceParentHeader: "00-00000000000000000000000000000000-b7ad6b7169203331-01",
+				httpbp.B3TraceIDHeader:      "64fe8b2a57d3eff7",
+				httpbp.B3SpanIDHeader:       "e457b5a2e4d86bd1",
+			},
+			expectedTraceID:  0x64fe8b2a57d3eff7,
+			expectedParentID: 0xe457b5a2e4d86bd1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+			_, span := httpbp.StartSpanFromTrustedRequest(
+				context.Background(),
+				"test",
+				httpbp.AlwaysTrustHeaders{},
+				r,
+				c.formats...,
+			)
+			if span.TraceID() != c.expectedTraceID {
+				t.Errorf("Expected trace id %d, got %d", c.expectedTraceID, span.TraceID())
+			}
+			if span.TraceIDHigh() != c.expectedTraceIDHigh {
+				t.Errorf("Expected trace id high %x, got %x", c.expectedTraceIDHigh, span.TraceIDHigh())
+			}
+			if span.ParentID() != c.expectedParentID {
+				t.Errorf("Expected parent id %d, got %d", c.expectedParentID, span.ParentID())
+			}
+			if span.Sampled() != c.expectedSampled {
+				t.Errorf("Expected sampled %v, got %v", c.expectedSampled, span.Sampled())
+			}
+			if debug := span.Flags()&tracing.FlagMaskDebug != 0; debug != c.expectedDebug {
+				t.Errorf("Expected debug %v, got %v", c.expectedDebug, debug)
+			}
+		})
+	}
+}
+
+func TestMonitorClientTraceHeaderFormats(t *testing.T) {
+	tracingtest.InitGlobalTracer(t)
+
+	const traceState = "vendor=value"
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+		},
+	))
+	defer server.Close()
+
+	incoming := httptest.NewRequest(http.MethodGet, "/", nil)
+	incoming.Header.Set(httpbp.W3CTraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	incoming.Header.Set(httpbp.W3CTraceStateHeader, traceState)
+	ctx, serverSpan := httpbp.StartSpanFromTrustedRequest(
+		context.Background(),
+		"server",
+		httpbp.AlwaysTrustHeaders{},
+		incoming,
+	)
+	defer serverSpan.Finish()
+
+	client := httpbp.NewClient(httpbp.ClientConfig{
+		Slug: "test",
+		TraceHeaderFormats: []httpbp.TraceHeaderFormat{
+			httpbp.TraceHeaderFormatBaseplate,
+			httpbp.TraceHeaderFormatW3C,
+			httpbp.TraceHeaderFormatB3,
+		},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for header, expected := range map[string]string{
+		httpbp.TraceIDHeader:        "0af7651916cd43dd8448eb211c80319c",
+		httpbp.ParentIDHeader:       strconv.FormatUint(serverSpan.ID(), 10),
+		httpbp.SpanSampledHeader:    "1",
+		httpbp.W3CTraceStateHeader:  traceState,
+		httpbp.B3TraceIDHeader:      "0af7651916cd43dd8448eb211c80319c",
+		httpbp.B3ParentSpanIDHeader: fmt.Sprintf("%016x", serverSpan.ID()),
+		httpbp.B3SampledHeader:      "1",
+	} {
+		if actual := received.Get(header); actual != expected {
+			t.Errorf("Expected header %q to be %q, got %q", header, expected, actual)
+		}
+	}
+
+	spanID := received.Get(httpbp.B3SpanIDHeader)
+	expectedParent := "00-0af7651916cd43dd8448eb211c80319c-" + spanID + "-01"
+	if actual := received.Get(httpbp.W3CTraceParentHeader); actual != expectedParent {
+		t.Errorf("Expected traceparent %q, got %q", expectedParent, actual)
+	}
+}
@@ -0,0 +1,24 @@
+// Autogenerated by Thrift Compiler (0.13.0)
+// DO NOT EDIT UNLESS YOU ARE SURE THAT YOU KNOW WHAT YOU ARE DOING
+
+package baseplatetest
+
+import(
+	"bytes"
+	"context"
+	"reflect"
+	"fmt"
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// (needed to ensure safety because of naive import list construction.)
+var _ = thrift.ZERO
+var _ = fmt.Printf
+var _ = context.Background
+var _ = reflect.DeepEqual
+var _ = bytes.Equal
+
+
+func init() {
+}
+
@@ -0,0 +1,7 @@
+// Autogenerated by Thrift Compiler (0.13.0)
+// DO NOT EDIT UNLESS YOU ARE SURE THAT YOU KNOW WHAT YOU ARE DOING
+
+package requestevent
+
+var GoUnusedProtection__ int;
+
@@ -0,0 +1,15 @@
+namespace go reddit.baseplatetest
+
+/** A copy of the Error exception defined in baseplate.thrift. */
+exception Error {
+    1: optional i32 code;
+    2: optional string message;
+    3: optional map<string, string> details;
+    4: optional bool retryable;
+}
+
+/** The service used by the tests of the thriftbp package. */
+service TestService {
+    /** Return the message, or raise an Error. */
+    string echo(1: string message) throws (1: Error err),
+}
@@ -0,0 +1,15 @@
+namespace go reddit.requestevent
+
+/** The canonical "wide event" of a single request.
+
+It's published once at the end of every request by the server middlewares of
+the requestevent package.
+
+*/
+struct RequestEvent {
+    /** The string fields of the request, e.g. endpoint, user_agent. */
+    1: map<string, string> strings;
+
+    /** The number fields of the request, e.g. duration_ms, success. */
+    2: map<string, double> numbers;
+}
@@ -0,0 +1,299 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Span tags set on the server spans created by the consumer.
+const (
+	SpanTagKeyTopic     = "kafka.topic"
+	SpanTagKeyPartition = "kafka.partition"
+	SpanTagKeyOffset    = "kafka.offset"
+	SpanTagKeyGroup     = "kafka.group"
+)
+
+// Message is a message consumed from Kafka.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string][]byte
+	Timestamp time.Time
+}
+
+// ConsumerGroupSession is the minimal interface of a consumer group session
+// needed by the consumer.
+//
+// A session lasts from joining the group until the next rebalance.
+type ConsumerGroupSession interface {
+	// Context returns the context of the session,
+	// which is canceled when the session ends.
+	Context() context.Context
+
+	// MarkMessage marks the message as consumed.
+	MarkMessage(msg *Message)
+
+	// Commit commits all the marked offsets synchronously.
+	Commit()
+}
+
+// ConsumerGroupClaim is the minimal interface of a single partition claimed
+// by a consumer group session.
+type ConsumerGroupClaim interface {
+	Topic() string
+	Partition() int32
+
+	// Messages returns the channel of the messages in the claimed partition.
+	//
+	// The channel should be closed when the session ends.
+	Messages() <-chan *Message
+}
+
+// ConsumerGroupHandler handles the claims of consumer group sessions.
+//
+// Setup is called at the beginning of a session, before any ConsumeClaim
+// calls.
+// ConsumeClaim is called in its own goroutine for every claimed partition.
+// Cleanup is called at the end of a session,
+// after all the ConsumeClaim calls returned.
+type ConsumerGroupHandler interface {
+	Setup(session ConsumerGroupSession) error
+	Cleanup(session ConsumerGroupSession) error
+	ConsumeClaim(session ConsumerGroupSession, claim ConsumerGroupClaim) error
+}
+
+// ConsumerGroup is the minimal interface of a Kafka consumer group client
+// needed by the consumer.
+type ConsumerGroup interface {
+	io.Closer
+
+	// Consume joins the consumer group and handles the claims of the session
+	// with handler.
+	//
+	// It should block until the session ends, e.g. on rebalance or when ctx is
+	// canceled.
+	Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error
+}
+
+// MessageHandler handles a single message consumed from Kafka.
+//
+// The context passed in has the server span of the message attached.
+//
+// Errors returned by MessageHandler are logged and attached to the server
+// span, but the message will still be marked as consumed,
+// so a single bad message won't block the whole partition.
+// Panics in MessageHandler are recovered and handled the same way as errors.
+// Handlers need to retry transient errors by themselves (e.g. with retrybp).
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// ConsumerArgs are the args used to create a new consumer.
+type ConsumerArgs struct {
+	// Required. The Baseplate the consumer is built on.
+	Baseplate baseplate.Baseplate
+
+	// Required. The consumer group client used to consume messages.
+	Group ConsumerGroup
+
+	// Required. The name of the consumer group,
+	// it's used as the name of the server spans and tagged on them.
+	GroupName string
+
+	// Required. The topics to consume.
+	Topics []string
+
+	// Required. The handler to handle every message.
+	Handler MessageHandler
+}
+
+// Validate checks ConsumerArgs for any missing or erroneous values.
+func (args ConsumerArgs) Validate() error {
+	switch {
+	case args.Baseplate == nil:
+		return errors.New("kafkabp: Baseplate is required")
+	case args.Group == nil:
+		return errors.New("kafkabp: Group is required")
+	case args.GroupName == "":
+		return errors.New("kafkabp: GroupName is required")
+	case len(args.Topics) == 0:
+		return errors.New("kafkabp: Topics is required")
+	case args.Handler == nil:
+		return errors.New("kafkabp: Handler is required")
+	}
+	return nil
+}
+
+// The backoff between rejoining the consumer group.
+//
+// It starts from rejoinBackoffMin and doubles every time the previous session
+// ended in less than rejoinBackoffMax,
+// so a consumer group failing to get any claims won't hot loop.
+const (
+	rejoinBackoffMin = time.Millisecond * 100
+	rejoinBackoffMax = time.Second * 10
+)
+
+// NewConsumer creates a new baseplate.Server consuming Kafka messages.
+//
+// Serve keeps rejoining the consumer group after every rebalance until Close
+// is called, with an exponential backoff when the sessions end quickly.
+// Close stops consuming new messages, waits for the in-flight messages to be
+// handled and their offsets committed, then closes the ConsumerGroup.
+func NewConsumer(args ConsumerArgs) (baseplate.Server, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &consumer{
+		args:   args,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+type consumer struct {
+	args ConsumerArgs
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg sync.WaitGroup
+}
+
+var (
+	_ baseplate.Server     = (*consumer)(nil)
+	_ ConsumerGroupHandler = (*consumer)(nil)
+)
+
+func (c *consumer) Baseplate() baseplate.Baseplate {
+	return c.args.Baseplate
+}
+
+func (c *consumer) Serve() error {
+	c.wg.Add(1)
+	defer c.wg.Done()
+
+	backoff := rejoinBackoffMin
+	for {
+		start := time.Now()
+		if err := c.args.Group.Consume(c.ctx, c.args.Topics, c); err != nil {
+			if c.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if time.Since(start) >= rejoinBackoffMax {
+			backoff = rejoinBackoffMin
+		}
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > rejoinBackoffMax {
+			backoff = rejoinBackoffMax
+		}
+	}
+}
+
+func (c *consumer) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	return c.args.Group.Close()
+}
+
+// Setup implements ConsumerGroupHandler.
+func (c *consumer) Setup(ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup implements ConsumerGroupHandler.
+//
+// It commits the marked offsets before the partitions are reassigned.
+func (c *consumer) Cleanup(session ConsumerGroupSession) error {
+	session.Commit()
+	return nil
+}
+
+// ConsumeClaim implements ConsumerGroupHandler.
+func (c *consumer) ConsumeClaim(session ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	ctx := session.Context()
+	for {
+		// Check the context first so we stop taking new messages as soon as the
+		// session ends, even if there are still buffered messages.
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			c.handle(msg)
+			session.MarkMessage(msg)
+		}
+	}
+}
+
+func (c *consumer) handle(msg *Message) {
+	span := tracing.AsSpan(opentracing.StartSpan(
+		c.args.GroupName,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeServer},
+	))
+	span.SetTag(SpanTagKeyGroup, c.args.GroupName)
+	span.SetTag(SpanTagKeyTopic, msg.Topic)
+	span.SetTag(SpanTagKeyPartition, msg.Partition)
+	span.SetTag(SpanTagKeyOffset, msg.Offset)
+
+	// Don't let the end of the session cancel an in-flight message.
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	ctx = span.InjectSentryHub(ctx)
+
+	err := c.callHandler(ctx, msg)
+	if err != nil {
+		log.Named("kafkabp").Errorw(
+			"kafkabp: Failed to handle message",
+			"err", err,
+			"topic", msg.Topic,
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+		)
+	}
+	span.Stop(ctx, err)
+}
+
+// callHandler calls the MessageHandler,
+// and converts the panics in it into errors.
+func (c *consumer) callHandler(ctx context.Context, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Named("kafkabp").Errorw(
+				"kafkabp: Recovered from panic in message handler",
+				"panic", r,
+				"stack", string(debug.Stack()),
+			)
+			err = fmt.Errorf("kafkabp: recovered from panic in message handler: %v", r)
+		}
+	}()
+	return c.args.Handler(ctx, msg)
+}
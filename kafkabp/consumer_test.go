@@ -0,0 +1,218 @@
+package kafkabp_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/kafkabp"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+type fakeSession struct {
+	ctx context.Context
+
+	lock      sync.Mutex
+	marked    []int64
+	committed []int64
+}
+
+func (s *fakeSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeSession) MarkMessage(msg *kafkabp.Message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+func (s *fakeSession) Commit() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.committed = append([]int64(nil), s.marked...)
+}
+
+type fakeClaim struct {
+	messages chan *kafkabp.Message
+}
+
+func (fakeClaim) Topic() string {
+	return "topic"
+}
+
+func (fakeClaim) Partition() int32 {
+	return 1
+}
+
+func (c fakeClaim) Messages() <-chan *kafkabp.Message {
+	return c.messages
+}
+
+// fakeGroup runs a single session with a single claim,
+// and blocks until ctx is canceled.
+type fakeGroup struct {
+	claim   fakeClaim
+	session *fakeSession
+	closed  bool
+}
+
+func (g *fakeGroup) Consume(ctx context.Context, topics []string, handler kafkabp.ConsumerGroupHandler) error {
+	g.session = &fakeSession{ctx: ctx}
+	if err := handler.Setup(g.session); err != nil {
+		return err
+	}
+	if err := handler.ConsumeClaim(g.session, g.claim); err != nil {
+		return err
+	}
+	return handler.Cleanup(g.session)
+}
+
+func (g *fakeGroup) Close() error {
+	g.closed = true
+	return nil
+}
+
+// fakeBaseplate is a baseplate.Baseplate only used for its identity.
+type fakeBaseplate struct {
+	baseplate.Baseplate
+}
+
+func TestConsumer(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	group := &fakeGroup{
+		claim: fakeClaim{messages: make(chan *kafkabp.Message, 3)},
+	}
+	group.claim.messages <- &kafkabp.Message{Topic: "topic", Partition: 1, Offset: 10}
+	group.claim.messages <- &kafkabp.Message{Topic: "topic", Partition: 1, Offset: 11}
+	group.claim.messages <- &kafkabp.Message{Topic: "topic", Partition: 1, Offset: 12}
+
+	var handled sync.WaitGroup
+	handled.Add(3)
+	server, err := kafkabp.NewConsumer(kafkabp.ConsumerArgs{
+		Baseplate: fakeBaseplate{},
+		Group:     group,
+		GroupName: "group",
+		Topics:    []string{"topic"},
+		Handler: func(ctx context.Context, msg *kafkabp.Message) error {
+			defer handled.Done()
+			if opentracing.SpanFromContext(ctx) == nil {
+				t.Error("Expected server span in context")
+			}
+			switch msg.Offset {
+			case 11:
+				return errors.New("failed")
+			case 12:
+				panic("oops")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve()
+	}()
+	handled.Wait()
+
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected Serve to return nil, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+
+	if !group.closed {
+		t.Error("Expected group to be closed")
+	}
+	if committed := group.session.committed; len(committed) != 3 {
+		t.Errorf("Expected 3 committed offsets, got %v", committed)
+	}
+
+	spans := recorder.FindSpans("group")
+	if len(spans) != 3 {
+		t.Fatalf("Expected 2 spans, got %+v", recorder.Spans())
+	}
+	for i, span := range spans {
+		if v, _ := span.Tag(kafkabp.SpanTagKeyTopic); v != "topic" {
+			t.Errorf("Expected topic tag %q, got %v", "topic", v)
+		}
+		if v, _ := span.Tag(kafkabp.SpanTagKeyPartition); v != "1" {
+			t.Errorf("Expected partition tag %q, got %v", "1", v)
+		}
+		expectedOffset := []string{"10", "11", "12"}[i]
+		if v, _ := span.Tag(kafkabp.SpanTagKeyOffset); v != expectedOffset {
+			t.Errorf("Expected offset tag %q, got %v", expectedOffset, v)
+		}
+		if expectedError := i > 0; span.IsError() != expectedError {
+			t.Errorf("Expected span %d IsError to be %v", i, expectedError)
+		}
+	}
+}
+
+// emptyGroup is a ConsumerGroup never getting any claims.
+type emptyGroup struct {
+	calls int32
+}
+
+func (g *emptyGroup) Consume(ctx context.Context, topics []string, handler kafkabp.ConsumerGroupHandler) error {
+	atomic.AddInt32(&g.calls, 1)
+	return nil
+}
+
+func (g *emptyGroup) Close() error {
+	return nil
+}
+
+func TestConsumerRejoinBackoff(t *testing.T) {
+	group := new(emptyGroup)
+	server, err := kafkabp.NewConsumer(kafkabp.ConsumerArgs{
+		Baseplate: fakeBaseplate{},
+		Group:     group,
+		GroupName: "group",
+		Topics:    []string{"topic"},
+		Handler: func(ctx context.Context, msg *kafkabp.Message) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve()
+	}()
+	time.Sleep(time.Millisecond * 500)
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-served
+
+	// With the backoff of 100ms, 200ms, 400ms... there should be at most 3 calls.
+	if calls := atomic.LoadInt32(&group.calls); calls > 3 {
+		t.Errorf("Expected at most 3 Consume calls, got %d", calls)
+	}
+}
+
+func TestConsumerArgsValidate(t *testing.T) {
+	_, err := kafkabp.NewConsumer(kafkabp.ConsumerArgs{})
+	if err == nil {
+		t.Error("Expected error for empty ConsumerArgs")
+	}
+}
@@ -0,0 +1,24 @@
+// Package kafkabp provides Baseplate specific Kafka related helpers.
+//
+// Consumers
+//
+// NewConsumer creates a baseplate.Server consuming messages as part of a Kafka
+// consumer group.
+// Every message is handled inside its own server span tagged with the topic,
+// partition and offset of the message,
+// and the offset is only marked as consumed after the handler returns,
+// so an in-flight message is never lost during a rebalance or a graceful
+// shutdown via baseplate.Serve.
+//
+// Producers
+//
+// NewProducer wraps a Kafka producer client with a client span per published
+// message and delivery metrics.
+// It supports both synchronous publishing (Publish) and asynchronous
+// publishing with a bounded queue (PublishAsync).
+//
+// This package does not depend on any specific Kafka client library.
+// Instead it defines the minimal ConsumerGroup and SyncProducer interfaces it
+// needs, and the caller should provide adapters for the Kafka client library
+// of their choice.
+package kafkabp
@@ -0,0 +1,218 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// DefaultMaxInFlight is the default MaxInFlight used by NewProducer.
+const DefaultMaxInFlight = 1000
+
+// ErrProducerQueueFull is the error returned by PublishAsync when the message
+// is dropped because there are already MaxInFlight messages waiting to be
+// published.
+var ErrProducerQueueFull = errors.New("kafkabp: producer queue is full")
+
+// ErrProducerClosed is the error returned by Publish and PublishAsync after
+// the Producer is closed.
+var ErrProducerClosed = errors.New("kafkabp: producer is closed")
+
+// SyncProducer is the minimal interface of a Kafka producer client needed by
+// the Producer.
+type SyncProducer interface {
+	io.Closer
+
+	// SendMessage publishes the message and blocks until it's acknowledged by
+	// the broker.
+	SendMessage(msg *Message) (partition int32, offset int64, err error)
+}
+
+// ProducerConfig is the configuration of a Producer.
+//
+// Can be deserialized from YAML.
+type ProducerConfig struct {
+	// Name is the name of the producer,
+	// used as the prefix of the client spans and metrics.
+	Name string `yaml:"name"`
+
+	// MaxInFlight is the max number of messages waiting to be published by
+	// PublishAsync.
+	//
+	// If MaxInFlight <= 0, DefaultMaxInFlight will be used.
+	MaxInFlight int `yaml:"maxInFlight"`
+}
+
+// Producer wraps a SyncProducer with tracing and metrics.
+//
+// Every publish creates a client span named "<name>.publish",
+// and reports the following metrics with metricsbp.M:
+//
+// - <name>.publish: timing of the delivery latency,
+// including the time waiting in the queue for async publishes;
+//
+// - <name>.publish.success and <name>.publish.fail: counters of the delivery
+// results;
+//
+// - <name>.publish.dropped: counter of the messages dropped by PublishAsync.
+type Producer struct {
+	client SyncProducer
+	name   string
+
+	latency metrics.Histogram
+	success metrics.Counter
+	fail    metrics.Counter
+	dropped metrics.Counter
+
+	lock   sync.RWMutex
+	closed bool
+	queue  chan asyncMessage
+	wg     sync.WaitGroup
+}
+
+type asyncMessage struct {
+	span  opentracing.Span
+	timer *metricsbp.Timer
+	msg   *Message
+}
+
+// NewProducer creates a new Producer.
+//
+// It starts a background goroutine to publish the messages from
+// PublishAsync, which stops when Close is called.
+func NewProducer(client SyncProducer, cfg ProducerConfig) *Producer {
+	maxInFlight := cfg.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	name := cfg.Name + ".publish"
+	p := &Producer{
+		client:  client,
+		name:    name,
+		latency: metricsbp.M.Timing(name),
+		success: metricsbp.M.Counter(name + ".success"),
+		fail:    metricsbp.M.Counter(name + ".fail"),
+		dropped: metricsbp.M.Counter(name + ".dropped"),
+		queue:   make(chan asyncMessage, maxInFlight),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Publish publishes the message and blocks until it's acknowledged.
+//
+// On success, the Partition and Offset of msg are set to the ones assigned by
+// the broker.
+func (p *Producer) Publish(ctx context.Context, msg *Message) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	ctx, span, timer := p.start(ctx, msg)
+	return p.send(ctx, span, timer, msg)
+}
+
+// PublishAsync queues the message to be published in the background and
+// returns immediately.
+//
+// If there are already MaxInFlight messages in the queue, the message will be
+// dropped and ErrProducerQueueFull will be returned.
+// Delivery errors are logged and reported via metrics and the client span.
+//
+// A shallow copy of msg is queued, so the Partition and Offset of msg are not
+// set, and msg can be reused after PublishAsync returns.
+// But the Key, Value and Headers are not copied,
+// they should not be modified until the message is published.
+func (p *Producer) PublishAsync(ctx context.Context, msg *Message) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	ctx, span, timer := p.start(ctx, msg)
+	copied := *msg
+	select {
+	case p.queue <- asyncMessage{span: span, timer: timer, msg: &copied}:
+		return nil
+	default:
+		p.dropped.Add(1)
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: ErrProducerQueueFull,
+		}.Convert())
+		return ErrProducerQueueFull
+	}
+}
+
+// Close stops accepting new messages, waits for all the queued messages to be
+// published, then closes the underlying SyncProducer.
+func (p *Producer) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.lock.Unlock()
+
+	p.wg.Wait()
+	return p.client.Close()
+}
+
+func (p *Producer) run() {
+	defer p.wg.Done()
+
+	for m := range p.queue {
+		// The context of the caller is likely already canceled by now.
+		if err := p.send(context.Background(), m.span, m.timer, m.msg); err != nil {
+			log.Named("kafkabp").Errorw(
+				"kafkabp: Failed to publish message",
+				"err", err,
+				"topic", m.msg.Topic,
+			)
+		}
+	}
+}
+
+func (p *Producer) start(ctx context.Context, msg *Message) (context.Context, opentracing.Span, *metricsbp.Timer) {
+	timer := metricsbp.NewTimer(p.latency)
+	span, ctx := opentracing.StartSpanFromContext(
+		ctx,
+		p.name,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	span.SetTag(SpanTagKeyTopic, msg.Topic)
+	return ctx, span, timer
+}
+
+func (p *Producer) send(ctx context.Context, span opentracing.Span, timer *metricsbp.Timer, msg *Message) error {
+	partition, offset, err := p.client.SendMessage(msg)
+	timer.ObserveDuration()
+	if err != nil {
+		p.fail.Add(1)
+	} else {
+		p.success.Add(1)
+		msg.Partition = partition
+		msg.Offset = offset
+		span.SetTag(SpanTagKeyPartition, partition)
+		span.SetTag(SpanTagKeyOffset, offset)
+	}
+	span.FinishWithOptions(tracing.FinishOptions{
+		Ctx: ctx,
+		Err: err,
+	}.Convert())
+	return err
+}
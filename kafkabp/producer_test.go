@@ -0,0 +1,130 @@
+package kafkabp_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/kafkabp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+type fakeProducer struct {
+	lock   sync.Mutex
+	sent   []*kafkabp.Message
+	block  chan struct{}
+	err    error
+	closed bool
+}
+
+func (p *fakeProducer) SendMessage(msg *kafkabp.Message) (int32, int64, error) {
+	if p.block != nil {
+		<-p.block
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.err != nil {
+		return 0, 0, p.err
+	}
+	p.sent = append(p.sent, msg)
+	return 2, int64(len(p.sent)), nil
+}
+
+func (p *fakeProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestProducerPublish(t *testing.T) {
+	metrics := metricstest.Replace(t)
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	client := &fakeProducer{}
+	producer := kafkabp.NewProducer(client, kafkabp.ProducerConfig{Name: "producer"})
+	defer producer.Close()
+
+	msg := &kafkabp.Message{Topic: "topic"}
+	if err := producer.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Partition != 2 || msg.Offset != 1 {
+		t.Errorf("Expected partition 2 offset 1, got %d %d", msg.Partition, msg.Offset)
+	}
+
+	client.err = errors.New("failed")
+	if err := producer.Publish(context.Background(), &kafkabp.Message{Topic: "topic"}); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	metrics.AssertCounterEquals(t, "producer.publish.success", 1)
+	metrics.AssertCounterEquals(t, "producer.publish.fail", 1)
+	metrics.AssertHistogramCount(t, "producer.publish", 2)
+
+	spans := recorder.FindSpans("producer.publish")
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %+v", recorder.Spans())
+	}
+	if v, _ := spans[0].Tag(kafkabp.SpanTagKeyTopic); v != "topic" {
+		t.Errorf("Expected topic tag %q, got %v", "topic", v)
+	}
+	if v, _ := spans[0].Tag(kafkabp.SpanTagKeyOffset); v != "1" {
+		t.Errorf("Expected offset tag %q, got %v", "1", v)
+	}
+	if spans[0].IsError() || !spans[1].IsError() {
+		t.Errorf("Expected only the second span to be error, got %+v", spans)
+	}
+}
+
+func TestProducerPublishAsync(t *testing.T) {
+	metrics := metricstest.Replace(t)
+
+	client := &fakeProducer{block: make(chan struct{})}
+	producer := kafkabp.NewProducer(client, kafkabp.ProducerConfig{
+		Name:        "producer",
+		MaxInFlight: 1,
+	})
+
+	// The first message is taken by the background goroutine and blocked in
+	// SendMessage, the second one fills the queue, so the rest are dropped.
+	// Since we don't know when the first message is taken, keep publishing
+	// until one is dropped.
+	var published int
+	var msgs []*kafkabp.Message
+	for {
+		msg := &kafkabp.Message{Topic: "topic"}
+		msgs = append(msgs, msg)
+		err := producer.PublishAsync(context.Background(), msg)
+		if errors.Is(err, kafkabp.ErrProducerQueueFull) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		published++
+	}
+	close(client.block)
+
+	if err := producer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !client.closed {
+		t.Error("Expected client to be closed")
+	}
+	if len(client.sent) != published {
+		t.Errorf("Expected %d messages sent, got %d", published, len(client.sent))
+	}
+	metrics.AssertCounterEquals(t, "producer.publish.dropped", 1)
+	metrics.AssertCounterEquals(t, "producer.publish.success", float64(published))
+	for _, msg := range msgs {
+		// PublishAsync publishes a copy of msg.
+		if msg.Offset != 0 {
+			t.Errorf("Expected Offset of msg not set by PublishAsync, got %d", msg.Offset)
+		}
+	}
+
+	if err := producer.PublishAsync(context.Background(), &kafkabp.Message{}); !errors.Is(err, kafkabp.ErrProducerClosed) {
+		t.Errorf("Expected ErrProducerClosed after Close, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+// Package liveconfig provides live configuration documents bound to structs.
+//
+// A live configuration document is a JSON or YAML file watched by
+// filewatcher.
+// Every time the file changes,
+// it's decoded into a new value of the bound struct and validated,
+// and the new value replaces the previous one atomically if it's valid.
+// Invalid changes are logged and ignored, so the last valid value is kept.
+//
+// Alternatively, the document can be stored in a ZooKeeper node watched by
+// NewZooKeeper, for parity with the services getting their live
+// configuration from ZooKeeper.
+//
+// WatchLogLevels is a ready-to-use binding changing the levels of the named
+// loggers (see log.Named) at runtime,
+// e.g. to turn on the debug logs of a package in production temporarily.
+//
+// As Go doesn't have generics,
+// the typed binding is usually done by wrapping the Watcher:
+//
+//     type Limits struct {
+//       MaxRequestsPerSecond int  `json:"maxRequestsPerSecond"`
+//       EnableNewFeature     bool `json:"enableNewFeature"`
+//     }
+//
+//     func (l *Limits) Validate() error {
+//       if l.MaxRequestsPerSecond <= 0 {
+//         return errors.New("maxRequestsPerSecond must be positive")
+//       }
+//       return nil
+//     }
+//
+//     type LiveLimits struct {
+//       *liveconfig.Watcher
+//     }
+//
+//     func (l LiveLimits) Get() *Limits {
+//       return l.Watcher.Get().(*Limits)
+//     }
+package liveconfig
@@ -0,0 +1,220 @@
+package liveconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// Format is the format of a live configuration document.
+type Format int
+
+// Supported formats.
+const (
+	// FormatAuto detects the format from the file extension:
+	// ".yaml" and ".yml" files are YAML, all other files are JSON.
+	FormatAuto Format = iota
+	FormatJSON
+	FormatYAML
+)
+
+func (f Format) String() string {
+	switch f {
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	case FormatAuto:
+		return "auto"
+	case FormatJSON:
+		return "json"
+	case FormatYAML:
+		return "yaml"
+	}
+}
+
+// detect returns the actual format of the document at path.
+func (f Format) detect(path string) Format {
+	if f != FormatAuto {
+		return f
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// Validator can be implemented by the bound struct to validate the decoded
+// document.
+//
+// When Validate returns an error, the document is rejected.
+type Validator interface {
+	Validate() error
+}
+
+// A Subscriber is called when the live configuration document changed.
+//
+// oldValue and newValue are the values returned by Watcher.Get before and after
+// the change.
+// Subscribers are called sequentially from the watcher goroutine,
+// so they should return quickly.
+type Subscriber func(oldValue, newValue interface{})
+
+// Config is the arg struct for New.
+type Config struct {
+	// The path to the live configuration document, required.
+	Path string
+
+	// New returns a pointer to a new value of the struct the document is
+	// decoded into, required.
+	//
+	// It's called before every decode, and the fields set by New are the
+	// defaults for the fields missing from the document.
+	//
+	// If the returned value implements Validator,
+	// it will be validated after decoded.
+	New func() interface{}
+
+	// Optional, the format of the document. Default to FormatAuto.
+	Format Format
+
+	// Optional. When non-nil, it will be used to log the rejected changes.
+	Logger log.Wrapper
+
+	// Optional, see filewatcher.Config.MaxFileSize.
+	MaxFileSize int64
+
+	// Optional, see filewatcher.Config.PollingInterval.
+	PollingInterval time.Duration
+}
+
+// ErrNewRequired is returned by New when Config.New is nil.
+var ErrNewRequired = errors.New("liveconfig: Config.New is required")
+
+// Watcher is a live configuration document bound to a struct,
+// created by New or NewZooKeeper.
+type Watcher struct {
+	format Format
+	newFn  func() interface{}
+
+	value atomic.Value
+	stop  func()
+
+	lock        sync.Mutex
+	subscribers []Subscriber
+}
+
+// New creates a new Watcher.
+//
+// Like filewatcher.New, if the path is not available at the time of calling,
+// it blocks until the file becomes available, or context is cancelled,
+// whichever comes first.
+//
+// If the initial document cannot be decoded or is invalid,
+// the error is returned directly.
+func New(ctx context.Context, cfg Config) (*Watcher, error) {
+	if cfg.New == nil {
+		return nil, ErrNewRequired
+	}
+
+	w := &Watcher{
+		format: cfg.Format.detect(cfg.Path),
+		newFn:  cfg.New,
+	}
+	result, err := filewatcher.New(
+		ctx,
+		filewatcher.Config{
+			Path:            cfg.Path,
+			Parser:          w.parse,
+			Logger:          cfg.Logger,
+			MaxFileSize:     cfg.MaxFileSize,
+			PollingInterval: cfg.PollingInterval,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	w.stop = result.Stop
+	return w, nil
+}
+
+// Get returns the latest valid value of the document.
+//
+// The returned value is a pointer returned by Config.New,
+// and is shared by all the callers until the next change,
+// so it must not be modified.
+func (w *Watcher) Get() interface{} {
+	return w.value.Load()
+}
+
+// Subscribe registers a Subscriber to be called on every accepted change of
+// the document.
+//
+// Changes producing a value deeply equal to the current one do not call the
+// subscribers.
+func (w *Watcher) Subscribe(s Subscriber) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.subscribers = append(w.subscribers, s)
+}
+
+// Stop stops watching the document.
+//
+// After Stop is called, Get keeps returning the last valid value.
+//
+// It's OK to call Stop multiple times.
+func (w *Watcher) Stop() {
+	w.stop()
+}
+
+// parse is the filewatcher.Parser of the document.
+//
+// It also stores the decoded value and calls the subscribers,
+// so that Get returns the new value by the time subscribers are called.
+func (w *Watcher) parse(r io.Reader) (interface{}, error) {
+	v := w.newFn()
+	if err := w.decode(r, v); err != nil {
+		return nil, fmt.Errorf("liveconfig: failed to decode %s document: %w", w.format, err)
+	}
+	if validator, ok := v.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("liveconfig: invalid document: %w", err)
+		}
+	}
+
+	old := w.value.Load()
+	w.value.Store(v)
+	if old != nil && !reflect.DeepEqual(old, v) {
+		w.lock.Lock()
+		subscribers := w.subscribers
+		w.lock.Unlock()
+		for _, s := range subscribers {
+			s(old, v)
+		}
+	}
+	return v, nil
+}
+
+func (w *Watcher) decode(r io.Reader, v interface{}) error {
+	switch w.format {
+	default:
+		return fmt.Errorf("unsupported format %v", w.format)
+	case FormatJSON:
+		return json.NewDecoder(r).Decode(v)
+	case FormatYAML:
+		return yaml.NewDecoder(r).Decode(v)
+	}
+}
@@ -0,0 +1,152 @@
+package liveconfig_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/liveconfig"
+	"github.com/reddit/baseplate.go/log"
+)
+
+type limits struct {
+	MaxRequests int  `json:"maxRequests" yaml:"maxRequests"`
+	Enabled     bool `json:"enabled" yaml:"enabled"`
+}
+
+func (l *limits) Validate() error {
+	if l.MaxRequests <= 0 {
+		return errors.New("maxRequests must be positive")
+	}
+	return nil
+}
+
+func newLimits() interface{} {
+	return &limits{MaxRequests: 10}
+}
+
+// writeFile atomically replaces the file at path with content.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newWatcher(t *testing.T, name, content string, logger log.Wrapper) (*liveconfig.Watcher, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "liveconfig_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, name)
+	writeFile(t, path, content)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	w, err := liveconfig.New(ctx, liveconfig.Config{
+		Path:   path,
+		New:    newLimits,
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+	return w, path
+}
+
+func TestWatcher(t *testing.T) {
+	w, path := newWatcher(t, "limits.json", `{"enabled": true}`, log.TestWrapper(t))
+
+	expected := limits{MaxRequests: 10, Enabled: true}
+	if actual := *w.Get().(*limits); actual != expected {
+		t.Errorf("Expected %+v, got %+v", expected, actual)
+	}
+
+	changes := make(chan [2]limits, 1)
+	w.Subscribe(func(oldValue, newValue interface{}) {
+		changes <- [2]limits{*oldValue.(*limits), *newValue.(*limits)}
+	})
+
+	writeFile(t, path, `{"maxRequests": 20, "enabled": true}`)
+	select {
+	case change := <-changes:
+		expectedNew := limits{MaxRequests: 20, Enabled: true}
+		if change[0] != expected || change[1] != expectedNew {
+			t.Errorf("Expected change from %+v to %+v, got %+v", expected, expectedNew, change)
+		}
+		if actual := *w.Get().(*limits); actual != expectedNew {
+			t.Errorf("Expected %+v, got %+v", expectedNew, actual)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscriber not called after change")
+	}
+}
+
+func TestWatcherInvalidChange(t *testing.T) {
+	var logged int64
+	logger := func(msg string) {
+		atomic.AddInt64(&logged, 1)
+	}
+	w, path := newWatcher(t, "limits.yaml", "maxRequests: 5\n", logger)
+
+	called := make(chan struct{}, 1)
+	w.Subscribe(func(_, _ interface{}) {
+		called <- struct{}{}
+	})
+
+	writeFile(t, path, "maxRequests: -1\n")
+	select {
+	case <-called:
+		t.Error("Subscriber called for invalid change")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	expected := limits{MaxRequests: 5}
+	if actual := *w.Get().(*limits); actual != expected {
+		t.Errorf("Expected the last valid value %+v, got %+v", expected, actual)
+	}
+	if atomic.LoadInt64(&logged) == 0 {
+		t.Error("Expected the invalid change to be logged")
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "liveconfig_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "limits.json")
+	writeFile(t, path, `{"maxRequests": 0}`)
+
+	_, err = liveconfig.New(context.Background(), liveconfig.Config{
+		Path: path,
+		New:  newLimits,
+	})
+	if err == nil {
+		t.Error("Expected error for invalid initial document")
+	}
+
+	_, err = liveconfig.New(context.Background(), liveconfig.Config{
+		Path: path,
+	})
+	if !errors.Is(err, liveconfig.ErrNewRequired) {
+		t.Errorf("Expected %v, got %v", liveconfig.ErrNewRequired, err)
+	}
+}
@@ -0,0 +1,148 @@
+package liveconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// LogLevels is the live configuration document used by WatchLogLevels,
+// keyed by the names of the loggers returned by log.Named.
+//
+// In YAML it looks like:
+//
+//     redisbp:
+//       level: debug
+//       until: 2020-01-01T00:10:00Z
+//     thriftbp:
+//       level: warn
+type LogLevels map[string]LogLevel
+
+// LogLevel is the level of a named logger in LogLevels.
+type LogLevel struct {
+	// Level is the level of the named logger, required.
+	Level log.Level `json:"level" yaml:"level"`
+
+	// Until is the time the level expires at,
+	// after which the named logger goes back to the level of the global logger.
+	//
+	// Optional, when it's zero the level never expires.
+	Until time.Time `json:"until" yaml:"until"`
+}
+
+// Validate implements Validator.
+func (l *LogLevels) Validate() error {
+	for name, level := range *l {
+		if !level.Level.Valid() {
+			return fmt.Errorf("invalid level %q for logger %q", level.Level, name)
+		}
+	}
+	return nil
+}
+
+// WatchLogLevels creates a Watcher of the LogLevels document,
+// and applies the levels in it to the named loggers with log.SetNamedLevels,
+// every time the document changes and every time a level expires.
+//
+// cfg.New is ignored, Watcher.Get returns *LogLevels.
+//
+// After Stop is called on the returned Watcher,
+// all the named loggers go back to the level of the global logger.
+func WatchLogLevels(ctx context.Context, cfg Config) (*Watcher, error) {
+	cfg.New = func() interface{} {
+		return new(LogLevels)
+	}
+	w, err := New(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &logLevelsApplier{}
+	w.Subscribe(func(_, newValue interface{}) {
+		a.set(*newValue.(*LogLevels))
+	})
+	// Subscribe before the initial set,
+	// so no change can be missed in between.
+	a.set(*w.Get().(*LogLevels))
+
+	stop := w.stop
+	w.stop = func() {
+		stop()
+		a.stop()
+	}
+	return w, nil
+}
+
+// logLevelsApplier applies the LogLevels to the named loggers,
+// and re-applies them when the next level expires.
+type logLevelsApplier struct {
+	lock    sync.Mutex
+	levels  LogLevels
+	timer   *time.Timer
+	stopped bool
+}
+
+func (a *logLevelsApplier) set(levels LogLevels) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.stopped {
+		return
+	}
+	a.levels = levels
+	a.applyLocked()
+}
+
+func (a *logLevelsApplier) expire() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.stopped {
+		return
+	}
+	a.applyLocked()
+}
+
+func (a *logLevelsApplier) stop() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.stopped {
+		return
+	}
+	a.stopped = true
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	log.SetNamedLevels(nil)
+}
+
+// applyLocked must be called with the lock held.
+func (a *logLevelsApplier) applyLocked() {
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+
+	now := time.Now()
+	active := make(map[string]log.Level, len(a.levels))
+	var next time.Time
+	for name, level := range a.levels {
+		if !level.Until.IsZero() {
+			if !now.Before(level.Until) {
+				continue
+			}
+			if next.IsZero() || level.Until.Before(next) {
+				next = level.Until
+			}
+		}
+		active[name] = level.Level
+	}
+	// The levels are already validated by LogLevels.Validate,
+	// so it never fails.
+	log.SetNamedLevels(active)
+
+	if !next.IsZero() {
+		a.timer = time.AfterFunc(next.Sub(now), a.expire)
+	}
+}
@@ -0,0 +1,103 @@
+package liveconfig_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/reddit/baseplate.go/liveconfig"
+	"github.com/reddit/baseplate.go/log"
+)
+
+func debugEnabled(name string) bool {
+	return log.Named(name).Desugar().Core().Enabled(zapcore.DebugLevel)
+}
+
+// waitFor waits for the condition to become true, up to a second.
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", desc)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestWatchLogLevels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "liveconfig_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log-levels.yaml")
+	until := time.Now().Add(time.Millisecond * 200).UTC().Format(time.RFC3339Nano)
+	writeFile(t, path, `
+redisbp:
+  level: debug
+thriftbp:
+  level: debug
+  until: `+until+`
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	w, err := liveconfig.WatchLogLevels(ctx, liveconfig.Config{
+		Path:   path,
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if !debugEnabled("redisbp") {
+		t.Error("Expected debug level to be enabled for redisbp")
+	}
+	if !debugEnabled("thriftbp") {
+		t.Error("Expected debug level to be enabled for thriftbp before it expires")
+	}
+	if debugEnabled("httpbp") {
+		t.Error("Expected debug level to be disabled for httpbp")
+	}
+
+	waitFor(t, "thriftbp level to expire", func() bool {
+		return !debugEnabled("thriftbp")
+	})
+	if !debugEnabled("redisbp") {
+		t.Error("Expected debug level to be kept for redisbp")
+	}
+
+	writeFile(t, path, `
+httpbp:
+  level: debug
+`)
+	waitFor(t, "the change to be applied", func() bool {
+		return debugEnabled("httpbp") && !debugEnabled("redisbp")
+	})
+
+	w.Stop()
+	if debugEnabled("httpbp") {
+		t.Error("Expected debug level to be disabled for httpbp after stopped")
+	}
+}
+
+func TestLogLevelsValidate(t *testing.T) {
+	levels := liveconfig.LogLevels{
+		"redisbp": {Level: log.DebugLevel},
+	}
+	if err := levels.Validate(); err != nil {
+		t.Errorf("Expected valid levels, got %v", err)
+	}
+	levels["thriftbp"] = liveconfig.LogLevel{Level: "verbose"}
+	if err := levels.Validate(); err == nil {
+		t.Error("Expected error for invalid level")
+	}
+}
@@ -0,0 +1,287 @@
+package liveconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// Default values of ZooKeeperConfig.
+const (
+	DefaultZooKeeperSessionTimeout    = 10 * time.Second
+	DefaultZooKeeperReconnectInterval = time.Second
+	DefaultZooKeeperRewatchJitter     = time.Second
+)
+
+// ErrNoServers is returned by NewZooKeeper when ZooKeeperConfig.Servers is
+// empty.
+var ErrNoServers = errors.New("liveconfig: ZooKeeperConfig.Servers is required")
+
+// ZooKeeperConfig is the arg struct for NewZooKeeper.
+type ZooKeeperConfig struct {
+	// The ZooKeeper servers to connect to, in "host:port" format, required.
+	Servers []string
+
+	// The path of the ZooKeeper node holding the live configuration document,
+	// required.
+	Path string
+
+	// See Config.New, required.
+	New func() interface{}
+
+	// Optional, the format of the document. Default to FormatAuto,
+	// which means JSON unless the node name has a YAML extension.
+	Format Format
+
+	// Optional. When non-nil, it will be used to log the rejected changes and
+	// the connection errors.
+	Logger log.Wrapper
+
+	// Optional, the credentials to authenticate with using the "digest" scheme,
+	// required when the node is protected by digest ACLs.
+	//
+	// They are usually from secrets.Store.GetCredentialSecret.
+	Credentials *secrets.CredentialSecret
+
+	// Optional, the requested session timeout,
+	// the actual one is negotiated with the server.
+	//
+	// Default to DefaultZooKeeperSessionTimeout.
+	SessionTimeout time.Duration
+
+	// Optional, the interval between retries after reading the node failed,
+	// e.g. while the connection is lost, with 50% jitter applied.
+	//
+	// Default to DefaultZooKeeperReconnectInterval.
+	ReconnectInterval time.Duration
+
+	// Optional, the max random delay before reading the node again after it
+	// changed, so that all the clients watching the same node don't read it at
+	// the same time.
+	//
+	// Default to DefaultZooKeeperRewatchJitter.
+	RewatchJitter time.Duration
+}
+
+// NewZooKeeper creates a new Watcher watching a ZooKeeper node,
+// as an alternative to watching a file.
+//
+// The connection and the session are managed by github.com/go-zookeeper/zk:
+// when the connection is lost, it reconnects to the other servers,
+// resuming the session and the watch if the session is not expired yet.
+// When the session expired, the watcher reads the node again with the new
+// session, as changes might be missed while disconnected.
+//
+// Like New, if the node does not exist at the time of calling,
+// it blocks until the node is created, or context is cancelled,
+// whichever comes first.
+// Deleting the node later keeps the last valid value.
+//
+// If the initial document cannot be decoded or is invalid,
+// the error is returned directly.
+func NewZooKeeper(ctx context.Context, cfg ZooKeeperConfig) (*Watcher, error) {
+	if cfg.New == nil {
+		return nil, ErrNewRequired
+	}
+	if len(cfg.Servers) == 0 {
+		return nil, ErrNoServers
+	}
+	if cfg.SessionTimeout <= 0 {
+		cfg.SessionTimeout = DefaultZooKeeperSessionTimeout
+	}
+	logger := log.FallbackWrapper(cfg.Logger)
+	conn, _, err := zk.Connect(
+		cfg.Servers,
+		cfg.SessionTimeout,
+		zk.WithLogger(zkLogger(logger)),
+		zk.WithLogInfo(false),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return newZooKeeper(ctx, cfg, conn)
+}
+
+// newZooKeeper creates a new Watcher watching a ZooKeeper node with conn.
+//
+// conn is closed when the Watcher is stopped, or when it fails to create the
+// Watcher.
+func newZooKeeper(ctx context.Context, cfg ZooKeeperConfig, conn zkConn) (*Watcher, error) {
+	if cfg.ReconnectInterval <= 0 {
+		cfg.ReconnectInterval = DefaultZooKeeperReconnectInterval
+	}
+	if cfg.RewatchJitter <= 0 {
+		cfg.RewatchJitter = DefaultZooKeeperRewatchJitter
+	}
+
+	w := &Watcher{
+		format: cfg.Format.detect(cfg.Path),
+		newFn:  cfg.New,
+	}
+	z := &zkWatcher{
+		cfg:     cfg,
+		conn:    conn,
+		parse:   w.parse,
+		logger:  log.FallbackWrapper(cfg.Logger),
+		initial: make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+	z.ctx, z.cancel = context.WithCancel(context.Background())
+	go z.loop()
+
+	select {
+	case <-ctx.Done():
+		z.stop()
+		return nil, ctx.Err()
+	case err := <-z.initial:
+		if err != nil {
+			z.stop()
+			return nil, err
+		}
+	}
+	w.stop = z.stop
+	return w, nil
+}
+
+// zkConn is the subset of *zk.Conn used by zkWatcher.
+type zkConn interface {
+	AddAuth(scheme string, auth []byte) error
+	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	Close()
+}
+
+var _ zkConn = (*zk.Conn)(nil)
+
+// zkLogger adapts log.Wrapper into zk.Logger.
+type zkLogger log.Wrapper
+
+func (l zkLogger) Printf(format string, args ...interface{}) {
+	l("liveconfig: zookeeper: " + fmt.Sprintf(format, args...))
+}
+
+// zkWatcher watches a ZooKeeper node in a background goroutine.
+type zkWatcher struct {
+	cfg    ZooKeeperConfig
+	conn   zkConn
+	parse  func(io.Reader) (interface{}, error)
+	logger log.Wrapper
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// initial receives the result of the first parse.
+	initial chan error
+
+	// The fields below are only accessed by the loop goroutine.
+
+	authenticated bool
+	initialized   bool
+}
+
+// stop stops the loop and closes the connection.
+func (z *zkWatcher) stop() {
+	z.cancel()
+	// Closing the connection also fails the pending requests of the loop.
+	z.conn.Close()
+	<-z.done
+}
+
+func (z *zkWatcher) loop() {
+	defer close(z.done)
+
+	for {
+		events, err := z.watch()
+		if z.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			z.logger("liveconfig: zookeeper watcher error, retrying: " + err.Error())
+			jitter := 0.5 + randbp.R.Float64()
+			if !z.sleep(time.Duration(float64(z.cfg.ReconnectInterval) * jitter)) {
+				return
+			}
+			continue
+		}
+
+		// Wait for the watch to fire.
+		//
+		// When the session expired, the watch fires with EventNotWatching,
+		// and the node is read again with the new session.
+		select {
+		case <-z.ctx.Done():
+			return
+		case <-events:
+		}
+		if !z.sleep(time.Duration(randbp.R.Int63n(int64(z.cfg.RewatchJitter)))) {
+			return
+		}
+	}
+}
+
+// sleep returns false when the watcher is stopped before d elapses.
+func (z *zkWatcher) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-z.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// watch authenticates the connection if it's not done yet,
+// then reads the node and sets a watch on it.
+func (z *zkWatcher) watch() (<-chan zk.Event, error) {
+	if creds := z.cfg.Credentials; creds != nil && !z.authenticated {
+		// The credentials are sent again by zk.Conn after reconnecting.
+		if err := z.conn.AddAuth("digest", []byte(creds.Username+":"+creds.Password)); err != nil {
+			return nil, err
+		}
+		z.authenticated = true
+	}
+	return z.read()
+}
+
+// read reads the node and sets a watch on it.
+//
+// If the node does not exist, the watch fires when it's created.
+func (z *zkWatcher) read() (<-chan zk.Event, error) {
+	data, _, events, err := z.conn.GetW(z.cfg.Path)
+	if errors.Is(err, zk.ErrNoNode) {
+		exists, _, events, err := z.conn.ExistsW(z.cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			// Created in between, read it again.
+			return z.read()
+		}
+		return events, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = z.parse(bytes.NewReader(data))
+	if !z.initialized {
+		z.initialized = true
+		z.initial <- err
+		return events, nil
+	}
+	if err != nil {
+		z.logger(err.Error())
+	}
+	return events, nil
+}
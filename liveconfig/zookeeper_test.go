@@ -0,0 +1,304 @@
+package liveconfig
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// fakeZKConn is a fake zkConn holding the nodes in memory.
+type fakeZKConn struct {
+	lock    sync.Mutex
+	nodes   map[string][]byte
+	watches map[string][]chan zk.Event
+	auth    []string
+	err     error
+	closed  bool
+}
+
+func newFakeZKConn() *fakeZKConn {
+	return &fakeZKConn{
+		nodes:   make(map[string][]byte),
+		watches: make(map[string][]chan zk.Event),
+	}
+}
+
+func (c *fakeZKConn) AddAuth(scheme string, auth []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.auth = append(c.auth, scheme+" "+string(auth))
+	return nil
+}
+
+func (c *fakeZKConn) watch(path string) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	c.watches[path] = append(c.watches[path], ch)
+	return ch
+}
+
+func (c *fakeZKConn) GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return nil, nil, nil, c.err
+	}
+	data, ok := c.nodes[path]
+	if !ok {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	return data, &zk.Stat{}, c.watch(path), nil
+}
+
+func (c *fakeZKConn) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return false, nil, nil, c.err
+	}
+	_, ok := c.nodes[path]
+	return ok, &zk.Stat{}, c.watch(path), nil
+}
+
+func (c *fakeZKConn) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	c.fire("", zk.Event{Type: zk.EventNotWatching, Err: zk.ErrClosing})
+}
+
+// fire fires the watches on path, or all the watches if path is empty.
+//
+// It must be called with the lock held.
+func (c *fakeZKConn) fire(path string, ev zk.Event) {
+	for p, watches := range c.watches {
+		if path != "" && p != path {
+			continue
+		}
+		for _, ch := range watches {
+			ev.Path = p
+			ch <- ev
+			close(ch)
+		}
+		delete(c.watches, p)
+	}
+}
+
+// set sets the data of the node, and fires the watches on it.
+func (c *fakeZKConn) set(path, data string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	eventType := zk.EventNodeDataChanged
+	if _, ok := c.nodes[path]; !ok {
+		eventType = zk.EventNodeCreated
+	}
+	c.nodes[path] = []byte(data)
+	c.fire(path, zk.Event{Type: eventType})
+}
+
+// setSilently sets the data of the node without firing the watches,
+// like the changes missed by an expired session.
+func (c *fakeZKConn) setSilently(path, data string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.nodes[path] = []byte(data)
+}
+
+// expireSession fires all the watches with EventNotWatching,
+// like zk.Conn does when the session expired.
+func (c *fakeZKConn) expireSession() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.fire("", zk.Event{Type: zk.EventNotWatching, Err: zk.ErrSessionExpired})
+}
+
+// setError sets the error returned by all the requests, nil to clear it.
+func (c *fakeZKConn) setError(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.err = err
+}
+
+func (c *fakeZKConn) isClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+type zkTestConfig struct {
+	Value int `json:"value"`
+}
+
+func (c *zkTestConfig) Validate() error {
+	if c.Value < 0 {
+		return errors.New("value must not be negative")
+	}
+	return nil
+}
+
+func newZKTestConfig() interface{} {
+	return new(zkTestConfig)
+}
+
+const zkTestPath = "/test/config"
+
+func newZooKeeperWatcher(t *testing.T, conn *fakeZKConn, logger func(string)) *Watcher {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	w, err := newZooKeeper(ctx, ZooKeeperConfig{
+		Path:              zkTestPath,
+		New:               newZKTestConfig,
+		Logger:            logger,
+		Credentials:       &secrets.CredentialSecret{Username: "user", Password: "pass"},
+		ReconnectInterval: time.Millisecond * 10,
+		RewatchJitter:     time.Millisecond,
+	}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+	return w
+}
+
+func waitForValue(t *testing.T, changes <-chan int, expected int) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case value := <-changes:
+			if value == expected {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for value %d", expected)
+		}
+	}
+}
+
+func subscribeValues(w *Watcher) <-chan int {
+	changes := make(chan int, 10)
+	w.Subscribe(func(_, newValue interface{}) {
+		changes <- newValue.(*zkTestConfig).Value
+	})
+	return changes
+}
+
+func TestZooKeeper(t *testing.T) {
+	conn := newFakeZKConn()
+	conn.set(zkTestPath, `{"value": 1}`)
+
+	logs := make(chan string, 10)
+	w := newZooKeeperWatcher(t, conn, func(msg string) {
+		logs <- msg
+	})
+	if value := w.Get().(*zkTestConfig).Value; value != 1 {
+		t.Errorf("Expected value 1, got %d", value)
+	}
+	changes := subscribeValues(w)
+
+	conn.set(zkTestPath, `{"value": 2}`)
+	waitForValue(t, changes, 2)
+
+	// Invalid changes are logged and ignored.
+	conn.set(zkTestPath, `{"value": -1}`)
+	select {
+	case <-logs:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the invalid change to be logged")
+	}
+	if value := w.Get().(*zkTestConfig).Value; value != 2 {
+		t.Errorf("Expected value 2 after invalid change, got %d", value)
+	}
+	conn.set(zkTestPath, `{"value": 3}`)
+	waitForValue(t, changes, 3)
+
+	if expected := []string{"digest user:pass"}; len(conn.auth) != 1 || conn.auth[0] != expected[0] {
+		t.Errorf("Expected auth %q, got %q", expected, conn.auth)
+	}
+
+	w.Stop()
+	if !conn.isClosed() {
+		t.Error("Expected the connection to be closed after Stop")
+	}
+}
+
+func TestZooKeeperReconnect(t *testing.T) {
+	conn := newFakeZKConn()
+	conn.set(zkTestPath, `{"value": 1}`)
+
+	logs := make(chan string, 10)
+	w := newZooKeeperWatcher(t, conn, func(msg string) {
+		select {
+		case logs <- msg:
+		default:
+		}
+	})
+	changes := subscribeValues(w)
+
+	t.Run("expired-session", func(t *testing.T) {
+		conn.setSilently(zkTestPath, `{"value": 2}`)
+		conn.expireSession()
+		waitForValue(t, changes, 2)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		conn.setError(zk.ErrNoServer)
+		conn.set(zkTestPath, `{"value": 3}`)
+		select {
+		case <-logs:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the error to be logged")
+		}
+		conn.setError(nil)
+		waitForValue(t, changes, 3)
+	})
+}
+
+func TestZooKeeperNodeCreated(t *testing.T) {
+	conn := newFakeZKConn()
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		conn.set(zkTestPath, `{"value": 1}`)
+	}()
+
+	w := newZooKeeperWatcher(t, conn, nil)
+	if value := w.Get().(*zkTestConfig).Value; value != 1 {
+		t.Errorf("Expected value 1, got %d", value)
+	}
+}
+
+func TestZooKeeperInvalid(t *testing.T) {
+	conn := newFakeZKConn()
+	conn.set(zkTestPath, `{"value": -1}`)
+
+	_, err := newZooKeeper(context.Background(), ZooKeeperConfig{
+		Path: zkTestPath,
+		New:  newZKTestConfig,
+	}, conn)
+	if err == nil {
+		t.Error("Expected error for invalid initial document")
+	}
+	if !conn.isClosed() {
+		t.Error("Expected the connection to be closed")
+	}
+
+	_, err = NewZooKeeper(context.Background(), ZooKeeperConfig{
+		Path: zkTestPath,
+		New:  newZKTestConfig,
+	})
+	if !errors.Is(err, ErrNoServers) {
+		t.Errorf("Expected %v, got %v", ErrNoServers, err)
+	}
+}
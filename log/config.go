@@ -1,11 +1,20 @@
 package log
 
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
 // Config is the confuration struct for the log package.
 //
 // Can be deserialized from YAML.
 type Config struct {
 	// Level is the log level you want to set your service to.
 	Level Level `yaml:"level"`
+
+	// Sampling is the sampling of the repeated log entries,
+	// which is enabled with the default values unless disabled explicitly.
+	Sampling SamplingConfig `yaml:"sampling"`
 }
 
 // InitFromConfig initializes the log package using the given Config and JSON
@@ -15,5 +24,15 @@ func InitFromConfig(cfg Config) {
 		cfg.Level = InfoLevel
 	}
 	level := cfg.Level
-	InitLoggerJSON(level)
+	config := jsonConfig(level)
+	config.Sampling = nil
+	var opts []zap.Option
+	if wrap := cfg.Sampling.wrapCore(config.Level); wrap != nil {
+		config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+		opts = append(opts, zap.WrapCore(wrap))
+	}
+	if err := initLogger(level, config, opts...); err != nil {
+		// shouldn't happen, but just in case
+		panic(err)
+	}
 }
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const (
+	fieldsKey contextKey = iota
+	loggerKey
+)
+
+// Attach attaches the key-value pairs to the context object,
+// so that the logger returned by FromContext with the returned context object
+// will have them as additional fields.
+//
+// The key-value pairs are treated as they are in With.
+// Fields already attached to ctx are kept,
+// the new ones are appended after them.
+//
+// The server middlewares in baseplate.go attach trace id, span id, and the
+// user id from the edge request context (when available) to the request
+// context object automatically.
+func Attach(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	if len(keysAndValues) == 0 {
+		return ctx
+	}
+	fields := getFields(ctx)
+	// Make a copy to avoid races on the underlying array.
+	newFields := make([]interface{}, 0, len(fields)+len(keysAndValues))
+	newFields = append(newFields, fields...)
+	newFields = append(newFields, keysAndValues...)
+	return context.WithValue(ctx, fieldsKey, newFields)
+}
+
+// AttachLogger attaches logger to the context object,
+// so that FromContext with the returned context object uses it instead of the
+// global logger.
+//
+// The server middlewares in baseplate.go attach the Logger from their
+// arguments (when set) to the request context object automatically,
+// and log through FromContext.
+//
+// If logger is nil, ctx is returned as-is.
+func AttachLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	if logger == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger attached to the context object
+// (see AttachLogger), or the global logger when there's none,
+// with the fields attached to the context object (see Attach) added.
+//
+// If there's no fields attached to ctx,
+// the logger is returned as-is.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	l := LoggerFromContext(ctx)
+	if fields := getFields(ctx); len(fields) > 0 {
+		return l.With(fields...)
+	}
+	return l
+}
+
+// LoggerFromContext returns the logger attached to the context object
+// (see AttachLogger), or the global logger when there's none,
+// without the fields attached to the context object.
+//
+// It's useful when the caller already logs those fields on its own.
+func LoggerFromContext(ctx context.Context) *zap.SugaredLogger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey).(*zap.SugaredLogger); ok {
+			return l
+		}
+	}
+	return logger
+}
+
+func getFields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey).([]interface{})
+	return fields
+}
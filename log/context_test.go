@@ -0,0 +1,73 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAttach(t *testing.T) {
+	defer func(l *zap.SugaredLogger) {
+		logger = l
+	}(logger)
+	core, logs := observer.New(zap.DebugLevel)
+	logger = zap.New(core).Sugar()
+
+	ctx := context.Background()
+	if FromContext(ctx) != logger {
+		t.Error("Expected FromContext to return the global logger without attached fields")
+	}
+
+	ctx = Attach(ctx, "traceID", 1)
+	parent := ctx
+	ctx = Attach(ctx, "userID", "t2_foo")
+	// Attaching to the same parent again should not affect ctx.
+	Attach(parent, "spanID", 2)
+
+	FromContext(ctx).Infow("test", "foo", "bar")
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	expected := map[string]interface{}{
+		"traceID": int64(1),
+		"userID":  "t2_foo",
+		"foo":     "bar",
+	}
+	if len(fields) != len(expected) {
+		t.Errorf("Expected fields %v, got %v", expected, fields)
+	}
+	for k, v := range expected {
+		if fields[k] != v {
+			t.Errorf("Expected field %q to be %v, got %v", k, v, fields[k])
+		}
+	}
+}
+
+func TestAttachLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	attached := zap.New(core).Sugar()
+
+	ctx := AttachLogger(context.Background(), nil)
+	if FromContext(ctx) != logger {
+		t.Error("Expected FromContext to return the global logger when nil logger is attached")
+	}
+
+	ctx = AttachLogger(ctx, attached)
+	if FromContext(ctx) != attached {
+		t.Error("Expected FromContext to return the attached logger without attached fields")
+	}
+
+	ctx = Attach(ctx, "traceID", 1)
+	FromContext(ctx).Info("test")
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()["traceID"]; got != int64(1) {
+		t.Errorf("Expected field traceID to be 1, got %v", got)
+	}
+}
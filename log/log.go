@@ -49,6 +49,16 @@ func (l Level) ToZapLevel() zapcore.Level {
 	}
 }
 
+// Valid reports whether l is one of the defined Levels.
+func (l Level) Valid() bool {
+	switch l {
+	default:
+		return false
+	case NopLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel:
+		return true
+	}
+}
+
 // InitLogger provides a quick way to start or replace a logger.
 func InitLogger(logLevel Level) {
 	config := zap.NewProductionConfig()
@@ -69,6 +79,13 @@ func InitLogger(logLevel Level) {
 // The JSON format is also compatible with logdna's ingestion format:
 // https://docs.logdna.com/docs/ingestion
 func InitLoggerJSON(logLevel Level) {
+	if err := InitLoggerWithConfig(logLevel, jsonConfig(logLevel)); err != nil {
+		// shouldn't happen, but just in case
+		panic(err)
+	}
+}
+
+func jsonConfig(logLevel Level) zap.Config {
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(logLevel.ToZapLevel())
 	config.Encoding = "json"
@@ -78,22 +95,22 @@ func InitLoggerJSON(logLevel Level) {
 	// json keys expected by logdna:
 	config.EncoderConfig.MessageKey = "message"
 	config.EncoderConfig.TimeKey = "timestamp"
-
-	if err := InitLoggerWithConfig(logLevel, config); err != nil {
-		// shouldn't happen, but just in case
-		panic(err)
-	}
+	return config
 }
 
 // InitLoggerWithConfig provides a quick way to start or replace a logger.
 //
 // Pass in a cfg to provide a logger with custom setting
 func InitLoggerWithConfig(logLevel Level, cfg zap.Config) error {
+	return initLogger(logLevel, cfg)
+}
+
+func initLogger(logLevel Level, cfg zap.Config, opts ...zap.Option) error {
 	if logLevel == NopLevel {
 		logger = zap.NewNop().Sugar()
 		return nil
 	}
-	l, err := cfg.Build(zap.AddCallerSkip(2))
+	l, err := cfg.Build(append([]zap.Option{zap.AddCallerSkip(2)}, opts...)...)
 	if err != nil {
 		return err
 	}
@@ -244,6 +261,7 @@ func With(args ...interface{}) *zap.SugaredLogger {
 // then sends the error to Sentry.
 //
 // The variadic key-value pairs are treated as they are in With.
+// The fields attached to the context object (see Attach) are also logged.
 //
 // If a sentry hub is attached to the context object passed in
 // (it will be if the context object is from baseplate hooked request context),
@@ -251,7 +269,7 @@ func With(args ...interface{}) *zap.SugaredLogger {
 // Otherwise the global sentry hub will be used instead.
 func ErrorWithSentry(ctx context.Context, msg string, err error, keysAndValues ...interface{}) {
 	keysAndValues = append(keysAndValues, "err", err)
-	logger.Errorw(msg, keysAndValues...)
+	FromContext(ctx).Errorw(msg, keysAndValues...)
 
 	if hub := sentry.GetHubFromContext(ctx); hub != nil {
 		hub.CaptureException(err)
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// namedLevels holds the level overrides of the named loggers set by
+// SetNamedLevels, as a map[string]zapcore.Level.
+//
+// The whole map is replaced on every change,
+// so all the overrides are applied atomically.
+var namedLevels atomic.Value
+
+// namedLoggers caches the named loggers derived from the current global
+// logger.
+var namedLoggers struct {
+	sync.RWMutex
+
+	base    *zap.SugaredLogger
+	loggers map[string]*zap.SugaredLogger
+}
+
+// Named returns the global logger with the given name added,
+// whose level can be changed at runtime by SetNamedLevels,
+// independently from the level of the global logger.
+//
+// Without an override from SetNamedLevels,
+// the named logger uses the level of the global logger.
+//
+// The loggers are cached by name,
+// but like FromContext they are derived from the current global logger,
+// so Named should be called every time instead of storing the returned logger,
+// to pick up the changes made by InitLogger and its variants.
+//
+// Libraries usually use their package names as the names,
+// e.g. log.Named("redisbp").
+// The packages in baseplate.go log through the loggers named after them,
+// except for the logs of the server middlewares,
+// which go through log.FromContext with the request context object.
+func Named(name string) *zap.SugaredLogger {
+	base := logger
+
+	namedLoggers.RLock()
+	if namedLoggers.base == base {
+		if l, ok := namedLoggers.loggers[name]; ok {
+			namedLoggers.RUnlock()
+			return l
+		}
+	}
+	namedLoggers.RUnlock()
+
+	l := base.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &namedCore{
+			Core: core,
+			name: name,
+		}
+	})).Named(name).Sugar()
+
+	namedLoggers.Lock()
+	defer namedLoggers.Unlock()
+	if namedLoggers.base != base {
+		namedLoggers.base = base
+		namedLoggers.loggers = make(map[string]*zap.SugaredLogger)
+	}
+	namedLoggers.loggers[name] = l
+	return l
+}
+
+// SetNamedLevels replaces the level overrides of the named loggers returned by
+// Named, keyed by the names of the loggers.
+//
+// The named loggers not in levels go back to the level of the global logger,
+// so SetNamedLevels(nil) removes all the overrides.
+// All the changes are applied atomically.
+//
+// It returns an error without changing anything if any of the levels is
+// invalid.
+//
+// It's safe to be called concurrently with the logging,
+// and liveconfig.WatchLogLevels can be used to change the levels with a live
+// configuration document.
+func SetNamedLevels(levels map[string]Level) error {
+	m := make(map[string]zapcore.Level, len(levels))
+	for name, level := range levels {
+		if !level.Valid() {
+			return fmt.Errorf("log: invalid level %q for logger %q", level, name)
+		}
+		m[name] = level.ToZapLevel()
+	}
+	namedLevels.Store(m)
+	return nil
+}
+
+func getNamedLevel(name string) (level zapcore.Level, ok bool) {
+	m, _ := namedLevels.Load().(map[string]zapcore.Level)
+	level, ok = m[name]
+	return
+}
+
+// namedCore is the zapcore.Core of the named loggers,
+// which uses the level override of its name when there's one.
+type namedCore struct {
+	zapcore.Core
+
+	name string
+}
+
+func (c *namedCore) Enabled(l zapcore.Level) bool {
+	if level, ok := getNamedLevel(c.name); ok {
+		return level.Enabled(l)
+	}
+	return c.Core.Enabled(l)
+}
+
+func (c *namedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedCore{
+		Core: c.Core.With(fields),
+		name: c.name,
+	}
+}
+
+func (c *namedCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if level, ok := getNamedLevel(c.name); ok {
+		if level.Enabled(entry.Level) {
+			// Bypass the level of the wrapped core,
+			// Write of the wrapped core doesn't check the level again.
+			// But still apply the sampling if the wrapped core does it,
+			// the core wrapped by the sampling core is always enabled.
+			if s, ok := c.Core.(*samplingCore); ok {
+				return s.Core.Check(entry, ce)
+			}
+			return ce.AddCore(entry, c)
+		}
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNamed(t *testing.T) {
+	defer func(l *zap.SugaredLogger) {
+		logger = l
+	}(logger)
+	defer SetNamedLevels(nil)
+	core, logs := observer.New(zap.InfoLevel)
+	logger = zap.New(core).Sugar()
+
+	expectLogs := func(t *testing.T, expected int) {
+		t.Helper()
+		entries := logs.TakeAll()
+		if len(entries) != expected {
+			t.Fatalf("Expected %d log entries, got %d: %+v", expected, len(entries), entries)
+		}
+		for _, entry := range entries {
+			if entry.LoggerName != "foo" {
+				t.Errorf("Expected logger name %q, got %q", "foo", entry.LoggerName)
+			}
+		}
+	}
+
+	t.Run("global-level", func(t *testing.T) {
+		Named("foo").Debug("debug")
+		Named("foo").Info("info")
+		expectLogs(t, 1)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		if err := SetNamedLevels(map[string]Level{"foo": DebugLevel}); err != nil {
+			t.Fatal(err)
+		}
+		Named("foo").Debug("debug")
+		Named("foo").With("key", "value").Debug("debug")
+		Named("bar").Debug("debug")
+		logger.Debug("debug")
+		expectLogs(t, 2)
+
+		if err := SetNamedLevels(map[string]Level{"foo": ErrorLevel}); err != nil {
+			t.Fatal(err)
+		}
+		Named("foo").Info("info")
+		Named("foo").Error("error")
+		expectLogs(t, 1)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if err := SetNamedLevels(map[string]Level{"foo": "verbose"}); err == nil {
+			t.Error("Expected error for invalid level")
+		}
+		// The previous override should be kept.
+		Named("foo").Info("info")
+		expectLogs(t, 0)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		if err := SetNamedLevels(nil); err != nil {
+			t.Fatal(err)
+		}
+		Named("foo").Info("info")
+		expectLogs(t, 1)
+	})
+
+	t.Run("reinit", func(t *testing.T) {
+		old := Named("foo")
+		core, newLogs := observer.New(zap.InfoLevel)
+		logger = zap.New(core).Sugar()
+		if Named("foo") == old {
+			t.Error("Expected Named to return a new logger after the global logger changed")
+		}
+		Named("foo").Info("info")
+		if n := newLogs.Len(); n != 1 {
+			t.Errorf("Expected 1 log entry on the new global logger, got %d", n)
+		}
+	})
+}
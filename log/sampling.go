@@ -0,0 +1,203 @@
+package log
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Default values of SamplingConfig,
+// which are the same as the sampling of zap.NewProductionConfig.
+const (
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+)
+
+// samplingTick is the interval the sampling counters are reset at.
+const samplingTick = time.Second
+
+// SamplingConfig is the configuration of the sampling of the repeated log
+// entries,
+// so that a burst of the same entry (e.g. the same error from a failing
+// downstream for every request) doesn't saturate the log pipeline.
+//
+// Within every second,
+// the first Initial entries with the same level and message are logged,
+// after that only every Thereafter-th of them is logged
+// (see zapcore.NewSamplerWithOptions).
+// The number of the dropped entries is reported by an additional entry with
+// the message "suppressed N duplicates" and the original message in the
+// "duplicateOf" field,
+// written when the next entry is logged after the second,
+// or when Sync is called.
+//
+// Can be deserialized from YAML.
+type SamplingConfig struct {
+	// Optional, defaults to DefaultSamplingInitial.
+	Initial int `yaml:"initial"`
+
+	// Optional, defaults to DefaultSamplingThereafter.
+	Thereafter int `yaml:"thereafter"`
+
+	// Disabled disables the sampling, so all the entries are logged.
+	Disabled bool `yaml:"disabled"`
+}
+
+// wrapCore returns the function wrapping the core built by InitFromConfig with
+// the sampling,
+// or nil when the sampling is disabled.
+//
+// The level of the wrapped core must be DebugLevel,
+// level is enforced by the sampling core instead,
+// so that the named loggers overriding the level are still sampled.
+func (cfg SamplingConfig) wrapCore(level zapcore.LevelEnabler) func(zapcore.Core) zapcore.Core {
+	if cfg.Disabled {
+		return nil
+	}
+	if cfg.Initial <= 0 {
+		cfg.Initial = DefaultSamplingInitial
+	}
+	if cfg.Thereafter <= 0 {
+		cfg.Thereafter = DefaultSamplingThereafter
+	}
+	return func(core zapcore.Core) zapcore.Core {
+		return newSamplingCore(core, level, samplingTick, cfg.Initial, cfg.Thereafter)
+	}
+}
+
+// samplingCore is the zapcore.Core doing the sampling described in
+// SamplingConfig.
+//
+// The sampling is done by the sampler of zapcore,
+// with the dropped entries counted by its hook and reported by the
+// suppressedReporter.
+// The level check is done separately from the sampling,
+// so that the named loggers overriding the level can bypass the level check
+// without bypassing the sampling.
+type samplingCore struct {
+	// Core is the sampler of zapcore.
+	zapcore.Core
+
+	level    zapcore.LevelEnabler
+	reporter *suppressedReporter
+}
+
+func newSamplingCore(
+	core zapcore.Core,
+	level zapcore.LevelEnabler,
+	tick time.Duration,
+	initial, thereafter int,
+) zapcore.Core {
+	reporter := &suppressedReporter{
+		root: core,
+		tick: tick,
+	}
+	return &samplingCore{
+		Core: zapcore.NewSamplerWithOptions(
+			core,
+			tick,
+			initial,
+			thereafter,
+			zapcore.SamplerHook(reporter.hook),
+		),
+		level:    level,
+		reporter: reporter,
+	}
+}
+
+func (c *samplingCore) Enabled(l zapcore.Level) bool {
+	return c.level.Enabled(l)
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{
+		Core:     c.Core.With(fields),
+		level:    c.level,
+		reporter: c.reporter,
+	}
+}
+
+func (c *samplingCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+func (c *samplingCore) Sync() error {
+	c.reporter.report(time.Now())
+	return c.Core.Sync()
+}
+
+type samplingKey struct {
+	level   zapcore.Level
+	name    string
+	message string
+}
+
+// suppressedReporter counts the entries dropped by the sampler,
+// and writes the "suppressed N duplicates" entries for them.
+type suppressedReporter struct {
+	// root is the wrapped core without any fields added by With,
+	// used to write the "suppressed N duplicates" entries.
+	root zapcore.Core
+	tick time.Duration
+
+	// The time to report the dropped entries at next,
+	// in unix nanoseconds, accessed atomically.
+	next int64
+
+	// map[samplingKey]*uint64, with the values accessed atomically.
+	dropped sync.Map
+}
+
+// hook is the zapcore.SamplerHook of the sampler.
+func (r *suppressedReporter) hook(entry zapcore.Entry, dec zapcore.SamplingDecision) {
+	now := entry.Time.UnixNano()
+	if next := atomic.LoadInt64(&r.next); now >= next &&
+		atomic.CompareAndSwapInt64(&r.next, next, now+r.tick.Nanoseconds()) {
+		r.report(entry.Time)
+	}
+
+	if dec&zapcore.LogDropped != 0 {
+		key := samplingKey{
+			level:   entry.Level,
+			name:    entry.LoggerName,
+			message: entry.Message,
+		}
+		counter, ok := r.dropped.Load(key)
+		if !ok {
+			counter, _ = r.dropped.LoadOrStore(key, new(uint64))
+		}
+		atomic.AddUint64(counter.(*uint64), 1)
+	}
+}
+
+// report writes the "suppressed N duplicates" entries and resets the
+// counters.
+//
+// The counters without any dropped entries since the last report are removed.
+func (r *suppressedReporter) report(now time.Time) {
+	r.dropped.Range(func(k, v interface{}) bool {
+		key := k.(samplingKey)
+		dropped := atomic.SwapUint64(v.(*uint64), 0)
+		if dropped == 0 {
+			r.dropped.Delete(key)
+			return true
+		}
+		r.root.Write(
+			zapcore.Entry{
+				Level:      key.level,
+				Time:       now,
+				LoggerName: key.name,
+				Message:    "suppressed " + strconv.FormatUint(dropped, 10) + " duplicates",
+			},
+			[]zapcore.Field{zap.String("duplicateOf", key.message)},
+		)
+		return true
+	})
+}
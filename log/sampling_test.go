@@ -0,0 +1,121 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampling(t *testing.T) {
+	defer func(l *zap.SugaredLogger) {
+		logger = l
+	}(logger)
+	defer SetNamedLevels(nil)
+
+	// The wrapped core is always enabled, the level is enforced by the sampling
+	// core, the same as InitFromConfig.
+	core, logs := observer.New(zap.DebugLevel)
+	const tick = time.Millisecond * 100
+	logger = zap.New(newSamplingCore(core, zap.InfoLevel, tick, 2, 3)).Sugar()
+
+	expectMessages := func(t *testing.T, expected ...string) []observer.LoggedEntry {
+		t.Helper()
+		entries := logs.TakeAll()
+		if len(entries) != len(expected) {
+			t.Fatalf("Expected %d log entries, got %d: %+v", len(expected), len(entries), entries)
+		}
+		for i, entry := range entries {
+			if entry.Message != expected[i] {
+				t.Errorf("Expected message #%d to be %q, got %q", i, expected[i], entry.Message)
+			}
+		}
+		return entries
+	}
+
+	t.Run("sync", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			logger.Info("boom")
+		}
+		// The 1st, 2nd, 5th, and 8th are logged.
+		expectMessages(t, "boom", "boom", "boom", "boom")
+
+		// Below the level, neither logged nor counted.
+		logger.Debug("boom")
+		expectMessages(t)
+
+		Sync()
+		entries := expectMessages(t, "suppressed 6 duplicates")
+		if actual := entries[0].ContextMap()["duplicateOf"]; actual != "boom" {
+			t.Errorf("Expected duplicateOf to be %q, got %v", "boom", actual)
+		}
+
+		// Nothing more to report.
+		Sync()
+		expectMessages(t)
+	})
+
+	t.Run("tick", func(t *testing.T) {
+		time.Sleep(tick)
+		for i := 0; i < 3; i++ {
+			logger.With("foo", "bar").Warn("boom")
+		}
+		expectMessages(t, "boom", "boom")
+
+		time.Sleep(tick)
+		logger.Info("other")
+		entries := expectMessages(t, "suppressed 1 duplicates", "other")
+		if entries[0].Level != zapcore.WarnLevel {
+			t.Errorf("Expected the level of the dropped entries, got %v", entries[0].Level)
+		}
+		if _, ok := entries[0].ContextMap()["foo"]; ok {
+			t.Error("Expected fields added by With not to be in the suppressed entry")
+		}
+	})
+
+	t.Run("named", func(t *testing.T) {
+		time.Sleep(tick)
+		if err := SetNamedLevels(map[string]Level{"foo": DebugLevel}); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			Named("foo").Debug("boom")
+		}
+		expectMessages(t, "boom", "boom")
+
+		Sync()
+		entries := expectMessages(t, "suppressed 1 duplicates")
+		if entries[0].LoggerName != "foo" {
+			t.Errorf("Expected logger name %q, got %q", "foo", entries[0].LoggerName)
+		}
+	})
+}
+
+func TestInitFromConfigSampling(t *testing.T) {
+	defer func(l *zap.SugaredLogger) {
+		logger = l
+	}(logger)
+
+	InitFromConfig(Config{})
+	if _, ok := logger.Desugar().Core().(*samplingCore); !ok {
+		t.Errorf("Expected sampling core, got %T", logger.Desugar().Core())
+	}
+	if logger.Desugar().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("Expected the sampling core to enforce the level")
+	}
+
+	InitFromConfig(Config{Sampling: SamplingConfig{Disabled: true}})
+	if _, ok := logger.Desugar().Core().(*samplingCore); ok {
+		t.Error("Expected no sampling core when disabled")
+	}
+
+	// External configs keep the sampling of zap.
+	if err := InitLoggerWithConfig(InfoLevel, jsonConfig(InfoLevel)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := logger.Desugar().Core().(*samplingCore); ok {
+		t.Error("Expected no sampling core for external configs")
+	}
+}
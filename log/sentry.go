@@ -19,30 +19,32 @@ var ErrSentryFlushFailed = errors.New("log: sentry flushing failed")
 // SentryConfig is the config to be passed into InitSentry.
 //
 // All fields are optional.
+//
+// Can be deserialized from YAML.
 type SentryConfig struct {
 	// The Sentry DSN to use.
 	// If empty, SENTRY_DSN environment variable will be used instead.
 	// If that's also empty, then all sentry operations will be nop.
-	DSN string
+	DSN string `yaml:"dsn"`
 
 	// SampleRate between 0 and 1, default is 1.
-	SampleRate *float64
+	SampleRate *float64 `yaml:"sampleRate"`
 
 	// The name of your service.
-	ServerName string
+	ServerName string `yaml:"serverName"`
 
 	// An environment string like "prod", "staging".
-	Environment string
+	Environment string `yaml:"environment"`
 
 	// List of regexp strings that will be used to match against event's message
 	// and if applicable, caught errors type and value.
 	// If the match is found, then a whole event will be dropped.
-	IgnoreErrors []string
+	IgnoreErrors []string `yaml:"ignoreErrors"`
 
 	// FlushTimeout is the timeout to be used to call sentry.Flush when closing
 	// the Closer returned by InitSentry.
 	// If <=0, DefaultSentryFlushTimeout will be used.
-	FlushTimeout time.Duration
+	FlushTimeout time.Duration `yaml:"flushTimeout"`
 }
 
 // InitSentry initializes sentry reporting.
@@ -1,25 +1,46 @@
 package metricsbp
 
 import (
-	"fmt"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
 
 	"github.com/reddit/baseplate.go/tracing"
 )
 
 const (
-	success = "success"
-	fail    = "fail"
+	success        = "success"
+	fail           = "fail"
+	activeRequests = "active_requests"
+	requests       = "requests"
 )
 
 // CreateServerSpanHook registers each Server Span with a MetricsSpanHook.
 type CreateServerSpanHook struct {
 	// Optional, will fallback to M when it's nil.
 	Metrics *Statsd
+
+	// TaggedStatus controls how the status of the spans is reported.
+	//
+	// When it's false (default), the status is encoded in the path of the
+	// counters, e.g. "server.foo.success" and "server.foo.fail".
+	//
+	// When it's true, a single "<component>.requests" counter is reported
+	// instead, labeled by "endpoint" (the name of the span) and "success"
+	// ("true" or "false"), e.g.
+	// "server.requests,endpoint=foo,success=true".
+	TaggedStatus bool
 }
 
 // OnCreateServerSpan registers MetricSpanHooks on a server Span.
+//
+// In addition to the metrics reported for all the spans,
+// server spans also maintain an "active_requests" gauge of the number of
+// in-flight server spans with the same name.
 func (h CreateServerSpanHook) OnCreateServerSpan(span *tracing.Span) error {
-	span.AddHooks(newSpanHook(h.Metrics.fallback(), span))
+	hook := newSpanHook(h.Metrics.fallback(), span, h.TaggedStatus)
+	hook.active = hook.metrics.Gauge(hook.names.active)
+	span.AddHooks(hook)
 	return nil
 }
 
@@ -27,31 +48,98 @@ func (h CreateServerSpanHook) OnCreateServerSpan(span *tracing.Span) error {
 // metric when the Span ends based on whether an error was passed to `span.End`
 // or not.
 type spanHook struct {
-	name    string
+	names   *spanMetricNames
 	metrics *Statsd
+	tagged  bool
 
 	timer *Timer
+
+	// Only set for server spans.
+	active metrics.Gauge
 }
 
-func newSpanHook(metrics *Statsd, span *tracing.Span) spanHook {
-	name := span.Component() + "." + span.Name()
+func newSpanHook(metrics *Statsd, span *tracing.Span, tagged bool) spanHook {
+	names := getSpanMetricNames(span.Component(), span.Name())
 	return spanHook{
-		name:    name,
+		names:   names,
 		metrics: metrics,
-		timer:   &Timer{Histogram: metrics.Timing(name)},
+		tagged:  tagged,
+		timer:   &Timer{Histogram: metrics.Timing(names.name)},
+	}
+}
+
+// spanMetricNames are the metric names used by spanHook for spans with the
+// same component and name.
+type spanMetricNames struct {
+	endpoint string
+	name     string
+	success  string
+	fail     string
+	active   string
+	requests string
+}
+
+type spanMetricNamesKey struct {
+	component string
+	endpoint  string
+}
+
+// spanMetricNamesCache caches the spanMetricNames so that they are not
+// rebuilt on every span.
+//
+// The number of distinct span names in a service is bounded by its endpoints
+// and clients, so the cache doesn't need eviction.
+var spanMetricNamesCache = struct {
+	sync.RWMutex
+	m map[spanMetricNamesKey]*spanMetricNames
+}{
+	m: make(map[spanMetricNamesKey]*spanMetricNames),
+}
+
+func getSpanMetricNames(component, endpoint string) *spanMetricNames {
+	key := spanMetricNamesKey{
+		component: component,
+		endpoint:  endpoint,
+	}
+	spanMetricNamesCache.RLock()
+	names := spanMetricNamesCache.m[key]
+	spanMetricNamesCache.RUnlock()
+	if names != nil {
+		return names
+	}
+
+	name := component + "." + endpoint
+	names = &spanMetricNames{
+		endpoint: endpoint,
+		name:     name,
+		success:  name + "." + success,
+		fail:     name + "." + fail,
+		active:   name + "." + activeRequests,
+		requests: component + "." + requests,
 	}
+	spanMetricNamesCache.Lock()
+	defer spanMetricNamesCache.Unlock()
+	if existing := spanMetricNamesCache.m[key]; existing != nil {
+		return existing
+	}
+	spanMetricNamesCache.m[key] = names
+	return names
 }
 
 // OnCreateChild registers a child MetricsSpanHook on the child Span and starts
 // a new Timer around the Span.
 func (h spanHook) OnCreateChild(parent, child *tracing.Span) error {
-	child.AddHooks(newSpanHook(h.metrics, child))
+	child.AddHooks(newSpanHook(h.metrics, child, h.tagged))
 	return nil
 }
 
-// OnPostStart starts the timer.
+// OnPostStart starts the timer,
+// and increments the active requests gauge for server spans.
 func (h spanHook) OnPostStart(span *tracing.Span) error {
 	h.timer.Start()
+	if h.active != nil {
+		h.active.Add(1)
+	}
 	return nil
 }
 
@@ -60,13 +148,30 @@ func (h spanHook) OnPostStart(span *tracing.Span) error {
 //
 // A span is marked as "fail" if `err != nil` otherwise it is marked as
 // "success".
+// When TaggedStatus is set in CreateServerSpanHook,
+// the status is reported as the "success" label of the
+// "<component>.requests" counter instead.
+//
+// For server spans it also decrements the active requests gauge.
 func (h spanHook) OnPreStop(span *tracing.Span, err error) error {
 	h.timer.ObserveDuration()
-	var statusMetricPath string
+	if h.active != nil {
+		h.active.Add(-1)
+	}
+	if h.tagged {
+		successValue := "true"
+		if err != nil {
+			successValue = "false"
+		}
+		h.metrics.Counter(h.names.requests).With(
+			"endpoint", h.names.endpoint,
+			"success", successValue,
+		).Add(1)
+		return nil
+	}
+	statusMetricPath := h.names.success
 	if err != nil {
-		statusMetricPath = fmt.Sprintf("%s.%s", h.name, fail)
-	} else {
-		statusMetricPath = fmt.Sprintf("%s.%s", h.name, success)
+		statusMetricPath = h.names.fail
 	}
 	h.metrics.Counter(statusMetricPath).Add(1)
 	return nil
@@ -8,11 +8,13 @@ import (
 	"testing"
 	"time"
 
+	opentracing "github.com/opentracing/opentracing-go"
+
 	"github.com/reddit/baseplate.go/metricsbp"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
-func runSpan(st *metricsbp.Statsd, spanErr error) (counter string, successCounter string, histogram string, err error) {
+func runSpan(st *metricsbp.Statsd, spanErr error) (counter string, successCounter string, histogram string, gauge string, err error) {
 	ctx, span := tracing.StartSpanFromHeaders(context.Background(), "foo", tracing.Headers{})
 	time.Sleep(time.Millisecond)
 	span.AddCounter("bar.count", 1.0)
@@ -23,8 +25,8 @@ func runSpan(st *metricsbp.Statsd, spanErr error) (counter string, successCounte
 		return
 	}
 	stats := strings.Split(sb.String(), "\n")
-	if len(stats) != 4 {
-		err = fmt.Errorf("Expected 3 stats, got %d\n%v", len(stats)-1, stats)
+	if len(stats) != 5 {
+		err = fmt.Errorf("Expected 4 stats, got %d\n%v", len(stats)-1, stats)
 		return
 	}
 
@@ -35,6 +37,8 @@ func runSpan(st *metricsbp.Statsd, spanErr error) (counter string, successCounte
 			successCounter = stat
 		} else if strings.HasSuffix(stat, "|ms") {
 			histogram = stat
+		} else if strings.HasSuffix(stat, "|g") {
+			gauge = stat
 		}
 	}
 	return
@@ -58,7 +62,7 @@ func TestOnCreateServerSpan(t *testing.T) {
 	t.Run(
 		"success",
 		func(t *testing.T) {
-			counter, statusCounter, histogram, err := runSpan(st, nil)
+			counter, statusCounter, histogram, gauge, err := runSpan(st, nil)
 			if err != nil {
 				t.Fatalf("Got error: %s", err)
 			}
@@ -76,13 +80,18 @@ func TestOnCreateServerSpan(t *testing.T) {
 			if !histogramRegex.MatchString(histogram) {
 				t.Errorf("Histogram %s did not match expected format", histogram)
 			}
+
+			expected = "server.foo.active_requests:0.000000|g"
+			if gauge != expected {
+				t.Errorf("Expected active requests gauge: %s\nGot: %s", expected, gauge)
+			}
 		},
 	)
 
 	t.Run(
 		"fail",
 		func(t *testing.T) {
-			counter, statusCounter, histogram, err := runSpan(st, fmt.Errorf("test error"))
+			counter, statusCounter, histogram, gauge, err := runSpan(st, fmt.Errorf("test error"))
 			if err != nil {
 				t.Fatalf("Got error: %s", err)
 			}
@@ -100,6 +109,147 @@ func TestOnCreateServerSpan(t *testing.T) {
 			if !histogramRegex.MatchString(histogram) {
 				t.Errorf("Histogram %s did not match expected format", histogram)
 			}
+
+			expected = "server.foo.active_requests:0.000000|g"
+			if gauge != expected {
+				t.Errorf("Expected active requests gauge: %s\nGot: %s", expected, gauge)
+			}
 		},
 	)
 }
+
+func TestOnCreateServerSpanActiveRequests(t *testing.T) {
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.StatsdConfig{},
+	)
+
+	hook := metricsbp.CreateServerSpanHook{Metrics: st}
+	tracing.RegisterCreateServerSpanHooks(hook)
+	defer tracing.ResetHooks()
+
+	gauge := func() string {
+		t.Helper()
+		var sb strings.Builder
+		if _, err := st.Statsd.WriteTo(&sb); err != nil {
+			t.Fatal(err)
+		}
+		for _, stat := range strings.Split(sb.String(), "\n") {
+			if strings.HasSuffix(stat, "|g") {
+				return stat
+			}
+		}
+		return ""
+	}
+
+	ctx1, span1 := tracing.StartSpanFromHeaders(context.Background(), "foo", tracing.Headers{})
+	ctx2, span2 := tracing.StartSpanFromHeaders(context.Background(), "foo", tracing.Headers{})
+	// Child spans don't count as active requests.
+	child, _ := opentracing.StartSpanFromContext(ctx1, "bar")
+	child.Finish()
+	if actual, expected := gauge(), "server.foo.active_requests:2.000000|g"; actual != expected {
+		t.Errorf("Expected active requests gauge: %s\nGot: %s", expected, actual)
+	}
+
+	span1.Stop(ctx1, nil)
+	if actual, expected := gauge(), "server.foo.active_requests:1.000000|g"; actual != expected {
+		t.Errorf("Expected active requests gauge: %s\nGot: %s", expected, actual)
+	}
+
+	span2.Stop(ctx2, nil)
+	if actual, expected := gauge(), "server.foo.active_requests:0.000000|g"; actual != expected {
+		t.Errorf("Expected active requests gauge: %s\nGot: %s", expected, actual)
+	}
+}
+
+func TestOnCreateServerSpanTaggedStatus(t *testing.T) {
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.StatsdConfig{},
+	)
+
+	hook := metricsbp.CreateServerSpanHook{
+		Metrics:      st,
+		TaggedStatus: true,
+	}
+	tracing.RegisterCreateServerSpanHooks(hook)
+	defer tracing.ResetHooks()
+
+	for _, c := range []struct {
+		label    string
+		err      error
+		expected []string
+	}{
+		{
+			label: "success",
+			expected: []string{
+				"server.requests,endpoint=foo,success=true:1.000000|c",
+				"clients.requests,endpoint=bar,success=true:1.000000|c",
+			},
+		},
+		{
+			label: "fail",
+			err:   fmt.Errorf("test error"),
+			expected: []string{
+				"server.requests,endpoint=foo,success=false:1.000000|c",
+				"clients.requests,endpoint=bar,success=false:1.000000|c",
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx, span := tracing.StartSpanFromHeaders(context.Background(), "foo", tracing.Headers{})
+			child, childCtx := opentracing.StartSpanFromContext(
+				ctx,
+				"bar",
+				tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+			)
+			tracing.AsSpan(child).Stop(childCtx, c.err)
+			span.Stop(ctx, c.err)
+
+			var sb strings.Builder
+			if _, err := st.Statsd.WriteTo(&sb); err != nil {
+				t.Fatal(err)
+			}
+			stats := make(map[string]bool)
+			for _, stat := range strings.Split(sb.String(), "\n") {
+				stats[stat] = true
+				if strings.HasSuffix(stat, ".success:1.000000|c") || strings.HasSuffix(stat, ".fail:1.000000|c") {
+					t.Errorf("Unexpected status counter %s", stat)
+				}
+			}
+			for _, expected := range c.expected {
+				if !stats[expected] {
+					t.Errorf("Expected status counter %s, got %v", expected, stats)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSpanHook(b *testing.B) {
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.StatsdConfig{},
+	)
+
+	for _, tagged := range []bool{false, true} {
+		b.Run(
+			fmt.Sprintf("tagged=%v", tagged),
+			func(b *testing.B) {
+				hook := metricsbp.CreateServerSpanHook{
+					Metrics:      st,
+					TaggedStatus: tagged,
+				}
+				tracing.RegisterCreateServerSpanHooks(hook)
+				defer tracing.ResetHooks()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					ctx, span := tracing.StartSpanFromHeaders(context.Background(), "foo", tracing.Headers{})
+					span.Stop(ctx, nil)
+				}
+			},
+		)
+	}
+}
@@ -3,8 +3,10 @@ package metricsbp
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/prometheusbp"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -19,6 +21,21 @@ type Config struct {
 	// Endpoint is the endpoint for your metrics backend.
 	Endpoint string `yaml:"endpoint"`
 
+	// Network is the network type of Endpoint, e.g. "udp" or "unixgram".
+	//
+	// Optional, defaults to "udp".
+	Network string `yaml:"network"`
+
+	// FlushInterval is the interval buffered metrics are sent to Endpoint.
+	//
+	// Optional, defaults to ReporterTickerInterval.
+	FlushInterval time.Duration `yaml:"flushInterval"`
+
+	// Labels are the labels/tags to be attached to every metrics.
+	//
+	// Optional.
+	Labels Labels `yaml:"labels"`
+
 	// CounterSampleRate is the fraction of counters that you want to send to your
 	// metrics backend.
 	//
@@ -30,21 +47,78 @@ type Config struct {
 	//
 	// Optional, defaults to 1.0
 	HistogramSampleRate *float64 `yaml:"histogramSampleRate"`
+
+	// RunSysStats controls whether InitFromConfig also starts reporting sys
+	// stats (see Statsd.RunSysStats).
+	//
+	// Optional, defaults to false.
+	RunSysStats bool `yaml:"runSysStats"`
+
+	// TaggedStatus controls whether the span hooks registered by InitFromConfig
+	// report the status of the spans as labels of a single counter,
+	// instead of as suffixes of the counter paths.
+	//
+	// See CreateServerSpanHook.TaggedStatus for more details.
+	//
+	// Optional, defaults to false.
+	TaggedStatus bool `yaml:"taggedStatus"`
+
+	// Prometheus controls whether the metrics are exposed to be scraped by
+	// Prometheus instead of sent to Endpoint.
+	//
+	// When it's true, InitFromConfig creates a prometheusbp.Registry with
+	// Namespace and Labels (see StatsdConfig.Prometheus),
+	// and baseplate.New serves it at "/metrics" on the admin server.
+	//
+	// Optional, defaults to false.
+	Prometheus bool `yaml:"prometheus"`
+
+	// DualWrite controls whether the metrics are also sent to Endpoint when
+	// Prometheus is true, to validate the Prometheus dashboards against the
+	// existing statsd graphs before switching over.
+	//
+	// See StatsdConfig.DualWrite for more details.
+	//
+	// Optional, defaults to false.
+	DualWrite bool `yaml:"dualWrite"`
 }
 
 // InitFromConfig initializes the global metricsbp.M with the given context and
 // Config and returns an io.Closer to use to close out the metrics client when
 // your server exits.
 //
-// It also registers CreateServerSpanHook with the global tracing hook registry.
+// It also registers CreateServerSpanHook with the global tracing hook registry,
+// and calls RunSysStats on M when RunSysStats in cfg is true.
+//
+// When Prometheus in cfg is true, the metrics are registered with a new
+// prometheusbp.Registry, which can be accessed via M.Prometheus().
 func InitFromConfig(ctx context.Context, cfg Config) io.Closer {
+	var registry *prometheusbp.Registry
+	if cfg.Prometheus {
+		registry = prometheusbp.NewRegistry(prometheusbp.Config{
+			Namespace: cfg.Namespace,
+			Labels:    cfg.Labels,
+		})
+	}
 	M = NewStatsd(ctx, StatsdConfig{
 		CounterSampleRate:   cfg.CounterSampleRate,
 		HistogramSampleRate: cfg.HistogramSampleRate,
 		Prefix:              cfg.Namespace,
 		Address:             cfg.Endpoint,
+		Network:             cfg.Network,
+		FlushInterval:       cfg.FlushInterval,
+		Labels:              cfg.Labels,
 		LogLevel:            log.ErrorLevel,
+		Prometheus:          registry,
+		DualWrite:           cfg.DualWrite,
+	})
+	tracing.RegisterCreateServerSpanHooks(CreateServerSpanHook{
+		TaggedStatus: cfg.TaggedStatus,
 	})
-	tracing.RegisterCreateServerSpanHooks(CreateServerSpanHook{})
+	tracing.SetHookFailuresCounter(M.Counter("tracing.hook.failures"))
+	tracing.SetSpansDroppedCounter(M.Counter("tracing.spans.dropped"))
+	if cfg.RunSysStats {
+		M.RunSysStats(nil)
+	}
 	return M
 }
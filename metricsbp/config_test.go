@@ -3,6 +3,7 @@ package metricsbp_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -47,6 +48,61 @@ histogramSampleRate: 0.01
 				HistogramSampleRate: float64Ptr(0.01),
 			},
 		},
+		{
+			name: "unixgram-labels",
+			body: `
+namespace: foo
+endpoint: /var/run/statsd.sock
+network: unixgram
+flushInterval: 10s
+labels:
+  region: us-east-1
+`,
+			expected: metricsbp.Config{
+				Namespace:     "foo",
+				Endpoint:      "/var/run/statsd.sock",
+				Network:       "unixgram",
+				FlushInterval: 10 * time.Second,
+				Labels:        metricsbp.Labels{"region": "us-east-1"},
+			},
+		},
+		{
+			name: "sys-stats",
+			body: `
+namespace: foo
+endpoint: metrics:8125
+runSysStats: true
+`,
+			expected: metricsbp.Config{
+				Namespace:   "foo",
+				Endpoint:    "metrics:8125",
+				RunSysStats: true,
+			},
+		},
+		{
+			name: "prometheus",
+			body: `
+namespace: foo
+prometheus: true
+`,
+			expected: metricsbp.Config{
+				Namespace:  "foo",
+				Prometheus: true,
+			},
+		},
+		{
+			name: "dual-write",
+			body: `
+namespace: foo
+prometheus: true
+dualWrite: true
+`,
+			expected: metricsbp.Config{
+				Namespace:  "foo",
+				Prometheus: true,
+				DualWrite:  true,
+			},
+		},
 	}
 
 	for _, _c := range cases {
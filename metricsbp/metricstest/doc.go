@@ -0,0 +1,13 @@
+// Package metricstest provides an in-memory metricsbp.Statsd for tests.
+//
+// It allows asserting the metrics reported by the code under test
+// (e.g. middlewares and hooks) without standing up a statsd server.
+//
+// A typical test looks like:
+//
+//     func TestMyMiddleware(t *testing.T) {
+//       recorder := metricstest.Replace(t)
+//       // Call the code reporting metrics to metricsbp.M...
+//       recorder.AssertCounterEquals(t, "my-counter", 1)
+//     }
+package metricstest
@@ -0,0 +1,193 @@
+package metricstest
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// Snapshot is a snapshot of all the metrics recorded by a Recorder.
+//
+// The keys of the maps are the names of the metrics,
+// with the labels appended in the influxstatsd format,
+// e.g. "my-counter,label1=value1,label2=value2".
+type Snapshot struct {
+	// Counters are the sums of all the counter values.
+	Counters map[string]float64
+
+	// Gauges are the last values of the gauges.
+	Gauges map[string]float64
+
+	// Histograms are all the values observed by histograms.
+	Histograms map[string][]float64
+
+	// Timings are all the values observed by timings, in milliseconds.
+	Timings map[string][]float64
+}
+
+func newSnapshot() Snapshot {
+	return Snapshot{
+		Counters:   make(map[string]float64),
+		Gauges:     make(map[string]float64),
+		Histograms: make(map[string][]float64),
+		Timings:    make(map[string][]float64),
+	}
+}
+
+func (s Snapshot) clone() Snapshot {
+	c := newSnapshot()
+	for k, v := range s.Counters {
+		c.Counters[k] = v
+	}
+	for k, v := range s.Gauges {
+		c.Gauges[k] = v
+	}
+	for k, v := range s.Histograms {
+		c.Histograms[k] = append([]float64(nil), v...)
+	}
+	for k, v := range s.Timings {
+		c.Timings[k] = append([]float64(nil), v...)
+	}
+	return c
+}
+
+// Recorder records the metrics reported to its Statsd in memory.
+//
+// Please use New or Replace to create a Recorder.
+type Recorder struct {
+	// Statsd is the Statsd that records metrics into this Recorder.
+	Statsd *metricsbp.Statsd
+
+	lock     sync.Mutex
+	snapshot Snapshot
+}
+
+// New creates a new Recorder.
+//
+// The Statsd of the returned Recorder never sends the metrics anywhere.
+func New() *Recorder {
+	return &Recorder{
+		Statsd:   metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{}),
+		snapshot: newSnapshot(),
+	}
+}
+
+// Replace creates a new Recorder and replaces metricsbp.M with its Statsd.
+//
+// The original metricsbp.M will be restored when the test finishes.
+//
+// Note that metrics created before calling Replace
+// (e.g. by middlewares created in an init function)
+// are still reported to the original metricsbp.M.
+func Replace(tb testing.TB) *Recorder {
+	tb.Helper()
+
+	r := New()
+	orig := metricsbp.M
+	metricsbp.M = r.Statsd
+	tb.Cleanup(func() {
+		metricsbp.M = orig
+	})
+	return r
+}
+
+// Snapshot returns a snapshot of all the metrics recorded so far.
+func (r *Recorder) Snapshot() Snapshot {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.flush()
+	return r.snapshot.clone()
+}
+
+// Reset clears all the metrics recorded so far.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.flush()
+	r.snapshot = newSnapshot()
+}
+
+// flush reads the buffered metrics from the Statsd into the snapshot.
+//
+// Caller must hold the lock.
+func (r *Recorder) flush() {
+	var buf bytes.Buffer
+	r.Statsd.Statsd.WriteTo(&buf)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		r.parseLine(line)
+	}
+}
+
+// parseLine parses a single line in the influxstatsd format, e.g.
+// "name,label=value:1.000000|c|@0.5".
+//
+// Caller must hold the lock.
+func (r *Recorder) parseLine(line string) {
+	colon := strings.LastIndex(line, ":")
+	if colon < 0 {
+		return
+	}
+	name := line[:colon]
+	parts := strings.Split(line[colon+1:], "|")
+	if len(parts) < 2 {
+		return
+	}
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return
+	}
+	switch parts[1] {
+	case "c":
+		r.snapshot.Counters[name] += value
+	case "g":
+		r.snapshot.Gauges[name] = value
+	case "h":
+		r.snapshot.Histograms[name] = append(r.snapshot.Histograms[name], value)
+	case "ms":
+		r.snapshot.Timings[name] = append(r.snapshot.Timings[name], value)
+	}
+}
+
+// AssertCounterEquals asserts that the sum of the counter is expected.
+//
+// A counter never reported is treated as 0.
+func (r *Recorder) AssertCounterEquals(tb testing.TB, name string, expected float64) {
+	tb.Helper()
+
+	if actual := r.Snapshot().Counters[name]; actual != expected {
+		tb.Errorf("Expected counter %q to be %v, got %v", name, expected, actual)
+	}
+}
+
+// AssertGaugeEquals asserts that the last value of the gauge is expected.
+func (r *Recorder) AssertGaugeEquals(tb testing.TB, name string, expected float64) {
+	tb.Helper()
+
+	actual, ok := r.Snapshot().Gauges[name]
+	if !ok {
+		tb.Errorf("Expected gauge %q to be %v, got never reported", name, expected)
+		return
+	}
+	if actual != expected {
+		tb.Errorf("Expected gauge %q to be %v, got %v", name, expected, actual)
+	}
+}
+
+// AssertHistogramCount asserts that the number of values observed by the
+// histogram or timing is expected.
+func (r *Recorder) AssertHistogramCount(tb testing.TB, name string, expected int) {
+	tb.Helper()
+
+	snapshot := r.Snapshot()
+	actual := len(snapshot.Histograms[name]) + len(snapshot.Timings[name])
+	if actual != expected {
+		tb.Errorf("Expected histogram %q to have %d values, got %d", name, expected, actual)
+	}
+}
@@ -0,0 +1,56 @@
+package metricstest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+)
+
+func TestReplace(t *testing.T) {
+	orig := metricsbp.M
+	t.Run("replace", func(t *testing.T) {
+		recorder := metricstest.Replace(t)
+		if metricsbp.M != recorder.Statsd {
+			t.Error("Expected metricsbp.M to be replaced")
+		}
+	})
+	if metricsbp.M != orig {
+		t.Error("Expected metricsbp.M to be restored")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := metricstest.Replace(t)
+
+	metricsbp.M.Counter("counter").Add(1)
+	metricsbp.M.Counter("counter").Add(2)
+	metricsbp.M.Counter("labeled").With("key", "value").Add(1)
+	metricsbp.M.Gauge("gauge").Set(1)
+	metricsbp.M.Gauge("gauge").Set(5)
+	metricsbp.M.Histogram("histo").Observe(1)
+	metricsbp.M.Histogram("histo").Observe(2)
+	metricsbp.M.Timing("timing").Observe(float64(time.Millisecond))
+
+	recorder.AssertCounterEquals(t, "counter", 3)
+	recorder.AssertCounterEquals(t, "labeled,key=value", 1)
+	recorder.AssertCounterEquals(t, "never-reported", 0)
+	recorder.AssertGaugeEquals(t, "gauge", 5)
+	recorder.AssertHistogramCount(t, "histo", 2)
+	recorder.AssertHistogramCount(t, "timing", 1)
+
+	// Counters accumulate across snapshots.
+	metricsbp.M.Counter("counter").Add(1)
+	snapshot := recorder.Snapshot()
+	if actual := snapshot.Counters["counter"]; actual != 4 {
+		t.Errorf("Expected counter to be 4, got %v", actual)
+	}
+	if actual := snapshot.Histograms["histo"]; len(actual) != 2 || actual[0] != 1 || actual[1] != 2 {
+		t.Errorf("Expected histo values [1 2], got %v", actual)
+	}
+
+	recorder.Reset()
+	recorder.AssertCounterEquals(t, "counter", 0)
+	recorder.AssertHistogramCount(t, "histo", 0)
+}
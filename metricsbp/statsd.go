@@ -7,17 +7,24 @@ import (
 
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/influxstatsd"
+	"github.com/go-kit/kit/metrics/multi"
 	"github.com/go-kit/kit/util/conn"
 
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/prometheusbp"
 )
 
 // DefaultSampleRate is the default value to be used when *SampleRate in
 // StatsdConfig is nil (zero value).
 const DefaultSampleRate = 1
 
+// DefaultNetwork is the default value to be used when Network in StatsdConfig
+// is empty.
+const DefaultNetwork = "udp"
+
 // ReporterTickerInterval is the interval the reporter sends data to statsd
-// server. Default is one minute.
+// server when FlushInterval in StatsdConfig is not set.
+// Default is one minute.
 var ReporterTickerInterval = time.Minute
 
 // M is short for "Metrics".
@@ -41,7 +48,7 @@ var ReporterTickerInterval = time.Minute
 //           ...
 //         },
 //       }
-//       metricsbp.M.RunSysStats()
+//       metricsbp.M.RunSysStats(nil)
 //       ...
 //     }
 //
@@ -97,7 +104,10 @@ type StatsdConfig struct {
 	CounterSampleRate   *float64
 	HistogramSampleRate *float64
 
-	// Address is the UDP address (in "host:port" format) of the statsd service.
+	// Address is the address of the statsd service.
+	//
+	// For the default "udp" Network it should be in "host:port" format,
+	// for "unixgram" it should be the path to the unix domain socket.
 	//
 	// It could be empty string, in which case we won't start the background
 	// reporting goroutine.
@@ -110,6 +120,17 @@ type StatsdConfig struct {
 	// so it shouldn't be used in lieu of discarded metrics in prod code.
 	Address string
 
+	// Network is the network type of Address, e.g. "udp" or "unixgram".
+	//
+	// DefaultNetwork will be used when it's empty.
+	Network string
+
+	// FlushInterval is the interval the buffered metrics are sent to the statsd
+	// service.
+	//
+	// ReporterTickerInterval will be used when it's zero.
+	FlushInterval time.Duration
+
 	// The log level used by the reporting goroutine.
 	LogLevel log.Level
 
@@ -117,6 +138,22 @@ type StatsdConfig struct {
 	// from this Statsd object. For labels/tags only needed by some metrics,
 	// use Counter/Gauge/Timing.With() instead.
 	Labels Labels
+
+	// When Prometheus is non-nil, the metrics created from this Statsd object
+	// are registered with it to be scraped by Prometheus,
+	// instead of being sent to Address.
+	//
+	// Prefix, Labels and the sample rates are ignored in that case,
+	// use the Namespace and Labels of the prometheusbp.Config instead.
+	Prometheus *prometheusbp.Registry
+
+	// When DualWrite is true and Prometheus is non-nil,
+	// the metrics are both sent to Address and registered with Prometheus,
+	// so the Prometheus dashboards can be validated against the existing
+	// statsd graphs during the migration.
+	//
+	// Prefix, Labels and the sample rates only apply to the statsd side.
+	DualWrite bool
 }
 
 func convertSampleRate(rate *float64) float64 {
@@ -152,11 +189,15 @@ func NewStatsd(ctx context.Context, cfg StatsdConfig) *Statsd {
 	st.ctx, st.cancel = context.WithCancel(ctx)
 
 	if cfg.Address != "" {
+		interval := cfg.FlushInterval
+		if interval <= 0 {
+			interval = ReporterTickerInterval
+		}
 		go func() {
-			ticker := time.NewTicker(ReporterTickerInterval)
+			ticker := time.NewTicker(interval)
 			defer ticker.Stop()
 
-			st.Statsd.SendLoop(st.ctx, ticker.C, "udp", cfg.Address)
+			st.Statsd.SendLoop(st.ctx, ticker.C, st.network(), cfg.Address)
 		}()
 	}
 
@@ -167,42 +208,60 @@ func NewStatsd(ctx context.Context, cfg StatsdConfig) *Statsd {
 // with sample rate inherited from StatsdConfig.
 func (st *Statsd) Counter(name string) metrics.Counter {
 	st = st.fallback()
-	counter := st.Statsd.NewCounter(name, st.counterSampleRate)
-	if st.counterSampleRate >= 1 {
-		return counter
+	if st.prometheusOnly() {
+		return st.cfg.Prometheus.Counter(name)
+	}
+	var counter metrics.Counter = st.Statsd.NewCounter(name, st.counterSampleRate)
+	if st.counterSampleRate < 1 {
+		counter = SampledCounter{
+			Counter: counter,
+			Rate:    st.counterSampleRate,
+		}
 	}
-	return SampledCounter{
-		Counter: counter,
-		Rate:    st.counterSampleRate,
+	if st.cfg.Prometheus != nil {
+		return multi.NewCounter(counter, st.cfg.Prometheus.Counter(name))
 	}
+	return counter
 }
 
 // Histogram returns a histogram metrics to the name with no specific unit,
 // with sample rate inherited from StatsdConfig.
 func (st *Statsd) Histogram(name string) metrics.Histogram {
 	st = st.fallback()
-	histogram := st.Statsd.NewHistogram(name, st.histogramSampleRate)
-	if st.histogramSampleRate >= 1 {
-		return histogram
+	if st.prometheusOnly() {
+		return st.cfg.Prometheus.Histogram(name)
 	}
-	return SampledHistogram{
-		Histogram: histogram,
-		Rate:      st.histogramSampleRate,
+	var histogram metrics.Histogram = st.Statsd.NewHistogram(name, st.histogramSampleRate)
+	if st.histogramSampleRate < 1 {
+		histogram = SampledHistogram{
+			Histogram: histogram,
+			Rate:      st.histogramSampleRate,
+		}
 	}
+	if st.cfg.Prometheus != nil {
+		return multi.NewHistogram(histogram, st.cfg.Prometheus.Histogram(name))
+	}
+	return histogram
 }
 
 // Timing returns a histogram metrics to the name with milliseconds as the unit,
 // with sample rate inherited from StatsdConfig.
 func (st *Statsd) Timing(name string) metrics.Histogram {
 	st = st.fallback()
-	histogram := st.Statsd.NewTiming(name, st.histogramSampleRate)
-	if st.histogramSampleRate >= 1 {
-		return histogram
+	if st.prometheusOnly() {
+		return st.cfg.Prometheus.Timing(name)
+	}
+	var histogram metrics.Histogram = st.Statsd.NewTiming(name, st.histogramSampleRate)
+	if st.histogramSampleRate < 1 {
+		histogram = SampledHistogram{
+			Histogram: histogram,
+			Rate:      st.histogramSampleRate,
+		}
 	}
-	return SampledHistogram{
-		Histogram: histogram,
-		Rate:      st.histogramSampleRate,
+	if st.cfg.Prometheus != nil {
+		return multi.NewHistogram(histogram, st.cfg.Prometheus.Timing(name))
 	}
+	return histogram
 }
 
 // Gauge returns a gauge metrics to the name.
@@ -210,9 +269,34 @@ func (st *Statsd) Timing(name string) metrics.Histogram {
 // It's a shortcut to st.Statsd.NewGauge(name).
 func (st *Statsd) Gauge(name string) metrics.Gauge {
 	st = st.fallback()
+	if st.prometheusOnly() {
+		return st.cfg.Prometheus.Gauge(name)
+	}
+	if st.cfg.Prometheus != nil {
+		return multi.NewGauge(st.Statsd.NewGauge(name), st.cfg.Prometheus.Gauge(name))
+	}
 	return st.Statsd.NewGauge(name)
 }
 
+// prometheusOnly returns whether the metrics are only registered with
+// StatsdConfig.Prometheus.
+func (st *Statsd) prometheusOnly() bool {
+	return st.cfg.Prometheus != nil && !st.cfg.DualWrite
+}
+
+// Prometheus returns the prometheusbp.Registry the metrics are registered
+// with, or nil if StatsdConfig.Prometheus was not set.
+func (st *Statsd) Prometheus() *prometheusbp.Registry {
+	return st.fallback().cfg.Prometheus
+}
+
+func (st *Statsd) network() string {
+	if st.cfg.Network == "" {
+		return DefaultNetwork
+	}
+	return st.cfg.Network
+}
+
 func (st *Statsd) fallback() *Statsd {
 	if st == nil {
 		return M
@@ -269,12 +353,30 @@ func (st *Statsd) Ctx() context.Context {
 // and use Close() call to do the cleanup instead of canceling the context.
 func (st *Statsd) Close() error {
 	st.cancel()
+	return st.write()
+}
+
+// Flush writes all metrics not written to collector yet (if Address was set)
+// immediately, without waiting for the next FlushInterval.
+//
+// Unlike Close, the background reporting goroutine keeps running after Flush
+// returns.
+//
+// It's useful on the shutdown path of servers,
+// to make sure the metrics reported during the last FlushInterval
+// (including the ones reported by the shutdown itself) are not lost.
+// baseplate.Serve calls it on M after the server is closed.
+func (st *Statsd) Flush() error {
+	return st.fallback().write()
+}
+
+func (st *Statsd) write() error {
 	if st.cfg.Address == "" {
 		return nil
 	}
 
 	_, err := st.Statsd.WriteTo(conn.NewDefaultManager(
-		"udp",
+		st.network(),
 		st.cfg.Address,
 		log.KitLogger(st.cfg.LogLevel),
 	))
@@ -3,10 +3,16 @@ package metricsbp_test
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/prometheusbp"
 )
 
 func TestGlobalStatsd(t *testing.T) {
@@ -94,6 +100,182 @@ func TestNoFallback(t *testing.T) {
 	}
 }
 
+func TestUnixgramFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metricsbp_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "statsd.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+		Name: path,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st := metricsbp.NewStatsd(
+		ctx,
+		metricsbp.StatsdConfig{
+			Prefix:        "prefix",
+			Address:       path,
+			Network:       "unixgram",
+			FlushInterval: time.Millisecond * 10,
+			Labels: metricsbp.Labels{
+				"foo": "bar",
+			},
+		},
+	)
+	st.Counter("counter").Add(1)
+
+	if err := listener.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "prefix.counter,foo=bar:1.000000|c\n"
+	if actual := string(buf[:n]); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metricsbp_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "statsd.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+		Name: path,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.StatsdConfig{
+			Address: path,
+			Network: "unixgram",
+			// Make sure the metrics are only written by Flush.
+			FlushInterval: time.Hour,
+		},
+	)
+	defer st.Close()
+	st.Counter("counter").Add(1)
+	if err := st.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := listener.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "counter:1.000000|c\n"
+	if actual := string(buf[:n]); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+	if err := st.Ctx().Err(); err != nil {
+		t.Errorf("Expected Ctx to be still alive after Flush, got %v", err)
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	registry := prometheusbp.NewRegistry(prometheusbp.Config{Namespace: "prefix"})
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.StatsdConfig{
+			Prometheus: registry,
+		},
+	)
+	if st.Prometheus() != registry {
+		t.Errorf("Expected Prometheus to return the registry")
+	}
+	st.Counter("counter").With("foo", "bar").Add(1)
+	st.Gauge("gauge").Set(2)
+
+	var sb strings.Builder
+	if _, err := registry.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP prefix_counter counter
+# TYPE prefix_counter counter
+prefix_counter{foo="bar"} 1
+# HELP prefix_gauge gauge
+# TYPE prefix_gauge gauge
+prefix_gauge 2
+`
+	if actual := sb.String(); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+
+	var buf bytes.Buffer
+	st.Statsd.WriteTo(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no statsd metrics, got %q", buf.String())
+	}
+}
+
+func TestPrometheusDualWrite(t *testing.T) {
+	registry := prometheusbp.NewRegistry(prometheusbp.Config{Namespace: "prefix"})
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.StatsdConfig{
+			Prefix:     "prefix",
+			Prometheus: registry,
+			DualWrite:  true,
+		},
+	)
+	st.Counter("counter").With("foo", "bar").Add(1)
+	st.Gauge("gauge").Set(2)
+	st.Histogram("histogram").Observe(3)
+	st.Timing("timing").Observe(4)
+
+	var sb strings.Builder
+	if _, err := registry.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`prefix_counter{foo="bar"} 1`,
+		`prefix_gauge 2`,
+		`prefix_histogram_count 1`,
+		`prefix_timing_seconds_count 1`,
+	} {
+		if !strings.Contains(sb.String(), expected) {
+			t.Errorf("Expected %q in prometheus metrics, got %q", expected, sb.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	st.Statsd.WriteTo(&buf)
+	for _, expected := range []string{
+		"prefix.counter,foo=bar:1.000000|c",
+		"prefix.gauge:2.000000|g",
+		"prefix.histogram:3.000000|h",
+		"prefix.timing:4.000000|ms",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in statsd metrics, got %q", expected, buf.String())
+		}
+	}
+}
+
 func BenchmarkStatsd(b *testing.B) {
 	const (
 		label      = "label"
@@ -1,7 +1,11 @@
 package metricsbp
 
 import (
+	"context"
+	"io/ioutil"
+	"math"
 	"runtime"
+	"sort"
 	"time"
 )
 
@@ -21,12 +25,96 @@ func pullRuntimeStats() (cpu cpuStats, mem runtime.MemStats) {
 	return
 }
 
+// gcPausePercentiles returns the percentiles of the GC pauses happened since
+// lastNumGC, in nanoseconds.
+//
+// runtime.MemStats only keeps the most recent 256 pauses,
+// so if more than 256 GCs happened since lastNumGC only those are used.
+// When no GC happened since lastNumGC, ok will be false.
+func gcPausePercentiles(mem *runtime.MemStats, lastNumGC uint32, percentiles ...float64) (values []float64, ok bool) {
+	n := int(mem.NumGC - lastNumGC)
+	if n <= 0 {
+		return nil, false
+	}
+	if n > len(mem.PauseNs) {
+		n = len(mem.PauseNs)
+	}
+	pauses := make([]uint64, n)
+	for i := range pauses {
+		pauses[i] = mem.PauseNs[(int(mem.NumGC)-1-i+len(mem.PauseNs))%len(mem.PauseNs)]
+	}
+	sort.Slice(pauses, func(i, j int) bool {
+		return pauses[i] < pauses[j]
+	})
+	values = make([]float64, len(percentiles))
+	for i, p := range percentiles {
+		index := int(math.Ceil(p*float64(n))) - 1
+		if index < 0 {
+			index = 0
+		}
+		if index >= n {
+			index = n - 1
+		}
+		values[i] = float64(pauses[index])
+	}
+	return values, true
+}
+
+// openFDsDir is the directory to list to count the open file descriptors of
+// the current process.
+//
+// It only exists on linux.
+const openFDsDir = "/proc/self/fd"
+
+func countOpenFDs() (int, error) {
+	files, err := ioutil.ReadDir(openFDsDir)
+	if err != nil {
+		return 0, err
+	}
+	return len(files), nil
+}
+
 // RunSysStats starts a goroutine to periodically pull and report sys stats.
 //
+// The stats are reported every SysStatsTickerInterval,
+// and include goroutine count, GC pause percentiles (p50, p95 and p99 of the
+// pauses happened during the last interval), heap stats, allocations per
+// second and open file descriptors (linux only).
+//
 // Canceling the context passed into NewStatsd will stop this goroutine.
+//
+// See the package level RunSysStats for a version with custom interval and
+// context.
 func (st *Statsd) RunSysStats(labels Labels) {
 	st = st.fallback()
+	st.runSysStats(st.ctx, labels, SysStatsTickerInterval)
+}
+
+// RunSysStats starts a goroutine to report the same sys stats as
+// Statsd.RunSysStats to st every interval, without any labels.
+//
+// The goroutine stops when either ctx or the context passed into NewStatsd is
+// canceled.
+//
+// If st is nil, M will be used instead.
+// If interval is not positive, SysStatsTickerInterval will be used instead.
+func RunSysStats(ctx context.Context, st *Statsd, interval time.Duration) {
+	st = st.fallback()
+	if interval <= 0 {
+		interval = SysStatsTickerInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		select {
+		case <-ctx.Done():
+		case <-st.ctx.Done():
+		}
+	}()
+	st.runSysStats(ctx, nil, interval)
+}
 
+func (st *Statsd) runSysStats(ctx context.Context, labels Labels, interval time.Duration) {
 	l := labels.AsStatsdLabels()
 
 	// init the gauges
@@ -40,6 +128,9 @@ func (st *Statsd) RunSysStats(labels Labels) {
 	gcPauseTotal := st.Gauge("mem.gc.pause_total").With(l...)
 	gcPause := st.Gauge("mem.gc.pause").With(l...)
 	gcCount := st.Gauge("mem.gc.count").With(l...)
+	gcPauseP50 := st.Gauge("mem.gc.pause.p50").With(l...)
+	gcPauseP95 := st.Gauge("mem.gc.pause.p95").With(l...)
+	gcPauseP99 := st.Gauge("mem.gc.pause.p99").With(l...)
 	// general
 	memAlloc := st.Gauge("mem.alloc").With(l...)
 	memTotal := st.Gauge("mem.total").With(l...)
@@ -47,6 +138,7 @@ func (st *Statsd) RunSysStats(labels Labels) {
 	memLookups := st.Gauge("mem.lookups").With(l...)
 	memMalloc := st.Gauge("mem.malloc").With(l...)
 	memFrees := st.Gauge("mem.frees").With(l...)
+	memAllocsPerSec := st.Gauge("mem.allocs_per_sec").With(l...)
 	// heap
 	heapAlloc := st.Gauge("mem.heap.alloc").With(l...)
 	heapSys := st.Gauge("mem.heap.sys").With(l...)
@@ -63,16 +155,20 @@ func (st *Statsd) RunSysStats(labels Labels) {
 	mcacheSys := st.Gauge("mem.stack.mcache_sys").With(l...)
 	// other
 	memOther := st.Gauge("mem.othersys").With(l...)
+	// fd
+	fdOpen := st.Gauge("fd.open").With(l...)
 
 	go func() {
-		ticker := time.NewTicker(SysStatsTickerInterval)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
+		_, lastMem := pullRuntimeStats()
+		lastTime := time.Now()
 		for {
 			select {
-			case <-st.ctx.Done():
+			case <-ctx.Done():
 				return
-			case <-ticker.C:
+			case now := <-ticker.C:
 				cpu, mem := pullRuntimeStats()
 
 				// cpu
@@ -85,6 +181,11 @@ func (st *Statsd) RunSysStats(labels Labels) {
 				gcPauseTotal.Set(float64(mem.PauseTotalNs))
 				gcPause.Set(float64(mem.PauseNs[(mem.NumGC+255)%256]))
 				gcCount.Set(float64(mem.NumGC))
+				if pauses, ok := gcPausePercentiles(&mem, lastMem.NumGC, 0.5, 0.95, 0.99); ok {
+					gcPauseP50.Set(pauses[0])
+					gcPauseP95.Set(pauses[1])
+					gcPauseP99.Set(pauses[2])
+				}
 				// general
 				memAlloc.Set(float64(mem.Alloc))
 				memTotal.Set(float64(mem.TotalAlloc))
@@ -92,6 +193,9 @@ func (st *Statsd) RunSysStats(labels Labels) {
 				memLookups.Set(float64(mem.Lookups))
 				memMalloc.Set(float64(mem.Mallocs))
 				memFrees.Set(float64(mem.Frees))
+				if elapsed := now.Sub(lastTime).Seconds(); elapsed > 0 {
+					memAllocsPerSec.Set(float64(mem.Mallocs-lastMem.Mallocs) / elapsed)
+				}
 				// heap
 				heapAlloc.Set(float64(mem.HeapAlloc))
 				heapSys.Set(float64(mem.HeapSys))
@@ -108,6 +212,13 @@ func (st *Statsd) RunSysStats(labels Labels) {
 				mcacheSys.Set(float64(mem.MCacheSys))
 				// other
 				memOther.Set(float64(mem.OtherSys))
+				// fd
+				if fds, err := countOpenFDs(); err == nil {
+					fdOpen.Set(float64(fds))
+				}
+
+				lastMem = mem
+				lastTime = now
 			}
 		}
 	}()
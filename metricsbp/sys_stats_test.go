@@ -0,0 +1,76 @@
+package metricsbp_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+func TestRunSysStats(t *testing.T) {
+	defer func(interval time.Duration) {
+		metricsbp.SysStatsTickerInterval = interval
+	}(metricsbp.SysStatsTickerInterval)
+	metricsbp.SysStatsTickerInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st := metricsbp.NewStatsd(ctx, metricsbp.StatsdConfig{})
+	st.RunSysStats(nil)
+
+	runtime.GC()
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+	// Give the goroutine some time to exit.
+	time.Sleep(time.Millisecond * 10)
+
+	var buf bytes.Buffer
+	st.Statsd.WriteTo(&buf)
+	str := buf.String()
+	expected := []string{
+		"cpu.goroutines:",
+		"mem.gc.pause.p50:",
+		"mem.gc.pause.p95:",
+		"mem.gc.pause.p99:",
+		"mem.heap.inuse:",
+		"mem.allocs_per_sec:",
+	}
+	if runtime.GOOS == "linux" {
+		expected = append(expected, "fd.open:")
+	}
+	for _, name := range expected {
+		if !strings.Contains(str, name) {
+			t.Errorf("Expected %q in the reported metrics, got %q", name, str)
+		}
+	}
+}
+
+func TestRunSysStatsWithInterval(t *testing.T) {
+	st := metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	metricsbp.RunSysStats(ctx, st, time.Millisecond)
+
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+	// Give the goroutine some time to exit.
+	time.Sleep(time.Millisecond * 10)
+
+	var buf bytes.Buffer
+	st.Statsd.WriteTo(&buf)
+	if str := buf.String(); !strings.Contains(str, "cpu.goroutines:") {
+		t.Errorf("Expected %q in the reported metrics, got %q", "cpu.goroutines:", str)
+	}
+
+	// No more stats should be reported after ctx is canceled.
+	time.Sleep(time.Millisecond * 10)
+	buf.Reset()
+	st.Statsd.WriteTo(&buf)
+	if str := buf.String(); str != "" {
+		t.Errorf("Expected no metrics reported after cancel, got %q", str)
+	}
+}
@@ -0,0 +1,36 @@
+// Package prometheusbp provides go-kit metrics exposed to Prometheus,
+// as an alternative to reporting them to statsd.
+//
+// A Registry creates counters, gauges, and histograms the same way
+// metricsbp.Statsd does,
+// registers them with the official Prometheus client via go-kit's
+// metrics/prometheus package,
+// and serves all of them over HTTP with promhttp when they are scraped.
+// Usually it's not used directly,
+// but set as StatsdConfig.Prometheus so that the metrics created from
+// metricsbp.M go to the Registry instead,
+// without changing the instrumentation call sites:
+//
+//     metricsbp.M = metricsbp.NewStatsd(ctx, metricsbp.StatsdConfig{
+//       Prometheus: prometheusbp.NewRegistry(prometheusbp.Config{
+//         Namespace: "myservice",
+//       }),
+//     })
+//     adminServer.Handle("/metrics", metricsbp.M.Prometheus())
+//
+// Or with "prometheus: true" in the metrics section of the baseplate config,
+// in which case baseplate.New serves it at "/metrics" on the admin server.
+//
+// During the migration from statsd, set StatsdConfig.DualWrite
+// ("dualWrite: true" in the config) to keep sending the metrics to statsd as
+// well, so the Prometheus dashboards can be validated against the existing
+// statsd graphs before switching over.
+//
+// The metric names are converted to valid Prometheus names by replacing the
+// invalid characters (e.g. periods) with underscores,
+// and the label pairs passed into With become Prometheus labels.
+// Metrics conflicting with the registered ones, e.g. using the same name for
+// different types of metrics, are logged and dropped.
+// Timings are observed in milliseconds like statsd timings,
+// but exposed in seconds, with "_seconds" appended to their names.
+package prometheusbp
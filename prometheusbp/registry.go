@@ -0,0 +1,460 @@
+package prometheusbp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// DefaultBuckets are the default upper bounds of the histogram buckets,
+// same as the default buckets of the official Prometheus client.
+//
+// They are tailored to measure latencies in seconds.
+var DefaultBuckets = prometheus.DefBuckets
+
+// ContentType is the content type of the text exposition format served by
+// Registry.
+const ContentType = string(expfmt.FmtText)
+
+// Config is the arg struct for NewRegistry.
+type Config struct {
+	// Namespace is the prefix of all the metric names, usually the name of the
+	// service.
+	//
+	// Optional.
+	Namespace string
+
+	// Labels are the labels attached to all the metrics.
+	//
+	// Optional.
+	Labels map[string]string
+
+	// Buckets are the upper bounds of the buckets of the histograms created by
+	// Histogram.
+	//
+	// Optional, defaults to DefaultBuckets.
+	Buckets []float64
+
+	// TimingBuckets are the upper bounds of the buckets of the histograms
+	// created by Timing, in seconds.
+	//
+	// Optional, defaults to DefaultBuckets.
+	TimingBuckets []float64
+}
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// Registry holds the metrics to be exposed to Prometheus.
+//
+// It's backed by a prometheus.Registry from the official Prometheus client,
+// and implements http.Handler to serve the metrics with promhttp.
+//
+// The Prometheus client requires the label names of a metric to be declared
+// when it's registered,
+// so the metrics are registered the first time they are used with a new set of
+// label names.
+// Metrics conflicting with the registered ones,
+// e.g. using the same name (after being converted into a valid Prometheus
+// name) for different types of metrics or with different label names,
+// are logged and dropped.
+//
+// Please use NewRegistry to initialize it.
+type Registry struct {
+	namespace     string
+	labels        prometheus.Labels
+	buckets       []float64
+	timingBuckets []float64
+
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	// metrics are the go-kit metrics wrapping the registered collectors,
+	// keyed by metricKey.
+	// The values are nil for the dropped metrics.
+	metrics sync.Map
+	// lock is only held when registering new metrics.
+	lock sync.Mutex
+}
+
+var _ http.Handler = (*Registry)(nil)
+
+// NewRegistry creates a new Registry.
+func NewRegistry(cfg Config) *Registry {
+	r := &Registry{
+		namespace:     cfg.Namespace,
+		buckets:       cfg.Buckets,
+		timingBuckets: cfg.TimingBuckets,
+		registry:      prometheus.NewRegistry(),
+	}
+	if len(r.buckets) == 0 {
+		r.buckets = DefaultBuckets
+	}
+	if len(r.timingBuckets) == 0 {
+		r.timingBuckets = DefaultBuckets
+	}
+	if len(cfg.Labels) > 0 {
+		r.labels = make(prometheus.Labels, len(cfg.Labels))
+		for k, v := range cfg.Labels {
+			r.labels[sanitizeLabelName(k)] = v
+		}
+	}
+	r.handler = promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	return r
+}
+
+// Counter returns a counter to the name.
+func (r *Registry) Counter(name string) metrics.Counter {
+	return counter{
+		r:    r,
+		name: r.fqName(name),
+		help: name,
+	}
+}
+
+// Gauge returns a gauge to the name.
+func (r *Registry) Gauge(name string) metrics.Gauge {
+	return gauge{
+		r:    r,
+		name: r.fqName(name),
+		help: name,
+	}
+}
+
+// Histogram returns a histogram to the name with no specific unit,
+// using Config.Buckets.
+func (r *Registry) Histogram(name string) metrics.Histogram {
+	return histogram{
+		r:       r,
+		name:    r.fqName(name),
+		help:    name,
+		buckets: r.buckets,
+		scale:   1,
+	}
+}
+
+// Timing returns a histogram to the name observing milliseconds,
+// using Config.TimingBuckets.
+//
+// The observed values are exposed in seconds,
+// with "_seconds" appended to the name.
+func (r *Registry) Timing(name string) metrics.Histogram {
+	return histogram{
+		r:       r,
+		name:    r.fqName(name + "_seconds"),
+		help:    name,
+		buckets: r.timingBuckets,
+		scale:   0.001,
+	}
+}
+
+// fqName returns the Prometheus name of the metric name.
+func (r *Registry) fqName(name string) string {
+	name = sanitizeName(name)
+	if r.namespace != "" {
+		name = sanitizeName(r.namespace) + "_" + name
+	}
+	return name
+}
+
+type metricKey struct {
+	typ        metricType
+	name       string
+	labelNames string
+}
+
+// metric returns the go-kit metric of the type to the name with the label
+// names,
+// registering a new collector created by newCollector when it's not registered
+// yet.
+//
+// It returns nil when the metric conflicts with the registered ones.
+func (r *Registry) metric(
+	typ metricType,
+	name string,
+	labelNames []string,
+	newCollector func() prometheus.Collector,
+) interface{} {
+	key := metricKey{
+		typ:        typ,
+		name:       name,
+		labelNames: strings.Join(labelNames, ","),
+	}
+	if m, ok := r.metrics.Load(key); ok {
+		return m
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if m, ok := r.metrics.Load(key); ok {
+		return m
+	}
+	m := r.register(typ, name, labelNames, newCollector)
+	r.metrics.Store(key, m)
+	return m
+}
+
+func (r *Registry) register(
+	typ metricType,
+	name string,
+	labelNames []string,
+	newCollector func() prometheus.Collector,
+) interface{} {
+	var c prometheus.Collector
+	err := checkLabelNames(typ, labelNames, r.labels)
+	if err == nil {
+		c = newCollector()
+		err = r.registry.Register(c)
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		// Registered by the same type of metric with a different key,
+		// e.g. a histogram and a timing, or different names sanitized into the
+		// same one.
+		c = are.ExistingCollector
+		err = nil
+	}
+	if err == nil {
+		switch vec := c.(type) {
+		case *prometheus.CounterVec:
+			if typ == typeCounter {
+				return kitprometheus.NewCounter(vec)
+			}
+		case *prometheus.GaugeVec:
+			if typ == typeGauge {
+				return kitprometheus.NewGauge(vec)
+			}
+		case *prometheus.HistogramVec:
+			if typ == typeHistogram {
+				return kitprometheus.NewHistogram(vec)
+			}
+		}
+		err = errTypeConflict
+	}
+	log.Named("prometheusbp").Errorw(
+		"prometheusbp: Dropping metric conflicting with the registered ones",
+		"name", name,
+		"type", typ,
+		"labels", labelNames,
+		"err", err,
+	)
+	return nil
+}
+
+var (
+	errTypeConflict = errors.New("prometheusbp: the name is already registered by another type of metric")
+	errLabelNames   = errors.New("prometheusbp: the label names are reserved or used by Config.Labels")
+)
+
+// checkLabelNames checks the label names that would make the Prometheus
+// client panic instead of returning errors.
+func checkLabelNames(typ metricType, labelNames []string, constLabels prometheus.Labels) error {
+	for _, name := range labelNames {
+		if _, ok := constLabels[name]; ok {
+			return errLabelNames
+		}
+		if typ == typeHistogram && name == "le" {
+			return errLabelNames
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+//
+// It serves all the metrics with promhttp.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+// WriteTo writes all the metrics in the text exposition format to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	families, err := r.registry.Gather()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, family := range families {
+		n, err := expfmt.MetricFamilyToText(w, family)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// sanitizeName replaces the characters not allowed in Prometheus metric names
+// with underscores.
+func sanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// sanitizeLabelName replaces the characters not allowed in Prometheus label
+// names with underscores.
+func sanitizeLabelName(name string) string {
+	return sanitize(name, false)
+}
+
+func sanitize(name string, allowColon bool) string {
+	var sb strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		case c == ':' && allowColon:
+		default:
+			c = '_'
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// withLabelValues returns the label values with the additional ones appended,
+// padded with "unknown" like go-kit's lv.LabelValues.
+func withLabelValues(labelValues []string, additional []string) []string {
+	if len(additional)%2 != 0 {
+		additional = append(additional, "unknown")
+	}
+	lvs := make([]string, 0, len(labelValues)+len(additional))
+	lvs = append(lvs, labelValues...)
+	return append(lvs, additional...)
+}
+
+// splitLabelValues converts the label values into the sorted Prometheus label
+// names and the label values with the sanitized names to be passed into the
+// go-kit metrics.
+//
+// When the same name is used more than once, the last value wins.
+func splitLabelValues(labelValues []string) (names []string, lvs []string) {
+	values := make(map[string]string, len(labelValues)/2)
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		name := sanitizeLabelName(labelValues[i])
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = labelValues[i+1]
+	}
+	sort.Strings(names)
+	lvs = make([]string, 0, len(names)*2)
+	for _, name := range names {
+		lvs = append(lvs, name, values[name])
+	}
+	return names, lvs
+}
+
+type counter struct {
+	r           *Registry
+	name        string
+	help        string
+	labelValues []string
+}
+
+func (c counter) With(labelValues ...string) metrics.Counter {
+	c.labelValues = withLabelValues(c.labelValues, labelValues)
+	return c
+}
+
+func (c counter) Add(delta float64) {
+	names, lvs := splitLabelValues(c.labelValues)
+	m, _ := c.r.metric(typeCounter, c.name, names, func() prometheus.Collector {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        c.name,
+			Help:        c.help,
+			ConstLabels: c.r.labels,
+		}, names)
+	}).(*kitprometheus.Counter)
+	if m != nil {
+		m.With(lvs...).Add(delta)
+	}
+}
+
+type gauge struct {
+	r           *Registry
+	name        string
+	help        string
+	labelValues []string
+}
+
+func (g gauge) With(labelValues ...string) metrics.Gauge {
+	g.labelValues = withLabelValues(g.labelValues, labelValues)
+	return g
+}
+
+func (g gauge) metric() (*kitprometheus.Gauge, []string) {
+	names, lvs := splitLabelValues(g.labelValues)
+	m, _ := g.r.metric(typeGauge, g.name, names, func() prometheus.Collector {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        g.name,
+			Help:        g.help,
+			ConstLabels: g.r.labels,
+		}, names)
+	}).(*kitprometheus.Gauge)
+	return m, lvs
+}
+
+func (g gauge) Set(value float64) {
+	if m, lvs := g.metric(); m != nil {
+		m.With(lvs...).Set(value)
+	}
+}
+
+func (g gauge) Add(delta float64) {
+	if m, lvs := g.metric(); m != nil {
+		m.With(lvs...).Add(delta)
+	}
+}
+
+type histogram struct {
+	r           *Registry
+	name        string
+	help        string
+	buckets     []float64
+	labelValues []string
+	scale       float64
+}
+
+func (h histogram) With(labelValues ...string) metrics.Histogram {
+	h.labelValues = withLabelValues(h.labelValues, labelValues)
+	return h
+}
+
+func (h histogram) Observe(value float64) {
+	names, lvs := splitLabelValues(h.labelValues)
+	m, _ := h.r.metric(typeHistogram, h.name, names, func() prometheus.Collector {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        h.name,
+			Help:        h.help,
+			ConstLabels: h.r.labels,
+			Buckets:     h.buckets,
+		}, names)
+	}).(*kitprometheus.Histogram)
+	if m != nil {
+		m.With(lvs...).Observe(value * h.scale)
+	}
+}
+
+var (
+	_ metrics.Counter   = counter{}
+	_ metrics.Gauge     = gauge{}
+	_ metrics.Histogram = histogram{}
+)
@@ -0,0 +1,115 @@
+package prometheusbp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/prometheusbp"
+)
+
+func TestRegistry(t *testing.T) {
+	r := prometheusbp.NewRegistry(prometheusbp.Config{
+		Namespace: "my-service",
+		Labels:    map[string]string{"region": "us-east-1"},
+		Buckets:   []float64{1, 10},
+	})
+
+	counter := r.Counter("thrift.foo.requests")
+	counter.With("success", "true").Add(2)
+	counter.With("success", "true").Add(1)
+	counter.With("success", "false").Add(1)
+
+	gauge := r.Gauge("pool.active")
+	gauge.Set(5)
+	gauge.Add(-2)
+
+	histogram := r.Histogram("payload.size").With("path", `a"b`)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	histogram.Observe(50)
+
+	r.Timing("latency").With("odd").Observe(20)
+
+	expected := strings.Join([]string{
+		`# HELP my_service_latency_seconds latency`,
+		`# TYPE my_service_latency_seconds histogram`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="0.005"} 0`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="0.01"} 0`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="0.025"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="0.05"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="0.1"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="0.25"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="0.5"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="1"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="2.5"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="5"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="10"} 1`,
+		`my_service_latency_seconds_bucket{odd="unknown",region="us-east-1",le="+Inf"} 1`,
+		`my_service_latency_seconds_sum{odd="unknown",region="us-east-1"} 0.02`,
+		`my_service_latency_seconds_count{odd="unknown",region="us-east-1"} 1`,
+		`# HELP my_service_payload_size payload.size`,
+		`# TYPE my_service_payload_size histogram`,
+		`my_service_payload_size_bucket{path="a\"b",region="us-east-1",le="1"} 1`,
+		`my_service_payload_size_bucket{path="a\"b",region="us-east-1",le="10"} 2`,
+		`my_service_payload_size_bucket{path="a\"b",region="us-east-1",le="+Inf"} 3`,
+		`my_service_payload_size_sum{path="a\"b",region="us-east-1"} 55.5`,
+		`my_service_payload_size_count{path="a\"b",region="us-east-1"} 3`,
+		`# HELP my_service_pool_active pool.active`,
+		`# TYPE my_service_pool_active gauge`,
+		`my_service_pool_active{region="us-east-1"} 3`,
+		`# HELP my_service_thrift_foo_requests thrift.foo.requests`,
+		`# TYPE my_service_thrift_foo_requests counter`,
+		`my_service_thrift_foo_requests{region="us-east-1",success="false"} 1`,
+		`my_service_thrift_foo_requests{region="us-east-1",success="true"} 3`,
+		``,
+	}, "\n")
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if actual := sb.String(); actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	t.Run("http", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if ct := w.Header().Get("Content-Type"); ct != prometheusbp.ContentType {
+			t.Errorf("Expected content type %q, got %q", prometheusbp.ContentType, ct)
+		}
+		if body := w.Body.String(); body != expected {
+			t.Errorf("Expected body:\n%s\nGot:\n%s", expected, body)
+		}
+	})
+}
+
+func TestRegistryConflicts(t *testing.T) {
+	r := prometheusbp.NewRegistry(prometheusbp.Config{})
+	r.Counter("foo").Add(1)
+	// Conflicts are logged and dropped instead of panicking.
+	r.Gauge("foo").Set(1)
+	r.Gauge("foo_bar").Set(2)
+	r.Counter("foo.bar").Add(1)
+	r.Counter("foo").With("label", "value").Add(1)
+	r.Histogram("size").With("le", "1").Observe(1)
+
+	expected := strings.Join([]string{
+		`# HELP foo foo`,
+		`# TYPE foo counter`,
+		`foo 1`,
+		`# HELP foo_bar foo_bar`,
+		`# TYPE foo_bar gauge`,
+		`foo_bar 2`,
+		``,
+	}, "\n")
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if actual := sb.String(); actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+}
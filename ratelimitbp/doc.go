@@ -0,0 +1,14 @@
+// Package ratelimitbp provides a local, in-memory token bucket rate limiter.
+//
+// The limiter keeps a separate bucket for every key (e.g. per endpoint or per
+// client), and the number of buckets is bounded by evicting the least recently
+// used ones.
+//
+// thriftbp.RateLimit and httpbp.RateLimit integrate any RateLimiter with
+// thrift servers and http servers respectively.
+//
+// IdentityLimiter rate limits per authenticated user or OAuth client from the
+// edge request context instead, with a separate limit for the anonymous
+// requests,
+// see thriftbp.RateLimitByIdentity and httpbp.RateLimitByIdentity.
+package ratelimitbp
@@ -0,0 +1,105 @@
+package ratelimitbp
+
+import (
+	"context"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+)
+
+// Prefixes of the identities returned by Identity.
+const (
+	IdentityPrefixUser        = "user:"
+	IdentityPrefixOAuthClient = "oauth-client:"
+)
+
+// IdentityConfig is the configuration used by NewIdentityLimiter.
+//
+// Can be deserialized from YAML.
+type IdentityConfig struct {
+	// Authenticated is the config of the limiter for the requests with a
+	// logged in user or an OAuth client,
+	// the limit applies to every user and OAuth client separately.
+	Authenticated Config `yaml:"authenticated"`
+
+	// Anonymous is the config of the limiter for the anonymous requests,
+	// the limit applies to all the anonymous requests together.
+	Anonymous Config `yaml:"anonymous"`
+}
+
+// IdentityLimiter is a RateLimiter that rate limits the requests per
+// authenticated identity from the edge request context,
+// instead of per client IP,
+// with a separate limit for the anonymous requests.
+//
+// Allow uses Authenticated with the identity returned by Identity prefixed to
+// the key (e.g. "user:t2_foo:endpoint") when there's one,
+// and uses Anonymous with the key as-is otherwise.
+// So when it's used with thriftbp.RateLimit or httpbp.RateLimit with nil
+// keyFunc,
+// every endpoint is rate limited separately for every identity,
+// and all the anonymous requests to the same endpoint share the same limit.
+//
+// The edge request context must be attached to the context object before
+// Allow is called, which is the case for the middlewares added after the
+// default middlewares of thriftbp and httpbp.
+//
+// Authenticated and Anonymous can be any RateLimiter,
+// e.g. the Redis backed redisbp.RateLimiter to share the limits across the
+// instances of the service.
+// When either of them is nil,
+// the corresponding requests are not rate limited.
+type IdentityLimiter struct {
+	Authenticated RateLimiter
+	Anonymous     RateLimiter
+}
+
+var _ RateLimiter = IdentityLimiter{}
+
+// NewIdentityLimiter creates a new IdentityLimiter with Limiters.
+func NewIdentityLimiter(cfg IdentityConfig) IdentityLimiter {
+	return IdentityLimiter{
+		Authenticated: NewLimiter(cfg.Authenticated),
+		Anonymous:     NewLimiter(cfg.Anonymous),
+	}
+}
+
+// Allow implements RateLimiter.
+func (l IdentityLimiter) Allow(ctx context.Context, key string) bool {
+	if identity, ok := Identity(ctx); ok {
+		if l.Authenticated == nil {
+			return true
+		}
+		if key != "" {
+			identity += ":" + key
+		}
+		return l.Authenticated.Allow(ctx, identity)
+	}
+	if l.Anonymous == nil {
+		return true
+	}
+	return l.Anonymous.Allow(ctx, key)
+}
+
+// Identity returns the authenticated identity of the edge request context
+// attached to ctx.
+//
+// It's the user id prefixed by IdentityPrefixUser for the logged in users,
+// or the OAuth client id prefixed by IdentityPrefixOAuthClient when there's
+// no logged in user but an OAuth client.
+// ok is false when there's no edge request context,
+// or the request is anonymous.
+func Identity(ctx context.Context) (identity string, ok bool) {
+	ec, ok := edgecontext.GetEdgeContext(ctx)
+	if !ok || ec == nil {
+		return "", false
+	}
+	if id, ok := ec.User().ID(); ok {
+		return IdentityPrefixUser + id, true
+	}
+	if client, ok := ec.OAuthClient(); ok {
+		if id := client.ID(); id != "" {
+			return IdentityPrefixOAuthClient + id, true
+		}
+	}
+	return "", false
+}
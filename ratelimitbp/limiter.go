@@ -0,0 +1,160 @@
+package ratelimitbp
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// DefaultMaxKeys is the default MaxKeys used when it's not set in Config.
+const DefaultMaxKeys = 10000
+
+// ErrRateLimited is the error returned by the middlewares when a request is
+// rejected by the Limiter.
+var ErrRateLimited = errors.New("ratelimitbp: rate limit exceeded")
+
+// RateLimiter is the interface used by the rate limit middlewares,
+// e.g. thriftbp.RateLimit and httpbp.RateLimit.
+//
+// Allow should return true when the request with the key is allowed,
+// and false when it should be rejected.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) bool
+}
+
+var _ RateLimiter = (*Limiter)(nil)
+
+// Config is the configuration used by NewLimiter.
+//
+// Can be deserialized from YAML.
+type Config struct {
+	// Name of the limiter, used in the metrics.
+	Name string `yaml:"name"`
+
+	// RatePerSecond is the rate the tokens are refilled for every key.
+	//
+	// When RatePerSecond <= 0, the limiter allows everything.
+	RatePerSecond float64 `yaml:"ratePerSecond"`
+
+	// Burst is the max number of tokens a bucket can hold,
+	// i.e. the max number of requests allowed at once for a key.
+	//
+	// When Burst <= 0, RatePerSecond rounded up (min 1) will be used.
+	Burst int `yaml:"burst"`
+
+	// MaxKeys is the max number of buckets kept in memory.
+	//
+	// When there are more keys than MaxKeys,
+	// the least recently used buckets are evicted,
+	// and those keys start with full buckets the next time they are seen.
+	//
+	// When MaxKeys <= 0, DefaultMaxKeys will be used.
+	MaxKeys int `yaml:"maxKeys"`
+}
+
+// Limiter is a concurrency-safe token bucket rate limiter with per key
+// buckets.
+//
+// It reports the number of rejected requests to a counter named
+// "<name>.ratelimit.throttled" on metricsbp.M.
+type Limiter struct {
+	rate    float64
+	burst   float64
+	maxKeys int
+
+	throttled metrics.Counter
+
+	lock    sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a new Limiter.
+func NewLimiter(cfg Config) *Limiter {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(cfg.RatePerSecond)))
+	}
+	maxKeys := cfg.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	return &Limiter{
+		rate:      cfg.RatePerSecond,
+		burst:     float64(burst),
+		maxKeys:   maxKeys,
+		throttled: metricsbp.M.Counter(cfg.Name + ".ratelimit.throttled"),
+		buckets:   make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// Allow takes a token from the bucket of key and returns true if there was
+// one available.
+//
+// When it returns false the request should be rejected.
+func (l *Limiter) Allow(_ context.Context, key string) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	if l.allow(key, time.Now()) {
+		return true
+	}
+	l.throttled.Add(1)
+	return false
+}
+
+func (l *Limiter) allow(key string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var b *bucket
+	if elem, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(elem)
+		b = elem.Value.(*bucket)
+		elapsed := now.Sub(b.last).Seconds()
+		if elapsed > 0 {
+			b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		}
+		b.last = now
+	} else {
+		b = &bucket{
+			key:    key,
+			tokens: l.burst,
+			last:   now,
+		}
+		l.buckets[key] = l.lru.PushFront(b)
+		for l.lru.Len() > l.maxKeys {
+			oldest := l.lru.Back()
+			l.lru.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*bucket).key)
+		}
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// NumKeys returns the number of buckets currently kept in memory.
+func (l *Limiter) NumKeys() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.lru.Len()
+}
@@ -0,0 +1,110 @@
+package ratelimitbp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	metrics := metricstest.Replace(t)
+
+	limiter := ratelimitbp.NewLimiter(ratelimitbp.Config{
+		Name:          "test",
+		RatePerSecond: 0.001,
+		Burst:         2,
+	})
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow(ctx, "a") {
+			t.Errorf("Expected request #%d to be allowed", i)
+		}
+	}
+	if limiter.Allow(ctx, "a") {
+		t.Error("Expected request over burst to be rejected")
+	}
+	if !limiter.Allow(ctx, "b") {
+		t.Error("Expected request for another key to be allowed")
+	}
+	metrics.AssertCounterEquals(t, "test.ratelimit.throttled", 1)
+}
+
+func TestLimiterRefill(t *testing.T) {
+	ctx := context.Background()
+	limiter := ratelimitbp.NewLimiter(ratelimitbp.Config{
+		RatePerSecond: 1000,
+		Burst:         1,
+	})
+	if !limiter.Allow(ctx, "a") {
+		t.Fatal("Expected first request to be allowed")
+	}
+	time.Sleep(time.Millisecond * 5)
+	if !limiter.Allow(ctx, "a") {
+		t.Error("Expected request to be allowed after refill")
+	}
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	ctx := context.Background()
+	limiter := ratelimitbp.NewLimiter(ratelimitbp.Config{
+		RatePerSecond: 0.001,
+		Burst:         1,
+		MaxKeys:       2,
+	})
+	for _, key := range []string{"a", "b", "c"} {
+		if !limiter.Allow(ctx, key) {
+			t.Errorf("Expected first request for %q to be allowed", key)
+		}
+	}
+	if n := limiter.NumKeys(); n != 2 {
+		t.Errorf("Expected 2 keys, got %d", n)
+	}
+	// "a" was evicted so it starts with a full bucket again.
+	if !limiter.Allow(ctx, "a") {
+		t.Error("Expected request for evicted key to be allowed")
+	}
+	// "c" is still tracked.
+	if limiter.Allow(ctx, "c") {
+		t.Error("Expected request for tracked key to be rejected")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	ctx := context.Background()
+	limiter := ratelimitbp.NewLimiter(ratelimitbp.Config{})
+	for i := 0; i < 100; i++ {
+		if !limiter.Allow(ctx, "a") {
+			t.Fatal("Expected limiter with zero rate to allow everything")
+		}
+	}
+}
+
+func TestIdentityLimiterAnonymous(t *testing.T) {
+	ctx := context.Background()
+	if identity, ok := ratelimitbp.Identity(ctx); ok {
+		t.Errorf("Expected no identity without edge request context, got %q", identity)
+	}
+
+	limiter := ratelimitbp.NewIdentityLimiter(ratelimitbp.IdentityConfig{
+		Anonymous: ratelimitbp.Config{
+			RatePerSecond: 0.001,
+			Burst:         1,
+		},
+	})
+	if !limiter.Allow(ctx, "a") {
+		t.Error("Expected the first anonymous request to be allowed")
+	}
+	if limiter.Allow(ctx, "a") {
+		t.Error("Expected the second anonymous request to be rejected")
+	}
+
+	var nilLimiters ratelimitbp.IdentityLimiter
+	for i := 0; i < 2; i++ {
+		if !nilLimiters.Allow(ctx, "a") {
+			t.Errorf("Expected request #%d to be allowed with nil limiters", i)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package redisbp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/breakerbp"
+)
+
+type breakerDoneKey struct{}
+
+// BreakerHook is a redis.Hook that guards the Redis commands and pipelines
+// with a circuit breaker.
+//
+// When the breaker rejects a command, the command fails with either
+// breakerbp.ErrOpen or breakerbp.ErrTooManyRequests without reaching Redis.
+// redis.Nil errors are not counted as failures.
+//
+// BreakerHook should be added to the client before creating the
+// MonitoredCmdableFactory, so that rejected commands don't create client spans.
+type BreakerHook struct {
+	Breaker *breakerbp.FailureRatioBreaker
+}
+
+var _ redis.Hook = BreakerHook{}
+
+// BeforeProcess checks whether the breaker allows the command.
+func (h BreakerHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.allow(ctx)
+}
+
+// AfterProcess records the result of the command to the breaker.
+func (h BreakerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.done(ctx, cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline checks whether the breaker allows the pipeline.
+//
+// The whole pipeline counts as a single call to the breaker.
+func (h BreakerHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.allow(ctx)
+}
+
+// AfterProcessPipeline records the result of the pipeline to the breaker.
+//
+// The pipeline is counted as a failure if any of the commands failed.
+func (h BreakerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if e := cmd.Err(); isRedisFailure(e) {
+			err = e
+			break
+		}
+	}
+	h.done(ctx, err)
+	return nil
+}
+
+func (h BreakerHook) allow(ctx context.Context) (context.Context, error) {
+	done, err := h.Breaker.Allow()
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, breakerDoneKey{}, done), nil
+}
+
+func (h BreakerHook) done(ctx context.Context, err error) {
+	if done, ok := ctx.Value(breakerDoneKey{}).(func(error)); ok {
+		if !isRedisFailure(err) {
+			err = nil
+		}
+		done(err)
+	}
+}
+
+func isRedisFailure(err error) bool {
+	return err != nil && !errors.Is(err, redis.Nil)
+}
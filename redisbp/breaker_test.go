@@ -0,0 +1,55 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/redisbp"
+)
+
+func TestBreakerHook(t *testing.T) {
+	newHook := func() redisbp.BreakerHook {
+		return redisbp.BreakerHook{
+			Breaker: breakerbp.NewFailureRatioBreaker(breakerbp.Config{
+				Name:              "redis",
+				MinRequestsToTrip: 2,
+				FailureThreshold:  1,
+			}),
+		}
+	}
+
+	process := func(hook redisbp.BreakerHook, err error) error {
+		cmd := redis.NewStringResult("", err)
+		ctx, beforeErr := hook.BeforeProcess(context.Background(), cmd)
+		if beforeErr != nil {
+			return beforeErr
+		}
+		return hook.AfterProcess(ctx, cmd)
+	}
+
+	t.Run("nil-is-not-failure", func(t *testing.T) {
+		hook := newHook()
+		for i := 0; i < 3; i++ {
+			if err := process(hook, redis.Nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("trip", func(t *testing.T) {
+		hook := newHook()
+		cmdErr := errors.New("connection refused")
+		for i := 0; i < 2; i++ {
+			if err := process(hook, cmdErr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := process(hook, nil); !errors.Is(err, breakerbp.ErrOpen) {
+			t.Errorf("Expected error %v, got %v", breakerbp.ErrOpen, err)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package redisbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/requestevent"
+)
+
+// DefaultCacheTTLJitter is the TTL jitter used by CachedLoader when TTLJitter
+// in CachedLoaderConfig is not set.
+const DefaultCacheTTLJitter = 0.1
+
+// CacheClient is the subset of redis.Cmdable used by CachedLoader.
+//
+// *redis.Client, *redis.ClusterClient, and *redis.Ring all implement it.
+type CacheClient interface {
+	Get(key string) *redis.StringCmd
+	Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+}
+
+var (
+	_ CacheClient = (*redis.Client)(nil)
+	_ CacheClient = (*redis.ClusterClient)(nil)
+	_ CacheClient = (*redis.Ring)(nil)
+)
+
+// LoadFunc loads the value to be cached when it's not in the cache.
+type LoadFunc func(ctx context.Context) (string, error)
+
+// CachedLoaderConfig is the configuration used by NewCachedLoader.
+type CachedLoaderConfig struct {
+	// Name of the cache, used as the prefix of the metrics.
+	Name string
+
+	// TTLJitter is the fraction of the TTL to be randomized when writing the
+	// loaded values back into redis,
+	// to avoid a lot of keys expiring at the same time.
+	//
+	// For example, when TTLJitter is 0.1 and the ttl is 100s,
+	// the actual ttl will be randomized in the range of [90s, 110s].
+	//
+	// Optional, defaults to DefaultCacheTTLJitter.
+	// Set it to a negative value to disable jitter.
+	TTLJitter float64
+}
+
+// CachedLoader implements the cache-aside pattern with redis.
+//
+// It reports the following counters with metricsbp.M:
+//
+// - "${name}.cache.hit": the number of values found in redis.
+//
+// - "${name}.cache.miss": the number of values not found in redis.
+//
+// - "${name}.cache.load-error": the number of errors returned by LoadFunc.
+//
+// When there's a requestevent.Event attached to the context object passed into
+// GetOrLoad, the hits and misses are also added to the "cache.${name}.hit" and
+// "cache.${name}.miss" fields of the event,
+// so the hit ratio of every request can be calculated from them.
+//
+// Please use NewCachedLoader to create a CachedLoader.
+type CachedLoader struct {
+	client CacheClient
+	name   string
+	jitter float64
+
+	hits       metrics.Counter
+	misses     metrics.Counter
+	loadErrors metrics.Counter
+
+	group loadGroup
+}
+
+// NewCachedLoader creates a new CachedLoader.
+func NewCachedLoader(client CacheClient, cfg CachedLoaderConfig) *CachedLoader {
+	jitter := cfg.TTLJitter
+	if jitter == 0 {
+		jitter = DefaultCacheTTLJitter
+	}
+	return &CachedLoader{
+		client:     client,
+		name:       cfg.Name,
+		jitter:     math.Min(jitter, 1),
+		hits:       metricsbp.M.Counter(cfg.Name + ".cache.hit"),
+		misses:     metricsbp.M.Counter(cfg.Name + ".cache.miss"),
+		loadErrors: metricsbp.M.Counter(cfg.Name + ".cache.load-error"),
+	}
+}
+
+// GetOrLoad returns the value of key from redis.
+//
+// If the key is not in redis, it calls load to load the value,
+// then writes it back into redis with the jittered ttl.
+// Concurrent misses of the same key in the same CachedLoader are deduplicated,
+// only one of them calls load and the rest share its result.
+// The callers waiting for the shared result return ctx.Err() when their ctx is
+// done first,
+// and they don't share the error when load failed because the context of the
+// caller calling it is done, one of them calls load again instead.
+//
+// Panics in load are recovered, logged, and returned as errors.
+//
+// Errors from redis are logged and treated as misses,
+// and errors from load are returned as-is.
+func (c *CachedLoader) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load LoadFunc) (string, error) {
+	value, err := c.client.Get(key).Result()
+	if err == nil {
+		c.hits.Add(1)
+		requestevent.Add(ctx, "cache."+c.name+".hit", 1)
+		return value, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		log.Named("redisbp").Errorw(
+			"redisbp: CachedLoader failed to get from redis",
+			"key", key,
+			"err", err,
+		)
+	}
+	c.misses.Add(1)
+	requestevent.Add(ctx, "cache."+c.name+".miss", 1)
+
+	return c.group.do(ctx, key, func(ctx context.Context) (string, error) {
+		value, err := c.callLoad(ctx, key, load)
+		if err != nil {
+			c.loadErrors.Add(1)
+			return "", err
+		}
+		if err := c.client.Set(key, value, c.jitterTTL(ttl)).Err(); err != nil {
+			log.Named("redisbp").Errorw(
+				"redisbp: CachedLoader failed to write back to redis",
+				"key", key,
+				"err", err,
+			)
+		}
+		return value, nil
+	})
+}
+
+// callLoad calls load,
+// and converts the panics in it into errors.
+func (c *CachedLoader) callLoad(ctx context.Context, key string, load LoadFunc) (value string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Named("redisbp").Errorw(
+				"redisbp: Recovered from panic in LoadFunc",
+				"key", key,
+				"panic", r,
+				"stack", string(debug.Stack()),
+			)
+			err = fmt.Errorf("redisbp: recovered from panic in LoadFunc: %v", r)
+		}
+	}()
+	return load(ctx)
+}
+
+func (c *CachedLoader) jitterTTL(ttl time.Duration) time.Duration {
+	if c.jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	delta := float64(ttl) * c.jitter
+	return ttl + time.Duration(delta*(randbp.R.Float64()*2-1))
+}
+
+// errLoadGroupPanic is the error returned to the callers waiting for a call
+// panicked in loadGroup.
+var errLoadGroupPanic = errors.New("redisbp: the shared load call panicked")
+
+// loadGroup deduplicates concurrent calls with the same key.
+type loadGroup struct {
+	lock  sync.Mutex
+	calls map[string]*loadCall
+}
+
+type loadCall struct {
+	done  chan struct{}
+	value string
+	err   error
+
+	// canceled is true when the call failed because the context of the caller
+	// running it is done.
+	canceled bool
+}
+
+// do calls fn with ctx, or waits for the result of the in-flight call with the
+// same key.
+//
+// When ctx is done before the in-flight call finishes,
+// it returns ctx.Err() without waiting any longer.
+// When the in-flight call failed because the context of its caller is done,
+// do calls fn again instead of returning that error.
+//
+// If fn panics, the panic is propagated to the caller calling fn,
+// and the others get errLoadGroupPanic.
+func (g *loadGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (string, error)) (string, error) {
+	for {
+		g.lock.Lock()
+		if g.calls == nil {
+			g.calls = make(map[string]*loadCall)
+		}
+		call, ok := g.calls[key]
+		if !ok {
+			call = &loadCall{done: make(chan struct{})}
+			g.calls[key] = call
+			g.lock.Unlock()
+
+			g.call(ctx, key, call, fn)
+			return call.value, call.err
+		}
+		g.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-call.done:
+		}
+		if !call.canceled {
+			return call.value, call.err
+		}
+	}
+}
+
+func (g *loadGroup) call(ctx context.Context, key string, call *loadCall, fn func(ctx context.Context) (string, error)) {
+	// Make sure the waiters are released with an error even if fn panics.
+	call.err = errLoadGroupPanic
+	defer func() {
+		call.canceled = call.err != nil && ctx.Err() != nil
+
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn(ctx)
+}
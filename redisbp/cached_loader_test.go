@@ -0,0 +1,263 @@
+package redisbp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/redisbp"
+	"github.com/reddit/baseplate.go/requestevent"
+)
+
+type fakeCacheClient struct {
+	lock sync.Mutex
+	data map[string]string
+	ttls map[string]time.Duration
+}
+
+func newFakeCacheClient() *fakeCacheClient {
+	return &fakeCacheClient{
+		data: make(map[string]string),
+		ttls: make(map[string]time.Duration),
+	}
+}
+
+func (c *fakeCacheClient) Get(key string) *redis.StringCmd {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if value, ok := c.data[key]; ok {
+		return redis.NewStringResult(value, nil)
+	}
+	return redis.NewStringResult("", redis.Nil)
+}
+
+func (c *fakeCacheClient) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.data[key] = value.(string)
+	c.ttls[key] = expiration
+	return redis.NewStatusResult("OK", nil)
+}
+
+func TestCachedLoader(t *testing.T) {
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	const ttl = time.Second * 100
+	client := newFakeCacheClient()
+	loader := redisbp.NewCachedLoader(client, redisbp.CachedLoaderConfig{
+		Name:      "cache",
+		TTLJitter: 0.1,
+	})
+
+	var loads int64
+	release := make(chan struct{})
+	load := func(context.Context) (string, error) {
+		atomic.AddInt64(&loads, 1)
+		<-release
+		return "value", nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	values := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i] = loader.GetOrLoad(context.Background(), "key", ttl, load)
+		}(i)
+	}
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Errorf("Expected load to be called once, got %d", loads)
+	}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil || values[i] != "value" {
+			t.Errorf("#%d: Expected (%q, nil), got (%q, %v)", i, "value", values[i], errs[i])
+		}
+	}
+	if actual := client.ttls["key"]; actual < ttl*9/10 || actual > ttl*11/10 {
+		t.Errorf("Expected jittered ttl around %v, got %v", ttl, actual)
+	}
+
+	value, err := loader.GetOrLoad(context.Background(), "key", ttl, func(context.Context) (string, error) {
+		t.Error("Expected load not called on cache hit")
+		return "", nil
+	})
+	if err != nil || value != "value" {
+		t.Errorf("Expected (%q, nil), got (%q, %v)", "value", value, err)
+	}
+
+	errLoad := errors.New("load error")
+	_, err = loader.GetOrLoad(context.Background(), "other", ttl, func(context.Context) (string, error) {
+		return "", errLoad
+	})
+	if !errors.Is(err, errLoad) {
+		t.Errorf("Expected error %v, got %v", errLoad, err)
+	}
+	if _, ok := client.data["other"]; ok {
+		t.Error("Expected failed load not written back")
+	}
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	str := buf.String()
+	for _, expected := range []string{
+		"cache.cache.hit:",
+		"cache.cache.miss:",
+		"cache.cache.load-error:1.000000|c",
+	} {
+		if !strings.Contains(str, expected) {
+			t.Errorf("Expected %q in metrics, got %q", expected, str)
+		}
+	}
+}
+
+func TestCachedLoaderRequestEvent(t *testing.T) {
+	client := newFakeCacheClient()
+	loader := redisbp.NewCachedLoader(client, redisbp.CachedLoaderConfig{
+		Name: "cache",
+	})
+	load := func(context.Context) (string, error) {
+		return "value", nil
+	}
+
+	event := requestevent.New()
+	ctx := requestevent.NewContext(context.Background(), event)
+	for i := 0; i < 3; i++ {
+		if _, err := loader.GetOrLoad(ctx, "key", time.Second, load); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nums := event.Numbers()
+	if nums["cache.cache.hit"] != 2 || nums["cache.cache.miss"] != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %v", nums)
+	}
+}
+
+func TestCachedLoaderPanic(t *testing.T) {
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	client := newFakeCacheClient()
+	loader := redisbp.NewCachedLoader(client, redisbp.CachedLoaderConfig{
+		Name: "cache",
+	})
+
+	release := make(chan struct{})
+	load := func(context.Context) (string, error) {
+		<-release
+		panic("load panic")
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	values := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i] = loader.GetOrLoad(context.Background(), "key", time.Second, load)
+		}(i)
+	}
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] == nil || values[i] != "" {
+			t.Errorf("#%d: Expected (\"\", error), got (%q, %v)", i, values[i], errs[i])
+		}
+	}
+	if _, ok := client.data["key"]; ok {
+		t.Error("Expected panicked load not written back")
+	}
+
+	value, err := loader.GetOrLoad(context.Background(), "key", time.Second, func(context.Context) (string, error) {
+		return "value", nil
+	})
+	if err != nil || value != "value" {
+		t.Errorf("Expected (%q, nil) after the panic, got (%q, %v)", "value", value, err)
+	}
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	if str := buf.String(); !strings.Contains(str, "cache.cache.load-error:1.000000|c") {
+		t.Errorf("Expected the panic counted as load error, got %q", str)
+	}
+}
+
+func TestCachedLoaderContext(t *testing.T) {
+	client := newFakeCacheClient()
+	loader := redisbp.NewCachedLoader(client, redisbp.CachedLoaderConfig{
+		Name: "cache",
+	})
+
+	var loads int64
+	started := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		if atomic.AddInt64(&loads, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "value", nil
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := loader.GetOrLoad(leaderCtx, "key", time.Second, load)
+		leaderErr <- err
+	}()
+	<-started
+
+	t.Run("waiter-canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		_, err := loader.GetOrLoad(ctx, "key", time.Second, load)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("leader-canceled", func(t *testing.T) {
+		waiter := make(chan error, 1)
+		var value string
+		go func() {
+			var err error
+			value, err = loader.GetOrLoad(context.Background(), "key", time.Second, load)
+			waiter <- err
+		}()
+		time.Sleep(time.Millisecond * 10)
+		cancelLeader()
+
+		if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the leader to get %v, got %v", context.Canceled, err)
+		}
+		if err := <-waiter; err != nil || value != "value" {
+			t.Errorf("Expected the waiter to load again and get (%q, nil), got (%q, %v)", "value", value, err)
+		}
+	})
+}
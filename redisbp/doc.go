@@ -1,5 +1,7 @@
 // Package redisbp provides Baseplate integrations for go-redis.
 //
 // See https://pkg.go.dev/github.com/go-redis/redis/v7 for documentation for
-// go-redis
+// go-redis.
+//
+// For go-redis v8, see the redisbpv8 subpackage.
 package redisbp
@@ -2,19 +2,69 @@ package redisbp
 
 import (
 	"context"
-	"fmt"
+	"errors"
 
 	"github.com/go-redis/redis/v7"
 	opentracing "github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go/batcherror"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/redisbp/internal/commandnames"
+	"github.com/reddit/baseplate.go/requestevent"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
+// Span tags set by SpanHook on the client spans.
+const (
+	// The name of the Redis command, or "pipeline" for pipelines.
+	SpanTagKeyCommand = "redis.command"
+	// The DB index the client is connected to.
+	SpanTagKeyDB = "redis.db"
+	// The number of keys touched by the command or pipeline.
+	SpanTagKeyNumKeys = "redis.keys"
+	// Whether the span is for a pipeline.
+	SpanTagKeyPipeline = "redis.pipeline"
+	// The number of commands in the pipeline, only set on pipeline spans.
+	SpanTagKeyPipelineCommands = "redis.pipeline.commands"
+	// The address of the Redis server, only set when Addr is set in SpanHook.
+	SpanTagKeyPeerAddress = "peer.address"
+)
+
 // SpanHook is a redis.Hook for wrapping Redis commands and pipelines
 // in Client Spans and metrics.
 type SpanHook struct {
 	ClientName string
+
+	// DB is the DB index the client is connected to,
+	// it's only used to tag the spans.
+	DB int
+
+	// Addr is the address of the Redis server,
+	// it's only used to tag the spans.
+	//
+	// Optional.
+	Addr string
+
+	// IsFailure decides whether an error returned by a command should mark the
+	// span, and the "success"/"fail" metrics, as failed.
+	//
+	// Optional, defaults to DefaultErrorClassifier.
+	IsFailure ErrorClassifier
+}
+
+// ErrorClassifier reports whether err returned by a Redis command should be
+// treated as a failure.
+type ErrorClassifier func(err error) bool
+
+// DefaultErrorClassifier is the default ErrorClassifier used by SpanHook.
+//
+// It treats all non-nil errors as failures except redis.Nil,
+// which is returned on cache misses (e.g. GET on a key that doesn't exist)
+// and is usually expected.
+// Misses are instead counted by SpanHook in the "<ClientName>.<cmd>.miss"
+// counter.
+func DefaultErrorClassifier(err error) bool {
+	return err != nil && !errors.Is(err, redis.Nil)
 }
 
 var _ redis.Hook = SpanHook{}
@@ -22,49 +72,158 @@ var _ redis.Hook = SpanHook{}
 // BeforeProcess starts a client Span before processing a Redis command and
 // starts a timer to record how long the command took.
 func (h SpanHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
-	return h.startChildSpan(ctx, cmd.Name()), nil
+	ctx, span := h.startChildSpan(ctx, cmd.Name())
+	span.SetTag(SpanTagKeyCommand, cmd.Name())
+	span.SetTag(SpanTagKeyNumKeys, numKeys(cmd))
+	span.SetTag(SpanTagKeyPipeline, false)
+	return ctx, nil
 }
 
 // AfterProcess ends the client Span started by BeforeProcess, publishes the
 // time the Redis command took to complete, and a metric indicating whether the
 // command was a "success" or "fail"
+//
+// A command returning redis.Nil increments the "<ClientName>.<cmd>.miss"
+// counter.
+// When there's a requestevent.Event attached to the context object,
+// the "redis.<ClientName>.calls" and "redis.<ClientName>.miss" fields of the
+// event are incremented accordingly.
+// Errors not classified as failures by IsFailure don't fail the span,
+// but are still returned as-is.
 func (h SpanHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
-	return h.endChildSpan(ctx, cmd.Err())
+	err := cmd.Err()
+	misses := 0
+	if errors.Is(err, redis.Nil) {
+		misses = 1
+		h.countMisses(cmd.Name(), misses)
+	}
+	h.addToRequestEvent(ctx, 1, misses)
+	spanErr := err
+	if !h.isFailure(err) {
+		spanErr = nil
+	}
+	h.endChildSpan(ctx, spanErr)
+	return err
 }
 
 // BeforeProcessPipeline starts a client span before processing a Redis pipeline
 // and starts a timer to record how long the pipeline took.
 func (h SpanHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
-	return h.startChildSpan(ctx, "pipeline"), nil
+	ctx, span := h.startChildSpan(ctx, pipelineName)
+	var keys int
+	for _, cmd := range cmds {
+		keys += numKeys(cmd)
+	}
+	span.SetTag(SpanTagKeyCommand, pipelineName)
+	span.SetTag(SpanTagKeyNumKeys, keys)
+	span.SetTag(SpanTagKeyPipeline, true)
+	span.SetTag(SpanTagKeyPipelineCommands, len(cmds))
+	return ctx, nil
 }
 
 // AfterProcessPipeline ends the client span started by BeforeProcessPipeline,
 // publishes the time the Redis pipeline took to complete, and a metric
 // indicating whether the pipeline was a "success" or "fail"
+//
+// The errors of all the failed commands are returned in a
+// batcherror.BatchError, which can be inspected with errors.Is and errors.As,
+// e.g. errors.Is(err, redis.Nil) reports whether any command returned
+// redis.Nil.
+// Only the errors classified as failures by IsFailure fail the span,
+// and the number of commands returning redis.Nil is added to the
+// "<ClientName>.pipeline.miss" counter.
+// The requestevent.Event attached to the context object, if any,
+// is updated the same way as in AfterProcess,
+// with every command in the pipeline counted as a call.
 func (h SpanHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
-	var errs batcherror.BatchError
+	var errs, failures batcherror.BatchError
+	var misses int
 	for _, cmd := range cmds {
-		errs.Add(cmd.Err())
+		err := cmd.Err()
+		errs.Add(err)
+		if errors.Is(err, redis.Nil) {
+			misses++
+		}
+		if h.isFailure(err) {
+			failures.Add(err)
+		}
+	}
+	if misses > 0 {
+		h.countMisses(pipelineName, misses)
 	}
-	return h.endChildSpan(ctx, errs.Compile())
+	h.addToRequestEvent(ctx, len(cmds), misses)
+	h.endChildSpan(ctx, failures.Compile())
+	return errs.Compile()
 }
 
-func (h SpanHook) startChildSpan(ctx context.Context, cmdName string) context.Context {
-	name := fmt.Sprintf("%s.%s", h.ClientName, cmdName)
-	_, ctx = opentracing.StartSpanFromContext(
+func (h SpanHook) startChildSpan(ctx context.Context, cmdName string) (context.Context, opentracing.Span) {
+	span, ctx := opentracing.StartSpanFromContext(
 		ctx,
-		name,
+		commandnames.Get(h.ClientName, cmdName).Span,
 		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
 	)
-	return ctx
+	span.SetTag(SpanTagKeyDB, h.DB)
+	if h.Addr != "" {
+		span.SetTag(SpanTagKeyPeerAddress, h.Addr)
+	}
+	return ctx, span
 }
 
-func (h SpanHook) endChildSpan(ctx context.Context, err error) error {
+func (h SpanHook) endChildSpan(ctx context.Context, err error) {
 	if span := opentracing.SpanFromContext(ctx); span != nil {
 		span.FinishWithOptions(tracing.FinishOptions{
 			Ctx: ctx,
 			Err: err,
 		}.Convert())
 	}
-	return err
+}
+
+func (h SpanHook) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if h.IsFailure != nil {
+		return h.IsFailure(err)
+	}
+	return DefaultErrorClassifier(err)
+}
+
+func (h SpanHook) countMisses(cmdName string, n int) {
+	metricsbp.M.Counter(commandnames.Get(h.ClientName, cmdName).Miss).Add(float64(n))
+}
+
+func (h SpanHook) addToRequestEvent(ctx context.Context, calls, misses int) {
+	event, ok := requestevent.FromContext(ctx)
+	if !ok {
+		return
+	}
+	prefix := "redis." + h.ClientName
+	event.Add(prefix+".calls", float64(calls))
+	if misses > 0 {
+		event.Add(prefix+".miss", float64(misses))
+	}
+}
+
+const pipelineName = "pipeline"
+
+// numKeys returns the best-effort number of keys touched by the command.
+//
+// Redis commands don't carry key positions on the client side,
+// so for the commands known to take only keys we count all the args,
+// for MSET and MSETNX we count every other arg,
+// and for all other commands we assume the first arg (if any) is the only key.
+func numKeys(cmd redis.Cmder) int {
+	args := len(cmd.Args()) - 1
+	if args <= 0 {
+		return 0
+	}
+	switch cmd.Name() {
+	case "del", "exists", "mget", "touch", "unlink", "watch",
+		"sdiff", "sinter", "sunion", "pfcount":
+		return args
+	case "mset", "msetnx":
+		return args / 2
+	default:
+		return 1
+	}
 }
@@ -2,14 +2,21 @@ package redisbp_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redis/v7"
 	opentracing "github.com/opentracing/opentracing-go"
 
+	"github.com/reddit/baseplate.go/batcherror"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/mqsend"
 	"github.com/reddit/baseplate.go/redisbp"
 	"github.com/reddit/baseplate.go/thriftbp"
 	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
 )
 
 func TestSpanHook(t *testing.T) {
@@ -60,3 +67,218 @@ func TestSpanHook(t *testing.T) {
 		},
 	)
 }
+
+func TestSpanHookTags(t *testing.T) {
+	recorder := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxQueueSize:   100,
+		MaxMessageSize: tracing.MaxSpanSize,
+	})
+	tracing.InitGlobalTracer(tracing.TracerConfig{
+		SampleRate:               1,
+		TestOnlyMockMessageQueue: recorder,
+	})
+	defer func() {
+		tracing.CloseTracer()
+		tracing.InitGlobalTracer(tracing.TracerConfig{})
+	}()
+
+	ctx, span := thriftbp.StartSpanFromThriftContext(context.Background(), "foo")
+	span.SetDebug(true)
+	hooks := redisbp.SpanHook{ClientName: "redis", DB: 2, Addr: "localhost:6379"}
+
+	getTags := func(t *testing.T) map[string]interface{} {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		msg, err := recorder.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var span tracing.ZipkinSpan
+		if err := json.Unmarshal(msg, &span); err != nil {
+			t.Fatal(err)
+		}
+		tags := make(map[string]interface{})
+		for _, annotation := range span.BinaryAnnotations {
+			tags[annotation.Key] = annotation.Value
+		}
+		return tags
+	}
+
+	cases := []struct {
+		name     string
+		run      func(t *testing.T)
+		expected map[string]interface{}
+	}{
+		{
+			name: "command",
+			run: func(t *testing.T) {
+				cmd := redis.NewIntCmd("del", "a", "b", "c")
+				ctx, err := hooks.BeforeProcess(ctx, cmd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				hooks.AfterProcess(ctx, cmd)
+			},
+			expected: map[string]interface{}{
+				redisbp.SpanTagKeyCommand:     "del",
+				redisbp.SpanTagKeyDB:          "2",
+				redisbp.SpanTagKeyNumKeys:     "3",
+				redisbp.SpanTagKeyPipeline:    "false",
+				redisbp.SpanTagKeyPeerAddress: "localhost:6379",
+			},
+		},
+		{
+			name: "pipeline",
+			run: func(t *testing.T) {
+				cmds := []redis.Cmder{
+					redis.NewStatusCmd("set", "a", "value"),
+					redis.NewStatusCmd("mset", "b", "1", "c", "2"),
+				}
+				ctx, err := hooks.BeforeProcessPipeline(ctx, cmds)
+				if err != nil {
+					t.Fatal(err)
+				}
+				hooks.AfterProcessPipeline(ctx, cmds)
+			},
+			expected: map[string]interface{}{
+				redisbp.SpanTagKeyCommand:          "pipeline",
+				redisbp.SpanTagKeyDB:               "2",
+				redisbp.SpanTagKeyNumKeys:          "3",
+				redisbp.SpanTagKeyPipeline:         "true",
+				redisbp.SpanTagKeyPipelineCommands: "2",
+				redisbp.SpanTagKeyPeerAddress:      "localhost:6379",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t)
+			tags := getTags(t)
+			for k, v := range c.expected {
+				if tags[k] != v {
+					t.Errorf("Expected tag %q to be %v, got %v", k, v, tags[k])
+				}
+			}
+		})
+	}
+}
+
+func TestSpanHookPipelineErrors(t *testing.T) {
+	ctx, _ := thriftbp.StartSpanFromThriftContext(context.Background(), "foo")
+	hooks := redisbp.SpanHook{ClientName: "redis"}
+
+	cmds := []redis.Cmder{
+		redis.NewStringResult("", redis.Nil),
+		redis.NewStatusResult("", errors.New("oops")),
+		redis.NewStatusResult("PONG", nil),
+	}
+
+	ctx, err := hooks.BeforeProcessPipeline(ctx, cmds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = hooks.AfterProcessPipeline(ctx, cmds)
+	if !errors.Is(err, redis.Nil) {
+		t.Errorf("Expected pipeline error %v to be redis.Nil", err)
+	}
+	var batch batcherror.BatchError
+	if !errors.As(err, &batch) {
+		t.Fatalf("Expected pipeline error %v to be a BatchError", err)
+	}
+	if errs := batch.GetErrors(); len(errs) != 2 {
+		t.Errorf("Expected 2 errors in the batch, got %v", errs)
+	}
+}
+
+// getCmd is a GET command with preset result.
+type getCmd struct {
+	redis.Cmder
+}
+
+func (getCmd) Name() string {
+	return "get"
+}
+
+func TestSpanHookMiss(t *testing.T) {
+	errOops := errors.New("oops")
+	for _, c := range []struct {
+		label    string
+		hooks    redisbp.SpanHook
+		cmd      redis.Cmder
+		errored  bool
+		misses   float64
+		expected error
+	}{
+		{
+			label:    "nil",
+			hooks:    redisbp.SpanHook{ClientName: "redis"},
+			cmd:      getCmd{redis.NewStringResult("", redis.Nil)},
+			misses:   1,
+			expected: redis.Nil,
+		},
+		{
+			label: "nil-as-failure",
+			hooks: redisbp.SpanHook{
+				ClientName: "redis",
+				IsFailure: func(err error) bool {
+					return err != nil
+				},
+			},
+			cmd:      getCmd{redis.NewStringResult("", redis.Nil)},
+			errored:  true,
+			misses:   1,
+			expected: redis.Nil,
+		},
+		{
+			label:    "error",
+			hooks:    redisbp.SpanHook{ClientName: "redis"},
+			cmd:      getCmd{redis.NewStringResult("", errOops)},
+			errored:  true,
+			expected: errOops,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			recorder := tracingtest.InitGlobalTracer(t)
+			metrics := metricstest.Replace(t)
+
+			ctx, err := c.hooks.BeforeProcess(context.Background(), c.cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.hooks.AfterProcess(ctx, c.cmd); !errors.Is(err, c.expected) {
+				t.Errorf("Expected error %v, got %v", c.expected, err)
+			}
+			span := recorder.MustFindSpan(t, "redis.get")
+			if span.IsError() != c.errored {
+				t.Errorf("Expected span to be errored %v", c.errored)
+			}
+			metrics.AssertCounterEquals(t, "redis.get.miss", c.misses)
+		})
+	}
+}
+
+func TestSpanHookPipelineMiss(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+	metrics := metricstest.Replace(t)
+	hooks := redisbp.SpanHook{ClientName: "redis"}
+
+	cmds := []redis.Cmder{
+		redis.NewStringResult("", redis.Nil),
+		redis.NewStringResult("", redis.Nil),
+		redis.NewStatusResult("PONG", nil),
+	}
+	ctx, err := hooks.BeforeProcessPipeline(context.Background(), cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hooks.AfterProcessPipeline(ctx, cmds); !errors.Is(err, redis.Nil) {
+		t.Errorf("Expected pipeline error %v to be redis.Nil", err)
+	}
+	if span := recorder.MustFindSpan(t, "redis.pipeline"); span.IsError() {
+		t.Error("Expected pipeline span with only misses to not be errored")
+	}
+	metrics.AssertCounterEquals(t, "redis.pipeline.miss", 2)
+}
@@ -0,0 +1,58 @@
+// Package commandnames caches the span and metric names used by the span
+// hooks in redisbp and redisbpv8.
+package commandnames
+
+import (
+	"sync"
+)
+
+// maxCached is the maximum number of entries in cache.
+//
+// The vocabulary of Redis commands is small,
+// the limit is only to guard against unbounded growth from arbitrary
+// command names sent via Do.
+const maxCached = 1024
+
+// Names are the span and metric names used for a command of a client.
+type Names struct {
+	Span string
+	Miss string
+}
+
+type key struct {
+	client string
+	cmd    string
+}
+
+// cache caches the Names so that they are not rebuilt on every command.
+var cache = struct {
+	sync.RWMutex
+	m map[key]Names
+}{
+	m: make(map[key]Names),
+}
+
+// Get returns the Names for the cmd of the client.
+func Get(client, cmd string) Names {
+	k := key{
+		client: client,
+		cmd:    cmd,
+	}
+	cache.RLock()
+	names, ok := cache.m[k]
+	cache.RUnlock()
+	if ok {
+		return names
+	}
+
+	names = Names{
+		Span: client + "." + cmd,
+		Miss: client + "." + cmd + ".miss",
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	if len(cache.m) < maxCached {
+		cache.m[k] = names
+	}
+	return names
+}
@@ -61,11 +61,18 @@ func (c *monitoredRing) WithMonitoredContext(ctx context.Context) MonitoredCmdab
 // A MonitoredCmdableFactory should be created using one of the New methods
 // provided in this package.
 type MonitoredCmdableFactory struct {
-	client MonitoredCmdable
+	client monitoredCmdableSource
 }
 
-func newMonitoredCmdableFactory(name string, client MonitoredCmdable) MonitoredCmdableFactory {
-	client.AddHook(SpanHook{ClientName: name})
+// monitoredCmdableSource is what MonitoredCmdableFactory needs from the
+// clients, implemented by MonitoredCmdable.
+type monitoredCmdableSource interface {
+	AddHook(hook redis.Hook)
+	WithMonitoredContext(ctx context.Context) MonitoredCmdable
+}
+
+func newMonitoredCmdableFactory(name string, db int, addr string, client monitoredCmdableSource) MonitoredCmdableFactory {
+	client.AddHook(SpanHook{ClientName: name, DB: db, Addr: addr})
 	return MonitoredCmdableFactory{client: client}
 }
 
@@ -75,19 +82,21 @@ func newMonitoredCmdableFactory(name string, client MonitoredCmdable) MonitoredC
 // This may connect to a single redis instance, or be a failover client using
 // Redis Sentinel.
 func NewMonitoredClientFactory(name string, client *redis.Client) MonitoredCmdableFactory {
-	return newMonitoredCmdableFactory(name, &monitoredClient{Client: client})
+	opts := client.Options()
+	return newMonitoredCmdableFactory(name, opts.DB, opts.Addr, &monitoredClient{Client: client})
 }
 
 // NewMonitoredClusterFactory creates a MonitoredCmdableFactory for a
 // redis.ClusterClient object.
 func NewMonitoredClusterFactory(name string, client *redis.ClusterClient) MonitoredCmdableFactory {
-	return newMonitoredCmdableFactory(name, &monitoredCluster{ClusterClient: client})
+	// Redis Cluster only supports DB 0.
+	return newMonitoredCmdableFactory(name, 0, "", &monitoredCluster{ClusterClient: client})
 }
 
 // NewMonitoredRingFactory creates a MonitoredCmdableFactory for a redis.Ring
 // object.
 func NewMonitoredRingFactory(name string, client *redis.Ring) MonitoredCmdableFactory {
-	return newMonitoredCmdableFactory(name, &monitoredRing{Ring: client})
+	return newMonitoredCmdableFactory(name, client.Options().DB, "", &monitoredRing{Ring: client})
 }
 
 // BuildClient returns a new MonitoredCmdable with its context set to the
@@ -97,6 +106,9 @@ func (f MonitoredCmdableFactory) BuildClient(ctx context.Context) MonitoredCmdab
 }
 
 var (
+	_ monitoredCmdableSource = MonitoredCmdable(nil)
+	_ monitoredCmdableSource = monitoredSecretPasswordClient{}
+
 	_ MonitoredCmdable = (*monitoredClient)(nil)
 	_ MonitoredCmdable = (*monitoredCluster)(nil)
 	_ MonitoredCmdable = (*monitoredRing)(nil)
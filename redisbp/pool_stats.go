@@ -0,0 +1,87 @@
+package redisbp
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// DefaultPoolStatsInterval is the fallback value to be used when the interval
+// passed into MonitorPoolStats is <= 0.
+const DefaultPoolStatsInterval = time.Second * 10
+
+// PoolStatser is the interface that wraps the PoolStats method.
+//
+// *redis.Client, *redis.ClusterClient, and *redis.Ring all implement it.
+type PoolStatser interface {
+	PoolStats() *redis.PoolStats
+}
+
+// MonitorPoolStats periodically reads the connection pool stats from the
+// client and reports them as gauges with metricsbp.M.
+//
+// It reports the following gauges:
+//
+// - "${name}.pool-hits": the number of times a free connection was found in
+// the pool.
+//
+// - "${name}.pool-misses": the number of times a free connection was NOT found
+// in the pool.
+//
+// - "${name}.pool-timeouts": the number of times a wait timeout occurred.
+//
+// - "${name}.pool-total-connections": the number of total connections in the
+// pool.
+//
+// - "${name}.pool-idle-connections": the number of idle connections in the
+// pool.
+//
+// - "${name}.pool-stale-connections": the number of stale connections removed
+// from the pool.
+//
+// Note that hits, misses, timeouts, and stale connections are cumulative since
+// the client was created.
+//
+// When interval <= 0, DefaultPoolStatsInterval will be used instead.
+//
+// This function blocks until the passed in context is cancelled,
+// so it should usually be started in its own goroutine:
+//
+//     go redisbp.MonitorPoolStats(metricsbp.M.Ctx(), client, "redis", 0)
+func MonitorPoolStats(ctx context.Context, client PoolStatser, name string, interval time.Duration) {
+	hitsGauge := metricsbp.M.Gauge(name + ".pool-hits")
+	missesGauge := metricsbp.M.Gauge(name + ".pool-misses")
+	timeoutsGauge := metricsbp.M.Gauge(name + ".pool-timeouts")
+	totalGauge := metricsbp.M.Gauge(name + ".pool-total-connections")
+	idleGauge := metricsbp.M.Gauge(name + ".pool-idle-connections")
+	staleGauge := metricsbp.M.Gauge(name + ".pool-stale-connections")
+
+	if interval <= 0 {
+		interval = DefaultPoolStatsInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := client.PoolStats()
+			hitsGauge.Set(float64(stats.Hits))
+			missesGauge.Set(float64(stats.Misses))
+			timeoutsGauge.Set(float64(stats.Timeouts))
+			totalGauge.Set(float64(stats.TotalConns))
+			idleGauge.Set(float64(stats.IdleConns))
+			staleGauge.Set(float64(stats.StaleConns))
+		}
+	}
+}
+
+var (
+	_ PoolStatser = (*redis.Client)(nil)
+	_ PoolStatser = (*redis.ClusterClient)(nil)
+	_ PoolStatser = (*redis.Ring)(nil)
+)
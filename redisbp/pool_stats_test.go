@@ -0,0 +1,65 @@
+package redisbp_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/redisbp"
+)
+
+type fakePoolStatser redis.PoolStats
+
+func (s fakePoolStatser) PoolStats() *redis.PoolStats {
+	stats := redis.PoolStats(s)
+	return &stats
+}
+
+func TestMonitorPoolStats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	prevM := metricsbp.M
+	defer func() {
+		metricsbp.M = prevM
+	}()
+	metricsbp.M = metricsbp.NewStatsd(ctx, metricsbp.StatsdConfig{})
+
+	stats := fakePoolStatser{
+		Hits:       1,
+		Misses:     2,
+		Timeouts:   3,
+		TotalConns: 4,
+		IdleConns:  5,
+		StaleConns: 6,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		redisbp.MonitorPoolStats(ctx, stats, "redis", time.Millisecond)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	cancel()
+	<-done
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	output := buf.String()
+	for _, expected := range []string{
+		"redis.pool-hits:1.000000|g",
+		"redis.pool-misses:2.000000|g",
+		"redis.pool-timeouts:3.000000|g",
+		"redis.pool-total-connections:4.000000|g",
+		"redis.pool-idle-connections:5.000000|g",
+		"redis.pool-stale-connections:6.000000|g",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the reported metrics, got %q", expected, output)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package redisbp
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+)
+
+// ScriptClient is the subset of redis.Cmdable needed to run lua scripts.
+//
+// *redis.Client, *redis.ClusterClient, and *redis.Ring all implement it.
+type ScriptClient interface {
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	ScriptExists(hashes ...string) *redis.BoolSliceCmd
+	ScriptLoad(script string) *redis.StringCmd
+}
+
+var (
+	_ ScriptClient = (*redis.Client)(nil)
+	_ ScriptClient = (*redis.ClusterClient)(nil)
+	_ ScriptClient = (*redis.Ring)(nil)
+
+	_ ratelimitbp.RateLimiter = (*RateLimiter)(nil)
+)
+
+// gcraScript implements the generic cell rate algorithm (GCRA).
+//
+// KEYS[1]: the key of the bucket.
+// ARGV[1]: the emission interval (time between two requests) in microseconds.
+// ARGV[2]: the burst tolerance in microseconds.
+// ARGV[3]: the current time in microseconds.
+//
+// It stores the theoretical arrival time (TAT) of the next request in the key,
+// and returns 1 if the request is allowed, 0 otherwise.
+var gcraScript = redis.NewScript(`
+local emission = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+if tat - tolerance > now then
+	return 0
+end
+local new_tat = tat + emission
+redis.call("SET", KEYS[1], new_tat, "PX", math.ceil((new_tat - now) / 1000))
+return 1
+`)
+
+// RateLimiterConfig is the configuration used by NewRateLimiter.
+type RateLimiterConfig struct {
+	// Name of the rate limiter, used as the prefix of the metrics.
+	Name string
+
+	// KeyPrefix is prepended to all the keys before writing to redis.
+	KeyPrefix string
+
+	// RatePerSecond is the sustained rate allowed for every key.
+	//
+	// When RatePerSecond <= 0, the rate limiter allows everything without
+	// talking to redis.
+	RatePerSecond float64
+
+	// Burst is the max number of requests allowed at once for a key.
+	//
+	// When Burst <= 0, 1 will be used.
+	Burst int
+
+	// FailOpen controls the behavior when redis is unavailable:
+	// when true the requests are allowed, otherwise they are rejected.
+	FailOpen bool
+}
+
+// RateLimiter is a rate limiter backed by redis,
+// so the limits can be shared by multiple instances of a service.
+//
+// It uses GCRA (generic cell rate algorithm) implemented in a lua script to
+// make every check atomic.
+// It uses the local clock of the caller,
+// so the clocks of the instances sharing the limits should be reasonably in
+// sync.
+//
+// It reports the following counters with metricsbp.M:
+//
+// - "${name}.ratelimit.allowed": the number of allowed requests.
+//
+// - "${name}.ratelimit.throttled": the number of rejected requests.
+//
+// - "${name}.ratelimit.error": the number of failed redis calls,
+// those requests are also counted as either allowed or throttled based on
+// FailOpen.
+//
+// Please use NewRateLimiter to create a RateLimiter.
+type RateLimiter struct {
+	client    ScriptClient
+	keyPrefix string
+	emission  time.Duration
+	tolerance time.Duration
+	failOpen  bool
+
+	allowed   metrics.Counter
+	throttled metrics.Counter
+	errors    metrics.Counter
+}
+
+// NewRateLimiter creates a new RateLimiter.
+func NewRateLimiter(client ScriptClient, cfg RateLimiterConfig) *RateLimiter {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	var emission time.Duration
+	if cfg.RatePerSecond > 0 {
+		emission = time.Duration(math.Ceil(float64(time.Second) / cfg.RatePerSecond))
+	}
+	return &RateLimiter{
+		client:    client,
+		keyPrefix: cfg.KeyPrefix,
+		emission:  emission,
+		tolerance: emission * time.Duration(burst-1),
+		failOpen:  cfg.FailOpen,
+		allowed:   metricsbp.M.Counter(cfg.Name + ".ratelimit.allowed"),
+		throttled: metricsbp.M.Counter(cfg.Name + ".ratelimit.throttled"),
+		errors:    metricsbp.M.Counter(cfg.Name + ".ratelimit.error"),
+	}
+}
+
+// Allow implements ratelimitbp.RateLimiter.
+func (l *RateLimiter) Allow(ctx context.Context, key string) bool {
+	if l.emission <= 0 {
+		return true
+	}
+
+	allowed, err := gcraScript.Run(
+		l.client,
+		[]string{l.keyPrefix + key},
+		l.emission.Microseconds(),
+		l.tolerance.Microseconds(),
+		time.Now().UnixNano()/int64(time.Microsecond),
+	).Int()
+	if err != nil {
+		l.errors.Add(1)
+		log.Named("redisbp").Errorw(
+			"redisbp: rate limiter failed to talk to redis",
+			"err", err,
+			"key", key,
+			"failOpen", l.failOpen,
+		)
+		return l.record(l.failOpen)
+	}
+	return l.record(allowed == 1)
+}
+
+func (l *RateLimiter) record(allowed bool) bool {
+	if allowed {
+		l.allowed.Add(1)
+	} else {
+		l.throttled.Add(1)
+	}
+	return allowed
+}
@@ -0,0 +1,103 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/redisbp"
+)
+
+// fakeScriptClient returns result for all EVALSHA calls.
+type fakeScriptClient struct {
+	result interface{}
+	err    error
+
+	calls int
+	keys  []string
+	args  []interface{}
+}
+
+func (c *fakeScriptClient) Eval(script string, keys []string, args ...interface{}) *redis.Cmd {
+	return c.EvalSha("", keys, args...)
+}
+
+func (c *fakeScriptClient) EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	c.calls++
+	c.keys = keys
+	c.args = args
+	return redis.NewCmdResult(c.result, c.err)
+}
+
+func (c *fakeScriptClient) ScriptExists(hashes ...string) *redis.BoolSliceCmd {
+	return redis.NewBoolSliceResult(make([]bool, len(hashes)), nil)
+}
+
+func (c *fakeScriptClient) ScriptLoad(script string) *redis.StringCmd {
+	return redis.NewStringResult("", nil)
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	metrics := metricstest.Replace(t)
+
+	client := &fakeScriptClient{result: int64(1)}
+	limiter := redisbp.NewRateLimiter(client, redisbp.RateLimiterConfig{
+		Name:          "limiter",
+		KeyPrefix:     "rl:",
+		RatePerSecond: 10,
+		Burst:         5,
+	})
+
+	if !limiter.Allow(ctx, "key") {
+		t.Error("Expected request to be allowed")
+	}
+	if len(client.keys) != 1 || client.keys[0] != "rl:key" {
+		t.Errorf("Expected keys [rl:key], got %v", client.keys)
+	}
+	// emission interval is 100ms, burst tolerance is 4 emission intervals.
+	if len(client.args) != 3 || client.args[0] != int64(100000) || client.args[1] != int64(400000) {
+		t.Errorf("Unexpected script args %v", client.args)
+	}
+
+	client.result = int64(0)
+	if limiter.Allow(ctx, "key") {
+		t.Error("Expected request to be throttled")
+	}
+
+	metrics.AssertCounterEquals(t, "limiter.ratelimit.allowed", 1)
+	metrics.AssertCounterEquals(t, "limiter.ratelimit.throttled", 1)
+	metrics.AssertCounterEquals(t, "limiter.ratelimit.error", 0)
+}
+
+func TestRateLimiterRedisError(t *testing.T) {
+	ctx := context.Background()
+
+	for _, failOpen := range []bool{true, false} {
+		metrics := metricstest.Replace(t)
+		client := &fakeScriptClient{err: errors.New("connection refused")}
+		limiter := redisbp.NewRateLimiter(client, redisbp.RateLimiterConfig{
+			Name:          "limiter",
+			RatePerSecond: 10,
+			FailOpen:      failOpen,
+		})
+		if actual := limiter.Allow(ctx, "key"); actual != failOpen {
+			t.Errorf("Expected Allow to return %v with FailOpen %v, got %v", failOpen, failOpen, actual)
+		}
+		metrics.AssertCounterEquals(t, "limiter.ratelimit.error", 1)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	client := &fakeScriptClient{result: int64(0)}
+	limiter := redisbp.NewRateLimiter(client, redisbp.RateLimiterConfig{})
+	if !limiter.Allow(context.Background(), "key") {
+		t.Error("Expected disabled rate limiter to allow the request")
+	}
+	if client.calls != 0 {
+		t.Errorf("Expected no redis calls, got %d", client.calls)
+	}
+}
@@ -0,0 +1,23 @@
+// Package redisbpv8 provides Baseplate integrations for go-redis v8.
+//
+// go-redis v8 takes a context object in every command,
+// so unlike redisbp there's no need to create a new client with the context
+// of every request via MonitoredCmdableFactory:
+// add a SpanHook to the client once,
+// and the client spans will be created as the children of the span on the
+// context objects passed into the commands:
+//
+//     client := redis.NewClient(opts)
+//     redisbpv8.MonitorClient("redis", client)
+//
+//     func (h *Handler) Do(ctx context.Context) error {
+//       return h.client.Set(ctx, "key", "value", 0).Err()
+//     }
+//
+// The spans are tagged the same way as redisbp,
+// so both packages can be used side by side during the migration from
+// go-redis v7.
+//
+// See https://pkg.go.dev/github.com/go-redis/redis/v8 for documentation for
+// go-redis v8.
+package redisbpv8
@@ -0,0 +1,204 @@
+package redisbpv8
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/batcherror"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/redisbp"
+	"github.com/reddit/baseplate.go/redisbp/internal/commandnames"
+	"github.com/reddit/baseplate.go/requestevent"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// SpanHook is a redis.Hook for wrapping Redis commands and pipelines
+// in Client Spans and metrics.
+//
+// It's the go-redis v8 version of redisbp.SpanHook,
+// the spans are started from the context objects passed into the commands,
+// and tagged with the same redisbp.SpanTagKey* tags.
+type SpanHook struct {
+	ClientName string
+
+	// DB is the DB index the client is connected to,
+	// it's only used to tag the spans.
+	DB int
+
+	// Addr is the address of the Redis server,
+	// it's only used to tag the spans.
+	//
+	// Optional.
+	Addr string
+
+	// IsFailure decides whether an error returned by a command should mark the
+	// span, and the "success"/"fail" metrics, as failed.
+	//
+	// Optional, defaults to DefaultErrorClassifier.
+	IsFailure redisbp.ErrorClassifier
+}
+
+// DefaultErrorClassifier is the default redisbp.ErrorClassifier used by
+// SpanHook.
+//
+// It's the go-redis v8 version of redisbp.DefaultErrorClassifier,
+// treating all non-nil errors as failures except redis.Nil.
+func DefaultErrorClassifier(err error) bool {
+	return err != nil && !errors.Is(err, redis.Nil)
+}
+
+var _ redis.Hook = SpanHook{}
+
+// BeforeProcess starts a client Span before processing a Redis command.
+func (h SpanHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, span := h.startChildSpan(ctx, cmd.Name())
+	span.SetTag(redisbp.SpanTagKeyCommand, cmd.Name())
+	span.SetTag(redisbp.SpanTagKeyNumKeys, numKeys(cmd))
+	span.SetTag(redisbp.SpanTagKeyPipeline, false)
+	return ctx, nil
+}
+
+// AfterProcess ends the client Span started by BeforeProcess.
+//
+// A command returning redis.Nil increments the "<ClientName>.<cmd>.miss"
+// counter.
+// When there's a requestevent.Event attached to the context object,
+// the "redis.<ClientName>.calls" and "redis.<ClientName>.miss" fields of the
+// event are incremented accordingly.
+// Errors not classified as failures by IsFailure don't fail the span,
+// but are still returned as-is.
+func (h SpanHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	err := cmd.Err()
+	misses := 0
+	if errors.Is(err, redis.Nil) {
+		misses = 1
+		h.countMisses(cmd.Name(), misses)
+	}
+	h.addToRequestEvent(ctx, 1, misses)
+	spanErr := err
+	if !h.isFailure(err) {
+		spanErr = nil
+	}
+	h.endChildSpan(ctx, spanErr)
+	return err
+}
+
+// BeforeProcessPipeline starts a client span before processing a Redis
+// pipeline.
+func (h SpanHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx, span := h.startChildSpan(ctx, pipelineName)
+	var keys int
+	for _, cmd := range cmds {
+		keys += numKeys(cmd)
+	}
+	span.SetTag(redisbp.SpanTagKeyCommand, pipelineName)
+	span.SetTag(redisbp.SpanTagKeyNumKeys, keys)
+	span.SetTag(redisbp.SpanTagKeyPipeline, true)
+	span.SetTag(redisbp.SpanTagKeyPipelineCommands, len(cmds))
+	return ctx, nil
+}
+
+// AfterProcessPipeline ends the client span started by BeforeProcessPipeline.
+//
+// The errors of all the failed commands are returned in a
+// batcherror.BatchError, which can be inspected with errors.Is and errors.As,
+// e.g. errors.Is(err, redis.Nil) reports whether any command returned
+// redis.Nil.
+// Only the errors classified as failures by IsFailure fail the span,
+// and the number of commands returning redis.Nil is added to the
+// "<ClientName>.pipeline.miss" counter.
+// The requestevent.Event attached to the context object, if any,
+// is updated the same way as in AfterProcess,
+// with every command in the pipeline counted as a call.
+func (h SpanHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var errs, failures batcherror.BatchError
+	var misses int
+	for _, cmd := range cmds {
+		err := cmd.Err()
+		errs.Add(err)
+		if errors.Is(err, redis.Nil) {
+			misses++
+		}
+		if h.isFailure(err) {
+			failures.Add(err)
+		}
+	}
+	if misses > 0 {
+		h.countMisses(pipelineName, misses)
+	}
+	h.addToRequestEvent(ctx, len(cmds), misses)
+	h.endChildSpan(ctx, failures.Compile())
+	return errs.Compile()
+}
+
+func (h SpanHook) startChildSpan(ctx context.Context, cmdName string) (context.Context, opentracing.Span) {
+	span, ctx := opentracing.StartSpanFromContext(
+		ctx,
+		commandnames.Get(h.ClientName, cmdName).Span,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	span.SetTag(redisbp.SpanTagKeyDB, h.DB)
+	if h.Addr != "" {
+		span.SetTag(redisbp.SpanTagKeyPeerAddress, h.Addr)
+	}
+	return ctx, span
+}
+
+func (h SpanHook) endChildSpan(ctx context.Context, err error) {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}
+}
+
+func (h SpanHook) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if h.IsFailure != nil {
+		return h.IsFailure(err)
+	}
+	return DefaultErrorClassifier(err)
+}
+
+func (h SpanHook) countMisses(cmdName string, n int) {
+	metricsbp.M.Counter(commandnames.Get(h.ClientName, cmdName).Miss).Add(float64(n))
+}
+
+func (h SpanHook) addToRequestEvent(ctx context.Context, calls, misses int) {
+	event, ok := requestevent.FromContext(ctx)
+	if !ok {
+		return
+	}
+	prefix := "redis." + h.ClientName
+	event.Add(prefix+".calls", float64(calls))
+	if misses > 0 {
+		event.Add(prefix+".miss", float64(misses))
+	}
+}
+
+const pipelineName = "pipeline"
+
+// numKeys returns the best-effort number of keys touched by the command.
+//
+// It's the same as the one used by redisbp.SpanHook.
+func numKeys(cmd redis.Cmder) int {
+	args := len(cmd.Args()) - 1
+	if args <= 0 {
+		return 0
+	}
+	switch cmd.Name() {
+	case "del", "exists", "mget", "touch", "unlink", "watch",
+		"sdiff", "sinter", "sunion", "pfcount":
+		return args
+	case "mset", "msetnx":
+		return args / 2
+	default:
+		return 1
+	}
+}
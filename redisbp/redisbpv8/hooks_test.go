@@ -0,0 +1,156 @@
+package redisbpv8_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/redisbp"
+	"github.com/reddit/baseplate.go/redisbp/redisbpv8"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+// newClient returns a monitored client connecting to an address that always
+// fails to dial, so the commands fail without a running redis server.
+func newClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: "localhost:0",
+		DB:   2,
+	})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	redisbpv8.MonitorClient("redis", client)
+	return client
+}
+
+func TestSpanHook(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		run      func(ctx context.Context, client *redis.Client)
+		name     string
+		expected map[string]interface{}
+	}{
+		{
+			label: "command",
+			run: func(ctx context.Context, client *redis.Client) {
+				client.Del(ctx, "a", "b", "c")
+			},
+			name: "redis.del",
+			expected: map[string]interface{}{
+				redisbp.SpanTagKeyCommand:     "del",
+				redisbp.SpanTagKeyDB:          "2",
+				redisbp.SpanTagKeyNumKeys:     "3",
+				redisbp.SpanTagKeyPipeline:    "false",
+				redisbp.SpanTagKeyPeerAddress: "localhost:0",
+			},
+		},
+		{
+			label: "pipeline",
+			run: func(ctx context.Context, client *redis.Client) {
+				pipe := client.Pipeline()
+				pipe.Set(ctx, "a", "value", 0)
+				pipe.MSet(ctx, "b", "1", "c", "2")
+				pipe.Exec(ctx)
+			},
+			name: "redis.pipeline",
+			expected: map[string]interface{}{
+				redisbp.SpanTagKeyCommand:          "pipeline",
+				redisbp.SpanTagKeyDB:               "2",
+				redisbp.SpanTagKeyNumKeys:          "3",
+				redisbp.SpanTagKeyPipeline:         "true",
+				redisbp.SpanTagKeyPipelineCommands: "2",
+				redisbp.SpanTagKeyPeerAddress:      "localhost:0",
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			recorder := tracingtest.InitGlobalTracer(t)
+			client := newClient(t)
+
+			sampled := true
+			ctx, server := tracing.StartSpanFromHeaders(
+				context.Background(),
+				"server",
+				tracing.Headers{Sampled: &sampled},
+			)
+			c.run(ctx, client)
+			server.Stop(ctx, nil)
+
+			span := recorder.MustFindSpan(t, c.name)
+			if parent, ok := recorder.Parent(span); !ok || parent.Name != "server" {
+				t.Errorf(
+					"Expected the span on the command context to be the parent, got %+v",
+					recorder.Spans(),
+				)
+			}
+			if !span.IsError() {
+				t.Errorf("Expected the failed command to finish the span with error, got %+v", span)
+			}
+			for k, v := range c.expected {
+				if tag, _ := span.Tag(k); tag != v {
+					t.Errorf("Expected tag %q to be %v, got %v", k, v, tag)
+				}
+			}
+		})
+	}
+}
+
+// getCmd is a GET command with preset result.
+type getCmd struct {
+	redis.Cmder
+}
+
+func (getCmd) Name() string {
+	return "get"
+}
+
+func TestSpanHookMiss(t *testing.T) {
+	hooks := redisbpv8.SpanHook{ClientName: "redis"}
+
+	t.Run("command", func(t *testing.T) {
+		recorder := tracingtest.InitGlobalTracer(t)
+		metrics := metricstest.Replace(t)
+
+		cmd := getCmd{redis.NewStringResult("", redis.Nil)}
+		ctx, err := hooks.BeforeProcess(context.Background(), cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := hooks.AfterProcess(ctx, cmd); !errors.Is(err, redis.Nil) {
+			t.Errorf("Expected error %v, got %v", redis.Nil, err)
+		}
+		if span := recorder.MustFindSpan(t, "redis.get"); span.IsError() {
+			t.Error("Expected span of a miss to not be errored")
+		}
+		metrics.AssertCounterEquals(t, "redis.get.miss", 1)
+	})
+
+	t.Run("pipeline", func(t *testing.T) {
+		recorder := tracingtest.InitGlobalTracer(t)
+		metrics := metricstest.Replace(t)
+
+		cmds := []redis.Cmder{
+			redis.NewStringResult("", redis.Nil),
+			redis.NewStringResult("", redis.Nil),
+			redis.NewStatusResult("PONG", nil),
+		}
+		ctx, err := hooks.BeforeProcessPipeline(context.Background(), cmds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := hooks.AfterProcessPipeline(ctx, cmds); !errors.Is(err, redis.Nil) {
+			t.Errorf("Expected pipeline error %v to be redis.Nil", err)
+		}
+		if span := recorder.MustFindSpan(t, "redis.pipeline"); span.IsError() {
+			t.Error("Expected pipeline span with only misses to not be errored")
+		}
+		metrics.AssertCounterEquals(t, "redis.pipeline.miss", 2)
+	})
+}
@@ -0,0 +1,25 @@
+package redisbpv8
+
+import (
+	"github.com/go-redis/redis/v8"
+)
+
+// MonitorClient adds a SpanHook to a redis.Client.
+//
+// The client may connect to a single redis instance, or be a failover client
+// using Redis Sentinel.
+func MonitorClient(name string, client *redis.Client) {
+	opts := client.Options()
+	client.AddHook(SpanHook{ClientName: name, DB: opts.DB, Addr: opts.Addr})
+}
+
+// MonitorCluster adds a SpanHook to a redis.ClusterClient.
+func MonitorCluster(name string, client *redis.ClusterClient) {
+	// Redis Cluster only supports DB 0.
+	client.AddHook(SpanHook{ClientName: name})
+}
+
+// MonitorRing adds a SpanHook to a redis.Ring.
+func MonitorRing(name string, client *redis.Ring) {
+	client.AddHook(SpanHook{ClientName: name, DB: client.Options().DB})
+}
@@ -0,0 +1,410 @@
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The types of the replies, other than string (bulk string), int64, nil and
+// []interface{} (array).
+type (
+	statusReply string
+	errorReply  string
+)
+
+const (
+	replyOK = statusReply("OK")
+
+	errNotInteger = errorReply("ERR value is not an integer or out of range")
+	errSyntax     = errorReply("ERR syntax error")
+	errWrongType  = errorReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+)
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case statusReply:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case errorReply:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, elem := range v {
+			writeReply(w, elem)
+		}
+	default:
+		w.WriteString("$-1\r\n")
+	}
+}
+
+type command struct {
+	// The number of args allowed, maxArgs < 0 means no upper limit.
+	minArgs int
+	maxArgs int
+
+	// handler is called with the lock held.
+	handler func(s *Server, args []string) interface{}
+}
+
+var commands = map[string]command{
+	"ping": {
+		minArgs: 0,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			if len(args) > 0 {
+				return args[0]
+			}
+			return statusReply("PONG")
+		},
+	},
+	"echo": {
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			return args[0]
+		},
+	},
+	"flushdb": {
+		minArgs: 0,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			s.flush()
+			return replyOK
+		},
+	},
+	"flushall": {
+		minArgs: 0,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			s.flush()
+			return replyOK
+		},
+	},
+	"get": {
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			switch v := s.lookup(args[0]).(type) {
+			case nil:
+				return nil
+			case string:
+				return v
+			default:
+				return errWrongType
+			}
+		},
+	},
+	"set": {
+		minArgs: 2,
+		maxArgs: -1,
+		handler: handleSet,
+	},
+	"del": {
+		minArgs: 1,
+		maxArgs: -1,
+		handler: func(s *Server, args []string) interface{} {
+			var n int64
+			for _, key := range args {
+				if s.lookup(key) != nil {
+					s.delete(key)
+					n++
+				}
+			}
+			return n
+		},
+	},
+	"exists": {
+		minArgs: 1,
+		maxArgs: -1,
+		handler: func(s *Server, args []string) interface{} {
+			var n int64
+			for _, key := range args {
+				if s.lookup(key) != nil {
+					n++
+				}
+			}
+			return n
+		},
+	},
+	"incr": {
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			return s.incrBy(args[0], 1)
+		},
+	},
+	"decr": {
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			return s.incrBy(args[0], -1)
+		},
+	},
+	"incrby": {
+		minArgs: 2,
+		maxArgs: 2,
+		handler: func(s *Server, args []string) interface{} {
+			delta, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return errNotInteger
+			}
+			return s.incrBy(args[0], delta)
+		},
+	},
+	"decrby": {
+		minArgs: 2,
+		maxArgs: 2,
+		handler: func(s *Server, args []string) interface{} {
+			delta, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return errNotInteger
+			}
+			return s.incrBy(args[0], -delta)
+		},
+	},
+	"expire": {
+		minArgs: 2,
+		maxArgs: 2,
+		handler: func(s *Server, args []string) interface{} {
+			return s.expire(args[0], args[1], time.Second)
+		},
+	},
+	"pexpire": {
+		minArgs: 2,
+		maxArgs: 2,
+		handler: func(s *Server, args []string) interface{} {
+			return s.expire(args[0], args[1], time.Millisecond)
+		},
+	},
+	"ttl": {
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			return s.ttl(args[0], time.Second)
+		},
+	},
+	"pttl": {
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			return s.ttl(args[0], time.Millisecond)
+		},
+	},
+	"hset": {
+		minArgs: 3,
+		maxArgs: -1,
+		handler: func(s *Server, args []string) interface{} {
+			if len(args)%2 != 1 {
+				return errorReply("ERR wrong number of arguments for 'hset' command")
+			}
+			hash, errReply := s.hash(args[0], true)
+			if errReply != nil {
+				return errReply
+			}
+			var n int64
+			for i := 1; i < len(args); i += 2 {
+				if _, ok := hash[args[i]]; !ok {
+					n++
+				}
+				hash[args[i]] = args[i+1]
+			}
+			return n
+		},
+	},
+	"hget": {
+		minArgs: 2,
+		maxArgs: 2,
+		handler: func(s *Server, args []string) interface{} {
+			hash, errReply := s.hash(args[0], false)
+			if errReply != nil {
+				return errReply
+			}
+			if v, ok := hash[args[1]]; ok {
+				return v
+			}
+			return nil
+		},
+	},
+	"hdel": {
+		minArgs: 2,
+		maxArgs: -1,
+		handler: func(s *Server, args []string) interface{} {
+			hash, errReply := s.hash(args[0], false)
+			if errReply != nil {
+				return errReply
+			}
+			var n int64
+			for _, field := range args[1:] {
+				if _, ok := hash[field]; ok {
+					delete(hash, field)
+					n++
+				}
+			}
+			if hash != nil && len(hash) == 0 {
+				s.delete(args[0])
+			}
+			return n
+		},
+	},
+	"hgetall": {
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(s *Server, args []string) interface{} {
+			hash, errReply := s.hash(args[0], false)
+			if errReply != nil {
+				return errReply
+			}
+			fields := make([]string, 0, len(hash))
+			for field := range hash {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			reply := make([]interface{}, 0, len(hash)*2)
+			for _, field := range fields {
+				reply = append(reply, field, hash[field])
+			}
+			return reply
+		},
+	},
+}
+
+func handleSet(s *Server, args []string) interface{} {
+	key, value := args[0], args[1]
+	var expiration time.Duration
+	var nx, xx bool
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToLower(args[i]); opt {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "ex", "px":
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return errNotInteger
+			}
+			if n <= 0 {
+				return errorReply("ERR invalid expire time in set")
+			}
+			unit := time.Second
+			if opt == "px" {
+				unit = time.Millisecond
+			}
+			expiration = time.Duration(n) * unit
+		default:
+			return errSyntax
+		}
+	}
+	if nx && xx {
+		return errSyntax
+	}
+
+	exists := s.lookup(key) != nil
+	if (nx && exists) || (xx && !exists) {
+		return nil
+	}
+	s.delete(key)
+	s.values[key] = value
+	if expiration > 0 {
+		s.expires[key] = time.Now().Add(expiration)
+	}
+	return replyOK
+}
+
+// The following methods are called with the lock held.
+
+func (s *Server) flush() {
+	s.values = make(map[string]interface{})
+	s.expires = make(map[string]time.Time)
+}
+
+// lookup returns the value of the key, or nil if it doesn't exist or is
+// already expired.
+func (s *Server) lookup(key string) interface{} {
+	if deadline, ok := s.expires[key]; ok && !time.Now().Before(deadline) {
+		s.delete(key)
+	}
+	return s.values[key]
+}
+
+func (s *Server) delete(key string) {
+	delete(s.values, key)
+	delete(s.expires, key)
+}
+
+func (s *Server) incrBy(key string, delta int64) interface{} {
+	var n int64
+	switch v := s.lookup(key).(type) {
+	case nil:
+	case string:
+		var err error
+		n, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return errNotInteger
+		}
+	default:
+		return errWrongType
+	}
+	n += delta
+	// INCR keeps the expiration of the key.
+	s.values[key] = strconv.FormatInt(n, 10)
+	return n
+}
+
+func (s *Server) expire(key, arg string, unit time.Duration) interface{} {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return errNotInteger
+	}
+	if s.lookup(key) == nil {
+		return int64(0)
+	}
+	if n <= 0 {
+		s.delete(key)
+	} else {
+		s.expires[key] = time.Now().Add(time.Duration(n) * unit)
+	}
+	return int64(1)
+}
+
+func (s *Server) ttl(key string, unit time.Duration) interface{} {
+	if s.lookup(key) == nil {
+		return int64(-2)
+	}
+	deadline, ok := s.expires[key]
+	if !ok {
+		return int64(-1)
+	}
+	return int64((time.Until(deadline) + unit - 1) / unit)
+}
+
+// hash returns the hash of the key.
+//
+// When the key doesn't exist, it returns nil if create is false,
+// or creates an empty hash otherwise.
+func (s *Server) hash(key string, create bool) (map[string]string, interface{}) {
+	switch v := s.lookup(key).(type) {
+	case nil:
+		if !create {
+			return nil, nil
+		}
+		hash := make(map[string]string)
+		s.values[key] = hash
+		return hash, nil
+	case map[string]string:
+		return v, nil
+	default:
+		return nil, errWrongType
+	}
+}
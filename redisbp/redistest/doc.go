@@ -0,0 +1,17 @@
+// Package redistest provides an in-process fake redis server for tests.
+//
+// It allows testing code taking a redisbp.MonitoredCmdableFactory (or any
+// redis client) hermetically, without standing up a real redis server,
+// and asserting the commands issued by the code under test.
+//
+// A typical test looks like:
+//
+//     func TestMyHandler(t *testing.T) {
+//       server := redistest.NewServer(t)
+//       factory := server.NewMonitoredClientFactory("redis")
+//       // Call the code using factory...
+//       if cmds := server.FindCommands("set"); len(cmds) != 1 {
+//         t.Errorf("Expected 1 SET command, got %v", server.Commands())
+//       }
+//     }
+package redistest
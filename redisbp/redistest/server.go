@@ -0,0 +1,304 @@
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/redisbp"
+)
+
+// Command is a command received by a Server,
+// with the command name followed by the arguments, as sent by the client.
+type Command []string
+
+// Name returns the name of the command, in lower case.
+func (c Command) Name() string {
+	if len(c) == 0 {
+		return ""
+	}
+	return strings.ToLower(c[0])
+}
+
+// Args returns the arguments of the command.
+func (c Command) Args() []string {
+	if len(c) == 0 {
+		return nil
+	}
+	return c[1:]
+}
+
+func (c Command) String() string {
+	return strings.Join(c, " ")
+}
+
+// Server is an in-process fake redis server.
+//
+// It listens on a random port on localhost, records all the commands it
+// received, and keeps the data in memory.
+// It only supports a subset of the redis commands (see SupportedCommands),
+// other commands fail with an error reply.
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	lock     sync.Mutex
+	closed   bool
+	conns    map[net.Conn]struct{}
+	clients  []io.Closer
+	commands []Command
+	errors   map[string]string
+	// The values are either string or map[string]string (hashes).
+	values  map[string]interface{}
+	expires map[string]time.Time
+}
+
+// NewServer starts a new Server.
+//
+// The Server and all the clients created from it will be closed when the test
+// finishes.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("redistest: failed to listen: %v", err)
+	}
+	s := &Server{
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+		errors:   make(map[string]string),
+		values:   make(map[string]interface{}),
+		expires:  make(map[string]time.Time),
+	}
+	s.wg.Add(1)
+	go s.acceptLoop()
+	tb.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+// Addr returns the address the Server is listening on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// NewClient creates a new redis.Client connecting to the Server.
+func (s *Server) NewClient() *redis.Client {
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clients = append(s.clients, client)
+	return client
+}
+
+// NewMonitoredClientFactory creates a redisbp.MonitoredCmdableFactory with a
+// new redis.Client connecting to the Server.
+func (s *Server) NewMonitoredClientFactory(name string) redisbp.MonitoredCmdableFactory {
+	return redisbp.NewMonitoredClientFactory(name, s.NewClient())
+}
+
+// Commands returns all the commands received so far, in the order they were
+// received.
+func (s *Server) Commands() []Command {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Command(nil), s.commands...)
+}
+
+// FindCommands returns all the received commands with the given name
+// (case insensitive).
+func (s *Server) FindCommands(name string) []Command {
+	name = strings.ToLower(name)
+	var commands []Command
+	for _, cmd := range s.Commands() {
+		if cmd.Name() == name {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+// SetError makes all the following commands with the given name
+// (case insensitive) fail with msg as the error reply,
+// e.g. "ERR something went wrong".
+//
+// An empty msg clears the error set on the command.
+func (s *Server) SetError(name, msg string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	name = strings.ToLower(name)
+	if msg == "" {
+		delete(s.errors, name)
+	} else {
+		s.errors[name] = msg
+	}
+}
+
+// Reset clears all the received commands, the data and the errors set by
+// SetError.
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commands = nil
+	s.errors = make(map[string]string)
+	s.flush()
+}
+
+// Close closes the Server, all the open connections,
+// and all the clients created by NewClient and NewMonitoredClientFactory.
+//
+// The received commands are still accessible after Close.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	clients := s.clients
+	s.clients = nil
+	s.lock.Unlock()
+
+	for _, client := range clients {
+		client.Close()
+	}
+	err := s.listener.Close()
+	s.lock.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.lock.Unlock()
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		writeReply(w, s.handle(cmd))
+		// Only flush after all the pipelined commands are handled.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) handle(cmd Command) interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.commands = append(s.commands, cmd)
+	name := cmd.Name()
+	if msg, ok := s.errors[name]; ok {
+		return errorReply(msg)
+	}
+	c, ok := commands[name]
+	if !ok {
+		return errorReply(fmt.Sprintf("ERR unknown command '%s'", name))
+	}
+	args := cmd.Args()
+	if len(args) < c.minArgs || (c.maxArgs >= 0 && len(args) > c.maxArgs) {
+		return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+	}
+	return c.handler(s, args)
+}
+
+// SupportedCommands returns the names of the commands supported by Server,
+// sorted.
+func SupportedCommands() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var errMalformedCommand = errors.New("redistest: malformed command")
+
+// readCommand reads a command sent by the client,
+// which is always an array of bulk strings.
+func readCommand(r *bufio.Reader) (Command, error) {
+	n, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	cmd := make(Command, n)
+	for i := range cmd {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(buf[:size])
+	}
+	return cmd, nil
+}
+
+func readLength(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) < 2 || line[0] != prefix {
+		return 0, errMalformedCommand
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return 0, errMalformedCommand
+	}
+	return n, nil
+}
@@ -0,0 +1,114 @@
+package redistest_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/redisbp/redistest"
+)
+
+func TestServer(t *testing.T) {
+	server := redistest.NewServer(t)
+	client := server.NewMonitoredClientFactory("redis").BuildClient(context.Background())
+
+	if err := client.Set("foo", "bar", time.Minute).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.Get("foo").Result(); err != nil || v != "bar" {
+		t.Errorf("Expected GET foo to be %q, got %q, %v", "bar", v, err)
+	}
+	if ttl, err := client.TTL("foo").Result(); err != nil || ttl != time.Minute {
+		t.Errorf("Expected TTL foo to be %v, got %v, %v", time.Minute, ttl, err)
+	}
+	if err := client.Get("missing").Err(); err != redis.Nil {
+		t.Errorf("Expected GET missing to return redis.Nil, got %v", err)
+	}
+	if n, err := client.Incr("counter").Result(); err != nil || n != 1 {
+		t.Errorf("Expected INCR counter to be 1, got %d, %v", n, err)
+	}
+	if err := client.HSet("hash", "a", "1").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.HSet("hash", "b", "2").Err(); err != nil {
+		t.Fatal(err)
+	}
+	expectedHash := map[string]string{"a": "1", "b": "2"}
+	if hash, err := client.HGetAll("hash").Result(); err != nil || !reflect.DeepEqual(hash, expectedHash) {
+		t.Errorf("Expected HGETALL hash to be %v, got %v, %v", expectedHash, hash, err)
+	}
+	if err := client.Get("hash").Err(); err == nil {
+		t.Error("Expected GET on a hash to fail")
+	}
+
+	expected := []string{
+		"set foo bar ex 60",
+		"get foo",
+		"ttl foo",
+		"get missing",
+		"incr counter",
+		"hset hash a 1",
+		"hset hash b 2",
+		"hgetall hash",
+		"get hash",
+	}
+	var actual []string
+	for _, cmd := range server.Commands() {
+		actual = append(actual, cmd.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected commands %q, got %q", expected, actual)
+	}
+	if cmds := server.FindCommands("GET"); len(cmds) != 3 {
+		t.Errorf("Expected 3 GET commands, got %v", cmds)
+	}
+}
+
+func TestServerPipeline(t *testing.T) {
+	server := redistest.NewServer(t)
+	client := server.NewClient()
+
+	pipe := client.Pipeline()
+	incr := pipe.Incr("counter")
+	expire := pipe.Expire("counter", time.Second)
+	if _, err := pipe.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if incr.Val() != 1 {
+		t.Errorf("Expected INCR counter to be 1, got %d", incr.Val())
+	}
+	if !expire.Val() {
+		t.Error("Expected EXPIRE counter to succeed")
+	}
+	if cmds := server.Commands(); len(cmds) != 2 {
+		t.Errorf("Expected 2 commands, got %v", cmds)
+	}
+}
+
+func TestServerSetError(t *testing.T) {
+	const msg = "ERR something went wrong"
+
+	server := redistest.NewServer(t)
+	client := server.NewClient()
+
+	server.SetError("get", msg)
+	if err := client.Get("foo").Err(); err == nil || err.Error() != msg {
+		t.Errorf("Expected error %q, got %v", msg, err)
+	}
+	server.SetError("get", "")
+	if err := client.Get("foo").Err(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil after clearing the error, got %v", err)
+	}
+
+	if err := client.Do("unsupported").Err(); err == nil {
+		t.Error("Expected unsupported commands to fail")
+	}
+
+	server.Reset()
+	if cmds := server.Commands(); len(cmds) != 0 {
+		t.Errorf("Expected no commands after Reset, got %v", cmds)
+	}
+}
@@ -0,0 +1,224 @@
+package redisbp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/batcherror"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// SecretPasswordCloseDelay is the delay SecretPasswordClient waits before
+// closing the previous redis.Client after a rotation,
+// so the requests still using it can finish.
+const SecretPasswordCloseDelay = time.Minute
+
+// SecretPasswordOnConnect returns a function to be used as the OnConnect of
+// redis.Options, redis.ClusterOptions, or redis.RingOptions,
+// which authenticates every new connection with the password from the
+// versioned secret at path in store.
+//
+// The secret is read from store for every new connection,
+// so when the secret rotates the new connections pick up the new password
+// without a deploy.
+// The versions are tried in the order of current, previous, and next,
+// so the connections can still be established during the rotation,
+// when the Redis server might not be using the current version yet.
+//
+// The connections already authenticated are not affected by the rotation,
+// use SecretPasswordClient to also recycle them when the secret rotates,
+// or set MaxConnAge in the options to recycle them periodically.
+//
+// The Password in the options should be left empty,
+// otherwise the connections are also authenticated with it before OnConnect
+// is called.
+// Example:
+//
+//     client := redis.NewClient(&redis.Options{
+//       Addr:      addr,
+//       OnConnect: redisbp.SecretPasswordOnConnect(store, "secret/myservice/redis"),
+//     })
+//     factory := redisbp.NewMonitoredClientFactory("redis", client)
+func SecretPasswordOnConnect(store *secrets.Store, path string) func(*redis.Conn) error {
+	return func(conn *redis.Conn) error {
+		secret, err := store.GetVersionedSecret(path)
+		if err != nil {
+			return fmt.Errorf("redisbp: failed to get redis password from secret %q: %w", path, err)
+		}
+		return authenticate(conn, path, secret)
+	}
+}
+
+// authenticate authenticates conn with the versions of secret in the order of
+// current, previous, and next.
+func authenticate(conn *redis.Conn, path string, secret secrets.VersionedSecret) error {
+	var errs batcherror.BatchError
+	for _, password := range secret.GetAll() {
+		err := conn.Auth(string(password)).Err()
+		if err == nil {
+			return nil
+		}
+		errs.Add(err)
+	}
+	return fmt.Errorf("redisbp: failed to authenticate with secret %q: %w", path, errs.Compile())
+}
+
+// SecretPasswordClient is a redis.Client authenticated with the password from
+// a versioned secret, which is rebuilt when the secret rotates.
+//
+// The connections are authenticated the same way as SecretPasswordOnConnect.
+// When the current version of the secret changes,
+// a new redis.Client is created from the same options,
+// so all the connections are authenticated again with the new version.
+// The previous redis.Client is closed after SecretPasswordCloseDelay.
+//
+// Use Client or NewMonitoredSecretPasswordClientFactory to get the current
+// redis.Client for every request, instead of holding onto one.
+//
+// SecretPasswordClient only supports redis.Client,
+// use SecretPasswordOnConnect with MaxConnAge for redis.ClusterClient and
+// redis.Ring.
+type SecretPasswordClient struct {
+	opts      redis.Options
+	onConnect func(*redis.Conn) error
+	path      string
+
+	client atomic.Value // *redis.Client
+
+	lock   sync.Mutex
+	secret secrets.VersionedSecret
+	hooks  []redis.Hook
+	closed bool
+}
+
+// NewSecretPasswordClient creates a SecretPasswordClient from opts,
+// authenticated with the versioned secret at path in store.
+//
+// The Password in opts should be left empty.
+// The OnConnect in opts, if any, is called after the authentication.
+//
+// It subscribes to the updates of store with store.AddMiddlewares,
+// so it must not be called concurrently with other AddMiddlewares calls.
+func NewSecretPasswordClient(opts redis.Options, store *secrets.Store, path string) (*SecretPasswordClient, error) {
+	secret, err := store.GetVersionedSecret(path)
+	if err != nil {
+		return nil, fmt.Errorf("redisbp: failed to get redis password from secret %q: %w", path, err)
+	}
+
+	c := &SecretPasswordClient{
+		opts:      opts,
+		onConnect: opts.OnConnect,
+		path:      path,
+		secret:    secret,
+	}
+	c.client.Store(c.newClient())
+	store.AddMiddlewares(c.middleware)
+	return c, nil
+}
+
+// Client returns the current redis.Client.
+func (c *SecretPasswordClient) Client() *redis.Client {
+	return c.client.Load().(*redis.Client)
+}
+
+// AddHook adds the hook to the current and the future redis.Clients.
+func (c *SecretPasswordClient) AddHook(hook redis.Hook) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.hooks = append(c.hooks, hook)
+	c.Client().AddHook(hook)
+}
+
+// Close closes the current redis.Client and stops rebuilding it.
+//
+// The previous redis.Clients are still closed after SecretPasswordCloseDelay.
+func (c *SecretPasswordClient) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	return c.Client().Close()
+}
+
+// newClient creates a new redis.Client with the hooks added,
+// authenticating its connections with c.secret.
+//
+// The secret is not read from the store,
+// as the updated secrets are only available from the store after the
+// middlewares are called.
+//
+// It must be called with c.lock held, except from NewSecretPasswordClient.
+func (c *SecretPasswordClient) newClient() *redis.Client {
+	opts := c.opts
+	secret := c.secret
+	opts.OnConnect = func(conn *redis.Conn) error {
+		if err := authenticate(conn, c.path, secret); err != nil {
+			return err
+		}
+		if c.onConnect != nil {
+			return c.onConnect(conn)
+		}
+		return nil
+	}
+	client := redis.NewClient(&opts)
+	for _, hook := range c.hooks {
+		client.AddHook(hook)
+	}
+	return client
+}
+
+func (c *SecretPasswordClient) middleware(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+	return func(sec *secrets.Secrets) {
+		defer next(sec)
+
+		secret, err := sec.GetVersionedSecret(c.path)
+		if err != nil {
+			log.Named("redisbp").Errorw(
+				"redisbp: Failed to get redis password from updated secrets",
+				"err", err,
+				"path", c.path,
+			)
+			return
+		}
+		c.rotate(secret)
+	}
+}
+
+// rotate replaces the current redis.Client with a new one when the current
+// version of the secret changes.
+func (c *SecretPasswordClient) rotate(secret secrets.VersionedSecret) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed || string(secret.Current) == string(c.secret.Current) {
+		return
+	}
+	c.secret = secret
+
+	previous := c.Client()
+	c.client.Store(c.newClient())
+	time.AfterFunc(SecretPasswordCloseDelay, func() {
+		previous.Close()
+	})
+}
+
+type monitoredSecretPasswordClient struct {
+	*SecretPasswordClient
+}
+
+func (c monitoredSecretPasswordClient) WithMonitoredContext(ctx context.Context) MonitoredCmdable {
+	return &monitoredClient{Client: c.Client().WithContext(ctx)}
+}
+
+// NewMonitoredSecretPasswordClientFactory creates a MonitoredCmdableFactory
+// for a SecretPasswordClient.
+//
+// Every client built uses the current redis.Client of the
+// SecretPasswordClient.
+func NewMonitoredSecretPasswordClientFactory(name string, client *SecretPasswordClient) MonitoredCmdableFactory {
+	return newMonitoredCmdableFactory(name, client.opts.DB, client.opts.Addr, monitoredSecretPasswordClient{client})
+}
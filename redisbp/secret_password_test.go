@@ -0,0 +1,296 @@
+package redisbp_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/redisbp"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+const redisPasswordSecret = `{
+	"secrets": {
+		"secret/redis/password": {
+			"type": "versioned",
+			"current": "%s",
+			"previous": "old"
+		}
+	},
+	"vault": {
+		"url": "vault.reddit.ue1.snooguts.net",
+		"token": "17213328-36d4-11e7-8459-525400f56d04"
+	}
+}`
+
+// fakeRedisServer is a Redis server only supporting AUTH and PING commands.
+type fakeRedisServer struct {
+	listener net.Listener
+
+	lock     sync.Mutex
+	password string
+	auths    []string
+}
+
+func newFakeRedisServer(t *testing.T, password string) *fakeRedisServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedisServer{
+		listener: listener,
+		password: password,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return s
+}
+
+func (s *fakeRedisServer) setPassword(password string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.password = password
+}
+
+func (s *fakeRedisServer) getAuths() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.auths...)
+}
+
+func (s *fakeRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	var authenticated bool
+	for {
+		args, err := readRESPArray(r)
+		if err != nil {
+			return
+		}
+		var reply string
+		switch strings.ToLower(args[0]) {
+		case "auth":
+			s.lock.Lock()
+			s.auths = append(s.auths, args[1])
+			authenticated = args[1] == s.password
+			s.lock.Unlock()
+			if authenticated {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-ERR invalid password\r\n"
+			}
+		case "ping":
+			if authenticated {
+				reply = "+PONG\r\n"
+			} else {
+				reply = "-NOAUTH Authentication required.\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// readRESPArray reads a RESP array of bulk strings, which is how the clients
+// send the commands.
+func readRESPArray(r *bufio.Reader) ([]string, error) {
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		return strings.TrimSuffix(line, "\r\n"), err
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	if _, err := fmt.Sscanf(line, "*%d", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		// Skip the size line of the bulk string.
+		if _, err := readLine(); err != nil {
+			return nil, err
+		}
+		if args[i], err = readLine(); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// newPasswordSecretsStore returns a store of a secrets file with password as
+// the current version of the password secret, and the path to the file.
+func newPasswordSecretsStore(t *testing.T, password string) (*secrets.Store, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "redisbp_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "secrets.json")
+	writePasswordSecret(t, path, password)
+	store, err := secrets.NewStore(context.Background(), path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return store, path
+}
+
+func writePasswordSecret(t *testing.T, path, password string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	content := fmt.Sprintf(redisPasswordSecret, password)
+	if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecretPasswordOnConnect(t *testing.T) {
+	server := newFakeRedisServer(t, "new")
+	store, _ := newPasswordSecretsStore(t, "new")
+
+	newClient := func() *redis.Client {
+		client := redis.NewClient(&redis.Options{
+			Addr:      server.listener.Addr().String(),
+			OnConnect: redisbp.SecretPasswordOnConnect(store, "secret/redis/password"),
+		})
+		t.Cleanup(func() {
+			client.Close()
+		})
+		return client
+	}
+
+	t.Run("current", func(t *testing.T) {
+		if err := newClient().Ping().Err(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("previous", func(t *testing.T) {
+		// The server is not updated to the current version yet.
+		server.setPassword("old")
+		defer server.setPassword("new")
+
+		if err := newClient().Ping().Err(); err != nil {
+			t.Fatal(err)
+		}
+		auths := server.getAuths()
+		if len(auths) < 2 || auths[len(auths)-2] != "new" || auths[len(auths)-1] != "old" {
+			t.Errorf("Expected to auth with current then previous versions, got %v", auths)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		server.setPassword("other")
+		defer server.setPassword("new")
+
+		if err := newClient().Ping().Err(); err == nil {
+			t.Error("Expected error when no version of the secret matches, got nil")
+		}
+	})
+}
+
+func TestSecretPasswordOnConnectMissingSecret(t *testing.T) {
+	server := newFakeRedisServer(t, "new")
+	store, _ := newPasswordSecretsStore(t, "new")
+
+	client := redis.NewClient(&redis.Options{
+		Addr:      server.listener.Addr().String(),
+		OnConnect: redisbp.SecretPasswordOnConnect(store, "secret/redis/missing"),
+	})
+	defer client.Close()
+	if err := client.Ping().Err(); err == nil {
+		t.Error("Expected error when the secret is missing, got nil")
+	}
+}
+
+func TestSecretPasswordClient(t *testing.T) {
+	server := newFakeRedisServer(t, "new")
+	store, path := newPasswordSecretsStore(t, "new")
+
+	client, err := redisbp.NewSecretPasswordClient(
+		redis.Options{Addr: server.listener.Addr().String()},
+		store,
+		"secret/redis/password",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	factory := redisbp.NewMonitoredSecretPasswordClientFactory("redis", client)
+
+	if err := factory.BuildClient(context.Background()).Ping().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the password on both the server and the secrets store.
+	previous := client.Client()
+	server.setPassword("newer")
+	writePasswordSecret(t, path, "newer")
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Client() == previous && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.Client() == previous {
+		t.Fatal("Expected the client to be rebuilt after the secret rotated")
+	}
+
+	// The pooled connection of the previous client is not reused.
+	if err := factory.BuildClient(context.Background()).Ping().Err(); err != nil {
+		t.Fatal(err)
+	}
+	auths := server.getAuths()
+	if len(auths) == 0 || auths[len(auths)-1] != "newer" {
+		t.Errorf("Expected the new connection to auth with the rotated password, got %v", auths)
+	}
+}
+
+func TestSecretPasswordClientMissingSecret(t *testing.T) {
+	store, _ := newPasswordSecretsStore(t, "new")
+
+	if _, err := redisbp.NewSecretPasswordClient(
+		redis.Options{Addr: "localhost:6379"},
+		store,
+		"secret/redis/missing",
+	); err == nil {
+		t.Error("Expected error when the secret is missing, got nil")
+	}
+}
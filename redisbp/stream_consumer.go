@@ -0,0 +1,600 @@
+package redisbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Default values used by StreamConsumer when the corresponding values in
+// StreamConsumerArgs are not set.
+const (
+	DefaultStreamConsumerWorkers       = 10
+	DefaultStreamConsumerBlock         = time.Second
+	DefaultStreamConsumerClaimInterval = 30 * time.Second
+	DefaultStreamConsumerClaimMinIdle  = time.Minute
+	DefaultStreamConsumerMaxDeliveries = 5
+	DefaultStreamConsumerMinBackoff    = 100 * time.Millisecond
+	DefaultStreamConsumerMaxBackoff    = 10 * time.Second
+)
+
+// Span tags set on the server spans created by StreamConsumer.
+const (
+	SpanTagKeyStream          = "redis.stream"
+	SpanTagKeyStreamMessageID = "redis.stream.message_id"
+	SpanTagKeyStreamClaimed   = "redis.stream.claimed"
+)
+
+// The extra fields added to the messages written to the dead-letter stream,
+// in addition to the fields of the original message.
+const (
+	DeadLetterFieldStream     = "dead-letter-stream"
+	DeadLetterFieldID         = "dead-letter-id"
+	DeadLetterFieldDeliveries = "dead-letter-deliveries"
+)
+
+// StreamClient is the subset of redis.Cmdable needed by StreamConsumer.
+//
+// XINFO GROUPS is sent via Do and its raw reply is parsed by StreamConsumer.
+//
+// *redis.Client, *redis.ClusterClient, and *redis.Ring all implement it.
+type StreamClient interface {
+	XReadGroup(a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XPendingExt(a *redis.XPendingExtArgs) *redis.XPendingExtCmd
+	XClaim(a *redis.XClaimArgs) *redis.XMessageSliceCmd
+	XAck(stream, group string, ids ...string) *redis.IntCmd
+	XAdd(a *redis.XAddArgs) *redis.StringCmd
+	Do(args ...interface{}) *redis.Cmd
+}
+
+var (
+	_ StreamClient = (*redis.Client)(nil)
+	_ StreamClient = (*redis.ClusterClient)(nil)
+	_ StreamClient = (*redis.Ring)(nil)
+)
+
+// StreamHandler handles a single message read from a Redis stream.
+//
+// The context passed in has the server span of the message attached.
+//
+// The message is acknowledged when StreamHandler returns nil.
+// When it returns an error, the message stays pending and will be claimed and
+// delivered again after StreamConsumerArgs.ClaimMinIdle,
+// until it's delivered StreamConsumerArgs.MaxDeliveries times.
+// Panics in StreamHandler are recovered and handled the same way as errors,
+// and counted as "<Name>.panic" with metricsbp.M.
+type StreamHandler func(ctx context.Context, stream string, msg redis.XMessage) error
+
+// StreamConsumerArgs are the args used to create a new StreamConsumer.
+type StreamConsumerArgs struct {
+	// Required. The Baseplate the StreamConsumer is built on.
+	Baseplate baseplate.Baseplate
+
+	// Required. The client used to read the streams.
+	Client StreamClient
+
+	// Required. The streams to consume.
+	Streams []string
+
+	// Required. The consumer group and the name of this consumer in the group.
+	//
+	// The consumer group must be already created on all the streams,
+	// e.g. via "XGROUP CREATE <stream> <group> $ MKSTREAM".
+	Group    string
+	Consumer string
+
+	// Required. Name is used as the name of the server spans and the prefix of
+	// the metrics.
+	Name string
+
+	// Required. The handler to handle every message.
+	Handler StreamHandler
+
+	// Workers is the number of goroutines handling the messages concurrently.
+	//
+	// Optional, defaults to DefaultStreamConsumerWorkers.
+	Workers int
+
+	// Count is the max number of messages read by every XREADGROUP and XPENDING
+	// call.
+	//
+	// Optional, defaults to Workers.
+	Count int64
+
+	// Block is the time every XREADGROUP call blocks waiting for new messages.
+	// It's also the max time Close waits for the in-flight XREADGROUP call.
+	//
+	// Optional, defaults to DefaultStreamConsumerBlock.
+	Block time.Duration
+
+	// ClaimInterval is how often the pending messages are checked,
+	// and the lag gauges are reported.
+	//
+	// Optional, defaults to DefaultStreamConsumerClaimInterval.
+	ClaimInterval time.Duration
+
+	// ClaimMinIdle is the time a pending message has to be idle before it's
+	// claimed by this consumer and delivered again.
+	//
+	// Optional, defaults to DefaultStreamConsumerClaimMinIdle.
+	ClaimMinIdle time.Duration
+
+	// MaxDeliveries is the max number of times a message is delivered.
+	// A pending message already delivered MaxDeliveries times is
+	// acknowledged without being handled again,
+	// after being added to DeadLetterStream if it's set.
+	//
+	// Optional, defaults to DefaultStreamConsumerMaxDeliveries.
+	MaxDeliveries int64
+
+	// DeadLetterStream is the stream the messages exceeding MaxDeliveries are
+	// added to, with the DeadLetterField* fields added.
+	//
+	// Optional. When it's empty such messages are dropped.
+	DeadLetterStream string
+
+	// MinBackoff and MaxBackoff control the exponential backoff after failed
+	// XREADGROUP calls.
+	//
+	// Optional, default to DefaultStreamConsumerMinBackoff and
+	// DefaultStreamConsumerMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Validate checks StreamConsumerArgs for any missing or erroneous values.
+func (args StreamConsumerArgs) Validate() error {
+	switch {
+	case args.Baseplate == nil:
+		return errors.New("redisbp: Baseplate is required")
+	case args.Client == nil:
+		return errors.New("redisbp: Client is required")
+	case len(args.Streams) == 0:
+		return errors.New("redisbp: Streams is required")
+	case args.Group == "":
+		return errors.New("redisbp: Group is required")
+	case args.Consumer == "":
+		return errors.New("redisbp: Consumer is required")
+	case args.Name == "":
+		return errors.New("redisbp: Name is required")
+	case args.Handler == nil:
+		return errors.New("redisbp: Handler is required")
+	}
+	return nil
+}
+
+type streamMessage struct {
+	stream  string
+	msg     redis.XMessage
+	claimed bool
+}
+
+// StreamConsumer is a baseplate.Server consuming Redis streams as a member of
+// a consumer group, with a pool of goroutines.
+//
+// Besides reading new messages with XREADGROUP,
+// it periodically claims the messages left pending for longer than
+// ClaimMinIdle by any consumer in the group (including itself, e.g. when the
+// handler failed) and delivers them again,
+// until they are delivered MaxDeliveries times.
+//
+// It reports the following metrics, labeled by stream and group:
+//
+//     <Name>.pending: gauge of the number of pending messages of the group.
+//     <Name>.lag: gauge of the number of messages not yet delivered to the
+//       group, only reported by Redis 7.0+.
+//     <Name>.dead-letters: counter of the messages exceeding MaxDeliveries.
+type StreamConsumer struct {
+	args StreamConsumerArgs
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	msgs    chan streamMessage
+	serving sync.WaitGroup
+	workers sync.WaitGroup
+
+	// closed is set by Close with lock held,
+	// so Serve never starts after Close.
+	lock   sync.Mutex
+	closed bool
+}
+
+var _ baseplate.Server = (*StreamConsumer)(nil)
+
+// NewStreamConsumer creates a new StreamConsumer.
+func NewStreamConsumer(args StreamConsumerArgs) (*StreamConsumer, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	if args.Workers <= 0 {
+		args.Workers = DefaultStreamConsumerWorkers
+	}
+	if args.Count <= 0 {
+		args.Count = int64(args.Workers)
+	}
+	if args.Block <= 0 {
+		args.Block = DefaultStreamConsumerBlock
+	}
+	if args.ClaimInterval <= 0 {
+		args.ClaimInterval = DefaultStreamConsumerClaimInterval
+	}
+	if args.ClaimMinIdle <= 0 {
+		args.ClaimMinIdle = DefaultStreamConsumerClaimMinIdle
+	}
+	if args.MaxDeliveries <= 0 {
+		args.MaxDeliveries = DefaultStreamConsumerMaxDeliveries
+	}
+	if args.MinBackoff <= 0 {
+		args.MinBackoff = DefaultStreamConsumerMinBackoff
+	}
+	if args.MaxBackoff <= 0 {
+		args.MaxBackoff = DefaultStreamConsumerMaxBackoff
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamConsumer{
+		args:   args,
+		ctx:    ctx,
+		cancel: cancel,
+		msgs:   make(chan streamMessage, args.Workers),
+	}, nil
+}
+
+// Baseplate implements baseplate.Server.
+func (c *StreamConsumer) Baseplate() baseplate.Baseplate {
+	return c.args.Baseplate
+}
+
+// Serve implements baseplate.Server.
+//
+// It blocks until Close is called.
+// It returns nil immediately if Close was called before Serve.
+func (c *StreamConsumer) Serve() error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.serving.Add(2)
+	c.lock.Unlock()
+	defer c.serving.Done()
+
+	for i := 0; i < c.args.Workers; i++ {
+		c.workers.Add(1)
+		go c.work()
+	}
+	go c.claimLoop()
+
+	streams := make([]string, 0, len(c.args.Streams)*2)
+	streams = append(streams, c.args.Streams...)
+	for range c.args.Streams {
+		// Only the messages never delivered to other consumers.
+		streams = append(streams, ">")
+	}
+	backoff := c.args.MinBackoff
+	for c.ctx.Err() == nil {
+		results, err := c.args.Client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    c.args.Group,
+			Consumer: c.args.Consumer,
+			Streams:  streams,
+			Count:    c.args.Count,
+			Block:    c.args.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			// Timed out without new messages.
+			continue
+		}
+		if err != nil {
+			log.Named("redisbp").Warnw(
+				"redisbp: XREADGROUP failed, retrying",
+				"err", err,
+				"name", c.args.Name,
+				"backoff", backoff,
+			)
+			if !c.sleep(backoff) {
+				return nil
+			}
+			backoff *= 2
+			if backoff > c.args.MaxBackoff {
+				backoff = c.args.MaxBackoff
+			}
+			continue
+		}
+		backoff = c.args.MinBackoff
+		for _, result := range results {
+			for _, msg := range result.Messages {
+				// Still hand the already read messages to the workers even after
+				// Close is called, as they drain the channel before exiting.
+				c.msgs <- streamMessage{stream: result.Stream, msg: msg}
+			}
+		}
+	}
+	return nil
+}
+
+// sleep sleeps for d, or returns false if the consumer is closed before that.
+func (c *StreamConsumer) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-c.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (c *StreamConsumer) claimLoop() {
+	defer c.serving.Done()
+
+	for {
+		for _, stream := range c.args.Streams {
+			c.claim(stream)
+			c.reportGauges(stream)
+		}
+		if !c.sleep(c.args.ClaimInterval) {
+			return
+		}
+	}
+}
+
+// claim claims the idle pending messages of the stream,
+// and either delivers them again or moves them to the dead-letter stream.
+func (c *StreamConsumer) claim(stream string) {
+	entries, err := c.args.Client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  c.args.Group,
+		Start:  "-",
+		End:    "+",
+		Count:  c.args.Count,
+	}).Result()
+	if err != nil {
+		log.Named("redisbp").Warnw(
+			"redisbp: XPENDING failed",
+			"err", err,
+			"name", c.args.Name,
+			"stream", stream,
+		)
+		return
+	}
+
+	deliveries := make(map[string]int64, len(entries))
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Idle < c.args.ClaimMinIdle {
+			continue
+		}
+		deliveries[entry.ID] = entry.RetryCount
+		ids = append(ids, entry.ID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// XCLAIM checks the min idle time again,
+	// so only one consumer in the group claims every message.
+	msgs, err := c.args.Client.XClaim(&redis.XClaimArgs{
+		Stream:   stream,
+		Group:    c.args.Group,
+		Consumer: c.args.Consumer,
+		MinIdle:  c.args.ClaimMinIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		log.Named("redisbp").Warnw(
+			"redisbp: XCLAIM failed",
+			"err", err,
+			"name", c.args.Name,
+			"stream", stream,
+		)
+		return
+	}
+	for _, msg := range msgs {
+		if n := deliveries[msg.ID]; n >= c.args.MaxDeliveries {
+			c.deadLetter(stream, msg, n)
+			continue
+		}
+		select {
+		case c.msgs <- streamMessage{stream: stream, msg: msg, claimed: true}:
+		case <-c.ctx.Done():
+			// The claimed messages not handed to the workers will be claimed again
+			// after ClaimMinIdle.
+			return
+		}
+	}
+}
+
+func (c *StreamConsumer) deadLetter(stream string, msg redis.XMessage, deliveries int64) {
+	metricsbp.M.Counter(c.args.Name+".dead-letters").With(
+		"stream", stream,
+		"group", c.args.Group,
+	).Add(1)
+
+	if c.args.DeadLetterStream != "" {
+		values := make(map[string]interface{}, len(msg.Values)+3)
+		for k, v := range msg.Values {
+			values[k] = v
+		}
+		values[DeadLetterFieldStream] = stream
+		values[DeadLetterFieldID] = msg.ID
+		values[DeadLetterFieldDeliveries] = deliveries
+		if err := c.args.Client.XAdd(&redis.XAddArgs{
+			Stream: c.args.DeadLetterStream,
+			Values: values,
+		}).Err(); err != nil {
+			// Don't ack it so it will be retried on the next claim.
+			log.Named("redisbp").Errorw(
+				"redisbp: Failed to add message to dead-letter stream",
+				"err", err,
+				"name", c.args.Name,
+				"stream", stream,
+				"id", msg.ID,
+			)
+			return
+		}
+	} else {
+		log.Named("redisbp").Warnw(
+			"redisbp: Dropping message exceeding max deliveries",
+			"name", c.args.Name,
+			"stream", stream,
+			"id", msg.ID,
+			"deliveries", deliveries,
+		)
+	}
+	c.ack(stream, msg.ID)
+}
+
+func (c *StreamConsumer) reportGauges(stream string) {
+	reply, err := c.args.Client.Do("XINFO", "GROUPS", stream).Result()
+	if err != nil {
+		log.Named("redisbp").Warnw(
+			"redisbp: XINFO GROUPS failed",
+			"err", err,
+			"name", c.args.Name,
+			"stream", stream,
+		)
+		return
+	}
+	info, ok := findXInfoGroup(reply, c.args.Group)
+	if !ok {
+		return
+	}
+	if pending, ok := info["pending"].(int64); ok {
+		metricsbp.M.Gauge(c.args.Name+".pending").With(
+			"stream", stream,
+			"group", c.args.Group,
+		).Set(float64(pending))
+	}
+	// "lag" is only available on Redis 7.0+, and could be nil when unknown.
+	if lag, ok := info["lag"].(int64); ok {
+		metricsbp.M.Gauge(c.args.Name+".lag").With(
+			"stream", stream,
+			"group", c.args.Group,
+		).Set(float64(lag))
+	}
+}
+
+func (c *StreamConsumer) work() {
+	defer c.workers.Done()
+
+	for msg := range c.msgs {
+		c.handle(msg)
+	}
+}
+
+func (c *StreamConsumer) handle(msg streamMessage) {
+	span := tracing.AsSpan(opentracing.StartSpan(
+		c.args.Name,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeServer},
+	))
+	span.SetTag(SpanTagKeyStream, msg.stream)
+	span.SetTag(SpanTagKeyStreamMessageID, msg.msg.ID)
+	if msg.claimed {
+		span.SetTag(SpanTagKeyStreamClaimed, true)
+	}
+
+	// Don't let Close cancel an in-flight message.
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	ctx = span.InjectSentryHub(ctx)
+
+	err := c.callHandler(ctx, msg)
+	if err != nil {
+		log.Named("redisbp").Errorw(
+			"redisbp: Failed to handle stream message",
+			"err", err,
+			"name", c.args.Name,
+			"stream", msg.stream,
+			"id", msg.msg.ID,
+		)
+	} else {
+		c.ack(msg.stream, msg.msg.ID)
+	}
+	span.Stop(ctx, err)
+}
+
+// callHandler calls the handler of the consumer
+// and converts the panics in it into errors,
+// so the message stays pending to be claimed again.
+func (c *StreamConsumer) callHandler(ctx context.Context, msg streamMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Named("redisbp").Errorw(
+				"redisbp: Recovered from panic in stream handler",
+				"panic", r,
+				"stack", string(debug.Stack()),
+				"name", c.args.Name,
+				"stream", msg.stream,
+				"id", msg.msg.ID,
+			)
+			metricsbp.M.Counter(c.args.Name+".panic").With(
+				"stream", msg.stream,
+				"group", c.args.Group,
+			).Add(1)
+			err = fmt.Errorf("redisbp: recovered from panic in stream handler: %v", r)
+		}
+	}()
+	return c.args.Handler(ctx, msg.stream, msg.msg)
+}
+
+func (c *StreamConsumer) ack(stream, id string) {
+	if err := c.args.Client.XAck(stream, c.args.Group, id).Err(); err != nil {
+		log.Named("redisbp").Errorw(
+			"redisbp: XACK failed",
+			"err", err,
+			"name", c.args.Name,
+			"stream", stream,
+			"id", id,
+		)
+	}
+}
+
+// Close implements baseplate.Server.
+//
+// It stops reading new messages and waits for the read messages to be handled.
+// It could block for up to StreamConsumerArgs.Block waiting for the in-flight
+// XREADGROUP call.
+func (c *StreamConsumer) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	c.cancel()
+	c.serving.Wait()
+	// Nothing sends to msgs after Serve and claimLoop returned.
+	close(c.msgs)
+	c.workers.Wait()
+	return nil
+}
+
+// findXInfoGroup finds the group from the raw reply of
+// "XINFO GROUPS <stream>", as a map of its fields.
+func findXInfoGroup(reply interface{}, group string) (map[string]interface{}, bool) {
+	items, _ := reply.([]interface{})
+	for _, item := range items {
+		fields, _ := item.([]interface{})
+		info := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if key, ok := fields[i].(string); ok {
+				info[key] = fields[i+1]
+			}
+		}
+		if info["name"] == group {
+			return info, true
+		}
+	}
+	return nil, false
+}
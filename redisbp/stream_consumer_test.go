@@ -0,0 +1,409 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/redisbp"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+// fakeStreamClient returns reads in order from XREADGROUP,
+// pending from the first XPENDING call,
+// and groups for the raw XINFO GROUPS calls.
+type fakeStreamClient struct {
+	lock    sync.Mutex
+	reads   [][]redis.XStream
+	pending []redis.XPendingExt
+	groups  []interface{}
+	claimed map[string]redis.XMessage
+	acked   []string
+	added   []*redis.XAddArgs
+}
+
+func (c *fakeStreamClient) XReadGroup(a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.reads) == 0 {
+		c.lock.Unlock()
+		time.Sleep(time.Millisecond)
+		c.lock.Lock()
+		return redis.NewXStreamSliceCmdResult(nil, redis.Nil)
+	}
+	read := c.reads[0]
+	c.reads = c.reads[1:]
+	return redis.NewXStreamSliceCmdResult(read, nil)
+}
+
+func (c *fakeStreamClient) XPendingExt(a *redis.XPendingExtArgs) *redis.XPendingExtCmd {
+	c.lock.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.lock.Unlock()
+
+	return newXPendingExtCmd(a, pending)
+}
+
+// newXPendingExtCmd returns the XPendingExtCmd of the entries,
+// by having go-redis parse the reply of a fake connection,
+// as there's no way to create one with the value directly.
+func newXPendingExtCmd(a *redis.XPendingExtArgs, entries []redis.XPendingExt) *redis.XPendingExtCmd {
+	var reply strings.Builder
+	fmt.Fprintf(&reply, "*%d\r\n", len(entries))
+	for _, entry := range entries {
+		fmt.Fprintf(
+			&reply,
+			"*4\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n:%d\r\n:%d\r\n",
+			len(entry.ID),
+			entry.ID,
+			len(entry.Consumer),
+			entry.Consumer,
+			entry.Idle.Milliseconds(),
+			entry.RetryCount,
+		)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			server, conn := net.Pipe()
+			go func() {
+				defer server.Close()
+				// Discard the command and reply.
+				if _, err := server.Read(make([]byte, 1024)); err != nil {
+					return
+				}
+				io.WriteString(server, reply.String())
+			}()
+			return conn, nil
+		},
+	})
+	defer client.Close()
+	return client.XPendingExt(a)
+}
+
+func (c *fakeStreamClient) XClaim(a *redis.XClaimArgs) *redis.XMessageSliceCmd {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var msgs []redis.XMessage
+	for _, id := range a.Messages {
+		if msg, ok := c.claimed[id]; ok {
+			msgs = append(msgs, msg)
+			delete(c.claimed, id)
+		}
+	}
+	return redis.NewXMessageSliceCmdResult(msgs, nil)
+}
+
+func (c *fakeStreamClient) XAck(stream, group string, ids ...string) *redis.IntCmd {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.acked = append(c.acked, ids...)
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+func (c *fakeStreamClient) XAdd(a *redis.XAddArgs) *redis.StringCmd {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.added = append(c.added, a)
+	return redis.NewStringResult("1-0", nil)
+}
+
+func (c *fakeStreamClient) Do(args ...interface{}) *redis.Cmd {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch args[0] {
+	case "XINFO":
+		return redis.NewCmdResult(c.groups, nil)
+	}
+	return redis.NewCmdResult(nil, errors.New("unknown command"))
+}
+
+func (c *fakeStreamClient) snapshot() (acked []string, added []*redis.XAddArgs) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string(nil), c.acked...), append([]*redis.XAddArgs(nil), c.added...)
+}
+
+func TestStreamConsumer(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+	metrics := metricstest.Replace(t)
+
+	client := &fakeStreamClient{
+		reads: [][]redis.XStream{
+			{
+				{
+					Stream: "stream",
+					Messages: []redis.XMessage{
+						{ID: "1-0", Values: map[string]interface{}{"key": "ok"}},
+						{ID: "2-0", Values: map[string]interface{}{"key": "fail"}},
+					},
+				},
+			},
+		},
+		pending: []redis.XPendingExt{
+			{ID: "3-0", Consumer: "other", Idle: 2 * time.Second, RetryCount: 1},
+			{ID: "4-0", Consumer: "other", Idle: 2 * time.Second, RetryCount: 3},
+			// Not idle long enough to be claimed.
+			{ID: "5-0", Consumer: "other", Idle: 10 * time.Millisecond, RetryCount: 1},
+		},
+		groups: []interface{}{
+			[]interface{}{
+				"name", "other-group",
+				"pending", int64(100),
+			},
+			[]interface{}{
+				"name", "group",
+				"consumers", int64(2),
+				"pending", int64(5),
+				"last-delivered-id", "2-0",
+				"entries-read", int64(2),
+				"lag", int64(7),
+			},
+		},
+		claimed: map[string]redis.XMessage{
+			"3-0": {ID: "3-0", Values: map[string]interface{}{"key": "ok"}},
+			"4-0": {ID: "4-0", Values: map[string]interface{}{"key": "dead"}},
+		},
+	}
+
+	handled := make(chan string, 10)
+	consumer, err := redisbp.NewStreamConsumer(redisbp.StreamConsumerArgs{
+		Baseplate:        fakeBaseplate{},
+		Client:           client,
+		Streams:          []string{"stream"},
+		Group:            "group",
+		Consumer:         "consumer",
+		Name:             "consumer",
+		Workers:          2,
+		ClaimMinIdle:     time.Second,
+		MaxDeliveries:    3,
+		DeadLetterStream: "dead",
+		Handler: func(ctx context.Context, stream string, msg redis.XMessage) error {
+			defer func() {
+				handled <- msg.ID
+			}()
+			if msg.Values["key"] == "fail" {
+				return errors.New("handler error")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error)
+	go func() {
+		served <- consumer.Serve()
+	}()
+
+	handledIDs := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		select {
+		case id := <-handled:
+			handledIDs[id] = true
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for messages to be handled")
+		}
+	}
+	if err := consumer.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned error: %v", err)
+	}
+
+	for _, id := range []string{"1-0", "2-0", "3-0"} {
+		if !handledIDs[id] {
+			t.Errorf("Expected message %q to be handled, got %v", id, handledIDs)
+		}
+	}
+
+	acked, added := client.snapshot()
+	ackedIDs := make(map[string]bool)
+	for _, id := range acked {
+		ackedIDs[id] = true
+	}
+	if len(ackedIDs) != 3 || !ackedIDs["1-0"] || !ackedIDs["3-0"] || !ackedIDs["4-0"] {
+		t.Errorf("Expected messages 1-0, 3-0, 4-0 to be acked, got %v", acked)
+	}
+
+	if len(added) != 1 {
+		t.Fatalf("Expected 1 message added to the dead-letter stream, got %+v", added)
+	}
+	if added[0].Stream != "dead" {
+		t.Errorf("Expected dead-letter stream %q, got %q", "dead", added[0].Stream)
+	}
+	for k, expected := range map[string]interface{}{
+		"key":                             "dead",
+		redisbp.DeadLetterFieldStream:     "stream",
+		redisbp.DeadLetterFieldID:         "4-0",
+		redisbp.DeadLetterFieldDeliveries: int64(3),
+	} {
+		if actual := added[0].Values[k]; actual != expected {
+			t.Errorf("Expected dead-letter field %q to be %v, got %v", k, expected, actual)
+		}
+	}
+
+	metrics.AssertCounterEquals(t, "consumer.dead-letters,stream=stream,group=group", 1)
+	metrics.AssertGaugeEquals(t, "consumer.pending,stream=stream,group=group", 5)
+	metrics.AssertGaugeEquals(t, "consumer.lag,stream=stream,group=group", 7)
+
+	spans := recorder.FindSpans("consumer")
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %+v", spans)
+	}
+	for _, span := range spans {
+		id, _ := span.Tag(redisbp.SpanTagKeyStreamMessageID)
+		if v, _ := span.Tag(redisbp.SpanTagKeyStream); v != "stream" {
+			t.Errorf("Expected tag %q to be %q, got %v", redisbp.SpanTagKeyStream, "stream", v)
+		}
+		if errored := id == "2-0"; span.IsError() != errored {
+			t.Errorf("Expected span of message %v to be errored %v", id, errored)
+		}
+		if _, claimed := span.Tag(redisbp.SpanTagKeyStreamClaimed); claimed != (id == "3-0") {
+			t.Errorf("Unexpected claimed tag on span of message %v", id)
+		}
+	}
+}
+
+func TestStreamConsumerPanic(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+	metrics := metricstest.Replace(t)
+
+	client := &fakeStreamClient{
+		reads: [][]redis.XStream{
+			{
+				{
+					Stream: "stream",
+					Messages: []redis.XMessage{
+						{ID: "1-0", Values: map[string]interface{}{"key": "panic"}},
+						{ID: "2-0", Values: map[string]interface{}{"key": "ok"}},
+					},
+				},
+			},
+		},
+	}
+
+	handled := make(chan string, 10)
+	consumer, err := redisbp.NewStreamConsumer(redisbp.StreamConsumerArgs{
+		Baseplate: fakeBaseplate{},
+		Client:    client,
+		Streams:   []string{"stream"},
+		Group:     "group",
+		Consumer:  "consumer",
+		Name:      "consumer",
+		// A single worker handles both messages, so it must survive the panic.
+		Workers: 1,
+		Handler: func(ctx context.Context, stream string, msg redis.XMessage) error {
+			handled <- msg.ID
+			if msg.Values["key"] == "panic" {
+				panic("handler panic")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error)
+	go func() {
+		served <- consumer.Serve()
+	}()
+
+	for _, expected := range []string{"1-0", "2-0"} {
+		select {
+		case id := <-handled:
+			if id != expected {
+				t.Errorf("Expected message %q to be handled, got %q", expected, id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for message %q to be handled", expected)
+		}
+	}
+	if err := consumer.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned error: %v", err)
+	}
+
+	// The message the handler panicked on stays pending.
+	acked, _ := client.snapshot()
+	if len(acked) != 1 || acked[0] != "2-0" {
+		t.Errorf("Expected only message 2-0 to be acked, got %v", acked)
+	}
+
+	metrics.AssertCounterEquals(t, "consumer.panic,stream=stream,group=group", 1)
+
+	spans := recorder.FindSpans("consumer")
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %+v", spans)
+	}
+	for _, span := range spans {
+		id, _ := span.Tag(redisbp.SpanTagKeyStreamMessageID)
+		if errored := id == "1-0"; span.IsError() != errored {
+			t.Errorf("Expected span of message %v to be errored %v", id, errored)
+		}
+	}
+}
+
+func TestStreamConsumerCloseBeforeServe(t *testing.T) {
+	consumer, err := redisbp.NewStreamConsumer(redisbp.StreamConsumerArgs{
+		Baseplate: fakeBaseplate{},
+		Client:    &fakeStreamClient{},
+		Streams:   []string{"stream"},
+		Group:     "group",
+		Consumer:  "consumer",
+		Name:      "consumer",
+		Handler: func(ctx context.Context, stream string, msg redis.XMessage) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := consumer.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+
+	served := make(chan error)
+	go func() {
+		served <- consumer.Serve()
+	}()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Serve to return immediately after Close")
+	}
+}
+
+func TestStreamConsumerValidate(t *testing.T) {
+	_, err := redisbp.NewStreamConsumer(redisbp.StreamConsumerArgs{
+		Baseplate: fakeBaseplate{},
+		Client:    &fakeStreamClient{},
+		Name:      "consumer",
+	})
+	if err == nil {
+		t.Error("Expected error for missing Streams, Group, Consumer and Handler, got nil")
+	}
+}
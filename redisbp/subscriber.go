@@ -0,0 +1,312 @@
+package redisbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Default values used by SubscriberGroup when the corresponding values in
+// SubscriberGroupArgs are not set.
+const (
+	DefaultSubscriberWorkers    = 10
+	DefaultSubscriberMinBackoff = 100 * time.Millisecond
+	DefaultSubscriberMaxBackoff = 10 * time.Second
+)
+
+// Span tags set on the server spans created by SubscriberGroup.
+const (
+	SpanTagKeyPubSubChannel = "redis.channel"
+	SpanTagKeyPubSubPattern = "redis.pattern"
+)
+
+// PubSubReceiver is the minimal interface of a Pub/Sub subscription needed by
+// SubscriberGroup.
+//
+// *redis.PubSub implements it.
+type PubSubReceiver interface {
+	io.Closer
+
+	ReceiveMessage() (*redis.Message, error)
+}
+
+var _ PubSubReceiver = (*redis.PubSub)(nil)
+
+// PubSubHandler handles a single message received from Redis Pub/Sub.
+//
+// The context passed in has the server span of the message attached.
+// Errors returned by PubSubHandler are logged and attached to the server span.
+// Panics in PubSubHandler are recovered and handled the same way as errors,
+// and counted as "<Name>.panic" with metricsbp.M.
+type PubSubHandler func(ctx context.Context, msg *redis.Message) error
+
+// SubscriberGroupArgs are the args used to create a new SubscriberGroup.
+type SubscriberGroupArgs struct {
+	// Required. The Baseplate the SubscriberGroup is built on.
+	Baseplate baseplate.Baseplate
+
+	// Required. Subscribe creates a new subscription,
+	// it's called again to reconnect after the previous subscription failed.
+	//
+	// For example:
+	//
+	//     Subscribe: func() redisbp.PubSubReceiver {
+	//       return client.Subscribe("channel1", "channel2")
+	//     },
+	Subscribe func() PubSubReceiver
+
+	// Required. Name is used as the name of the server spans.
+	Name string
+
+	// Required. The handler to handle every message.
+	Handler PubSubHandler
+
+	// Workers is the number of goroutines handling the messages concurrently.
+	//
+	// Optional, defaults to DefaultSubscriberWorkers.
+	Workers int
+
+	// MinBackoff and MaxBackoff control the exponential backoff between
+	// reconnections.
+	//
+	// Optional, default to DefaultSubscriberMinBackoff and
+	// DefaultSubscriberMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Validate checks SubscriberGroupArgs for any missing or erroneous values.
+func (args SubscriberGroupArgs) Validate() error {
+	switch {
+	case args.Baseplate == nil:
+		return errors.New("redisbp: Baseplate is required")
+	case args.Subscribe == nil:
+		return errors.New("redisbp: Subscribe is required")
+	case args.Name == "":
+		return errors.New("redisbp: Name is required")
+	case args.Handler == nil:
+		return errors.New("redisbp: Handler is required")
+	}
+	return nil
+}
+
+// SubscriberGroup is a baseplate.Server consuming Redis Pub/Sub messages with a
+// pool of goroutines.
+//
+// Serve keeps reconnecting with backoff when the subscription fails,
+// until Close is called.
+// Close stops receiving new messages, closes the subscription,
+// and waits for the received messages to be handled.
+type SubscriberGroup struct {
+	args SubscriberGroupArgs
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock sync.Mutex
+	sub  PubSubReceiver
+
+	msgs      chan *redis.Message
+	serving   sync.WaitGroup
+	workers   sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+var _ baseplate.Server = (*SubscriberGroup)(nil)
+
+// NewSubscriberGroup creates a new SubscriberGroup.
+func NewSubscriberGroup(args SubscriberGroupArgs) (*SubscriberGroup, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	if args.Workers <= 0 {
+		args.Workers = DefaultSubscriberWorkers
+	}
+	if args.MinBackoff <= 0 {
+		args.MinBackoff = DefaultSubscriberMinBackoff
+	}
+	if args.MaxBackoff <= 0 {
+		args.MaxBackoff = DefaultSubscriberMaxBackoff
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SubscriberGroup{
+		args:   args,
+		ctx:    ctx,
+		cancel: cancel,
+		msgs:   make(chan *redis.Message, args.Workers),
+	}, nil
+}
+
+// Baseplate implements baseplate.Server.
+func (g *SubscriberGroup) Baseplate() baseplate.Baseplate {
+	return g.args.Baseplate
+}
+
+// Serve implements baseplate.Server.
+//
+// It blocks until Close is called.
+func (g *SubscriberGroup) Serve() error {
+	g.serving.Add(1)
+	defer g.serving.Done()
+
+	for i := 0; i < g.args.Workers; i++ {
+		g.workers.Add(1)
+		go g.work()
+	}
+
+	backoff := g.args.MinBackoff
+	for {
+		sub, ok := g.subscribe()
+		if !ok {
+			return nil
+		}
+		received, err := g.receive(sub)
+		sub.Close()
+		if g.ctx.Err() != nil {
+			return nil
+		}
+		if received {
+			backoff = g.args.MinBackoff
+		}
+		log.Named("redisbp").Warnw(
+			"redisbp: Pub/Sub subscription failed, reconnecting",
+			"err", err,
+			"name", g.args.Name,
+			"backoff", backoff,
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-g.ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > g.args.MaxBackoff {
+			backoff = g.args.MaxBackoff
+		}
+	}
+}
+
+// subscribe creates a new subscription,
+// or returns false if the group is already closed.
+func (g *SubscriberGroup) subscribe() (PubSubReceiver, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.ctx.Err() != nil {
+		return nil, false
+	}
+	g.sub = g.args.Subscribe()
+	return g.sub, true
+}
+
+// receive dispatches the messages from sub to the workers until it fails.
+//
+// It returns true if any message was received.
+func (g *SubscriberGroup) receive(sub PubSubReceiver) (received bool, err error) {
+	for {
+		msg, err := sub.ReceiveMessage()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		select {
+		case g.msgs <- msg:
+		case <-g.ctx.Done():
+			// Still hand the already received message to the workers,
+			// as they drain the channel before exiting.
+			g.msgs <- msg
+			return received, nil
+		}
+	}
+}
+
+func (g *SubscriberGroup) work() {
+	defer g.workers.Done()
+
+	for msg := range g.msgs {
+		g.handle(msg)
+	}
+}
+
+func (g *SubscriberGroup) handle(msg *redis.Message) {
+	span := tracing.AsSpan(opentracing.StartSpan(
+		g.args.Name,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeServer},
+	))
+	span.SetTag(SpanTagKeyPubSubChannel, msg.Channel)
+	if msg.Pattern != "" {
+		span.SetTag(SpanTagKeyPubSubPattern, msg.Pattern)
+	}
+
+	// Don't let Close cancel an in-flight message.
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	ctx = span.InjectSentryHub(ctx)
+
+	err := g.callHandler(ctx, msg)
+	if err != nil {
+		log.Named("redisbp").Errorw(
+			"redisbp: Failed to handle Pub/Sub message",
+			"err", err,
+			"name", g.args.Name,
+			"channel", msg.Channel,
+		)
+	}
+	span.Stop(ctx, err)
+}
+
+// callHandler calls the handler of the group
+// and converts the panics in it into errors.
+func (g *SubscriberGroup) callHandler(ctx context.Context, msg *redis.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Named("redisbp").Errorw(
+				"redisbp: Recovered from panic in Pub/Sub handler",
+				"panic", r,
+				"stack", string(debug.Stack()),
+				"name", g.args.Name,
+				"channel", msg.Channel,
+			)
+			metricsbp.M.Counter(g.args.Name + ".panic").Add(1)
+			err = fmt.Errorf("redisbp: recovered from panic in Pub/Sub handler: %v", r)
+		}
+	}()
+	return g.args.Handler(ctx, msg)
+}
+
+// Close implements baseplate.Server.
+//
+// It stops receiving new messages and waits for the received messages to be
+// handled.
+func (g *SubscriberGroup) Close() error {
+	g.closeOnce.Do(func() {
+		g.lock.Lock()
+		g.cancel()
+		sub := g.sub
+		g.lock.Unlock()
+
+		if sub != nil {
+			// Unblocks the ReceiveMessage call in Serve.
+			g.closeErr = sub.Close()
+		}
+		g.serving.Wait()
+		close(g.msgs)
+		g.workers.Wait()
+	})
+	return g.closeErr
+}
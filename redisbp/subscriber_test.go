@@ -0,0 +1,249 @@
+package redisbp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	baseplate "github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/redisbp"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+var errSubscriptionClosed = errors.New("subscription closed")
+
+// fakePubSub returns the messages from the channel until it's closed,
+// or it fails with err once the channel is drained.
+type fakePubSub struct {
+	messages chan *redis.Message
+	err      error
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newFakePubSub(err error, messages ...*redis.Message) *fakePubSub {
+	ch := make(chan *redis.Message, len(messages))
+	for _, msg := range messages {
+		ch <- msg
+	}
+	return &fakePubSub{
+		messages: ch,
+		err:      err,
+		closed:   make(chan struct{}),
+	}
+}
+
+func (p *fakePubSub) ReceiveMessage() (*redis.Message, error) {
+	select {
+	case msg := <-p.messages:
+		return msg, nil
+	default:
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	<-p.closed
+	return nil, errSubscriptionClosed
+}
+
+func (p *fakePubSub) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+	return nil
+}
+
+// fakeBaseplate is a baseplate.Baseplate only used for its identity.
+type fakeBaseplate struct {
+	baseplate.Baseplate
+}
+
+func TestSubscriberGroup(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	// The first subscription fails after one message,
+	// the second one stays open after one message.
+	subs := []*fakePubSub{
+		newFakePubSub(
+			errors.New("connection reset"),
+			&redis.Message{Channel: "channel", Payload: "1"},
+		),
+		newFakePubSub(
+			nil,
+			&redis.Message{Channel: "channel", Pattern: "chan*", Payload: "2"},
+		),
+	}
+	var subscribed int32
+
+	var lock sync.Mutex
+	var payloads []string
+	handled := make(chan struct{}, len(subs))
+	group, err := redisbp.NewSubscriberGroup(redisbp.SubscriberGroupArgs{
+		Baseplate: fakeBaseplate{},
+		Name:      "subscriber",
+		Subscribe: func() redisbp.PubSubReceiver {
+			i := atomic.AddInt32(&subscribed, 1) - 1
+			return subs[i]
+		},
+		Handler: func(ctx context.Context, msg *redis.Message) error {
+			lock.Lock()
+			payloads = append(payloads, msg.Payload)
+			lock.Unlock()
+			handled <- struct{}{}
+			if msg.Payload == "2" {
+				return errors.New("handler error")
+			}
+			return nil
+		},
+		Workers:    2,
+		MinBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error)
+	go func() {
+		served <- group.Serve()
+	}()
+
+	for range subs {
+		select {
+		case <-handled:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for messages to be handled")
+		}
+	}
+	if err := group.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned error: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&subscribed); n != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", n)
+	}
+	lock.Lock()
+	if len(payloads) != 2 {
+		t.Errorf("Expected 2 messages handled, got %v", payloads)
+	}
+	lock.Unlock()
+
+	spans := recorder.FindSpans("subscriber")
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %+v", spans)
+	}
+	var errored int
+	for _, span := range spans {
+		if v, _ := span.Tag(redisbp.SpanTagKeyPubSubChannel); v != "channel" {
+			t.Errorf("Expected tag %q to be %q, got %v", redisbp.SpanTagKeyPubSubChannel, "channel", v)
+		}
+		if span.IsError() {
+			errored++
+			if v, _ := span.Tag(redisbp.SpanTagKeyPubSubPattern); v != "chan*" {
+				t.Errorf("Expected tag %q to be %q, got %v", redisbp.SpanTagKeyPubSubPattern, "chan*", v)
+			}
+		}
+	}
+	if errored != 1 {
+		t.Errorf("Expected 1 errored span, got %d", errored)
+	}
+}
+
+func TestSubscriberGroupPanic(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	sub := newFakePubSub(
+		nil,
+		&redis.Message{Channel: "channel", Payload: "panic"},
+		&redis.Message{Channel: "channel", Payload: "ok"},
+	)
+	handled := make(chan string, 2)
+	group, err := redisbp.NewSubscriberGroup(redisbp.SubscriberGroupArgs{
+		Baseplate: fakeBaseplate{},
+		Name:      "subscriber",
+		Subscribe: func() redisbp.PubSubReceiver {
+			return sub
+		},
+		Handler: func(ctx context.Context, msg *redis.Message) error {
+			handled <- msg.Payload
+			if msg.Payload == "panic" {
+				panic("handler panic")
+			}
+			return nil
+		},
+		// A single worker handles both messages, so it must survive the panic.
+		Workers:    1,
+		MinBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error)
+	go func() {
+		served <- group.Serve()
+	}()
+
+	for _, expected := range []string{"panic", "ok"} {
+		select {
+		case payload := <-handled:
+			if payload != expected {
+				t.Errorf("Expected message %q to be handled, got %q", expected, payload)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for message %q to be handled", expected)
+		}
+	}
+	if err := group.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned error: %v", err)
+	}
+
+	spans := recorder.FindSpans("subscriber")
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %+v", spans)
+	}
+	var errored int
+	for _, span := range spans {
+		if span.IsError() {
+			errored++
+		}
+	}
+	if errored != 1 {
+		t.Errorf("Expected 1 errored span, got %d", errored)
+	}
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	if str := buf.String(); !strings.Contains(str, "subscriber.panic:1.000000|c") {
+		t.Errorf("Expected panic counter to be reported, got %q", str)
+	}
+}
+
+func TestSubscriberGroupValidate(t *testing.T) {
+	_, err := redisbp.NewSubscriberGroup(redisbp.SubscriberGroupArgs{
+		Baseplate: fakeBaseplate{},
+		Name:      "subscriber",
+	})
+	if err == nil {
+		t.Error("Expected error for missing Subscribe and Handler, got nil")
+	}
+}
@@ -0,0 +1,52 @@
+package requestevent
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	eventContextKey contextKey = iota
+)
+
+// NewContext returns a copy of ctx with event attached.
+//
+// It's usually called by the server middlewares,
+// e.g. thriftbp.EmitRequestEvent and httpbp.EmitRequestEvent.
+func NewContext(ctx context.Context, event *Event) context.Context {
+	return context.WithValue(ctx, eventContextKey, event)
+}
+
+// FromContext returns the Event attached to ctx by NewContext, if any.
+func FromContext(ctx context.Context) (event *Event, ok bool) {
+	event, ok = ctx.Value(eventContextKey).(*Event)
+	return event, ok && event != nil
+}
+
+// SetString sets the string field key to value on the Event attached to ctx.
+//
+// It's a no-op when there's no Event attached to ctx.
+func SetString(ctx context.Context, key, value string) {
+	if event, ok := FromContext(ctx); ok {
+		event.SetString(key, value)
+	}
+}
+
+// SetNumber sets the number field key to value on the Event attached to ctx.
+//
+// It's a no-op when there's no Event attached to ctx.
+func SetNumber(ctx context.Context, key string, value float64) {
+	if event, ok := FromContext(ctx); ok {
+		event.SetNumber(key, value)
+	}
+}
+
+// Add adds delta to the number field key on the Event attached to ctx.
+//
+// It's a no-op when there's no Event attached to ctx.
+func Add(ctx context.Context, key string, delta float64) {
+	if event, ok := FromContext(ctx); ok {
+		event.Add(key, delta)
+	}
+}
@@ -0,0 +1,36 @@
+package requestevent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/reddit/baseplate.go/requestevent"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+
+	// Should be no-ops without panicking.
+	requestevent.SetString(ctx, "foo", "bar")
+	requestevent.SetNumber(ctx, "foo", 1)
+	requestevent.Add(ctx, "foo", 1)
+	if _, ok := requestevent.FromContext(ctx); ok {
+		t.Error("Expected no event attached to the empty context")
+	}
+
+	event := requestevent.New()
+	ctx = requestevent.NewContext(ctx, event)
+	got, ok := requestevent.FromContext(ctx)
+	if !ok || got != event {
+		t.Fatalf("Expected event %p from context, got %p", event, got)
+	}
+	requestevent.SetString(ctx, "str", "bar")
+	requestevent.SetNumber(ctx, "num", 1)
+	requestevent.Add(ctx, "num", 2)
+	if v := event.Strings()["str"]; v != "bar" {
+		t.Errorf("Expected string field %q, got %q", "bar", v)
+	}
+	if v := event.Numbers()["num"]; v != 3 {
+		t.Errorf("Expected number field %v, got %v", 3, v)
+	}
+}
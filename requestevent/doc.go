@@ -0,0 +1,23 @@
+// Package requestevent implements the canonical "wide event" of a request:
+// a single record per request accumulating fields from all the layers
+// involved, emitted once at the end of the request.
+//
+// The server middlewares (thriftbp.EmitRequestEvent and
+// httpbp.EmitRequestEvent) attach a new Event to the request context object,
+// fill in the fields of the request itself (endpoint, duration, success, user
+// agent, etc.), and publish it when the request finishes.
+// In between, the handler and the client libraries contribute fields through
+// the context object with Set and Add, for example:
+//
+// - thriftbp clients count the downstream calls and their durations;
+//
+// - redisbp.SpanHook counts the redis commands and misses;
+//
+// - redisbp.CachedLoader counts the cache hits and misses.
+//
+// Set and Add are no-ops when there's no Event attached to the context object,
+// so libraries can always call them.
+//
+// Events are published as the RequestEvent struct defined in
+// internal/thrift/requestevent.thrift.
+package requestevent
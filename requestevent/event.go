@@ -0,0 +1,162 @@
+package requestevent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	schema "github.com/reddit/baseplate.go/internal/gen-go/reddit/requestevent"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// The keys of the fields set by the server middlewares.
+const (
+	// String fields.
+	KeyEndpoint  = "endpoint"
+	KeyProtocol  = "protocol"
+	KeyUserAgent = "user_agent"
+	KeyTraceID   = "trace_id"
+	KeyUserID    = "user_id"
+
+	// Number fields.
+	KeyDurationMS = "duration_ms"
+	// 1 for successful requests, 0 otherwise.
+	KeySuccess = "success"
+	// The thrift error code or the http status code of the failed requests.
+	KeyErrorCode = "error_code"
+)
+
+// Publisher publishes the Events when the requests finish.
+//
+// *events.Queue implements Publisher.
+type Publisher interface {
+	Put(ctx context.Context, event thrift.TStruct) error
+}
+
+// Event is the record of a single request.
+//
+// It has two kinds of fields: string fields set by SetString,
+// and number fields set by SetNumber and accumulated by Add.
+//
+// It's safe for concurrent use.
+// Please use New to create an Event.
+type Event struct {
+	lock    sync.Mutex
+	strings map[string]string
+	numbers map[string]float64
+}
+
+// New creates a new, empty Event.
+func New() *Event {
+	return &Event{
+		strings: make(map[string]string),
+		numbers: make(map[string]float64),
+	}
+}
+
+// SetString sets the string field key to value.
+func (e *Event) SetString(key, value string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.strings[key] = value
+}
+
+// SetNumber sets the number field key to value.
+func (e *Event) SetNumber(key string, value float64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.numbers[key] = value
+}
+
+// Add adds delta to the number field key.
+//
+// A number field never set is treated as 0.
+func (e *Event) Add(key string, delta float64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.numbers[key] += delta
+}
+
+// SetFromContext sets KeyTraceID and KeyUserID from the server span and the
+// edge request context on ctx, when they are available.
+func (e *Event) SetFromContext(ctx context.Context) {
+	if span, ok := opentracing.SpanFromContext(ctx).(*tracing.Span); ok && span != nil {
+		e.SetString(KeyTraceID, span.TraceIDString())
+	}
+	if ec, ok := edgecontext.GetEdgeContext(ctx); ok {
+		if id, ok := ec.User().ID(); ok {
+			e.SetString(KeyUserID, id)
+		}
+	}
+}
+
+// Strings returns a copy of the string fields.
+func (e *Event) Strings() map[string]string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	m := make(map[string]string, len(e.strings))
+	for k, v := range e.strings {
+		m[k] = v
+	}
+	return m
+}
+
+// Numbers returns a copy of the number fields.
+func (e *Event) Numbers() map[string]float64 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	m := make(map[string]float64, len(e.numbers))
+	for k, v := range e.numbers {
+		m[k] = v
+	}
+	return m
+}
+
+// Write implements thrift.TStruct.
+//
+// The Event is written as the RequestEvent struct defined in
+// internal/thrift/requestevent.thrift.
+func (e *Event) Write(p thrift.TProtocol) error {
+	return e.toThrift().Write(p)
+}
+
+// Read implements thrift.TStruct.
+//
+// It reads the RequestEvent struct written by Write,
+// replacing all the fields of the Event.
+func (e *Event) Read(p thrift.TProtocol) error {
+	t := schema.NewRequestEvent()
+	if err := t.Read(p); err != nil {
+		return err
+	}
+	if t.Strings == nil {
+		t.Strings = make(map[string]string)
+	}
+	if t.Numbers == nil {
+		t.Numbers = make(map[string]float64)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.strings = t.Strings
+	e.numbers = t.Numbers
+	return nil
+}
+
+func (e *Event) toThrift() *schema.RequestEvent {
+	return &schema.RequestEvent{
+		Strings: e.Strings(),
+		Numbers: e.Numbers(),
+	}
+}
+
+// String implements fmt.Stringer.
+func (e *Event) String() string {
+	return fmt.Sprintf("RequestEvent(strings=%v, numbers=%v)", e.Strings(), e.Numbers())
+}
+
+var _ thrift.TStruct = (*Event)(nil)
@@ -0,0 +1,106 @@
+package requestevent_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	schema "github.com/reddit/baseplate.go/internal/gen-go/reddit/requestevent"
+	"github.com/reddit/baseplate.go/requestevent"
+)
+
+func TestEventFields(t *testing.T) {
+	event := requestevent.New()
+	event.SetString("endpoint", "foo")
+	event.SetNumber("duration_ms", 12)
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			event.Add("calls", 1)
+		}()
+	}
+	wg.Wait()
+
+	expectedStrings := map[string]string{"endpoint": "foo"}
+	if actual := event.Strings(); !reflect.DeepEqual(actual, expectedStrings) {
+		t.Errorf("Expected string fields %v, got %v", expectedStrings, actual)
+	}
+	expectedNumbers := map[string]float64{"duration_ms": 12, "calls": n}
+	if actual := event.Numbers(); !reflect.DeepEqual(actual, expectedNumbers) {
+		t.Errorf("Expected number fields %v, got %v", expectedNumbers, actual)
+	}
+}
+
+func TestEventThrift(t *testing.T) {
+	event := requestevent.New()
+	event.SetString("endpoint", "foo")
+	event.SetString("user_agent", "bar")
+	event.SetNumber("duration_ms", 1.5)
+	event.Add("calls", 2)
+
+	for _, c := range []struct {
+		label   string
+		factory thrift.TProtocolFactory
+	}{
+		{
+			label:   "binary",
+			factory: thrift.NewTBinaryProtocolFactoryDefault(),
+		},
+		{
+			label:   "json",
+			factory: thrift.NewTJSONProtocolFactory(),
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			trans := thrift.NewTMemoryBuffer()
+			proto := c.factory.GetProtocol(trans)
+			if err := event.Write(proto); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			read := requestevent.New()
+			if err := read.Read(proto); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(read.Strings(), event.Strings()) {
+				t.Errorf("Expected string fields %v, got %v", event.Strings(), read.Strings())
+			}
+			if !reflect.DeepEqual(read.Numbers(), event.Numbers()) {
+				t.Errorf("Expected number fields %v, got %v", event.Numbers(), read.Numbers())
+			}
+		})
+	}
+}
+
+func TestEventSchema(t *testing.T) {
+	event := requestevent.New()
+	event.SetString("endpoint", "foo")
+	event.SetNumber("duration_ms", 1.5)
+
+	trans := thrift.NewTMemoryBuffer()
+	proto := thrift.NewTBinaryProtocolFactoryDefault().GetProtocol(trans)
+	if err := event.Write(proto); err != nil {
+		t.Fatal(err)
+	}
+
+	read := schema.NewRequestEvent()
+	if err := read.Read(proto); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.GetStrings(), event.Strings()) {
+		t.Errorf("Expected strings %v, got %v", event.Strings(), read.GetStrings())
+	}
+	if !reflect.DeepEqual(read.GetNumbers(), event.Numbers()) {
+		t.Errorf("Expected numbers %v, got %v", event.Numbers(), read.GetNumbers())
+	}
+}
@@ -0,0 +1,59 @@
+package retrybp
+
+import (
+	"sync"
+)
+
+// Budget is a retry budget shared by multiple Do calls.
+//
+// It limits the retries to a ratio of the total calls,
+// so that when the downstream service is having a brownout,
+// the retries won't multiply the load on it.
+//
+// Every Do call deposits Ratio tokens into the budget,
+// and every retry withdraws one token.
+// The budget is capped at MaxTokens,
+// and a retry is only allowed when there's at least one token left.
+//
+// Please use NewBudget to create a Budget.
+type Budget struct {
+	lock      sync.Mutex
+	ratio     float64
+	maxTokens float64
+	tokens    float64
+}
+
+// NewBudget creates a new Budget.
+//
+// ratio is the max ratio of retries to calls, e.g. 0.1 means at most one retry
+// every 10 calls.
+// maxTokens is the cap of the budget,
+// it's also the initial number of tokens in the budget.
+func NewBudget(ratio float64, maxTokens float64) *Budget {
+	return &Budget{
+		ratio:     ratio,
+		maxTokens: maxTokens,
+		tokens:    maxTokens,
+	}
+}
+
+func (b *Budget) deposit() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+func (b *Budget) withdraw() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
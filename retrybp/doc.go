@@ -0,0 +1,21 @@
+// Package retrybp provides retry helpers with exponential backoff, jitter,
+// max attempts, and retry budgets.
+//
+// The core of the package is Do, which calls a function until it succeeds,
+// the error is classified as not retryable by the Classifier,
+// the attempts are exhausted, or the retry Budget runs out:
+//
+//     budget := retrybp.NewBudget(0.1, 10)
+//     err := retrybp.Do(ctx, retrybp.Config{
+//         MaxAttempts:    3,
+//         InitialBackoff: time.Millisecond * 10,
+//         MaxBackoff:     time.Millisecond * 100,
+//         Jitter:         0.2,
+//         Budget:         budget,
+//     }, func(ctx context.Context) error {
+//         return doSomething(ctx)
+//     })
+//
+// thriftbp.Retry and httpbp.Retry adapt it into thrift client middleware and
+// http.RoundTripper middleware respectively.
+package retrybp
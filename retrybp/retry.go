@@ -0,0 +1,154 @@
+package retrybp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// DefaultMultiplier is the default value to be used when Multiplier in Config
+// is not positive.
+const DefaultMultiplier = 2
+
+// Classifier decides whether a non-nil error returned by the function passed
+// into Do is retryable.
+type Classifier func(err error) bool
+
+// DefaultClassifier is the Classifier used when Classifier in Config is nil.
+//
+// It treats all errors as retryable,
+// except context.Canceled and context.DeadlineExceeded.
+func DefaultClassifier(err error) bool {
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// Config is the configuration used by Do.
+//
+// Other than Classifier, Budget, and the hooks, it can be deserialized from
+// YAML.
+type Config struct {
+	// MaxAttempts is the max number of times the function will be called,
+	// including the first, non-retry call.
+	//
+	// When it's <= 1, the function will only be called once and never retried.
+	MaxAttempts int `yaml:"maxAttempts"`
+
+	// InitialBackoff is the backoff before the first retry.
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+
+	// MaxBackoff is the cap of the backoff.
+	//
+	// When it's <= 0, the backoff is not capped.
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+
+	// Multiplier is the multiplier applied to the backoff after every retry.
+	//
+	// When it's <= 0, DefaultMultiplier will be used.
+	Multiplier float64 `yaml:"multiplier"`
+
+	// Jitter is the fraction of the backoff to be randomized,
+	// should be in range of [0, 1].
+	//
+	// For example, when Jitter is 0.2 and the calculated backoff is 100ms,
+	// the actual backoff will be randomly picked in range of [80ms, 120ms).
+	Jitter float64 `yaml:"jitter"`
+
+	// Classifier decides whether an error is retryable.
+	//
+	// Optional, defaults to DefaultClassifier.
+	Classifier Classifier `yaml:"-"`
+
+	// Budget, if non-nil, limits the number of retries across all Do calls
+	// sharing the same Budget.
+	Budget *Budget `yaml:"-"`
+
+	// OnRetry, if non-nil, is called before every retry,
+	// with the number of the attempt about to be made (2 for the first retry)
+	// and the error of the previous attempt.
+	OnRetry func(attempt int, err error) `yaml:"-"`
+
+	// OnBudgetExhausted, if non-nil, is called when a retry is denied because
+	// the Budget ran out, with the error of the last attempt.
+	OnBudgetExhausted func(err error) `yaml:"-"`
+}
+
+// Backoff returns the backoff before the nth retry (1-indexed),
+// without jitter applied.
+func (cfg Config) Backoff(retry int) time.Duration {
+	multiplier := cfg.Multiplier
+	if multiplier <= 0 {
+		multiplier = DefaultMultiplier
+	}
+	backoff := float64(cfg.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if cfg.MaxBackoff > 0 && backoff > float64(cfg.MaxBackoff) {
+		return cfg.MaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+func (cfg Config) jitteredBackoff(retry int) time.Duration {
+	backoff := cfg.Backoff(retry)
+	if cfg.Jitter <= 0 || backoff <= 0 {
+		return backoff
+	}
+	jitter := math.Min(cfg.Jitter, 1)
+	factor := 1 - jitter + 2*jitter*randbp.R.Float64()
+	return time.Duration(float64(backoff) * factor)
+}
+
+func (cfg Config) classifier() Classifier {
+	if cfg.Classifier == nil {
+		return DefaultClassifier
+	}
+	return cfg.Classifier
+}
+
+// Do calls fn until it returns nil error,
+// or stops retrying in one of the following situations:
+//
+// - The error is not retryable according to the Classifier.
+//
+// - MaxAttempts is reached.
+//
+// - The Budget (if set) runs out.
+//
+// - The context object is canceled, or its deadline passes before the next
+// attempt.
+//
+// In all those situations the last error returned by fn is returned.
+func Do(ctx context.Context, cfg Config, fn func(ctx context.Context) error) error {
+	if cfg.Budget != nil {
+		cfg.Budget.deposit()
+	}
+	classifier := cfg.classifier()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.MaxAttempts || !classifier(err) {
+			return err
+		}
+		if cfg.Budget != nil && !cfg.Budget.withdraw() {
+			if cfg.OnBudgetExhausted != nil {
+				cfg.OnBudgetExhausted(err)
+			}
+			return err
+		}
+
+		timer := time.NewTimer(cfg.jitteredBackoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempt+1, err)
+		}
+	}
+}
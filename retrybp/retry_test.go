@@ -0,0 +1,162 @@
+package retrybp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+var errTest = errors.New("test error")
+
+func counterFunc(errs ...error) (fn func(context.Context) error, calls *int) {
+	calls = new(int)
+	fn = func(context.Context) error {
+		*calls++
+		if *calls > len(errs) {
+			return nil
+		}
+		return errs[*calls-1]
+	}
+	return
+}
+
+func TestDo(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		cfg      retrybp.Config
+		errs     []error
+		expected error
+		calls    int
+	}{
+		{
+			label:    "no-retry",
+			cfg:      retrybp.Config{},
+			errs:     []error{errTest},
+			expected: errTest,
+			calls:    1,
+		},
+		{
+			label:    "succeed-on-retry",
+			cfg:      retrybp.Config{MaxAttempts: 3},
+			errs:     []error{errTest, errTest},
+			expected: nil,
+			calls:    3,
+		},
+		{
+			label:    "max-attempts",
+			cfg:      retrybp.Config{MaxAttempts: 2},
+			errs:     []error{errTest, errTest, errTest},
+			expected: errTest,
+			calls:    2,
+		},
+		{
+			label:    "not-retryable",
+			cfg:      retrybp.Config{MaxAttempts: 3},
+			errs:     []error{context.Canceled},
+			expected: context.Canceled,
+			calls:    1,
+		},
+		{
+			label: "custom-classifier",
+			cfg: retrybp.Config{
+				MaxAttempts: 3,
+				Classifier: func(err error) bool {
+					return !errors.Is(err, errTest)
+				},
+			},
+			errs:     []error{errTest},
+			expected: errTest,
+			calls:    1,
+		},
+		{
+			label: "budget",
+			cfg: retrybp.Config{
+				MaxAttempts: 3,
+				Budget:      retrybp.NewBudget(0, 1),
+			},
+			errs:     []error{errTest, errTest, errTest},
+			expected: errTest,
+			calls:    2,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			fn, calls := counterFunc(c.errs...)
+			err := retrybp.Do(context.Background(), c.cfg, fn)
+			if !errors.Is(err, c.expected) {
+				t.Errorf("Expected error %v, got %v", c.expected, err)
+			}
+			if *calls != c.calls {
+				t.Errorf("Expected %d calls, got %d", c.calls, *calls)
+			}
+		})
+	}
+}
+
+func TestDoContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	fn, calls := counterFunc(errTest, errTest)
+	err := retrybp.Do(ctx, retrybp.Config{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+	}, fn)
+	if !errors.Is(err, errTest) {
+		t.Errorf("Expected error %v, got %v", errTest, err)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected 1 call, got %d", *calls)
+	}
+}
+
+func TestDoHooks(t *testing.T) {
+	var retries []int
+	var exhausted int
+	fn, calls := counterFunc(errTest, errTest, errTest)
+	err := retrybp.Do(context.Background(), retrybp.Config{
+		MaxAttempts: 4,
+		Budget:      retrybp.NewBudget(0, 2),
+		OnRetry: func(attempt int, err error) {
+			if !errors.Is(err, errTest) {
+				t.Errorf("Expected error %v passed into OnRetry, got %v", errTest, err)
+			}
+			retries = append(retries, attempt)
+		},
+		OnBudgetExhausted: func(err error) {
+			exhausted++
+		},
+	}, fn)
+	if !errors.Is(err, errTest) {
+		t.Errorf("Expected error %v, got %v", errTest, err)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+	if len(retries) != 2 || retries[0] != 2 || retries[1] != 3 {
+		t.Errorf("Expected OnRetry to be called with [2 3], got %v", retries)
+	}
+	if exhausted != 1 {
+		t.Errorf("Expected OnBudgetExhausted to be called once, got %d", exhausted)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := retrybp.Config{
+		InitialBackoff: time.Millisecond * 10,
+		MaxBackoff:     time.Millisecond * 50,
+	}
+	for retry, expected := range map[int]time.Duration{
+		1: time.Millisecond * 10,
+		2: time.Millisecond * 20,
+		3: time.Millisecond * 40,
+		4: time.Millisecond * 50,
+		5: time.Millisecond * 50,
+	} {
+		if actual := cfg.Backoff(retry); actual != expected {
+			t.Errorf("Expected backoff for retry #%d to be %v, got %v", retry, expected, actual)
+		}
+	}
+}
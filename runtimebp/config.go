@@ -0,0 +1,47 @@
+package runtimebp
+
+import (
+	"math"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// Config is the configuration struct for the runtimebp package.
+//
+// Can be deserialized from YAML.
+type Config struct {
+	// NumProcesses configures the bounds of GOMAXPROCS set by InitFromConfig.
+	NumProcesses NumProcessesConfig `yaml:"numProcesses"`
+}
+
+// NumProcessesConfig is the bounds of GOMAXPROCS.
+//
+// Can be deserialized from YAML.
+type NumProcessesConfig struct {
+	// Optional, defaults to 1.
+	Min int `yaml:"min"`
+
+	// Optional, defaults to no upper bound.
+	Max int `yaml:"max"`
+}
+
+// InitFromConfig sets GOMAXPROCS with the default formula (see GOMAXPROCS),
+// based on the cgroup CPU quota of the container and in bound of the
+// configured NumProcesses.
+func InitFromConfig(cfg Config) {
+	min := cfg.NumProcesses.Min
+	if min <= 0 {
+		min = 1
+	}
+	max := cfg.NumProcesses.Max
+	if max <= 0 {
+		max = math.MaxInt32
+	}
+	oldVal, newVal := GOMAXPROCS(min, max)
+	log.Named("runtimebp").Infow(
+		"runtimebp: GOMAXPROCS set",
+		"old", oldVal,
+		"new", newVal,
+		"numCPU", NumCPU(),
+	)
+}
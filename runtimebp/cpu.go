@@ -1,6 +1,7 @@
 package runtimebp
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -12,7 +13,8 @@ import (
 // NumCPU returns the number of CPUs assigned to this running container.
 //
 // This is the container aware version of runtime.NumCPU.
-// It reads from the cgroup sysfs values.
+// It reads from the cgroup sysfs values,
+// supporting both cgroup v1 and cgroup v2 (unified hierarchy).
 //
 // If the current process is not running inside a container,
 // or for whatever reason we failed to read the cgroup sysfs values,
@@ -23,6 +25,7 @@ func NumCPU() (n float64) {
 	const (
 		quotaPath  = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
 		periodPath = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+		cpuMaxPath = "/sys/fs/cgroup/cpu.max"
 	)
 
 	var err error
@@ -45,6 +48,8 @@ func NumCPU() (n float64) {
 
 	quota, err = readNumberFromFile(quotaPath, buf)
 	if err != nil {
+		// Not cgroup v1, try cgroup v2.
+		n, err = readCPUMaxFromFile(cpuMaxPath, buf)
 		return
 	}
 
@@ -56,6 +61,35 @@ func NumCPU() (n float64) {
 	return quota / period
 }
 
+// errNoCPULimit is the error returned by readCPUMaxFromFile when there's no
+// cpu limit set.
+var errNoCPULimit = errors.New("runtimebp: no cpu limit set in cpu.max")
+
+// readCPUMaxFromFile reads the cgroup v2 cpu.max file,
+// which is in the format of "$MAX $PERIOD", and returns $MAX/$PERIOD.
+func readCPUMaxFromFile(path string, buf []byte) (float64, error) {
+	content, err := readFile(path, buf)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(content)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("runtimebp: failed to parse %s: unexpected content %q", path, content)
+	}
+	if fields[0] == "max" {
+		return 0, errNoCPULimit
+	}
+	quota, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("runtimebp: failed to parse %s: %w", path, err)
+	}
+	period, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("runtimebp: failed to parse %s: %w", path, err)
+	}
+	return float64(quota) / float64(period), nil
+}
+
 // On some really old docker version the quota file will be -1, in which case we
 // should use this one instead.
 func numCPUSharesFallback() (n float64) {
@@ -91,22 +125,32 @@ func numCPUSharesFallback() (n float64) {
 }
 
 func readNumberFromFile(path string, buf []byte) (float64, error) {
-	file, err := os.Open(path)
+	content, err := readFile(path, buf)
 	if err != nil {
-		return 0, fmt.Errorf("runtimebp: failed to open %s: %w", path, err)
+		return 0, err
 	}
-	defer file.Close()
 
-	n, err := file.Read(buf)
+	f, err := strconv.ParseInt(content, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("runtimebp: failed to read %s: %w", path, err)
+		return 0, fmt.Errorf("runtimebp: failed to parse %s: %w", path, err)
 	}
+	return float64(f), nil
+}
 
-	f, err := strconv.ParseInt(strings.TrimSpace(string(buf[:n])), 10, 64)
+// readFile reads the content of a small sysfs file into buf,
+// and returns it with leading and trailing spaces trimmed.
+func readFile(path string, buf []byte) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return 0, fmt.Errorf("runtimebp: failed to parse %s: %w", path, err)
+		return "", fmt.Errorf("runtimebp: failed to open %s: %w", path, err)
 	}
-	return float64(f), nil
+	defer file.Close()
+
+	n, err := file.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("runtimebp: failed to read %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(buf[:n])), nil
 }
 
 // MaxProcsFormula is the function to calculate GOMAXPROCS based on NumCPU value
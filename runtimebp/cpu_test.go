@@ -138,3 +138,71 @@ func TestBoundNtoMinMax(t *testing.T) {
 		)
 	}
 }
+
+func TestReadCPUMaxFromFile(t *testing.T) {
+	buf := make([]byte, 1024)
+
+	cases := map[string]struct {
+		Content  string
+		Error    bool
+		Expected float64
+	}{
+		"normal": {
+			Content:  "150000 100000\n",
+			Expected: 1.5,
+		},
+		"no-limit": {
+			Content: "max 100000\n",
+			Error:   true,
+		},
+		"garbage": {
+			Content: "foo bar",
+			Error:   true,
+		},
+		"missing-period": {
+			Content: "150000",
+			Error:   true,
+		},
+	}
+
+	for label, data := range cases {
+		t.Run(
+			label,
+			func(t *testing.T) {
+				path := writeTempFile(t, data.Content)
+				defer os.Remove(path)
+				f, err := readCPUMaxFromFile(path, buf)
+				if data.Error {
+					if err == nil {
+						t.Errorf("Expected an error for %+v, got nil", data)
+					}
+				} else {
+					if err != nil {
+						t.Fatalf("Got error for %+v: %v", data, err)
+					}
+					if math.Abs(data.Expected-f) > 1e-5 {
+						t.Errorf("Expected %f, got %f", data.Expected, f)
+					}
+				}
+			},
+		)
+	}
+}
+
+func writeTempFile(t *testing.T, content string) string {
+	t.Helper()
+
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if _, err := file.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
@@ -0,0 +1,47 @@
+package runtimebp
+
+import (
+	"strconv"
+)
+
+// Memory limits larger than this are considered as no limit.
+//
+// When no limit is set, cgroup v1 reports a page aligned math.MaxInt64.
+const unlimitedMemoryThreshold = 1 << 62
+
+// MaxMemory returns the memory limit in bytes assigned to this running
+// container.
+//
+// It reads from the cgroup sysfs values,
+// supporting both cgroup v1 and cgroup v2 (unified hierarchy).
+//
+// ok will be false if the current process is not running inside a container,
+// there's no memory limit set,
+// or for whatever reason we failed to read the cgroup sysfs values.
+func MaxMemory() (bytes int64, ok bool) {
+	const (
+		v1Path = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+		v2Path = "/sys/fs/cgroup/memory.max"
+	)
+
+	// Big enough buffer to read the number in the file wholly into memory.
+	buf := make([]byte, 1024)
+	for _, path := range []string{v1Path, v2Path} {
+		if bytes, ok = readMemoryLimitFromFile(path, buf); ok {
+			return
+		}
+	}
+	return 0, false
+}
+
+func readMemoryLimitFromFile(path string, buf []byte) (int64, bool) {
+	content, err := readFile(path, buf)
+	if err != nil || content == "max" {
+		return 0, false
+	}
+	bytes, err := strconv.ParseInt(content, 10, 64)
+	if err != nil || bytes <= 0 || bytes >= unlimitedMemoryThreshold {
+		return 0, false
+	}
+	return bytes, true
+}
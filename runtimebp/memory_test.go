@@ -0,0 +1,48 @@
+package runtimebp
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReadMemoryLimitFromFile(t *testing.T) {
+	buf := make([]byte, 1024)
+
+	cases := map[string]struct {
+		Content  string
+		OK       bool
+		Expected int64
+	}{
+		"normal": {
+			Content:  "1073741824\n",
+			OK:       true,
+			Expected: 1073741824,
+		},
+		"v1-no-limit": {
+			Content: "9223372036854771712\n",
+		},
+		"v2-no-limit": {
+			Content: "max\n",
+		},
+		"garbage": {
+			Content: "Hello, world!",
+		},
+	}
+
+	for label, data := range cases {
+		t.Run(
+			label,
+			func(t *testing.T) {
+				path := writeTempFile(t, data.Content)
+				defer os.Remove(path)
+				bytes, ok := readMemoryLimitFromFile(path, buf)
+				if ok != data.OK {
+					t.Fatalf("Expected ok to be %v, got %v", data.OK, ok)
+				}
+				if bytes != data.Expected {
+					t.Errorf("Expected %d, got %d", data.Expected, bytes)
+				}
+			},
+		)
+	}
+}
@@ -2,28 +2,103 @@ package secrets
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/reddit/baseplate.go/filewatcher"
 	"github.com/reddit/baseplate.go/log"
 )
 
+// DefaultInitTimeout is the timeout used by InitFromConfig to wait for the
+// secrets file.
+const DefaultInitTimeout = time.Second * 30
+
+// Supported Config.Provider values.
+const (
+	// ProviderVault reads the secrets from the secrets.json file written by the
+	// secrets fetcher sidecar, using NewStore.
+	ProviderVault = "vault"
+
+	// ProviderVaultCSI reads the secrets from the directory mounted by the
+	// Vault CSI provider, using NewCSIStore.
+	ProviderVaultCSI = "vault_csi"
+)
+
 // Config is the confuration struct for the secrets package.
 //
 // Can be deserialized from YAML.
 type Config struct {
 	// Path is the path to the secrets.json file file to load your service's
-	// secrets from.
+	// secrets from,
+	// or the path to the directory mounted by the Vault CSI provider when
+	// Provider is ProviderVaultCSI.
 	Path string `yaml:"path"`
+
+	// Provider is where the secrets are read from,
+	// either ProviderVault or ProviderVaultCSI.
+	//
+	// Optional, defaults to ProviderVault.
+	Provider string `yaml:"provider"`
 }
 
 // InitFromConfig returns a new *secrets.Store using the given context and config.
+//
+// It waits for the secrets up to DefaultInitTimeout,
+// see InitFromFile for more details.
 func InitFromConfig(ctx context.Context, cfg Config) (*Store, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	switch cfg.Provider {
+	case "", ProviderVault:
+		return InitFromFile(ctx, cfg.Path, DefaultInitTimeout)
+	case ProviderVaultCSI:
+		return initStore(ctx, cfg.Path, DefaultInitTimeout, NewCSIStore)
+	default:
+		return nil, fmt.Errorf("secrets.InitFromConfig: unknown provider %q", cfg.Provider)
+	}
+}
+
+// InitFromFile returns a new *secrets.Store reading from the secrets file at
+// path.
+//
+// Unlike NewStore, which fails immediately when the file exists but cannot be
+// parsed, InitFromFile blocks until the file exists and can be parsed,
+// so the services don't crash by racing the sidecar writing the file at
+// startup.
+//
+// When the file is still not ready after timeout, or ctx is cancelled,
+// an InitTimeoutError is returned,
+// with the last error encountered while reading the file.
+func InitFromFile(ctx context.Context, path string, timeout time.Duration) (*Store, error) {
+	return initStore(ctx, path, timeout, NewStore)
+}
+
+func initStore(
+	ctx context.Context,
+	path string,
+	timeout time.Duration,
+	newStore func(context.Context, string, log.Wrapper, ...SecretMiddleware) (*Store, error),
+) (*Store, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	store, err := NewStore(ctx, cfg.Path, log.ErrorWithSentryWrapper())
-	if err != nil {
-		return nil, err
+	var lastErr error
+	for {
+		store, err := newStore(ctx, path, log.ErrorWithSentryWrapper())
+		if err == nil {
+			return store, nil
+		}
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, InitTimeoutError{
+				Path:    path,
+				Timeout: timeout,
+				Err:     lastErr,
+			}
+		case <-time.After(filewatcher.InitialReadInterval):
+		}
 	}
-	return store, nil
 }
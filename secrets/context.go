@@ -0,0 +1,27 @@
+package secrets
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	storeContextKey contextKey = iota
+)
+
+// NewContext returns a copy of ctx with store attached.
+//
+// It's usually called by the server middlewares,
+// e.g. thriftbp.InjectSecretsStore and httpbp.InjectSecretsStore,
+// so the handlers and client factories can get the Store via FromContext
+// without using global variables.
+func NewContext(ctx context.Context, store *Store) context.Context {
+	return context.WithValue(ctx, storeContextKey, store)
+}
+
+// FromContext returns the Store attached to ctx by NewContext, if any.
+func FromContext(ctx context.Context) (store *Store, ok bool) {
+	store, ok = ctx.Value(storeContextKey).(*Store)
+	return store, ok && store != nil
+}
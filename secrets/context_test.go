@@ -0,0 +1,25 @@
+package secrets_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestContext(t *testing.T) {
+	if _, ok := secrets.FromContext(context.Background()); ok {
+		t.Error("Expected no Store in empty context")
+	}
+
+	store := new(secrets.Store)
+	ctx := secrets.NewContext(context.Background(), store)
+	if actual, ok := secrets.FromContext(ctx); !ok || actual != store {
+		t.Errorf("Expected Store %p from context, got %p, %v", store, actual, ok)
+	}
+
+	ctx = secrets.NewContext(context.Background(), nil)
+	if _, ok := secrets.FromContext(ctx); ok {
+		t.Error("Expected nil Store to not be returned from context")
+	}
+}
@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// csiDataDir is the symlink inside the CSI volume pointing to the directory of
+// the current version of the secrets.
+//
+// The volume is updated atomically by writing the new version into a new
+// directory then swapping the symlink, so it's also the file we watch.
+const csiDataDir = "..data"
+
+// csiSecret is the content of a secret file in the CSI volume,
+// which is the JSON response of Vault reading the secret.
+type csiSecret struct {
+	Data GenericSecret `json:"data"`
+}
+
+// NewCSIStore returns a new instance of Store reading the secrets from the
+// directory in path,
+// which is a volume mounted by the Vault CSI provider.
+//
+// In the directory every secret is a file, and the path of the file relative to
+// the directory is the path of the secret (e.g. the file
+// "secret/myservice/some-api-key" holds the secret of the same path),
+// with the content of the JSON response of Vault reading the secret:
+//
+//     {
+//       "data": {
+//         "type": "simple",
+//         "value": "Y2RvVXhNMVdsTXJma3BDaHRGZ0dPYkVGSg==",
+//         "encoding": "base64"
+//       }
+//     }
+//
+// The fields under "data" are the same as the ones in the secrets JSON file
+// used by NewStore.
+// GetVault always returns an empty Vault for the stores created by
+// NewCSIStore.
+//
+// The directory is watched for the updates the same way as NewStore,
+// via the "..data" symlink the CSI driver swaps atomically on every update.
+//
+// Context should come with a timeout otherwise this might block forever, i.e.
+// if the path never becomes available.
+func NewCSIStore(ctx context.Context, path string, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
+	store := &Store{
+		secretHandlerFunc: nopSecretHandlerFunc,
+	}
+	store.secretHandler(middlewares...)
+
+	result, err := filewatcher.New(
+		ctx,
+		filewatcher.Config{
+			Path: filepath.Join(path, csiDataDir),
+			Parser: func(io.Reader) (interface{}, error) {
+				return store.csiParser(path)
+			},
+			Logger: logger,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	store.watcher = result
+	return store, nil
+}
+
+func (s *Store) csiParser(path string) (interface{}, error) {
+	secrets, err := NewCSISecrets(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.secretHandlerFunc(secrets)
+
+	return secrets, nil
+}
+
+// NewCSISecrets parses and validates the secrets from the directory in path
+// mounted by the Vault CSI provider.
+//
+// See NewCSIStore for the directory layout.
+func NewCSISecrets(path string) (*Secrets, error) {
+	root, err := filepath.EvalSymlinks(filepath.Join(path, csiDataDir))
+	if err != nil {
+		return nil, err
+	}
+
+	document := Document{
+		Secrets: make(map[string]GenericSecret),
+	}
+	err = filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip the hidden files and directories of the CSI driver,
+		// but not root itself, which is also named with the ".." prefix.
+		if file != root && strings.HasPrefix(info.Name(), "..") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var secret csiSecret
+		if err := json.Unmarshal(data, &secret); err != nil {
+			return fmt.Errorf("secrets.NewCSISecrets: failed to parse %q: %w", rel, err)
+		}
+		document.Secrets[filepath.ToSlash(rel)] = secret.Data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newSecretsFromDocument(document)
+}
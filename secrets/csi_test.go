@@ -0,0 +1,144 @@
+package secrets_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// writeCSIVersion writes the files into a new version directory of the CSI
+// volume in dir, and swaps the "..data" symlink to it atomically,
+// the same way the CSI driver does.
+func writeCSIVersion(t *testing.T, dir, version string, files map[string]string) {
+	t.Helper()
+
+	versionDir := filepath.Join(dir, version)
+	for name, content := range files {
+		path := filepath.Join(versionDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmpLink := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(version, tmpLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCSIStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCSIVersion(t, dir, "..2020_01_01_00_00_00.1", map[string]string{
+		"secret/myservice/some-api-key": `{
+			"request_id": "foo",
+			"data": {
+				"type": "simple",
+				"value": "Y2RvVXhNMVdsTXJma3BDaHRGZ0dPYkVGSg==",
+				"encoding": "base64"
+			}
+		}`,
+		"secret/myservice/external-account-key": `{
+			"data": {
+				"type": "versioned",
+				"current": "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXowMTIzNDU=",
+				"previous": "aHVudGVyMg==",
+				"encoding": "base64"
+			}
+		}`,
+		"secret/myservice/some-database-credentials": `{
+			"data": {
+				"type": "credential",
+				"username": "spez",
+				"password": "hunter2"
+			}
+		}`,
+	})
+	// The CSI driver also links the top level entries to "..data".
+	if err := os.Symlink(filepath.Join("..data", "secret"), filepath.Join(dir, "secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := secrets.NewCSIStore(context.Background(), dir, log.TestWrapper(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	simple, err := store.GetSimpleSecret("secret/myservice/some-api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "cdoUxM1WlMrfkpChtFgGObEFJ"; string(simple.Value) != expected {
+		t.Errorf("Expected simple secret %q, got %q", expected, simple.Value)
+	}
+
+	versioned, err := store.GetVersionedSecret("secret/myservice/external-account-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "hunter2"; string(versioned.Previous) != expected {
+		t.Errorf("Expected previous versioned secret %q, got %q", expected, versioned.Previous)
+	}
+
+	credential, err := store.GetCredentialSecret("secret/myservice/some-database-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.Username != "spez" || credential.Password != "hunter2" {
+		t.Errorf("Unexpected credential secret %+v", credential)
+	}
+
+	writeCSIVersion(t, dir, "..2020_01_01_00_00_00.2", map[string]string{
+		"secret/myservice/some-api-key": `{
+			"data": {
+				"type": "simple",
+				"value": "dXBkYXRlZCBzZWNyZXQ=",
+				"encoding": "base64"
+			}
+		}`,
+	})
+	time.Sleep(time.Millisecond * 100)
+
+	simple, err = store.GetSimpleSecret("secret/myservice/some-api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "updated secret"; string(simple.Value) != expected {
+		t.Errorf("Expected updated simple secret %q, got %q", expected, simple.Value)
+	}
+	if _, err := store.GetCredentialSecret("secret/myservice/some-database-credentials"); err == nil {
+		t.Error("Expected removed credential secret to be gone after the update")
+	}
+}
+
+func TestNewCSISecretsInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCSIVersion(t, dir, "..2020_01_01_00_00_00.1", map[string]string{
+		"secret/myservice/some-api-key": `{"data": {"type": "simple"}}`,
+	})
+	if _, err := secrets.NewCSISecrets(dir); err == nil {
+		t.Error("Expected error for simple secret without value")
+	}
+}
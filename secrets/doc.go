@@ -2,4 +2,9 @@
 // reading them out of a JSON file with automatic refresh on change.
 //
 // Store should be used to instantiate and configure the secret fetcher.
+// It reads the secrets either from the JSON file written by the secrets fetcher
+// sidecar (NewStore),
+// or from the directory mounted by the Vault CSI provider (NewCSIStore).
+// The Store can be attached to the request contexts by the server middlewares
+// and retrieved by FromContext.
 package secrets
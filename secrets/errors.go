@@ -3,6 +3,7 @@ package secrets
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ErrInvalidEncoding is the error returned by the parser when we got an invalid
@@ -27,6 +28,26 @@ func (e TooManyFieldsError) Error() string {
 	)
 }
 
+// MissingFieldError is a type of errors could be returned by
+// Document.Validate.
+//
+// Note that Document.Validate could also return a BatchError containing
+// multiple MissingFieldError.
+type MissingFieldError struct {
+	Key        string
+	SecretType string
+	Field      string
+}
+
+func (e MissingFieldError) Error() string {
+	return fmt.Sprintf(
+		"secrets: expected %s secret to have %q field but it's missing for %s",
+		e.SecretType,
+		e.Field,
+		e.Key,
+	)
+}
+
 // SecretNotFoundError is returned when the key for a secret is not present in
 // the secret store.
 type SecretNotFoundError string
@@ -34,3 +55,35 @@ type SecretNotFoundError string
 func (path SecretNotFoundError) Error() string {
 	return "secrets: no secret has been found for " + string(path)
 }
+
+// InitTimeoutError is the error returned by InitFromFile when the secrets file
+// is not ready before the timeout.
+type InitTimeoutError struct {
+	Path    string
+	Timeout time.Duration
+
+	// The last error encountered while reading the secrets file,
+	// nil if the file never became available.
+	Err error
+}
+
+func (e InitTimeoutError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf(
+			"secrets: secrets file %q was not available after %v, is the secrets sidecar running?",
+			e.Path,
+			e.Timeout,
+		)
+	}
+	return fmt.Sprintf(
+		"secrets: secrets file %q was not ready after %v: %v",
+		e.Path,
+		e.Timeout,
+		e.Err,
+	)
+}
+
+// Unwrap returns the last error encountered while reading the secrets file.
+func (e InitTimeoutError) Unwrap() error {
+	return e.Err
+}
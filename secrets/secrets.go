@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/reddit/baseplate.go/batcherror"
 )
@@ -175,47 +176,54 @@ type Document struct {
 // Validate checks the Document for any errors that violate the Baseplate
 // specification.
 //
+// Every invalid secret is reported.
 // When this function returns a non-nil error, the error is either a
-// TooManyFieldsError, or a BatchError containing multiple TooManyFieldsError.
+// TooManyFieldsError, a MissingFieldError, or a BatchError containing multiple
+// of them.
 func (s *Document) Validate() error {
 	var batch batcherror.BatchError
 	for key, value := range s.Secrets {
-		if value.Type == simpleSecret && notOnlySimpleSecret(value) {
-			batch.Add(TooManyFieldsError{
-				SecretType: simpleSecret,
-				Key:        key,
-			})
-		}
-		if value.Type == versionedSecret && notOnlyVersionedSecret(value) {
-			batch.Add(TooManyFieldsError{
-				SecretType: versionedSecret,
-				Key:        key,
-			})
-		}
-		if value.Type == credentialSecret && notOnlyCredentialSecret(value) {
-			batch.Add(TooManyFieldsError{
-				SecretType: credentialSecret,
-				Key:        key,
-			})
-		}
+		batch.Add(value.validate(key))
 	}
 	return batch.Compile()
 }
 
-func notOnlySimpleSecret(secret GenericSecret) bool {
-	return secret.Current != "" || secret.Previous != "" || secret.Next != "" || secret.Username != "" || secret.Password != ""
-}
-
-func notOnlyVersionedSecret(secret GenericSecret) bool {
-	return secret.Value != "" || secret.Username != "" || secret.Password != ""
+// secretFields are the fields of GenericSecret, other than type and encoding.
+var secretFields = []string{
+	"value",
+	"current",
+	"previous",
+	"next",
+	"username",
+	"password",
 }
 
-func notOnlyCredentialSecret(secret GenericSecret) bool {
-	return secret.Value != "" || secret.Current != "" || secret.Previous != "" || secret.Next != ""
+// secretSpecs are the allowed and required fields of each type of secret.
+var secretSpecs = map[string]struct {
+	allowed  map[string]bool
+	required []string
+}{
+	simpleSecret: {
+		allowed:  map[string]bool{"value": true},
+		required: []string{"value"},
+	},
+	versionedSecret: {
+		allowed:  map[string]bool{"current": true, "previous": true, "next": true},
+		required: []string{"current"},
+	},
+	credentialSecret: {
+		allowed:  map[string]bool{"username": true, "password": true},
+		required: []string{"username", "password"},
+	},
 }
 
 // GenericSecret is a placeholder to fit all types of secrets when parsing the
 // Secret JSON before processing them into their more typed equivalents.
+//
+// When parsed from JSON, GenericSecret remembers which fields are present,
+// so a field with empty value (e.g. an empty password) is not missing.
+// When constructed in code, a field is only considered present when it's not
+// empty.
 type GenericSecret struct {
 	Type     string   `json:"type"`
 	Value    string   `json:"value"`
@@ -227,6 +235,106 @@ type GenericSecret struct {
 
 	Username string `json:"username"`
 	Password string `json:"password"`
+
+	// present is the set of the fields present in the JSON,
+	// nil when the GenericSecret is not parsed from JSON.
+	present map[string]bool
+	// encodingErr is the error parsing the encoding field.
+	encodingErr error
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// An invalid encoding doesn't fail the parsing,
+// it's reported with the key of the secret when converting the Document to
+// Secrets, along with the other invalid secrets.
+func (s *GenericSecret) UnmarshalJSON(data []byte) error {
+	// plain doesn't have the UnmarshalJSON method to avoid infinite recursion.
+	type plain GenericSecret
+	var secret struct {
+		plain
+
+		Encoding json.RawMessage `json:"encoding"`
+	}
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*s = GenericSecret(secret.plain)
+	if len(secret.Encoding) > 0 {
+		s.encodingErr = s.Encoding.UnmarshalJSON(secret.Encoding)
+	}
+	s.present = make(map[string]bool, len(fields))
+	for name, value := range fields {
+		if string(value) != "null" {
+			// Same as encoding/json, the names of the fields are case-insensitive.
+			s.present[strings.ToLower(name)] = true
+		}
+	}
+	return nil
+}
+
+// value returns the value of the field.
+func (s GenericSecret) value(field string) string {
+	switch field {
+	case "value":
+		return s.Value
+	case "current":
+		return s.Current
+	case "previous":
+		return s.Previous
+	case "next":
+		return s.Next
+	case "username":
+		return s.Username
+	case "password":
+		return s.Password
+	}
+	return ""
+}
+
+// has returns true if the field is present in the secret.
+func (s GenericSecret) has(field string) bool {
+	if s.present == nil {
+		return s.value(field) != ""
+	}
+	return s.present[field]
+}
+
+// validate checks the secret at key for any errors that violate the Baseplate
+// specification.
+//
+// Secrets of unknown types are not checked.
+func (s GenericSecret) validate(key string) error {
+	spec, ok := secretSpecs[s.Type]
+	if !ok {
+		return nil
+	}
+
+	var batch batcherror.BatchError
+	for _, field := range secretFields {
+		if !spec.allowed[field] && s.has(field) {
+			batch.Add(TooManyFieldsError{
+				SecretType: s.Type,
+				Key:        key,
+			})
+			break
+		}
+	}
+	for _, field := range spec.required {
+		if !s.has(field) {
+			batch.Add(MissingFieldError{
+				SecretType: s.Type,
+				Key:        key,
+				Field:      field,
+			})
+		}
+	}
+	return batch.Compile()
 }
 
 // Vault provides authentication credentials so that applications can directly
@@ -237,14 +345,21 @@ type Vault struct {
 }
 
 // NewSecrets parses and validates the secret JSON provided by the reader.
+//
+// When any of the secrets is invalid, the errors of all the invalid secrets
+// are returned and no Secrets is created.
 func NewSecrets(r io.Reader) (*Secrets, error) {
 	var secretsDocument Document
 	err := json.NewDecoder(r).Decode(&secretsDocument)
 	if err != nil {
 		return nil, err
 	}
+	return newSecretsFromDocument(secretsDocument)
+}
 
-	err = secretsDocument.Validate()
+// newSecretsFromDocument validates the Document and converts it to Secrets.
+func newSecretsFromDocument(secretsDocument Document) (*Secrets, error) {
+	err := secretsDocument.Validate()
 	if err != nil {
 		return nil, err
 	}
@@ -254,36 +369,50 @@ func NewSecrets(r io.Reader) (*Secrets, error) {
 		credentialSecrets: make(map[string]CredentialSecret),
 		vault:             secretsDocument.Vault,
 	}
+	var batch batcherror.BatchError
 	for key, secret := range secretsDocument.Secrets {
-		switch secret.Type {
-		case "simple":
-			simple, err := newSimpleSecret(&secret)
-			if err != nil {
-				return nil, err
-			}
-			secrets.simpleSecrets[key] = simple
-		case "versioned":
-			versioned, err := newVersionedSecret(&secret)
-			if err != nil {
-				return nil, err
-			}
-			secrets.versionedSecrets[key] = versioned
-		case "credential":
-			credential, err := newCredentialSecret(&secret)
-			if err != nil {
-				return nil, err
-			}
-			secrets.credentialSecrets[key] = credential
-		default:
-			return nil, fmt.Errorf(
-				"secrets.NewSecrets: encountered unknown secret type %s",
-				secret.Type,
-			)
-		}
+		batch.Add(secrets.add(key, secret))
+	}
+	if err := batch.Compile(); err != nil {
+		return nil, err
 	}
 	return secrets, nil
 }
 
+// add converts the validated secret and adds it to Secrets.
+func (s *Secrets) add(key string, secret GenericSecret) error {
+	if secret.encodingErr != nil {
+		return fmt.Errorf("secrets: failed to parse %s: %w", key, secret.encodingErr)
+	}
+	switch secret.Type {
+	case simpleSecret:
+		simple, err := newSimpleSecret(&secret)
+		if err != nil {
+			return fmt.Errorf("secrets: failed to decode %s: %w", key, err)
+		}
+		s.simpleSecrets[key] = simple
+	case versionedSecret:
+		versioned, err := newVersionedSecret(&secret)
+		if err != nil {
+			return fmt.Errorf("secrets: failed to decode %s: %w", key, err)
+		}
+		s.versionedSecrets[key] = versioned
+	case credentialSecret:
+		credential, err := newCredentialSecret(&secret)
+		if err != nil {
+			return err
+		}
+		s.credentialSecrets[key] = credential
+	default:
+		return fmt.Errorf(
+			"secrets.NewSecrets: encountered unknown secret type %s for %s",
+			secret.Type,
+			key,
+		)
+	}
+	return nil
+}
+
 // encoding represents the encoding used to encode the secrets.
 type encoding int
 
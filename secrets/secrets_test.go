@@ -2,8 +2,12 @@ package secrets
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/reddit/baseplate.go/batcherror"
 )
 
 func TestNewSecrets(t *testing.T) {
@@ -99,6 +103,24 @@ func TestNewSecrets(t *testing.T) {
 				Key:        "secret/myservice/some-api-key",
 			},
 		},
+		{
+			name: "missing field",
+			input: `
+					{
+						"secrets": {
+							"secret/myservice/some-signing-key": {
+								"type": "versioned",
+								"previous": "hunter2"
+							}
+						}
+					}
+			`,
+			expectedError: MissingFieldError{
+				SecretType: versionedSecret,
+				Key:        "secret/myservice/some-signing-key",
+				Field:      "current",
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt // capture range variable for parallel testing
@@ -118,3 +140,138 @@ func TestNewSecrets(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSecretsFieldPresence(t *testing.T) {
+	const input = `
+		{
+			"secrets": {
+				"secret/myservice/empty-password": {
+					"type": "credential",
+					"username": "spez",
+					"password": ""
+				},
+				"secret/myservice/empty-key": {
+					"type": "simple",
+					"value": ""
+				}
+			}
+		}
+	`
+	secrets, err := NewSecrets(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credential, err := secrets.GetCredentialSecret("secret/myservice/empty-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (CredentialSecret{Username: "spez"}); credential != expected {
+		t.Errorf("expected %+v, actual: %+v", expected, credential)
+	}
+
+	simple, err := secrets.GetSimpleSecret("secret/myservice/empty-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !simple.Value.IsEmpty() {
+		t.Errorf("expected empty secret, actual: %q", simple.Value)
+	}
+}
+
+func TestNewSecretsInvalidSecrets(t *testing.T) {
+	const input = `
+		{
+			"secrets": {
+				"secret/myservice/some-api-key": {
+					"type": "simple",
+					"value": "hunter2",
+					"current": "hunter2"
+				},
+				"secret/myservice/no-password": {
+					"type": "credential",
+					"username": "spez"
+				},
+				"secret/myservice/some-database-credentials": {
+					"type": "credential",
+					"username": "spez",
+					"password": "hunter2"
+				}
+			}
+		}
+	`
+	secrets, err := NewSecrets(strings.NewReader(input))
+	if secrets != nil {
+		t.Errorf("expected no secrets for invalid document, actual: %v", secrets)
+	}
+	var batch batcherror.BatchError
+	if !errors.As(err, &batch) {
+		t.Fatalf("expected BatchError, actual: %v", err)
+	}
+	if len(batch.GetErrors()) != 2 {
+		t.Errorf("expected every invalid secret reported, actual: %v", batch.GetErrors())
+	}
+
+	for _, c := range []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "invalid encoding",
+			input: `{"secrets": {"secret/myservice/some-encoded-key": {"type": "simple", "value": "hunter2", "encoding": "rot13"}}}`,
+		},
+		{
+			name:  "unknown type",
+			input: `{"secrets": {"secret/myservice/some-other-key": {"type": "unknown", "value": "hunter2"}}}`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := NewSecrets(strings.NewReader(c.input)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDocumentValidate(t *testing.T) {
+	const input = `
+		{
+			"type": "simple",
+			"value": "hunter2",
+			"current": "hunter2"
+		}
+	`
+	var tooMany GenericSecret
+	if err := tooMany.UnmarshalJSON([]byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	doc := Document{
+		Secrets: map[string]GenericSecret{
+			"secret/myservice/some-api-key": tooMany,
+			"secret/myservice/some-signing-key": {
+				Type:     versionedSecret,
+				Previous: "hunter2",
+			},
+			"secret/myservice/some-database-credentials": {
+				Type:     credentialSecret,
+				Username: "spez",
+				Password: "hunter2",
+			},
+		},
+	}
+
+	var batch batcherror.BatchError
+	if !errors.As(doc.Validate(), &batch) {
+		t.Fatalf("expected BatchError, actual: %v", doc.Validate())
+	}
+	if len(batch.GetErrors()) != 2 {
+		t.Errorf("expected 2 errors, actual: %v", batch.GetErrors())
+	}
+	for _, err := range batch.GetErrors() {
+		switch err.(type) {
+		default:
+			t.Errorf("unexpected error: %v", err)
+		case TooManyFieldsError, MissingFieldError:
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Package secretstest provides an in-memory secrets.Store for tests.
+//
+// The secrets are populated programmatically instead of from a temp file,
+// and can be changed at any time to simulate rotations,
+// so the code depending on the store can be tested without temp files and
+// fsnotify.
+//
+// A typical test looks like:
+//
+//     func TestMyClient(t *testing.T) {
+//       store := secretstest.NewStore(t)
+//       store.SetSimpleSecret(t, "secret/myservice/some-api-key", []byte("foo"))
+//       client := NewMyClient(store.Store)
+//       // Test the client...
+//
+//       // Rotate the secret.
+//       store.SetVersionedSecret(t, "secret/myservice/signing-key", secrets.VersionedSecret{
+//         Current:  []byte("new"),
+//         Previous: []byte("old"),
+//       })
+//       // Test the client again...
+//     }
+package secretstest
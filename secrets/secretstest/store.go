@@ -0,0 +1,138 @@
+package secretstest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// Store is an in-memory secrets store for tests.
+//
+// All the Set* and Remove* functions update Store immediately,
+// and call the middlewares passed into NewStore again,
+// the same as secrets.Store does on the updates of the secrets file.
+//
+// Please use NewStore to create a Store.
+type Store struct {
+	// Store is the secrets.Store backed by this Store,
+	// to be passed into the code under test.
+	Store *secrets.Store
+
+	lock   sync.Mutex
+	doc    secrets.Document
+	update func(secrets.Document) error
+}
+
+// NewStore creates a new Store without any secrets.
+func NewStore(tb testing.TB, middlewares ...secrets.SecretMiddleware) *Store {
+	tb.Helper()
+
+	s := &Store{
+		doc: secrets.Document{
+			Secrets: make(map[string]secrets.GenericSecret),
+		},
+	}
+	var err error
+	s.Store, s.update, err = secrets.NewMemoryStore(s.doc, middlewares...)
+	if err != nil {
+		tb.Fatalf("secretstest: failed to create secrets store: %v", err)
+	}
+	return s
+}
+
+// SetSimpleSecret adds or replaces the simple secret at path.
+func (s *Store) SetSimpleSecret(tb testing.TB, path string, value secrets.Secret) {
+	tb.Helper()
+
+	s.set(tb, path, secrets.GenericSecret{
+		Type:  "simple",
+		Value: string(value),
+	})
+}
+
+// SetVersionedSecret adds or replaces the versioned secret at path.
+//
+// The Current version is required.
+func (s *Store) SetVersionedSecret(tb testing.TB, path string, secret secrets.VersionedSecret) {
+	tb.Helper()
+
+	s.set(tb, path, secrets.GenericSecret{
+		Type:     "versioned",
+		Current:  string(secret.Current),
+		Previous: string(secret.Previous),
+		Next:     string(secret.Next),
+	})
+}
+
+// SetCredentialSecret adds or replaces the credential secret at path.
+func (s *Store) SetCredentialSecret(tb testing.TB, path string, secret secrets.CredentialSecret) {
+	tb.Helper()
+
+	s.set(tb, path, secrets.GenericSecret{
+		Type:     "credential",
+		Username: secret.Username,
+		Password: secret.Password,
+	})
+}
+
+// RemoveSecret removes the secret at path.
+//
+// It's a no-op if there's no secret at path.
+func (s *Store) RemoveSecret(tb testing.TB, path string) {
+	tb.Helper()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	doc := s.clone()
+	delete(doc.Secrets, path)
+	s.apply(tb, doc)
+}
+
+// SetVault sets the Vault returned by GetVault.
+func (s *Store) SetVault(tb testing.TB, vault secrets.Vault) {
+	tb.Helper()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	doc := s.clone()
+	doc.Vault = vault
+	s.apply(tb, doc)
+}
+
+func (s *Store) set(tb testing.TB, path string, secret secrets.GenericSecret) {
+	tb.Helper()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	doc := s.clone()
+	doc.Secrets[path] = secret
+	s.apply(tb, doc)
+}
+
+// clone returns a copy of the current Document.
+//
+// It must be called with the lock held.
+func (s *Store) clone() secrets.Document {
+	doc := secrets.Document{
+		Secrets: make(map[string]secrets.GenericSecret, len(s.doc.Secrets)+1),
+		Vault:   s.doc.Vault,
+	}
+	for path, secret := range s.doc.Secrets {
+		doc.Secrets[path] = secret
+	}
+	return doc
+}
+
+// apply updates the store with the new Document.
+//
+// It must be called with the lock held.
+// When the new Document is invalid, the store is not changed.
+func (s *Store) apply(tb testing.TB, doc secrets.Document) {
+	tb.Helper()
+
+	if err := s.update(doc); err != nil {
+		tb.Fatalf("secretstest: failed to update secrets: %v", err)
+	}
+	s.doc = doc
+}
@@ -0,0 +1,89 @@
+package secretstest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reddit/baseplate.go/secrets"
+	"github.com/reddit/baseplate.go/secrets/secretstest"
+)
+
+func TestStore(t *testing.T) {
+	var calls int
+	var latest *secrets.Secrets
+	middleware := func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+		return func(sec *secrets.Secrets) {
+			calls++
+			latest = sec
+			next(sec)
+		}
+	}
+
+	store := secretstest.NewStore(t, middleware)
+	if calls != 1 {
+		t.Errorf("Expected middleware to be called once on creation, got %d", calls)
+	}
+
+	const (
+		simplePath     = "secret/myservice/some-api-key"
+		versionedPath  = "secret/myservice/signing-key"
+		credentialPath = "secret/myservice/some-database-credentials"
+	)
+	var notFound secrets.SecretNotFoundError
+	if _, err := store.Store.GetSimpleSecret(simplePath); !errors.As(err, &notFound) {
+		t.Errorf("Expected SecretNotFoundError, got %v", err)
+	}
+
+	store.SetSimpleSecret(t, simplePath, secrets.Secret("foo"))
+	simple, err := store.Store.GetSimpleSecret(simplePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(simple.Value) != "foo" {
+		t.Errorf("Expected simple secret %q, got %q", "foo", simple.Value)
+	}
+
+	store.SetVersionedSecret(t, versionedPath, secrets.VersionedSecret{
+		Current: secrets.Secret("v1"),
+	})
+	store.SetVersionedSecret(t, versionedPath, secrets.VersionedSecret{
+		Current:  secrets.Secret("v2"),
+		Previous: secrets.Secret("v1"),
+	})
+	versioned, err := store.Store.GetVersionedSecret(versionedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(versioned.Current) != "v2" || string(versioned.Previous) != "v1" {
+		t.Errorf("Expected rotated versioned secret, got %+v", versioned)
+	}
+
+	store.SetCredentialSecret(t, credentialPath, secrets.CredentialSecret{
+		Username: "spez",
+		Password: "hunter2",
+	})
+	credential, err := store.Store.GetCredentialSecret(credentialPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.Username != "spez" || credential.Password != "hunter2" {
+		t.Errorf("Unexpected credential secret %+v", credential)
+	}
+
+	store.SetVault(t, secrets.Vault{URL: "vault.example.com", Token: "token"})
+	vault, _ := store.Store.GetVault()
+	if vault.URL != "vault.example.com" || vault.Token != "token" {
+		t.Errorf("Unexpected vault %+v", vault)
+	}
+
+	store.RemoveSecret(t, simplePath)
+	if _, err := store.Store.GetSimpleSecret(simplePath); !errors.As(err, &notFound) {
+		t.Errorf("Expected SecretNotFoundError after removal, got %v", err)
+	}
+	if _, err := latest.GetVersionedSecret(versionedPath); err != nil {
+		t.Errorf("Expected middleware to get the latest secrets, got %v", err)
+	}
+	if calls != 7 {
+		t.Errorf("Expected middleware to be called on every update, got %d calls", calls)
+	}
+}
@@ -3,6 +3,7 @@ package secrets
 import (
 	"context"
 	"io"
+	"sync/atomic"
 
 	"github.com/reddit/baseplate.go/filewatcher"
 	"github.com/reddit/baseplate.go/log"
@@ -26,11 +27,18 @@ func nopSecretHandlerFunc(sec *Secrets) {}
 // memory so there's little performance impact to doing so and you will be sure
 // to always have the current version in the face of key rotation etc.
 type Store struct {
-	watcher *filewatcher.Result
+	watcher watcher
 
 	secretHandlerFunc SecretHandlerFunc
 }
 
+// watcher is the source of the parsed *Secrets of a Store,
+// implemented by *filewatcher.Result and *memoryWatcher.
+type watcher interface {
+	Get() interface{}
+	Stop()
+}
+
 // NewStore returns a new instance of Store by configuring it
 // with a filewatcher to watch the file in path for changes ensuring secrets
 // store will always return up to date secrets.
@@ -59,6 +67,51 @@ func NewStore(ctx context.Context, path string, logger log.Wrapper, middlewares
 	return store, nil
 }
 
+// NewMemoryStore returns a new instance of Store holding the secrets from doc
+// in memory, instead of reading them from a file.
+//
+// The returned update function replaces the secrets in the store with the ones
+// from the new Document, and calls the middlewares again.
+// When the Document is invalid, update returns the error and the store keeps
+// the previous secrets.
+//
+// It's mainly intended for tests, see package secretstest for a more
+// convenient API built on top of it.
+func NewMemoryStore(doc Document, middlewares ...SecretMiddleware) (store *Store, update func(Document) error, err error) {
+	store = &Store{
+		secretHandlerFunc: nopSecretHandlerFunc,
+	}
+	store.secretHandler(middlewares...)
+
+	w := new(memoryWatcher)
+	update = func(doc Document) error {
+		secrets, err := newSecretsFromDocument(doc)
+		if err != nil {
+			return err
+		}
+		store.secretHandlerFunc(secrets)
+		w.data.Store(secrets)
+		return nil
+	}
+	if err := update(doc); err != nil {
+		return nil, nil, err
+	}
+
+	store.watcher = w
+	return store, update, nil
+}
+
+// memoryWatcher is the watcher of the stores created by NewMemoryStore.
+type memoryWatcher struct {
+	data atomic.Value
+}
+
+func (w *memoryWatcher) Get() interface{} {
+	return w.data.Load()
+}
+
+func (w *memoryWatcher) Stop() {}
+
 func (s *Store) parser(r io.Reader) (interface{}, error) {
 	secrets, err := NewSecrets(r)
 	if err != nil {
@@ -2,8 +2,10 @@ package secrets_test
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -449,3 +451,99 @@ func TestAddMiddleware(t *testing.T) {
 		)
 	}
 }
+
+func TestInitFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secrets.json")
+
+	t.Run("partial", func(t *testing.T) {
+		// Simulate the sidecar writing the file in the middle of the startup.
+		if err := ioutil.WriteFile(path, []byte(specificationExample[:20]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			time.Sleep(time.Millisecond * 100)
+			if err := ioutil.WriteFile(path, []byte(specificationExample), 0644); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		store, err := secrets.InitFromFile(context.Background(), path, time.Second*5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		if _, err := store.GetSimpleSecret("secret/myservice/some-api-key"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if err := ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := secrets.InitFromFile(context.Background(), path, time.Millisecond*10)
+		var timeoutErr secrets.InitTimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("Expected InitTimeoutError, got %v", err)
+		}
+		if timeoutErr.Path != path || timeoutErr.Err == nil {
+			t.Errorf("Expected InitTimeoutError for %q with parser error, got %+v", path, timeoutErr)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.json")
+		_, err := secrets.InitFromFile(context.Background(), missing, time.Millisecond*10)
+		var timeoutErr secrets.InitTimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("Expected InitTimeoutError, got %v", err)
+		}
+		if timeoutErr.Path != missing || timeoutErr.Err != nil {
+			t.Errorf("Expected InitTimeoutError for %q without error, got %+v", missing, timeoutErr)
+		}
+	})
+}
+
+func TestNewMemoryStoreInvalidUpdate(t *testing.T) {
+	store, update, err := secrets.NewMemoryStore(secrets.Document{
+		Secrets: map[string]secrets.GenericSecret{
+			"secret/myservice/some-api-key": {
+				Type:  "simple",
+				Value: "hunter2",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = update(secrets.Document{
+		Secrets: map[string]secrets.GenericSecret{
+			"secret/myservice/some-api-key": {
+				Type:  "simple",
+				Value: "updated",
+			},
+			"secret/myservice/some-signing-key": {
+				Type:     "versioned",
+				Previous: "hunter2",
+			},
+		},
+	})
+	var missing secrets.MissingFieldError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected MissingFieldError, got %v", err)
+	}
+
+	secret, err := store.GetSimpleSecret("secret/myservice/some-api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "hunter2"; string(secret.Value) != expected {
+		t.Errorf("Expected the previous secret %q kept, got %q", expected, secret.Value)
+	}
+}
@@ -39,6 +39,12 @@ func Verify(message []byte, signature string, secret secrets.VersionedSecret) er
 		}
 	}
 
+	if len(buf) == 0 {
+		return VerifyError{
+			Data: "empty signature",
+		}
+	}
+
 	v := Version(buf[0])
 	verify, ok := versions[v]
 	if !ok {
@@ -0,0 +1,59 @@
+package signing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestVerify(t *testing.T) {
+	msg := []byte("Hello, world!")
+	secret := secrets.VersionedSecret{Current: secrets.Secret("hunter2")}
+
+	sig, err := Sign(SignArgs{
+		Message:   msg,
+		Secret:    secret,
+		ExpiresIn: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(msg, sig, secret); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+
+	for _, c := range []struct {
+		label     string
+		signature string
+		reason    VerifyErrorReason
+	}{
+		{
+			label:     "empty",
+			signature: "",
+			reason:    VerifyErrorReasonOther,
+		},
+		{
+			label:     "base64",
+			signature: "not base64!",
+			reason:    VerifyErrorReasonBase64,
+		},
+		{
+			label:     "unknown-version",
+			signature: "AA==",
+			reason:    VerifyErrorReasonUnknownVersion,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var e VerifyError
+			err := Verify(msg, c.signature, secret)
+			if !errors.As(err, &e) {
+				t.Fatalf("Expected VerifyError, got %v", err)
+			}
+			if e.Reason != c.reason {
+				t.Errorf("Expected reason %v, got %v: %v", c.reason, e.Reason, err)
+			}
+		})
+	}
+}
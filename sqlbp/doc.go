@@ -0,0 +1,7 @@
+// Package sqlbp provides Baseplate specific database/sql related helpers.
+//
+// OpenDB wraps a database/sql/driver.Connector so that every query and
+// transaction made through the returned *sql.DB creates a client span,
+// with the sanitized SQL statement attached as a tag.
+// It can also report the stats of the connection pool as gauges via metricsbp.
+package sqlbp
@@ -0,0 +1,304 @@
+package sqlbp
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// SpanTagKeyStatement is the span tag key of the sanitized SQL statement.
+const SpanTagKeyStatement = "sql.statement"
+
+// DSNConnector creates a driver.Connector from a driver and a data source
+// name.
+//
+// If d implements driver.DriverContext, its OpenConnector will be used.
+func DSNConnector(d driver.Driver, dsn string) (driver.Connector, error) {
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{driver: d, dsn: dsn}, nil
+}
+
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// WrapConnector wraps a driver.Connector so that the connections it creates
+// create client spans for every query, exec, and transaction.
+//
+// The spans are named "<name>.query", "<name>.exec", "<name>.begin",
+// "<name>.commit", and "<name>.rollback".
+// Most users should use OpenDB instead.
+func WrapConnector(c driver.Connector, name string) driver.Connector {
+	return connector{Connector: c, name: name}
+}
+
+type connector struct {
+	driver.Connector
+
+	name string
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, name: c.name}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return wrappedDriver{Driver: c.Connector.Driver(), name: c.name}
+}
+
+type wrappedDriver struct {
+	driver.Driver
+
+	name string
+}
+
+func (d wrappedDriver) Open(dsn string) (driver.Conn, error) {
+	cn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, name: d.name}, nil
+}
+
+func startSpan(ctx context.Context, name, op, query string) (opentracing.Span, context.Context) {
+	span, ctx := opentracing.StartSpanFromContext(
+		ctx,
+		name+"."+op,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	if query != "" {
+		span.SetTag(SpanTagKeyStatement, SanitizeQuery(query))
+	}
+	return span, ctx
+}
+
+func finishSpan(ctx context.Context, span opentracing.Span, err error) {
+	span.FinishWithOptions(tracing.FinishOptions{
+		Ctx: ctx,
+		Err: err,
+	}.Convert())
+}
+
+// finishSkippableSpan finishes the span of a call which could return
+// driver.ErrSkip.
+//
+// driver.ErrSkip is not a real error, database/sql retries the call with a
+// prepared statement, which creates its own span,
+// so the span is finished without the error.
+func finishSkippableSpan(ctx context.Context, span opentracing.Span, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		err = nil
+	}
+	finishSpan(ctx, span, err)
+}
+
+type conn struct {
+	driver.Conn
+
+	name string
+}
+
+var (
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if cp, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = cp.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, name: c.name, query: query}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	span, spanCtx := startSpan(ctx, c.name, "begin", "")
+	var t driver.Tx
+	var err error
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		t, err = bt.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 || opts.ReadOnly {
+		err = errors.New("sqlbp: driver does not support non-default isolation level or read-only transactions")
+	} else {
+		t, err = c.Conn.Begin()
+	}
+	finishSpan(spanCtx, span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, ctx: ctx, name: c.name}, nil
+}
+
+// ExecContext implements driver.ExecerContext.
+//
+// If the underlying driver doesn't implement it,
+// driver.ErrSkip is returned so that database/sql falls back to prepared
+// statements, which are traced as well.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	span, spanCtx := startSpan(ctx, c.name, "exec", query)
+	result, err := execer.ExecContext(ctx, query, args)
+	finishSkippableSpan(spanCtx, span, err)
+	return result, err
+}
+
+// QueryContext implements driver.QueryerContext.
+//
+// If the underlying driver doesn't implement it,
+// driver.ErrSkip is returned so that database/sql falls back to prepared
+// statements, which are traced as well.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	span, spanCtx := startSpan(ctx, c.name, "query", query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	finishSkippableSpan(spanCtx, span, err)
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type tx struct {
+	driver.Tx
+
+	ctx  context.Context
+	name string
+}
+
+func (t *tx) Commit() error {
+	span, ctx := startSpan(t.ctx, t.name, "commit", "")
+	err := t.Tx.Commit()
+	finishSpan(ctx, span, err)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	span, ctx := startSpan(t.ctx, t.name, "rollback", "")
+	err := t.Tx.Rollback()
+	finishSpan(ctx, span, err)
+	return err
+}
+
+type stmt struct {
+	driver.Stmt
+
+	name  string
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	span, spanCtx := startSpan(ctx, s.name, "exec", s.query)
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = s.Stmt.Exec(values)
+		}
+	}
+	finishSpan(spanCtx, span, err)
+	return result, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	span, spanCtx := startSpan(ctx, s.name, "query", s.query)
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	finishSpan(spanCtx, span, err)
+	return rows, err
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqlbp: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,84 @@
+package sqlbp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SanitizeQuery returns the query with all the literals replaced by "?" and
+// all the whitespaces collapsed,
+// so it's safe to be attached to spans without leaking any user data.
+//
+// Both single-quoted and double-quoted strings are treated as literals
+// (as in MySQL), the quotes can be escaped by either doubling them or
+// backslashes.
+//
+// For example,
+//
+//     SELECT * FROM users
+//     WHERE name = 'foo' AND age > 18
+//
+// will be sanitized into
+//
+//     SELECT * FROM users WHERE name = ? AND age > ?
+func SanitizeQuery(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	runes := []rune(query)
+	space := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			if sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			space = false
+		}
+
+		switch {
+		case r == '\'' || r == '"':
+			// String literal, with the quote doubled or escaped by backslash.
+			i = skipQuoted(runes, i)
+			sb.WriteByte('?')
+		case unicode.IsDigit(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			// Numeric literal.
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			sb.WriteByte('?')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// skipQuoted returns the index of the closing quote of the quoted string
+// starting at runes[start],
+// or len(runes) if the string is not terminated.
+func skipQuoted(runes []rune, start int) int {
+	quote := runes[start]
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(runes) && runes[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(runes)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
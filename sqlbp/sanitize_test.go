@@ -0,0 +1,53 @@
+package sqlbp_test
+
+import (
+	"testing"
+
+	"github.com/reddit/baseplate.go/sqlbp"
+)
+
+func TestSanitizeQuery(t *testing.T) {
+	for _, c := range []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "SELECT * FROM users",
+			expected: "SELECT * FROM users",
+		},
+		{
+			query:    "SELECT *\n\tFROM users\n\tWHERE name = 'foo' AND age > 18  ",
+			expected: "SELECT * FROM users WHERE name = ? AND age > ?",
+		},
+		{
+			query:    "INSERT INTO t1 (a, b) VALUES ('it''s', 1.5)",
+			expected: "INSERT INTO t1 (a, b) VALUES (?, ?)",
+		},
+		{
+			query:    "SELECT col2 FROM t WHERE id = $1",
+			expected: "SELECT col2 FROM t WHERE id = $1",
+		},
+		{
+			query:    "SELECT 'unterminated",
+			expected: "SELECT ?",
+		},
+		{
+			query:    `SELECT * FROM users WHERE name = 'it\'s' AND password = 'secret'`,
+			expected: "SELECT * FROM users WHERE name = ? AND password = ?",
+		},
+		{
+			query:    `SELECT * FROM users WHERE name = "foo" AND bio = "say ""hi"" \" there"`,
+			expected: "SELECT * FROM users WHERE name = ? AND bio = ?",
+		},
+		{
+			query:    `SELECT 'a\\' , 'b'`,
+			expected: "SELECT ? , ?",
+		},
+	} {
+		t.Run(c.query, func(t *testing.T) {
+			if actual := sqlbp.SanitizeQuery(c.query); actual != c.expected {
+				t.Errorf("Expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package sqlbp
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// DefaultPoolGaugeInterval is the fallback value to be used when
+// Config.PoolGaugeInterval <= 0.
+const DefaultPoolGaugeInterval = time.Second * 10
+
+// Config is the configuration used by OpenDB.
+type Config struct {
+	// Name is the name of the database,
+	// used as the prefix of the client spans and the pool stats metrics.
+	Name string
+
+	// Any labels that should be applied to the pool stats metrics.
+	MetricsLabels metricsbp.Labels
+
+	// ReportPoolStats signals OpenDB that it should report the stats of the
+	// connection pool in a background goroutine.
+	//
+	// It reports the following gauges:
+	//
+	// - "${Name}.pool-open-connections"
+	//
+	// - "${Name}.pool-in-use-connections"
+	//
+	// - "${Name}.pool-idle-connections"
+	//
+	// - "${Name}.pool-wait-count": the total number of connections waited for.
+	//
+	// - "${Name}.pool-wait-duration": the total time in milliseconds blocked
+	// waiting for new connections.
+	//
+	// The reporting goroutine is cancelled when the global metrics client
+	// context is Done.
+	ReportPoolStats bool
+
+	// PoolGaugeInterval indicates how often we should update the pool stats
+	// gauges.
+	//
+	// When PoolGaugeInterval <= 0 and ReportPoolStats is true,
+	// DefaultPoolGaugeInterval will be used instead.
+	PoolGaugeInterval time.Duration
+}
+
+// OpenDB opens a *sql.DB with the connector wrapped by WrapConnector,
+// and optionally starts reporting the stats of the connection pool.
+//
+// Example:
+//
+//     connector, err := sqlbp.DSNConnector(&pq.Driver{}, dsn)
+//     if err != nil {
+//       log.Fatal(err)
+//     }
+//     db := sqlbp.OpenDB(connector, sqlbp.Config{
+//       Name:            "postgres",
+//       ReportPoolStats: true,
+//     })
+func OpenDB(c driver.Connector, cfg Config) *sql.DB {
+	db := sql.OpenDB(WrapConnector(c, cfg.Name))
+	if cfg.ReportPoolStats {
+		go reportPoolStats(
+			metricsbp.M.Ctx(),
+			cfg.Name,
+			db,
+			cfg.PoolGaugeInterval,
+			cfg.MetricsLabels.AsStatsdLabels(),
+		)
+	}
+	return db
+}
+
+func reportPoolStats(ctx context.Context, prefix string, db *sql.DB, tickerDuration time.Duration, labels []string) {
+	openGauge := metricsbp.M.Gauge(prefix + ".pool-open-connections").With(labels...)
+	inUseGauge := metricsbp.M.Gauge(prefix + ".pool-in-use-connections").With(labels...)
+	idleGauge := metricsbp.M.Gauge(prefix + ".pool-idle-connections").With(labels...)
+	waitCountGauge := metricsbp.M.Gauge(prefix + ".pool-wait-count").With(labels...)
+	waitDurationGauge := metricsbp.M.Gauge(prefix + ".pool-wait-duration").With(labels...)
+	if tickerDuration <= 0 {
+		tickerDuration = DefaultPoolGaugeInterval
+	}
+	ticker := time.NewTicker(tickerDuration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := db.Stats()
+			openGauge.Set(float64(stats.OpenConnections))
+			inUseGauge.Set(float64(stats.InUse))
+			idleGauge.Set(float64(stats.Idle))
+			waitCountGauge.Set(float64(stats.WaitCount))
+			waitDurationGauge.Set(float64(stats.WaitDuration) / float64(time.Millisecond))
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package sqlbp_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/sqlbp"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+// fakeDriver is a driver only implementing the required (legacy) interfaces,
+// to test the fallbacks.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{query: query}, nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if s.query == "fail" {
+		return nil, errors.New("failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	done bool
+}
+
+func (*fakeRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (*fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+// skipConn is a fakeConn implementing driver.ExecerContext but always
+// returning driver.ErrSkip.
+type skipConn struct {
+	fakeConn
+}
+
+func (skipConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+type skipDriver struct{}
+
+func (skipDriver) Open(string) (driver.Conn, error) {
+	return skipConn{}, nil
+}
+
+func openFakeDB(t *testing.T, cfg sqlbp.Config) *sql.DB {
+	t.Helper()
+	connector, err := sqlbp.DSNConnector(fakeDriver{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sqlbp.OpenDB(connector, cfg)
+}
+
+func TestOpenDBSpans(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	db := openFakeDB(t, sqlbp.Config{Name: "db"})
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "UPDATE t SET a = 'secret' WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "fail"); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	var id int64
+	if err := db.QueryRowContext(ctx, "SELECT id FROM t").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	execs := recorder.FindSpans("db.exec")
+	if len(execs) != 2 {
+		t.Fatalf("Expected 2 exec spans, got %+v", recorder.Spans())
+	}
+	expected := "UPDATE t SET a = ? WHERE id = ?"
+	if v, _ := execs[0].Tag(sqlbp.SpanTagKeyStatement); v != expected {
+		t.Errorf("Expected statement tag %q, got %v", expected, v)
+	}
+	if execs[0].IsError() || !execs[1].IsError() {
+		t.Errorf("Expected only the second exec span to be error, got %+v", execs)
+	}
+	query := recorder.MustFindSpan(t, "db.query")
+	if v, _ := query.Tag(sqlbp.SpanTagKeyStatement); v != "SELECT id FROM t" {
+		t.Errorf("Expected statement tag %q, got %v", "SELECT id FROM t", v)
+	}
+	recorder.MustFindSpan(t, "db.begin")
+	recorder.MustFindSpan(t, "db.commit")
+}
+
+func TestOpenDBErrSkip(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	connector, err := sqlbp.DSNConnector(skipDriver{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sqlbp.OpenDB(connector, sqlbp.Config{Name: "db"})
+	defer db.Close()
+
+	if _, err := db.ExecContext(context.Background(), "UPDATE t SET a = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// One span for the skipped ExecContext and one for the prepared statement,
+	// both should be finished without error.
+	execs := recorder.FindSpans("db.exec")
+	if len(execs) != 2 {
+		t.Fatalf("Expected 2 exec spans, got %+v", recorder.Spans())
+	}
+	for _, span := range execs {
+		if span.IsError() {
+			t.Errorf("Expected exec span without error, got %+v", span)
+		}
+	}
+}
+
+func TestOpenDBPoolStats(t *testing.T) {
+	metrics := metricstest.Replace(t)
+
+	db := openFakeDB(t, sqlbp.Config{
+		Name:              "db",
+		ReportPoolStats:   true,
+		PoolGaugeInterval: time.Millisecond,
+	})
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := metrics.Snapshot().Gauges["db.pool-idle-connections"]; ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Pool stats not reported")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	metrics.AssertGaugeEquals(t, "db.pool-open-connections", 1)
+	metrics.AssertGaugeEquals(t, "db.pool-in-use-connections", 0)
+	metrics.AssertGaugeEquals(t, "db.pool-idle-connections", 1)
+}
@@ -0,0 +1,209 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// AccessLogEntry is the information about a single request collected by
+// AccessLog.
+type AccessLogEntry struct {
+	// Endpoint is the name of the thrift endpoint.
+	Endpoint string
+
+	// Duration is the time spent in the next TProcessorFunction.
+	Duration time.Duration
+
+	// Success and Err are the values returned by the next TProcessorFunction.
+	//
+	// The generated processors return no error for the BaseplateErrors
+	// declared as exceptions of the thrift methods,
+	// so when one is written to the client Err is a BaseplateError with the
+	// same fields instead.
+	//
+	// When the next TProcessorFunction panics, Success is false and Err is
+	// ErrAccessLogPanic.
+	Success bool
+	Err     error
+
+	// ErrorCode is the code of the BaseplateError or the type id of the
+	// thrift.TApplicationException in Err's chain.
+	//
+	// HasErrorCode is false when Err is neither of them.
+	ErrorCode    int32
+	HasErrorCode bool
+
+	// PayloadSize is the serialized size of the request payload in bytes,
+	// excluding the message header and the THeader frame and headers.
+	//
+	// It's -1 when the request is not read through a THeader protocol,
+	// which is used by the servers created by NewServer.
+	PayloadSize int64
+
+	// TraceID is the trace id of the server span,
+	// or empty when there's no server span.
+	TraceID string
+
+	// UserID is the id of the logged in user from the edge request context,
+	// or empty when there's no logged in user.
+	UserID string
+}
+
+// AccessLogger writes an AccessLogEntry.
+type AccessLogger func(ctx context.Context, entry AccessLogEntry)
+
+// ErrAccessLogPanic is the Err of AccessLogEntry when the next
+// TProcessorFunction panics.
+var ErrAccessLogPanic = errors.New("thriftbp: panic in thrift handler")
+
+// DefaultAccessLogger is the AccessLogger used when Logger in AccessLogConfig
+// is nil.
+//
+// It writes the entry as an info level structured log line through the logger
+// attached to the context object (see log.LoggerFromContext),
+// with the empty fields omitted.
+func DefaultAccessLogger(ctx context.Context, entry AccessLogEntry) {
+	kv := []interface{}{
+		"endpoint", entry.Endpoint,
+		"duration", entry.Duration,
+		"success", entry.Success,
+	}
+	if entry.Err != nil {
+		kv = append(kv, "err", entry.Err)
+	}
+	if entry.HasErrorCode {
+		kv = append(kv, "errorCode", entry.ErrorCode)
+	}
+	if entry.PayloadSize >= 0 {
+		kv = append(kv, "payloadSize", entry.PayloadSize)
+	}
+	if entry.TraceID != "" {
+		kv = append(kv, "traceID", entry.TraceID)
+	}
+	if entry.UserID != "" {
+		kv = append(kv, "userID", entry.UserID)
+	}
+	log.LoggerFromContext(ctx).Infow("thrift access", kv...)
+}
+
+// AccessLogConfig is the configuration used by AccessLog.
+type AccessLogConfig struct {
+	// SampleRate is the rate of successful requests to be logged,
+	// in range of [0, 1].
+	//
+	// Failed requests are always logged regardless of the sample rates.
+	//
+	// When it's <= 0, it's treated as 1 and all successful requests are logged.
+	SampleRate float64
+
+	// MethodSampleRates overrides SampleRate for the endpoints with the given
+	// names, for example to tone down the logs of high QPS endpoints.
+	//
+	// Unlike SampleRate, 0 here means the successful requests to the endpoint
+	// are never logged.
+	MethodSampleRates map[string]float64
+
+	// Logger is used to write the entries.
+	//
+	// Optional, defaults to DefaultAccessLogger.
+	Logger AccessLogger
+}
+
+func (cfg AccessLogConfig) sampleRate(name string) float64 {
+	if rate, ok := cfg.MethodSampleRates[name]; ok {
+		return rate
+	}
+	if cfg.SampleRate <= 0 {
+		return 1
+	}
+	return cfg.SampleRate
+}
+
+// AccessLog returns a server middleware that writes one access log entry for
+// every request, with the endpoint name, duration, success, error and error
+// code, request payload size, trace id, and the user id.
+//
+// The trace id and the user id are read from the server span and the edge
+// request context on the context object,
+// so it should be applied after InjectServerSpan and InjectEdgeContext.
+// Middlewares passed into NewBaseplateServer are already applied after
+// BaseplateDefaultProcessorMiddlewares.
+func AccessLog(cfg AccessLogConfig) thrift.ProcessorMiddleware {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = DefaultAccessLogger
+	}
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		rate := cfg.sampleRate(name)
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (success bool, err thrift.TException) {
+				entry := AccessLogEntry{
+					Endpoint:    name,
+					PayloadSize: -1,
+				}
+				counting := newCountingProtocol(in)
+				if counting != nil {
+					in = counting
+				}
+				declared := newDeclaredExceptionProtocol(out)
+				if declared != nil {
+					out = declared
+				}
+				start := time.Now()
+				completed := false
+				defer func() {
+					entry.Duration = time.Since(start)
+					entry.Success = success
+					entry.Err = declared.processError(err)
+					if counting != nil {
+						entry.PayloadSize = counting.trans.read
+					}
+					if !completed {
+						entry.Success = false
+						entry.Err = ErrAccessLogPanic
+					}
+					if entry.Success && entry.Err == nil && !randbp.ShouldSampleWithRate(rate) {
+						return
+					}
+					entry.ErrorCode, entry.HasErrorCode = exceptionCode(entry.Err)
+					if span, ok := opentracing.SpanFromContext(ctx).(*tracing.Span); ok && span != nil {
+						entry.TraceID = span.TraceIDString()
+					}
+					if ec, ok := edgecontext.GetEdgeContext(ctx); ok {
+						entry.UserID, _ = ec.User().ID()
+					}
+					logger(ctx, entry)
+				}()
+
+				success, err = next.Process(ctx, seqID, in, out)
+				completed = true
+				return
+			},
+		}
+	}
+}
+
+// exceptionCode returns the code of the BaseplateError or the type id of the
+// thrift.TApplicationException in err's chain.
+func exceptionCode(err error) (int32, bool) {
+	if err == nil {
+		return 0, false
+	}
+	if code, ok := BaseplateErrorCode(err); ok {
+		return code, true
+	}
+	var appErr thrift.TApplicationException
+	if errors.As(err, &appErr) {
+		return appErr.TypeId(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,268 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplatetest"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+type accessLogRecorder struct {
+	entries []thriftbp.AccessLogEntry
+}
+
+func (r *accessLogRecorder) log(ctx context.Context, entry thriftbp.AccessLogEntry) {
+	r.entries = append(r.entries, entry)
+}
+
+func TestAccessLog(t *testing.T) {
+	store, dir := newSecretsStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+	impl := edgecontext.Init(edgecontext.Config{Store: store})
+
+	errTest := thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "test")
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			"success": thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					return true, nil
+				},
+			},
+			"fail": thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					return true, errTest
+				},
+			},
+			"read": thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					if err := in.Skip(thrift.STRUCT); err != nil {
+						return false, err
+					}
+					return true, in.ReadMessageEnd()
+				},
+			},
+			"panic": thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					panic("oops")
+				},
+			},
+		},
+	)
+
+	recorder := &accessLogRecorder{}
+	wrapped := thrift.WrapProcessor(
+		processor,
+		thriftbp.InjectServerSpan,
+		thriftbp.InjectEdgeContext(impl),
+		thriftbp.AccessLog(thriftbp.AccessLogConfig{
+			MethodSampleRates: map[string]float64{
+				"success": 0,
+				"fail":    0,
+			},
+			Logger: recorder.log,
+		}),
+	)
+	process := func(name string) {
+		t.Helper()
+		ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+		ctx = thrift.SetHeader(ctx, thriftbp.HeaderEdgeRequest, headerWithValidAuth)
+		defer func() {
+			if r := recover(); r != nil && name != "panic" {
+				t.Errorf("Unexpected panic: %v", r)
+			}
+		}()
+		wrapped.Process(ctx, nil, nil)
+	}
+
+	t.Run("sampled-out", func(t *testing.T) {
+		recorder.entries = nil
+		process("success")
+		if len(recorder.entries) != 0 {
+			t.Errorf("Expected successful request to be sampled out, got %+v", recorder.entries)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		recorder.entries = nil
+		process("fail")
+		if len(recorder.entries) != 1 {
+			t.Fatalf("Expected 1 entry, got %+v", recorder.entries)
+		}
+		entry := recorder.entries[0]
+		if entry.Endpoint != "fail" {
+			t.Errorf("Expected endpoint %q, got %q", "fail", entry.Endpoint)
+		}
+		if !errors.Is(entry.Err, errTest) {
+			t.Errorf("Expected error %v, got %v", errTest, entry.Err)
+		}
+		if !entry.HasErrorCode || entry.ErrorCode != thrift.INTERNAL_ERROR {
+			t.Errorf("Expected error code %d, got %d (%v)", thrift.INTERNAL_ERROR, entry.ErrorCode, entry.HasErrorCode)
+		}
+		if entry.TraceID == "" {
+			t.Error("Expected trace id to be set")
+		}
+		if entry.UserID != "t2_example" {
+			t.Errorf("Expected user id %q, got %q", "t2_example", entry.UserID)
+		}
+		if entry.PayloadSize != -1 {
+			t.Errorf("Expected unknown payload size, got %d", entry.PayloadSize)
+		}
+	})
+
+	t.Run("payload-size", func(t *testing.T) {
+		recorder.entries = nil
+		in, size := newTHeaderRequest(t, "read", "hello")
+		ctx := thriftbp.SetMockTProcessorName(context.Background(), "read")
+		if _, err := wrapped.Process(ctx, in, in); err != nil {
+			t.Fatal(err)
+		}
+		if len(recorder.entries) != 1 {
+			t.Fatalf("Expected 1 entry, got %+v", recorder.entries)
+		}
+		if entry := recorder.entries[0]; entry.PayloadSize != size {
+			t.Errorf("Expected payload size %d, got %d", size, entry.PayloadSize)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		recorder.entries = nil
+		process("panic")
+		if len(recorder.entries) != 1 {
+			t.Fatalf("Expected 1 entry, got %+v", recorder.entries)
+		}
+		entry := recorder.entries[0]
+		if entry.Success || !errors.Is(entry.Err, thriftbp.ErrAccessLogPanic) {
+			t.Errorf("Expected failed entry with ErrAccessLogPanic, got %+v", entry)
+		}
+	})
+}
+
+func TestAccessLogDefaultSampleRate(t *testing.T) {
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			"success": thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					return true, nil
+				},
+			},
+		},
+	)
+	recorder := &accessLogRecorder{}
+	wrapped := thrift.WrapProcessor(
+		processor,
+		thriftbp.AccessLog(thriftbp.AccessLogConfig{Logger: recorder.log}),
+	)
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), "success")
+	wrapped.Process(ctx, nil, nil)
+	if len(recorder.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %+v", recorder.entries)
+	}
+	if entry := recorder.entries[0]; !entry.Success || entry.Err != nil || entry.HasErrorCode {
+		t.Errorf("Expected successful entry, got %+v", entry)
+	}
+}
+
+// newTHeaderRequest returns the THeader protocol of the server side with a
+// request to the endpoint of name, with the message header already read,
+// and the size of the request payload.
+//
+// The payload of the request is the struct written by writeTestStruct.
+func newTHeaderRequest(t *testing.T, name, value string) (*thrift.THeaderProtocol, int64) {
+	t.Helper()
+
+	buf := thrift.NewTMemoryBuffer()
+	client := thrift.NewTHeaderProtocol(buf)
+	if err := client.WriteMessageBegin(name, thrift.CALL, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTestStruct(client, value); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := thrift.NewTMemoryBuffer()
+	if err := writeTestStruct(thrift.NewTBinaryProtocolTransport(payload), value); err != nil {
+		t.Fatal(err)
+	}
+
+	in := thrift.NewTHeaderProtocol(buf)
+	if _, _, _, err := in.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+	return in, int64(payload.Len())
+}
+
+// writeTestStruct writes a struct with a string field and an i32 field.
+func writeTestStruct(p thrift.TProtocol, s string) error {
+	if err := p.WriteStructBegin("test"); err != nil {
+		return err
+	}
+	if err := p.WriteFieldBegin("s", thrift.STRING, 1); err != nil {
+		return err
+	}
+	if err := p.WriteString(s); err != nil {
+		return err
+	}
+	if err := p.WriteFieldEnd(); err != nil {
+		return err
+	}
+	if err := p.WriteFieldBegin("i", thrift.I32, 2); err != nil {
+		return err
+	}
+	if err := p.WriteI32(int32(len(s))); err != nil {
+		return err
+	}
+	if err := p.WriteFieldEnd(); err != nil {
+		return err
+	}
+	if err := p.WriteFieldStop(); err != nil {
+		return err
+	}
+	return p.WriteStructEnd()
+}
+
+func TestAccessLogDeclaredException(t *testing.T) {
+	entries := make(chan thriftbp.AccessLogEntry, 1)
+	addr := startEchoServer(
+		t,
+		echoHandler{
+			err: func(message string) error {
+				return &baseplatetest.Error{
+					Code:      thrift.Int32Ptr(thriftbp.ErrorCodeNotFound),
+					Message:   thrift.StringPtr("not found"),
+					Details:   map[string]string{"foo": "bar"},
+					Retryable: thrift.BoolPtr(false),
+				}
+			},
+		},
+		thriftbp.AccessLog(thriftbp.AccessLogConfig{
+			Logger: func(ctx context.Context, entry thriftbp.AccessLogEntry) {
+				entries <- entry
+			},
+		}),
+	)
+	client := newEchoClient(t, addr)
+
+	if _, err := client.Echo(context.Background(), "hello"); err == nil {
+		t.Fatal("Expected declared exception, got nil")
+	}
+	entry := <-entries
+	if !entry.HasErrorCode || entry.ErrorCode != thriftbp.ErrorCodeNotFound {
+		t.Errorf("Expected error code %d, got %d (%v)", thriftbp.ErrorCodeNotFound, entry.ErrorCode, entry.HasErrorCode)
+	}
+	const expected = `baseplate.Error: "not found" (code=404, retryable=false, details=map[foo:bar])`
+	if entry.Err == nil || entry.Err.Error() != expected {
+		t.Errorf("Expected err %q, got %v", expected, entry.Err)
+	}
+}
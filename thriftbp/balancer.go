@@ -0,0 +1,380 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/reddit/baseplate.go/discovery"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// LoadBalancePolicy is the policy to pick among ClientPoolConfig.Addrs or the
+// backends of ClientPoolConfig.Discovery when opening a new connection.
+//
+// The policies take the weights of the backends from Discovery into account,
+// the backends in Addrs all have the same weight.
+type LoadBalancePolicy int
+
+// Supported LoadBalancePolicy values.
+const (
+	// LoadBalanceRoundRobin picks the backends in turn,
+	// each one as many times as its weight.
+	LoadBalanceRoundRobin LoadBalancePolicy = iota
+
+	// LoadBalanceLeastLoaded picks the backend with the fewest open
+	// connections from the pool relative to its weight.
+	LoadBalanceLeastLoaded
+
+	// LoadBalancePowerOfTwoChoices picks two backends randomly by their
+	// weights, and uses the one with fewer open connections from the pool
+	// relative to its weight.
+	LoadBalancePowerOfTwoChoices
+)
+
+func (p LoadBalancePolicy) String() string {
+	switch p {
+	default:
+		return fmt.Sprintf("LoadBalancePolicy(%d)", int(p))
+	case LoadBalanceRoundRobin:
+		return "round-robin"
+	case LoadBalanceLeastLoaded:
+		return "least-loaded"
+	case LoadBalancePowerOfTwoChoices:
+		return "power-of-two-choices"
+	}
+}
+
+// backendHealth is the health accounting of the backends,
+// implemented by discovery.Watcher and discovery.HealthTracker.
+type backendHealth interface {
+	ReportSuccess(addr string)
+	ReportFailure(addr string)
+	Ejected(addr string) bool
+}
+
+var (
+	_ backendHealth = (*discovery.Watcher)(nil)
+	_ backendHealth = (*discovery.HealthTracker)(nil)
+)
+
+// balancer picks the backend of every new connection of a client pool.
+type balancer struct {
+	policy LoadBalancePolicy
+	health backendHealth
+
+	// backends returns the current backends to pick from.
+	backends func() []discovery.Backend
+
+	// contains returns whether the backend with the address is still one of
+	// the current backends, nil when the backends never change.
+	contains func(addr string) bool
+
+	slug   string
+	labels []string
+
+	next uint64
+
+	lock  sync.Mutex
+	stats map[string]*backend
+}
+
+func newBalancer(cfg ClientPoolConfig) (*balancer, error) {
+	b := &balancer{
+		policy: cfg.LoadBalancePolicy,
+		slug:   cfg.ServiceSlug,
+		labels: cfg.MetricsLabels.AsStatsdLabels(),
+		stats:  make(map[string]*backend),
+	}
+	if cfg.Discovery != nil {
+		b.health = cfg.Discovery
+		b.backends = cfg.Discovery.Backends
+		b.contains = cfg.Discovery.Contains
+		return b, nil
+	}
+
+	backends := make([]discovery.Backend, 0, len(cfg.Addrs))
+	for _, addr := range cfg.Addrs {
+		be, err := parseBackend(addr)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, be)
+	}
+	b.health = discovery.NewHealthTracker(cfg.EjectFailures, cfg.EjectDuration)
+	b.backends = func() []discovery.Backend {
+		return backends
+	}
+	return b, nil
+}
+
+// parseBackend parses addr in "${host}:${port}" format into a
+// discovery.Backend with weight 1.
+func parseBackend(addr string) (discovery.Backend, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return discovery.Backend{}, fmt.Errorf("thriftbp: invalid address %q in Addrs: %w", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return discovery.Backend{}, fmt.Errorf("thriftbp: invalid port in address %q in Addrs: %w", addr, err)
+	}
+	return discovery.Backend{
+		Host:   host,
+		Port:   port,
+		Weight: 1,
+	}, nil
+}
+
+// candidate is a backend that can be picked, with its weight.
+type candidate struct {
+	*backend
+
+	weight int64
+}
+
+// less returns whether c has fewer open connections than other relative to
+// their weights.
+func (c candidate) less(other candidate) bool {
+	return c.load()*other.weight < other.load()*c.weight
+}
+
+// pick picks a backend by the policy.
+//
+// Backends with weight 0 are never picked.
+// Ejected backends are skipped, unless all the backends are ejected.
+func (b *balancer) pick() (*backend, error) {
+	backends := b.backends()
+	all := make([]candidate, 0, len(backends))
+	healthy := make([]candidate, 0, len(backends))
+
+	b.lock.Lock()
+	for _, be := range backends {
+		if be.Weight <= 0 {
+			continue
+		}
+		c := candidate{
+			backend: b.backendLocked(be.Addr()),
+			weight:  int64(be.Weight),
+		}
+		all = append(all, c)
+		if !b.health.Ejected(c.addr) {
+			healthy = append(healthy, c)
+		}
+	}
+	if len(b.stats) > len(backends) {
+		b.pruneLocked(backends)
+	}
+	b.lock.Unlock()
+
+	available := healthy
+	if len(available) == 0 {
+		available = all
+	}
+	n := len(available)
+	if n == 0 {
+		return nil, discovery.ErrNoBackends
+	}
+
+	switch b.policy {
+	default:
+		var total int64
+		for _, c := range available {
+			total += c.weight
+		}
+		i := int64(atomic.AddUint64(&b.next, 1) % uint64(total))
+		for _, c := range available {
+			i -= c.weight
+			if i < 0 {
+				return c.backend, nil
+			}
+		}
+		// Unreachable.
+		return available[n-1].backend, nil
+	case LoadBalanceLeastLoaded:
+		// Start from a random backend so that the ties are broken randomly.
+		offset := randbp.R.Intn(n)
+		best := available[offset]
+		for i := 1; i < n; i++ {
+			if c := available[(offset+i)%n]; c.less(best) {
+				best = c
+			}
+		}
+		return best.backend, nil
+	case LoadBalancePowerOfTwoChoices:
+		if n == 1 {
+			return available[0].backend, nil
+		}
+		i := pickWeighted(available, -1)
+		j := pickWeighted(available, i)
+		if available[j].less(available[i]) {
+			return available[j].backend, nil
+		}
+		return available[i].backend, nil
+	}
+}
+
+// pickWeighted picks the index of a candidate randomly by their weights,
+// excluding the candidate at index exclude.
+func pickWeighted(candidates []candidate, exclude int) int {
+	var total int64
+	for i, c := range candidates {
+		if i != exclude {
+			total += c.weight
+		}
+	}
+	n := randbp.R.Int63n(total)
+	for i, c := range candidates {
+		if i == exclude {
+			continue
+		}
+		n -= c.weight
+		if n < 0 {
+			return i
+		}
+	}
+	// Unreachable.
+	return len(candidates) - 1
+}
+
+// backendLocked returns the backend with the address,
+// creating it on the first use.
+//
+// b.lock must be held.
+func (b *balancer) backendLocked(addr string) *backend {
+	if be := b.stats[addr]; be != nil {
+		return be
+	}
+	labels := append(append([]string(nil), b.labels...), "backend", addr)
+	be := &backend{
+		addr:   addr,
+		health: b.health,
+		successCounter: metricsbp.M.Counter(
+			b.slug + ".backend.success",
+		).With(labels...),
+		failCounter: metricsbp.M.Counter(
+			b.slug + ".backend.fail",
+		).With(labels...),
+		latency: metricsbp.M.Timing(
+			b.slug + ".backend.latency",
+		).With(labels...),
+	}
+	b.stats[addr] = be
+	return be
+}
+
+// pruneLocked drops the removed backends without open connections.
+//
+// b.lock must be held.
+func (b *balancer) pruneLocked(backends []discovery.Backend) {
+	current := make(map[string]bool, len(backends))
+	for _, be := range backends {
+		current[be.Addr()] = true
+	}
+	for addr, be := range b.stats {
+		if !current[addr] && be.load() == 0 {
+			delete(b.stats, addr)
+		}
+	}
+}
+
+// backend is a backend of a client pool with its metrics and open
+// connections.
+type backend struct {
+	addr   string
+	health backendHealth
+
+	successCounter metrics.Counter
+	failCounter    metrics.Counter
+	latency        metrics.Histogram
+
+	// The number of open connections to the backend, accessed atomically.
+	conns int64
+}
+
+func (b *backend) load() int64 {
+	return atomic.LoadInt64(&b.conns)
+}
+
+// report reports the result of a connection attempt or a call to the backend.
+//
+// Only transport errors are counted as failures.
+func (b *backend) report(err error) {
+	var te thrift.TTransportException
+	if errors.As(err, &te) {
+		b.failCounter.Add(1)
+		b.health.ReportFailure(b.addr)
+		return
+	}
+	b.successCounter.Add(1)
+	b.health.ReportSuccess(b.addr)
+}
+
+// balancedClient wraps a Client connected to a backend picked by a balancer.
+type balancedClient struct {
+	Client
+
+	backend   *backend
+	contains  func(addr string) bool
+	closeOnce sync.Once
+}
+
+func newBalancedClient(c Client, b *backend, contains func(addr string) bool) *balancedClient {
+	atomic.AddInt64(&b.conns, 1)
+	return &balancedClient{
+		Client:   c,
+		backend:  b,
+		contains: contains,
+	}
+}
+
+// Call calls the underlying Client's Call function,
+// and reports the result and latency to the backend.
+func (c *balancedClient) Call(ctx context.Context, method string, args, result thrift.TStruct) error {
+	timer := metricsbp.NewTimer(c.backend.latency)
+	err := c.Client.Call(ctx, method, args, result)
+	timer.ObserveDuration()
+	c.backend.report(err)
+	return err
+}
+
+// Close calls the underlying Client's Close function.
+func (c *balancedClient) Close() error {
+	c.release()
+	return c.Client.Close()
+}
+
+// IsOpen calls the underlying Client's IsOpen function.
+//
+// When the backend is no longer one of the current backends,
+// it closes the underlying Client and returns false,
+// so the connections are rebalanced to the current backends as the pool opens
+// new ones.
+//
+// As the pool drops the clients no longer open without closing them,
+// it also stops counting the connection towards the backend's load.
+func (c *balancedClient) IsOpen() bool {
+	if !c.Client.IsOpen() {
+		c.release()
+		return false
+	}
+	if c.contains != nil && !c.contains(c.backend.addr) {
+		c.Close()
+		return false
+	}
+	return true
+}
+
+func (c *balancedClient) release() {
+	c.closeOnce.Do(func() {
+		atomic.AddInt64(&c.backend.conns, -1)
+	})
+}
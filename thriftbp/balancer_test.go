@@ -0,0 +1,192 @@
+package thriftbp_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+// newBalancedClientPool creates a client pool over the addrs.
+func newBalancedClientPool(t *testing.T, cfg thriftbp.ClientPoolConfig) thriftbp.ClientPool {
+	t.Helper()
+
+	cfg.ServiceSlug = "test"
+	cfg.MaxConnections = 10
+	pool, err := thriftbp.NewCustomClientPool(
+		cfg,
+		nil,
+		func(thriftbp.TClientFactory, thrift.TTransport, thrift.TProtocolFactory) thriftbp.Client {
+			return &thriftbp.MockClient{}
+		},
+		thriftbp.StandardTClientFactory,
+		thrift.NewTBinaryProtocolFactoryDefault(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+	return pool
+}
+
+// acceptCounter counts the connections accepted by the listeners.
+type acceptCounter struct {
+	lock     sync.Mutex
+	accepted map[string]int
+}
+
+func (c *acceptCounter) get(addr string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.accepted[addr]
+}
+
+// waitFor waits for the number of connections accepted by addr to reach n,
+// and returns the final number.
+func (c *acceptCounter) waitFor(addr string, n int) int {
+	deadline := time.Now().Add(time.Second)
+	for c.get(addr) < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return c.get(addr)
+}
+
+func listenAddrs(t *testing.T, n int) ([]string, *acceptCounter) {
+	t.Helper()
+
+	counter := &acceptCounter{accepted: make(map[string]int)}
+	addrs := make([]string, n)
+	for i := range addrs {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			ln.Close()
+		})
+		addr := ln.Addr().String()
+		addrs[i] = addr
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				counter.lock.Lock()
+				counter.accepted[addr]++
+				counter.lock.Unlock()
+			}
+		}()
+	}
+	return addrs, counter
+}
+
+func TestClientPoolLoadBalancing(t *testing.T) {
+	for _, c := range []struct {
+		policy   thriftbp.LoadBalancePolicy
+		backends int
+		clients  int
+	}{
+		{
+			policy:   thriftbp.LoadBalanceRoundRobin,
+			backends: 3,
+			clients:  6,
+		},
+		{
+			policy:   thriftbp.LoadBalanceLeastLoaded,
+			backends: 3,
+			clients:  6,
+		},
+		{
+			// With 2 backends, both of them are always compared.
+			policy:   thriftbp.LoadBalancePowerOfTwoChoices,
+			backends: 2,
+			clients:  4,
+		},
+	} {
+		t.Run(c.policy.String(), func(t *testing.T) {
+			addrs, accepted := listenAddrs(t, c.backends)
+			pool := newBalancedClientPool(t, thriftbp.ClientPoolConfig{
+				Addrs:             addrs,
+				LoadBalancePolicy: c.policy,
+			})
+
+			// Hold all the clients so every GetClient opens a new connection.
+			for i := 0; i < c.clients; i++ {
+				client, err := pool.GetClient()
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer pool.ReleaseClient(client)
+			}
+			for _, addr := range addrs {
+				if n := accepted.waitFor(addr, c.clients/c.backends); n != c.clients/c.backends {
+					t.Errorf("Expected %d connections to %s, got %d", c.clients/c.backends, addr, n)
+				}
+			}
+		})
+	}
+}
+
+func TestClientPoolLoadBalancingEject(t *testing.T) {
+	const ejectDuration = time.Millisecond * 20
+
+	recorder := metricstest.Replace(t)
+	addrs, _ := listenAddrs(t, 2)
+	good := addrs[0]
+	// Closing the listener makes the connections to it fail.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := ln.Addr().String()
+	ln.Close()
+
+	pool := newBalancedClientPool(t, thriftbp.ClientPoolConfig{
+		// Round robin starts from the second backend.
+		Addrs:         []string{bad, good},
+		EjectFailures: 1,
+		EjectDuration: ejectDuration,
+	})
+
+	getClients := func(n int) (failures int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			client, err := pool.GetClient()
+			if err != nil {
+				failures++
+				continue
+			}
+			defer pool.ReleaseClient(client)
+			if err := client.Call(context.Background(), "foo", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return failures
+	}
+
+	if failures := getClients(4); failures != 1 {
+		t.Errorf("Expected 1 failure before the backend is ejected, got %d", failures)
+	}
+	recorder.AssertCounterEquals(t, "test.backend.fail,backend="+bad, 1)
+	recorder.AssertCounterEquals(t, "test.backend.success,backend="+good, 3)
+	recorder.AssertHistogramCount(t, "test.backend.latency,backend="+good, 3)
+
+	// After the ejection, the backend is probed again and ejected right away.
+	// The first 3 clients are reused from the pool,
+	// and the first new connection goes to the good backend in turn.
+	time.Sleep(ejectDuration * 2)
+	if failures := getClients(6); failures != 1 {
+		t.Errorf("Expected 1 failure probing the ejected backend, got %d", failures)
+	}
+	recorder.AssertCounterEquals(t, "test.backend.fail,backend="+bad, 2)
+}
@@ -0,0 +1,31 @@
+package thriftbp
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/breakerbp"
+)
+
+// CircuitBreaker returns a thrift.ClientMiddleware that guards the calls with
+// the given circuit breaker.
+//
+// When the breaker rejects a call, the call fails with either
+// breakerbp.ErrOpen or breakerbp.ErrTooManyRequests without reaching the
+// server.
+//
+// The same breaker should be shared by all the clients talking to the same
+// downstream service, e.g. by passing the middleware into
+// NewBaseplateClientPool.
+func CircuitBreaker(breaker *breakerbp.FailureRatioBreaker) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) error {
+				return breaker.Execute(func() error {
+					return next.Call(ctx, method, args, result)
+				})
+			},
+		}
+	}
+}
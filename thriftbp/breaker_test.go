@@ -0,0 +1,45 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls int
+	callErr := errors.New("call error")
+	mock := &thriftbp.MockClient{FailUnregisteredMethods: true}
+	mock.AddMockCall(
+		method,
+		func(ctx context.Context, args, result thrift.TStruct) error {
+			calls++
+			return callErr
+		},
+	)
+	client := thrift.WrapClient(
+		mock,
+		thriftbp.CircuitBreaker(breakerbp.NewFailureRatioBreaker(breakerbp.Config{
+			Name:              "test",
+			MinRequestsToTrip: 2,
+			FailureThreshold:  1,
+		})),
+	)
+
+	for i := 0; i < 2; i++ {
+		if err := client.Call(context.Background(), method, nil, nil); !errors.Is(err, callErr) {
+			t.Errorf("Expected error %v, got %v", callErr, err)
+		}
+	}
+	if err := client.Call(context.Background(), method, nil, nil); !errors.Is(err, breakerbp.ErrOpen) {
+		t.Errorf("Expected error %v, got %v", breakerbp.ErrOpen, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls reaching the client, got %d", calls)
+	}
+}
@@ -0,0 +1,55 @@
+package thriftbp
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+type clientCallContextKey int
+
+const (
+	clientTransportKey clientCallContextKey = iota
+)
+
+// clientTransport is the thrift.TTransport wrapping the socket of a client
+// connection created by a ClientPool, counting the bytes written.
+//
+// It's attached to the context object of every call made through the
+// connection by clientTransportClient,
+// so MonitorClientWithArgs can tag the client spans with the address of the
+// connection and the size of the request.
+type clientTransport struct {
+	thrift.TTransport
+
+	addr    string
+	written int64
+}
+
+func (t *clientTransport) Write(p []byte) (int, error) {
+	n, err := t.TTransport.Write(p)
+	t.written += int64(n)
+	return n, err
+}
+
+func clientTransportFromContext(ctx context.Context) (trans *clientTransport, ok bool) {
+	trans, ok = ctx.Value(clientTransportKey).(*clientTransport)
+	return trans, ok && trans != nil
+}
+
+// clientTransportClient wraps a Client to attach its clientTransport to the
+// context object of every call.
+type clientTransportClient struct {
+	Client
+
+	trans *clientTransport
+}
+
+func (c clientTransportClient) Call(ctx context.Context, method string, args, result thrift.TStruct) error {
+	return c.Client.Call(
+		context.WithValue(ctx, clientTransportKey, c.trans),
+		method,
+		args,
+		result,
+	)
+}
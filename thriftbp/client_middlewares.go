@@ -2,6 +2,7 @@ package thriftbp
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"time"
 
@@ -30,36 +31,185 @@ func BaseplateDefaultClientMiddlewares() []thrift.ClientMiddleware {
 	}
 }
 
+// WrapClient wraps the given thrift.TClient with
+// BaseplateDefaultClientMiddlewares plus any additional middlewares passed in.
+//
+// It's the client side counterpart of the processor middlewares applied by
+// NewBaseplateServer.
+// Middlewares will be called in the order that they are defined, with
+// BaseplateDefaultClientMiddlewares always come first.
+//
+// If you are using a thrift ClientPool created by NewBaseplateClientPool,
+// the clients are already wrapped and you should not call WrapClient on them.
+// WrapClient is provided for cases where you manage the underlying
+// thrift.TClient yourself.
+func WrapClient(client thrift.TClient, middlewares ...thrift.ClientMiddleware) thrift.TClient {
+	return thrift.WrapClient(client, withDefaultClientMiddlewares(middlewares)...)
+}
+
+func withDefaultClientMiddlewares(middlewares []thrift.ClientMiddleware) []thrift.ClientMiddleware {
+	return withMonitoredDefaultClientMiddlewares(MonitorClient, middlewares)
+}
+
+// withMonitoredDefaultClientMiddlewares is the same as
+// withDefaultClientMiddlewares, with MonitorClient replaced by monitor.
+func withMonitoredDefaultClientMiddlewares(monitor thrift.ClientMiddleware, middlewares []thrift.ClientMiddleware) []thrift.ClientMiddleware {
+	defaults := BaseplateDefaultClientMiddlewares()
+	// MonitorClient is always the first one in the defaults.
+	defaults[0] = monitor
+	wrappers := make([]thrift.ClientMiddleware, 0, len(defaults)+len(middlewares))
+	wrappers = append(wrappers, defaults...)
+	return append(wrappers, middlewares...)
+}
+
+// Span tags set by MonitorClientWithArgs on the client spans.
+const (
+	SpanTagKeyPeerAddress = "peer.address"
+	SpanTagKeyProtocol    = "thrift.protocol"
+	SpanTagKeyPayloadSize = "thrift.payload_size"
+	SpanTagKeyErrorType   = "thrift.error_type"
+)
+
+// Values of the SpanTagKeyErrorType tag.
+const (
+	SpanErrorTypeTransport   = "transport"
+	SpanErrorTypeApplication = "application"
+)
+
+// MonitorClientArgs are the args to be passed into MonitorClientWithArgs.
+type MonitorClientArgs struct {
+	// ServiceSlug is used as the prefix of the span names,
+	// in the format of "${ServiceSlug}.${method}".
+	//
+	// When ServiceSlug is empty, the span names are just the method names.
+	ServiceSlug string
+
+	// PeerAddress is the address of the thrift server being called,
+	// reported as the SpanTagKeyPeerAddress tag when non-empty.
+	//
+	// It's only used when the call is not made through a ClientPool,
+	// which reports the address of the connection serving the call instead.
+	PeerAddress string
+
+	// Protocol is the name of the thrift protocol used (e.g. "header"),
+	// reported as the SpanTagKeyProtocol tag when non-empty.
+	Protocol string
+}
+
 // MonitorClient is a ClientMiddleware that wraps the inner thrift.TClient.Call
 // in a thrift client span.
 //
+// It's equivalent to MonitorClientWithArgs with empty args.
+//
 // If you are using a thrift ClientPool created by NewBaseplateClientPool,
 // this will be included automatically and should not be passed in as a
 // ClientMiddleware to NewBaseplateClientPool.
 func MonitorClient(next thrift.TClient) thrift.TClient {
-	return thrift.WrappedTClient{
-		Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (err error) {
-			span, ctx := opentracing.StartSpanFromContext(
-				ctx,
-				method,
-				tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
-			)
-			ctx = CreateThriftContextFromSpan(ctx, tracing.AsSpan(span))
-			defer func() {
-				span.FinishWithOptions(tracing.FinishOptions{
-					Ctx: ctx,
-					Err: err,
-				}.Convert())
-			}()
+	return MonitorClientWithArgs(MonitorClientArgs{})(next)
+}
 
-			return next.Call(ctx, method, args, result)
-		},
+// MonitorClientWithArgs returns a ClientMiddleware that wraps the inner
+// thrift.TClient.Call in a thrift client span named after args.ServiceSlug and
+// the method.
+//
+// For sampled spans, it also tags the span with the peer address, protocol,
+// and the size of the request.
+// When the call is made through a ClientPool,
+// the peer address is the address of the connection serving the call,
+// and the request size is the number of bytes written to that connection.
+// Otherwise args.PeerAddress is used, and the request size is not tagged.
+// When the call fails with a thrift.TTransportException or
+// thrift.TApplicationException, the span is tagged with the type of the error
+// in addition to being marked as failed.
+//
+// When there's a requestevent.Event attached to the context object,
+// it also adds the number of calls, failed calls, and the total duration of
+// the calls to the "thrift.<slug>.calls", "thrift.<slug>.errors", and
+// "thrift.<slug>.duration_ms" fields of the event.
+//
+// If you are using a thrift ClientPool created by NewBaseplateClientPool,
+// this will be included automatically and should not be passed in as a
+// ClientMiddleware to NewBaseplateClientPool.
+func MonitorClientWithArgs(args MonitorClientArgs) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, req, result thrift.TStruct) (err error) {
+				name := method
+				if args.ServiceSlug != "" {
+					name = args.ServiceSlug + "." + method
+				}
+				span, ctx := opentracing.StartSpanFromContext(
+					ctx,
+					name,
+					tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+				)
+				ctx = CreateThriftContextFromSpan(ctx, tracing.AsSpan(span))
+				sampled := tracing.AsSpan(span).Sampled()
+				trans, hasTrans := clientTransportFromContext(ctx)
+				var written int64
+				if hasTrans {
+					written = trans.written
+				}
+				start := time.Now()
+				defer func() {
+					addClientCallToRequestEvent(ctx, args.ServiceSlug, start, err)
+					if sampled {
+						peer := args.PeerAddress
+						var size int64
+						if hasTrans {
+							peer = trans.addr
+							size = trans.written - written
+						}
+						setClientSpanTags(span, peer, args.Protocol, size)
+					}
+					if errType := clientErrorType(err); errType != "" {
+						span.SetTag(SpanTagKeyErrorType, errType)
+					}
+					span.FinishWithOptions(tracing.FinishOptions{
+						Ctx: ctx,
+						Err: err,
+					}.Convert())
+				}()
+
+				return next.Call(ctx, method, req, result)
+			},
+		}
+	}
+}
+
+func setClientSpanTags(span opentracing.Span, peer, protocol string, size int64) {
+	if peer != "" {
+		span.SetTag(SpanTagKeyPeerAddress, peer)
+	}
+	if protocol != "" {
+		span.SetTag(SpanTagKeyProtocol, protocol)
+	}
+	if size > 0 {
+		span.SetTag(SpanTagKeyPayloadSize, size)
 	}
 }
 
+func clientErrorType(err error) string {
+	var transportErr thrift.TTransportException
+	if errors.As(err, &transportErr) {
+		return SpanErrorTypeTransport
+	}
+	var appErr thrift.TApplicationException
+	if errors.As(err, &appErr) {
+		return SpanErrorTypeApplication
+	}
+	return ""
+}
+
 // ForwardEdgeRequestContext forwards the EdgeRequestContext set on the context
 // object to the Thrift service being called if one is set.
 //
+// If there's no EdgeRequestContext set on the context object,
+// but the context object is from an incoming thrift request with the
+// "Edge-Request" header
+// (e.g. the server doesn't use InjectEdgeContext),
+// the incoming header is forwarded as-is instead.
+//
 // If you are using a thrift ClientPool created by NewBaseplateClientPool,
 // this will be included automatically and should not be passed in as a
 // ClientMiddleware to NewBaseplateClientPool.
@@ -68,40 +218,88 @@ func ForwardEdgeRequestContext(next thrift.TClient) thrift.TClient {
 		Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (err error) {
 			if ec, ok := edgecontext.GetEdgeContext(ctx); ok {
 				ctx = AttachEdgeRequestContext(ctx, ec)
+			} else if _, ok := thrift.GetHeader(ctx, HeaderEdgeRequest); ok {
+				ctx = addToWriteHeaderList(ctx, HeaderEdgeRequest)
 			}
 			return next.Call(ctx, method, args, result)
 		},
 	}
 }
 
+// addToWriteHeaderList adds key to the write header list of ctx,
+// if it's not already in the list.
+func addToWriteHeaderList(ctx context.Context, key string) context.Context {
+	headers := thrift.GetWriteHeaderList(ctx)
+	for _, header := range headers {
+		if header == key {
+			return ctx
+		}
+	}
+	return thrift.SetWriteHeaderList(ctx, append(headers, key))
+}
+
 // SetDeadlineBudget is the client middleware implementing Phase 1 of Baseplate
 // deadline propogation.
+//
+// It's the same as SetDeadlineBudgetWithBuffer(0).
 func SetDeadlineBudget(next thrift.TClient) thrift.TClient {
-	return thrift.WrappedTClient{
-		Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) error {
-			if ctx.Err() != nil {
-				// Deadline already passed, no need to even try
-				return ctx.Err()
-			}
+	return SetDeadlineBudgetWithBuffer(0)(next)
+}
+
+// SetDeadlineBudgetWithBuffer returns a client middleware implementing Phase 1
+// of Baseplate deadline propogation,
+// with buffer subtracted from the remaining deadline budget written to the
+// header.
+//
+// The buffer is meant to cover the network round trip and the time needed to
+// handle the response, so the server won't keep working on a request the
+// client is going to give up on anyway.
+//
+// If the context deadline already passed,
+// or the remaining budget minus the buffer is less than 1ms,
+// the call fails immediately with context.DeadlineExceeded (or the ctx.Err())
+// without reaching the server.
+//
+// SetDeadlineBudget is already included in BaseplateDefaultClientMiddlewares.
+// To use a buffer, pass SetDeadlineBudgetWithBuffer into NewBaseplateClientPool
+// or WrapClient as an additional middleware,
+// which overrides the header set by SetDeadlineBudget.
+func SetDeadlineBudgetWithBuffer(buffer time.Duration) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) error {
+				if ctx.Err() != nil {
+					// Deadline already passed, no need to even try
+					return ctx.Err()
+				}
 
-			if deadline, ok := ctx.Deadline(); ok {
-				// Round up to the next millisecond.
-				// In the scenario that the caller set an 10ms timeout and send the
-				// request, by the time we get into this middleware function it's
-				// definitely gonna be less than 10ms.
-				// If we use round down then we are only gonna send 9 over the wire.
-				timeout := deadline.Sub(time.Now()) + time.Millisecond - 1
-				ms := timeout.Milliseconds()
-				if ms < 1 {
-					// Make sure we give it at least 1ms.
-					ms = 1
+				if deadline, ok := ctx.Deadline(); ok {
+					// Round up to the next millisecond.
+					// In the scenario that the caller set an 10ms timeout and send the
+					// request, by the time we get into this middleware function it's
+					// definitely gonna be less than 10ms.
+					// If we use round down then we are only gonna send 9 over the wire.
+					timeout := deadline.Sub(time.Now()) + time.Millisecond - 1
+					if buffer > 0 {
+						timeout -= buffer
+						if timeout < time.Millisecond {
+							// Not enough budget left after the buffer.
+							return context.DeadlineExceeded
+						}
+					}
+					ms := timeout.Milliseconds()
+					if ms < 1 {
+						// Make sure we give it at least 1ms.
+						ms = 1
+					}
+					value := strconv.FormatInt(ms, 10)
+					ctx = thrift.SetHeader(ctx, HeaderDeadlineBudget, value)
+					ctx = addToWriteHeaderList(ctx, HeaderDeadlineBudget)
 				}
-				value := strconv.FormatInt(ms, 10)
-				ctx = thrift.SetHeader(ctx, HeaderDeadlineBudget, value)
-			}
 
-			return next.Call(ctx, method, args, result)
-		},
+				return next.Call(ctx, method, args, result)
+			},
+		}
 	}
 }
 
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -12,9 +14,11 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go/edgecontext"
+	bpgen "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 	"github.com/reddit/baseplate.go/mqsend"
 	"github.com/reddit/baseplate.go/thriftbp"
 	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
 )
 
 const method = "testMethod"
@@ -249,7 +253,7 @@ func TestForwardEdgeRequestContextNotSet(t *testing.T) {
 	}
 }
 
-func TesetSetDeadlineBudget(t *testing.T) {
+func TestSetDeadlineBudget(t *testing.T) {
 	mock, recorder, client := initClients()
 	mock.AddMockCall(
 		method,
@@ -304,3 +308,281 @@ func TesetSetDeadlineBudget(t *testing.T) {
 		},
 	)
 }
+
+func TestSetDeadlineBudgetWithBuffer(t *testing.T) {
+	mock := &thriftbp.MockClient{FailUnregisteredMethods: true}
+	mock.AddNopMockCalls(method)
+	recorder := thriftbp.NewRecordedClient(mock)
+	client := thrift.WrapClient(
+		recorder,
+		thriftbp.SetDeadlineBudgetWithBuffer(time.Millisecond*100),
+	)
+
+	t.Run(
+		"exhausted",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+			defer cancel()
+
+			err := client.Call(ctx, method, nil, nil)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+			}
+			if len(recorder.Calls()) != 0 {
+				t.Fatalf("Wrong number of calls: %d", len(recorder.Calls()))
+			}
+		},
+	)
+
+	t.Run(
+		"buffered",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if err := client.Call(ctx, method, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if len(recorder.Calls()) != 1 {
+				t.Fatalf("Wrong number of calls: %d", len(recorder.Calls()))
+			}
+
+			ctx = recorder.Calls()[0].Ctx
+			v, ok := thrift.GetHeader(ctx, thriftbp.HeaderDeadlineBudget)
+			if !ok {
+				t.Fatalf("%s header not set", thriftbp.HeaderDeadlineBudget)
+			}
+			ms, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ms > 900 || ms < 800 {
+				t.Errorf(
+					"Expected around 900 in header %s, got %q",
+					thriftbp.HeaderDeadlineBudget,
+					v,
+				)
+			}
+		},
+	)
+}
+
+func TestWrapClient(t *testing.T) {
+	mock := &thriftbp.MockClient{FailUnregisteredMethods: true}
+	mock.AddNopMockCalls(method)
+
+	var order []string
+	record := func(name string) thrift.ClientMiddleware {
+		return func(next thrift.TClient) thrift.TClient {
+			return thrift.WrappedTClient{
+				Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) error {
+					order = append(order, name)
+					if _, ok := thrift.GetHeader(ctx, thriftbp.HeaderDeadlineBudget); !ok {
+						t.Errorf("%s: expected %s header to be set by the default middlewares", name, thriftbp.HeaderDeadlineBudget)
+					}
+					return next.Call(ctx, method, args, result)
+				},
+			}
+		}
+	}
+	client := thriftbp.WrapClient(mock, record("first"), record("second"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Call(ctx, method, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Expected middlewares to be called in order [first second], got %v", order)
+	}
+}
+
+func TestForwardEdgeRequestContextRawHeader(t *testing.T) {
+	mock, recorder, client := initClients()
+	mock.AddMockCall(
+		method,
+		func(ctx context.Context, args, result thrift.TStruct) error {
+			return nil
+		},
+	)
+
+	// No EdgeRequestContext set on ctx, only the incoming header.
+	ctx := thrift.SetHeader(
+		context.Background(),
+		thriftbp.HeaderEdgeRequest,
+		headerWithValidAuth,
+	)
+	if err := client.Call(ctx, method, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorder.Calls()) != 1 {
+		t.Fatalf("wrong number of calls: %d", len(recorder.Calls()))
+	}
+
+	ctx = recorder.Calls()[0].Ctx
+	var found int
+	for _, key := range thrift.GetWriteHeaderList(ctx) {
+		if key == thriftbp.HeaderEdgeRequest {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Errorf("Expected header added to thrift write list once, got %d", found)
+	}
+
+	header, ok := thrift.GetHeader(ctx, thriftbp.HeaderEdgeRequest)
+	if !ok {
+		t.Fatal("header not set")
+	}
+	if header != headerWithValidAuth {
+		t.Errorf("header mismatch, expected %q, got %q", headerWithValidAuth, header)
+	}
+}
+
+func TestMonitorClientWithArgs(t *testing.T) {
+	args := thriftbp.MonitorClientArgs{
+		ServiceSlug: "test-service",
+		PeerAddress: "localhost:9090",
+		Protocol:    "header",
+	}
+	const expectedName = "test-service." + method
+
+	cases := []struct {
+		name          string
+		err           error
+		expectedError string
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:          "transport-error",
+			err:           thrift.NewTTransportException(thrift.TIMED_OUT, "timeout"),
+			expectedError: thriftbp.SpanErrorTypeTransport,
+		},
+		{
+			name: "application-error",
+			err: thrift.NewTApplicationException(
+				thrift.INTERNAL_ERROR,
+				"internal error",
+			),
+			expectedError: thriftbp.SpanErrorTypeApplication,
+		},
+		{
+			name: "other-error",
+			err:  errors.New("test error"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := tracingtest.InitGlobalTracer(t)
+
+			mock := &thriftbp.MockClient{FailUnregisteredMethods: true}
+			mock.AddMockCall(
+				method,
+				func(ctx context.Context, args, result thrift.TStruct) error {
+					return c.err
+				},
+			)
+			client := thrift.WrapClient(mock, thriftbp.MonitorClientWithArgs(args))
+
+			span, ctx := opentracing.StartSpanFromContext(
+				context.Background(),
+				"server",
+				tracing.SpanTypeOption{Type: tracing.SpanTypeServer},
+			)
+			err := client.Call(ctx, method, bpgen.NewBaseplateServiceIsHealthyArgs(), nil)
+			span.Finish()
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v, got %v", c.err, err)
+			}
+
+			clientSpan := recorder.MustFindSpan(t, expectedName)
+			if parent, ok := recorder.Parent(clientSpan); !ok || parent.Name != "server" {
+				t.Errorf("Expected client span to be a child of the server span, got %+v", parent)
+			}
+			for key, expected := range map[string]string{
+				thriftbp.SpanTagKeyPeerAddress: args.PeerAddress,
+				thriftbp.SpanTagKeyProtocol:    args.Protocol,
+			} {
+				if actual, _ := clientSpan.Tag(key); actual != expected {
+					t.Errorf("Expected tag %q to be %q, got %v", key, expected, actual)
+				}
+			}
+			// The mock client doesn't write to any transport.
+			if size, ok := clientSpan.Tag(thriftbp.SpanTagKeyPayloadSize); ok {
+				t.Errorf("Expected no %q tag, got %v", thriftbp.SpanTagKeyPayloadSize, size)
+			}
+			errType, ok := clientSpan.Tag(thriftbp.SpanTagKeyErrorType)
+			if c.expectedError == "" {
+				if ok {
+					t.Errorf("Expected no %q tag, got %v", thriftbp.SpanTagKeyErrorType, errType)
+				}
+			} else if errType != c.expectedError {
+				t.Errorf("Expected tag %q to be %q, got %v", thriftbp.SpanTagKeyErrorType, c.expectedError, errType)
+			}
+			if clientSpan.IsError() != (c.err != nil) {
+				t.Errorf("Expected span error to be %v, got %v", c.err != nil, clientSpan.IsError())
+			}
+		})
+	}
+}
+
+func TestMonitorClientWithArgsClientPool(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	server, err := thriftbp.NewServer(
+		thriftbp.ServerConfig{
+			Addr:    "127.0.0.1:0",
+			Timeout: time.Second,
+			Logger:  thrift.NopLogger,
+		},
+		bpgen.NewBaseplateServiceProcessor(healthyHandler{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go server.AcceptLoop()
+	defer server.Stop()
+	addr := server.ServerTransport().(*thrift.TServerSocket).Addr().String()
+
+	pool, err := thriftbp.NewBaseplateClientPool(
+		thriftbp.ClientPoolConfig{
+			ServiceSlug:    "test-service",
+			Addr:           addr,
+			MaxConnections: 1,
+			SocketTimeout:  time.Second,
+		},
+		time.Minute,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	client := bpgen.NewBaseplateServiceClient(thriftbp.NewPooledTClient(pool))
+	span, ctx := opentracing.StartSpanFromContext(
+		context.Background(),
+		"server",
+		tracing.SpanTypeOption{Type: tracing.SpanTypeServer},
+	)
+	_, err = client.IsHealthy(ctx)
+	span.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientSpan := recorder.MustFindSpan(t, "test-service.is_healthy")
+	if actual, _ := clientSpan.Tag(thriftbp.SpanTagKeyPeerAddress); actual != addr {
+		t.Errorf("Expected tag %q to be %q, got %v", thriftbp.SpanTagKeyPeerAddress, addr, actual)
+	}
+	size, _ := clientSpan.Tag(thriftbp.SpanTagKeyPayloadSize)
+	if n, err := strconv.Atoi(fmt.Sprint(size)); err != nil || n <= 0 {
+		t.Errorf("Expected tag %q to be a positive size, got %v", thriftbp.SpanTagKeyPayloadSize, size)
+	}
+}
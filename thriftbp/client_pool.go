@@ -10,6 +10,7 @@ import (
 	"github.com/go-kit/kit/metrics"
 
 	"github.com/reddit/baseplate.go/clientpool"
+	"github.com/reddit/baseplate.go/discovery"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/metricsbp"
 )
@@ -35,6 +36,58 @@ type ClientPoolConfig struct {
 	// "${host}:${port}"
 	Addr string
 
+	// Addrs are the addresses of multiple backends of the thrift service,
+	// in the same format as Addr.
+	//
+	// When Addrs is non-empty, the address of every new connection is picked
+	// from Addrs by LoadBalancePolicy instead,
+	// ignoring Addr and the AddressGenerator passed into NewCustomClientPool.
+	// Addrs can't be used together with Discovery.
+	//
+	// The health of the backends in Addrs is tracked by a
+	// discovery.HealthTracker created with EjectFailures and EjectDuration,
+	// the same way as the backends of Discovery:
+	// backends failing EjectFailures times in a row with transport errors,
+	// either connecting or calling, are ejected for EjectDuration.
+	// After that the backend is probed by the next connection:
+	// a success brings it back, a failure ejects it again.
+	// When all the backends are ejected, they are all picked as usual.
+	//
+	// For each backend in Addrs or Discovery, it reports the following
+	// metrics, with MetricsLabels and an additional "backend" label of the
+	// address:
+	//
+	// - the number of successful calls to a counter named
+	// "${ServiceSlug}.backend.success".
+	//
+	// - the number of failed calls and connection attempts to a counter
+	// named "${ServiceSlug}.backend.fail".
+	//
+	// - the latency of the calls to a timing named
+	// "${ServiceSlug}.backend.latency".
+	Addrs []string
+
+	// LoadBalancePolicy is the policy to pick among Addrs or the backends of
+	// Discovery.
+	//
+	// Default to LoadBalanceRoundRobin.
+	LoadBalancePolicy LoadBalancePolicy
+
+	// EjectFailures is the number of consecutive failures to eject a backend
+	// in Addrs.
+	//
+	// Default to discovery.DefaultFailureThreshold.
+	// It can't be used together with Discovery,
+	// use discovery.Config.FailureThreshold instead.
+	EjectFailures int
+
+	// EjectDuration is the duration a backend in Addrs is ejected for.
+	//
+	// Default to discovery.DefaultEjectDuration.
+	// It can't be used together with Discovery,
+	// use discovery.Config.EjectDuration instead.
+	EjectDuration time.Duration
+
 	// InitialConnections is the inital number of thrift connections created by
 	// the client pool.
 	InitialConnections int
@@ -71,6 +124,61 @@ type ClientPoolConfig struct {
 	// When PoolGaugeInterval <= 0 and ReportPoolStats is true,
 	// DefaultPoolGaugeInterval will be used instead.
 	PoolGaugeInterval time.Duration
+
+	// IdleTimeout is the max amount of time a connection is allowed to sit in
+	// the pool unused.
+	//
+	// Connections that have been idle for longer than IdleTimeout are closed
+	// and replaced with new ones instead of being handed out by GetClient.
+	// This is useful when there's a load balancer or a server in between that
+	// silently drops idle connections.
+	//
+	// When IdleTimeout <= 0, connections never expire for being idle.
+	// The max age of a connection is controlled by the ttl arg passed into
+	// NewBaseplateClientPool instead.
+	IdleTimeout time.Duration
+
+	// AcquireTimeout is the max amount of time GetClient waits for a
+	// connection to be released back to the pool when the pool is exhausted.
+	//
+	// When AcquireTimeout <= 0, GetClient fails immediately when the pool is
+	// exhausted.
+	AcquireTimeout time.Duration
+
+	// When TLS is non-nil, the connections are established over TLS,
+	// using the certificates from the secrets store.
+	//
+	// The secrets are read again for every new connection,
+	// so the connections opened after a rotation use the new certificates.
+	// The connections already in the pool are not affected,
+	// use the ttl of NewBaseplateClientPool or IdleTimeout to recycle them.
+	TLS *TLSConfig
+
+	// When Discovery is non-nil, the address of every new connection is picked
+	// from the backends of Discovery by LoadBalancePolicy instead,
+	// ignoring Addr and the AddressGenerator passed into NewCustomClientPool.
+	//
+	// The results of the connection attempts and the calls are reported to
+	// Discovery for its health accounting,
+	// only transport errors are reported as failures.
+	// Connections to the backends removed from Discovery are closed when they
+	// are next taken from the pool, so the connections are rebalanced to the
+	// current backends as the pool opens new ones.
+	//
+	// See Addrs for the metrics reported for each backend.
+	Discovery *discovery.Watcher
+
+	// MaxFrameSize is the max size in bytes of the frames sent to and received
+	// from the server.
+	//
+	// Requests and responses larger than MaxFrameSize fail with
+	// FrameTooLargeError, the connection is closed,
+	// and the counter named "${ServiceSlug}.frame-too-large" is incremented.
+	// Oversized requests are rejected before being sent to the server.
+	//
+	// When MaxFrameSize is 0, the limit of the THeader transport (1 GiB) is
+	// used.
+	MaxFrameSize uint32
 }
 
 // Client is a client object that implements both the clientpool.Client and
@@ -85,6 +193,18 @@ type Client interface {
 
 // ClientPool defines an object that can be used to manage a pool of
 // Client objects.
+//
+// ClientPools created by NewBaseplateClientPool and NewCustomClientPool always
+// report the following metrics, regardless of ReportPoolStats:
+//
+// - the number of GetClient calls failed because the pool is exhausted to a
+// counter named "${ServiceSlug}.pool-exhausted".
+//
+// - the number of clients failed to be released back to the pool to a counter
+// named "${ServiceSlug}.pool-release-error".
+//
+// - the time spent in GetClient, including the time to open a new connection
+// when needed, to a timing named "${ServiceSlug}.pool-wait-time".
 type ClientPool interface {
 	// Passthrough APIs from clientpool.Pool:
 	io.Closer
@@ -130,16 +250,21 @@ type TClientFactory func(thrift.TTransport, thrift.TProtocolFactory) thrift.TCli
 //
 // 2. Wraps the TClient objects with BaseplateDefaultClientMiddlewares plus any
 // additional client middlewares passed into this function.
+// MonitorClient in the defaults is replaced by MonitorClientWithArgs,
+// using cfg.ServiceSlug.
 func NewBaseplateClientPool(cfg ClientPoolConfig, ttl time.Duration, middlewares ...thrift.ClientMiddleware) (ClientPool, error) {
-	defaults := BaseplateDefaultClientMiddlewares()
-	wrappers := make([]thrift.ClientMiddleware, 0, len(defaults)+len(middlewares))
-	wrappers = append(wrappers, defaults...)
-	wrappers = append(wrappers, middlewares...)
+	monitor := MonitorClientWithArgs(MonitorClientArgs{
+		ServiceSlug: cfg.ServiceSlug,
+		Protocol:    "header",
+	})
 	return NewCustomClientPool(
 		cfg,
 		SingleAddressGenerator(cfg.Addr),
 		NewTTLClientFactory(ttl),
-		NewWrappedTClientFactory(StandardTClientFactory, wrappers...),
+		NewWrappedTClientFactory(
+			StandardTClientFactory,
+			withMonitoredDefaultClientMiddlewares(monitor, middlewares)...,
+		),
 		thrift.NewTHeaderProtocolFactory(),
 	)
 }
@@ -157,7 +282,7 @@ func NewCustomClientPool(
 	protoFactory thrift.TProtocolFactory,
 ) (ClientPool, error) {
 	if cfg.Addr != "" {
-		log.Warnw(
+		log.Named("thriftbp").Warnw(
 			"NewCustomClientPool received a non-empty cfg.Addr, "+
 				"this will be ignored in favor of what is returned by genAddr",
 			"addr",
@@ -220,6 +345,36 @@ func NewWrappedTClientFactory(base TClientFactory, middlewares ...thrift.ClientM
 	}
 }
 
+// NewPooledTClient returns a thrift.TClient that gets a Client from the pool
+// for every Call, and releases it back to the pool after the Call.
+//
+// When the Call fails with a transport error (e.g. a timeout),
+// the connection might still have unread data of the call,
+// so the Client is closed instead of being reused by the next Call.
+//
+// See IsRetryableTransportError for how to use it to retry the calls.
+func NewPooledTClient(pool ClientPool) thrift.TClient {
+	return pooledTClient{pool: pool}
+}
+
+type pooledTClient struct {
+	pool ClientPool
+}
+
+func (c pooledTClient) Call(ctx context.Context, method string, args, result thrift.TStruct) error {
+	client, err := c.pool.GetClient()
+	if err != nil {
+		return err
+	}
+	defer c.pool.ReleaseClient(client)
+
+	err = client.Call(ctx, method, args, result)
+	if isTransportError(err) {
+		client.Close()
+	}
+	return err
+}
+
 // convenience struct for passing around the different factories needed to
 // create a Client.
 type factories struct {
@@ -230,11 +385,60 @@ type factories struct {
 
 func newClientPool(cfg ClientPoolConfig, genAddr AddressGenerator, factories factories) (*clientPool, error) {
 	labels := cfg.MetricsLabels.AsStatsdLabels()
-	pool, err := clientpool.NewChannelPool(
-		cfg.InitialConnections,
-		cfg.MaxConnections,
+	limit := frameLimit{
+		max:         cfg.MaxFrameSize,
+		checkWrites: true,
+		counter: metricsbp.M.Counter(
+			cfg.ServiceSlug + ".frame-too-large",
+		).With(labels...),
+	}
+	var bal *balancer
+	if cfg.Discovery != nil || len(cfg.Addrs) > 0 {
+		if cfg.Discovery != nil && (len(cfg.Addrs) > 0 || cfg.EjectFailures > 0 || cfg.EjectDuration > 0) {
+			return nil, errors.New("thriftbp: Addrs, EjectFailures and EjectDuration can't be used together with Discovery")
+		}
+		var err error
+		bal, err = newBalancer(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pool, err := clientpool.NewChannelPoolWithConfig(
+		clientpool.ChannelPoolConfig{
+			InitialClients: cfg.InitialConnections,
+			MaxClients:     cfg.MaxConnections,
+			AcquireTimeout: cfg.AcquireTimeout,
+			IdleTimeout:    cfg.IdleTimeout,
+		},
 		func() (clientpool.Client, error) {
-			return newClient(cfg.SocketTimeout, genAddr, factories)
+			var addr string
+			var b *backend
+			if bal != nil {
+				var err error
+				b, err = bal.pick()
+				if err != nil {
+					return nil, err
+				}
+				addr = b.addr
+			} else {
+				var err error
+				addr, err = genAddr()
+				if err != nil {
+					return nil, err
+				}
+			}
+			client, err := newClient(cfg.SocketTimeout, cfg.TLS, limit, addr, factories)
+			if err != nil {
+				var te thrift.TTransportException
+				if b != nil && errors.As(err, &te) {
+					b.report(err)
+				}
+				return nil, err
+			}
+			if b != nil {
+				client = newBalancedClient(client, b, bal.contains)
+			}
+			return client, nil
 		},
 	)
 	if err != nil {
@@ -258,23 +462,48 @@ func newClientPool(cfg ClientPoolConfig, genAddr AddressGenerator, factories fac
 		releaseErrorCounter: metricsbp.M.Counter(
 			cfg.ServiceSlug + ".pool-release-error",
 		).With(labels...),
+		waitTiming: metricsbp.M.Timing(
+			cfg.ServiceSlug + ".pool-wait-time",
+		).With(labels...),
 	}, nil
 }
 
-func newClient(socketTimeout time.Duration, genAddr AddressGenerator, factories factories) (Client, error) {
-	addr, err := genAddr()
-	if err != nil {
-		return nil, err
-	}
-	trans, err := thrift.NewTSocketTimeout(addr, socketTimeout, socketTimeout)
-	if err != nil {
-		return nil, err
+func newClient(
+	socketTimeout time.Duration,
+	tlsCfg *TLSConfig,
+	limit frameLimit,
+	addr string,
+	factories factories,
+) (Client, error) {
+	var trans thrift.TTransport
+	var err error
+	if tlsCfg != nil {
+		config, err := tlsCfg.ClientTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		trans, err = thrift.NewTSSLSocketTimeout(addr, config, socketTimeout)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		trans, err = thrift.NewTSocketTimeout(addr, socketTimeout, socketTimeout)
+		if err != nil {
+			return nil, err
+		}
 	}
 	err = trans.Open()
 	if err != nil {
 		return nil, err
 	}
-	return factories.Client(factories.TClient, trans, factories.Protocol), nil
+	counting := &clientTransport{
+		TTransport: limit.wrap(trans),
+		addr:       addr,
+	}
+	return clientTransportClient{
+		Client: factories.Client(factories.TClient, counting, factories.Protocol),
+		trans:  counting,
+	}, nil
 }
 
 func reportPoolStats(ctx context.Context, prefix string, pool clientpool.Pool, tickerDuration time.Duration, labels []string) {
@@ -301,10 +530,13 @@ type clientPool struct {
 
 	poolExhaustedCounter metrics.Counter
 	releaseErrorCounter  metrics.Counter
+	waitTiming           metrics.Histogram
 }
 
 func (p *clientPool) GetClient() (Client, error) {
+	timer := metricsbp.NewTimer(p.waitTiming)
 	c, err := p.Pool.Get()
+	timer.ObserveDuration()
 	if err != nil {
 		if errors.Is(err, clientpool.ErrExhausted) {
 			p.poolExhaustedCounter.Add(1)
@@ -316,7 +548,7 @@ func (p *clientPool) GetClient() (Client, error) {
 
 func (p *clientPool) ReleaseClient(c Client) {
 	if err := p.Pool.Release(c); err != nil {
-		log.Errorw("Failed to release client back to pool", "err", err)
+		log.Named("thriftbp").Errorw("Failed to release client back to pool", "err", err)
 		p.releaseErrorCounter.Add(1)
 	}
 }
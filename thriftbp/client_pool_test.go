@@ -182,3 +182,100 @@ func TestMockClientPool(t *testing.T) {
 		},
 	)
 }
+
+func TestClientPoolIdleTimeout(t *testing.T) {
+	const idleTimeout = time.Millisecond * 10
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	pool, err := thriftbp.NewCustomClientPool(
+		thriftbp.ClientPoolConfig{
+			ServiceSlug:        "test",
+			InitialConnections: 1,
+			MaxConnections:     5,
+			IdleTimeout:        idleTimeout,
+		},
+		thriftbp.SingleAddressGenerator(ln.Addr().String()),
+		func(thriftbp.TClientFactory, thrift.TTransport, thrift.TProtocolFactory) thriftbp.Client {
+			return &thriftbp.MockClient{}
+		},
+		thriftbp.StandardTClientFactory,
+		thrift.NewTBinaryProtocolFactoryDefault(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	first, err := pool.GetClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.ReleaseClient(first)
+
+	second, err := pool.GetClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Error("Expected the client to be reused before IdleTimeout")
+	}
+	pool.ReleaseClient(second)
+
+	time.Sleep(idleTimeout * 2)
+	third, err := pool.GetClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first {
+		t.Error("Expected the client to be replaced after IdleTimeout")
+	}
+}
+
+func TestClientPoolAcquireTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	pool, err := thriftbp.NewCustomClientPool(
+		thriftbp.ClientPoolConfig{
+			ServiceSlug:        "test",
+			InitialConnections: 1,
+			MaxConnections:     1,
+			AcquireTimeout:     time.Millisecond * 500,
+		},
+		thriftbp.SingleAddressGenerator(ln.Addr().String()),
+		func(thriftbp.TClientFactory, thrift.TTransport, thrift.TProtocolFactory) thriftbp.Client {
+			return &thriftbp.MockClient{}
+		},
+		thriftbp.StandardTClientFactory,
+		thrift.NewTBinaryProtocolFactoryDefault(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	first, err := pool.GetClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		pool.ReleaseClient(first)
+	}()
+
+	second, err := pool.GetClient()
+	if err != nil {
+		t.Fatalf("Expected GetClient to wait for the released client, got %v", err)
+	}
+	if second != first {
+		t.Error("Expected the released client to be reused")
+	}
+}
@@ -0,0 +1,229 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// DefaultConcurrencyLimitName is the default ConcurrencyLimitConfig.Name.
+const DefaultConcurrencyLimitName = "thrift.server"
+
+// ErrServerOverloaded is the error whose message is used by the
+// TApplicationException written to the client when a request is shed by
+// ConcurrencyLimit.
+var ErrServerOverloaded = errors.New("thriftbp: server overloaded")
+
+// ConcurrencyLimitConfig is the configuration used by ConcurrencyLimit.
+//
+// Can be deserialized from YAML.
+type ConcurrencyLimitConfig struct {
+	// Name is used as the prefix of the metrics.
+	//
+	// Optional, defaults to DefaultConcurrencyLimitName.
+	Name string `yaml:"name"`
+
+	// MaxConcurrency is the max number of in-flight requests across all the
+	// endpoints.
+	//
+	// Optional, 0 means unlimited.
+	MaxConcurrency int `yaml:"maxConcurrency"`
+
+	// MaxConcurrencyPerMethod is the max number of in-flight requests of every
+	// endpoint, keyed by the name of the endpoint.
+	//
+	// Optional, endpoints not in the map are only limited by MaxConcurrency.
+	MaxConcurrencyPerMethod map[string]int `yaml:"maxConcurrencyPerMethod"`
+
+	// MaxQueueDepth is the max number of requests waiting for an in-flight slot.
+	// Requests arriving when the queue is full are shed.
+	//
+	// Optional, 0 means requests are shed immediately when they can't be
+	// processed.
+	MaxQueueDepth int `yaml:"maxQueueDepth"`
+
+	// QueueTimeout is the max time a request waits in the queue before it's
+	// shed.
+	//
+	// Optional, 0 means a request waits until its deadline,
+	// which is usually set by ExtractDeadlineBudget.
+	QueueTimeout time.Duration `yaml:"queueTimeout"`
+}
+
+// ConcurrencyLimit returns a server middleware that caps the number of
+// in-flight requests, globally and per endpoint.
+//
+// Requests over the limits wait in a queue of up to MaxQueueDepth requests.
+// Requests arriving when the queue is full, or timing out in the queue,
+// are shed: they are not passed to the next TProcessorFunction,
+// instead a TApplicationException wrapping ErrServerOverloaded's message is
+// written to the client and returned as the error.
+//
+// It reports the following metrics, which can be used by autoscaling to react
+// to saturation:
+//
+//     <Name>.concurrency.in-flight: gauge of the in-flight requests.
+//     <Name>.concurrency.queued: gauge of the requests waiting in the queue.
+//     <Name>.concurrency.saturation: gauge of the ratio of the in-flight
+//       requests to MaxConcurrency, only reported when MaxConcurrency is set.
+//     <Name>.concurrency.shed: counter of the shed requests,
+//       labeled by endpoint.
+//
+// The middleware shares its limits across all the endpoints it wraps,
+// so the same middleware should be used for the whole processor.
+func ConcurrencyLimit(cfg ConcurrencyLimitConfig) thrift.ProcessorMiddleware {
+	if cfg.Name == "" {
+		cfg.Name = DefaultConcurrencyLimitName
+	}
+	l := &concurrencyLimiter{
+		cfg:        cfg,
+		methods:    make(map[string]chan struct{}),
+		inFlight:   metricsbp.M.Gauge(cfg.Name + ".concurrency.in-flight"),
+		queued:     metricsbp.M.Gauge(cfg.Name + ".concurrency.queued"),
+		saturation: metricsbp.M.Gauge(cfg.Name + ".concurrency.saturation"),
+		shed:       metricsbp.M.Counter(cfg.Name + ".concurrency.shed"),
+	}
+	if cfg.MaxConcurrency > 0 {
+		l.global = make(chan struct{}, cfg.MaxConcurrency)
+	}
+	if cfg.MaxQueueDepth > 0 {
+		l.queue = make(chan struct{}, cfg.MaxQueueDepth)
+	}
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		sems := l.semaphores(name)
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				if !l.acquire(ctx, sems) {
+					l.shed.With("endpoint", name).Add(1)
+					return writeApplicationException(ctx, name, seqID, in, out, ErrServerOverloaded.Error())
+				}
+				defer l.release(sems)
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+}
+
+type concurrencyLimiter struct {
+	cfg ConcurrencyLimitConfig
+
+	// Semaphores of the in-flight slots, nil means unlimited.
+	global  chan struct{}
+	lock    sync.Mutex
+	methods map[string]chan struct{}
+
+	// Semaphore of the queue slots, nil means no queue.
+	queue chan struct{}
+
+	inFlightCount int64
+	queuedCount   int64
+
+	inFlight   metrics.Gauge
+	queued     metrics.Gauge
+	saturation metrics.Gauge
+	shed       metrics.Counter
+}
+
+// semaphores returns the semaphores a request to the endpoint needs to
+// acquire, in order.
+//
+// The per-endpoint semaphore is acquired before the global one,
+// so a request waiting for its endpoint doesn't hold a global slot.
+func (l *concurrencyLimiter) semaphores(name string) []chan struct{} {
+	sems := make([]chan struct{}, 0, 2)
+	if max := l.cfg.MaxConcurrencyPerMethod[name]; max > 0 {
+		l.lock.Lock()
+		sem, ok := l.methods[name]
+		if !ok {
+			sem = make(chan struct{}, max)
+			l.methods[name] = sem
+		}
+		l.lock.Unlock()
+		sems = append(sems, sem)
+	}
+	if l.global != nil {
+		sems = append(sems, l.global)
+	}
+	return sems
+}
+
+// acquire acquires all the semaphores, waiting in the queue if necessary.
+//
+// It returns false if the request should be shed.
+func (l *concurrencyLimiter) acquire(ctx context.Context, sems []chan struct{}) bool {
+	var queued bool
+	defer func() {
+		if queued {
+			<-l.queue
+			l.updateQueued(-1)
+		}
+	}()
+
+	var timeout <-chan time.Time
+	for i, sem := range sems {
+		select {
+		case sem <- struct{}{}:
+			continue
+		default:
+		}
+
+		if !queued {
+			select {
+			case l.queue <- struct{}{}:
+				// A nil queue blocks forever, so we always end up in default.
+				queued = true
+				l.updateQueued(1)
+			default:
+				l.releaseN(sems[:i])
+				return false
+			}
+			if l.cfg.QueueTimeout > 0 {
+				timer := time.NewTimer(l.cfg.QueueTimeout)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			l.releaseN(sems[:i])
+			return false
+		case <-timeout:
+			l.releaseN(sems[:i])
+			return false
+		}
+	}
+	l.updateInFlight(1)
+	return true
+}
+
+func (l *concurrencyLimiter) release(sems []chan struct{}) {
+	l.releaseN(sems)
+	l.updateInFlight(-1)
+}
+
+func (l *concurrencyLimiter) releaseN(sems []chan struct{}) {
+	for _, sem := range sems {
+		<-sem
+	}
+}
+
+func (l *concurrencyLimiter) updateInFlight(delta int64) {
+	n := atomic.AddInt64(&l.inFlightCount, delta)
+	l.inFlight.Set(float64(n))
+	if l.cfg.MaxConcurrency > 0 {
+		l.saturation.Set(float64(n) / float64(l.cfg.MaxConcurrency))
+	}
+}
+
+func (l *concurrencyLimiter) updateQueued(delta int64) {
+	l.queued.Set(float64(atomic.AddInt64(&l.queuedCount, delta)))
+}
@@ -0,0 +1,160 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+// blockingProcessor returns a MockTProcessor with the given endpoints,
+// which block until release is closed.
+func blockingProcessor(t *testing.T, release chan struct{}, names ...string) thrift.TProcessor {
+	t.Helper()
+
+	processorMap := make(map[string]thrift.TProcessorFunction, len(names))
+	for _, name := range names {
+		processorMap[name] = thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				<-release
+				return true, nil
+			},
+		}
+	}
+	return thriftbp.NewMockTProcessor(t, processorMap)
+}
+
+func assertServerOverloaded(t *testing.T, err error) {
+	t.Helper()
+
+	var appErr thrift.TApplicationException
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Expected TApplicationException, got %v", err)
+	}
+	if appErr.Error() != thriftbp.ErrServerOverloaded.Error() {
+		t.Errorf("Expected error %q, got %q", thriftbp.ErrServerOverloaded, appErr)
+	}
+}
+
+// waitForGauge waits until the gauge reaches the expected value.
+func waitForGauge(t *testing.T, recorder *metricstest.Recorder, name string, expected float64) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for recorder.Snapshot().Gauges[name] != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for gauge %q to be %v", name, expected)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	const name = "test"
+	recorder := metricstest.Replace(t)
+
+	release := make(chan struct{})
+	wrapped := thrift.WrapProcessor(
+		blockingProcessor(t, release, name),
+		thriftbp.ConcurrencyLimit(thriftbp.ConcurrencyLimitConfig{
+			MaxConcurrency: 1,
+			MaxQueueDepth:  1,
+		}),
+	)
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := wrapped.Process(ctx, nil, nil)
+			results <- err
+		}()
+	}
+	// One request in-flight, the other one in the queue.
+	waitForGauge(t, recorder, "thrift.server.concurrency.in-flight", 1)
+	waitForGauge(t, recorder, "thrift.server.concurrency.queued", 1)
+	recorder.AssertGaugeEquals(t, "thrift.server.concurrency.saturation", 1)
+
+	out := thrift.NewTBinaryProtocolTransport(thrift.NewTMemoryBuffer())
+	_, err := wrapped.Process(ctx, nil, out)
+	assertServerOverloaded(t, err)
+	msgName, msgType, _, err := out.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgName != name || msgType != thrift.EXCEPTION {
+		t.Errorf(
+			"Expected %q message with EXCEPTION type, got %q with type %d",
+			name,
+			msgName,
+			msgType,
+		)
+	}
+	recorder.AssertCounterEquals(t, "thrift.server.concurrency.shed,endpoint=test", 1)
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("Expected queued requests to be processed, got %v", err)
+		}
+	}
+	recorder.AssertGaugeEquals(t, "thrift.server.concurrency.in-flight", 0)
+	recorder.AssertGaugeEquals(t, "thrift.server.concurrency.queued", 0)
+}
+
+func TestConcurrencyLimitPerMethod(t *testing.T) {
+	recorder := metricstest.Replace(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	wrapped := thrift.WrapProcessor(
+		blockingProcessor(t, release, "limited", "unlimited"),
+		thriftbp.ConcurrencyLimit(thriftbp.ConcurrencyLimitConfig{
+			Name: "server",
+			MaxConcurrencyPerMethod: map[string]int{
+				"limited": 1,
+			},
+		}),
+	)
+	limited := thriftbp.SetMockTProcessorName(context.Background(), "limited")
+	unlimited := thriftbp.SetMockTProcessorName(context.Background(), "unlimited")
+
+	for i := 0; i < 2; i++ {
+		go wrapped.Process(unlimited, nil, nil)
+	}
+	go wrapped.Process(limited, nil, nil)
+	waitForGauge(t, recorder, "server.concurrency.in-flight", 3)
+
+	_, err := wrapped.Process(limited, nil, nil)
+	assertServerOverloaded(t, err)
+	recorder.AssertCounterEquals(t, "server.concurrency.shed,endpoint=limited", 1)
+}
+
+func TestConcurrencyLimitQueueTimeout(t *testing.T) {
+	const name = "test"
+	recorder := metricstest.Replace(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	wrapped := thrift.WrapProcessor(
+		blockingProcessor(t, release, name),
+		thriftbp.ConcurrencyLimit(thriftbp.ConcurrencyLimitConfig{
+			MaxConcurrency: 1,
+			MaxQueueDepth:  1,
+			QueueTimeout:   time.Millisecond,
+		}),
+	)
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+
+	go wrapped.Process(ctx, nil, nil)
+	waitForGauge(t, recorder, "thrift.server.concurrency.in-flight", 1)
+
+	_, err := wrapped.Process(ctx, nil, nil)
+	assertServerOverloaded(t, err)
+	recorder.AssertGaugeEquals(t, "thrift.server.concurrency.queued", 0)
+}
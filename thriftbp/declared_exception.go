@@ -0,0 +1,185 @@
+package thriftbp
+
+import (
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// declaredExceptionProtocol is a thrift.TProtocol that records the
+// BaseplateError written as a declared exception of a reply.
+//
+// The generated processors write the declared exceptions into the result
+// structs of the replies and return no error,
+// so the server middlewares need to read them from the written replies.
+//
+// The exceptions are recognized by the name ("Error") and the fields of the
+// "Error" exception defined in baseplate.thrift.
+type declaredExceptionProtocol struct {
+	thrift.TProtocol
+
+	reply bool
+	depth int
+
+	// exceptionField is true when the current field of the result struct is a
+	// declared exception.
+	exceptionField bool
+
+	recording *declaredError
+	field     int16
+	key       *string
+
+	err *declaredError
+}
+
+func newDeclaredExceptionProtocol(out thrift.TProtocol) *declaredExceptionProtocol {
+	if out == nil {
+		return nil
+	}
+	return &declaredExceptionProtocol{TProtocol: out}
+}
+
+// processError returns err,
+// or the BaseplateError written as a declared exception when err is nil.
+//
+// p can be nil, in which case err is returned as-is.
+func (p *declaredExceptionProtocol) processError(err thrift.TException) thrift.TException {
+	if err != nil || p == nil || p.err == nil {
+		return err
+	}
+	return p.err
+}
+
+func (p *declaredExceptionProtocol) WriteMessageBegin(name string, typeID thrift.TMessageType, seqID int32) error {
+	p.reply = typeID == thrift.REPLY
+	p.depth = 0
+	p.exceptionField = false
+	p.recording = nil
+	p.err = nil
+	return p.TProtocol.WriteMessageBegin(name, typeID, seqID)
+}
+
+func (p *declaredExceptionProtocol) WriteStructBegin(name string) error {
+	p.depth++
+	if p.reply && p.depth == 2 && p.exceptionField && name == "Error" {
+		p.recording = new(declaredError)
+		p.field = 0
+		p.key = nil
+	}
+	return p.TProtocol.WriteStructBegin(name)
+}
+
+func (p *declaredExceptionProtocol) WriteStructEnd() error {
+	if p.depth == 2 && p.recording != nil {
+		p.err = p.recording
+		p.recording = nil
+	}
+	p.depth--
+	return p.TProtocol.WriteStructEnd()
+}
+
+func (p *declaredExceptionProtocol) WriteFieldBegin(name string, typeID thrift.TType, id int16) error {
+	switch {
+	case p.depth == 1:
+		// Field 0 is the return value, the others are the declared exceptions.
+		p.exceptionField = id != 0 && typeID == thrift.STRUCT
+	case p.depth == 2 && p.recording != nil:
+		p.field = id
+	}
+	return p.TProtocol.WriteFieldBegin(name, typeID, id)
+}
+
+// recordingField returns whether the field with id of the exception is being
+// written.
+func (p *declaredExceptionProtocol) recordingField(id int16) bool {
+	return p.recording != nil && p.depth == 2 && p.field == id
+}
+
+func (p *declaredExceptionProtocol) WriteI32(value int32) error {
+	if p.recordingField(1) {
+		p.recording.code = &value
+	}
+	return p.TProtocol.WriteI32(value)
+}
+
+func (p *declaredExceptionProtocol) WriteString(value string) error {
+	switch {
+	case p.recordingField(2):
+		p.recording.message = &value
+	case p.recordingField(3):
+		if p.key == nil {
+			p.key = &value
+		} else {
+			p.recording.details[*p.key] = value
+			p.key = nil
+		}
+	}
+	return p.TProtocol.WriteString(value)
+}
+
+func (p *declaredExceptionProtocol) WriteMapBegin(keyType thrift.TType, valueType thrift.TType, size int) error {
+	if p.recordingField(3) {
+		p.recording.details = make(map[string]string, size)
+	}
+	return p.TProtocol.WriteMapBegin(keyType, valueType, size)
+}
+
+func (p *declaredExceptionProtocol) WriteBool(value bool) error {
+	if p.recordingField(4) {
+		p.recording.retryable = &value
+	}
+	return p.TProtocol.WriteBool(value)
+}
+
+// declaredError is the BaseplateError recorded by declaredExceptionProtocol.
+type declaredError struct {
+	code      *int32
+	message   *string
+	details   map[string]string
+	retryable *bool
+}
+
+var _ BaseplateError = (*declaredError)(nil)
+
+func (e *declaredError) IsSetCode() bool {
+	return e.code != nil
+}
+
+func (e *declaredError) GetCode() int32 {
+	if e.code == nil {
+		return 0
+	}
+	return *e.code
+}
+
+func (e *declaredError) IsSetMessage() bool {
+	return e.message != nil
+}
+
+func (e *declaredError) GetMessage() string {
+	if e.message == nil {
+		return ""
+	}
+	return *e.message
+}
+
+func (e *declaredError) IsSetDetails() bool {
+	return e.details != nil
+}
+
+func (e *declaredError) GetDetails() map[string]string {
+	return e.details
+}
+
+func (e *declaredError) IsSetRetryable() bool {
+	return e.retryable != nil
+}
+
+func (e *declaredError) GetRetryable() bool {
+	if e.retryable == nil {
+		return false
+	}
+	return *e.retryable
+}
+
+func (e *declaredError) Error() string {
+	return (&wrappedBaseplateError{cause: e, bpErr: e}).Error()
+}
@@ -0,0 +1,237 @@
+package thriftbp_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/discovery"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func writeEndpoints(t *testing.T, path string, addrs ...net.Addr) {
+	t.Helper()
+
+	content := "["
+	for i, addr := range addrs {
+		if i > 0 {
+			content += ","
+		}
+		tcpAddr := addr.(*net.TCPAddr)
+		content += fmt.Sprintf(`{"host": %q, "port": %d}`, tcpAddr.IP.String(), tcpAddr.Port)
+	}
+	content += "]"
+	writeEndpointsJSON(t, path, content)
+}
+
+func writeEndpointsJSON(t *testing.T, path, content string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientPoolDiscovery(t *testing.T) {
+	var listeners [2]net.Listener
+	for i := range listeners {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		listeners[i] = ln
+	}
+
+	dir, err := ioutil.TempDir("", "thriftbp_discovery_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "endpoints.json")
+	writeEndpoints(t, path, listeners[0].Addr())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	watcher, err := discovery.New(ctx, discovery.Config{
+		Path:             path,
+		FailureThreshold: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	pool, err := thriftbp.NewCustomClientPool(
+		thriftbp.ClientPoolConfig{
+			ServiceSlug:        "test",
+			InitialConnections: 1,
+			MaxConnections:     5,
+			Discovery:          watcher,
+		},
+		nil,
+		func(thriftbp.TClientFactory, thrift.TTransport, thrift.TProtocolFactory) thriftbp.Client {
+			return &thriftbp.MockClient{}
+		},
+		thriftbp.StandardTClientFactory,
+		thrift.NewTBinaryProtocolFactoryDefault(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	first, err := pool.GetClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Call(context.Background(), "foo", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	pool.ReleaseClient(first)
+
+	t.Run("rebalance", func(t *testing.T) {
+		writeEndpoints(t, path, listeners[1].Addr())
+		removed := listeners[0].Addr().String()
+		deadline := time.Now().Add(time.Second)
+		for watcher.Contains(removed) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+
+		second, err := pool.GetClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.ReleaseClient(second)
+		if second == first {
+			t.Error("Expected the client to the removed backend to be replaced")
+		}
+	})
+
+	t.Run("health", func(t *testing.T) {
+		ln := listeners[1]
+		addr := ln.Addr().String()
+		ln.Close()
+
+		// Take the pooled client so that the next one needs a new connection.
+		pooled, err := pool.GetClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.ReleaseClient(pooled)
+
+		if _, err := pool.GetClient(); err == nil {
+			t.Fatal("Expected error connecting to closed backend")
+		}
+		if !watcher.Ejected(addr) {
+			t.Error("Expected backend to be ejected after connection failure")
+		}
+	})
+}
+
+func newDiscoveryWatcher(t *testing.T, content string) *discovery.Watcher {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "thriftbp_discovery_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "endpoints.json")
+	writeEndpointsJSON(t, path, content)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	watcher, err := discovery.New(ctx, discovery.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(watcher.Stop)
+	return watcher
+}
+
+func TestClientPoolDiscoveryLoadBalancing(t *testing.T) {
+	for _, policy := range []thriftbp.LoadBalancePolicy{
+		thriftbp.LoadBalanceRoundRobin,
+		thriftbp.LoadBalanceLeastLoaded,
+	} {
+		t.Run(policy.String(), func(t *testing.T) {
+			addrs, accepted := listenAddrs(t, 3)
+			content := "["
+			for i, addr := range addrs {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i > 0 {
+					content += ","
+				}
+				// The backends are weighted 0, 1 and 2.
+				content += fmt.Sprintf(`{"host": %q, "port": %s, "weight": %d}`, host, port, i)
+			}
+			content += "]"
+			watcher := newDiscoveryWatcher(t, content)
+
+			pool := newBalancedClientPool(t, thriftbp.ClientPoolConfig{
+				Discovery:         watcher,
+				LoadBalancePolicy: policy,
+			})
+
+			// Hold all the clients so every GetClient opens a new connection.
+			const clients = 6
+			for i := 0; i < clients; i++ {
+				client, err := pool.GetClient()
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer pool.ReleaseClient(client)
+			}
+			for i, addr := range addrs {
+				expected := clients * i / 3
+				if n := accepted.waitFor(addr, expected); n != expected {
+					t.Errorf("Expected %d connections to backend with weight %d, got %d", expected, i, n)
+				}
+			}
+		})
+	}
+}
+
+func TestClientPoolDiscoveryConflicts(t *testing.T) {
+	watcher := newDiscoveryWatcher(t, `[{"host": "127.0.0.1", "port": 9090}]`)
+	for _, cfg := range []thriftbp.ClientPoolConfig{
+		{
+			Discovery: watcher,
+			Addrs:     []string{"127.0.0.1:9090"},
+		},
+		{
+			Discovery:     watcher,
+			EjectFailures: 1,
+		},
+	} {
+		cfg.ServiceSlug = "test"
+		if _, err := thriftbp.NewCustomClientPool(
+			cfg,
+			nil,
+			func(thriftbp.TClientFactory, thrift.TTransport, thrift.TProtocolFactory) thriftbp.Client {
+				return &thriftbp.MockClient{}
+			},
+			thriftbp.StandardTClientFactory,
+			thrift.NewTBinaryProtocolFactoryDefault(),
+		); err == nil {
+			t.Errorf("Expected error for config %+v, got nil", cfg)
+		}
+	}
+}
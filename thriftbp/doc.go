@@ -4,7 +4,8 @@
 //
 // On the client side,
 // this package provides a middleware framework for thrift.TClient to allow you
-// to automatically run code before and after making a Thrift call.
+// to automatically run code before and after making a Thrift call
+// (see WrapClient).
 // It also includes middleware implementations to wrap each call in a Thrift
 // client span as well as a function that most services can use as the
 // "golden path" for setting up a Thrift client pool.
@@ -0,0 +1,252 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Error codes of the "ErrorCode" enum defined in baseplate.thrift,
+// to be used as the code of baseplate.Error exceptions.
+//
+// The codes in the 4xx and 5xx ranges share the meanings of the HTTP status
+// codes.
+// Services can define their own codes starting from ErrorCodeUserDefined.
+const (
+	ErrorCodeBadRequest                  int32 = 400
+	ErrorCodeUnauthorized                int32 = 401
+	ErrorCodePaymentRequired             int32 = 402
+	ErrorCodeForbidden                   int32 = 403
+	ErrorCodeNotFound                    int32 = 404
+	ErrorCodeConflict                    int32 = 409
+	ErrorCodeGone                        int32 = 410
+	ErrorCodePreconditionFailed          int32 = 412
+	ErrorCodePayloadTooLarge             int32 = 413
+	ErrorCodeImATeapot                   int32 = 418
+	ErrorCodeMisdirectedRequest          int32 = 421
+	ErrorCodeUnprocessableEntity         int32 = 422
+	ErrorCodeLocked                      int32 = 423
+	ErrorCodeFailedDependency            int32 = 424
+	ErrorCodeTooEarly                    int32 = 425
+	ErrorCodePreconditionRequired        int32 = 428
+	ErrorCodeTooManyRequests             int32 = 429
+	ErrorCodeRequestHeaderFieldsTooLarge int32 = 431
+	ErrorCodeUnavailableForLegalReasons  int32 = 451
+	ErrorCodeInternalServerError         int32 = 500
+	ErrorCodeNotImplemented              int32 = 501
+	ErrorCodeBadGateway                  int32 = 502
+	ErrorCodeServiceUnavailable          int32 = 503
+	ErrorCodeTimeout                     int32 = 504
+	ErrorCodeInsufficientStorage         int32 = 507
+	ErrorCodeLoopDetected                int32 = 508
+	ErrorCodeUserDefined                 int32 = 1000
+)
+
+// BaseplateError defines the interface of the thrift exceptions generated from
+// the "Error" exception defined in baseplate.thrift:
+//
+//     exception Error {
+//         1: optional i32 code
+//         2: optional string message
+//         3: optional map<string, string> details
+//         4: optional bool retryable
+//     }
+//
+// The baseplate.thrift IDL is compiled by every service,
+// so instead of a concrete type the helpers in thriftbp use this interface,
+// which is implemented by the generated Go code of the exception.
+// To create one, use the generated type with the ErrorCode* constants, e.g.:
+//
+//     return &baseplate.Error{
+//       Code:    thrift.Int32Ptr(thriftbp.ErrorCodeNotFound),
+//       Message: thrift.StringPtr("user not found"),
+//     }
+type BaseplateError interface {
+	thrift.TException
+
+	IsSetCode() bool
+	GetCode() int32
+
+	IsSetMessage() bool
+	GetMessage() string
+
+	IsSetDetails() bool
+	GetDetails() map[string]string
+
+	IsSetRetryable() bool
+	GetRetryable() bool
+}
+
+// BaseplateErrorCode returns the code of the BaseplateError in err's chain.
+//
+// It returns false if err is not a BaseplateError, or its code is not set.
+func BaseplateErrorCode(err error) (int32, bool) {
+	var bpErr BaseplateError
+	if errors.As(err, &bpErr) && bpErr.IsSetCode() {
+		return bpErr.GetCode(), true
+	}
+	return 0, false
+}
+
+// resultBaseplateError returns the BaseplateError set as a declared exception
+// in result, the result struct of a call.
+//
+// The generated clients only read the declared exceptions from the result
+// structs after TClient.Call returns nil,
+// so the client middlewares need to check the result structs to see them.
+func resultBaseplateError(result thrift.TStruct) (BaseplateError, bool) {
+	v := reflect.ValueOf(result)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Ptr || f.IsNil() || !f.CanInterface() {
+			continue
+		}
+		if bpErr, ok := f.Interface().(BaseplateError); ok {
+			return bpErr, true
+		}
+	}
+	return nil, false
+}
+
+// callError returns err,
+// or the BaseplateError declared in result when err is nil.
+func callError(err error, result thrift.TStruct) error {
+	if err != nil {
+		return err
+	}
+	if bpErr, ok := resultBaseplateError(result); ok {
+		return bpErr
+	}
+	return nil
+}
+
+// IsBaseplateErrorRetryable returns the retryable field of the BaseplateError
+// in err's chain.
+//
+// The second return value is false if err is not a BaseplateError,
+// or its retryable field is not set.
+func IsBaseplateErrorRetryable(err error) (retryable bool, ok bool) {
+	var bpErr BaseplateError
+	if errors.As(err, &bpErr) && bpErr.IsSetRetryable() {
+		return bpErr.GetRetryable(), true
+	}
+	return false, false
+}
+
+// WrapBaseplateError wraps e into an error with a more readable message if
+// e is a BaseplateError.
+//
+// The message is in the format of:
+//
+//     baseplate.Error: "message" (code=404, retryable=false, details=map[...])
+//
+// with the unset fields omitted, e.g. "baseplate.Error (code=429)".
+//
+// The returned error still unwraps to e,
+// so errors.As can be used to get the original exception.
+// Errors that are not BaseplateErrors, including nil, are returned as-is.
+func WrapBaseplateError(e error) error {
+	var bpErr BaseplateError
+	if !errors.As(e, &bpErr) {
+		return e
+	}
+	var wrapped *wrappedBaseplateError
+	if errors.As(e, &wrapped) {
+		// Already wrapped.
+		return e
+	}
+	return &wrappedBaseplateError{
+		cause: e,
+		bpErr: bpErr,
+	}
+}
+
+type wrappedBaseplateError struct {
+	cause error
+	bpErr BaseplateError
+}
+
+func (e *wrappedBaseplateError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("baseplate.Error")
+	if e.bpErr.IsSetMessage() {
+		sb.WriteString(": ")
+		sb.WriteString(strconv.Quote(e.bpErr.GetMessage()))
+	}
+	var fields []string
+	if e.bpErr.IsSetCode() {
+		fields = append(fields, fmt.Sprintf("code=%d", e.bpErr.GetCode()))
+	}
+	if e.bpErr.IsSetRetryable() {
+		fields = append(fields, fmt.Sprintf("retryable=%v", e.bpErr.GetRetryable()))
+	}
+	if e.bpErr.IsSetDetails() {
+		fields = append(fields, fmt.Sprintf("details=%v", e.bpErr.GetDetails()))
+	}
+	if len(fields) > 0 {
+		sb.WriteString(" (")
+		sb.WriteString(strings.Join(fields, ", "))
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+func (e *wrappedBaseplateError) Unwrap() error {
+	return e.cause
+}
+
+// SpanTagKeyBaseplateErrorCode is the span tag set by BaseplateErrorWrapper
+// with the code of the BaseplateError returned by the call.
+const SpanTagKeyBaseplateErrorCode = "thrift.baseplate_error_code"
+
+// BaseplateErrorWrapper is a ClientMiddleware that wraps the BaseplateErrors
+// returned by the calls with WrapBaseplateError.
+//
+// The BaseplateErrors declared as exceptions of the thrift methods are read
+// into the result structs by the generated clients instead of being returned
+// by the calls, so BaseplateErrorWrapper also checks the result structs and
+// returns the BaseplateErrors found there.
+// The generated clients then return them as-is.
+//
+// When the BaseplateError has a code, it also tags the client span with
+// SpanTagKeyBaseplateErrorCode,
+// and increments the "<name>.baseplate-error" counter labeled by the code on
+// metricsbp.M, where name is the name of the client span
+// (or the method when there's no client span).
+//
+// It should be passed into NewBaseplateClientPool or WrapClient,
+// so it's applied after the client span is created by
+// BaseplateDefaultClientMiddlewares.
+func BaseplateErrorWrapper(next thrift.TClient) thrift.TClient {
+	return thrift.WrappedTClient{
+		Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) error {
+			err := callError(next.Call(ctx, method, args, result), result)
+			if code, ok := BaseplateErrorCode(err); ok {
+				name := method
+				if span, ok := opentracing.SpanFromContext(ctx).(*tracing.Span); ok && span != nil {
+					span.SetTag(SpanTagKeyBaseplateErrorCode, code)
+					name = span.Name()
+				}
+				metricsbp.M.Counter(name+".baseplate-error").With(
+					"code", strconv.FormatInt(int64(code), 10),
+				).Add(1)
+			}
+			return WrapBaseplateError(err)
+		},
+	}
+}
+
+var _ thrift.ClientMiddleware = BaseplateErrorWrapper
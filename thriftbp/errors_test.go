@@ -0,0 +1,256 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplatetest"
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/thriftbp"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+// bpError mimics the Go code generated from the "Error" exception in
+// baseplate.thrift.
+type bpError struct {
+	Code      *int32
+	Message   *string
+	Details   map[string]string
+	Retryable *bool
+}
+
+func (e *bpError) IsSetCode() bool {
+	return e.Code != nil
+}
+
+func (e *bpError) GetCode() int32 {
+	if e.Code == nil {
+		return 0
+	}
+	return *e.Code
+}
+
+func (e *bpError) IsSetMessage() bool {
+	return e.Message != nil
+}
+
+func (e *bpError) GetMessage() string {
+	if e.Message == nil {
+		return ""
+	}
+	return *e.Message
+}
+
+func (e *bpError) IsSetDetails() bool {
+	return e.Details != nil
+}
+
+func (e *bpError) GetDetails() map[string]string {
+	return e.Details
+}
+
+func (e *bpError) IsSetRetryable() bool {
+	return e.Retryable != nil
+}
+
+func (e *bpError) GetRetryable() bool {
+	if e.Retryable == nil {
+		return false
+	}
+	return *e.Retryable
+}
+
+func (e *bpError) Error() string {
+	return fmt.Sprintf("Error(%+v)", *e)
+}
+
+var _ thriftbp.BaseplateError = (*bpError)(nil)
+
+func TestWrapBaseplateError(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		err      error
+		expected string
+	}{
+		{
+			label: "nil",
+		},
+		{
+			label:    "not-baseplate-error",
+			err:      errors.New("foo"),
+			expected: "foo",
+		},
+		{
+			label:    "empty",
+			err:      &bpError{},
+			expected: "baseplate.Error",
+		},
+		{
+			label: "all",
+			err: &bpError{
+				Code:      thrift.Int32Ptr(thriftbp.ErrorCodeNotFound),
+				Message:   thrift.StringPtr("not found"),
+				Details:   map[string]string{"foo": "bar"},
+				Retryable: thrift.BoolPtr(false),
+			},
+			expected: `baseplate.Error: "not found" (code=404, retryable=false, details=map[foo:bar])`,
+		},
+		{
+			label: "no-message",
+			err: &bpError{
+				Code: thrift.Int32Ptr(thriftbp.ErrorCodeTooManyRequests),
+			},
+			expected: `baseplate.Error (code=429)`,
+		},
+		{
+			label: "already-wrapped",
+			err: fmt.Errorf("wrapped: %w", thriftbp.WrapBaseplateError(&bpError{
+				Message: thrift.StringPtr("oops"),
+			})),
+			expected: `wrapped: baseplate.Error: "oops"`,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := thriftbp.WrapBaseplateError(c.err)
+			if c.err == nil {
+				if err != nil {
+					t.Errorf("Expected nil error, got %v", err)
+				}
+				return
+			}
+			if actual := err.Error(); actual != c.expected {
+				t.Errorf("Expected error message %q, got %q", c.expected, actual)
+			}
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected %v to unwrap to %v", err, c.err)
+			}
+		})
+	}
+}
+
+func TestIsRetryableErrorBaseplateError(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		err      error
+		expected bool
+	}{
+		{
+			label:    "retryable",
+			err:      &bpError{Retryable: thrift.BoolPtr(true)},
+			expected: true,
+		},
+		{
+			label:    "not-retryable",
+			err:      &bpError{Retryable: thrift.BoolPtr(false)},
+			expected: false,
+		},
+		{
+			label:    "unset",
+			err:      &bpError{},
+			expected: false,
+		},
+		{
+			label: "wrapped",
+			err: thriftbp.WrapBaseplateError(&bpError{
+				Retryable: thrift.BoolPtr(true),
+			}),
+			expected: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if actual := thriftbp.IsRetryableError(c.err); actual != c.expected {
+				t.Errorf("Expected IsRetryableError to be %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBaseplateErrorWrapper(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+	metrics := metricstest.Replace(t)
+
+	bpErr := &bpError{
+		Code:    thrift.Int32Ptr(thriftbp.ErrorCodeNotFound),
+		Message: thrift.StringPtr("not found"),
+	}
+	mock := &thriftbp.MockClient{FailUnregisteredMethods: true}
+	mock.AddMockCall(
+		method,
+		func(ctx context.Context, args, result thrift.TStruct) error {
+			return bpErr
+		},
+	)
+	client := thrift.WrapClient(
+		mock,
+		thriftbp.MonitorClientWithArgs(thriftbp.MonitorClientArgs{
+			ServiceSlug: "test-service",
+		}),
+		thriftbp.BaseplateErrorWrapper,
+	)
+
+	err := client.Call(context.Background(), method, nil, nil)
+	var actual thriftbp.BaseplateError
+	if !errors.As(err, &actual) || actual != bpErr {
+		t.Errorf("Expected error to unwrap to %v, got %v", bpErr, err)
+	}
+	const expectedMsg = `baseplate.Error: "not found" (code=404)`
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message %q, got %q", expectedMsg, err.Error())
+	}
+
+	const name = "test-service." + method
+	span := recorder.MustFindSpan(t, name)
+	if code, _ := span.Tag(thriftbp.SpanTagKeyBaseplateErrorCode); code != "404" {
+		t.Errorf("Expected tag %q to be %q, got %v", thriftbp.SpanTagKeyBaseplateErrorCode, "404", code)
+	}
+	metrics.AssertCounterEquals(t, name+".baseplate-error,code=404", 1)
+}
+
+func TestBaseplateErrorWrapperDeclaredException(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+	metrics := metricstest.Replace(t)
+
+	bpErr := &baseplatetest.Error{
+		Code:    thrift.Int32Ptr(thriftbp.ErrorCodeNotFound),
+		Message: thrift.StringPtr("not found"),
+	}
+	addr := startEchoServer(t, echoHandler{
+		err: func(message string) error {
+			if message == "missing" {
+				return bpErr
+			}
+			return nil
+		},
+	})
+	client := newEchoClient(t, addr, thriftbp.BaseplateErrorWrapper)
+
+	if msg, err := client.Echo(context.Background(), "hello"); err != nil || msg != "hello" {
+		t.Errorf("Expected (%q, nil), got (%q, %v)", "hello", msg, err)
+	}
+
+	_, err := client.Echo(context.Background(), "missing")
+	var actual *baseplatetest.Error
+	if !errors.As(err, &actual) || actual.GetCode() != thriftbp.ErrorCodeNotFound {
+		t.Fatalf("Expected error to unwrap to %v, got %v", bpErr, err)
+	}
+	const expectedMsg = `baseplate.Error: "not found" (code=404)`
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message %q, got %q", expectedMsg, err.Error())
+	}
+
+	const name = "test-service.echo"
+	var tagged int
+	for _, span := range recorder.FindSpans(name) {
+		if code, _ := span.Tag(thriftbp.SpanTagKeyBaseplateErrorCode); code == "404" {
+			tagged++
+		}
+	}
+	if tagged != 1 {
+		t.Errorf("Expected 1 span tagged with %q, got %d", thriftbp.SpanTagKeyBaseplateErrorCode, tagged)
+	}
+	metrics.AssertCounterEquals(t, name+".baseplate-error,code=404", 1)
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"io/ioutil"
 	"testing"
+	"time"
 
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplatetest"
 	"github.com/reddit/baseplate.go/secrets"
+	"github.com/reddit/baseplate.go/thriftbp"
 )
 
 const (
@@ -60,3 +65,75 @@ type counter struct {
 func (c *counter) incr() {
 	c.count++
 }
+
+// echoHandler implements baseplatetest.TestService.
+//
+// Echo returns the message, or the error returned by err when it's non-nil.
+type echoHandler struct {
+	err func(message string) error
+}
+
+func (h echoHandler) Echo(ctx context.Context, message string) (string, error) {
+	if h.err != nil {
+		if err := h.err(message); err != nil {
+			return "", err
+		}
+	}
+	return message, nil
+}
+
+// startEchoServer starts a thrift server serving handler with the generated
+// baseplatetest processor wrapped with middlewares,
+// and returns its address.
+//
+// The server is stopped when the test finishes.
+func startEchoServer(t testing.TB, handler baseplatetest.TestService, middlewares ...thrift.ProcessorMiddleware) string {
+	t.Helper()
+
+	server, err := thriftbp.NewServer(
+		thriftbp.ServerConfig{
+			Addr:    "127.0.0.1:0",
+			Timeout: time.Second,
+			Logger:  thrift.NopLogger,
+		},
+		baseplatetest.NewTestServiceProcessor(handler),
+		middlewares...,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go server.AcceptLoop()
+	t.Cleanup(func() {
+		server.Stop()
+	})
+	return server.ServerTransport().(*thrift.TServerSocket).Addr().String()
+}
+
+// newEchoClient creates a generated baseplatetest client using a client pool
+// to addr with middlewares.
+//
+// The pool is closed when the test finishes.
+func newEchoClient(t testing.TB, addr string, middlewares ...thrift.ClientMiddleware) *baseplatetest.TestServiceClient {
+	t.Helper()
+
+	pool, err := thriftbp.NewBaseplateClientPool(
+		thriftbp.ClientPoolConfig{
+			ServiceSlug:    "test-service",
+			Addr:           addr,
+			MaxConnections: 1,
+			SocketTimeout:  time.Second,
+		},
+		time.Minute,
+		middlewares...,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+	return baseplatetest.NewTestServiceClient(thriftbp.NewPooledTClient(pool))
+}
@@ -0,0 +1,165 @@
+package thriftbp
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/go-kit/kit/metrics"
+)
+
+// FrameTooLargeError is the error returned when the size of a thrift frame
+// exceeds the MaxFrameSize configured in ServerConfig or ClientPoolConfig.
+//
+// It implements thrift.TProtocolException with thrift.SIZE_LIMIT type.
+type FrameTooLargeError struct {
+	Size    uint32
+	MaxSize uint32
+}
+
+func (e FrameTooLargeError) Error() string {
+	return fmt.Sprintf(
+		"thriftbp: frame size %d exceeds the max frame size %d",
+		e.Size,
+		e.MaxSize,
+	)
+}
+
+// TypeId implements thrift.TProtocolException.
+func (e FrameTooLargeError) TypeId() int {
+	return thrift.SIZE_LIMIT
+}
+
+var _ thrift.TProtocolException = FrameTooLargeError{}
+
+// unframedMask is the bit set on the first 4 bytes of the unframed binary and
+// compact protocols, which are also accepted by THeader.
+const unframedMask = 0x80000000
+
+// frameTracker tracks the frames in a stream of framed (including THeader)
+// messages, and checks their sizes against max.
+type frameTracker struct {
+	max uint32
+
+	header    [4]byte
+	headerLen int
+	remaining uint32
+	unframed  bool
+}
+
+func (t *frameTracker) consume(p []byte) error {
+	for len(p) > 0 && !t.unframed {
+		if t.remaining > 0 {
+			n := t.remaining
+			if uint32(len(p)) < n {
+				n = uint32(len(p))
+			}
+			t.remaining -= n
+			p = p[n:]
+			continue
+		}
+
+		n := copy(t.header[t.headerLen:], p)
+		t.headerLen += n
+		p = p[n:]
+		if t.headerLen < len(t.header) {
+			return nil
+		}
+		t.headerLen = 0
+		size := binary.BigEndian.Uint32(t.header[:])
+		if size&unframedMask != 0 {
+			// There's no frame size to check for unframed protocols.
+			t.unframed = true
+			return nil
+		}
+		if size > t.max {
+			return FrameTooLargeError{
+				Size:    size,
+				MaxSize: t.max,
+			}
+		}
+		t.remaining = size
+	}
+	return nil
+}
+
+// frameLimit is the max frame size enforced on a transport.
+//
+// The zero value means no limit.
+type frameLimit struct {
+	// Max frame size in bytes.
+	max uint32
+
+	// Check the frames written in addition to the frames read.
+	checkWrites bool
+
+	// Incremented for every oversized frame.
+	counter metrics.Counter
+}
+
+// wrap wraps trans to check the sizes of the frames,
+// it returns trans as-is when there's no limit.
+func (l frameLimit) wrap(trans thrift.TTransport) thrift.TTransport {
+	if l.max == 0 {
+		return trans
+	}
+	t := &frameLimitTransport{
+		TTransport: trans,
+		limit:      l,
+		reads:      &frameTracker{max: l.max},
+	}
+	if l.checkWrites {
+		t.writes = &frameTracker{max: l.max}
+	}
+	return t
+}
+
+// frameLimitTransport is the thrift.TTransport wrapping the socket underneath
+// the THeader transport to enforce the frame size limit.
+//
+// After an oversized frame, the transport is closed as the stream is no longer
+// usable.
+type frameLimitTransport struct {
+	thrift.TTransport
+
+	limit  frameLimit
+	reads  *frameTracker
+	writes *frameTracker
+}
+
+func (t *frameLimitTransport) Read(p []byte) (int, error) {
+	n, err := t.TTransport.Read(p)
+	if checkErr := t.reads.consume(p[:n]); checkErr != nil {
+		return 0, t.reject(checkErr)
+	}
+	return n, err
+}
+
+func (t *frameLimitTransport) Write(p []byte) (int, error) {
+	if t.writes != nil {
+		if err := t.writes.consume(p); err != nil {
+			return 0, t.reject(err)
+		}
+	}
+	return t.TTransport.Write(p)
+}
+
+func (t *frameLimitTransport) reject(err error) error {
+	if t.limit.counter != nil {
+		t.limit.counter.Add(1)
+	}
+	t.TTransport.Close()
+	return err
+}
+
+// frameLimitTransportFactory wraps the transports returned by another
+// thrift.TTransportFactory with frameLimit.
+type frameLimitTransportFactory struct {
+	thrift.TTransportFactory
+
+	limit frameLimit
+}
+
+func (f frameLimitTransportFactory) GetTransport(trans thrift.TTransport) (thrift.TTransport, error) {
+	return f.TTransportFactory.GetTransport(f.limit.wrap(trans))
+}
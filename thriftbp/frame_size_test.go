@@ -0,0 +1,71 @@
+package thriftbp_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestServerMaxFrameSize(t *testing.T) {
+	recorder := metricstest.Replace(t)
+
+	server, err := thriftbp.NewServer(
+		thriftbp.ServerConfig{
+			Addr:         "127.0.0.1:0",
+			Timeout:      time.Second,
+			Logger:       thrift.NopLogger,
+			MaxFrameSize: 16,
+		},
+		thriftbp.NewMockTProcessor(t, map[string]thrift.TProcessorFunction{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go server.AcceptLoop()
+	defer server.Stop()
+
+	addr := server.ServerTransport().(*thrift.TServerSocket).Addr()
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	frame := make([]byte, 4+17)
+	binary.BigEndian.PutUint32(frame, 17)
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server should close the connection without responding.
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected connection closed by the server, got %v", err)
+	}
+	recorder.AssertCounterEquals(t, "thrift.frame-too-large", 1)
+}
+
+func TestFrameTooLargeError(t *testing.T) {
+	var err error = thriftbp.FrameTooLargeError{
+		Size:    17,
+		MaxSize: 16,
+	}
+	var protoErr thrift.TProtocolException
+	if !errors.As(err, &protoErr) {
+		t.Fatalf("Expected FrameTooLargeError to be a thrift.TProtocolException, got %T", err)
+	}
+	if protoErr.TypeId() != thrift.SIZE_LIMIT {
+		t.Errorf("Expected type id %d, got %d", thrift.SIZE_LIMIT, protoErr.TypeId())
+	}
+}
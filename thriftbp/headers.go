@@ -17,7 +17,8 @@ const (
 // Tracing related headers, as defined in
 // https://pages.github.snooguts.net/reddit/baseplate.spec/component-apis/thrift#tracing
 const (
-	// The Trace ID, a 64-bit integer encoded in decimal.
+	// The Trace ID, a 64-bit integer encoded in decimal,
+	// or a 128-bit integer hex encoded in 32 characters.
 	HeaderTracingTrace = "Trace"
 	// The Span ID, a 64-bit integer encoded in decimal.
 	HeaderTracingSpan = "Span"
@@ -36,6 +37,10 @@ const (
 // should be sampled.
 const HeaderTracingSampledTrue = "1"
 
+// HeaderUserAgent is the header set by the clients to identify themselves,
+// usually the name of the calling service.
+const HeaderUserAgent = "User-Agent"
+
 // Deadline propagation related headers.
 const (
 	// Number of milliseconds, 64-bit integer encoded in decimal.
@@ -57,12 +62,9 @@ var HeadersToForward = []string{
 // EdgeRequestContext set to forward using the "Edge-Request" header on any
 // Thrift calls made with that context object.
 func AttachEdgeRequestContext(ctx context.Context, ec *edgecontext.EdgeRequestContext) context.Context {
-	headers := thrift.GetWriteHeaderList(ctx)
 	if ec == nil {
-		ctx = thrift.UnsetHeader(ctx, HeaderEdgeRequest)
-	} else {
-		ctx = thrift.SetHeader(ctx, HeaderEdgeRequest, ec.Header())
-		headers = append(headers, HeaderEdgeRequest)
+		return thrift.UnsetHeader(ctx, HeaderEdgeRequest)
 	}
-	return thrift.SetWriteHeaderList(ctx, headers)
+	ctx = thrift.SetHeader(ctx, HeaderEdgeRequest, ec.Header())
+	return addToWriteHeaderList(ctx, HeaderEdgeRequest)
 }
@@ -0,0 +1,137 @@
+package thriftbp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/reddit/baseplate.go/batcherror"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// IsHealthyProbe is the type of the health check probe.
+//
+// Its values match the IsHealthyProbe enum defined in baseplate.thrift.
+type IsHealthyProbe int32
+
+// Possible IsHealthyProbe values.
+const (
+	IsHealthyProbeReadiness IsHealthyProbe = 1
+	IsHealthyProbeLiveness  IsHealthyProbe = 2
+	IsHealthyProbeStartup   IsHealthyProbe = 3
+)
+
+func (p IsHealthyProbe) String() string {
+	switch p {
+	default:
+		return fmt.Sprintf("unknown(%d)", int32(p))
+	case IsHealthyProbeReadiness:
+		return "readiness"
+	case IsHealthyProbeLiveness:
+		return "liveness"
+	case IsHealthyProbeStartup:
+		return "startup"
+	}
+}
+
+// HealthReporter reports the health of a single dependency of the service,
+// for example a redis ping or a call to a downstream service.
+//
+// It should return nil when the dependency is healthy.
+type HealthReporter func(ctx context.Context) error
+
+type registeredHealthReporter struct {
+	name     string
+	reporter HealthReporter
+	probes   []IsHealthyProbe
+}
+
+func (r registeredHealthReporter) hasProbe(probe IsHealthyProbe) bool {
+	if len(r.probes) == 0 {
+		return true
+	}
+	for _, p := range r.probes {
+		if p == probe {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthChecker implements the standard baseplate health check endpoint.
+//
+// The application registers HealthReporters to it,
+// and the service is healthy for a probe when all the HealthReporters
+// registered for that probe report healthy.
+//
+// HealthChecker implements the IsHealthy endpoint of BaseplateService defined
+// in baseplate.thrift, so it can be embedded in the thrift handler of services
+// extending BaseplateService directly.
+// Services extending BaseplateServiceV2 should call IsHealthyWithProbe from
+// their IsHealthy implementation with the probe from the request.
+//
+// The zero value is a HealthChecker with no HealthReporters registered,
+// which always reports healthy.
+// It's safe to be used concurrently.
+type HealthChecker struct {
+	lock      sync.RWMutex
+	reporters []registeredHealthReporter
+}
+
+// Register registers a HealthReporter with name for the given probes.
+//
+// If no probes are given,
+// the HealthReporter will be used for all of them.
+func (hc *HealthChecker) Register(name string, reporter HealthReporter, probes ...IsHealthyProbe) {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+
+	hc.reporters = append(hc.reporters, registeredHealthReporter{
+		name:     name,
+		reporter: reporter,
+		probes:   probes,
+	})
+}
+
+// Check calls all the HealthReporters registered for the probe,
+// and returns the errors reported by them as a batcherror.BatchError.
+//
+// It returns nil when all of them report healthy.
+func (hc *HealthChecker) Check(ctx context.Context, probe IsHealthyProbe) error {
+	hc.lock.RLock()
+	reporters := hc.reporters
+	hc.lock.RUnlock()
+
+	var errs batcherror.BatchError
+	for _, r := range reporters {
+		if !r.hasProbe(probe) {
+			continue
+		}
+		if err := r.reporter(ctx); err != nil {
+			errs.Add(fmt.Errorf("thriftbp: health reporter %q: %w", r.name, err))
+		}
+	}
+	return errs.Compile()
+}
+
+// IsHealthyWithProbe returns whether the service is healthy for the probe.
+//
+// When it's unhealthy, the errors reported by the HealthReporters are logged.
+func (hc *HealthChecker) IsHealthyWithProbe(ctx context.Context, probe IsHealthyProbe) bool {
+	if err := hc.Check(ctx, probe); err != nil {
+		log.Named("thriftbp").Warnw(
+			"Health check failed",
+			"probe", probe.String(),
+			"err", err,
+		)
+		return false
+	}
+	return true
+}
+
+// IsHealthy implements the IsHealthy endpoint of BaseplateService.
+//
+// It uses IsHealthyProbeReadiness as the probe and never returns an error.
+func (hc *HealthChecker) IsHealthy(ctx context.Context) (bool, error) {
+	return hc.IsHealthyWithProbe(ctx, IsHealthyProbeReadiness), nil
+}
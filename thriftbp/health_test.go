@@ -0,0 +1,54 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+var _ baseplate.BaseplateService = (*thriftbp.HealthChecker)(nil)
+
+func TestHealthChecker(t *testing.T) {
+	errUnhealthy := errors.New("unhealthy")
+	healthy := func(context.Context) error {
+		return nil
+	}
+	unhealthy := func(context.Context) error {
+		return errUnhealthy
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		var hc thriftbp.HealthChecker
+		if ok, err := hc.IsHealthy(context.Background()); !ok || err != nil {
+			t.Errorf("Expected (true, nil), got (%v, %v)", ok, err)
+		}
+	})
+
+	t.Run("probes", func(t *testing.T) {
+		var hc thriftbp.HealthChecker
+		hc.Register("all", healthy)
+		hc.Register("redis", unhealthy, thriftbp.IsHealthyProbeReadiness)
+		hc.Register("startup", healthy, thriftbp.IsHealthyProbeStartup)
+
+		for probe, expected := range map[thriftbp.IsHealthyProbe]bool{
+			thriftbp.IsHealthyProbeReadiness: false,
+			thriftbp.IsHealthyProbeLiveness:  true,
+			thriftbp.IsHealthyProbeStartup:   true,
+		} {
+			if actual := hc.IsHealthyWithProbe(context.Background(), probe); actual != expected {
+				t.Errorf("Expected probe %v to be %v, got %v", probe, expected, actual)
+			}
+		}
+
+		err := hc.Check(context.Background(), thriftbp.IsHealthyProbeReadiness)
+		if !errors.Is(err, errUnhealthy) {
+			t.Errorf("Expected error %v, got %v", errUnhealthy, err)
+		}
+		if ok, err := hc.IsHealthy(context.Background()); ok || err != nil {
+			t.Errorf("Expected (false, nil), got (%v, %v)", ok, err)
+		}
+	})
+}
@@ -1,18 +1,56 @@
 package thriftbp
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/apache/thrift/lib/go/thrift"
 )
 
+// ErrNoProcessors is returned by Merge when it's called without any
+// processors.
+var ErrNoProcessors = errors.New("thriftbp.Merge: no processors to merge")
+
+// MethodCollisionError is returned by Merge when more than one of the
+// processors implement the same method.
+type MethodCollisionError struct {
+	Method string
+}
+
+func (e MethodCollisionError) Error() string {
+	return fmt.Sprintf("thriftbp.Merge: method %q is implemented by more than one processor", e.Method)
+}
+
 // Merge merges together multiple processors into the first one.
 //
 // It's useful when the server needs to support more than one separated thrift
-// file.
+// file, for example to expose multiple thrift services and the baseplate
+// health check service from the same server.
 //
 // It's kind of like thrift's TMultiplexedProcessor. The key difference is that
 // TMultiplexedProcessor requires the client to also use TMultiplexedProtocol,
 // while here the client doesn't need any special handling.
-func Merge(processors ...thrift.TProcessor) thrift.TProcessor {
+//
+// If more than one of the processors implement the same method,
+// a MethodCollisionError is returned and none of the processors is modified.
+// Note that if your service extends BaseplateService,
+// its processor already implements the health check,
+// so merging it with the processor of BaseplateService is a collision.
+func Merge(processors ...thrift.TProcessor) (thrift.TProcessor, error) {
+	if len(processors) == 0 {
+		return nil, ErrNoProcessors
+	}
+
+	seen := make(map[string]bool)
+	for _, processor := range processors {
+		for k := range processor.ProcessorMap() {
+			if seen[k] {
+				return nil, MethodCollisionError{Method: k}
+			}
+			seen[k] = true
+		}
+	}
+
 	firstProcessor := processors[0]
 	for i := 1; i < len(processors); i++ {
 		processor := processors[i]
@@ -20,5 +58,5 @@ func Merge(processors ...thrift.TProcessor) thrift.TProcessor {
 			firstProcessor.AddToProcessorMap(k, v)
 		}
 	}
-	return firstProcessor
+	return firstProcessor, nil
 }
@@ -0,0 +1,71 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	bpgen "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestMerge(t *testing.T) {
+	nop := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			return true, nil
+		},
+	}
+
+	t.Run("merged", func(t *testing.T) {
+		merged, err := thriftbp.Merge(
+			bpgen.NewBaseplateServiceProcessor(healthyHandler{}),
+			thriftbp.NewMockTProcessor(t, map[string]thrift.TProcessorFunction{
+				"foo": nop,
+			}),
+			thriftbp.NewMockTProcessor(t, map[string]thrift.TProcessorFunction{
+				"bar": nop,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"is_healthy", "foo", "bar"} {
+			if _, ok := merged.ProcessorMap()[name]; !ok {
+				t.Errorf("Expected %q in the merged processor", name)
+			}
+		}
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		first := thriftbp.NewMockTProcessor(t, map[string]thrift.TProcessorFunction{
+			"foo": nop,
+		})
+		_, err := thriftbp.Merge(
+			first,
+			thriftbp.NewMockTProcessor(t, map[string]thrift.TProcessorFunction{
+				"bar": nop,
+			}),
+			thriftbp.NewMockTProcessor(t, map[string]thrift.TProcessorFunction{
+				"foo": nop,
+			}),
+		)
+		var collision thriftbp.MethodCollisionError
+		if !errors.As(err, &collision) {
+			t.Fatalf("Expected MethodCollisionError, got %v", err)
+		}
+		if collision.Method != "foo" {
+			t.Errorf("Expected collision on %q, got %q", "foo", collision.Method)
+		}
+		if _, ok := first.ProcessorMap()["bar"]; ok {
+			t.Error("Expected the first processor not modified on collision")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if _, err := thriftbp.Merge(); !errors.Is(err, thriftbp.ErrNoProcessors) {
+			t.Errorf("Expected ErrNoProcessors, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package thriftbp
+
+import (
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// MethodPredicate reports whether a middleware should be applied to the thrift
+// method with the given name.
+//
+// The name is the method name on the wire as defined in the thrift IDL,
+// e.g. "is_healthy", not the generated go function name.
+type MethodPredicate func(name string) bool
+
+// OnlyMethods returns a MethodPredicate matching only the given methods.
+func OnlyMethods(names ...string) MethodPredicate {
+	set := methodSet(names)
+	return func(name string) bool {
+		_, ok := set[name]
+		return ok
+	}
+}
+
+// ExceptMethods returns a MethodPredicate matching all the methods except the
+// given ones.
+func ExceptMethods(names ...string) MethodPredicate {
+	set := methodSet(names)
+	return func(name string) bool {
+		_, ok := set[name]
+		return !ok
+	}
+}
+
+func methodSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return set
+}
+
+// ForMethods returns a ProcessorMiddleware that only applies middleware to the
+// methods matched by predicate,
+// all the other methods are left unwrapped by it.
+//
+// It's useful to apply a middleware to a subset of the methods, for example:
+//
+//     server, err := thriftbp.NewBaseplateServer(
+//       bp,
+//       processor,
+//       // Rate limit only the write endpoints.
+//       thriftbp.ForMethods(
+//         thriftbp.OnlyMethods("create_post", "delete_post"),
+//         rateLimitMiddleware,
+//       ),
+//       // Skip auth for the health check.
+//       thriftbp.ForMethods(
+//         thriftbp.ExceptMethods("is_healthy"),
+//         authMiddleware,
+//       ),
+//     )
+//
+// As the ProcessorMiddlewares are applied once per method when wrapping the
+// processor, predicate is only called once per method,
+// not on every request.
+func ForMethods(predicate MethodPredicate, middleware thrift.ProcessorMiddleware) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		if !predicate(name) {
+			return next
+		}
+		return middleware(name, next)
+	}
+}
@@ -0,0 +1,75 @@
+package thriftbp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestForMethods(t *testing.T) {
+	var called []string
+	middleware := func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				called = append(called, name)
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+	nop := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			return true, nil
+		},
+	}
+
+	for _, c := range []struct {
+		label     string
+		predicate thriftbp.MethodPredicate
+		expected  []string
+	}{
+		{
+			label:     "only",
+			predicate: thriftbp.OnlyMethods("foo", "bar"),
+			expected:  []string{"foo", "bar"},
+		},
+		{
+			label:     "except",
+			predicate: thriftbp.ExceptMethods("is_healthy"),
+			expected:  []string{"foo", "bar", "baz"},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			called = nil
+			processor := thriftbp.NewMockTProcessor(
+				t,
+				map[string]thrift.TProcessorFunction{
+					"foo":        nop,
+					"bar":        nop,
+					"baz":        nop,
+					"is_healthy": nop,
+				},
+			)
+			wrapped := thrift.WrapProcessor(
+				processor,
+				thriftbp.ForMethods(c.predicate, middleware),
+			)
+			for _, name := range []string{"foo", "bar", "baz", "is_healthy"} {
+				ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+				if _, err := wrapped.Process(ctx, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(called) != len(c.expected) {
+				t.Fatalf("Expected middleware called for %v, got %v", c.expected, called)
+			}
+			for i, name := range c.expected {
+				if called[i] != name {
+					t.Errorf("Expected middleware called for %v, got %v", c.expected, called)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package thriftbp
+
+import (
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// countingTransport is a thrift.TTransport counting the bytes read from and
+// written to the wrapped transport.
+type countingTransport struct {
+	thrift.TTransport
+
+	read    int64
+	written int64
+}
+
+func (t *countingTransport) Read(p []byte) (int, error) {
+	n, err := t.TTransport.Read(p)
+	t.read += int64(n)
+	return n, err
+}
+
+func (t *countingTransport) Write(p []byte) (int, error) {
+	n, err := t.TTransport.Write(p)
+	t.written += int64(n)
+	return n, err
+}
+
+// countingProtocol is a thrift.TProtocol reading and writing the payload of a
+// THeader protocol through a countingTransport,
+// to measure the serialized sizes of the requests and responses.
+//
+// The payload is read and written with the same protocol the client used
+// (binary or compact), so the sizes are the sizes on the wire,
+// excluding the THeader frames and headers.
+//
+// A countingProtocol should only be used for a single request.
+type countingProtocol struct {
+	thrift.TProtocol
+
+	trans *countingTransport
+	id    thrift.THeaderProtocolID
+}
+
+// newCountingProtocol wraps p into a countingProtocol.
+//
+// p must be either a *thrift.THeaderProtocol,
+// or a *countingProtocol (so that the middlewares measuring the payloads can be
+// stacked).
+// For other protocols it returns nil, as there's no way to tell where the
+// payload starts and ends on their transports.
+func newCountingProtocol(p thrift.TProtocol) *countingProtocol {
+	var trans thrift.TTransport
+	var id thrift.THeaderProtocolID
+	switch p := p.(type) {
+	default:
+		return nil
+	case *thrift.THeaderProtocol:
+		ht, ok := p.Transport().(*thrift.THeaderTransport)
+		if !ok {
+			return nil
+		}
+		trans, id = ht, ht.Protocol()
+	case *countingProtocol:
+		trans, id = p.trans, p.id
+	}
+	counter := &countingTransport{TTransport: trans}
+	prot, err := id.GetProtocol(counter)
+	if err != nil {
+		return nil
+	}
+	return &countingProtocol{
+		TProtocol: prot,
+		trans:     counter,
+		id:        id,
+	}
+}
@@ -0,0 +1,54 @@
+package thriftbp
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// ReportPayloadSizeMetrics returns a server middleware that reports the
+// serialized sizes of the requests and responses, in bytes,
+// to the histograms named "thrift.<name>.request-payload-size" and
+// "thrift.<name>.response-payload-size" on metricsbp.M.
+//
+// The sizes are measured by counting the bytes read from and written to the
+// THeader transport by the handler,
+// so they are the sizes of the payloads in the protocol the client used,
+// excluding the THeader frames and headers.
+// The request sizes don't include the message header either,
+// which is already read before the middlewares are called.
+// Requests not read through a THeader protocol,
+// which is used by the servers created by NewServer,
+// are not measured.
+//
+// Only a fraction of the requests (rate, in range of [0, 1]) are measured.
+// The requests not sampled are passed to the next TProcessorFunction as-is.
+func ReportPayloadSizeMetrics(rate float64) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		requestSize := metricsbp.M.Histogram("thrift." + name + ".request-payload-size")
+		responseSize := metricsbp.M.Histogram("thrift." + name + ".response-payload-size")
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				if !randbp.ShouldSampleWithRate(rate) {
+					return next.Process(ctx, seqID, in, out)
+				}
+				countingIn := newCountingProtocol(in)
+				countingOut := newCountingProtocol(out)
+				if countingIn == nil || countingOut == nil {
+					return next.Process(ctx, seqID, in, out)
+				}
+				defer func() {
+					requestSize.Observe(float64(countingIn.trans.read))
+					// Oneway requests don't write any responses.
+					if countingOut.trans.written > 0 {
+						responseSize.Observe(float64(countingOut.trans.written))
+					}
+				}()
+				return next.Process(ctx, seqID, countingIn, countingOut)
+			},
+		}
+	}
+}
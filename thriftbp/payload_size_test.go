@@ -0,0 +1,128 @@
+package thriftbp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestReportPayloadSizeMetrics(t *testing.T) {
+	recorder := metricstest.Replace(t)
+
+	const name = "echo"
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					// Skip the whole request struct, to make sure skipped bytes are
+					// also counted.
+					if err := in.Skip(thrift.STRUCT); err != nil {
+						return false, err
+					}
+					if err := in.ReadMessageEnd(); err != nil {
+						return false, err
+					}
+					if err := out.WriteMessageBegin(name, thrift.REPLY, seqID); err != nil {
+						return false, err
+					}
+					if err := writeTestStruct(out, "world"); err != nil {
+						return false, err
+					}
+					if err := out.WriteMessageEnd(); err != nil {
+						return false, err
+					}
+					return true, out.Flush(ctx)
+				},
+			},
+		},
+	)
+	// Stack the middleware with AccessLog to make sure they can be combined.
+	wrapped := thrift.WrapProcessor(
+		processor,
+		thriftbp.ReportPayloadSizeMetrics(1),
+		thriftbp.AccessLog(thriftbp.AccessLogConfig{
+			Logger: func(context.Context, thriftbp.AccessLogEntry) {},
+		}),
+	)
+
+	in, requestSize := newTHeaderRequest(t, name, "hello")
+	response := thrift.NewTMemoryBuffer()
+	p := thrift.NewTBinaryProtocolTransport(response)
+	if err := p.WriteMessageBegin(name, thrift.REPLY, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTestStruct(p, "world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	responseSize := response.Len()
+
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+	if _, err := wrapped.Process(ctx, in, in); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := recorder.Snapshot()
+	for _, c := range []struct {
+		metric   string
+		expected float64
+	}{
+		{
+			metric:   "thrift.echo.request-payload-size",
+			expected: float64(requestSize),
+		},
+		{
+			metric:   "thrift.echo.response-payload-size",
+			expected: float64(responseSize),
+		},
+	} {
+		values := snapshot.Histograms[c.metric]
+		if len(values) != 1 {
+			t.Errorf("Expected 1 value for %q, got %v", c.metric, values)
+			continue
+		}
+		if values[0] != c.expected {
+			t.Errorf("Expected %q to be %v, got %v", c.metric, c.expected, values[0])
+		}
+	}
+
+	// The response should be readable by the client.
+	client := thrift.NewTHeaderProtocol(in.Transport())
+	if _, _, _, err := client.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReportPayloadSizeMetricsNotTHeader(t *testing.T) {
+	recorder := metricstest.Replace(t)
+
+	const name = "echo"
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					return true, nil
+				},
+			},
+		},
+	)
+	wrapped := thrift.WrapProcessor(processor, thriftbp.ReportPayloadSizeMetrics(1))
+
+	buf := thrift.NewTMemoryBuffer()
+	p := thrift.NewTBinaryProtocolTransport(buf)
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+	if _, err := wrapped.Process(ctx, p, p); err != nil {
+		t.Fatal(err)
+	}
+	if histograms := recorder.Snapshot().Histograms; len(histograms) != 0 {
+		t.Errorf("Expected no histograms for non-THeader protocols, got %v", histograms)
+	}
+}
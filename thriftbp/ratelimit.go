@@ -0,0 +1,56 @@
+package thriftbp
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/ratelimitbp"
+)
+
+// RateLimitKeyFunc returns the key used by the rate limiter for a request to
+// the endpoint with the given name.
+type RateLimitKeyFunc func(ctx context.Context, name string) string
+
+// RateLimit returns a server middleware that rejects the requests over the
+// rate limit of the given limiter.
+//
+// The key of a request is returned by keyFunc.
+// If keyFunc is nil, the name of the endpoint will be used as the key,
+// so every endpoint is rate limited separately.
+//
+// Rejected requests are not passed to the next TProcessorFunction,
+// instead a TApplicationException wrapping ratelimitbp.ErrRateLimited's
+// message is written to the client and returned as the error.
+func RateLimit(limiter ratelimitbp.RateLimiter, keyFunc RateLimitKeyFunc) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				key := name
+				if keyFunc != nil {
+					key = keyFunc(ctx, name)
+				}
+				if limiter.Allow(ctx, key) {
+					return next.Process(ctx, seqID, in, out)
+				}
+				return writeApplicationException(ctx, name, seqID, in, out, ratelimitbp.ErrRateLimited.Error())
+			},
+		}
+	}
+}
+
+// RateLimitByIdentity returns a server middleware that rate limits the
+// requests per authenticated user or OAuth client from the edge request
+// context, instead of per client IP,
+// with a separate limit for the anonymous requests.
+//
+// Every endpoint is rate limited separately.
+// See ratelimitbp.IdentityLimiter for more details.
+//
+// The identity is read from the edge request context on the context object,
+// so it should be applied after InjectEdgeContext.
+// Middlewares passed into NewBaseplateServer are already applied after
+// BaseplateDefaultProcessorMiddlewares.
+func RateLimitByIdentity(limiter ratelimitbp.IdentityLimiter) thrift.ProcessorMiddleware {
+	return RateLimit(limiter, nil)
+}
@@ -0,0 +1,132 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/ratelimitbp"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestRateLimit(t *testing.T) {
+	name := "test"
+	var called int
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					called++
+					return true, nil
+				},
+			},
+		},
+	)
+	limiter := ratelimitbp.NewLimiter(ratelimitbp.Config{
+		RatePerSecond: 0.001,
+		Burst:         1,
+	})
+
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+	wrapped := thrift.WrapProcessor(processor, thriftbp.RateLimit(limiter, nil))
+
+	if _, err := wrapped.Process(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if called != 1 {
+		t.Errorf("Expected the first request to be processed, called %d", called)
+	}
+
+	out := thrift.NewTBinaryProtocolTransport(thrift.NewTMemoryBuffer())
+	_, err := wrapped.Process(ctx, nil, out)
+	var appErr thrift.TApplicationException
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Expected TApplicationException, got %v", err)
+	}
+	if appErr.Error() != ratelimitbp.ErrRateLimited.Error() {
+		t.Errorf("Expected error %q, got %q", ratelimitbp.ErrRateLimited, appErr)
+	}
+	if called != 1 {
+		t.Errorf("Expected the second request to be rejected, called %d", called)
+	}
+	msgName, msgType, _, err := out.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgName != name || msgType != thrift.EXCEPTION {
+		t.Errorf(
+			"Expected %q message with EXCEPTION type, got %q with type %d",
+			name,
+			msgName,
+			msgType,
+		)
+	}
+}
+
+func TestRateLimitByIdentity(t *testing.T) {
+	store, dir := newSecretsStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+	impl := edgecontext.Init(edgecontext.Config{Store: store})
+
+	name := "test"
+	var called int
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					called++
+					return true, nil
+				},
+			},
+		},
+	)
+	limiter := ratelimitbp.NewIdentityLimiter(ratelimitbp.IdentityConfig{
+		Authenticated: ratelimitbp.Config{
+			RatePerSecond: 0.001,
+			Burst:         1,
+		},
+		Anonymous: ratelimitbp.Config{
+			RatePerSecond: 0.001,
+			Burst:         1,
+		},
+	})
+	wrapped := thrift.WrapProcessor(
+		processor,
+		thriftbp.InjectEdgeContext(impl),
+		thriftbp.RateLimitByIdentity(limiter),
+	)
+
+	process := func(authenticated bool) error {
+		t.Helper()
+		ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+		if authenticated {
+			ctx = thrift.SetHeader(ctx, thriftbp.HeaderEdgeRequest, headerWithValidAuth)
+		}
+		out := thrift.NewTBinaryProtocolTransport(thrift.NewTMemoryBuffer())
+		_, err := wrapped.Process(ctx, nil, out)
+		return err
+	}
+
+	if err := process(true); err != nil {
+		t.Errorf("Expected the first authenticated request to be allowed, got %v", err)
+	}
+	if err := process(true); err == nil {
+		t.Error("Expected the second authenticated request to be rejected")
+	}
+	if err := process(false); err != nil {
+		t.Errorf("Expected the first anonymous request to be allowed, got %v", err)
+	}
+	if err := process(false); err == nil {
+		t.Error("Expected the second anonymous request to be rejected")
+	}
+	if called != 2 {
+		t.Errorf("Expected 2 requests to be processed, got %d", called)
+	}
+}
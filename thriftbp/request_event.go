@@ -0,0 +1,100 @@
+package thriftbp
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/requestevent"
+)
+
+// EmitRequestEvent returns a server middleware that attaches a new
+// requestevent.Event to the context object passed into the `next`
+// TProcessorFunction, and publishes it with publisher when the request
+// finishes.
+//
+// It sets the endpoint, protocol ("thrift"), user agent (from the
+// HeaderUserAgent header), duration, success, error code, trace id and user id
+// fields on the event.
+// The requests failed with the BaseplateErrors declared as exceptions of the
+// thrift methods are not successful, and their codes are used as the error
+// codes.
+// The handler and the clients used by the handler can add more fields via
+// requestevent.SetString and requestevent.Add.
+//
+// The trace id and the user id are read from the server span and the edge
+// request context on the context object,
+// so it should be applied after InjectServerSpan and InjectEdgeContext.
+// Middlewares passed into NewBaseplateServer are already applied after
+// BaseplateDefaultProcessorMiddlewares.
+//
+// Publishing errors are not returned to the client,
+// they are reported by the metrics of the publisher (e.g. events.Queue).
+func EmitRequestEvent(publisher requestevent.Publisher) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (success bool, err thrift.TException) {
+				event := requestevent.New()
+				event.SetString(requestevent.KeyEndpoint, name)
+				event.SetString(requestevent.KeyProtocol, "thrift")
+				if ua, ok := thrift.GetHeader(ctx, HeaderUserAgent); ok {
+					event.SetString(requestevent.KeyUserAgent, ua)
+				}
+				event.SetFromContext(ctx)
+				ctx = requestevent.NewContext(ctx, event)
+
+				declared := newDeclaredExceptionProtocol(out)
+				if declared != nil {
+					out = declared
+				}
+				start := time.Now()
+				defer func() {
+					exc := declared.processError(err)
+					event.SetNumber(
+						requestevent.KeyDurationMS,
+						float64(time.Since(start))/float64(time.Millisecond),
+					)
+					if success && exc == nil {
+						event.SetNumber(requestevent.KeySuccess, 1)
+					} else {
+						event.SetNumber(requestevent.KeySuccess, 0)
+					}
+					if code, ok := exceptionCode(exc); ok {
+						event.SetNumber(requestevent.KeyErrorCode, float64(code))
+					}
+					// Use a new context object as ctx might be already canceled.
+					publisher.Put(context.Background(), event)
+				}()
+
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+}
+
+// addClientCallToRequestEvent adds a client call to the requestevent.Event
+// attached to ctx, if any, to the following number fields:
+//
+// - "thrift.<slug>.calls": the number of calls.
+//
+// - "thrift.<slug>.errors": the number of failed calls.
+//
+// - "thrift.<slug>.duration_ms": the total duration of the calls.
+//
+// When slug is empty, "client" is used instead.
+func addClientCallToRequestEvent(ctx context.Context, slug string, start time.Time, err error) {
+	event, ok := requestevent.FromContext(ctx)
+	if !ok {
+		return
+	}
+	if slug == "" {
+		slug = "client"
+	}
+	prefix := "thrift." + slug
+	event.Add(prefix+".calls", 1)
+	if err != nil {
+		event.Add(prefix+".errors", 1)
+	}
+	event.Add(prefix+".duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+}
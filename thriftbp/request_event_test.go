@@ -0,0 +1,131 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplatetest"
+	"github.com/reddit/baseplate.go/requestevent"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+type requestEventRecorder struct {
+	events []*requestevent.Event
+}
+
+func (r *requestEventRecorder) Put(ctx context.Context, event thrift.TStruct) error {
+	r.events = append(r.events, event.(*requestevent.Event))
+	return nil
+}
+
+func TestEmitRequestEvent(t *testing.T) {
+	downstream := thriftbp.MockClient{}
+	downstream.AddMockCall("ok", func(ctx context.Context, args, result thrift.TStruct) error {
+		return nil
+	})
+	downstream.AddMockCall("fail", func(ctx context.Context, args, result thrift.TStruct) error {
+		return errors.New("fail")
+	})
+	client := thrift.WrapClient(
+		&downstream,
+		thriftbp.MonitorClientWithArgs(thriftbp.MonitorClientArgs{ServiceSlug: "downstream"}),
+	)
+
+	errTest := thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "test")
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			"test": thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					requestevent.SetString(ctx, "custom", "value")
+					client.Call(ctx, "ok", nil, nil)
+					client.Call(ctx, "ok", nil, nil)
+					client.Call(ctx, "fail", nil, nil)
+					return true, errTest
+				},
+			},
+		},
+	)
+
+	recorder := &requestEventRecorder{}
+	wrapped := thrift.WrapProcessor(
+		processor,
+		thriftbp.InjectServerSpan,
+		thriftbp.EmitRequestEvent(recorder),
+	)
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), "test")
+	ctx = thrift.SetHeader(ctx, thriftbp.HeaderUserAgent, "test-client")
+	wrapped.Process(ctx, nil, nil)
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("Expected 1 event published, got %d", len(recorder.events))
+	}
+	event := recorder.events[0]
+
+	strs := event.Strings()
+	for key, expected := range map[string]string{
+		requestevent.KeyEndpoint:  "test",
+		requestevent.KeyProtocol:  "thrift",
+		requestevent.KeyUserAgent: "test-client",
+		"custom":                  "value",
+	} {
+		if strs[key] != expected {
+			t.Errorf("Expected string field %q to be %q, got %q", key, expected, strs[key])
+		}
+	}
+	if strs[requestevent.KeyTraceID] == "" {
+		t.Error("Expected trace id to be set")
+	}
+
+	nums := event.Numbers()
+	for key, expected := range map[string]float64{
+		requestevent.KeySuccess:    0,
+		requestevent.KeyErrorCode:  thrift.INTERNAL_ERROR,
+		"thrift.downstream.calls":  3,
+		"thrift.downstream.errors": 1,
+	} {
+		if nums[key] != expected {
+			t.Errorf("Expected number field %q to be %v, got %v", key, expected, nums[key])
+		}
+	}
+	if _, ok := nums[requestevent.KeyDurationMS]; !ok {
+		t.Error("Expected duration to be set")
+	}
+}
+
+type requestEventChannel chan *requestevent.Event
+
+func (c requestEventChannel) Put(ctx context.Context, event thrift.TStruct) error {
+	c <- event.(*requestevent.Event)
+	return nil
+}
+
+func TestEmitRequestEventDeclaredException(t *testing.T) {
+	events := make(requestEventChannel, 1)
+	addr := startEchoServer(
+		t,
+		echoHandler{
+			err: func(message string) error {
+				return &baseplatetest.Error{
+					Code: thrift.Int32Ptr(thriftbp.ErrorCodeNotFound),
+				}
+			},
+		},
+		thriftbp.EmitRequestEvent(events),
+	)
+	client := newEchoClient(t, addr)
+
+	if _, err := client.Echo(context.Background(), "hello"); err == nil {
+		t.Fatal("Expected declared exception, got nil")
+	}
+	numbers := (<-events).Numbers()
+	if actual := numbers[requestevent.KeySuccess]; actual != 0 {
+		t.Errorf("Expected %q to be 0, got %v", requestevent.KeySuccess, actual)
+	}
+	if actual := numbers[requestevent.KeyErrorCode]; actual != float64(thriftbp.ErrorCodeNotFound) {
+		t.Errorf("Expected %q to be %d, got %v", requestevent.KeyErrorCode, thriftbp.ErrorCodeNotFound, actual)
+	}
+}
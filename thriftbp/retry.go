@@ -0,0 +1,96 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+// IsRetryableError is the retrybp.Classifier used by Retry when the
+// Classifier in retrybp.Config is nil.
+//
+// When err is a BaseplateError with the retryable field set,
+// the retryable field is used.
+// Other errors are not retried:
+// thrift.TApplicationException and the other errors returned by the server
+// usually fail again when retried,
+// and transport errors (e.g. timeouts) are not safe to retry on the same
+// connection, see IsRetryableTransportError for retrying them.
+func IsRetryableError(err error) bool {
+	retryable, _ := IsBaseplateErrorRetryable(err)
+	return retryable
+}
+
+// IsRetryableTransportError is a retrybp.Classifier that also treats
+// thrift.TTransportException as retryable,
+// which includes the timeouts and the connection errors.
+// BaseplateErrors are classified the same way as IsRetryableError.
+//
+// The connection might still have unread data of the failed call after a
+// transport error, so it must only be used when every attempt gets a
+// different connection, by wrapping the TClient returned by NewPooledTClient:
+//
+//     client := thrift.WrapClient(
+//         thriftbp.NewPooledTClient(pool),
+//         thriftbp.Retry(retrybp.Config{
+//             MaxAttempts: 3,
+//             Classifier:  thriftbp.IsRetryableTransportError,
+//         }),
+//     )
+func IsRetryableTransportError(err error) bool {
+	if retryable, ok := IsBaseplateErrorRetryable(err); ok {
+		return retryable
+	}
+	return isTransportError(err)
+}
+
+func isTransportError(err error) bool {
+	var transErr thrift.TTransportException
+	return errors.As(err, &transErr)
+}
+
+// Retry returns a thrift.ClientMiddleware that retries the calls using
+// retrybp.Do with the given config.
+//
+// When cfg.Classifier is nil, IsRetryableError will be used.
+//
+// The BaseplateErrors declared as exceptions of the thrift methods are
+// classified as well, see BaseplateErrorWrapper for more details.
+// The result struct is reset before every attempt,
+// so the exceptions of the failed attempts don't leak into the result of the
+// successful one.
+//
+// When passed into NewBaseplateClientPool or WrapClient,
+// it's applied after BaseplateDefaultClientMiddlewares,
+// so all the attempts share the same client span.
+// But in that case all the attempts also use the same connection,
+// so it must not be used with IsRetryableTransportError,
+// see IsRetryableTransportError for how to retry transport errors instead.
+func Retry(cfg retrybp.Config) thrift.ClientMiddleware {
+	if cfg.Classifier == nil {
+		cfg.Classifier = IsRetryableError
+	}
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) error {
+				return retrybp.Do(ctx, cfg, func(ctx context.Context) error {
+					resetResult(result)
+					return callError(next.Call(ctx, method, args, result), result)
+				})
+			},
+		}
+	}
+}
+
+// resetResult resets result, the result struct of a call, to its zero value.
+func resetResult(result thrift.TStruct) {
+	v := reflect.ValueOf(result)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	v.Elem().Set(reflect.Zero(v.Elem().Type()))
+}
@@ -0,0 +1,180 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplatetest"
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestRetry(t *testing.T) {
+	for _, c := range []struct {
+		label      string
+		err        error
+		classifier retrybp.Classifier
+		expected   int
+	}{
+		{
+			label:    "application-exception",
+			err:      thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "oops"),
+			expected: 1,
+		},
+		{
+			label:    "transport-exception",
+			err:      thrift.NewTTransportException(thrift.TIMED_OUT, "timeout"),
+			expected: 1,
+		},
+		{
+			label:      "transport-exception-opt-in",
+			err:        thrift.NewTTransportException(thrift.TIMED_OUT, "timeout"),
+			classifier: thriftbp.IsRetryableTransportError,
+			expected:   3,
+		},
+		{
+			label:      "application-exception-opt-in",
+			err:        thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "oops"),
+			classifier: thriftbp.IsRetryableTransportError,
+			expected:   1,
+		},
+		{
+			label:    "success",
+			err:      nil,
+			expected: 1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var calls int
+			mock := &thriftbp.MockClient{FailUnregisteredMethods: true}
+			mock.AddMockCall(
+				method,
+				func(ctx context.Context, args, result thrift.TStruct) error {
+					calls++
+					return c.err
+				},
+			)
+			client := thrift.WrapClient(
+				mock,
+				thriftbp.Retry(retrybp.Config{
+					MaxAttempts: 3,
+					Classifier:  c.classifier,
+				}),
+			)
+			err := client.Call(context.Background(), method, nil, nil)
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v, got %v", c.err, err)
+			}
+			if calls != c.expected {
+				t.Errorf("Expected %d calls, got %d", c.expected, calls)
+			}
+		})
+	}
+}
+
+type closeRecordingClient struct {
+	*thriftbp.MockClient
+
+	closed bool
+}
+
+func (c *closeRecordingClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRetryPooledTClient(t *testing.T) {
+	var clients []*closeRecordingClient
+	pool := thriftbp.MockClientPool{
+		CreateClient: func() (thriftbp.Client, error) {
+			client := &closeRecordingClient{
+				MockClient: &thriftbp.MockClient{FailUnregisteredMethods: true},
+			}
+			attempt := len(clients)
+			client.AddMockCall(
+				method,
+				func(ctx context.Context, args, result thrift.TStruct) error {
+					if attempt == 0 {
+						return thrift.NewTTransportException(thrift.TIMED_OUT, "timeout")
+					}
+					return nil
+				},
+			)
+			clients = append(clients, client)
+			return client, nil
+		},
+	}
+	client := thrift.WrapClient(
+		thriftbp.NewPooledTClient(pool),
+		thriftbp.Retry(retrybp.Config{
+			MaxAttempts: 3,
+			Classifier:  thriftbp.IsRetryableTransportError,
+		}),
+	)
+	if err := client.Call(context.Background(), method, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 2 {
+		t.Fatalf("Expected 2 clients from the pool, got %d", len(clients))
+	}
+	if !clients[0].closed {
+		t.Error("Expected the client with transport error to be closed")
+	}
+	if clients[1].closed {
+		t.Error("Expected the successful client not to be closed")
+	}
+}
+
+func TestRetryDeclaredException(t *testing.T) {
+	for _, c := range []struct {
+		label     string
+		retryable bool
+		expected  int
+	}{
+		{
+			label:     "retryable",
+			retryable: true,
+			expected:  2,
+		},
+		{
+			label:     "not-retryable",
+			retryable: false,
+			expected:  1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var calls int32
+			addr := startEchoServer(t, echoHandler{
+				err: func(message string) error {
+					if atomic.AddInt32(&calls, 1) == 1 {
+						return &baseplatetest.Error{
+							Code:      thrift.Int32Ptr(thriftbp.ErrorCodeServiceUnavailable),
+							Retryable: thrift.BoolPtr(c.retryable),
+						}
+					}
+					return nil
+				},
+			})
+			client := newEchoClient(t, addr, thriftbp.Retry(retrybp.Config{MaxAttempts: 3}))
+
+			msg, err := client.Echo(context.Background(), "hello")
+			if c.retryable {
+				if err != nil || msg != "hello" {
+					t.Errorf("Expected (%q, nil), got (%q, %v)", "hello", msg, err)
+				}
+			} else {
+				var bpErr *baseplatetest.Error
+				if !errors.As(err, &bpErr) {
+					t.Errorf("Expected declared exception, got %v", err)
+				}
+			}
+			if actual := atomic.LoadInt32(&calls); int(actual) != c.expected {
+				t.Errorf("Expected %d calls, got %d", c.expected, actual)
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package thriftbp
 
 import (
+	"crypto/tls"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 
 	baseplate "github.com/reddit/baseplate.go"
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
 )
 
 // ServerConfig is the arg struct for NewServer.
@@ -17,6 +19,12 @@ type ServerConfig struct {
 	// The timeout for the underlying thrift.TServerSocket transport.
 	Timeout time.Duration
 
+	// When UnixSocket is non-nil, the server listens on the unix domain socket
+	// instead of Addr, e.g. for the sidecar proxy running in the same pod.
+	//
+	// Timeout and TLS still apply to the connections over the socket.
+	UnixSocket *UnixSocketConfig
+
 	// A log wrapper that is used by the TSimpleServer.
 	//
 	// It's compatible with log.Wrapper (with an extra typecasting),
@@ -24,6 +32,31 @@ type ServerConfig struct {
 	// as it would log all the network I/O errors,
 	// which would be too spammy for sentry.
 	Logger thrift.Logger
+
+	// When TLS is non-nil, the server only accepts TLS connections,
+	// using the certificates from the secrets store.
+	TLS *TLSConfig
+
+	// MaxFrameSize is the max size in bytes of the request frames accepted by
+	// the server.
+	//
+	// When a client sends a larger frame, the server returns a
+	// FrameTooLargeError without reading the frame into memory,
+	// closes the connection,
+	// and increments the "thrift.frame-too-large" counter on metricsbp.M.
+	//
+	// When MaxFrameSize is 0, the server uses the limit of the THeader
+	// transport (1 GiB).
+	// The sizes of the requests from unframed binary or compact protocol
+	// clients are not checked.
+	MaxFrameSize uint32
+
+	// Optional, the server transport to use instead of the socket created from
+	// Addr or UnixSocket, Timeout and TLS,
+	// which are ignored when Socket is non-nil.
+	//
+	// This is mostly useful in tests, see thrifttest package.
+	Socket thrift.TServerTransport
 }
 
 // NewServer returns a thrift.TSimpleServer using the THeader transport
@@ -34,15 +67,25 @@ func NewServer(
 	processor thrift.TProcessor,
 	middlewares ...thrift.ProcessorMiddleware,
 ) (*thrift.TSimpleServer, error) {
-	transport, err := thrift.NewTServerSocketTimeout(cfg.Addr, cfg.Timeout)
-	if err != nil {
-		return nil, err
+	transport := cfg.Socket
+	if transport == nil {
+		var err error
+		transport, err = newServerSocket(cfg)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	server := thrift.NewTSimpleServer4(
 		thrift.WrapProcessor(processor, middlewares...),
 		transport,
-		thrift.NewTHeaderTransportFactory(nil),
+		frameLimitTransportFactory{
+			TTransportFactory: thrift.NewTHeaderTransportFactory(nil),
+			limit: frameLimit{
+				max:     cfg.MaxFrameSize,
+				counter: metricsbp.M.Counter("thrift.frame-too-large"),
+			},
+		},
 		thrift.NewTHeaderProtocolFactory(),
 	)
 	server.SetForwardHeaders(HeadersToForward)
@@ -50,6 +93,26 @@ func NewServer(
 	return server, nil
 }
 
+// newServerSocket creates the server socket from cfg.Addr or cfg.UnixSocket,
+// cfg.Timeout and cfg.TLS.
+func newServerSocket(cfg ServerConfig) (thrift.TServerTransport, error) {
+	var tlsConfig *tls.Config
+	if cfg.TLS != nil {
+		var err error
+		tlsConfig, err = cfg.TLS.ServerTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.UnixSocket != nil {
+		return newUnixServerSocket(*cfg.UnixSocket, cfg.Timeout, tlsConfig), nil
+	}
+	if tlsConfig != nil {
+		return thrift.NewTSSLServerSocketTimeout(cfg.Addr, tlsConfig, cfg.Timeout)
+	}
+	return thrift.NewTServerSocketTimeout(cfg.Addr, cfg.Timeout)
+}
+
 // NewBaseplateServer returns a new Thrift implementation of a Baseplate
 // server with the given TProcessor.
 //
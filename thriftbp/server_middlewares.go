@@ -2,36 +2,89 @@ package thriftbp
 
 import (
 	"context"
+	"runtime/debug"
 	"strconv"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"go.uber.org/zap"
 
 	"github.com/reddit/baseplate.go/edgecontext"
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/secrets"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
 var (
 	_ thrift.ProcessorMiddleware = InjectServerSpan
 	_ thrift.ProcessorMiddleware = ExtractDeadlineBudget
+	_ thrift.ProcessorMiddleware = RecoverPanic
 )
 
 // BaseplateDefaultProcessorMiddlewares returns the default processor
 //  middlewares that should be used by a baseplate Thrift service.
 //
+// It's the same as DefaultProcessorMiddlewares with only EdgeContextImpl set.
+func BaseplateDefaultProcessorMiddlewares(ecImpl *edgecontext.Impl) []thrift.ProcessorMiddleware {
+	return DefaultProcessorMiddlewares(DefaultProcessorMiddlewaresArgs{
+		EdgeContextImpl: ecImpl,
+	})
+}
+
+// DefaultProcessorMiddlewaresArgs are the args to be passed into
+// DefaultProcessorMiddlewares function.
+type DefaultProcessorMiddlewaresArgs struct {
+	EdgeContextImpl *edgecontext.Impl
+
+	// Logger, if non-nil, is attached to the request context object by
+	// InjectLogger, and used by the middlewares and log.FromContext instead of
+	// the global logger.
+	//
+	// Optional.
+	Logger *zap.SugaredLogger
+}
+
+// DefaultProcessorMiddlewares returns the default processor middlewares that
+// should be used by a baseplate Thrift service.
+//
 // Currently they are (in order):
 //
-// 1. ExtractDeadlineBudget
+// 1. InjectLogger (only when Logger is set)
 //
-// 2. InjectServerSpan
+// 2. ExtractDeadlineBudget
 //
-// 3. InjectEdgeContext
-func BaseplateDefaultProcessorMiddlewares(ecImpl *edgecontext.Impl) []thrift.ProcessorMiddleware {
-	return []thrift.ProcessorMiddleware{
+// 3. InjectServerSpan
+//
+// 4. InjectEdgeContext
+//
+// 5. RecoverPanic
+func DefaultProcessorMiddlewares(args DefaultProcessorMiddlewaresArgs) []thrift.ProcessorMiddleware {
+	var middlewares []thrift.ProcessorMiddleware
+	if args.Logger != nil {
+		middlewares = append(middlewares, InjectLogger(args.Logger))
+	}
+	return append(
+		middlewares,
 		ExtractDeadlineBudget,
 		InjectServerSpan,
-		InjectEdgeContext(ecImpl),
+		InjectEdgeContext(args.EdgeContextImpl),
+		RecoverPanic,
+	)
+}
+
+// InjectLogger returns a ProcessorMiddleware that attaches logger to the
+// context object passed into the `next` TProcessorFunction (see
+// log.AttachLogger),
+// so the logs from the middlewares after it and log.FromContext in the
+// handlers go through logger instead of the global logger.
+func InjectLogger(logger *zap.SugaredLogger) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				return next.Process(log.AttachLogger(ctx, logger), seqID, in, out)
+			},
+		}
 	}
 }
 
@@ -111,7 +164,7 @@ func InitializeEdgeContext(ctx context.Context, impl *edgecontext.Impl) context.
 
 	ec, err := edgecontext.FromHeader(header, impl)
 	if err != nil {
-		log.Error("Error while parsing EdgeRequestContext: " + err.Error())
+		log.FromContext(ctx).Errorw("Error while parsing EdgeRequestContext", "err", err)
 		return ctx
 	}
 	if ec == nil {
@@ -139,6 +192,20 @@ func InjectEdgeContext(impl *edgecontext.Impl) thrift.ProcessorMiddleware {
 	}
 }
 
+// InjectSecretsStore returns a ProcessorMiddleware that attaches store to the
+// context object passed into the `next` thrift.TProcessorFunction,
+// so it can be retrieved by secrets.FromContext.
+func InjectSecretsStore(store *secrets.Store) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				ctx = secrets.NewContext(ctx, store)
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+}
+
 // ExtractDeadlineBudget is the server middleware implementing Phase 1 of
 // Baseplate deadline propagation.
 //
@@ -158,3 +225,84 @@ func ExtractDeadlineBudget(name string, next thrift.TProcessorFunction) thrift.T
 		},
 	}
 }
+
+// RecoverPanic is a server middleware that recovers from panics in the `next`
+// TProcessorFunction.
+//
+// When a panic happens, it logs the panic with the stack trace,
+// increments the "thrift.<name>.panic" counter on metricsbp.M,
+// and writes a TApplicationException with INTERNAL_ERROR type to the client
+// instead of crashing the whole server.
+// The TApplicationException only has a generic message,
+// the panic value is never sent to the client.
+// If the panic happened after `next` already started writing the response,
+// the TApplicationException is not written to avoid writing a second response.
+// The TApplicationException is also returned as the error,
+// so that middlewares wrapping RecoverPanic (e.g. InjectServerSpan) can see it.
+func RecoverPanic(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+	return thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (success bool, err thrift.TException) {
+			var tracked *writeTrackingProtocol
+			if out != nil {
+				tracked = &writeTrackingProtocol{TProtocol: out}
+				out = tracked
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					log.FromContext(ctx).Errorw(
+						"recovered from panic in thrift handler",
+						"endpoint", name,
+						"panic", r,
+						"stack", string(debug.Stack()),
+					)
+					metricsbp.M.Counter("thrift." + name + ".panic").Add(1)
+
+					exc := thrift.NewTApplicationException(
+						thrift.INTERNAL_ERROR,
+						"Internal error processing "+name,
+					)
+					if tracked != nil && !tracked.written {
+						tracked.WriteMessageBegin(name, thrift.EXCEPTION, seqID)
+						exc.Write(tracked)
+						tracked.WriteMessageEnd()
+						tracked.Flush(ctx)
+					}
+					success, err = true, exc
+				}
+			}()
+			return next.Process(ctx, seqID, in, out)
+		},
+	}
+}
+
+// writeTrackingProtocol is a thrift.TProtocol that records whether a message
+// has been written to it.
+type writeTrackingProtocol struct {
+	thrift.TProtocol
+
+	written bool
+}
+
+func (p *writeTrackingProtocol) WriteMessageBegin(name string, typeID thrift.TMessageType, seqID int32) error {
+	p.written = true
+	return p.TProtocol.WriteMessageBegin(name, typeID, seqID)
+}
+
+// writeApplicationException rejects a request without passing it to the next
+// TProcessorFunction, by writing a TApplicationException with the given message
+// to the client and returning it as the error.
+func writeApplicationException(ctx context.Context, name string, seqID int32, in, out thrift.TProtocol, msg string) (bool, thrift.TException) {
+	if in != nil {
+		// Consume the args of the request so the connection can be reused.
+		in.Skip(thrift.STRUCT)
+		in.ReadMessageEnd()
+	}
+	exc := thrift.NewTApplicationException(thrift.UNKNOWN_APPLICATION_EXCEPTION, msg)
+	if out != nil {
+		out.WriteMessageBegin(name, thrift.EXCEPTION, seqID)
+		exc.Write(out)
+		out.WriteMessageEnd()
+		out.Flush(ctx)
+	}
+	return true, exc
+}
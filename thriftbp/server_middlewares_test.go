@@ -1,17 +1,23 @@
 package thriftbp_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/metricsbp"
 	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/secrets"
 	"github.com/reddit/baseplate.go/thriftbp"
 	"github.com/reddit/baseplate.go/tracing"
 )
@@ -213,6 +219,31 @@ func TestInjectEdgeContext(t *testing.T) {
 	}
 }
 
+func TestInjectSecretsStore(t *testing.T) {
+	store, dir := newSecretsStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	const name = "test"
+	var actual *secrets.Store
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					actual, _ = secrets.FromContext(ctx)
+					return true, nil
+				},
+			},
+		},
+	)
+	wrapped := thrift.WrapProcessor(processor, thriftbp.InjectSecretsStore(store))
+	wrapped.Process(thriftbp.SetMockTProcessorName(context.Background(), name), nil, nil)
+	if actual != store {
+		t.Errorf("Expected secrets store %p from context, got %p", store, actual)
+	}
+}
+
 func TestExtractDeadlineBudget(t *testing.T) {
 	name := "test"
 	processor := func(checker func(context.Context)) thrift.TProcessor {
@@ -300,3 +331,136 @@ func TestExtractDeadlineBudget(t *testing.T) {
 		},
 	)
 }
+
+func TestRecoverPanic(t *testing.T) {
+	name := "test"
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					panic("oops")
+				},
+			},
+		},
+	)
+
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+	out := thrift.NewTBinaryProtocolTransport(thrift.NewTMemoryBuffer())
+	wrapped := thrift.WrapProcessor(processor, thriftbp.RecoverPanic)
+	_, err := wrapped.Process(ctx, nil, out)
+
+	var appErr thrift.TApplicationException
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Expected TApplicationException, got %v", err)
+	}
+	if appErr.TypeId() != thrift.INTERNAL_ERROR {
+		t.Errorf("Expected INTERNAL_ERROR type, got %d", appErr.TypeId())
+	}
+	if strings.Contains(appErr.Error(), "oops") {
+		t.Errorf("Expected the panic value not sent to the client, got %q", appErr.Error())
+	}
+
+	msgName, msgType, _, err := out.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgName != name || msgType != thrift.EXCEPTION {
+		t.Errorf(
+			"Expected %q message with EXCEPTION type, got %q with type %d",
+			name,
+			msgName,
+			msgType,
+		)
+	}
+
+	var buf bytes.Buffer
+	metricsbp.M.Statsd.WriteTo(&buf)
+	expected := "thrift.test.panic:1.000000|c\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("Expected metrics %q, got %q", expected, actual)
+	}
+}
+
+func TestRecoverPanicInjectLogger(t *testing.T) {
+	name := "test"
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					panic("oops")
+				},
+			},
+		},
+	)
+
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+	wrapped := thrift.WrapProcessor(
+		processor,
+		thriftbp.InjectLogger(zap.New(core).Sugar()),
+		thriftbp.RecoverPanic,
+	)
+	wrapped.Process(ctx, nil, thrift.NewTBinaryProtocolTransport(thrift.NewTMemoryBuffer()))
+
+	entries := logs.FilterMessage("recovered from panic in thrift handler").AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry from the injected logger, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()["endpoint"]; got != name {
+		t.Errorf("Expected endpoint %q, got %v", name, got)
+	}
+}
+
+func TestRecoverPanicAfterWrite(t *testing.T) {
+	name := "test"
+	processor := thriftbp.NewMockTProcessor(
+		t,
+		map[string]thrift.TProcessorFunction{
+			name: thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					out.WriteMessageBegin(name, thrift.REPLY, seqID)
+					panic("oops")
+				},
+			},
+		},
+	)
+
+	defer func(st *metricsbp.Statsd) {
+		metricsbp.M = st
+	}(metricsbp.M)
+	metricsbp.M = metricsbp.NewStatsd(context.Background(), metricsbp.StatsdConfig{})
+
+	ctx := thriftbp.SetMockTProcessorName(context.Background(), name)
+	buf := thrift.NewTMemoryBuffer()
+	out := thrift.NewTBinaryProtocolTransport(buf)
+	wrapped := thrift.WrapProcessor(processor, thriftbp.RecoverPanic)
+	_, err := wrapped.Process(ctx, nil, out)
+
+	var appErr thrift.TApplicationException
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Expected TApplicationException, got %v", err)
+	}
+
+	msgName, msgType, _, err := out.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgName != name || msgType != thrift.REPLY {
+		t.Errorf(
+			"Expected %q message with REPLY type, got %q with type %d",
+			name,
+			msgName,
+			msgType,
+		)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no exception written after the reply, got %d more bytes", buf.Len())
+	}
+}
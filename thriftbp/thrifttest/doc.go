@@ -0,0 +1,21 @@
+// Package thrifttest provides an in-memory thrift client/server harness for
+// tests.
+//
+// It serves a thrift.TProcessor (usually a generated processor wrapping the
+// handler under test) with the Baseplate default processor middlewares,
+// and provides clients wrapped with the Baseplate default client middlewares,
+// connected to the server over in-memory connections,
+// so the handlers can be tested together with the middlewares without binding
+// sockets.
+//
+// A typical test looks like:
+//
+//     func TestMyEndpoint(t *testing.T) {
+//       server := thrifttest.NewServer(t, thrifttest.Config{
+//         Processor: myservice.NewMyServiceProcessor(&myHandler{}),
+//       })
+//       client := myservice.NewMyServiceClient(server.TClient())
+//       resp, err := client.MyEndpoint(context.Background(), req)
+//       // Assert on resp and err...
+//     }
+package thrifttest
@@ -0,0 +1,159 @@
+package thrifttest
+
+import (
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/edgecontext"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+// Config is the arg struct for NewServer.
+type Config struct {
+	// The processor to serve, usually a generated processor wrapping the
+	// handler under test.
+	Processor thrift.TProcessor
+
+	// Optional, the edgecontext.Impl used by the InjectEdgeContext server
+	// middleware.
+	EdgeContextImpl *edgecontext.Impl
+
+	// Optional, additional server middlewares to be applied after
+	// thriftbp.BaseplateDefaultProcessorMiddlewares.
+	ServerMiddlewares []thrift.ProcessorMiddleware
+
+	// Optional, additional client middlewares to be applied after
+	// thriftbp.BaseplateDefaultClientMiddlewares.
+	ClientMiddlewares []thrift.ClientMiddleware
+
+	// Optional, the logger used by the thrift server.
+	//
+	// Default to thrift.NopLogger, as closing the in-memory connections when
+	// the test finishes would be logged as errors.
+	Logger thrift.Logger
+}
+
+// Server is an in-memory thrift server started by NewServer.
+type Server struct {
+	// ClientPool creates clients connected to the server.
+	//
+	// Every client returned by GetClient uses a new in-memory connection,
+	// which is closed by ReleaseClient.
+	ClientPool thriftbp.ClientPool
+
+	server *thrift.TSimpleServer
+}
+
+// NewServer starts an in-memory thrift server serving cfg.Processor.
+//
+// The server is created by thriftbp.NewServer,
+// with thriftbp.BaseplateDefaultProcessorMiddlewares and
+// cfg.ServerMiddlewares.
+//
+// The server will be stopped when the test finishes.
+func NewServer(tb testing.TB, cfg Config) *Server {
+	tb.Helper()
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = thrift.NopLogger
+	}
+	transport := newPipeServerTransport()
+	middlewares := thriftbp.BaseplateDefaultProcessorMiddlewares(cfg.EdgeContextImpl)
+	middlewares = append(middlewares, cfg.ServerMiddlewares...)
+	server, err := thriftbp.NewServer(
+		thriftbp.ServerConfig{
+			Logger: logger,
+			Socket: transport,
+		},
+		cfg.Processor,
+		middlewares...,
+	)
+	if err != nil {
+		tb.Fatalf("thrifttest: failed to create server: %v", err)
+	}
+	if err := server.Listen(); err != nil {
+		tb.Fatalf("thrifttest: failed to listen: %v", err)
+	}
+	go server.AcceptLoop()
+
+	s := &Server{
+		ClientPool: clientPool{
+			transport:   transport,
+			middlewares: cfg.ClientMiddlewares,
+		},
+		server: server,
+	}
+	tb.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+// TClient returns a thrift.TClient that can be used to create generated
+// clients.
+//
+// The returned thrift.TClient is safe for concurrent use,
+// every Call gets a client from ClientPool and releases it after the call.
+func (s *Server) TClient() thrift.TClient {
+	return thriftbp.NewPooledTClient(s.ClientPool)
+}
+
+// Close stops the server and closes all the in-memory connections.
+func (s *Server) Close() error {
+	return s.server.Stop()
+}
+
+// clientPool is a thriftbp.ClientPool creating a new in-memory connection for
+// every client.
+type clientPool struct {
+	transport   *pipeServerTransport
+	middlewares []thrift.ClientMiddleware
+}
+
+func (p clientPool) GetClient() (thriftbp.Client, error) {
+	trans, err := p.transport.dial()
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		TClient: thriftbp.WrapClient(
+			thriftbp.StandardTClientFactory(trans, thrift.NewTHeaderProtocolFactory()),
+			p.middlewares...,
+		),
+		trans: trans,
+	}, nil
+}
+
+func (p clientPool) ReleaseClient(c thriftbp.Client) {
+	c.Close()
+}
+
+func (p clientPool) IsExhausted() bool {
+	return false
+}
+
+// Close is nop, the connections are closed by Server.Close.
+func (p clientPool) Close() error {
+	return nil
+}
+
+type client struct {
+	thrift.TClient
+
+	trans thrift.TTransport
+}
+
+func (c *client) Close() error {
+	return c.trans.Close()
+}
+
+func (c *client) IsOpen() bool {
+	return c.trans.IsOpen()
+}
+
+var (
+	_ thriftbp.ClientPool = clientPool{}
+	_ thriftbp.Client     = (*client)(nil)
+)
@@ -0,0 +1,106 @@
+package thrifttest_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/thriftbp/thrifttest"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+type handler struct {
+	healthy  bool
+	deadline bool
+	span     bool
+}
+
+func (h *handler) IsHealthy(ctx context.Context) (bool, error) {
+	_, h.deadline = ctx.Deadline()
+	h.span = opentracing.SpanFromContext(ctx) != nil
+	return h.healthy, nil
+}
+
+func TestServer(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	var called int64
+	h := &handler{healthy: true}
+	server := thrifttest.NewServer(t, thrifttest.Config{
+		Processor: baseplate.NewBaseplateServiceProcessor(h),
+		ServerMiddlewares: []thrift.ProcessorMiddleware{
+			func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+				return thrift.WrappedTProcessorFunction{
+					Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+						atomic.AddInt64(&called, 1)
+						return next.Process(ctx, seqID, in, out)
+					},
+				}
+			},
+		},
+	})
+	client := baseplate.NewBaseplateServiceClient(server.TClient())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	healthy, err := client.IsHealthy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !healthy {
+		t.Error("Expected IsHealthy to return true")
+	}
+	if called := atomic.LoadInt64(&called); called != 1 {
+		t.Errorf("Expected the server middleware to be called once, got %d", called)
+	}
+	if !h.deadline {
+		t.Error("Expected the deadline to be propagated to the server")
+	}
+	if !h.span {
+		t.Error("Expected the server span to be injected")
+	}
+
+	// The server span is finished after the response is written,
+	// give it some time to be recorded.
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Spans()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	var clientSpan, serverSpan *tracingtest.Span
+	for _, span := range recorder.Spans() {
+		span := span
+		if span.HasAnnotation(tracing.ZipkinTimeAnnotationKeyClientSend) {
+			clientSpan = &span
+		}
+		if span.HasAnnotation(tracing.ZipkinTimeAnnotationKeyServerReceive) {
+			serverSpan = &span
+		}
+	}
+	if clientSpan == nil || serverSpan == nil {
+		t.Fatalf("Expected both client and server spans, got %+v", recorder.Spans())
+	}
+	if parent, ok := recorder.Parent(*serverSpan); !ok || parent.SpanID != clientSpan.SpanID {
+		t.Errorf("Expected the server span to be a child of the client span, got %+v", recorder.Spans())
+	}
+}
+
+func TestServerClosed(t *testing.T) {
+	server := thrifttest.NewServer(t, thrifttest.Config{
+		Processor: baseplate.NewBaseplateServiceProcessor(&handler{}),
+	})
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	client := baseplate.NewBaseplateServiceClient(server.TClient())
+	var transportErr thrift.TTransportException
+	if _, err := client.IsHealthy(context.Background()); !errors.As(err, &transportErr) {
+		t.Errorf("Expected thrift.TTransportException after the server is closed, got %v", err)
+	}
+}
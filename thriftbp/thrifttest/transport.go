@@ -0,0 +1,84 @@
+package thrifttest
+
+import (
+	"net"
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// pipeServerTransport is a thrift.TServerTransport accepting in-memory
+// connections created by net.Pipe.
+type pipeServerTransport struct {
+	conns chan net.Conn
+
+	lock      sync.Mutex
+	open      map[net.Conn]struct{}
+	closed    bool
+	interrupt chan struct{}
+}
+
+func newPipeServerTransport() *pipeServerTransport {
+	return &pipeServerTransport{
+		conns:     make(chan net.Conn),
+		open:      make(map[net.Conn]struct{}),
+		interrupt: make(chan struct{}),
+	}
+}
+
+func (t *pipeServerTransport) Listen() error {
+	return nil
+}
+
+func (t *pipeServerTransport) Accept() (thrift.TTransport, error) {
+	select {
+	case conn := <-t.conns:
+		return thrift.NewTSocketFromConnTimeout(conn, 0), nil
+	case <-t.interrupt:
+		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, "thrifttest: server closed")
+	}
+}
+
+func (t *pipeServerTransport) Close() error {
+	return t.Interrupt()
+}
+
+// Interrupt closes the transport and all the connections created by dial.
+func (t *pipeServerTransport) Interrupt() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	close(t.interrupt)
+	for conn := range t.open {
+		conn.Close()
+	}
+	t.open = nil
+	return nil
+}
+
+// dial creates a new in-memory connection to the server,
+// and returns the client side of it.
+func (t *pipeServerTransport) dial() (thrift.TTransport, error) {
+	client, server := net.Pipe()
+
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, "thrifttest: server closed")
+	}
+	t.open[client] = struct{}{}
+	t.open[server] = struct{}{}
+	t.lock.Unlock()
+
+	select {
+	case t.conns <- server:
+		return thrift.NewTSocketFromConnTimeout(client, 0), nil
+	case <-t.interrupt:
+		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, "thrifttest: server closed")
+	}
+}
+
+var _ thrift.TServerTransport = (*pipeServerTransport)(nil)
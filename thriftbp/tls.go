@@ -0,0 +1,163 @@
+package thriftbp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// TLSConfig is the configuration for TLS sockets used by thrift servers and
+// client pools.
+//
+// The certificates and keys are read from simple secrets in Store,
+// all of them PEM-encoded.
+// They are read again for every new connection,
+// so when the secrets rotate the new connections pick up the new certificates
+// without a restart.
+type TLSConfig struct {
+	// Store is the secrets store to read the certificates and keys from.
+	//
+	// It's required.
+	Store *secrets.Store
+
+	// CertPath and KeyPath are the paths of the simple secrets containing the
+	// certificate chain and the private key of this end of the connection.
+	//
+	// They are required for servers.
+	// For clients they are only needed when the server verifies client
+	// certificates (mutual TLS).
+	CertPath string
+	KeyPath  string
+
+	// CAPath is the path of the simple secret containing the CA certificates
+	// used to verify the other end of the connection.
+	//
+	// For clients, when it's empty the host's root CA set is used instead.
+	// For servers, it's required when VerifyClientCert is true.
+	CAPath string
+
+	// ServerName is used by clients to verify the hostname on the certificate
+	// returned by the server.
+	//
+	// When it's empty, the host part of the address is used instead.
+	// It's ignored by servers.
+	ServerName string
+
+	// VerifyClientCert makes the servers require and verify client
+	// certificates against the CA certificates from CAPath (mutual TLS).
+	//
+	// It's ignored by clients.
+	VerifyClientCert bool
+}
+
+// ServerTLSConfig returns the *tls.Config to be used by thrift servers.
+//
+// The returned *tls.Config reads the secrets again for every handshake.
+// The secrets are also read once by ServerTLSConfig to report configuration
+// errors early.
+func (cfg TLSConfig) ServerTLSConfig() (*tls.Config, error) {
+	if cfg.CertPath == "" || cfg.KeyPath == "" {
+		return nil, errors.New("thriftbp: CertPath and KeyPath are required for TLS servers")
+	}
+	if cfg.VerifyClientCert && cfg.CAPath == "" {
+		return nil, errors.New("thriftbp: CAPath is required to verify client certificates")
+	}
+	if _, err := cfg.loadServerConfig(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return cfg.loadServerConfig()
+		},
+	}, nil
+}
+
+// ClientTLSConfig returns the *tls.Config to be used by a new client
+// connection.
+//
+// Unlike ServerTLSConfig, the returned *tls.Config is a snapshot of the
+// current secrets and should only be used for a single connection.
+func (cfg TLSConfig) ClientTLSConfig() (*tls.Config, error) {
+	if (cfg.CertPath == "") != (cfg.KeyPath == "") {
+		return nil, errors.New("thriftbp: CertPath and KeyPath must be set together")
+	}
+	if cfg.Store == nil {
+		return nil, errors.New("thriftbp: Store is required for TLS")
+	}
+	config := &tls.Config{
+		ServerName: cfg.ServerName,
+	}
+	if cfg.CertPath != "" {
+		cert, err := cfg.loadCert()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.CAPath != "" {
+		pool, err := cfg.loadCAs()
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+func (cfg TLSConfig) loadServerConfig() (*tls.Config, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("thriftbp: Store is required for TLS")
+	}
+	cert, err := cfg.loadCert()
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	if cfg.VerifyClientCert {
+		pool, err := cfg.loadCAs()
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+func (cfg TLSConfig) loadCert() (tls.Certificate, error) {
+	certSecret, err := cfg.Store.GetSimpleSecret(cfg.CertPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("thriftbp: failed to get TLS certificate: %w", err)
+	}
+	keySecret, err := cfg.Store.GetSimpleSecret(cfg.KeyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("thriftbp: failed to get TLS key: %w", err)
+	}
+	cert, err := tls.X509KeyPair(certSecret.Value, keySecret.Value)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf(
+			"thriftbp: failed to load TLS key pair from secrets %q and %q: %w",
+			cfg.CertPath,
+			cfg.KeyPath,
+			err,
+		)
+	}
+	return cert, nil
+}
+
+func (cfg TLSConfig) loadCAs() (*x509.CertPool, error) {
+	caSecret, err := cfg.Store.GetSimpleSecret(cfg.CAPath)
+	if err != nil {
+		return nil, fmt.Errorf("thriftbp: failed to get TLS CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caSecret.Value) {
+		return nil, fmt.Errorf("thriftbp: no valid CA certificates found in secret %q", cfg.CAPath)
+	}
+	return pool, nil
+}
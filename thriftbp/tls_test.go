@@ -0,0 +1,294 @@
+package thriftbp_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+// newTestCert creates a certificate signed by parent,
+// or a self-signed CA certificate when parent is nil.
+func newTestCert(t *testing.T, parent *testCert, serial int64) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "thriftbp-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+func newTLSSecretsStore(t *testing.T, values map[string]string) *secrets.Store {
+	t.Helper()
+
+	type simpleSecret struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	doc := map[string]interface{}{
+		"vault": map[string]string{
+			"url":   "vault.reddit.ue1.snooguts.net",
+			"token": "17213328-36d4-11e7-8459-525400f56d04",
+		},
+	}
+	secretsMap := make(map[string]simpleSecret)
+	for path, value := range values {
+		secretsMap[path] = simpleSecret{Type: "simple", Value: value}
+	}
+	doc["secrets"] = secretsMap
+	content, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "thriftbp_tls_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "secrets.json")
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := secrets.NewStore(context.Background(), path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return store
+}
+
+// handshake does a TLS handshake between the server and client configs and
+// returns the error from the client side.
+func handshake(t *testing.T, server, client *tls.Config) error {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+		// Read until the client closes the connection, so the server side
+		// errors are surfaced to the client.
+		ioutil.ReadAll(conn)
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), client)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// With TLS 1.3 client certificate failures are only reported to the client
+	// after the handshake, on the first read.
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 100))
+	_, err = conn.Read(make([]byte, 1))
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return nil
+	}
+	return err
+}
+
+func TestTLSConfig(t *testing.T) {
+	ca := newTestCert(t, nil, 1)
+	server := newTestCert(t, ca, 2)
+	client := newTestCert(t, ca, 3)
+	store := newTLSSecretsStore(t, map[string]string{
+		"secret/tls/ca":         ca.certPEM,
+		"secret/tls/server.crt": server.certPEM,
+		"secret/tls/server.key": server.keyPEM,
+		"secret/tls/client.crt": client.certPEM,
+		"secret/tls/client.key": client.keyPEM,
+	})
+
+	serverCfg := thriftbp.TLSConfig{
+		Store:            store,
+		CertPath:         "secret/tls/server.crt",
+		KeyPath:          "secret/tls/server.key",
+		CAPath:           "secret/tls/ca",
+		VerifyClientCert: true,
+	}
+	serverTLS, err := serverCfg.ServerTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("mutual", func(t *testing.T) {
+		clientTLS, err := thriftbp.TLSConfig{
+			Store:    store,
+			CertPath: "secret/tls/client.crt",
+			KeyPath:  "secret/tls/client.key",
+			CAPath:   "secret/tls/ca",
+		}.ClientTLSConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := handshake(t, serverTLS, clientTLS); err != nil {
+			t.Errorf("Expected handshake to succeed, got %v", err)
+		}
+	})
+
+	t.Run("no-client-cert", func(t *testing.T) {
+		clientTLS, err := thriftbp.TLSConfig{
+			Store:  store,
+			CAPath: "secret/tls/ca",
+		}.ClientTLSConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := handshake(t, serverTLS, clientTLS); err == nil {
+			t.Error("Expected handshake to fail without client certificate, got nil")
+		}
+	})
+
+	t.Run("unknown-server-ca", func(t *testing.T) {
+		clientTLS, err := thriftbp.TLSConfig{
+			Store:    store,
+			CertPath: "secret/tls/client.crt",
+			KeyPath:  "secret/tls/client.key",
+		}.ClientTLSConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := handshake(t, serverTLS, clientTLS); err == nil {
+			t.Error("Expected handshake to fail with unknown server CA, got nil")
+		}
+	})
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	ca := newTestCert(t, nil, 1)
+	store := newTLSSecretsStore(t, map[string]string{
+		"secret/tls/ca": ca.certPEM,
+	})
+
+	for _, c := range []struct {
+		label  string
+		cfg    thriftbp.TLSConfig
+		server bool
+	}{
+		{
+			label:  "server-no-cert",
+			cfg:    thriftbp.TLSConfig{Store: store},
+			server: true,
+		},
+		{
+			label: "server-no-ca",
+			cfg: thriftbp.TLSConfig{
+				Store:            store,
+				CertPath:         "secret/tls/server.crt",
+				KeyPath:          "secret/tls/server.key",
+				VerifyClientCert: true,
+			},
+			server: true,
+		},
+		{
+			label: "server-missing-secret",
+			cfg: thriftbp.TLSConfig{
+				Store:    store,
+				CertPath: "secret/tls/server.crt",
+				KeyPath:  "secret/tls/server.key",
+			},
+			server: true,
+		},
+		{
+			label: "client-no-store",
+			cfg:   thriftbp.TLSConfig{},
+		},
+		{
+			label: "client-cert-without-key",
+			cfg: thriftbp.TLSConfig{
+				Store:    store,
+				CertPath: "secret/tls/client.crt",
+			},
+		},
+		{
+			label: "client-invalid-ca",
+			cfg: thriftbp.TLSConfig{
+				Store:  store,
+				CAPath: "secret/tls/missing",
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var err error
+			if c.server {
+				_, err = c.cfg.ServerTLSConfig()
+			} else {
+				_, err = c.cfg.ClientTLSConfig()
+			}
+			if err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -36,7 +36,7 @@ func CreateThriftContextFromSpan(ctx context.Context, span *tracing.Span) contex
 	ctx = thrift.SetHeader(
 		ctx,
 		HeaderTracingTrace,
-		strconv.FormatUint(span.TraceID(), 10),
+		span.TraceIDString(),
 	)
 	headers = append(headers, HeaderTracingTrace)
 
@@ -3,6 +3,7 @@ package thriftbp_test
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/apache/thrift/lib/go/thrift"
@@ -154,6 +155,46 @@ func TestCreateThriftContextFromSpan(t *testing.T) {
 				)
 			}
 
+			checkContextKey(t, ctx, thriftbp.HeaderTracingSampled)
+			if v, ok := thrift.GetHeader(ctx, thriftbp.HeaderTracingSampled); !ok || v != thriftbp.HeaderTracingSampledTrue {
+				t.Errorf(
+					"sampled in the context expected to be %q, got %q & %v",
+					thriftbp.HeaderTracingSampledTrue,
+					v,
+					ok,
+				)
+			}
+		},
+	)
+	parentCtx = thrift.SetHeader(parentCtx, thriftbp.HeaderTracingSampled, "0")
+	parentCtx = thrift.SetHeader(
+		parentCtx,
+		thriftbp.HeaderTracingFlags,
+		strconv.FormatInt(tracing.FlagMaskDebug, 10),
+	)
+	_, span = thriftbp.StartSpanFromThriftContext(parentCtx, name)
+
+	t.Run(
+		"debug",
+		func(t *testing.T) {
+			child := tracing.AsSpan(opentracing.StartSpan(
+				"test",
+				opentracing.ChildOf(span),
+				tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+			))
+			ctx := thriftbp.CreateThriftContextFromSpan(context.Background(), child)
+
+			checkContextKey(t, ctx, thriftbp.HeaderTracingFlags)
+			expectedFlags := strconv.FormatInt(tracing.FlagMaskDebug, 10)
+			if v, ok := thrift.GetHeader(ctx, thriftbp.HeaderTracingFlags); !ok || v != expectedFlags {
+				t.Errorf(
+					"flags in the context expected to be %q, got %q & %v",
+					expectedFlags,
+					v,
+					ok,
+				)
+			}
+
 			checkContextKey(t, ctx, thriftbp.HeaderTracingSampled)
 			if v, ok := thrift.GetHeader(ctx, thriftbp.HeaderTracingSampled); !ok || v != thriftbp.HeaderTracingSampledTrue {
 				t.Errorf(
@@ -0,0 +1,150 @@
+package thriftbp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// DefaultUnixSocketMode is the permission bits of the unix domain socket file
+// used when UnixSocketConfig.Mode is 0.
+//
+// It allows the processes running as the same user or group,
+// e.g. the sidecar proxy, to connect.
+const DefaultUnixSocketMode os.FileMode = 0660
+
+// UnixSocketConfig is the config to serve thrift over a unix domain socket.
+type UnixSocketConfig struct {
+	// The path of the socket file, required.
+	//
+	// If a stale socket file is left at the path, e.g. by a crashed server,
+	// it's removed before listening.
+	// The server fails to start if the path is another kind of file,
+	// or if another server is still listening on it.
+	//
+	// The socket file is removed when the server stops.
+	Path string `yaml:"path"`
+
+	// Optional, the permission bits of the socket file.
+	//
+	// Default to DefaultUnixSocketMode.
+	Mode os.FileMode `yaml:"mode"`
+}
+
+// unixServerSocket is a thrift.TServerTransport listening on a unix domain
+// socket.
+type unixServerSocket struct {
+	cfg     UnixSocketConfig
+	timeout time.Duration
+	tls     *tls.Config
+
+	mu          sync.Mutex
+	listener    net.Listener
+	interrupted bool
+}
+
+var _ thrift.TServerTransport = (*unixServerSocket)(nil)
+
+func newUnixServerSocket(cfg UnixSocketConfig, timeout time.Duration, tlsConfig *tls.Config) *unixServerSocket {
+	if cfg.Mode == 0 {
+		cfg.Mode = DefaultUnixSocketMode
+	}
+	return &unixServerSocket{
+		cfg:     cfg,
+		timeout: timeout,
+		tls:     tlsConfig,
+	}
+}
+
+// Listen implements thrift.TServerTransport.
+func (s *unixServerSocket) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return nil
+	}
+
+	if err := removeStaleUnixSocket(s.cfg.Path); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", s.cfg.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(s.cfg.Path, s.cfg.Mode); err != nil {
+		listener.Close()
+		return err
+	}
+	if s.tls != nil {
+		listener = tls.NewListener(listener, s.tls)
+	}
+	s.listener = listener
+	return nil
+}
+
+// Accept implements thrift.TServerTransport.
+func (s *unixServerSocket) Accept() (thrift.TTransport, error) {
+	s.mu.Lock()
+	listener := s.listener
+	interrupted := s.interrupted
+	s.mu.Unlock()
+
+	if interrupted {
+		return nil, thrift.NewTTransportException(thrift.END_OF_FILE, "Transport Interrupted")
+	}
+	if listener == nil {
+		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, "No underlying server socket")
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, thrift.NewTTransportExceptionFromError(err)
+	}
+	return thrift.NewTSocketFromConnTimeout(conn, s.timeout), nil
+}
+
+// Close implements thrift.TServerTransport.
+//
+// Closing the listener also removes the socket file.
+func (s *unixServerSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.listener = nil
+	return err
+}
+
+// Interrupt implements thrift.TServerTransport.
+func (s *unixServerSocket) Interrupt() error {
+	s.mu.Lock()
+	s.interrupted = true
+	s.mu.Unlock()
+	return s.Close()
+}
+
+// removeStaleUnixSocket removes the socket file at path if no one is listening
+// on it.
+func removeStaleUnixSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("thriftbp: %q exists and is not a unix domain socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("thriftbp: unix domain socket %q is already in use", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,129 @@
+package thriftbp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	bpgen "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+type healthyHandler struct{}
+
+func (healthyHandler) IsHealthy(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func newUnixSocketServer(t *testing.T, cfg thriftbp.UnixSocketConfig) *thrift.TSimpleServer {
+	t.Helper()
+
+	server, err := thriftbp.NewServer(
+		thriftbp.ServerConfig{
+			UnixSocket: &cfg,
+			Logger:     thrift.NopLogger,
+		},
+		bpgen.NewBaseplateServiceProcessor(healthyHandler{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thriftbp_unix_socket_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "thrift.sock")
+
+	// Leave a stale socket file behind.
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	server := newUnixSocketServer(t, thriftbp.UnixSocketConfig{
+		Path: path,
+		Mode: 0600,
+	})
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go server.AcceptLoop()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Expected socket file mode %v, got %v", os.FileMode(0600), mode)
+	}
+
+	t.Run("call", func(t *testing.T) {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trans := thrift.NewTSocketFromConnTimeout(conn, time.Second)
+		defer trans.Close()
+		factory := thrift.NewTHeaderProtocolFactory()
+		client := bpgen.NewBaseplateServiceClient(thrift.NewTStandardClient(
+			factory.GetProtocol(trans),
+			factory.GetProtocol(trans),
+		))
+		healthy, err := client.IsHealthy(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !healthy {
+			t.Error("Expected IsHealthy to return true")
+		}
+	})
+
+	t.Run("in-use", func(t *testing.T) {
+		other := newUnixSocketServer(t, thriftbp.UnixSocketConfig{Path: path})
+		if err := other.Listen(); err == nil {
+			other.Stop()
+			t.Error("Expected error listening on a socket in use")
+		}
+	})
+
+	if err := server.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file to be removed after stop, got %v", err)
+	}
+}
+
+func TestUnixSocketNotSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thriftbp_unix_socket_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "thrift.sock")
+	if err := ioutil.WriteFile(path, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newUnixSocketServer(t, thriftbp.UnixSocketConfig{Path: path})
+	if err := server.Listen(); err == nil {
+		server.Stop()
+		t.Error("Expected error listening on a regular file")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the regular file to be kept, got %v", err)
+	}
+}
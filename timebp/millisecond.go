@@ -9,6 +9,8 @@ import (
 var (
 	_ json.Unmarshaler = (*TimestampMillisecond)(nil)
 	_ json.Marshaler   = TimestampMillisecond{}
+	_ json.Marshaler   = DurationMillisecond(0)
+	_ json.Unmarshaler = (*DurationMillisecond)(nil)
 )
 
 const millisecondsPerSecond = int64(time.Second / time.Millisecond)
@@ -74,3 +76,41 @@ func TimeToMilliseconds(t time.Time) int64 {
 	ms += int64(t.Nanosecond()) / int64(time.Millisecond)
 	return ms
 }
+
+// DurationMillisecond implements json encoding/decoding using milliseconds
+// as int.
+type DurationMillisecond time.Duration
+
+func (zd DurationMillisecond) String() string {
+	return zd.ToDuration().String()
+}
+
+// ToDuration converts DurationMillisecond back to time.Duration.
+func (zd DurationMillisecond) ToDuration() time.Duration {
+	return time.Duration(zd)
+}
+
+// MarshalJSON implements json.Marshaler interface, using milliseconds.
+func (zd DurationMillisecond) MarshalJSON() ([]byte, error) {
+	if zd == 0 {
+		return []byte("null"), nil
+	}
+	n := int64(zd.ToDuration() / time.Millisecond)
+	return []byte(strconv.FormatInt(n, 10)), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (zd *DurationMillisecond) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*zd = 0
+		return nil
+	}
+
+	d, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*zd = DurationMillisecond(time.Duration(d) * time.Millisecond)
+	return nil
+}
@@ -128,3 +128,53 @@ func TestTimestampMillisecondJSON(t *testing.T) {
 		},
 	)
 }
+
+type jsonTestTypeDurationMillisecond struct {
+	Duration timebp.DurationMillisecond `json:"duration"`
+}
+
+func TestDurationMillisecondJSON(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		duration time.Duration
+		expected string
+	}{
+		{
+			label:    "null",
+			duration: 0,
+			expected: `{"duration":null}`,
+		},
+		{
+			label:    "10s",
+			duration: 10 * time.Second,
+			expected: `{"duration":10000}`,
+		},
+		{
+			label:    "truncated",
+			duration: 1500*time.Microsecond + 1,
+			expected: `{"duration":1}`,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			v := jsonTestTypeDurationMillisecond{
+				Duration: timebp.DurationMillisecond(c.duration),
+			}
+			s, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(s) != c.expected {
+				t.Errorf("Encoded json expected to be %q, got %q", c.expected, s)
+			}
+
+			v.Duration = timebp.DurationMillisecond(time.Hour)
+			if err := json.Unmarshal(s, &v); err != nil {
+				t.Fatal(err)
+			}
+			expected := c.duration.Truncate(time.Millisecond)
+			if v.Duration.ToDuration() != expected {
+				t.Errorf("Duration expected %v, got %v", expected, v.Duration)
+			}
+		})
+	}
+}
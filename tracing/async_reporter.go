@@ -0,0 +1,235 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/mqsend"
+)
+
+// DefaultAsyncReporterBatchSize is the BatchSize used by AsyncReporter when
+// it's not set in AsyncReporterConfig.
+const DefaultAsyncReporterBatchSize = 100
+
+// Errors returned by AsyncReporter.Send.
+var (
+	ErrAsyncReporterQueueFull = errors.New("tracing: async reporter queue is full")
+	ErrAsyncReporterClosed    = errors.New("tracing: async reporter is closed")
+)
+
+// AsyncReporterConfig is the configuration used by NewAsyncReporter.
+//
+// Other than Logger, it can be deserialized from YAML.
+type AsyncReporterConfig struct {
+	// MaxQueueSize is the max number of finished spans buffered in memory
+	// waiting to be published.
+	//
+	// When MaxQueueSize <= 0, MaxQueueSize in this package will be used.
+	MaxQueueSize int `yaml:"maxQueueSize"`
+
+	// BatchSize is the max number of spans the background goroutine takes from
+	// the queue and publishes in one go.
+	//
+	// When BatchSize <= 0, DefaultAsyncReporterBatchSize will be used.
+	BatchSize int `yaml:"batchSize"`
+
+	// Block controls what happens when the queue is full.
+	//
+	// When Block is false (default), the span is dropped immediately.
+	// When Block is true, Send blocks until there's room in the queue or the
+	// context object passed into Send is done (see
+	// TracerConfig.MaxRecordTimeout), and the span is dropped in the latter case.
+	Block bool `yaml:"block"`
+
+	// Logger, if non-nil, will be used to log the errors from the underlying
+	// message queue.
+	Logger log.Wrapper `yaml:"-"`
+}
+
+// AsyncReporter publishes finished spans to another mqsend.MessageQueue in a
+// background goroutine, so slow message queues can't add latency to the
+// requests.
+//
+// Finished spans are put into a bounded in-memory queue consumed by the
+// background goroutine, which takes them out in batches and publishes them to
+// the underlying message queue one by one.
+//
+// It implements mqsend.MessageQueue so it can be used as the message queue of
+// the Tracer, see TracerConfig.AsyncReporter.
+type AsyncReporter struct {
+	// Accessed atomically, keep it as the first field for 64-bit alignment.
+	droppedSpans uint64
+
+	cfg     AsyncReporterConfig
+	queue   mqsend.MessageQueue
+	timeout time.Duration
+	logger  log.Wrapper
+
+	spans     chan []byte
+	flushes   chan chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ mqsend.MessageQueue = (*AsyncReporter)(nil)
+
+// NewAsyncReporter creates a new AsyncReporter publishing to queue,
+// and starts its background goroutine.
+//
+// Every span is published to queue with a context object with
+// DefaultMaxRecordTimeout.
+func NewAsyncReporter(cfg AsyncReporterConfig, queue mqsend.MessageQueue) *AsyncReporter {
+	return newAsyncReporter(cfg, queue, DefaultMaxRecordTimeout)
+}
+
+func newAsyncReporter(cfg AsyncReporterConfig, queue mqsend.MessageQueue, timeout time.Duration) *AsyncReporter {
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = MaxQueueSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultAsyncReporterBatchSize
+	}
+	r := &AsyncReporter{
+		cfg:     cfg,
+		queue:   queue,
+		timeout: timeout,
+		logger:  log.FallbackWrapper(cfg.Logger),
+		spans:   make(chan []byte, cfg.MaxQueueSize),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Send implements mqsend.MessageQueue.
+//
+// It puts data into the queue to be published by the background goroutine.
+// When the queue is full, it either drops the span and returns
+// ErrAsyncReporterQueueFull, or blocks until there's room or ctx is done,
+// depending on AsyncReporterConfig.Block.
+func (r *AsyncReporter) Send(ctx context.Context, data []byte) error {
+	select {
+	case <-r.stop:
+		return ErrAsyncReporterClosed
+	default:
+	}
+	select {
+	case r.spans <- data:
+		return nil
+	default:
+	}
+	if r.cfg.Block {
+		select {
+		case r.spans <- data:
+			return nil
+		case <-r.stop:
+			return ErrAsyncReporterClosed
+		case <-ctx.Done():
+			atomic.AddUint64(&r.droppedSpans, 1)
+			return ctx.Err()
+		}
+	}
+	atomic.AddUint64(&r.droppedSpans, 1)
+	return ErrAsyncReporterQueueFull
+}
+
+// Flush blocks until all the spans buffered before the call are published,
+// or ctx is done, whichever comes first.
+func (r *AsyncReporter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case r.flushes <- flushed:
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the background goroutine after publishing all the buffered
+// spans, then closes the underlying message queue.
+func (r *AsyncReporter) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+	return r.queue.Close()
+}
+
+// DroppedSpans returns the total number of spans dropped by the reporter,
+// either because the queue is full or the underlying message queue failed to
+// publish them.
+//
+// It's useful to report it periodically as a gauge, for example:
+//
+//     gauge := metricsbp.M.Gauge("tracing.async-reporter.dropped-spans")
+//     gauge.Set(float64(reporter.DroppedSpans()))
+func (r *AsyncReporter) DroppedSpans() uint64 {
+	return atomic.LoadUint64(&r.droppedSpans)
+}
+
+func (r *AsyncReporter) run() {
+	defer close(r.done)
+
+	batch := make([][]byte, 0, r.cfg.BatchSize)
+	for {
+		select {
+		case <-r.stop:
+			r.drain()
+			return
+		case flushed := <-r.flushes:
+			r.drain()
+			close(flushed)
+		case data := <-r.spans:
+			batch = append(batch, data)
+		fill:
+			for len(batch) < r.cfg.BatchSize {
+				select {
+				case data := <-r.spans:
+					batch = append(batch, data)
+				default:
+					break fill
+				}
+			}
+			for _, data := range batch {
+				r.publish(data)
+			}
+			batch = batch[:0]
+		}
+	}
+}
+
+// drain publishes all the spans currently in the queue.
+func (r *AsyncReporter) drain() {
+	for {
+		select {
+		case data := <-r.spans:
+			r.publish(data)
+		default:
+			return
+		}
+	}
+}
+
+func (r *AsyncReporter) publish(data []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if err := r.queue.Send(ctx, data); err != nil {
+		atomic.AddUint64(&r.droppedSpans, 1)
+		r.logger(fmt.Sprintf("tracing: async reporter failed to publish span: %v", err))
+	}
+}
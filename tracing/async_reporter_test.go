@@ -0,0 +1,162 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+type slowQueue struct {
+	// If non-nil, Send is blocked until it's closed.
+	release chan struct{}
+
+	lock   sync.Mutex
+	msgs   []string
+	closed bool
+}
+
+func (q *slowQueue) Send(ctx context.Context, data []byte) error {
+	if q.release != nil {
+		<-q.release
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.msgs = append(q.msgs, string(data))
+	return nil
+}
+
+func (q *slowQueue) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	return nil
+}
+
+func (q *slowQueue) snapshot() (msgs []string, closed bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return append([]string(nil), q.msgs...), q.closed
+}
+
+func TestAsyncReporter(t *testing.T) {
+	q := &slowQueue{}
+	reporter := tracing.NewAsyncReporter(tracing.AsyncReporterConfig{
+		BatchSize: 2,
+	}, q)
+
+	expected := []string{"a", "b", "c", "d", "e"}
+	for _, msg := range expected {
+		if err := reporter.Send(context.Background(), []byte(msg)); err != nil {
+			t.Fatalf("Send(%q) failed: %v", msg, err)
+		}
+	}
+	if err := reporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, closed := q.snapshot()
+	if len(msgs) != len(expected) {
+		t.Fatalf("Expected messages %v, got %v", expected, msgs)
+	}
+	for i := range expected {
+		if msgs[i] != expected[i] {
+			t.Errorf("Expected messages %v, got %v", expected, msgs)
+			break
+		}
+	}
+	if !closed {
+		t.Error("Expected the underlying queue to be closed")
+	}
+	if err := reporter.Send(context.Background(), []byte("f")); !errors.Is(err, tracing.ErrAsyncReporterClosed) {
+		t.Errorf("Expected ErrAsyncReporterClosed, got %v", err)
+	}
+	if dropped := reporter.DroppedSpans(); dropped != 0 {
+		t.Errorf("Expected no dropped spans, got %d", dropped)
+	}
+}
+
+func TestAsyncReporterQueueFull(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		block    bool
+		expected error
+	}{
+		{
+			label:    "drop",
+			expected: tracing.ErrAsyncReporterQueueFull,
+		},
+		{
+			label:    "block",
+			block:    true,
+			expected: context.DeadlineExceeded,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			q := &slowQueue{release: make(chan struct{})}
+			reporter := tracing.NewAsyncReporter(tracing.AsyncReporterConfig{
+				MaxQueueSize: 1,
+				BatchSize:    1,
+				Block:        c.block,
+			}, q)
+
+			// The first span might be taken off the queue and blocked in the
+			// underlying queue, so the queue is full on either the 2nd or 3rd span.
+			var sent int
+			var err error
+			for sent < 3 && err == nil {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+				err = reporter.Send(ctx, []byte("span"))
+				cancel()
+				sent++
+			}
+			if !errors.Is(err, c.expected) {
+				t.Errorf("Expected %v, got %v", c.expected, err)
+			}
+			if dropped := reporter.DroppedSpans(); dropped != 1 {
+				t.Errorf("Expected 1 dropped span, got %d", dropped)
+			}
+
+			close(q.release)
+			reporter.Close()
+			if msgs, _ := q.snapshot(); len(msgs) != sent-1 {
+				t.Errorf("Expected %d published spans, got %d", sent-1, len(msgs))
+			}
+		})
+	}
+}
+
+func TestAsyncReporterFlush(t *testing.T) {
+	q := &slowQueue{release: make(chan struct{})}
+	reporter := tracing.NewAsyncReporter(tracing.AsyncReporterConfig{}, q)
+	defer reporter.Close()
+
+	if err := reporter.Send(context.Background(), []byte("span")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		if err := reporter.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	close(q.release)
+
+	t.Run("flushed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := reporter.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if msgs, _ := q.snapshot(); len(msgs) != 1 {
+			t.Errorf("Expected 1 published span after Flush, got %v", msgs)
+		}
+	})
+}
@@ -18,11 +18,47 @@ type Config struct {
 	// to be read by a trace publisher sidecar.
 	QueueName string `yaml:"queueName"`
 
+	// HTTPReporter configures sending traces directly to a Zipkin V2 collector
+	// via HTTP, used when QueueName is empty.
+	HTTPReporter HTTPReporterConfig `yaml:"httpReporter"`
+
 	// RecordTimeout is the timeout on writing a trace to the POSIX queue.
 	RecordTimeout time.Duration `yaml:"recordTimeout"`
 
-	// SampleRate is the % of new trace's to sample.
+	// Sampler is the sampling strategy for new traces,
+	// either "probabilistic" (SamplerTypeProbabilistic) or
+	// "rateLimited" (SamplerTypeRateLimited).
+	//
+	// Optional, defaults to "probabilistic".
+	Sampler string `yaml:"sampler"`
+
+	// SampleRate is the % of new trace's to sample,
+	// used by the probabilistic sampler.
 	SampleRate float64 `yaml:"sampleRate"`
+
+	// MaxSampledPerSecond is the max number of new traces to sample per second,
+	// used by the rate limited sampler.
+	MaxSampledPerSecond float64 `yaml:"maxSampledPerSecond"`
+
+	// TraceID128Bit controls whether new traces use 128-bit trace ids.
+	//
+	// Optional, defaults to false (64-bit trace ids).
+	TraceID128Bit bool `yaml:"traceID128Bit"`
+
+	// PoolSpans controls whether the spans are reused after they are stopped,
+	// see TracerConfig.PoolSpans for the caveats.
+	//
+	// Optional, defaults to false.
+	PoolSpans bool `yaml:"poolSpans"`
+
+	// EdgeContextSpanTags controls whether the fields of the edge request
+	// context are set as tags on the server spans,
+	// see edgecontext.Config.SpanTags.
+	//
+	// It's not used by InitFromConfig but by baseplate.New.
+	//
+	// Optional, defaults to false.
+	EdgeContextSpanTags bool `yaml:"edgeContextSpanTags"`
 }
 
 // InitFromConfig initializes the global tracer using the given Config and
@@ -32,11 +68,18 @@ type Config struct {
 // It returns an io.Closer that can be used to close out the tracer when the
 // server is done executing.
 func InitFromConfig(cfg Config) (io.Closer, error) {
+	sampler, err := NewSamplerFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
 	closer, err := InitGlobalTracerWithCloser(TracerConfig{
 		ServiceName:      cfg.Namespace,
-		SampleRate:       cfg.SampleRate,
+		Sampler:          sampler,
 		MaxRecordTimeout: cfg.RecordTimeout,
 		QueueName:        cfg.QueueName,
+		HTTPReporter:     cfg.HTTPReporter,
+		TraceID128Bit:    cfg.TraceID128Bit,
+		PoolSpans:        cfg.PoolSpans,
 		Logger:           log.ErrorWithSentryWrapper(),
 	})
 	if err != nil {
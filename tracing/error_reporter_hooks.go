@@ -1,11 +1,29 @@
 package tracing
 
+import (
+	sentry "github.com/getsentry/sentry-go"
+)
+
+// SentryTagKeyEndpoint is the sentry tag set by
+// ErrorReporterCreateServerSpanHook to the name of the server span.
+const SentryTagKeyEndpoint = "endpoint"
+
 // ErrorReporterCreateServerSpanHook registers each Server Span with an
 // ErrorReporterSpanHook that will publish errors sent to OnPreStop to Sentry.
+//
+// It also tags the sentry hub of the Server Span with the endpoint name,
+// in addition to the trace id already tagged,
+// so that all errors reported via the hub
+// (including the ones reported by log.ErrorWithSentry with the request context)
+// can be correlated with the request.
 type ErrorReporterCreateServerSpanHook struct{}
 
 // OnCreateServerSpan registers SentrySpanHook on a Server Span.
 func (h ErrorReporterCreateServerSpanHook) OnCreateServerSpan(span *Span) error {
+	span.getHub().ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag(SentryTagKeyEndpoint, span.Name())
+		scope.SetTransaction(span.Name())
+	})
 	span.AddHooks(errorReporterSpanHook{})
 	return nil
 }
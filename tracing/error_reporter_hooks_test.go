@@ -0,0 +1,39 @@
+package tracing_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	sentry "github.com/getsentry/sentry-go"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func TestErrorReporterCreateServerSpanHook(t *testing.T) {
+	defer tracing.ResetHooks()
+	tracing.RegisterCreateServerSpanHooks(tracing.ErrorReporterCreateServerSpanHook{})
+
+	ctx, span := tracing.StartSpanFromHeaders(context.Background(), "endpoint", tracing.Headers{})
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		t.Fatal("Expected sentry hub injected into the context object")
+	}
+	event := hub.Scope().ApplyToEvent(sentry.NewEvent(), nil)
+	expected := map[string]string{
+		"trace_id":                   strconv.FormatUint(span.TraceID(), 10),
+		tracing.SentryTagKeyEndpoint: "endpoint",
+	}
+	for k, v := range expected {
+		if event.Tags[k] != v {
+			t.Errorf("Expected sentry tag %q to be %q, got %q", k, v, event.Tags[k])
+		}
+	}
+	if event.Transaction != "endpoint" {
+		t.Errorf("Expected sentry transaction to be %q, got %q", "endpoint", event.Transaction)
+	}
+
+	if sentry.CurrentHub().Scope() == hub.Scope() {
+		t.Error("Expected the hub of the server span to be a clone of the global hub")
+	}
+}
@@ -1,5 +1,12 @@
 package tracing
 
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/metrics"
+)
+
 // CreateServerSpanHook allows you to inject functionality into the lifecycle of a
 // Baseplate request.
 type CreateServerSpanHook interface {
@@ -53,6 +60,34 @@ var (
 	createServerSpanHooks []CreateServerSpanHook
 )
 
+// hookFailures holds the counter set by SetHookFailuresCounter,
+// as a counterValue.
+var hookFailures atomic.Value
+
+// counterValue wraps a metrics.Counter to be stored in an atomic.Value,
+// which can't store nil.
+type counterValue struct {
+	metrics.Counter
+}
+
+// SetHookFailuresCounter sets the counter incremented every time a hook
+// returns an error or panics,
+// with the "hook" label set to the type of the hook (e.g. "*mypkg.myHook"),
+// and the "method" label set to the hook method (e.g. "OnPreStop").
+// A nil counter stops the counting.
+//
+// Hooks can never fail the requests:
+// the errors returned and the panics happened in the hooks are logged with the
+// tracer's logger, counted by the counter, and otherwise ignored,
+// and the remaining hooks are still called.
+//
+// metricsbp.InitFromConfig sets it to the "tracing.hook.failures" counter.
+//
+// It's safe to be called concurrently with the spans being created.
+func SetHookFailuresCounter(counter metrics.Counter) {
+	hookFailures.Store(counterValue{counter})
+}
+
 // IsSpanHook returns true if hook implements at least one of the span Hook
 // interfaces and false if it implements none.
 func IsSpanHook(hook interface{}) bool {
@@ -99,8 +134,73 @@ func onCreateServerSpan(span *Span) {
 	}
 
 	for _, hook := range createServerSpanHooks {
-		if err := hook.OnCreateServerSpan(span); err != nil {
-			span.logError("OnCreateServerSpan hook error: ", err)
-		}
+		callOnCreateServerSpan(hook, span)
+	}
+}
+
+// hookFailed logs and counts the error returned by the hook.
+func hookFailed(span *Span, method string, hook interface{}, err error) {
+	span.logError(method+" hook error: ", err)
+	if counter, _ := hookFailures.Load().(counterValue); counter.Counter != nil {
+		counter.With(
+			"hook", fmt.Sprintf("%T", hook),
+			"method", method,
+		).Add(1)
+	}
+}
+
+// recoverHook is deferred by the call* functions below to contain the panics
+// happened in the hooks.
+func recoverHook(span *Span, method string, hook interface{}) {
+	if r := recover(); r != nil {
+		hookFailed(span, method, hook, fmt.Errorf("panic: %v", r))
+	}
+}
+
+func callOnCreateServerSpan(hook CreateServerSpanHook, span *Span) {
+	const method = "OnCreateServerSpan"
+	defer recoverHook(span, method, hook)
+	if err := hook.OnCreateServerSpan(span); err != nil {
+		hookFailed(span, method, hook, err)
+	}
+}
+
+func callOnCreateChild(hook CreateChildSpanHook, parent, child *Span) {
+	const method = "OnCreateChild"
+	defer recoverHook(parent, method, hook)
+	if err := hook.OnCreateChild(parent, child); err != nil {
+		hookFailed(parent, method, hook, err)
+	}
+}
+
+func callOnPostStart(hook StartStopSpanHook, span *Span) {
+	const method = "OnPostStart"
+	defer recoverHook(span, method, hook)
+	if err := hook.OnPostStart(span); err != nil {
+		hookFailed(span, method, hook, err)
+	}
+}
+
+func callOnPreStop(hook StartStopSpanHook, span *Span, spanErr error) {
+	const method = "OnPreStop"
+	defer recoverHook(span, method, hook)
+	if err := hook.OnPreStop(span, spanErr); err != nil {
+		hookFailed(span, method, hook, err)
+	}
+}
+
+func callOnSetTag(hook SetSpanTagHook, span *Span, key string, value interface{}) {
+	const method = "OnSetTag"
+	defer recoverHook(span, method, hook)
+	if err := hook.OnSetTag(span, key, value); err != nil {
+		hookFailed(span, method, hook, err)
+	}
+}
+
+func callOnAddCounter(hook AddSpanCounterHook, span *Span, key string, delta float64) {
+	const method = "OnAddCounter"
+	defer recoverHook(span, method, hook)
+	if err := hook.OnAddCounter(span, key, delta); err != nil {
+		hookFailed(span, method, hook, err)
 	}
 }
@@ -8,6 +8,7 @@ import (
 
 	opentracing "github.com/opentracing/opentracing-go"
 
+	"github.com/reddit/baseplate.go/metricsbp/metricstest"
 	"github.com/reddit/baseplate.go/thriftbp"
 	"github.com/reddit/baseplate.go/tracing"
 )
@@ -102,6 +103,7 @@ func TestHooks(t *testing.T) {
 }
 
 func TestHookFailures(t *testing.T) {
+	recorder := replaceHookFailures(t)
 	hook := TestCreateServerSpanHook{
 		Calls: &CallContainer{},
 		Fail:  true,
@@ -129,4 +131,115 @@ func TestHookFailures(t *testing.T) {
 	if !reflect.DeepEqual(hook.Calls.Calls, expected) {
 		t.Fatalf("Expected %v:\nGot: %v", expected, hook.Calls.Calls)
 	}
+	recorder.AssertCounterEquals(
+		t,
+		"tracing.hook.failures,hook=tracing_test.TestCreateServerSpanHook,method=OnCreateServerSpan",
+		1,
+	)
+	recorder.AssertCounterEquals(
+		t,
+		"tracing.hook.failures,hook=tracing_test.TestSpanHook,method=OnPreStop",
+		1,
+	)
+}
+
+type panicCreateServerSpanHook struct {
+	Calls *CallContainer
+}
+
+func (h panicCreateServerSpanHook) OnCreateServerSpan(span *tracing.Span) error {
+	span.AddHooks(panicSpanHook{}, TestSpanHook{Calls: h.Calls})
+	panic("on-server-span-create")
+}
+
+type panicSpanHook struct{}
+
+func (panicSpanHook) OnCreateChild(parent, child *tracing.Span) error {
+	panic("on-create-child")
+}
+
+func (panicSpanHook) OnPostStart(span *tracing.Span) error {
+	panic("on-start")
+}
+
+func (panicSpanHook) OnPreStop(span *tracing.Span, err error) error {
+	panic("on-end")
+}
+
+func (panicSpanHook) OnSetTag(span *tracing.Span, key string, value interface{}) error {
+	panic("on-set-tag")
+}
+
+func (panicSpanHook) OnAddCounter(span *tracing.Span, key string, delta float64) error {
+	panic("on-add-counter")
+}
+
+func replaceHookFailures(t *testing.T) *metricstest.Recorder {
+	t.Helper()
+
+	recorder := metricstest.New()
+	tracing.SetHookFailuresCounter(recorder.Statsd.Counter("tracing.hook.failures"))
+	t.Cleanup(func() {
+		tracing.SetHookFailuresCounter(nil)
+	})
+	return recorder
+}
+
+func TestHookPanics(t *testing.T) {
+	recorder := replaceHookFailures(t)
+	calls := &CallContainer{}
+	tracing.RegisterCreateServerSpanHooks(
+		panicCreateServerSpanHook{Calls: calls},
+		TestCreateServerSpanHook{Calls: calls},
+	)
+	defer tracing.ResetHooks()
+
+	ctx, span := thriftbp.StartSpanFromThriftContext(context.Background(), "foo")
+	span.SetTag("foo", "bar")
+	opentracing.StartSpanFromContext(
+		ctx,
+		"bar",
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	span.AddCounter("foo", 1)
+	if err := span.Stop(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The hooks after the panicking ones should still be called.
+	expected := []string{
+		"on-server-span-create",
+		"on-start",
+		"on-start",
+		"on-set-tag",
+		"on-set-tag",
+		"on-create-child",
+		"on-create-child",
+		"on-add-counter",
+		"on-add-counter",
+		"on-end",
+		"on-end",
+	}
+	if !reflect.DeepEqual(calls.Calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, calls.Calls)
+	}
+
+	recorder.AssertCounterEquals(
+		t,
+		"tracing.hook.failures,hook=tracing_test.panicCreateServerSpanHook,method=OnCreateServerSpan",
+		1,
+	)
+	for _, method := range []string{
+		"OnCreateChild",
+		"OnPostStart",
+		"OnPreStop",
+		"OnSetTag",
+		"OnAddCounter",
+	} {
+		recorder.AssertCounterEquals(
+			t,
+			"tracing.hook.failures,hook=tracing_test.panicSpanHook,method="+method,
+			1,
+		)
+	}
 }
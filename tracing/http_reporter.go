@@ -0,0 +1,348 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+// Default values used by HTTPReporter when the corresponding values in
+// HTTPReporterConfig are not set.
+const (
+	DefaultHTTPReporterBatchSize     = 100
+	DefaultHTTPReporterBatchInterval = time.Second
+	DefaultHTTPReporterTimeout       = time.Second * 5
+)
+
+// Errors returned by HTTPReporter.Send.
+var (
+	ErrHTTPReporterQueueFull = errors.New("tracing: http reporter queue is full")
+	ErrHTTPReporterClosed    = errors.New("tracing: http reporter is closed")
+)
+
+// HTTPReporterConfig is the configuration used by NewHTTPReporter.
+//
+// Other than Client, Logger and LocalEndpoint, it can be deserialized from
+// YAML.
+type HTTPReporterConfig struct {
+	// Endpoint is the URL of the Zipkin V2 span collector,
+	// e.g. "http://zipkin:9411/api/v2/spans".
+	Endpoint string `yaml:"endpoint"`
+
+	// MaxQueueSize is the max number of spans buffered in memory waiting to be
+	// sent. Spans reported when the buffer is full are dropped.
+	//
+	// When MaxQueueSize <= 0, MaxQueueSize in this package will be used.
+	MaxQueueSize int `yaml:"maxQueueSize"`
+
+	// BatchSize is the max number of spans sent in a single request.
+	//
+	// When BatchSize <= 0, DefaultHTTPReporterBatchSize will be used.
+	BatchSize int `yaml:"batchSize"`
+
+	// BatchInterval is the max amount of time a span is buffered before being
+	// sent, when the batch is not full.
+	//
+	// When BatchInterval <= 0, DefaultHTTPReporterBatchInterval will be used.
+	BatchInterval time.Duration `yaml:"batchInterval"`
+
+	// Timeout is the timeout of every request sent to the collector.
+	//
+	// When Timeout <= 0, DefaultHTTPReporterTimeout will be used.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Retry controls how failed requests are retried.
+	//
+	// Batches still failing after the retries are dropped.
+	// The zero value means no retries.
+	Retry retrybp.Config `yaml:"retry"`
+
+	// Client is the http client used to send requests to the collector.
+	//
+	// Optional, defaults to http.DefaultClient.
+	Client *http.Client `yaml:"-"`
+
+	// Logger, if non-nil, will be used to log dropped batches.
+	Logger log.Wrapper `yaml:"-"`
+
+	// LocalEndpoint is the local endpoint of the spans without any
+	// annotations.
+	//
+	// InitGlobalTracer sets it to the endpoint of the Tracer when it's empty.
+	LocalEndpoint ZipkinEndpointInfo `yaml:"-"`
+}
+
+// HTTPReporter batches finished spans and sends them to a Zipkin V2 collector
+// via HTTP, as an alternative to the trace publishing sidecar.
+//
+// It implements mqsend.MessageQueue and SpanRecorder so it can be used as the
+// message queue of the Tracer, see TracerConfig.HTTPReporter.
+type HTTPReporter struct {
+	// Accessed atomically, keep them as the first fields for 64-bit alignment.
+	droppedSpans uint64
+	retries      uint64
+
+	cfg    HTTPReporterConfig
+	client *http.Client
+	logger log.Wrapper
+
+	spans   chan zipkinV2Span
+	flushes chan chan struct{}
+	done    chan struct{}
+
+	// stop is closed by Close with lock held,
+	// so no spans are put into spans after the background goroutine drained it.
+	lock   sync.RWMutex
+	closed bool
+	stop   chan struct{}
+}
+
+var (
+	_ mqsend.MessageQueue = (*HTTPReporter)(nil)
+	_ SpanRecorder        = (*HTTPReporter)(nil)
+)
+
+// NewHTTPReporter creates a new HTTPReporter and starts its background
+// goroutine sending batches to the collector.
+func NewHTTPReporter(cfg HTTPReporterConfig) (*HTTPReporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("tracing: http reporter endpoint is required")
+	}
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = MaxQueueSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultHTTPReporterBatchSize
+	}
+	if cfg.BatchInterval <= 0 {
+		cfg.BatchInterval = DefaultHTTPReporterBatchInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultHTTPReporterTimeout
+	}
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r := &HTTPReporter{
+		cfg:     cfg,
+		client:  client,
+		logger:  log.FallbackWrapper(cfg.Logger),
+		spans:   make(chan zipkinV2Span, cfg.MaxQueueSize),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Send implements mqsend.MessageQueue.
+//
+// data should be a json encoded ZipkinSpan, it's decoded and passed to
+// RecordSpan.
+// The Tracer calls RecordSpan directly instead.
+func (r *HTTPReporter) Send(ctx context.Context, data []byte) error {
+	var zs ZipkinSpan
+	if err := json.Unmarshal(data, &zs); err != nil {
+		return err
+	}
+	return r.RecordSpan(ctx, zs)
+}
+
+// RecordSpan implements SpanRecorder.
+//
+// It never blocks, when the queue is full the span is dropped and
+// ErrHTTPReporterQueueFull is returned.
+// After Close, the span is dropped and ErrHTTPReporterClosed is returned.
+func (r *HTTPReporter) RecordSpan(ctx context.Context, zs ZipkinSpan) error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.closed {
+		atomic.AddUint64(&r.droppedSpans, 1)
+		return ErrHTTPReporterClosed
+	}
+	select {
+	case r.spans <- toZipkinV2Span(zs, r.cfg.LocalEndpoint):
+		return nil
+	default:
+		atomic.AddUint64(&r.droppedSpans, 1)
+		return ErrHTTPReporterQueueFull
+	}
+}
+
+// Flush blocks until all the spans buffered before the call are sent to the
+// collector (or dropped after failures), or ctx is done,
+// whichever comes first.
+func (r *HTTPReporter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case r.flushes <- flushed:
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the background goroutine after sending out all the buffered
+// spans.
+func (r *HTTPReporter) Close() error {
+	r.lock.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.stop)
+	}
+	r.lock.Unlock()
+	<-r.done
+	return nil
+}
+
+// DroppedSpans returns the total number of spans dropped by the reporter,
+// either because the queue is full, the collector keeps failing,
+// or the reporter is closed.
+func (r *HTTPReporter) DroppedSpans() uint64 {
+	return atomic.LoadUint64(&r.droppedSpans)
+}
+
+// Retries returns the total number of retried requests to the collector.
+func (r *HTTPReporter) Retries() uint64 {
+	return atomic.LoadUint64(&r.retries)
+}
+
+func (r *HTTPReporter) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.BatchInterval)
+	defer ticker.Stop()
+
+	batch := make([]zipkinV2Span, 0, r.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			r.sendBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-r.spans:
+				batch = append(batch, span)
+				if len(batch) >= r.cfg.BatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case <-r.stop:
+			drain()
+			return
+		case flushed := <-r.flushes:
+			drain()
+			close(flushed)
+		case span := <-r.spans:
+			batch = append(batch, span)
+			if len(batch) >= r.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// httpReporterStatusError is the error used when the collector returns a non
+// 2xx response.
+type httpReporterStatusError struct {
+	code int
+}
+
+func (e httpReporterStatusError) Error() string {
+	return fmt.Sprintf("tracing: collector returned http status %d", e.code)
+}
+
+// retryable returns true on 5xx and 429 responses.
+func (e httpReporterStatusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests
+}
+
+func (r *HTTPReporter) sendBatch(batch []zipkinV2Span) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		atomic.AddUint64(&r.droppedSpans, uint64(len(batch)))
+		r.logger("tracing: failed to encode spans: " + err.Error())
+		return
+	}
+
+	retryCfg := r.cfg.Retry
+	retryCfg.Classifier = func(err error) bool {
+		var statusErr httpReporterStatusError
+		if errors.As(err, &statusErr) {
+			return statusErr.retryable()
+		}
+		return retrybp.DefaultClassifier(err)
+	}
+	var attempts int
+	err = retrybp.Do(context.Background(), retryCfg, func(ctx context.Context) error {
+		attempts++
+		if attempts > 1 {
+			atomic.AddUint64(&r.retries, 1)
+		}
+		return r.post(ctx, body)
+	})
+	if err != nil {
+		atomic.AddUint64(&r.droppedSpans, uint64(len(batch)))
+		r.logger(fmt.Sprintf(
+			"tracing: dropped %d spans after %d attempts: %v",
+			len(batch),
+			attempts,
+			err,
+		))
+	}
+}
+
+func (r *HTTPReporter) post(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, r.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return httpReporterStatusError{code: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+type collector struct {
+	// If non-nil, requests are blocked until it's closed.
+	release chan struct{}
+
+	lock     sync.Mutex
+	failures int
+	requests int
+	spans    []map[string]interface{}
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.release != nil {
+		<-c.release
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.requests++
+	if c.failures > 0 {
+		c.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var spans []map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&spans); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.spans = append(c.spans, spans...)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *collector) snapshot() (requests int, spans []map[string]interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.requests, append([]map[string]interface{}(nil), c.spans...)
+}
+
+func TestHTTPReporterTracer(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	err := tracing.InitGlobalTracer(tracing.TracerConfig{
+		ServiceName: "test-service",
+		SampleRate:  1,
+		HTTPReporter: tracing.HTTPReporterConfig{
+			Endpoint: server.URL,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracing.InitGlobalTracer(tracing.TracerConfig{})
+
+	span := opentracing.StartSpan(
+		"server",
+		tracing.SpanTypeOption{Type: tracing.SpanTypeServer},
+	)
+	child := opentracing.StartSpan(
+		"client",
+		opentracing.ChildOf(span.Context()),
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	child.SetTag("key", 1)
+	child.FinishWithOptions(tracing.FinishOptions{
+		Err: errors.New("test error"),
+	}.Convert())
+	span.Finish()
+
+	if err := tracing.CloseTracer(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, spans := c.snapshot()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans reported, got %+v", spans)
+	}
+	clientSpan, serverSpan := spans[0], spans[1]
+	if clientSpan["name"] != "client" || serverSpan["name"] != "server" {
+		t.Fatalf("Unexpected spans: %+v", spans)
+	}
+	if clientSpan["kind"] != "CLIENT" || serverSpan["kind"] != "SERVER" {
+		t.Errorf("Unexpected span kinds: %v, %v", clientSpan["kind"], serverSpan["kind"])
+	}
+	if clientSpan["traceId"] != serverSpan["traceId"] {
+		t.Errorf("Expected same trace id, got %v and %v", clientSpan["traceId"], serverSpan["traceId"])
+	}
+	if clientSpan["parentId"] != serverSpan["id"] {
+		t.Errorf("Expected client parent id to be %v, got %v", serverSpan["id"], clientSpan["parentId"])
+	}
+	if id, _ := serverSpan["id"].(string); len(id) != 16 {
+		t.Errorf("Expected 16 hex digits span id, got %q", id)
+	}
+	if _, ok := serverSpan["parentId"]; ok {
+		t.Errorf("Expected no parent id on root span, got %v", serverSpan["parentId"])
+	}
+	tags, _ := clientSpan["tags"].(map[string]interface{})
+	if tags["key"] != "1" || tags["error"] != "true" {
+		t.Errorf("Unexpected client span tags: %v", tags)
+	}
+	endpoint, _ := clientSpan["localEndpoint"].(map[string]interface{})
+	if endpoint["serviceName"] != "test-service" {
+		t.Errorf("Unexpected local endpoint: %v", endpoint)
+	}
+}
+
+func sendSpan(t *testing.T, reporter *tracing.HTTPReporter) error {
+	t.Helper()
+
+	data, err := json.Marshal(tracing.ZipkinSpan{
+		TraceID: 1,
+		SpanID:  2,
+		Name:    "span",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reporter.Send(context.Background(), data)
+}
+
+func TestHTTPReporterRetry(t *testing.T) {
+	c := &collector{failures: 1}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	reporter, err := tracing.NewHTTPReporter(tracing.HTTPReporterConfig{
+		Endpoint: server.URL,
+		Retry: retrybp.Config{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sendSpan(t, reporter); err != nil {
+		t.Fatal(err)
+	}
+	reporter.Close()
+
+	requests, spans := c.snapshot()
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	if len(spans) != 1 {
+		t.Errorf("Expected 1 span reported, got %+v", spans)
+	}
+	if reporter.Retries() != 1 {
+		t.Errorf("Expected 1 retry, got %d", reporter.Retries())
+	}
+	if reporter.DroppedSpans() != 0 {
+		t.Errorf("Expected no dropped spans, got %d", reporter.DroppedSpans())
+	}
+}
+
+func TestHTTPReporterDrop(t *testing.T) {
+	c := &collector{
+		release:  make(chan struct{}),
+		failures: 10,
+	}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	reporter, err := tracing.NewHTTPReporter(tracing.HTTPReporterConfig{
+		Endpoint:     server.URL,
+		MaxQueueSize: 1,
+		BatchSize:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent int
+	t.Run("queue-full", func(t *testing.T) {
+		// The first span might be taken off the queue and blocked in the request
+		// to the collector, so the queue is full on either the 2nd or 3rd span.
+		var err error
+		for sent < 3 && err == nil {
+			err = sendSpan(t, reporter)
+			sent++
+		}
+		if !errors.Is(err, tracing.ErrHTTPReporterQueueFull) {
+			t.Errorf("Expected ErrHTTPReporterQueueFull, got %v", err)
+		}
+	})
+
+	close(c.release)
+	reporter.Close()
+
+	t.Run("closed", func(t *testing.T) {
+		if err := sendSpan(t, reporter); !errors.Is(err, tracing.ErrHTTPReporterClosed) {
+			t.Errorf("Expected ErrHTTPReporterClosed, got %v", err)
+		}
+	})
+
+	t.Run("collector-failure", func(t *testing.T) {
+		// Every span is either dropped because of the full queue,
+		// or because the request to the collector failed without retries,
+		// plus the one sent after Close.
+		requests, spans := c.snapshot()
+		if requests != sent-1 {
+			t.Errorf("Expected %d requests without retries, got %d", sent-1, requests)
+		}
+		if len(spans) != 0 {
+			t.Errorf("Expected no spans reported, got %+v", spans)
+		}
+		if dropped := reporter.DroppedSpans(); dropped != uint64(sent+1) {
+			t.Errorf("Expected %d dropped spans, got %d", sent+1, dropped)
+		}
+	})
+}
+
+func TestHTTPReporterFlush(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	reporter, err := tracing.NewHTTPReporter(tracing.HTTPReporterConfig{
+		Endpoint:      server.URL,
+		BatchInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reporter.Close()
+
+	if err := sendSpan(t, reporter); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := reporter.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, spans := c.snapshot(); len(spans) != 1 {
+		t.Errorf("Expected 1 span sent after Flush, got %+v", spans)
+	}
+}
+
+func TestHTTPReporterLocalEndpoint(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	reporter, err := tracing.NewHTTPReporter(tracing.HTTPReporterConfig{
+		Endpoint: server.URL,
+		LocalEndpoint: tracing.ZipkinEndpointInfo{
+			ServiceName: "test-service",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The span has no annotations to take the endpoint from.
+	if err := reporter.RecordSpan(context.Background(), tracing.ZipkinSpan{
+		TraceID: 1,
+		SpanID:  2,
+		Name:    "span",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reporter.Close()
+
+	_, spans := c.snapshot()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span reported, got %+v", spans)
+	}
+	endpoint, _ := spans[0]["localEndpoint"].(map[string]interface{})
+	if endpoint["serviceName"] != "test-service" {
+		t.Errorf("Unexpected local endpoint: %v", endpoint)
+	}
+}
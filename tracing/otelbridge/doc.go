@@ -0,0 +1,28 @@
+// Package otelbridge converts the spans recorded by the baseplate tracer into
+// OpenTelemetry spans.
+//
+// It allows services instrumented with baseplate's tracing package to publish
+// their spans through an OpenTelemetry exporter,
+// so the tracing backend can be migrated without rewriting the
+// instrumentations.
+//
+// A typical setup looks like:
+//
+//     processor, err := sdktrace.NewBatchSpanProcessor(exporter)
+//     if err != nil {
+//       log.Fatal(err)
+//     }
+//     tracing.InitGlobalTracer(tracing.TracerConfig{
+//       ServiceName:  "my-service",
+//       SampleRate:   0.01,
+//       MessageQueue: otelbridge.NewExporter(processor),
+//     })
+//     defer tracing.CloseTracer()
+//
+// Where sdktrace is go.opentelemetry.io/otel/sdk/trace,
+// and exporter is an OpenTelemetry export.SpanBatcher,
+// for example the one from go.opentelemetry.io/otel/exporters/otlp.
+// The batch span processor exports the spans in the background,
+// and closing the tracer shuts it down and exports the queued spans,
+// see Exporter.Close for more details.
+package otelbridge
@@ -0,0 +1,160 @@
+package otelbridge
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/standard"
+	apitrace "go.opentelemetry.io/otel/api/trace"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+
+	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Exporter publishes the spans recorded by the baseplate tracer through an
+// OpenTelemetry exporter.
+//
+// It implements mqsend.MessageQueue and tracing.SpanRecorder so it can be used
+// as the message queue of the tracer, see tracing.TracerConfig.MessageQueue.
+type Exporter struct {
+	processor sdktrace.SpanProcessor
+}
+
+var (
+	_ mqsend.MessageQueue  = (*Exporter)(nil)
+	_ tracing.SpanRecorder = (*Exporter)(nil)
+)
+
+// NewExporter creates an Exporter publishing the spans through processor.
+//
+// processor should usually be an sdktrace.BatchSpanProcessor,
+// so the spans are exported asynchronously in batches instead of blocking the
+// requests finishing them.
+func NewExporter(processor sdktrace.SpanProcessor) *Exporter {
+	return &Exporter{
+		processor: processor,
+	}
+}
+
+// Send implements mqsend.MessageQueue.
+//
+// data should be a json encoded tracing.ZipkinSpan,
+// it's decoded and passed to RecordSpan.
+// The tracer calls RecordSpan directly instead.
+func (e *Exporter) Send(ctx context.Context, data []byte) error {
+	var zs tracing.ZipkinSpan
+	if err := json.Unmarshal(data, &zs); err != nil {
+		return fmt.Errorf("otelbridge: failed to decode span: %w", err)
+	}
+	return e.RecordSpan(ctx, zs)
+}
+
+// RecordSpan implements tracing.SpanRecorder.
+//
+// The span is converted by SpanData and then passed to the OnEnd of the span
+// processor.
+func (e *Exporter) RecordSpan(ctx context.Context, zs tracing.ZipkinSpan) error {
+	e.processor.OnEnd(SpanData(zs))
+	return nil
+}
+
+// Close implements mqsend.MessageQueue.
+//
+// It shuts down the span processor,
+// which exports the spans still queued in it.
+// Note that sdktrace.BatchSpanProcessor exports the final batch in the
+// background without waiting for it in Shutdown.
+// The OpenTelemetry exporter should be shut down separately after that.
+func (e *Exporter) Close() error {
+	e.processor.Shutdown()
+	return nil
+}
+
+// SpanData converts a baseplate span into an OpenTelemetry span.
+//
+// The mappings are:
+//
+// - The trace, span and parent ids are converted as-is,
+// with 64-bit trace ids left padded with zeros and 128-bit trace ids using
+// TraceIDHigh as the higher 64 bits.
+//
+// - The well-known time annotations ("cs", "cr", "sr", "ss") are converted to
+// the kind of the span, other time annotations are converted to events.
+//
+// - The "error" binary annotation is converted to the status of the span,
+// other binary annotations (tags) are converted to attributes.
+//
+// - The service name of the endpoint is converted to the "service.name"
+// resource, and the ip of the endpoint to the "net.host.ip" attribute.
+func SpanData(zs tracing.ZipkinSpan) *export.SpanData {
+	start := zs.Start.ToTime()
+	sd := &export.SpanData{
+		SpanContext: apitrace.SpanContext{
+			TraceID:    traceID(zs.TraceIDHigh, zs.TraceID),
+			SpanID:     spanID(zs.SpanID),
+			TraceFlags: apitrace.FlagsSampled,
+		},
+		SpanKind:  apitrace.SpanKindInternal,
+		Name:      zs.Name,
+		StartTime: start,
+		EndTime:   start.Add(zs.Duration.ToDuration()),
+	}
+	if zs.ParentID != 0 {
+		sd.ParentSpanID = spanID(zs.ParentID)
+	}
+
+	var endpoint tracing.ZipkinEndpointInfo
+	for _, a := range zs.TimeAnnotations {
+		endpoint = a.Endpoint
+		switch a.Key {
+		case tracing.ZipkinTimeAnnotationKeyClientSend, tracing.ZipkinTimeAnnotationKeyClientReceive:
+			sd.SpanKind = apitrace.SpanKindClient
+		case tracing.ZipkinTimeAnnotationKeyServerReceive, tracing.ZipkinTimeAnnotationKeyServerSend:
+			sd.SpanKind = apitrace.SpanKindServer
+		default:
+			sd.MessageEvents = append(sd.MessageEvents, export.Event{
+				Name: a.Key,
+				Time: a.Timestamp.ToTime(),
+			})
+		}
+	}
+	// Server spans with a parent are always continuing a trace from upstream.
+	sd.HasRemoteParent = sd.SpanKind == apitrace.SpanKindServer && zs.ParentID != 0
+
+	for _, a := range zs.BinaryAnnotations {
+		endpoint = a.Endpoint
+		if a.Key == tracing.ZipkinBinaryAnnotationKeyError {
+			if fmt.Sprint(a.Value) == "true" {
+				sd.StatusCode = codes.Unknown
+			}
+			continue
+		}
+		sd.Attributes = append(sd.Attributes, kv.Infer(a.Key, a.Value))
+	}
+
+	if endpoint.IPv4 != "" {
+		sd.Attributes = append(sd.Attributes, standard.NetHostIPKey.String(endpoint.IPv4))
+	}
+	if endpoint.ServiceName != "" {
+		sd.Resource = resource.New(standard.ServiceNameKey.String(endpoint.ServiceName))
+	}
+	return sd
+}
+
+func traceID(high, low uint64) (traceID apitrace.ID) {
+	binary.BigEndian.PutUint64(traceID[:8], high)
+	binary.BigEndian.PutUint64(traceID[8:], low)
+	return
+}
+
+func spanID(id uint64) (spanID apitrace.SpanID) {
+	binary.BigEndian.PutUint64(spanID[:], id)
+	return
+}
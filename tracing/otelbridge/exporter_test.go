@@ -0,0 +1,247 @@
+package otelbridge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/kv/value"
+	"go.opentelemetry.io/otel/api/standard"
+	apitrace "go.opentelemetry.io/otel/api/trace"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/otelbridge"
+)
+
+// batcher records the exported spans,
+// after waiting for release to be closed when it's not nil.
+type batcher struct {
+	release chan struct{}
+
+	lock  sync.Mutex
+	spans []*export.SpanData
+}
+
+func (b *batcher) ExportSpans(_ context.Context, spans []*export.SpanData) {
+	if b.release != nil {
+		<-b.release
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.spans = append(b.spans, spans...)
+}
+
+// exported returns the exported spans,
+// after waiting up to a second for n spans to be exported,
+// as the batch span processor exports them in the background.
+func (b *batcher) exported(n int) []*export.SpanData {
+	deadline := time.Now().Add(time.Second)
+	for {
+		b.lock.Lock()
+		spans := append([]*export.SpanData(nil), b.spans...)
+		b.lock.Unlock()
+		if len(spans) >= n || time.Now().After(deadline) {
+			return spans
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newExporter(t *testing.T, b *batcher) *otelbridge.Exporter {
+	t.Helper()
+	processor, err := sdktrace.NewBatchSpanProcessor(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return otelbridge.NewExporter(processor)
+}
+
+func findAttribute(sd *export.SpanData, key kv.Key) (value.Value, bool) {
+	for _, attr := range sd.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return value.Value{}, false
+}
+
+func TestExporter(t *testing.T) {
+	b := new(batcher)
+	if err := tracing.InitGlobalTracer(tracing.TracerConfig{
+		ServiceName:  "test-service",
+		SampleRate:   1,
+		MessageQueue: newExporter(t, b),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sampled := true
+	ctx, server := tracing.StartSpanFromHeaders(
+		context.Background(),
+		"server",
+		tracing.Headers{
+			TraceID: "12345",
+			SpanID:  "54321",
+			Sampled: &sampled,
+		},
+	)
+	server.SetTag("foo", "bar")
+	server.AddAnnotation(time.Now(), "event")
+	client, _ := opentracing.StartSpanFromContext(
+		ctx,
+		"client",
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	if err := tracing.AsSpan(client).Stop(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Stop(ctx, errors.New("error")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing the tracer exports the spans queued in the batch span processor.
+	if err := tracing.CloseTracer(); err != nil {
+		t.Fatal(err)
+	}
+	spans := b.exported(2)
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans exported, got %d", len(spans))
+	}
+	clientData, serverData := spans[0], spans[1]
+
+	const traceID = "00000000000000000000000000003039"
+	if got := serverData.SpanContext.TraceID.String(); got != traceID {
+		t.Errorf("Expected trace id %q, got %q", traceID, got)
+	}
+	if clientData.SpanContext.TraceID != serverData.SpanContext.TraceID {
+		t.Errorf(
+			"Expected the same trace id, got %v and %v",
+			clientData.SpanContext.TraceID,
+			serverData.SpanContext.TraceID,
+		)
+	}
+	const parentID = "000000000000d431"
+	if got := serverData.ParentSpanID.String(); got != parentID {
+		t.Errorf("Expected parent id %q, got %q", parentID, got)
+	}
+	if clientData.ParentSpanID != serverData.SpanContext.SpanID {
+		t.Errorf(
+			"Expected the server span %v to be the parent of client span, got %v",
+			serverData.SpanContext.SpanID,
+			clientData.ParentSpanID,
+		)
+	}
+
+	if serverData.Name != "server" {
+		t.Errorf("Expected name %q, got %q", "server", serverData.Name)
+	}
+	if serverData.SpanKind != apitrace.SpanKindServer {
+		t.Errorf("Expected server kind, got %v", serverData.SpanKind)
+	}
+	if !serverData.HasRemoteParent {
+		t.Error("Expected server span to have remote parent")
+	}
+	if clientData.SpanKind != apitrace.SpanKindClient {
+		t.Errorf("Expected client kind, got %v", clientData.SpanKind)
+	}
+	if clientData.HasRemoteParent {
+		t.Error("Expected client span to not have remote parent")
+	}
+
+	if serverData.StatusCode != codes.Unknown {
+		t.Errorf("Expected server span status %v, got %v", codes.Unknown, serverData.StatusCode)
+	}
+	if clientData.StatusCode != codes.OK {
+		t.Errorf("Expected client span status %v, got %v", codes.OK, clientData.StatusCode)
+	}
+	if _, ok := findAttribute(serverData, tracing.ZipkinBinaryAnnotationKeyError); ok {
+		t.Error("Expected error tag to be converted to status, got attribute")
+	}
+	if v, ok := findAttribute(serverData, "foo"); !ok || v.AsString() != "bar" {
+		t.Errorf("Expected attribute foo=bar, got %v, %v", v.Emit(), ok)
+	}
+
+	if len(serverData.MessageEvents) != 1 || serverData.MessageEvents[0].Name != "event" {
+		t.Errorf("Expected 1 event named %q, got %+v", "event", serverData.MessageEvents)
+	}
+	if !serverData.EndTime.After(serverData.StartTime) {
+		t.Errorf(
+			"Expected end time %v after start time %v",
+			serverData.EndTime,
+			serverData.StartTime,
+		)
+	}
+
+	if v, ok := serverData.Resource.LabelSet().Value(standard.ServiceNameKey); !ok || v.AsString() != "test-service" {
+		t.Errorf("Expected service name resource %q, got %v, %v", "test-service", v.Emit(), ok)
+	}
+}
+
+func TestExporterAsync(t *testing.T) {
+	b := &batcher{release: make(chan struct{})}
+	exporter := newExporter(t, b)
+
+	// RecordSpan doesn't wait for the blocked exporter.
+	done := make(chan error)
+	go func() {
+		done <- exporter.RecordSpan(context.Background(), tracing.ZipkinSpan{
+			TraceID: 1,
+			SpanID:  2,
+			Name:    "span",
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RecordSpan blocked on the exporter")
+	}
+
+	close(b.release)
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if spans := b.exported(1); len(spans) != 1 || spans[0].Name != "span" {
+		t.Errorf("Expected span %q to be exported on Close, got %+v", "span", spans)
+	}
+}
+
+func TestSpanDataMinimal(t *testing.T) {
+	sd := otelbridge.SpanData(tracing.ZipkinSpan{
+		TraceID: 1,
+		SpanID:  2,
+	})
+	if !sd.SpanContext.IsSampled() {
+		t.Error("Expected span to be sampled")
+	}
+	if !sd.SpanContext.IsValid() {
+		t.Errorf("Expected valid span context, got %+v", sd.SpanContext)
+	}
+	if sd.ParentSpanID.IsValid() {
+		t.Errorf("Expected no parent, got %v", sd.ParentSpanID)
+	}
+	if sd.Resource != nil {
+		t.Errorf("Expected no resource without endpoint, got %v", sd.Resource)
+	}
+}
+
+func TestSpanData128BitTraceID(t *testing.T) {
+	sd := otelbridge.SpanData(tracing.ZipkinSpan{
+		TraceIDHigh: 0x0102030405060708,
+		TraceID:     0x090a0b0c0d0e0f10,
+		SpanID:      1,
+	})
+	const expected = "0102030405060708090a0b0c0d0e0f10"
+	if got := sd.SpanContext.TraceID.String(); got != expected {
+		t.Errorf("Expected trace id %q, got %q", expected, got)
+	}
+}
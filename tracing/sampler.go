@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// Sampler decides whether a new trace should be sampled.
+//
+// It's only used for the root spans created inside this service.
+// For spans with parents (e.g. server spans created from the tracing headers
+// of the client request), the sampling decision is always inherited from the
+// parent, and propagated to the outgoing requests via the tracing headers.
+//
+// Implementations must be safe to be called concurrently.
+type Sampler interface {
+	ShouldSample() bool
+}
+
+// SamplerFunc is a function that implements Sampler.
+type SamplerFunc func() bool
+
+// ShouldSample implements Sampler.
+func (f SamplerFunc) ShouldSample() bool {
+	return f()
+}
+
+// Supported sampler types in Config.
+const (
+	// SamplerTypeProbabilistic samples SampleRate of the new traces.
+	SamplerTypeProbabilistic = "probabilistic"
+
+	// SamplerTypeRateLimited samples at most MaxSampledPerSecond new traces per
+	// second.
+	SamplerTypeRateLimited = "rateLimited"
+)
+
+// ProbabilisticSampler returns a Sampler that samples rate of the new traces.
+//
+// rate should be in the range of [0, 1].
+// When rate <= 0 none of the traces will be sampled;
+// When rate >= 1 all of the traces will be sampled.
+func ProbabilisticSampler(rate float64) Sampler {
+	return SamplerFunc(func() bool {
+		return randbp.ShouldSampleWithRate(rate)
+	})
+}
+
+// RateLimitedSampler is a token bucket based Sampler that samples at most
+// MaxPerSecond new traces per second.
+//
+// Please use NewRateLimitedSampler to create a RateLimitedSampler.
+type RateLimitedSampler struct {
+	lock         sync.Mutex
+	maxPerSecond float64
+	maxTokens    float64
+	tokens       float64
+	last         time.Time
+}
+
+var _ Sampler = (*RateLimitedSampler)(nil)
+
+// NewRateLimitedSampler creates a new RateLimitedSampler.
+//
+// The bucket starts full, and allows bursts of up to maxPerSecond (or 1,
+// whichever is larger) sampled traces.
+// When maxPerSecond <= 0 none of the traces will be sampled.
+func NewRateLimitedSampler(maxPerSecond float64) *RateLimitedSampler {
+	maxTokens := maxPerSecond
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
+	return &RateLimitedSampler{
+		maxPerSecond: maxPerSecond,
+		maxTokens:    maxTokens,
+		tokens:       maxTokens,
+		last:         time.Now(),
+	}
+}
+
+// ShouldSample implements Sampler.
+func (s *RateLimitedSampler) ShouldSample() bool {
+	if s.maxPerSecond <= 0 {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	s.tokens += now.Sub(s.last).Seconds() * s.maxPerSecond
+	if s.tokens > s.maxTokens {
+		s.tokens = s.maxTokens
+	}
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// NewSamplerFromConfig creates the Sampler configured by cfg.
+//
+// It returns an error when cfg.Sampler is not one of the supported sampler
+// types.
+func NewSamplerFromConfig(cfg Config) (Sampler, error) {
+	switch cfg.Sampler {
+	default:
+		return nil, fmt.Errorf("tracing: unsupported sampler type %q", cfg.Sampler)
+	case "", SamplerTypeProbabilistic:
+		return ProbabilisticSampler(cfg.SampleRate), nil
+	case SamplerTypeRateLimited:
+		return NewRateLimitedSampler(cfg.MaxSampledPerSecond), nil
+	}
+}
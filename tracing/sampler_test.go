@@ -0,0 +1,116 @@
+package tracing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func TestProbabilisticSampler(t *testing.T) {
+	const n = 100
+	for _, c := range []struct {
+		rate     float64
+		expected int
+	}{
+		{rate: 0, expected: 0},
+		{rate: 1, expected: n},
+	} {
+		sampler := tracing.ProbabilisticSampler(c.rate)
+		var sampled int
+		for i := 0; i < n; i++ {
+			if sampler.ShouldSample() {
+				sampled++
+			}
+		}
+		if sampled != c.expected {
+			t.Errorf("Expected %d sampled with rate %v, got %d", c.expected, c.rate, sampled)
+		}
+	}
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+	t.Run("burst", func(t *testing.T) {
+		sampler := tracing.NewRateLimitedSampler(5)
+		var sampled int
+		for i := 0; i < 100; i++ {
+			if sampler.ShouldSample() {
+				sampled++
+			}
+		}
+		// Allow an extra one in case the test runs slow.
+		if sampled < 5 || sampled > 6 {
+			t.Errorf("Expected 5 sampled, got %d", sampled)
+		}
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		sampler := tracing.NewRateLimitedSampler(100)
+		for sampler.ShouldSample() {
+		}
+		time.Sleep(time.Millisecond * 50)
+		if !sampler.ShouldSample() {
+			t.Error("Expected the sampler to be refilled")
+		}
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		sampler := tracing.NewRateLimitedSampler(0)
+		if sampler.ShouldSample() {
+			t.Error("Expected zero rate sampler to never sample")
+		}
+	})
+}
+
+func TestNewSamplerFromConfig(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		cfg      tracing.Config
+		err      bool
+		expected bool
+	}{
+		{
+			label:    "default",
+			cfg:      tracing.Config{SampleRate: 1},
+			expected: true,
+		},
+		{
+			label: "probabilistic",
+			cfg: tracing.Config{
+				Sampler:    tracing.SamplerTypeProbabilistic,
+				SampleRate: 0,
+			},
+			expected: false,
+		},
+		{
+			label: "rate-limited",
+			cfg: tracing.Config{
+				Sampler:             tracing.SamplerTypeRateLimited,
+				SampleRate:          0,
+				MaxSampledPerSecond: 1,
+			},
+			expected: true,
+		},
+		{
+			label: "unknown",
+			cfg:   tracing.Config{Sampler: "foo"},
+			err:   true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			sampler, err := tracing.NewSamplerFromConfig(c.cfg)
+			if c.err {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := sampler.ShouldSample(); actual != c.expected {
+				t.Errorf("Expected ShouldSample to return %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
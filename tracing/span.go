@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	sentry "github.com/getsentry/sentry-go"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
+
+	baseplatelog "github.com/reddit/baseplate.go/log"
 )
 
 var (
@@ -78,11 +81,18 @@ func AsSpan(s opentracing.Span) *Span {
 	return newSpan(nil, "", SpanTypeLocal)
 }
 
+// spanPool holds the spans released by Stop when TracerConfig.PoolSpans is
+// true, along with their tag maps and annotation slices.
+var spanPool = sync.Pool{
+	New: func() interface{} {
+		return &Span{trace: new(trace)}
+	},
+}
+
 func newSpan(tracer *Tracer, name string, spanType SpanType) *Span {
-	span := &Span{
-		trace:    newTrace(tracer, name),
-		spanType: spanType,
-	}
+	span := spanPool.Get().(*Span)
+	span.trace.init(tracer, name)
+	span.spanType = spanType
 	switch spanType {
 	case SpanTypeServer:
 		span.trace.timeAnnotationReceiveKey = ZipkinTimeAnnotationKeyServerReceive
@@ -107,9 +117,7 @@ type Span struct {
 func (s *Span) onStart() {
 	for _, h := range s.hooks {
 		if hook, ok := h.(StartStopSpanHook); ok {
-			if err := hook.OnPostStart(s); err != nil {
-				s.logError("OnPostStart hook error: ", err)
-			}
+			callOnPostStart(hook, s)
 		}
 	}
 }
@@ -130,10 +138,37 @@ func (s Span) SpanType() SpanType {
 }
 
 // TraceID returns the ID for the Trace that this span is a part of.
+//
+// For 128-bit trace IDs it's the lower 64 bits,
+// see TraceIDHigh for the higher 64 bits.
 func (s Span) TraceID() uint64 {
 	return s.trace.traceID
 }
 
+// TraceIDHigh returns the higher 64 bits of the 128-bit trace ID,
+// or 0 if the trace ID is 64-bit.
+func (s Span) TraceIDHigh() uint64 {
+	return s.trace.traceIDHigh
+}
+
+// TraceIDHex returns the hex encoded trace ID,
+// in 32 characters for 128-bit trace IDs and 16 characters for 64-bit ones,
+// as used by Zipkin, B3 and W3C Trace Context.
+func (s Span) TraceIDHex() string {
+	return formatHexTraceID(s.trace.traceIDHigh, s.trace.traceID)
+}
+
+// TraceIDString returns the trace ID in the format of the Baseplate trace
+// headers: decimal for 64-bit trace IDs, and TraceIDHex for 128-bit ones.
+//
+// Headers.TraceID and StartSpanFromHeaders accept both formats.
+func (s Span) TraceIDString() string {
+	if s.trace.traceIDHigh != 0 {
+		return s.TraceIDHex()
+	}
+	return strconv.FormatUint(s.trace.traceID, 10)
+}
+
 // ParentID returns the ID for the parent span of the current span.
 func (s Span) ParentID() uint64 {
 	return s.trace.parentID
@@ -145,8 +180,13 @@ func (s Span) Flags() int64 {
 }
 
 // Sampled returns if the current span is sampled.
+//
+// Spans with the debug flag set are always sampled,
+// regardless of the sampling decision made by the upstream or the Sampler,
+// so the flag set by SetDebug or the upstream headers also forces the
+// downstream services to sample the trace.
 func (s Span) Sampled() bool {
-	return s.trace.sampled
+	return s.trace.shouldSample()
 }
 
 // logError is a helper method to log an error plus a message.
@@ -182,6 +222,11 @@ func (s *Span) AddHooks(hooks ...interface{}) {
 }
 
 // SetDebug sets or unsets the debug flag of this Span.
+//
+// The debug flag is inherited by the child spans created after it's set,
+// and propagated to the downstream services via the flags headers.
+// Spans with debug flag set are always sampled,
+// and are reported with the "debug" binary annotation.
 func (s *Span) SetDebug(v bool) {
 	s.trace.setDebug(v)
 }
@@ -192,9 +237,7 @@ func (s *Span) SetTag(key string, value interface{}) opentracing.Span {
 	s.trace.setTag(key, value)
 	for _, h := range s.hooks {
 		if hook, ok := h.(SetSpanTagHook); ok {
-			if err := hook.OnSetTag(s, key, value); err != nil {
-				s.logError("OnSetTag hook error: ", err)
-			}
+			callOnSetTag(hook, s, key, value)
 		}
 	}
 	return s
@@ -206,13 +249,23 @@ func (s *Span) AddCounter(key string, delta float64) {
 	s.trace.addCounter(key, delta)
 	for _, h := range s.hooks {
 		if hook, ok := h.(AddSpanCounterHook); ok {
-			if err := hook.OnAddCounter(s, key, delta); err != nil {
-				s.logError("OnAddCounter hook error: ", err)
-			}
+			callOnAddCounter(hook, s, key, delta)
 		}
 	}
 }
 
+// AddAnnotation adds a timestamped annotation (a log) to the span.
+//
+// Annotations are reported as zipkin time annotations,
+// with value as the annotation key.
+// If timestamp is zero, current time will be used instead.
+func (s *Span) AddAnnotation(timestamp time.Time, value string) {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	s.trace.addAnnotation(timestamp, value)
+}
+
 // Component returns the local component name of this span, with special cases.
 //
 // For local spans,
@@ -237,6 +290,7 @@ func (s *Span) Component() string {
 func (s Span) initChildSpan(child *Span) {
 	child.trace.parentID = s.trace.spanID
 	child.trace.traceID = s.trace.traceID
+	child.trace.traceIDHigh = s.trace.traceIDHigh
 	child.trace.sampled = s.trace.sampled
 	child.trace.flags = s.trace.flags
 	child.hub = s.hub
@@ -247,9 +301,7 @@ func (s Span) initChildSpan(child *Span) {
 		// their hooks here. See also: Tracer.StartSpan.
 		for _, h := range s.hooks {
 			if hook, ok := h.(CreateChildSpanHook); ok {
-				if err := hook.OnCreateChild(&s, child); err != nil {
-					s.logError("OnCreateChild hook error: ", err)
-				}
+				callOnCreateChild(hook, &s, child)
 			}
 		}
 		child.onStart()
@@ -263,17 +315,37 @@ func (s Span) initChildSpan(child *Span) {
 // In most cases FinishWithOptions should be used instead,
 // which calls Stop and auto logs the error returned by Stop.
 // Stop is still provided in case there's need to handle the error differently.
+//
+// When the tracer is initialized with TracerConfig.PoolSpans,
+// the Span is owned by the pool after Stop returns,
+// and must not be used in any way afterwards,
+// including via the context objects it's attached to.
 func (s *Span) Stop(ctx context.Context, err error) error {
 	s.preStop(err)
 	for _, h := range s.hooks {
 		if hook, ok := h.(StartStopSpanHook); ok {
-			if hookErr := hook.OnPreStop(s, err); hookErr != nil {
-				s.logError("OnPreStop hook error: ", hookErr)
-			}
+			callOnPreStop(hook, s, err)
 		}
 	}
 	s.trace.stop = time.Now()
-	return s.trace.publish(ctx)
+	publishErr := s.trace.publish(ctx)
+	s.release()
+	return publishErr
+}
+
+// release puts the span back into spanPool if the tracer has PoolSpans set.
+func (s *Span) release() {
+	if !s.trace.tracer.poolSpans || !s.trace.reset() {
+		return
+	}
+	for i := range s.hooks {
+		s.hooks[i] = nil
+	}
+	*s = Span{
+		trace: s.trace,
+		hooks: s.hooks[:0],
+	}
+	spanPool.Put(s)
 }
 
 func (s *Span) preStop(err error) {
@@ -347,8 +419,10 @@ func (s *Span) BaggageItem(restrictedKey string) string {
 // It calls Stop with background context and nil error.
 // If Stop returns an error, it will also be logged with the tracer's logger.
 func (s *Span) Finish() {
+	// The span could be released by Stop, so get the tracer beforehand.
+	tracer := s.trace.tracer
 	if err := s.Stop(context.Background(), nil); err != nil {
-		s.logError("Span.Stop returned error: ", err)
+		tracer.getLogger()("Span.Stop returned error: " + err.Error())
 	}
 }
 
@@ -379,8 +453,10 @@ func (s *Span) FinishWithOptions(opts opentracing.FinishOptions) {
 			}
 		}
 	}
+	// The span could be released by Stop, so get the tracer beforehand.
+	tracer := s.trace.tracer
 	if stopErr := s.Stop(ctx, err); stopErr != nil {
-		s.logError("Span.Stop returned error: ", stopErr)
+		tracer.getLogger()("Span.Stop returned error: " + stopErr.Error())
 	}
 }
 
@@ -404,13 +480,27 @@ func (s *Span) Tracer() opentracing.Tracer {
 
 // LogFields implements opentracing.Span.
 //
-// In this implementation it's a no-op.
-func (s *Span) LogFields(fields ...log.Field) {}
+// In this implementation each field is added as an annotation in the format of
+// "key=value" with current time, see AddAnnotation.
+func (s *Span) LogFields(fields ...log.Field) {
+	now := time.Now()
+	for _, field := range fields {
+		s.AddAnnotation(now, fmt.Sprintf("%s=%v", field.Key(), field.Value()))
+	}
+}
 
 // LogKV implements opentracing.Span.
 //
-// In this implementation it's a no-op.
-func (s *Span) LogKV(alternatingKeyValues ...interface{}) {}
+// In this implementation each key value pair is added as an annotation,
+// see LogFields.
+func (s *Span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.logError("LogKV error: ", err)
+		return
+	}
+	s.LogFields(fields...)
+}
 
 // LogEvent implements opentracing.Span.
 //
@@ -430,6 +520,9 @@ func (s *Span) Log(data opentracing.LogData) {}
 // Headers is the argument struct for starting a Span from upstream headers.
 type Headers struct {
 	// TraceID is the trace ID passed via upstream headers.
+	//
+	// It's either a decimal 64-bit ID,
+	// or a 128-bit ID hex encoded in 32 characters.
 	TraceID string
 
 	// SpanID is the span ID passed via upstream headers.
@@ -448,6 +541,11 @@ type Headers struct {
 // Please note that "Sampled" header is default to false according to baseplate
 // spec, so if the headers are incorrect, this span (and all its child-spans)
 // will never be sampled, unless debug flag was set explicitly later.
+// When the debug flag is set in Flags, the span (and all its child-spans) will
+// always be sampled, regardless of the Sampled header.
+//
+// The trace id (in the format of TraceIDString) and span id of the new span are
+// attached to the returned context object as log fields, see log.Attach.
 //
 // If any headers are missing or malformed, they will be ignored.
 // Malformed headers will be logged if InitGlobalTracer was last called with a
@@ -463,14 +561,15 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 	ctx = opentracing.ContextWithSpan(ctx, span)
 
 	if headers.TraceID != "" {
-		if id, err := strconv.ParseUint(headers.TraceID, 10, 64); err != nil {
+		if high, low, err := parseTraceID(headers.TraceID); err != nil {
 			logger(fmt.Sprintf(
 				"Malformed trace id in http ctx: %q, %v",
 				headers.TraceID,
 				err,
 			))
 		} else {
-			span.trace.traceID = id
+			span.trace.traceID = low
+			span.trace.traceIDHigh = high
 		}
 	}
 
@@ -504,6 +603,11 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 
 	initRootSpan(span)
 	ctx = span.InjectSentryHub(ctx)
+	ctx = baseplatelog.Attach(
+		ctx,
+		"traceID", span.TraceIDString(),
+		"spanID", span.ID(),
+	)
 
 	return ctx, span
 }
@@ -529,7 +633,7 @@ func initRootSpan(s *Span) {
 		hub = hub.Clone()
 	}
 	hub.ConfigureScope(func(scope *sentry.Scope) {
-		scope.SetTag("trace_id", strconv.FormatUint(s.TraceID(), 10))
+		scope.SetTag("trace_id", s.TraceIDString())
 	})
 	s.hub = hub
 }
@@ -1,13 +1,19 @@
 package tracing
 
 import (
+	"context"
+	"encoding/json"
 	"math/rand"
 	"reflect"
+	"strconv"
 	"testing"
 	"testing/quick"
+	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
 
+	"github.com/reddit/baseplate.go/mqsend"
 	"github.com/reddit/baseplate.go/randbp"
 )
 
@@ -307,3 +313,94 @@ func TestSpanTypeStrings(t *testing.T) {
 		)
 	}
 }
+
+func TestAnnotations(t *testing.T) {
+	span := AsSpan(opentracing.StartSpan("span", SpanTypeOption{Type: SpanTypeLocal}))
+	ts := time.Unix(1234567890, 0)
+	span.AddAnnotation(ts, "foo")
+	span.LogKV("bar", 1)
+	span.LogFields(log.String("fizz", "buzz"))
+
+	expected := map[string]bool{
+		"foo":       true,
+		"bar=1":     true,
+		"fizz=buzz": true,
+	}
+	zs := span.trace.toZipkinSpan()
+	if len(zs.TimeAnnotations) != len(expected) {
+		t.Fatalf("Expected %d time annotations, got %#v", len(expected), zs.TimeAnnotations)
+	}
+	for _, annotation := range zs.TimeAnnotations {
+		if !expected[annotation.Key] {
+			t.Errorf("Unexpected time annotation %#v", annotation)
+		}
+		if annotation.Key == "foo" && !time.Time(annotation.Timestamp).Equal(ts) {
+			t.Errorf("Expected timestamp %v, got %v", ts, time.Time(annotation.Timestamp))
+		}
+	}
+}
+
+func TestDebugFlagFromHeaders(t *testing.T) {
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(TracerConfig{})
+	}()
+	recorder := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxQueueSize:   10,
+		MaxMessageSize: MaxSpanSize,
+	})
+	logger, startFailing := TestWrapper(t)
+	InitGlobalTracer(TracerConfig{
+		SampleRate:               0,
+		Logger:                   logger,
+		TestOnlyMockMessageQueue: recorder,
+	})
+	startFailing()
+
+	sampled := false
+	ctx, span := StartSpanFromHeaders(context.Background(), "server", Headers{
+		TraceID: "1234",
+		SpanID:  "5678",
+		Flags:   strconv.FormatInt(FlagMaskDebug, 10),
+		Sampled: &sampled,
+	})
+	if !span.Sampled() {
+		t.Error("Expected server span with debug flag to be sampled")
+	}
+	child := AsSpan(opentracing.StartSpan(
+		"client",
+		opentracing.ChildOf(span),
+		SpanTypeOption{Type: SpanTypeClient},
+	))
+	if child.Flags()&FlagMaskDebug == 0 {
+		t.Errorf("Expected child span to inherit debug flag, got flags %d", child.Flags())
+	}
+	if !child.Sampled() {
+		t.Error("Expected child span with debug flag to be sampled")
+	}
+
+	for _, s := range []*Span{child, span} {
+		if err := s.Stop(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+		msg, err := recorder.Receive(ctx)
+		if err != nil {
+			t.Fatalf("Expected span %q to be reported, got %v", s.Name(), err)
+		}
+		var zs ZipkinSpan
+		if err := json.Unmarshal(msg, &zs); err != nil {
+			t.Fatal(err)
+		}
+		var debug bool
+		for _, a := range zs.BinaryAnnotations {
+			if a.Key == ZipkinBinaryAnnotationKeyDebug {
+				debug = a.Value == "true"
+			}
+		}
+		if !debug {
+			t.Errorf("Expected span %q to have debug annotation, got %+v", s.Name(), zs.BinaryAnnotations)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package tracing
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/reddit/baseplate.go/randbp"
@@ -32,34 +33,84 @@ type trace struct {
 	sampled  bool
 	flags    int64
 
+	// The higher 64 bits of 128-bit trace ids, 0 for 64-bit trace ids.
+	traceIDHigh uint64
+
 	timeAnnotationReceiveKey string
 	timeAnnotationSendKey    string
 	start                    time.Time
 	stop                     time.Time
 
-	counters map[string]float64
-	tags     map[string]string
+	counters    map[string]float64
+	tags        map[string]string
+	annotations []annotation
+}
+
+type annotation struct {
+	timestamp time.Time
+	value     string
 }
 
-func newTrace(tracer *Tracer, name string) *trace {
+// init initializes a trace that's either newly allocated or reset,
+// reusing its counters, tags and annotations.
+func (t *trace) init(tracer *Tracer, name string) {
 	if tracer == nil {
 		tracer = &globalTracer
 	}
-	return &trace{
+	counters, tags, annotations := t.counters, t.tags, t.annotations
+	if counters == nil {
+		counters = make(map[string]float64)
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags[ZipkinBinaryAnnotationKeyComponent] = baseplateComponent
+	*t = trace{
 		tracer: tracer,
 
-		name:    name,
-		traceID: nonZeroRandUint64(),
-		spanID:  nonZeroRandUint64(),
-		start:   time.Now(),
+		name:        name,
+		traceID:     nonZeroRandUint64(),
+		traceIDHigh: tracer.newTraceIDHigh(),
+		spanID:      nonZeroRandUint64(),
+		start:       time.Now(),
 
-		counters: make(map[string]float64),
-		tags: map[string]string{
-			ZipkinBinaryAnnotationKeyComponent: baseplateComponent,
-		},
+		counters:    counters,
+		tags:        tags,
+		annotations: annotations,
 	}
 }
 
+// reset clears the trace for reuse.
+//
+// It returns false when the trace grew too big to be worth keeping,
+// in which case it should be left to the GC instead.
+func (t *trace) reset() bool {
+	if len(t.counters) > maxPooledTraceEntries ||
+		len(t.tags) > maxPooledTraceEntries ||
+		cap(t.annotations) > maxPooledTraceEntries {
+		return false
+	}
+	for key := range t.counters {
+		delete(t.counters, key)
+	}
+	for key := range t.tags {
+		delete(t.tags, key)
+	}
+	for i := range t.annotations {
+		t.annotations[i] = annotation{}
+	}
+	*t = trace{
+		counters:    t.counters,
+		tags:        t.tags,
+		annotations: t.annotations[:0],
+	}
+	return true
+}
+
+// maxPooledTraceEntries is the max number of counters, tags or annotations of
+// a span for it to be put back into spanPool.
+const maxPooledTraceEntries = 64
+
 func (t *trace) addCounter(key string, delta float64) {
 	t.counters[key] += delta
 }
@@ -68,13 +119,21 @@ func (t *trace) setTag(key string, value interface{}) {
 	t.tags[key] = fmt.Sprintf("%v", value)
 }
 
+func (t *trace) addAnnotation(timestamp time.Time, value string) {
+	t.annotations = append(t.annotations, annotation{
+		timestamp: timestamp,
+		value:     value,
+	})
+}
+
 func (t *trace) toZipkinSpan() ZipkinSpan {
 	zs := ZipkinSpan{
-		TraceID:  t.traceID,
-		Name:     t.name,
-		SpanID:   t.spanID,
-		Start:    timebp.TimestampMicrosecond(t.start),
-		ParentID: t.parentID,
+		TraceID:     t.traceID,
+		TraceIDHigh: t.traceIDHigh,
+		Name:        t.name,
+		SpanID:      t.spanID,
+		Start:       timebp.TimestampMicrosecond(t.start),
+		ParentID:    t.parentID,
 	}
 	end := t.stop
 	if end.IsZero() {
@@ -103,6 +162,14 @@ func (t *trace) toZipkinSpan() ZipkinSpan {
 		})
 	}
 
+	for _, a := range t.annotations {
+		zs.TimeAnnotations = append(zs.TimeAnnotations, ZipkinTimeAnnotation{
+			Endpoint:  endpoint,
+			Key:       a.value,
+			Timestamp: timebp.TimestampMicrosecond(a.timestamp),
+		})
+	}
+
 	zs.BinaryAnnotations = make([]ZipkinBinaryAnnotation, 0, len(t.counters)+len(t.tags))
 	for key, value := range t.counters {
 		zs.BinaryAnnotations = append(
@@ -154,6 +221,33 @@ func (t *trace) publish(ctx context.Context) error {
 	return t.tracer.Record(ctx, t.toZipkinSpan())
 }
 
+// formatHexTraceID returns the hex encoded trace id,
+// in 32 characters for 128-bit trace ids and 16 characters for 64-bit ones.
+func formatHexTraceID(high, low uint64) string {
+	if high != 0 {
+		return fmt.Sprintf("%016x%016x", high, low)
+	}
+	return fmt.Sprintf("%016x", low)
+}
+
+// parseTraceID parses a trace id from upstream headers.
+//
+// The trace id is either a decimal 64-bit id,
+// or a 128-bit id hex encoded in 32 characters.
+func parseTraceID(s string) (high, low uint64, err error) {
+	low, err = strconv.ParseUint(s, 10, 64)
+	if err == nil || len(s) != 32 {
+		return 0, low, err
+	}
+	if high, err = strconv.ParseUint(s[:16], 16, 64); err != nil {
+		return 0, 0, err
+	}
+	if low, err = strconv.ParseUint(s[16:], 16, 64); err != nil {
+		return 0, 0, err
+	}
+	return high, low, nil
+}
+
 // In opentracing spec, zero trace/span/parent ids have special meanings.
 // So we should use this function to generate non-zero random ids.
 func nonZeroRandUint64() uint64 {
@@ -14,3 +14,49 @@ func TestNonZeroRandUint64(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestParseTraceID(t *testing.T) {
+	for _, c := range []struct {
+		id   string
+		high uint64
+		low  uint64
+		err  bool
+	}{
+		{
+			id:  "1234",
+			low: 1234,
+		},
+		{
+			id:   "80f198ee56343ba864fe8b2a57d3eff7",
+			high: 0x80f198ee56343ba8,
+			low:  0x64fe8b2a57d3eff7,
+		},
+		{
+			id:  "64fe8b2a57d3eff7",
+			err: true,
+		},
+		{
+			id:  "80f198ee56343ba864fe8b2a57d3effg",
+			err: true,
+		},
+	} {
+		t.Run(c.id, func(t *testing.T) {
+			high, low, err := parseTraceID(c.id)
+			if c.err {
+				if err == nil {
+					t.Errorf("Expected error, got %x, %x", high, low)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if high != c.high || low != c.low {
+				t.Errorf("Expected %x, %x, got %x, %x", c.high, c.low, high, low)
+			}
+			if actual := formatHexTraceID(high, low); c.high != 0 && actual != c.id {
+				t.Errorf("Expected hex trace id %q, got %q", c.id, actual)
+			}
+		})
+	}
+}
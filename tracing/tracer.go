@@ -6,13 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-kit/kit/metrics"
 	opentracing "github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/mqsend"
-	"github.com/reddit/baseplate.go/randbp"
 	"github.com/reddit/baseplate.go/runtimebp"
 )
 
@@ -39,13 +40,34 @@ func init() {
 
 var globalTracer = Tracer{logger: log.NopWrapper}
 
+// spansDropped holds the counter set by SetSpansDroppedCounter,
+// as a counterValue.
+var spansDropped atomic.Value
+
+// SetSpansDroppedCounter sets the counter incremented every time a Tracer
+// fails to publish a span to the message queue,
+// usually because the queue is full or the span is too big.
+// A nil counter stops the counting.
+//
+// metricsbp.InitFromConfig sets it to the "tracing.spans.dropped" counter.
+//
+// It's safe to be called concurrently with the spans being recorded.
+func SetSpansDroppedCounter(counter metrics.Counter) {
+	spansDropped.Store(counterValue{counter})
+}
+
 // A Tracer creates and manages spans.
 type Tracer struct {
-	sampleRate       float64
+	// Accessed atomically, keep it as the first field for 64-bit alignment.
+	droppedSpans uint64
+
+	sampler          Sampler
 	recorder         mqsend.MessageQueue
 	logger           log.Wrapper
 	endpoint         ZipkinEndpointInfo
 	maxRecordTimeout time.Duration
+	traceID128Bit    bool
+	poolSpans        bool
 }
 
 // TracerConfig are the configuration values to be used in InitGlobalTracer.
@@ -63,6 +85,13 @@ type TracerConfig struct {
 	// headers from the client.
 	SampleRate float64
 
+	// Sampler, if non-nil, will be used to decide whether new traces should be
+	// sampled, and SampleRate will be ignored.
+	//
+	// Same as SampleRate, it only affects top level spans created inside this
+	// service.
+	Sampler Sampler
+
 	// Logger, if non-nil, will be used to log additional informations Record
 	// returned certain errors.
 	Logger log.Wrapper
@@ -81,16 +110,65 @@ type TracerConfig struct {
 	//
 	// QueueName should not contain "traces-" prefix, it will be auto added.
 	//
-	// If QueueName is empty, no spans will be sampled,
-	// including the ones with debug flag set.
+	// If QueueName, HTTPReporter.Endpoint and MessageQueue are all empty,
+	// no spans will be sampled, including the ones with debug flag set.
 	QueueName string
 
+	// HTTPReporter, when QueueName is empty and HTTPReporter.Endpoint is
+	// non-empty, is used to send sampled spans directly to a Zipkin V2
+	// collector via HTTP, for environments without the trace publishing
+	// sidecar.
+	//
+	// If HTTPReporter.Logger is nil, Logger will be used instead.
+	HTTPReporter HTTPReporterConfig
+
+	// MessageQueue, when both QueueName and HTTPReporter.Endpoint are empty,
+	// is used to publish the json encoded ZipkinSpans of the sampled spans,
+	// for example an otelbridge.Exporter publishing them through an
+	// OpenTelemetry exporter.
+	//
+	// It will be closed by CloseTracer.
+	MessageQueue mqsend.MessageQueue
+
+	// AsyncReporter, if non-nil, moves the publishing of the finished spans to
+	// a background goroutine via an AsyncReporter wrapping the message queue
+	// (or the HTTPReporter),
+	// so Span.Stop only puts the span into a bounded in-memory queue.
+	//
+	// MaxRecordTimeout is used as the timeout of publishing every span in the
+	// background goroutine, and also as the max time Span.Stop blocks when
+	// AsyncReporter.Block is true.
+	//
+	// If AsyncReporter.Logger is nil, Logger will be used instead.
+	AsyncReporter *AsyncReporterConfig
+
+	// TraceID128Bit controls whether new traces created in this service use
+	// 128-bit trace ids instead of 64-bit ones,
+	// for compatibility with the tracing systems requiring 128-bit trace ids.
+	//
+	// Trace ids from upstream headers are always kept as-is,
+	// 64-bit or 128-bit.
+	TraceID128Bit bool
+
+	// PoolSpans controls whether the spans, along with their tags and
+	// annotations, are reused via a sync.Pool after they are stopped,
+	// to reduce the GC pressure of the services creating a lot of spans.
+	//
+	// When it's true, a Span must not be used in any way after Stop
+	// (or Finish/FinishWithOptions) is called on it,
+	// including reading its IDs, calling Stop again,
+	// or using the context objects the span is attached to to create child
+	// spans.
+	// The hooks are called before the span is released so they are safe,
+	// but they must not keep the *Span after OnPreStop returns.
+	PoolSpans bool
+
 	// In test code,
 	// this field can be used to set the message queue the tracer publishes to,
 	// usually an *mqsend.MockMessageQueue.
 	//
-	// This field will be ignored when QueueName is non-empty,
-	// to help avoiding footgun prod code.
+	// This field will be ignored when QueueName, HTTPReporter.Endpoint or
+	// MessageQueue is non-empty, to help avoiding footgun prod code.
 	//
 	// DO NOT USE IN PROD CODE.
 	TestOnlyMockMessageQueue mqsend.MessageQueue
@@ -103,7 +181,22 @@ type TracerConfig struct {
 // If it fails to do so, UndefinedIP will be used instead,
 // and the error will be logged if logger is non-nil.
 func InitGlobalTracer(cfg TracerConfig) error {
-	if cfg.QueueName != "" {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.NopWrapper
+	}
+
+	ip, err := runtimebp.GetFirstIPv4()
+	if err != nil {
+		logger(`Unable to get local ip address: ` + err.Error())
+	}
+	endpoint := ZipkinEndpointInfo{
+		ServiceName: cfg.ServiceName,
+		IPv4:        ip,
+	}
+
+	switch {
+	case cfg.QueueName != "":
 		recorder, err := mqsend.OpenMessageQueue(mqsend.MessageQueueConfig{
 			Name:           QueueNamePrefix + cfg.QueueName,
 			MaxQueueSize:   MaxQueueSize,
@@ -113,32 +206,47 @@ func InitGlobalTracer(cfg TracerConfig) error {
 			return err
 		}
 		globalTracer.recorder = recorder
-	} else {
+	case cfg.HTTPReporter.Endpoint != "":
+		reporterCfg := cfg.HTTPReporter
+		if reporterCfg.Logger == nil {
+			reporterCfg.Logger = logger
+		}
+		if reporterCfg.LocalEndpoint == (ZipkinEndpointInfo{}) {
+			reporterCfg.LocalEndpoint = endpoint
+		}
+		reporter, err := NewHTTPReporter(reporterCfg)
+		if err != nil {
+			return err
+		}
+		globalTracer.recorder = reporter
+	case cfg.MessageQueue != nil:
+		globalTracer.recorder = cfg.MessageQueue
+	default:
 		globalTracer.recorder = cfg.TestOnlyMockMessageQueue
 	}
 
-	globalTracer.sampleRate = cfg.SampleRate
-
-	logger := cfg.Logger
-	if logger == nil {
-		logger = log.NopWrapper
-	}
-	globalTracer.logger = logger
-
 	timeout := cfg.MaxRecordTimeout
 	if timeout <= 0 {
 		timeout = DefaultMaxRecordTimeout
 	}
-	globalTracer.maxRecordTimeout = timeout
-
-	ip, err := runtimebp.GetFirstIPv4()
-	if err != nil {
-		logger(`Unable to get local ip address: ` + err.Error())
+	if cfg.AsyncReporter != nil && globalTracer.recorder != nil {
+		reporterCfg := *cfg.AsyncReporter
+		if reporterCfg.Logger == nil {
+			reporterCfg.Logger = logger
+		}
+		globalTracer.recorder = newAsyncReporter(reporterCfg, globalTracer.recorder, timeout)
 	}
-	globalTracer.endpoint = ZipkinEndpointInfo{
-		ServiceName: cfg.ServiceName,
-		IPv4:        ip,
+
+	sampler := cfg.Sampler
+	if sampler == nil {
+		sampler = ProbabilisticSampler(cfg.SampleRate)
 	}
+	globalTracer.sampler = sampler
+	globalTracer.logger = logger
+	globalTracer.maxRecordTimeout = timeout
+	globalTracer.endpoint = endpoint
+	globalTracer.traceID128Bit = cfg.TraceID128Bit
+	globalTracer.poolSpans = cfg.PoolSpans
 
 	opentracing.SetGlobalTracer(&globalTracer)
 	return nil
@@ -174,25 +282,59 @@ func (t *Tracer) Close() error {
 	return err
 }
 
+// flusher is implemented by the message queues buffering spans in memory,
+// e.g. AsyncReporter and HTTPReporter.
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Flush blocks until the spans buffered in memory by the message queue of the
+// tracer (e.g. AsyncReporter and HTTPReporter) are published,
+// or ctx is done, whichever comes first.
+//
+// It's a no-op when the message queue doesn't buffer spans.
+//
+// Unlike Close, the tracer still records spans after Flush returns.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if f, ok := t.recorder.(flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// SpanRecorder is an optional interface the message queue of the Tracer can
+// implement to take the finished spans as-is,
+// instead of json encoded via mqsend.MessageQueue.Send.
+//
+// HTTPReporter and otelbridge.Exporter implement it.
+type SpanRecorder interface {
+	RecordSpan(ctx context.Context, zs ZipkinSpan) error
+}
+
 // Record records a span with the Recorder.
 //
 // Span.Stop(), Span.Finish(), and Span.FinishWithOptions() call this function
 // automatically.
 // In most cases that should be enough and you should not call this function
 // directly.
+//
+// When the message queue implements SpanRecorder, the span is passed to
+// RecordSpan without being json encoded.
 func (t *Tracer) Record(ctx context.Context, zs ZipkinSpan) error {
 	if t.recorder == nil {
 		return nil
 	}
-	data, err := json.Marshal(zs)
-	if err != nil {
-		return err
-	}
 
 	timeout := t.maxRecordTimeout
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	err = t.recorder.Send(ctx, data)
+	err := t.record(ctx, zs)
+	if err != nil {
+		atomic.AddUint64(&t.droppedSpans, 1)
+		if counter, _ := spansDropped.Load().(counterValue); counter.Counter != nil {
+			counter.Add(1)
+		}
+	}
 	if t.logger != nil {
 		if errors.As(err, new(mqsend.MessageTooLargeError)) {
 			t.logger(fmt.Sprintf(
@@ -210,6 +352,26 @@ func (t *Tracer) Record(ctx context.Context, zs ZipkinSpan) error {
 	return err
 }
 
+func (t *Tracer) record(ctx context.Context, zs ZipkinSpan) error {
+	if recorder, ok := t.recorder.(SpanRecorder); ok {
+		return recorder.RecordSpan(ctx, zs)
+	}
+	data, err := json.Marshal(zs)
+	if err != nil {
+		return err
+	}
+	return t.recorder.Send(ctx, data)
+}
+
+// DroppedSpans returns the total number of spans Record failed to publish to
+// the message queue, usually because the queue is full or the span is too big.
+//
+// The dropped spans are also reported to the counter set by
+// SetSpansDroppedCounter.
+func (t *Tracer) DroppedSpans() uint64 {
+	return atomic.LoadUint64(&t.droppedSpans)
+}
+
 // StartSpan implements opentracing.Tracer.
 //
 // For opentracing.StartSpanOptions,
@@ -244,7 +406,8 @@ func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOp
 		parent.initChildSpan(span)
 	} else {
 		span.trace.traceID = nonZeroRandUint64()
-		span.trace.sampled = randbp.ShouldSampleWithRate(t.sampleRate)
+		span.trace.traceIDHigh = t.newTraceIDHigh()
+		span.trace.sampled = t.shouldSample()
 		initRootSpan(span)
 	}
 
@@ -276,6 +439,22 @@ func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.S
 	return nil, opentracing.ErrInvalidCarrier
 }
 
+func (t *Tracer) shouldSample() bool {
+	if t.sampler == nil {
+		return false
+	}
+	return t.sampler.ShouldSample()
+}
+
+// newTraceIDHigh returns the higher 64 bits for a new trace id,
+// which is 0 unless the tracer is configured to use 128-bit trace ids.
+func (t *Tracer) newTraceIDHigh() uint64 {
+	if !t.traceID128Bit {
+		return 0
+	}
+	return nonZeroRandUint64()
+}
+
 func (t *Tracer) getLogger() log.Wrapper {
 	return log.FallbackWrapper(t.logger)
 }
@@ -292,6 +471,17 @@ func findFirstParentReference(refs []opentracing.SpanReference) *Span {
 	return nil
 }
 
+// FlushTracer tries to cast opentracing.GlobalTracer() into *Tracer, and calls
+// its Flush function.
+//
+// See Tracer.Flush for more details.
+func FlushTracer(ctx context.Context) error {
+	if tracer, ok := opentracing.GlobalTracer().(*Tracer); ok {
+		return tracer.Flush(ctx)
+	}
+	return nil
+}
+
 // CloseTracer tries to cast opentracing.GlobalTracer() into *Tracer, and calls
 // its Close function.
 //
@@ -2,10 +2,13 @@ package tracing
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/metrics/generic"
 	opentracing "github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go/log"
@@ -44,9 +47,19 @@ func TestTracer(t *testing.T) {
 			// The above InitGlobalTracer might call the logger once for unable to get
 			// ip, so clear the called state.
 			*called = false
+			dropped := globalTracer.DroppedSpans()
+			counter := generic.NewCounter("tracing.spans.dropped")
+			SetSpansDroppedCounter(counter)
+			defer SetSpansDroppedCounter(nil)
 
 			span := AsSpan(opentracing.StartSpan("span"))
 			err := span.Stop(context.Background(), nil)
+			if actual := globalTracer.DroppedSpans(); actual != dropped+1 {
+				t.Errorf("Expected DroppedSpans to be %d, got %d", dropped+1, actual)
+			}
+			if actual := counter.Value(); actual != 1 {
+				t.Errorf("Expected spans dropped counter to be 1, got %v", actual)
+			}
 			var e mqsend.MessageTooLargeError
 			if !errors.As(err, &e) {
 				t.Errorf("Expected MessageTooLargeError, got %v", err)
@@ -78,11 +91,15 @@ func TestTracer(t *testing.T) {
 	t.Run(
 		"first-message",
 		func(t *testing.T) {
+			dropped := globalTracer.DroppedSpans()
 			span := AsSpan(opentracing.StartSpan("span"))
 			err := span.Stop(context.Background(), nil)
 			if err != nil {
 				t.Errorf("End returned error: %v", err)
 			}
+			if actual := globalTracer.DroppedSpans(); actual != dropped {
+				t.Errorf("Expected DroppedSpans to be %d, got %d", dropped, actual)
+			}
 			if *called {
 				t.Errorf("Logger shouldn't be called with first span.")
 			}
@@ -98,9 +115,13 @@ func TestTracer(t *testing.T) {
 			span := AsSpan(opentracing.StartSpan("span"))
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
+			dropped := globalTracer.DroppedSpans()
 			start := span.trace.start
 			err := span.Stop(ctx, nil)
 			duration := time.Since(start)
+			if actual := globalTracer.DroppedSpans(); actual != dropped+1 {
+				t.Errorf("Expected DroppedSpans to be %d, got %d", dropped+1, actual)
+			}
 			if duration > DefaultMaxRecordTimeout*2 {
 				t.Errorf(
 					"Expected duration of around %v, got %v",
@@ -133,3 +154,137 @@ func TestTracer(t *testing.T) {
 		},
 	)
 }
+
+func TestTracerSampler(t *testing.T) {
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(TracerConfig{})
+	}()
+
+	for _, sampled := range []bool{true, false} {
+		InitGlobalTracer(TracerConfig{
+			// SampleRate should be ignored when Sampler is set.
+			SampleRate: 0.5,
+			Sampler: SamplerFunc(func() bool {
+				return sampled
+			}),
+		})
+
+		span := AsSpan(opentracing.StartSpan("root"))
+		if span.Sampled() != sampled {
+			t.Errorf("Expected root span sampled to be %v, got %v", sampled, span.Sampled())
+		}
+		child := AsSpan(opentracing.StartSpan("child", opentracing.ChildOf(span)))
+		if child.Sampled() != sampled {
+			t.Errorf("Expected child span sampled to be %v, got %v", sampled, child.Sampled())
+		}
+	}
+}
+
+func TestTracerPoolSpans(t *testing.T) {
+	recorder := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxQueueSize:   10,
+		MaxMessageSize: MaxSpanSize,
+	})
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(TracerConfig{})
+	}()
+	InitGlobalTracer(TracerConfig{
+		SampleRate:               1,
+		PoolSpans:                true,
+		TestOnlyMockMessageQueue: recorder,
+	})
+
+	for i := 0; i < 3; i++ {
+		span := AsSpan(opentracing.StartSpan("span"))
+		if len(span.hooks) != 0 || span.hub == nil || span.component != "" {
+			t.Fatalf("Expected a clean span, got %#v", span)
+		}
+		if len(span.trace.counters) != 0 || len(span.trace.annotations) != 0 {
+			t.Fatalf("Expected no counters or annotations, got %#v", span.trace)
+		}
+		if len(span.trace.tags) != 1 || span.trace.tags[ZipkinBinaryAnnotationKeyComponent] != baseplateComponent {
+			t.Fatalf("Expected only the component tag, got %#v", span.trace.tags)
+		}
+		span.SetTag("foo", "bar")
+		span.AddCounter("counter", 1)
+		span.AddAnnotation(time.Now(), "annotation")
+		if err := span.Stop(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+		msg, err := recorder.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var zs ZipkinSpan
+		if err := json.Unmarshal(msg, &zs); err != nil {
+			t.Fatal(err)
+		}
+		if zs.Name != "span" || len(zs.BinaryAnnotations) != 3 || len(zs.TimeAnnotations) != 1 {
+			t.Errorf("Unexpected published span: %#v", zs)
+		}
+	}
+}
+
+func TestTraceResetTooBig(t *testing.T) {
+	var tr trace
+	tr.init(nil, "span")
+	for i := 0; i <= maxPooledTraceEntries; i++ {
+		tr.setTag(strconv.Itoa(i), i)
+	}
+	if tr.reset() {
+		t.Error("Expected reset to return false for a trace with too many tags")
+	}
+}
+
+func TestTracerTraceID128Bit(t *testing.T) {
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(TracerConfig{})
+	}()
+	InitGlobalTracer(TracerConfig{TraceID128Bit: true})
+
+	t.Run("new-trace", func(t *testing.T) {
+		span := AsSpan(opentracing.StartSpan("span"))
+		if span.TraceIDHigh() == 0 {
+			t.Fatal("Expected 128-bit trace id, got 64-bit")
+		}
+		child := AsSpan(opentracing.StartSpan("child", opentracing.ChildOf(span)))
+		if child.TraceIDHex() != span.TraceIDHex() {
+			t.Errorf("Expected child trace id %q, got %q", span.TraceIDHex(), child.TraceIDHex())
+		}
+		zs := child.trace.toZipkinSpan()
+		if zs.TraceIDHigh != span.TraceIDHigh() || zs.TraceID != span.TraceID() {
+			t.Errorf("Unexpected trace id in zipkin span: %x, %x", zs.TraceIDHigh, zs.TraceID)
+		}
+		if v2 := toZipkinV2Span(zs, ZipkinEndpointInfo{}); v2.TraceID != span.TraceIDHex() || len(v2.TraceID) != 32 {
+			t.Errorf("Expected zipkin v2 trace id %q, got %q", span.TraceIDHex(), v2.TraceID)
+		}
+	})
+
+	t.Run("upstream-64-bit", func(t *testing.T) {
+		_, span := StartSpanFromHeaders(context.Background(), "span", Headers{
+			TraceID: "1234",
+		})
+		if span.TraceIDHigh() != 0 || span.TraceID() != 1234 {
+			t.Errorf("Expected 64-bit trace id 1234 kept, got %q", span.TraceIDHex())
+		}
+		if actual := span.TraceIDString(); actual != "1234" {
+			t.Errorf("Expected trace id string %q, got %q", "1234", actual)
+		}
+	})
+
+	t.Run("upstream-128-bit", func(t *testing.T) {
+		const id = "80f198ee56343ba864fe8b2a57d3eff7"
+		_, span := StartSpanFromHeaders(context.Background(), "span", Headers{
+			TraceID: id,
+		})
+		if actual := span.TraceIDString(); actual != id {
+			t.Errorf("Expected trace id string %q, got %q", id, actual)
+		}
+	})
+}
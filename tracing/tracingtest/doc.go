@@ -0,0 +1,16 @@
+// Package tracingtest provides an in-memory span recorder for tests.
+//
+// It allows inspecting the spans published by the code under test without
+// setting up a message queue manually.
+//
+// A typical test looks like:
+//
+//     func TestMyMiddleware(t *testing.T) {
+//       recorder := tracingtest.InitGlobalTracer(t)
+//       // Call the code creating spans...
+//       span := recorder.MustFindSpan(t, "my-span")
+//       if span.IsError() {
+//         t.Error("Expected span to succeed")
+//       }
+//     }
+package tracingtest
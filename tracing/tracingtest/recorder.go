@@ -0,0 +1,175 @@
+package tracingtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Span is a span recorded by a Recorder.
+type Span struct {
+	tracing.ZipkinSpan
+}
+
+// Tag returns the value of the binary annotation (tag) with the given key.
+//
+// Please note that the tracer converts all tag values into strings,
+// e.g. a tag set to true will be "true".
+func (s Span) Tag(key string) (value interface{}, ok bool) {
+	for _, annotation := range s.BinaryAnnotations {
+		if annotation.Key == key {
+			return annotation.Value, true
+		}
+	}
+	return nil, false
+}
+
+// HasAnnotation returns true if the span has the time annotation with the
+// given value, e.g. tracing.ZipkinTimeAnnotationKeyServerReceive.
+func (s Span) HasAnnotation(value string) bool {
+	for _, annotation := range s.TimeAnnotations {
+		if annotation.Key == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IsError returns true if the span was finished with an error.
+func (s Span) IsError() bool {
+	v, _ := s.Tag(tracing.ZipkinBinaryAnnotationKeyError)
+	return fmt.Sprint(v) == "true"
+}
+
+// IsRoot returns true if the span has no parent.
+func (s Span) IsRoot() bool {
+	return s.ParentID == 0
+}
+
+// Recorder is an mqsend.MessageQueue implementation that records all the
+// spans published to it in memory.
+//
+// The zero value is ready to be used as
+// tracing.TracerConfig.TestOnlyMockMessageQueue,
+// but in most cases InitGlobalTracer should be used instead.
+type Recorder struct {
+	lock  sync.Mutex
+	spans []Span
+}
+
+var _ mqsend.MessageQueue = (*Recorder)(nil)
+
+// InitGlobalTracer creates a new Recorder and initializes the global tracer
+// to sample all spans and publish them to the Recorder.
+//
+// The global tracer will be closed when the test finishes.
+func InitGlobalTracer(tb testing.TB) *Recorder {
+	tb.Helper()
+
+	recorder := new(Recorder)
+	logger, startFailing := tracing.TestWrapper(tb)
+	if err := tracing.InitGlobalTracer(tracing.TracerConfig{
+		SampleRate:               1,
+		TestOnlyMockMessageQueue: recorder,
+		Logger:                   logger,
+	}); err != nil {
+		tb.Fatalf("Failed to init global tracer: %v", err)
+	}
+	startFailing()
+	tb.Cleanup(func() {
+		tracing.CloseTracer()
+	})
+	return recorder
+}
+
+// Send implements mqsend.MessageQueue.
+//
+// It decodes data as a tracing.ZipkinSpan and records it.
+func (r *Recorder) Send(_ context.Context, data []byte) error {
+	var span Span
+	if err := json.Unmarshal(data, &span.ZipkinSpan); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = append(r.spans, span)
+	return nil
+}
+
+// Close implements mqsend.MessageQueue.
+//
+// It's a no-op and the recorded spans are still accessible after Close.
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Spans returns all the spans recorded so far, in the order they finished.
+func (r *Recorder) Spans() []Span {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Span(nil), r.spans...)
+}
+
+// Reset clears all the spans recorded so far.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = nil
+}
+
+// FindSpans returns all the recorded spans with the given name.
+func (r *Recorder) FindSpans(name string) []Span {
+	var spans []Span
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// MustFindSpan returns the only recorded span with the given name.
+//
+// It fails the test if there isn't exactly one span with the name.
+func (r *Recorder) MustFindSpan(tb testing.TB, name string) Span {
+	tb.Helper()
+
+	spans := r.FindSpans(name)
+	if len(spans) != 1 {
+		tb.Fatalf("Expected exactly 1 span named %q, got %d: %+v", name, len(spans), r.Spans())
+	}
+	return spans[0]
+}
+
+// Parent returns the recorded parent span of the given span.
+//
+// It returns false if the span is a root span,
+// or the parent span was not recorded (yet).
+func (r *Recorder) Parent(span Span) (parent Span, ok bool) {
+	if span.IsRoot() {
+		return Span{}, false
+	}
+	for _, s := range r.Spans() {
+		if s.TraceID == span.TraceID && s.SpanID == span.ParentID {
+			return s, true
+		}
+	}
+	return Span{}, false
+}
+
+// Children returns all the recorded child spans of the given span.
+func (r *Recorder) Children(span Span) []Span {
+	var children []Span
+	for _, s := range r.Spans() {
+		if s.TraceID == span.TraceID && s.ParentID == span.SpanID {
+			children = append(children, s)
+		}
+	}
+	return children
+}
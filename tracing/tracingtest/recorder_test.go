@@ -0,0 +1,67 @@
+package tracingtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/tracing/tracingtest"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := tracingtest.InitGlobalTracer(t)
+
+	sampled := true
+	ctx, server := tracing.StartSpanFromHeaders(
+		context.Background(),
+		"server",
+		tracing.Headers{Sampled: &sampled},
+	)
+	server.SetTag("foo", "bar")
+	child, _ := opentracing.StartSpanFromContext(ctx, "child")
+	if err := tracing.AsSpan(child).Stop(ctx, errors.New("failed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Stop(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if spans := recorder.Spans(); len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %+v", spans)
+	}
+
+	serverSpan := recorder.MustFindSpan(t, "server")
+	if serverSpan.IsError() {
+		t.Errorf("Expected server span to not be error, got %+v", serverSpan)
+	}
+	if v, ok := serverSpan.Tag("foo"); !ok || v != "bar" {
+		t.Errorf("Expected tag foo to be %q, got %v, %v", "bar", v, ok)
+	}
+	if !serverSpan.HasAnnotation(tracing.ZipkinTimeAnnotationKeyServerReceive) {
+		t.Errorf("Expected server span to have sr annotation, got %+v", serverSpan)
+	}
+	if _, ok := recorder.Parent(serverSpan); ok {
+		t.Error("Expected server span to have no parent")
+	}
+
+	childSpan := recorder.MustFindSpan(t, "child")
+	if !childSpan.IsError() {
+		t.Errorf("Expected child span to be error, got %+v", childSpan)
+	}
+	parent, ok := recorder.Parent(childSpan)
+	if !ok || parent.SpanID != serverSpan.SpanID {
+		t.Errorf("Expected parent of child span to be server span, got %+v, %v", parent, ok)
+	}
+	children := recorder.Children(serverSpan)
+	if len(children) != 1 || children[0].SpanID != childSpan.SpanID {
+		t.Errorf("Expected children of server span to be [child], got %+v", children)
+	}
+
+	recorder.Reset()
+	if spans := recorder.Spans(); len(spans) != 0 {
+		t.Errorf("Expected no spans after Reset, got %+v", spans)
+	}
+}
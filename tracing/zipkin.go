@@ -1,6 +1,9 @@
 package tracing
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/reddit/baseplate.go/timebp"
 )
 
@@ -23,6 +26,10 @@ type ZipkinSpan struct {
 	// but when it's absent 0 needs to be set explicitly.
 	ParentID uint64 `json:"parentId"`
 
+	// TraceIDHigh is the higher 64 bits of 128-bit trace ids,
+	// it's omitted for 64-bit trace ids.
+	TraceIDHigh uint64 `json:"traceIdHigh,omitempty"`
+
 	// Annotations are all optional.
 	TimeAnnotations   []ZipkinTimeAnnotation   `json:"annotations,omitempty"`
 	BinaryAnnotations []ZipkinBinaryAnnotation `json:"binaryAnnotations,omitempty"`
@@ -69,3 +76,86 @@ const (
 	ZipkinBinaryAnnotationKeyError   = "error"
 	ZipkinBinaryAnnotationKeyTimeOut = "timed_out"
 )
+
+// Zipkin V2 span kinds.
+const (
+	zipkinV2KindClient = "CLIENT"
+	zipkinV2KindServer = "SERVER"
+)
+
+// zipkinV2Span defines a span in zipkin's V2 json format, used by HTTPReporter.
+//
+// Reference:
+// https://zipkin.io/zipkin-api/#/default/post_spans
+type zipkinV2Span struct {
+	TraceID       string                   `json:"traceId"`
+	ID            string                   `json:"id"`
+	ParentID      string                   `json:"parentId,omitempty"`
+	Name          string                   `json:"name"`
+	Kind          string                   `json:"kind,omitempty"`
+	Timestamp     int64                    `json:"timestamp"`
+	Duration      int64                    `json:"duration"`
+	Debug         bool                     `json:"debug,omitempty"`
+	LocalEndpoint *ZipkinEndpointInfo      `json:"localEndpoint,omitempty"`
+	Annotations   []zipkinV2TimeAnnotation `json:"annotations,omitempty"`
+	Tags          map[string]string        `json:"tags,omitempty"`
+}
+
+type zipkinV2TimeAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// toZipkinV2Span converts zs into Zipkin V2 format.
+//
+// The well-known time annotations are converted to the kind of the span,
+// debug binary annotation is converted to the debug field,
+// and other binary annotations are converted to string tags.
+//
+// The endpoint in the annotations is used as the local endpoint,
+// with fallback being used when zs has no annotations.
+func toZipkinV2Span(zs ZipkinSpan, fallback ZipkinEndpointInfo) zipkinV2Span {
+	span := zipkinV2Span{
+		TraceID:   formatHexTraceID(zs.TraceIDHigh, zs.TraceID),
+		ID:        fmt.Sprintf("%016x", zs.SpanID),
+		Name:      zs.Name,
+		Timestamp: timebp.TimeToMicroseconds(zs.Start.ToTime()),
+		Duration:  int64(zs.Duration.ToDuration() / time.Microsecond),
+	}
+	if zs.ParentID != 0 {
+		span.ParentID = fmt.Sprintf("%016x", zs.ParentID)
+	}
+
+	endpoint := fallback
+	for _, a := range zs.TimeAnnotations {
+		endpoint = a.Endpoint
+		switch a.Key {
+		case ZipkinTimeAnnotationKeyClientSend, ZipkinTimeAnnotationKeyClientReceive:
+			span.Kind = zipkinV2KindClient
+		case ZipkinTimeAnnotationKeyServerReceive, ZipkinTimeAnnotationKeyServerSend:
+			span.Kind = zipkinV2KindServer
+		default:
+			span.Annotations = append(span.Annotations, zipkinV2TimeAnnotation{
+				Timestamp: timebp.TimeToMicroseconds(a.Timestamp.ToTime()),
+				Value:     a.Key,
+			})
+		}
+	}
+
+	for _, a := range zs.BinaryAnnotations {
+		endpoint = a.Endpoint
+		if a.Key == ZipkinBinaryAnnotationKeyDebug {
+			span.Debug = fmt.Sprint(a.Value) == "true"
+			continue
+		}
+		if span.Tags == nil {
+			span.Tags = make(map[string]string, len(zs.BinaryAnnotations))
+		}
+		span.Tags[a.Key] = fmt.Sprint(a.Value)
+	}
+
+	if endpoint != (ZipkinEndpointInfo{}) {
+		span.LocalEndpoint = &endpoint
+	}
+	return span
+}